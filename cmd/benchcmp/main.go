@@ -0,0 +1,57 @@
+// main.go
+//
+// benchcmp gates proof generation throughput against a baseline:
+//
+//	go test -run '^$' -bench ProofGeneration -count 3 ./test | go run ./cmd/benchcmp
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/internal/benchcmp"
+)
+
+func main() {
+	baselineFile := flag.String("baseline", "test/bench_baseline.json", "baseline JSON file")
+	update := flag.Bool("update", false, "overwrite the baseline with the benchmark output")
+	metric := flag.String("metric", benchcmp.DefaultMetric, "metric recorded by -update")
+	tolerance := flag.Float64("tolerance", benchcmp.DefaultTolerance, "allowed fractional drop recorded by -update")
+	flag.Parse()
+
+	results, err := benchcmp.Parse(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *update {
+		baseline, err := benchcmp.NewBaseline(results, *metric, *tolerance)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := baseline.Save(*baselineFile); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf(" Recorded %d benchmarks in %s\n", len(baseline.Benchmarks), *baselineFile)
+		return
+	}
+
+	baseline, err := benchcmp.LoadBaseline(*baselineFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	regressions, err := baseline.Compare(results)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(regressions) > 0 {
+		fmt.Printf(" %s dropped more than %.0f%% below the baseline:\n", baseline.Metric, baseline.Tolerance*100)
+		for _, regression := range regressions {
+			fmt.Printf("   %s\n", regression)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf(" No %s regressions against %s\n", baseline.Metric, *baselineFile)
+}
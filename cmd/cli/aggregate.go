@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// runAggregate folds the claims of users held at custodians such as
+// exchanges into one claim per custodian wallet, builds the tree over the
+// result and writes each custodian its proof and users' breakdown
+func runAggregate(args []string) {
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	in := fs.String("in", "airdrop_data.csv", "claims CSV with one row per user")
+	custodiansFile := fs.String("custodians", "custodians.csv", "CSV of user,custodian rows mapping users to custodian wallets")
+	out := fs.String("out", "aggregated_data.csv", "output claims CSV with one claim per custodian")
+	proofsFile := fs.String("proofs", "aggregated_proofs.json", "JSON proofs file of the aggregated tree")
+	dir := fs.String("dir", "custodians", "directory for one <custodian>.json allocation file per custodian")
+	overwrite := fs.Bool("overwrite", false, "replace existing output files")
+	caseName := fs.String("address-case", "checksum", "address case in the claims CSV and proofs: checksum or lower")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	addressCase, err := data.ParseAddressCase(*caseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	claims, err := data.LoadAirdropFromCSVWithOptions(*in, layout())
+	if err != nil {
+		log.Fatal("Failed to load claims: ", err)
+	}
+	custodians, err := data.LoadCustodiansFromCSV(*custodiansFile)
+	if err != nil {
+		log.Fatal("Failed to load custodians: ", err)
+	}
+	aggregated, users, err := data.AggregateByCustodian(claims, custodians)
+	if err != nil {
+		log.Fatal("Aggregation failed: ", err)
+	}
+
+	allocationFile := func(custodian string) string { return filepath.Join(*dir, custodian+".json") }
+	outputs := []string{*out, *proofsFile}
+	for custodian := range users {
+		outputs = append(outputs, allocationFile(custodian.Hex()))
+	}
+	checkOutputs(*overwrite, outputs...)
+
+	tree, err := merkle.NewMerkleTreeWithOptions(aggregated, merkle.DefaultTreeOptions())
+	if err != nil {
+		log.Fatal("Failed to build tree: ", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		log.Fatal("Failed to generate proofs: ", err)
+	}
+	proofSet := &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}
+	// The tree numbers claims by leaf position, so check the sums against
+	// what it holds rather than the aggregation's own claims
+	if err := data.CheckCustodianAllocations(tree.Claims, users); err != nil {
+		log.Fatal("Allocation check failed: ", err)
+	}
+
+	if err := data.SaveClaimsToCSV(aggregated, *out, addressCase); err != nil {
+		log.Fatal("Failed to save claims: ", err)
+	}
+	err = fsutil.AtomicWriteFile(*proofsFile, func(w io.Writer) error {
+		return data.ExportProofsCanonical(proofSet, tree.GetRootHash(), w, addressCase, data.ProofMarshaller{})
+	})
+	if err != nil {
+		log.Fatal("Failed to save proofs: ", err)
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		log.Fatal("Failed to create allocation directory: ", err)
+	}
+	for custodian, allocations := range users {
+		err := fsutil.AtomicWriteFile(allocationFile(custodian.Hex()), func(w io.Writer) error {
+			return data.ExportCustodianAllocation(proofSet, tree.GetRootHash(), custodian, allocations, w)
+		})
+		if err != nil {
+			log.Fatal("Failed to save allocation: ", err)
+		}
+		fmt.Printf(" %s: %d users, %s\n", custodian.Hex(), len(allocations), merkle.TotalAmount(allocations))
+	}
+
+	fmt.Printf(" Aggregated %d claims into %d (%d custodians, %d passed through)\n",
+		len(claims), len(aggregated), len(users), len(aggregated)-len(users))
+	fmt.Printf(" Root hash: %s\n", tree.GetRootHash())
+	fmt.Printf(" Claims saved to %s, proofs to %s and allocations to %s\n", *out, *proofsFile, *dir)
+}
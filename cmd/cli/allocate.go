@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// runAllocate splits a total between the addresses of a weights CSV and
+// writes the claims, adding up to exactly the total
+func runAllocate(args []string) {
+	fs := flag.NewFlagSet("allocate", flag.ExitOnError)
+	weightsFile := fs.String("weights", "weights.csv", "CSV of address,weight rows")
+	total := fs.String("total", "", "amount to split in base units, e.g. 1000000e18")
+	out := fs.String("out", "airdrop_data.csv", "output claims CSV")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	caseName := fs.String("address-case", "checksum", "address case in the output: checksum or lower")
+	fs.Parse(args)
+
+	addressCase, err := data.ParseAddressCase(*caseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *total == "" {
+		log.Fatal("-total is required")
+	}
+	amount, err := parseTokenAmount(*total)
+	if err != nil {
+		log.Fatalf("Invalid -total: %v", err)
+	}
+	checkOutputs(*overwrite, *out)
+
+	weights, err := data.LoadWeightsFromCSV(*weightsFile)
+	if err != nil {
+		log.Fatal("Failed to load weights: ", err)
+	}
+	claims, err := data.AllocateProRata(weights, amount)
+	if err != nil {
+		log.Fatal("Allocation failed: ", err)
+	}
+
+	if err := data.SaveClaimsToCSV(claims, *out, addressCase); err != nil {
+		log.Fatal("Failed to save claims: ", err)
+	}
+	fmt.Printf(" Allocated %s between %d of %d weighted addresses\n", merkle.TotalAmount(claims), len(claims), len(weights))
+	if dropped := len(weights) - len(claims); dropped > 0 {
+		fmt.Printf(" %d addresses have a zero share and get no claim\n", dropped)
+	}
+	fmt.Printf(" Claims saved to %s\n", *out)
+}
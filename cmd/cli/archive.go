@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/archive"
+	"merkle-airdrop/pkg/data"
+)
+
+// runArchive uploads a sharded proofs export to the archive bucket, where
+// the server keeps serving it after the campaign leaves memory
+func runArchive(args []string) {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	dir := fs.String("dir", "", "sharded export directory written by build -shard-bits")
+	campaign := fs.String("campaign", "", "name to archive the campaign under")
+	configFile := fs.String("config", "config.json", "configuration file with the archive bucket")
+	timeout := fs.Duration("timeout", 30*time.Minute, "give up on uploads still running after this long")
+	fs.Parse(args)
+
+	if *dir == "" || *campaign == "" {
+		log.Fatal("-dir and -campaign are required")
+	}
+	if err := archive.ValidateCampaign(*campaign); err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+	if !cfg.Archive.Enabled() {
+		log.Fatalf("No archive endpoint in %s", *configFile)
+	}
+	store, err := archive.NewS3Archive(archive.S3Config{
+		Endpoint: cfg.Archive.Endpoint,
+		Bucket:   cfg.Archive.Bucket,
+		Region:   cfg.Archive.Region,
+		Credentials: archive.Credentials{
+			AccessKeyID:     cfg.Archive.AccessKeyID,
+			SecretAccessKey: cfg.Archive.SecretAccessKey,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Check every shard before anything is uploaded
+	root, proofs, err := data.LoadShardedProofs(*dir)
+	if err != nil {
+		log.Fatal("Failed to load export: ", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if err := store.PutCampaign(ctx, *campaign, *dir); err != nil {
+		log.Fatal("Failed to archive campaign: ", err)
+	}
+	fmt.Printf(" Archived %d proofs under root %s as %s in bucket %s\n", len(proofs.Proofs), root, *campaign, cfg.Archive.Bucket)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"time"
+
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// attestationFile is the output of the attest command
+type attestationFile struct {
+	Payload   json.RawMessage `json:"payload"` // For reading; re-indented here
+	Message   string          `json:"message"` // The exact bytes signed and hashed
+	Digest    string          `json:"digest"`
+	Signer    string          `json:"signer"`
+	Signature string          `json:"signature"`
+	ChainID   string          `json:"chainId,omitempty"`
+	TxHash    string          `json:"txHash,omitempty"`
+}
+
+// runAttest rebuilds the tree from the claims file, checks it against the
+// published proofs, and signs and publishes an attestation tying the root
+// to the file's SHA-256
+func runAttest(args []string) {
+	fs := flag.NewFlagSet("attest", flag.ExitOnError)
+	input := fs.String("input", "airdrop_data.csv", "claims CSV the tree was built from")
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "published proofs whose root and leaf encoding are attested")
+	name := fs.String("name", "", "campaign name to record (default the config's campaign name)")
+	configFile := fs.String("config", "config.json", "configuration file with the signing key and RPC URL")
+	registry := fs.String("registry", "", "attestation registry to call attest(bytes32) on (default a transaction to self)")
+	publish := fs.Bool("publish", true, "send the digest on chain; false only signs")
+	out := fs.String("out", "attestation.json", "file to write the payload, signature and transaction hash to")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	timeout := fs.Duration("timeout", 5*time.Minute, "give up on publishing after this long")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	checkOutputs(*overwrite, *out)
+	if *registry != "" && !common.IsHexAddress(*registry) {
+		log.Fatalf("Invalid -registry %q", *registry)
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+	if *name == "" {
+		*name = cfg.Campaign.Name
+	}
+
+	content, err := os.ReadFile(*input)
+	if err != nil {
+		log.Fatal("Failed to read claims: ", err)
+	}
+	inputHash := sha256.Sum256(content)
+	claims, err := data.LoadAirdropFromCSVWithOptions(*input, layout())
+	if err != nil {
+		log.Fatal("Failed to load data: ", err)
+	}
+	root, proofs, err := data.LoadProofsFile(*proofsFile)
+	if err != nil {
+		log.Fatal("Failed to load proofs: ", err)
+	}
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, proofs.Metadata.Options())
+	if err != nil {
+		log.Fatal("Failed to build tree: ", err)
+	}
+	if tree.GetRootHash() != root {
+		log.Fatalf("%s builds root %s, but %s has root %s", *input, tree.GetRootHash(), *proofsFile, root)
+	}
+
+	att, err := contract.BuildRootAttestation(tree, contract.CampaignMeta{Name: *name, InputSHA256: hex.EncodeToString(inputHash[:])})
+	if err != nil {
+		log.Fatal(err)
+	}
+	signer, err := loadSigner(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := att.Sign(signer); err != nil {
+		log.Fatal(err)
+	}
+	result := attestationFile{
+		Payload:   att.Encoded,
+		Message:   string(att.Encoded),
+		Digest:    att.Digest.Hex(),
+		Signer:    att.Signer.Hex(),
+		Signature: hexutil.Encode(att.Signature),
+	}
+
+	if *publish {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		eth, err := ethclient.DialContext(ctx, cfg.Ethereum.RPCURL)
+		if err != nil {
+			log.Fatal("Failed to connect: ", err)
+		}
+		chainID, err := eth.ChainID(ctx)
+		if err != nil {
+			log.Fatal("Failed to get chain ID: ", err)
+		}
+		client := contract.NewContractClientWithSigner(eth, signer, chainID)
+		client.SetGas(cfg.Ethereum.GasLimit, big.NewInt(cfg.Ethereum.GasPrice))
+		if *registry != "" {
+			client.SetAttestationRegistry(common.HexToAddress(*registry))
+		}
+		tx, err := client.PublishAttestation(ctx, att.Digest)
+		if err != nil {
+			log.Fatal("Failed to publish attestation: ", err)
+		}
+		result.ChainID, result.TxHash = chainID.String(), tx.Hash().Hex()
+	}
+
+	if err := saveToJSON(result, *out); err != nil {
+		log.Fatal("Failed to save attestation: ", err)
+	}
+	fmt.Printf(" Attested root %s of %d claims as %s\n", root, len(tree.Claims), att.Digest.Hex())
+	fmt.Printf("   Signed by %s; written to %s\n", att.Signer.Hex(), *out)
+	if result.TxHash != "" {
+		fmt.Printf("   Published in transaction %s on chain %s\n", result.TxHash, result.ChainID)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/pkg/data"
+)
+
+// runAudit checks a proofs file against the CSV it was built from: every
+// claim must have a proof with the CSV's index and amount that verifies
+// against the file's root
+func runAudit(args []string) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	input := fs.String("input", "airdrop_data.csv", "claims CSV with an index column")
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs file to audit (.json, .bin or .bin.gz)")
+	fs.Parse(args)
+
+	claims, err := data.LoadAirdropFromCSVWithIndices(*input)
+	if err != nil {
+		log.Fatal("Failed to load data: ", err)
+	}
+	rootHash, proofs, err := data.LoadProofsFile(*proofsFile)
+	if err != nil {
+		log.Fatal("Failed to load proofs: ", err)
+	}
+	fmt.Printf(" Auditing %d proofs against %d claims (root %s)\n", len(proofs.Proofs), len(claims), rootHash)
+
+	issues := data.CheckProofIndices(claims, proofs)
+
+	// Proofs that agree with the CSV must also verify; the others are
+	// already reported
+	flagged := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		flagged[issue.Address] = true
+	}
+	opts := proofs.Metadata.Options()
+	invalid := 0
+	for _, claim := range claims {
+		address := claim.Address.Hex()
+		if flagged[address] {
+			continue
+		}
+		if !verifyProof(proofs.Proofs[address], claim, rootHash, opts) {
+			issues = append(issues, data.IndexIssue{Address: address, Problem: "proof does not verify against the root"})
+			invalid++
+		}
+	}
+
+	if len(issues) == 0 {
+		fmt.Printf(" All %d claims have a matching proof that verifies\n", len(claims))
+		return
+	}
+	for _, issue := range issues {
+		fmt.Printf("   - %s\n", issue)
+	}
+	fmt.Printf(" Audit failed: %d issues (%d proofs do not verify)\n", len(issues), invalid)
+	os.Exit(1)
+}
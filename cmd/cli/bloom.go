@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// runBloom writes a Bloom filter of the claims' addresses for client-side
+// eligibility pre-checks
+func runBloom(args []string) {
+	fs := flag.NewFlagSet("bloom", flag.ExitOnError)
+	input := fs.String("input", "airdrop_data.csv", "claims CSV to take the addresses from")
+	out := fs.String("out", "allowlist.bloom", "filter file to write")
+	fpr := fs.Float64("fpr", 0.001, "false positive rate the filter is sized for")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	checkOutputs(*overwrite, *out)
+
+	claims, err := data.LoadAirdropFromCSVWithOptions(*input, layout())
+	if err != nil {
+		log.Fatal("Failed to load data:", err)
+	}
+	filter, err := merkle.BuildBloomFilter(claims, *fpr)
+	if err != nil {
+		log.Fatal("Failed to build filter:", err)
+	}
+	encoded, err := filter.MarshalBinary()
+	if err != nil {
+		log.Fatal("Failed to encode filter:", err)
+	}
+	err = fsutil.AtomicWriteFile(*out, func(w io.Writer) error {
+		_, err := w.Write(encoded)
+		return err
+	})
+	if err != nil {
+		log.Fatal("Failed to save filter:", err)
+	}
+
+	params := filter.Params()
+	fmt.Printf(" Wrote a Bloom filter of %d addresses to %s (%d bytes)\n", params.Elements, *out, len(encoded))
+	fmt.Printf("   Bits: %d, hashes: %d, expected false positive rate: %.6f\n", params.Bits, params.Hashes, params.FalsePositiveRate)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// consistencyFile is what consistency writes and -verify reads, the same
+// fields as the body of GET /api/consistency/{address}
+type consistencyFile struct {
+	Changed     bool                     `json:"changed"`
+	Consistency *merkle.ConsistencyProof `json:"consistency,omitempty"`
+	Difference  *merkle.DifferenceProof  `json:"difference,omitempty"`
+}
+
+// runConsistency proves that an address's claim is the same in the trees
+// of two claims CSVs, or shows how it differs, and checks such proofs with
+// -verify. It exits with 1 when the claim changed or a proof fails.
+func runConsistency(args []string) {
+	fs := flag.NewFlagSet("consistency", flag.ExitOnError)
+	addressFlag := fs.String("address", "", "address whose claims to compare")
+	oldFile := fs.String("old", "", "claims CSV of the earlier tree, with an index column")
+	newFile := fs.String("new", "", "claims CSV of the later tree, with an index column")
+	domain := fs.String("domain", "", "campaign name both trees' leaves were separated with")
+	out := fs.String("out", "", "also write the proof as JSON to this file")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	verifyFile := fs.String("verify", "", "check a proof written by -out or served at /api/consistency instead")
+	fs.Parse(args)
+
+	if *verifyFile != "" {
+		verifyConsistencyFile(*verifyFile)
+		return
+	}
+	if !common.IsHexAddress(*addressFlag) {
+		log.Fatalf("-address must be an address, got %q", *addressFlag)
+	}
+	if *oldFile == "" || *newFile == "" {
+		log.Fatal("-old and -new are required")
+	}
+	if *out != "" {
+		checkOutputs(*overwrite, *out)
+	}
+
+	// Appended claims keep their indices, so both trees keep the CSVs'
+	opts := merkle.DefaultTreeOptions()
+	opts.KeepIndices = true
+	opts.DomainSeparator = domainSeparator(*domain)
+	trees := make([]*merkle.MerkleTree, 2)
+	for i, file := range []string{*oldFile, *newFile} {
+		claims, err := data.LoadAirdropFromCSVWithIndices(file)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", file, err)
+		}
+		if trees[i], err = merkle.NewMerkleTreeWithOptions(claims, opts); err != nil {
+			log.Fatalf("Failed to build the tree of %s: %v", file, err)
+		}
+	}
+
+	var result consistencyFile
+	proof, err := merkle.ProveConsistency(trees[0], trees[1], common.HexToAddress(*addressFlag))
+	var changed *merkle.AllocationChangedError
+	switch {
+	case errors.As(err, &changed):
+		result.Changed, result.Difference = true, changed.Difference
+	case err != nil:
+		log.Fatal(err)
+	default:
+		result.Consistency = proof
+	}
+
+	if *out != "" {
+		if err := saveToJSON(result, *out); err != nil {
+			log.Fatal("Failed to save proof: ", err)
+		}
+		fmt.Printf(" Proof written to %s\n", *out)
+	}
+	fmt.Printf(" Roots: %s -> %s\n", trees[0].GetRootHash(), trees[1].GetRootHash())
+	if result.Changed {
+		fmt.Printf(" Changed: %v\n", changed)
+		os.Exit(1)
+	}
+	fmt.Printf(" Unchanged: %s has %s at index %d in both trees\n", proof.Address, proof.Amount, proof.Index)
+}
+
+// verifyConsistencyFile checks the consistency or difference proof in file
+func verifyConsistencyFile(file string) {
+	encoded, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var proof consistencyFile
+	if err := json.Unmarshal(encoded, &proof); err != nil {
+		log.Fatalf("Failed to read %s: %v", file, err)
+	}
+
+	switch {
+	case proof.Consistency != nil:
+		cp := proof.Consistency
+		valid, err := merkle.VerifyConsistency(cp)
+		if err != nil || !valid {
+			log.Fatalf("Consistency proof does not verify: valid=%v, err=%v", valid, err)
+		}
+		fmt.Printf(" Verified: %s has %s at index %d under both %s and %s\n", cp.Address, cp.Amount, cp.Index, cp.OldRoot, cp.NewRoot)
+	case proof.Difference != nil:
+		valid, err := merkle.VerifyDifference(proof.Difference)
+		if err != nil || !valid {
+			log.Fatalf("Difference proof does not verify: valid=%v, err=%v", valid, err)
+		}
+		fmt.Printf(" Verified: %v\n", &merkle.AllocationChangedError{Difference: proof.Difference})
+		os.Exit(1)
+	default:
+		log.Fatalf("%s holds neither a consistency nor a difference proof", file)
+	}
+}
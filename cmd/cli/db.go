@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	_ "github.com/lib/pq" // Registers the "postgres" driver
+)
+
+// databaseDrivers maps config database types to database/sql driver names
+var databaseDrivers = map[string]string{
+	"postgres": "postgres",
+}
+
+// loadClaimsFromDB runs query against the database described in configFile,
+// reading no more than maxClaims rows unless zero
+func loadClaimsFromDB(configFile, query string, maxClaims int) ([]merkle.AirdropClaim, error) {
+	if query == "" {
+		return nil, fmt.Errorf("-query is required with -source db")
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.ValidateAll(); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", configFile, err)
+	}
+
+	driver, ok := databaseDrivers[cfg.Database.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type %q", cfg.Database.Type)
+	}
+
+	db, err := sql.Open(driver, cfg.GetDatabaseURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	return data.LoadAirdropFromDBWithLimit(ctx, db, query, maxClaims)
+}
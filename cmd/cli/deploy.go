@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// chainEntry is one chain in the -chains file: its connection and gas
+// settings plus the token the distributor pays out there
+type chainEntry struct {
+	contract.ChainConfig
+	TokenAddress string `json:"token_address"`
+}
+
+// runDeploy deploys the distributor for one root to every chain in -chains
+// and records the results
+func runDeploy(args []string) {
+	fs := flag.NewFlagSet("deploy", flag.ExitOnError)
+	chainsFile := fs.String("chains", "chains.json", "JSON list of chains with name, rpc_url, chain_id, token_address and optional gas settings")
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs file (.json or .bin) whose root is deployed")
+	rootHex := fs.String("root", "", "Merkle root to deploy instead of the one in -proofs")
+	configFile := fs.String("config", "config.json", "configuration file with the deployer key")
+	out := fs.String("out", "deployments.json", "output file of per-chain results")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	timeout := fs.Duration("timeout", 10*time.Minute, "give up on deployments still pending after this long")
+	fs.Parse(args)
+
+	checkOutputs(*overwrite, *out)
+
+	root, err := deployRoot(*rootHex, *proofsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chains, tokens, err := loadChains(*chainsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+	signer, err := loadSigner(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := range chains {
+		chains[i].Signer = signer
+	}
+
+	fmt.Printf(" Deploying root 0x%x from %s to %d chains...\n", root, signer.Address().Hex(), len(chains))
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	results, err := contract.DeployToChains(ctx, chains, tokens, root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := contract.SaveDeployments(results, *out); err != nil {
+		log.Fatal("Failed to save deployments: ", err)
+	}
+
+	for _, chain := range chains {
+		result := results[chain.Name]
+		if result.Error != "" {
+			fmt.Printf("   - %s: failed after %d attempts: %s\n", chain.Name, result.Attempts, result.Error)
+			continue
+		}
+		fmt.Printf("   - %s: %s (tx %s, block %d)\n", chain.Name, result.Contract.Hex(), result.TxHash.Hex(), result.BlockNumber)
+	}
+	fmt.Printf(" Results saved to %s\n", *out)
+
+	if failed := contract.FailedChains(results); len(failed) > 0 {
+		log.Fatalf("Deployment failed on %s", strings.Join(failed, ", "))
+	}
+}
+
+// deployRoot returns the root given by -root, or else the one in proofsFile
+func deployRoot(rootHex, proofsFile string) ([32]byte, error) {
+	var root [32]byte
+	if rootHex == "" {
+		var err error
+		rootHex, _, err = data.LoadProofsFile(proofsFile)
+		if err != nil {
+			return root, fmt.Errorf("failed to load proofs: %w", err)
+		}
+	}
+
+	decoded, err := merkle.DecodeHash(rootHex)
+	if err != nil {
+		return root, fmt.Errorf("invalid Merkle root %q", rootHex)
+	}
+	copy(root[:], decoded)
+	return root, nil
+}
+
+// loadChains reads the -chains file
+func loadChains(filename string) ([]contract.ChainConfig, map[string]common.Address, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read chains: %w", err)
+	}
+	var entries []chainEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode chains %s: %w", filename, err)
+	}
+
+	chains := make([]contract.ChainConfig, len(entries))
+	tokens := make(map[string]common.Address, len(entries))
+	for i, entry := range entries {
+		if !common.IsHexAddress(entry.TokenAddress) {
+			return nil, nil, fmt.Errorf("chain %s: invalid token_address %q", entry.Name, entry.TokenAddress)
+		}
+		chains[i] = entry.ChainConfig
+		tokens[entry.Name] = common.HexToAddress(entry.TokenAddress)
+	}
+	return chains, tokens, nil
+}
+
+// loadSigner opens the deployer key configured in cfg: a keystore, a remote
+// signer or a private key
+func loadSigner(cfg *config.Config) (contract.Signer, error) {
+	switch {
+	case cfg.Ethereum.KeystoreFile != "":
+		return contract.NewKeystoreSigner(cfg.Ethereum.KeystoreFile, cfg.GetKeystorePassword())
+	case cfg.Ethereum.SignerURL != "":
+		if !common.IsHexAddress(cfg.Ethereum.SignerAddress) {
+			return nil, fmt.Errorf("invalid signer_address %q", cfg.Ethereum.SignerAddress)
+		}
+		return contract.NewRemoteSigner(cfg.Ethereum.SignerURL, common.HexToAddress(cfg.Ethereum.SignerAddress)), nil
+	case cfg.Ethereum.PrivateKey != "":
+		return contract.NewKeySignerFromHex(strings.TrimPrefix(cfg.Ethereum.PrivateKey, "0x"))
+	default:
+		return nil, fmt.Errorf("no deployer key: set private_key, keystore_file or signer_url in the config")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// runExport writes the proofs of a list of addresses, such as a partner's,
+// without the rest of the airdrop. With -redact-amounts it writes leaf
+// hashes in place of amounts, for every address unless -addresses is set.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	addressesFile := fs.String("addresses", "", "file with one address per line to export proofs for (default all with -redact-amounts)")
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs file or sharded export directory to read")
+	out := fs.String("out", "partner_proofs.json", "JSON proofs file to write")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	proofFieldsSpec := fs.String("proof-fields", "", "rename or leave out JSON proof fields for the partner's frontend, e.g. proof=merkleProof,amount=value,-index")
+	redact := fs.Bool("redact-amounts", false, "write each claim's leaf hash instead of its amount, checking every proof against the root")
+	fs.Parse(args)
+
+	if *addressesFile == "" && !*redact {
+		log.Fatal("-addresses is required")
+	}
+	if *redact && *proofFieldsSpec != "" {
+		log.Fatal("-proof-fields is not supported with -redact-amounts")
+	}
+	proofFields, err := data.ParseProofMarshaller(*proofFieldsSpec)
+	if err != nil {
+		log.Fatalf("Invalid -proof-fields: %v", err)
+	}
+	checkOutputs(*overwrite, *out)
+
+	var addresses []common.Address
+	if *addressesFile != "" {
+		if addresses, err = data.LoadAddressesFromFile(*addressesFile); err != nil {
+			log.Fatalf("Failed to load addresses: %v", err)
+		}
+	}
+	root, proofs, err := data.LoadProofsFile(*proofsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *redact {
+		exportRedacted(proofs, addresses, root, *out)
+		return
+	}
+
+	err = fsutil.AtomicWriteFile(*out, func(w io.Writer) error {
+		return data.ExportProofsSubset(proofs, addresses, root, w, proofFields)
+	})
+	if err != nil {
+		log.Fatal("Failed to save proofs:", err)
+	}
+
+	missing := data.MissingAddresses(proofs, addresses)
+	for _, address := range missing {
+		fmt.Fprintf(os.Stderr, " Not in the airdrop: %s\n", address.Hex())
+	}
+	fmt.Printf(" Wrote %d of %d requested proofs for root %s to %s\n", len(addresses)-len(missing), len(addresses), root, *out)
+}
+
+// exportRedacted writes the proofs of addresses, or all of them when nil,
+// without amounts
+func exportRedacted(proofs *merkle.ProofSet, addresses []common.Address, root, out string) {
+	err := fsutil.AtomicWriteFile(out, func(w io.Writer) error {
+		return data.ExportProofsRedacted(proofs, addresses, root, w)
+	})
+	if err != nil {
+		log.Fatal("Failed to save proofs: ", err)
+	}
+
+	written := proofs.Len()
+	if addresses != nil {
+		missing := data.MissingAddresses(proofs, addresses)
+		for _, address := range missing {
+			fmt.Fprintf(os.Stderr, " Not in the airdrop: %s\n", address.Hex())
+		}
+		written = len(addresses) - len(missing)
+	}
+	fmt.Printf(" Wrote %d proofs without amounts for root %s to %s, each verified from its leaf\n", written, root, out)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// buildGeneric runs the build pipeline for claims keyed by arbitrary bytes.
+// Proofs are keyed the way the CSV writes the keys.
+func buildGeneric(dataFile, outputFile string, keyFormat data.KeyFormat, opts merkle.TreeOptions) {
+	fmt.Printf(" Loading %s-keyed claims from %s...\n", keyFormat, dataFile)
+	claims, err := data.LoadGenericClaimsFromCSV(dataFile, keyFormat)
+	if err != nil {
+		log.Fatal("Failed to load data:", err)
+	}
+	fmt.Printf(" Loaded %d claims\n", len(claims))
+
+	fmt.Printf(" Building Merkle tree...\n")
+	start := time.Now()
+	tree, err := merkle.NewGenericMerkleTree(claims, opts)
+	if err != nil {
+		log.Fatal("Failed to build tree:", err)
+	}
+	buildTime := time.Since(start)
+	fmt.Printf(" Tree built in %v\n", buildTime)
+	fmt.Printf(" Root hash: %s\n", tree.GetRootHash())
+
+	start = time.Now()
+	proofs := make(map[string]*merkle.MerkleProof, len(tree.Claims))
+	for _, claim := range tree.Claims {
+		proof, err := tree.GenerateProof(claim.Key)
+		if err != nil {
+			log.Fatal("Failed to generate proofs:", err)
+		}
+		proofs[keyFormat.Encode(claim.Key)] = proof
+	}
+	proofTime := time.Since(start)
+	fmt.Printf(" Generated %d proofs in %v\n", len(proofs), proofTime)
+
+	result := map[string]interface{}{
+		"merkleRoot":  tree.GetRootHash(),
+		"metadata":    opts.Metadata(),
+		"keyFormat":   keyFormat.String(),
+		"proofs":      proofs,
+		"totalClaims": len(tree.Claims),
+		"generatedAt": time.Now().Unix(),
+		"buildTime":   buildTime.String(),
+		"proofTime":   proofTime.String(),
+	}
+	if err := saveToJSON(result, outputFile); err != nil {
+		log.Fatal("Failed to save results:", err)
+	}
+	fmt.Printf(" Results saved to %s\n", outputFile)
+
+	claim := tree.Claims[0]
+	proof := proofs[keyFormat.Encode(claim.Key)]
+	valid, err := merkle.VerifyGenericProof(tree.Root(), claim, proof.Proof, proof.Positions, opts)
+	if err != nil || !valid {
+		log.Fatalf("Proof for %s failed verification: %v", keyFormat.Encode(claim.Key), err)
+	}
+	fmt.Printf(" Proof for %s verified\n", keyFormat.Encode(claim.Key))
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxDOTLeaves is the largest tree inspect -dot draws in full; larger trees
+// get only the path to the inspected address
+const maxDOTLeaves = 64
+
+// runInspect rebuilds the tree from a CSV and prints the path from one
+// address's leaf to the root, to see where a failing proof diverges
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	addressFlag := fs.String("address", "", "address whose path to print")
+	input := fs.String("in", "airdrop_data.csv", "claims CSV the tree was built from")
+	order := fs.String("order", "address", "leaf order: address, index or input")
+	keepIndices := fs.Bool("keep-indices", false, "keep the CSV's index column instead of numbering claims by leaf position")
+	pairs := fs.String("pairs", "sorted", "how node pairs are hashed: sorted or positional")
+	oddLeaf := fs.String("odd-leaf", "duplicate", "last node of an odd level: duplicate, promote or zero")
+	domain := fs.String("domain", "", "campaign name the tree's leaves were separated with")
+	unitName := fs.String("unit", "token", "unit the claim amount is also shown in: wei, gwei, ether or token")
+	decimals := fs.Int("decimals", data.DefaultTokenDecimals, "token decimals, with -unit token")
+	dotFile := fs.String("dot", "", "also write the tree as Graphviz DOT to this file (just the address's path above 64 leaves)")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*addressFlag) {
+		log.Fatalf("-address must be an address, got %q", *addressFlag)
+	}
+	address := common.HexToAddress(*addressFlag)
+	if *pairs != "sorted" && *pairs != "positional" {
+		log.Fatalf("Unknown pair hashing %q (expected sorted or positional)", *pairs)
+	}
+	unit, err := data.ParseAmountUnit(*unitName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *decimals < 0 || *decimals > data.MaxTokenDecimals {
+		log.Fatalf("-decimals must be between 0 and %d", data.MaxTokenDecimals)
+	}
+
+	opts := merkle.DefaultTreeOptions()
+	if opts.SortOrder, err = merkle.ParseSortOrder(*order); err != nil {
+		log.Fatal(err)
+	}
+	if opts.OddLeafPolicy, err = merkle.ParseOddLeafPolicy(*oddLeaf); err != nil {
+		log.Fatal(err)
+	}
+	opts.KeepIndices = *keepIndices
+	opts.SortedPairs = *pairs == "sorted"
+	opts.DomainSeparator = domainSeparator(*domain)
+
+	var claims []merkle.AirdropClaim
+	if *keepIndices {
+		if layout() != data.DefaultLoadOptions() {
+			log.Fatal("-keep-indices reads an address,amount,index CSV; it takes no -delimiter, -address-col, -amount-col or -no-header")
+		}
+		claims, err = data.LoadAirdropFromCSVWithIndices(*input)
+	} else {
+		claims, err = data.LoadAirdropFromCSVWithOptions(*input, layout())
+	}
+	if err != nil {
+		log.Fatal("Failed to load data: ", err)
+	}
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+	if err != nil {
+		log.Fatal("Failed to build tree: ", err)
+	}
+
+	steps, err := tree.PathForAddress(address)
+	if err != nil {
+		log.Fatalf("%s: %v", address.Hex(), err)
+	}
+	claim, _ := tree.FindClaim(address)
+	fmt.Printf(" Root: %s (%d leaves, %d levels)\n", tree.GetRootHash(), len(tree.Leaves), tree.Levels())
+	fmt.Printf(" Claim: %s, amount %s (%s %s), index %d\n", address.Hex(), claim.Amount, unit.Format(claim.Amount, *decimals), unit, claim.Index)
+	fmt.Printf(" %-5s %-8s %-66s %-66s %s\n", "Level", "Position", "Hash", "Sibling", "Parent")
+	for _, step := range steps {
+		sibling := "(promoted)"
+		if step.Sibling != nil {
+			sibling = merkle.EncodeHash(step.Sibling)
+		}
+		fmt.Printf(" %-5d %-8d %s %-66s %s\n", step.Level, step.Position, merkle.EncodeHash(step.Hash), sibling, merkle.EncodeHash(step.Parent))
+	}
+
+	if *dotFile != "" {
+		err := fsutil.AtomicWriteFile(*dotFile, func(w io.Writer) error {
+			if len(tree.Leaves) <= maxDOTLeaves {
+				return tree.ExportDOT(w, maxDOTLeaves)
+			}
+			return tree.ExportAddressDOT(w, address)
+		})
+		if err != nil {
+			log.Fatal("Failed to write DOT graph: ", err)
+		}
+		fmt.Printf(" Graph written to %s\n", *dotFile)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"merkle-airdrop/pkg/data"
+)
+
+// csvLayoutFlags adds the flags describing the claims CSV's layout to fs,
+// returning a function that reads them once fs is parsed
+func csvLayoutFlags(fs *flag.FlagSet) func() data.LoadOptions {
+	delimiter := fs.String("delimiter", "", "field delimiter of the claims CSV, one character or tab (default detected from the first line)")
+	addressCol := fs.String("address-col", "", "claims CSV column of the addresses, by header name or zero-based position (default address, else the first)")
+	amountCol := fs.String("amount-col", "", "claims CSV column of the amounts, by header name or zero-based position (default amount, else the second)")
+	noHeader := fs.Bool("no-header", false, "the claims CSV has no header line")
+	return func() data.LoadOptions {
+		comma, err := data.ParseDelimiter(*delimiter)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return data.LoadOptions{Delimiter: comma, AddressColumn: *addressCol, AmountColumn: *amountCol, HasHeader: !*noHeader}
+	}
+}
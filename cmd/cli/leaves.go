@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// leafProofsFile is where build -leaves writes the proofs
+const leafProofsFile = "leaf_proofs.json"
+
+// leafProof is a proof of a prehashed leaf, addressed by its position
+type leafProof struct {
+	Index     uint32   `json:"index"`
+	Proof     []string `json:"proof"`
+	Positions uint64   `json:"positions,omitempty"`
+
+	PaddingCount int `json:"paddingCount,omitempty"`
+}
+
+// buildFromLeaves builds the tree over a leaf hash file from external
+// tooling and writes every leaf's proof in file order
+func buildFromLeaves(leavesFile string, format merkle.LeafFileFormat, opts merkle.TreeOptions) {
+	fmt.Printf(" Building Merkle tree from %s leaf hashes in %s...\n", format, leavesFile)
+	start := time.Now()
+	tree, err := merkle.BuildFromLeafHashFileWithOptions(leavesFile, format, opts)
+	if err != nil {
+		log.Fatal("Failed to build tree: ", err)
+	}
+	buildTime := time.Since(start)
+	fmt.Printf(" Tree of %d leaves built in %v\n", len(tree.Leaves), buildTime)
+	fmt.Printf(" Root hash: %s\n", tree.GetRootHash())
+
+	start = time.Now()
+	proofs := make([]leafProof, len(tree.Leaves))
+	for i := range proofs {
+		proof, err := tree.ProofAt(i)
+		if err != nil {
+			log.Fatal("Failed to generate proofs: ", err)
+		}
+		proofs[i] = leafProof{Index: proof.Index, Proof: proof.Proof, Positions: proof.Positions, PaddingCount: proof.PaddingCount}
+	}
+	proofTime := time.Since(start)
+	fmt.Printf(" Generated %d proofs in %v\n", len(proofs), proofTime)
+
+	result := map[string]interface{}{
+		"merkleRoot":  tree.GetRootHash(),
+		"metadata":    opts.Metadata(),
+		"proofs":      proofs,
+		"totalLeaves": len(proofs),
+		"generatedAt": time.Now().Unix(),
+		"buildTime":   buildTime.String(),
+		"proofTime":   proofTime.String(),
+	}
+	if err := saveToJSON(result, leafProofsFile); err != nil {
+		log.Fatal("Failed to save results:", err)
+	}
+	fmt.Printf(" Results saved to %s\n", leafProofsFile)
+
+	last := len(proofs) - 1
+	valid, err := merkle.VerifyLeafProof(tree.Root.Hash, tree.Leaves[last].Hash, proofs[last].Proof, proofs[last].Positions, opts)
+	if err != nil || !valid {
+		log.Fatalf("Proof of leaf %d failed verification: %v", last, err)
+	}
+	fmt.Printf(" Proof of leaf %d verified\n", last)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// runLinks writes a pre-filled claim link for every claim, e.g. for
+// printing as QR codes
+func runLinks(args []string) {
+	fs := flag.NewFlagSet("links", flag.ExitOnError)
+	input := fs.String("input", "airdrop_data.csv", "claims CSV to build the tree from")
+	out := fs.String("out", "links.csv", "output CSV of address, amount, index and link")
+	baseURL := fs.String("base", "", "claim site URL the links open")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	caseName := fs.String("address-case", "checksum", "address case in the output: checksum or lower")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	addressCase, err := data.ParseAddressCase(*caseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *baseURL == "" {
+		log.Fatal("-base is required")
+	}
+	checkOutputs(*overwrite, *out)
+
+	claims, err := data.LoadAirdropFromCSVWithOptions(*input, layout())
+	if err != nil {
+		log.Fatal("Failed to load data:", err)
+	}
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+	if err != nil {
+		log.Fatal("Failed to build tree:", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		log.Fatal("Failed to generate proofs:", err)
+	}
+
+	count, err := saveLinks(tree, proofs, *baseURL, *out, addressCase)
+	if err != nil {
+		log.Fatal("Failed to save links:", err)
+	}
+	fmt.Printf(" Wrote %d claim links for root %s to %s\n", count, tree.GetRootHash(), *out)
+}
+
+// saveLinks writes one claim link per tree claim to filename
+func saveLinks(tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof, baseURL, filename string, addressCase data.AddressCase) (int, error) {
+	count := 0
+	err := fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"address", "amount", "index", "link"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+
+		for _, claim := range tree.Claims {
+			proof, exists := proofs[claim.Address.Hex()]
+			if !exists || proof.Index != claim.Index {
+				continue // Repeated address; only its first claim has a proof
+			}
+
+			link, err := merkle.EncodeClaimLinkWithOptions(baseURL, claim, proof, tree.Options())
+			if err != nil {
+				return fmt.Errorf("failed to encode link for %s: %w", claim.Address.Hex(), err)
+			}
+			record := []string{
+				addressCase.Format(claim.Address),
+				claim.Amount.String(),
+				strconv.FormatUint(uint64(claim.Index), 10),
+				link,
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
+			count++
+		}
+
+		writer.Flush()
+		return writer.Error()
+	})
+	return count, err
+}
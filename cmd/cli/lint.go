@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+
+	"merkle-airdrop/pkg/data"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// runLint reports claims that look like data bugs before a tree is built
+// from them, exiting 1 when there are errors, or warnings with -strict
+func runLint(args []string) {
+	defaults := data.DefaultSanityOptions()
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	input := fs.String("in", "airdrop_data.csv", "claims CSV to check")
+	maxShared := fs.Int("max-shared", defaults.MaxSharedAmount, "claims that may share one exact amount (0 to not check)")
+	dust := fs.String("dust", "", "report amounts below this, in base units")
+	maxDigits := fs.Int("max-digits", defaults.MaxSignificantDigits, "significant digits allowed in an amount (0 to not check)")
+	rpcURL := fs.String("rpc", "", "RPC URL to look up recipients' code (default only known contracts are flagged)")
+	strict := fs.Bool("strict", false, "exit non-zero on warnings too")
+	jsonOutput := fs.Bool("json", false, "print the report as JSON")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	opts := data.SanityOptions{MaxSharedAmount: *maxShared, MaxSignificantDigits: *maxDigits}
+	if *dust != "" {
+		threshold, ok := new(big.Int).SetString(*dust, 10)
+		if !ok || threshold.Sign() < 0 {
+			log.Fatalf("Invalid -dust %q: expected a base-10 amount", *dust)
+		}
+		opts.DustThreshold = threshold
+	}
+	if *rpcURL != "" {
+		client, err := ethclient.Dial(*rpcURL)
+		if err != nil {
+			log.Fatal("Failed to connect: ", err)
+		}
+		defer client.Close()
+		opts.Code = client
+	}
+
+	claims, err := data.LoadAirdropFromCSVWithOptions(*input, layout())
+	if err != nil {
+		log.Fatal("Failed to load data: ", err)
+	}
+	report := data.SanityReport(claims, opts)
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		for _, finding := range report.Findings {
+			fmt.Printf("   - %s\n", finding)
+		}
+		fmt.Printf(" Checked %d claims: %d errors, %d warnings\n", report.Claims, report.Errors, report.Warnings)
+	}
+
+	if report.Errors > 0 || (*strict && report.Warnings > 0) {
+		os.Exit(1)
+	}
+}
@@ -2,65 +2,407 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/csv"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/internal/fsutil"
 	"merkle-airdrop/pkg/data"
 	"merkle-airdrop/pkg/merkle"
 )
 
 func main() {
+	// The first argument selects a subcommand; with none we run the build pipeline
+	args := os.Args[1:]
+	command := "build"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "aggregate":
+		runAggregate(args)
+	case "allocate":
+		runAllocate(args)
+	case "archive":
+		runArchive(args)
+	case "attest":
+		runAttest(args)
+	case "audit":
+		runAudit(args)
+	case "bloom":
+		runBloom(args)
+	case "build":
+		runBuild(args)
+	case "consistency":
+		runConsistency(args)
+	case "demo":
+		runDemo(args)
+	case "deploy":
+		runDeploy(args)
+	case "export":
+		runExport(args)
+	case "inspect":
+		runInspect(args)
+	case "links":
+		runLinks(args)
+	case "lint":
+		runLint(args)
+	case "manifest":
+		runManifest(args)
+	case "merge":
+		runMerge(args)
+	case "schema":
+		runSchema(args)
+	case "simulate":
+		runSimulate(args)
+	case "snapshot":
+		runSnapshot(args)
+	case "stats":
+		runStats(args)
+	case "vectors":
+		runVectors(args)
+	case "verify":
+		runVerify(args)
+	case "verify-file":
+		runVerifyFile(args)
+	default:
+		log.Fatalf("Unknown command %q (available: aggregate, allocate, archive, attest, audit, bloom, build, consistency, demo, deploy, export, inspect, links, lint, manifest, merge, schema, simulate, snapshot, stats, vectors, verify, verify-file)", command)
+	}
+}
+
+// runBuild loads claims, builds the tree, generates and saves all proofs
+func runBuild(args []string) {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	format := fs.String("format", "json", "proofs output format: json or bin")
+	compress := fs.Bool("gzip", false, "gzip-compress binary output")
+	canonical := fs.Bool("canonical", false, "write JSON proofs that are byte-identical for identical claims, with timings in "+data.CanonicalMetadataFile)
+	order := fs.String("order", "address", "leaf order: address, index or input")
+	keepIndices := fs.Bool("keep-indices", false, "keep the CSV's index column instead of numbering claims by leaf position")
+	indexFile := fs.String("index-file", "", "JSON file of stable claim indices by address, created if missing: known addresses keep theirs and new ones take the next free index")
+	pairs := fs.String("pairs", "sorted", "how node pairs are hashed: sorted, or positional for contracts that take sibling positions")
+	oddLeaf := fs.String("odd-leaf", "duplicate", "last node of an odd level: duplicate, promote or zero")
+	onDuplicate := fs.String("on-duplicate", "error", "repeated addresses: error, keep-first or sum")
+	source := fs.String("source", "csv", "claims source: csv or db")
+	query := fs.String("query", "", "SQL query returning (address, amount) rows, with -source db")
+	configFile := fs.String("config", "config.json", "configuration file with the database settings and worker_count")
+	workers := fs.Int("workers", 0, "goroutines building the tree and generating proofs, 0 for one per CPU (default worker_count from -config)")
+	maxClaims := fs.Int("max-claims", 0, "fail once the claims exceed this many, without reading the rest, 0 for unlimited (default max_claims from -config)")
+	shardBits := fs.Int("shard-bits", 0, "split JSON proofs into 2^N files by address prefix (multiple of 4)")
+	treeKind := fs.String("tree", "standard", "tree to build: standard, or sparse to also build a sparse tree for non-membership proofs")
+	maxTotal := fs.String("max-total", "", "fail when the claims add up to more than this many base units")
+	maxPerClaim := fs.String("max-per-claim", "", "fail when a claim is for more than this many base units")
+	amountBits := fs.Int("amount-bits", merkle.DefaultMaxAmountBits, "fail when an amount doesn't fit the distributor's uintN amount type, e.g. 96")
+	fixedDepth := fs.Int("fixed-depth", 0, "pad every proof to this many elements for fixed-depth verifiers, failing if the tree is deeper (0 to disable)")
+	overwrite := fs.Bool("overwrite", false, "replace existing output files")
+	caseName := fs.String("address-case", "checksum", "address case in the generated CSV and JSON proofs: checksum or lower")
+	sparseDepth := fs.Int("sparse-depth", merkle.DefaultSparseDepth, "sparse tree depth in bits of keccak256(address), with -tree sparse")
+	campaignFile := fs.String("campaign", "", "campaign.json to record in the JSON proofs' metadata (the sidecar with -canonical)")
+	deltaFrom := fs.String("delta-from", "", "previous proofs file or shard directory to also write the changes from, to proofs_delta.json")
+	keyFormatName := fs.String("key-format", "", "build from claims keyed by arbitrary bytes instead of addresses, with keys written as hex, base58 or raw")
+	leavesFile := fs.String("leaves", "", "build the tree from a file of precomputed leaf hashes, in file order, writing proofs by leaf position to "+leafProofsFile)
+	leavesFormatName := fs.String("leaves-format", "hex", "leaf hash file format with -leaves: hex, one hash per line, or binary, 32-byte hashes back to back")
+	domain := fs.String("domain", "", "campaign name whose keccak256 is prepended to every leaf, so proofs don't verify against other campaigns' roots")
+	proofFieldsSpec := fs.String("proof-fields", "", "rename or leave out JSON proof fields for a frontend, e.g. proof=merkleProof,amount=value,-index")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	duplicatePolicy, err := data.ParseDuplicatePolicy(*onDuplicate)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *source != "csv" && *source != "db" {
+		log.Fatalf("Unknown claims source %q (expected csv or db)", *source)
+	}
+	if *keepIndices && *source != "csv" {
+		log.Fatal("-keep-indices requires -source csv")
+	}
+	if *keepIndices && *indexFile != "" {
+		log.Fatal("-keep-indices and -index-file both set the indices; use one")
+	}
+	if *keepIndices && layout() != data.DefaultLoadOptions() {
+		log.Fatal("-keep-indices reads an address,amount,index CSV; it takes no -delimiter, -address-col, -amount-col or -no-header")
+	}
+
+	if *pairs != "sorted" && *pairs != "positional" {
+		log.Fatalf("Unknown pair hashing %q (expected sorted or positional)", *pairs)
+	}
+	addressCase, err := data.ParseAddressCase(*caseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	proofFields, err := data.ParseProofMarshaller(*proofFieldsSpec)
+	if err != nil {
+		log.Fatalf("Invalid -proof-fields: %v", err)
+	}
+	sortOrder, err := merkle.ParseSortOrder(*order)
+	if err != nil {
+		log.Fatal(err)
+	}
+	oddLeafPolicy, err := merkle.ParseOddLeafPolicy(*oddLeaf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *treeKind != "standard" && *treeKind != "sparse" {
+		log.Fatalf("Unknown tree %q (expected standard or sparse)", *treeKind)
+	}
+	workerCount, err := buildWorkers(fs, *workers, *configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	claimLimit, err := buildMaxClaims(fs, *maxClaims, *configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *sparseDepth < 1 || *sparseDepth > merkle.MaxSparseDepth {
+		log.Fatalf("-sparse-depth must be between 1 and %d", merkle.MaxSparseDepth)
+	}
+	totalCap, err := parseOptionalAmount(*maxTotal)
+	if err != nil {
+		log.Fatalf("Invalid -max-total: %v", err)
+	}
+	claimCap, err := parseOptionalAmount(*maxPerClaim)
+	if err != nil {
+		log.Fatalf("Invalid -max-per-claim: %v", err)
+	}
+	if err := merkle.CheckAmountBits(nil, *amountBits); err != nil {
+		log.Fatalf("Invalid -amount-bits: %v", err)
+	}
+	if *fixedDepth < 0 || *fixedDepth > merkle.MaxFixedDepth {
+		log.Fatalf("-fixed-depth must be between 0 and %d", merkle.MaxFixedDepth)
+	}
+
 	fmt.Println(" Merkle Tree Airdrop System")
 	fmt.Println("============================")
 
 	// Configuration
 	const (
-		dataFile   = "airdrop_data.csv"
-		outputFile = "merkle_proofs.json"
-		numClaims  = 10000 // For testing
+		dataFile  = "airdrop_data.csv"
+		rootsFile = "roots.json"
+		deltaFile = "proofs_delta.json"
+		numClaims = 10000 // For testing
 	)
 
+	var outputFile string
+	switch *format {
+	case "json":
+		outputFile = "merkle_proofs.json"
+	case "bin":
+		outputFile = "merkle_proofs.bin"
+	default:
+		log.Fatalf("Unknown output format %q (expected json or bin)", *format)
+	}
+	if *shardBits != 0 {
+		if *format != "json" {
+			log.Fatal("-shard-bits requires -format json")
+		}
+		if err := data.ValidateShardBits(*shardBits); err != nil {
+			log.Fatal(err)
+		}
+		outputFile = "proofs"
+	}
+	if !proofFields.IsDefault() && *format != "json" {
+		log.Fatal("-proof-fields requires -format json")
+	}
+	if *canonical && (*format != "json" || *shardBits != 0) {
+		log.Fatal("-canonical requires -format json without -shard-bits")
+	}
+	var campaign *api.CampaignMeta
+	if *campaignFile != "" {
+		if *format != "json" || *shardBits != 0 {
+			log.Fatal("-campaign requires -format json without -shard-bits")
+		}
+		meta, err := api.LoadCampaignFile(*campaignFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		campaign = &meta
+	}
+	if *leavesFile != "" {
+		leavesFormat, err := merkle.ParseLeafFileFormat(*leavesFormatName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *source != "csv" || *format != "json" || *shardBits != 0 || *canonical || *treeKind != "standard" || *keepIndices || *indexFile != "" || campaign != nil || *deltaFrom != "" || *keyFormatName != "" || totalCap != nil || claimCap != nil || *domain != "" || !proofFields.IsDefault() {
+			log.Fatal("-leaves requires -format json without -source db, -shard-bits, -canonical, -keep-indices, -index-file, -campaign, -delta-from, -key-format, -max-total, -max-per-claim, -domain, -proof-fields or -tree sparse")
+		}
+		checkOutputs(*overwrite, leafProofsFile)
+		opts := merkle.DefaultTreeOptions()
+		opts.SortOrder = merkle.PreserveInput
+		opts.SortedPairs = *pairs == "sorted"
+		opts.OddLeafPolicy = oddLeafPolicy
+		opts.FixedDepth = *fixedDepth
+		opts.Workers = workerCount
+		buildFromLeaves(*leavesFile, leavesFormat, opts)
+		return
+	}
+
+	outputs := []string{outputFile}
+	if *treeKind == "sparse" {
+		outputs = append(outputs, rootsFile)
+	}
+	if *canonical {
+		outputs = append(outputs, data.CanonicalMetadataFile)
+	}
+	if *deltaFrom != "" {
+		outputs = append(outputs, deltaFile)
+	}
+	checkOutputs(*overwrite, outputs...)
+
+	// Read the previous proofs before this build can replace them
+	var oldRoot string
+	var oldProofs *merkle.ProofSet
+	if *deltaFrom != "" {
+		if oldRoot, oldProofs, err = data.LoadProofsFile(*deltaFrom); err != nil {
+			log.Fatal("Failed to load previous proofs: ", err)
+		}
+	}
+
+	if *keyFormatName != "" {
+		keyFormat, err := data.ParseKeyFormat(*keyFormatName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *source != "csv" || *format != "json" || *shardBits != 0 || *canonical || *treeKind != "standard" || *keepIndices || *indexFile != "" || campaign != nil || *deltaFrom != "" || !proofFields.IsDefault() {
+			log.Fatal("-key-format requires -source csv and -format json without -shard-bits, -canonical, -keep-indices, -index-file, -campaign, -delta-from, -proof-fields or -tree sparse")
+		}
+		opts := merkle.DefaultTreeOptions()
+		opts.SortOrder = sortOrder
+		opts.SortedPairs = *pairs == "sorted"
+		opts.OddLeafPolicy = oddLeafPolicy
+		opts.MaxAmountBits = *amountBits
+		opts.FixedDepth = *fixedDepth
+		opts.DomainSeparator = domainSeparator(*domain)
+		opts.Workers = workerCount
+		buildGeneric(dataFile, outputFile, keyFormat, opts)
+		return
+	}
+
 	// Step 1: Load or generate airdrop data
 	fmt.Printf(" Loading airdrop data...\n")
 	var claims []merkle.AirdropClaim
-	var err error
 
-	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
+	if *source == "db" {
+		claims, err = loadClaimsFromDB(*configFile, *query, claimLimit.limit)
+		if err != nil {
+			log.Fatal("Failed to load data:", claimLimit.explain(err))
+		}
+	} else if _, err := os.Stat(dataFile); os.IsNotExist(err) {
 		fmt.Printf(" Generating %d test claims...\n", numClaims)
 		claims = data.GenerateTestData(numClaims)
 
 		// Save test data to CSV
-		if err := saveToCSV(claims, dataFile); err != nil {
+		if err := saveToCSV(claims, dataFile, addressCase); err != nil {
 			log.Fatal("Failed to save test data:", err)
 		}
+	} else if *keepIndices {
+		claims, err = data.LoadAirdropFromCSVWithIndicesLimit(dataFile, claimLimit.limit)
+		if err != nil {
+			log.Fatal("Failed to load data:", claimLimit.explain(err))
+		}
 	} else {
-		claims, err = data.LoadAirdropFromCSV(dataFile)
+		loadOpts := layout()
+		loadOpts.MaxClaims = claimLimit.limit
+		claims, err = data.LoadAirdropFromCSVWithOptions(dataFile, loadOpts)
 		if err != nil {
-			log.Fatal("Failed to load data:", err)
+			log.Fatal("Failed to load data:", claimLimit.explain(err))
 		}
 	}
 
 	fmt.Printf(" Loaded %d claims\n", len(claims))
 
+	rows, totalBefore := len(claims), merkle.TotalAmount(claims)
+	claims, err = data.ApplyDuplicatePolicy(claims, duplicatePolicy)
+	if err != nil {
+		log.Fatal("Invalid claims data:", err)
+	}
+	if merged := rows - len(claims); merged > 0 {
+		fmt.Printf(" Merged %d duplicate rows (%s): %d claims remain\n", merged, duplicatePolicy, len(claims))
+		fmt.Printf(" Total amount: %s before, %s after\n", totalBefore, merkle.TotalAmount(claims))
+	}
+
+	// Catch a tree the contract can't be funded for before anything is exported
+	if err := merkle.CheckAmountLimits(claims, totalCap, claimCap); err != nil {
+		log.Fatal("Funding check failed: ", err)
+	}
+	if totalCap != nil {
+		fmt.Printf(" Total amount %s is within the cap of %s\n", merkle.TotalAmount(claims), totalCap)
+	}
+
 	// Step 2: Building Merkle tree
 	fmt.Printf(" Building Merkle tree...\n")
 	start := time.Now()
 
-	tree, err := merkle.NewMerkleTree(claims)
+	opts := merkle.DefaultTreeOptions()
+	opts.CopyClaims = false
+	opts.SortOrder = sortOrder
+	opts.KeepIndices = *keepIndices
+	opts.SortedPairs = *pairs == "sorted"
+	opts.OddLeafPolicy = oddLeafPolicy
+	opts.MaxAmountBits = *amountBits
+	opts.FixedDepth = *fixedDepth
+	opts.DomainSeparator = domainSeparator(*domain)
+	opts.Workers = workerCount
+	opts.MaxClaims = claimLimit.limit
+	var known int // Addresses with an index before this build
+	if *indexFile != "" {
+		if opts.Indices, err = data.LoadIndexFile(*indexFile); err != nil {
+			log.Fatal(err)
+		}
+		known = opts.Indices.Len()
+	}
+
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
 	if err != nil {
-		log.Fatal("Failed to build tree:", err)
+		log.Fatal("Failed to build tree:", claimLimit.explain(err))
+	}
+	if opts.Indices != nil {
+		fmt.Printf(" Took indices from %s: %d new addresses, next index %d\n", *indexFile, opts.Indices.Len()-known, opts.Indices.Next())
+	}
+	if *domain != "" {
+		fmt.Printf(" Domain separator: %s (keccak256 of %q)\n", opts.Metadata().DomainSeparator, *domain)
 	}
 
 	buildTime := time.Since(start)
 	fmt.Printf(" Tree built in %v\n", buildTime)
 	fmt.Printf(" Root hash: %s\n", tree.GetRootHash())
 
+	var sparse *merkle.SparseMerkleTree
+	if *treeKind == "sparse" {
+		fmt.Printf(" Building %d-level sparse Merkle tree...\n", *sparseDepth)
+		start = time.Now()
+
+		sparse, err = merkle.NewSparseMerkleTreeFromClaims(claims, *sparseDepth)
+		if err != nil {
+			log.Fatal("Failed to build sparse tree:", err)
+		}
+		fmt.Printf(" Sparse tree built in %v\n", time.Since(start))
+		fmt.Printf(" Sparse root hash: %s\n", sparse.GetRootHash())
+
+		roots := map[string]interface{}{
+			"merkleRoot":  tree.GetRootHash(),
+			"sparseRoot":  sparse.GetRootHash(),
+			"sparseDepth": sparse.Depth(),
+			"totalClaims": len(claims),
+		}
+		if err := saveToJSON(roots, rootsFile); err != nil {
+			log.Fatal("Failed to save roots:", err)
+		}
+		fmt.Printf(" Roots saved to %s\n", rootsFile)
+	}
+
 	// Step 3: Generate all proofs in parallel
 	fmt.Printf(" Generating proofs with goroutines...\n")
 	start = time.Now()
@@ -77,21 +419,91 @@ func main() {
 	// Step 4: Save results
 	fmt.Printf(" Saving results...\n")
 
-	result := map[string]interface{}{
-		"merkleRoot":  tree.GetRootHash(),
-		"proofs":      proofs,
-		"totalClaims": len(claims),
-		"generatedAt": time.Now().Unix(),
-		"buildTime":   buildTime.String(),
-		"proofTime":   proofTime.String(),
-	}
+	if *shardBits != 0 {
+		proofSet := &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}
+		if err := data.ExportProofsSharded(proofSet, tree.GetRootHash(), outputFile, *shardBits, addressCase, proofFields); err != nil {
+			log.Fatal("Failed to save results:", err)
+		}
+	} else if *canonical {
+		proofSet := &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}
+		digest := sha256.New()
+		err := fsutil.AtomicWriteFile(outputFile, func(w io.Writer) error {
+			return data.ExportProofsCanonical(proofSet, tree.GetRootHash(), io.MultiWriter(w, digest), addressCase, proofFields)
+		})
+		if err != nil {
+			log.Fatal("Failed to save results:", err)
+		}
+
+		// Whatever varies between runs goes in the sidecar
+		sidecar := map[string]interface{}{
+			"proofsFile":  outputFile,
+			"sha256":      hex.EncodeToString(digest.Sum(nil)),
+			"generatedAt": time.Now().Unix(),
+			"buildTime":   buildTime.String(),
+			"proofTime":   proofTime.String(),
+		}
+		if sparse != nil {
+			sidecar["sparseRoot"] = sparse.GetRootHash()
+			sidecar["sparseDepth"] = sparse.Depth()
+		}
+		if campaign != nil {
+			sidecar["campaign"] = campaign
+		}
+		if err := saveToJSON(sidecar, data.CanonicalMetadataFile); err != nil {
+			log.Fatal("Failed to save metadata:", err)
+		}
+		fmt.Printf(" Metadata saved to %s (sha256 %s)\n", data.CanonicalMetadataFile, sidecar["sha256"])
+	} else if *format == "bin" {
+		if err := data.SaveProofsBinaryFile(&merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}, tree.Root.Hash, outputFile, *compress); err != nil {
+			log.Fatal("Failed to save results:", err)
+		}
+	} else {
+		result := map[string]interface{}{
+			"merkleRoot":  tree.GetRootHash(),
+			"metadata":    tree.Metadata(),
+			"proofs":      proofFields.Proofs(addressCase.FormatProofs(proofs)),
+			"totalClaims": len(claims),
+			"indexBitmap": merkle.EncodeIndexBitmap(tree.IndexBitmap()),
+			"generatedAt": time.Now().Unix(),
+			"buildTime":   buildTime.String(),
+			"proofTime":   proofTime.String(),
+		}
+		if sparse != nil {
+			result["sparseRoot"] = sparse.GetRootHash()
+			result["sparseDepth"] = sparse.Depth()
+		}
+		if campaign != nil {
+			result["campaign"] = campaign
+		}
+		if !proofFields.IsDefault() {
+			result["proofFields"] = proofFields
+		}
 
-	if err := saveToJSON(result, outputFile); err != nil {
-		log.Fatal("Failed to save results:", err)
+		if err := saveToJSON(result, outputFile); err != nil {
+			log.Fatal("Failed to save results:", err)
+		}
 	}
 
 	fmt.Printf(" Results saved to %s\n", outputFile)
 
+	// Record new addresses' indices only once proofs using them are saved
+	if opts.Indices != nil {
+		if err := data.SaveIndexFile(*indexFile, opts.Indices); err != nil {
+			log.Fatal("Failed to save indices:", err)
+		}
+		fmt.Printf(" Indices saved to %s\n", *indexFile)
+	}
+
+	if *deltaFrom != "" {
+		err := fsutil.AtomicWriteFile(deltaFile, func(w io.Writer) error {
+			return data.ExportProofsDelta(oldProofs, &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}, oldRoot, tree.GetRootHash(), w)
+		})
+		if err != nil {
+			log.Fatal("Failed to save delta:", err)
+		}
+		fmt.Printf(" Changes from root %s saved to %s\n", oldRoot, deltaFile)
+	}
+
 	// Step 5: Verify multiple random proofs
 	fmt.Printf(" Verifying proofs...\n")
 
@@ -103,7 +515,7 @@ func main() {
 			log.Fatal("Proof not found for test address")
 		}
 
-		valid := verifyProof(proof, testClaim, tree.GetRootHash())
+		valid := verifyProof(proof, testClaim, tree.GetRootHash(), tree.Options())
 		if valid {
 			fmt.Printf(" Proof verification successful for %s!\n", testClaim.Address.Hex())
 		} else {
@@ -117,7 +529,7 @@ func main() {
 				claim := claims[idx]
 				proof, exists := proofs[claim.Address.Hex()]
 				if exists {
-					valid := verifyProof(proof, claim, tree.GetRootHash())
+					valid := verifyProof(proof, claim, tree.GetRootHash(), tree.Options())
 					if valid {
 						fmt.Printf(" Proof verification successful for claim %d\n", idx)
 					} else {
@@ -132,6 +544,9 @@ func main() {
 	fmt.Printf("\n Summary Statistics:\n")
 	fmt.Printf("   - Total Claims: %d\n", len(claims))
 	fmt.Printf("   - Merkle Root: %s\n", tree.GetRootHash())
+	if sparse != nil {
+		fmt.Printf("   - Sparse Root: %s (depth %d)\n", sparse.GetRootHash(), sparse.Depth())
+	}
 	fmt.Printf("   - Tree Height: %d\n", calculateTreeHeight(len(claims)))
 	fmt.Printf("   - Average Proof Length: %.1f hashes\n", calculateAverageProofLength(proofs))
 
@@ -149,76 +564,133 @@ func main() {
 	fmt.Printf("   4. Test claim functionality\n")
 }
 
+
 // verifyProof verifies a Merkle proof against a claim and root hash
-func verifyProof(proof *merkle.MerkleProof, claim merkle.AirdropClaim, rootHash string) bool {
-	// Reconstruct the leaf hash
-	leafHash := merkle.HashLeaf(claim.Address, claim.Amount, claim.Index)
-
-	// Verify proof path
-	currentHash := leafHash
-	for _, proofHash := range proof.Proof {
-		// Removes "0x" prefix and decode hex
-		proofBytes, err := hex.DecodeString(proofHash[2:])
+func verifyProof(proof *merkle.MerkleProof, claim merkle.AirdropClaim, rootHash string, opts merkle.TreeOptions) bool {
+	root, err := merkle.DecodeHash(rootHash)
+	if err != nil {
+		fmt.Printf("Error decoding root hash %s: %v\n", rootHash, err)
+		return false
+	}
+
+	valid, err := merkle.VerifyMerkleProof(root, claim, proof, opts)
+	if err != nil {
+		fmt.Printf("Error verifying proof: %v\n", err)
+		return false
+	}
+	return valid
+}
+
+// buildWorkers returns the -workers flag when it was given, and otherwise
+// worker_count from configFile
+func buildWorkers(fs *flag.FlagSet, workers int, configFile string) (int, error) {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == "workers"
+	})
+	if !given {
+		cfg, err := config.LoadConfig(configFile)
 		if err != nil {
-			fmt.Printf("Error decoding proof hash %s: %v\n", proofHash, err)
-			return false
+			return 0, fmt.Errorf("failed to load config: %w", err)
 		}
-
-		// Hash with sibling
-		currentHash = merkle.HashInternal(currentHash, proofBytes)
+		workers = cfg.Merkle.WorkerCount
 	}
+	if workers < 0 {
+		return 0, fmt.Errorf("workers must not be negative: %d", workers)
+	}
+	return workers, nil
+}
 
-	// Compare with root hash
-	reconstructedRoot := fmt.Sprintf("0x%x", currentHash)
-	return reconstructedRoot == rootHash
+// claimLimit is build's limit on the claims it loads, with where it came
+// from
+type claimLimit struct {
+	limit  int
+	source string
 }
 
-// saveToCSV saves claims to CSV file
-func saveToCSV(claims []merkle.AirdropClaim, filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+// buildMaxClaims returns the -max-claims flag when it was given, and
+// otherwise max_claims from configFile
+func buildMaxClaims(fs *flag.FlagSet, maxClaims int, configFile string) (claimLimit, error) {
+	given := false
+	fs.Visit(func(f *flag.Flag) {
+		given = given || f.Name == "max-claims"
+	})
+	limit := claimLimit{limit: maxClaims, source: "-max-claims"}
+	if !given {
+		cfg, err := config.LoadConfig(configFile)
+		if err != nil {
+			return claimLimit{}, fmt.Errorf("failed to load config: %w", err)
+		}
+		limit.limit = cfg.Merkle.MaxClaims
+		limit.source = "max_claims in " + configFile
+		if _, err := os.Stat(configFile); os.IsNotExist(err) {
+			limit.source = "the default max_claims, " + configFile + " not being found"
+		}
+	}
+	if limit.limit < 0 {
+		return claimLimit{}, fmt.Errorf("max claims must not be negative: %d (from %s)", limit.limit, limit.source)
 	}
-	defer file.Close()
+	return limit, nil
+}
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// explain names the limit's source in a TooManyClaimsError
+func (l claimLimit) explain(err error) error {
+	if errors.Is(err, merkle.ErrTooManyClaims) {
+		return fmt.Errorf("%w (limit from %s; raise it or pass -max-claims 0 for none)", err, l.source)
+	}
+	return err
+}
 
-	// Write header
-	if err := writer.Write([]string{"address", "amount", "index"}); err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
+// checkOutputs exits before any work is done if an output would replace an
+// existing file without -overwrite
+func checkOutputs(overwrite bool, paths ...string) {
+	for _, path := range paths {
+		if err := fsutil.CheckOverwrite(path, overwrite); err != nil {
+			if errors.Is(err, fsutil.ErrExists) {
+				log.Fatalf("%v; pass -overwrite to replace it", err)
+			}
+			log.Fatal(err)
+		}
 	}
+}
+
+// saveToCSV saves claims to CSV file with addresses in addressCase
+func saveToCSV(claims []merkle.AirdropClaim, filename string, addressCase data.AddressCase) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
 
-	// Write claims
-	for _, claim := range claims {
-		record := []string{
-			claim.Address.Hex(),
-			claim.Amount.String(),
-			strconv.FormatUint(uint64(claim.Index), 10),
+		// Write header
+		if err := writer.Write([]string{"address", "amount", "index"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
 		}
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("failed to write record: %w", err)
+
+		// Write claims
+		for _, claim := range claims {
+			record := []string{
+				addressCase.Format(claim.Address),
+				claim.Amount.String(),
+				strconv.FormatUint(uint64(claim.Index), 10),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
 		}
-	}
 
-	return nil
+		writer.Flush()
+		return writer.Error()
+	})
 }
 
 // saveToJSON saves data to JSON file
 func saveToJSON(data interface{}, filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(data); err != nil {
-		return fmt.Errorf("failed to encode JSON: %w", err)
-	}
-
-	return nil
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(data); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return nil
+	})
 }
 
 // calculateTreeHeight calculates the height of a binary tree given number of leaves
@@ -251,3 +723,12 @@ func calculateAverageProofLength(proofs map[string]*merkle.MerkleProof) float64
 
 	return float64(totalLength) / float64(len(proofs))
 }
+
+// domainSeparator returns the leaf domain separator of a -domain campaign
+// name, nil without one
+func domainSeparator(name string) []byte {
+	if name == "" {
+		return nil
+	}
+	return merkle.DomainSeparatorFor(name)
+}
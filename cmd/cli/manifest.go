@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/pkg/release"
+)
+
+// runManifest writes release.json for a campaign's artifacts, or with the
+// verify subcommand checks a release against its manifest
+func runManifest(args []string) {
+	if len(args) > 0 && args[0] == "verify" {
+		runManifestVerify(args[1:])
+		return
+	}
+
+	fs := flag.NewFlagSet("manifest", flag.ExitOnError)
+	version := fs.String("version", "", "semantic version of the campaign release, e.g. 1.0.0 (required)")
+	name := fs.String("name", "", "campaign name to record")
+	input := fs.String("input", "airdrop_data.csv", "claims CSV the tree was built from")
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs file (.json or .bin) built from -input")
+	configFile := fs.String("config", "", "configuration file to record with its checksum")
+	deployments := fs.String("deployments", "", "deployments.json from the deploy command, to record the contracts")
+	out := fs.String("out", release.DefaultManifestFile, "manifest file; artifact paths are recorded relative to its directory")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	if *version == "" {
+		log.Fatal("-version is required")
+	}
+	checkOutputs(*overwrite, *out)
+
+	manifest, err := release.Create(*out, release.Options{
+		Campaign:    *name,
+		Version:     *version,
+		Input:       *input,
+		Layout:      layout(),
+		Proofs:      *proofsFile,
+		Config:      *configFile,
+		Deployments: *deployments,
+	})
+	if err != nil {
+		log.Fatal("Failed to create manifest: ", err)
+	}
+	fmt.Printf(" Release %s of root %s: %d claims, %s base units\n", manifest.Version, manifest.MerkleRoot, manifest.TotalClaims, manifest.TotalAmount)
+	for chain, deployment := range manifest.Deployments {
+		fmt.Printf("   - %s: %s (tx %s)\n", chain, deployment.Contract, deployment.TxHash)
+	}
+	fmt.Printf(" Manifest saved to %s\n", *out)
+}
+
+// runManifestVerify recomputes the checksums of a release and re-verifies a
+// sample of its proofs, exiting 1 when anything doesn't match
+func runManifestVerify(args []string) {
+	fs := flag.NewFlagSet("manifest verify", flag.ExitOnError)
+	in := fs.String("in", release.DefaultManifestFile, "manifest to verify")
+	sample := fs.Int("sample", release.DefaultSampleSize, "proofs to re-verify against the root, 0 for all")
+	fs.Parse(args)
+
+	log.SetFlags(0)
+	if *sample < 0 {
+		log.Fatal("-sample must not be negative")
+	}
+	err := release.VerifyManifestSample(*in, *sample)
+	if err == nil {
+		fmt.Printf(" %s matches every artifact\n", *in)
+		return
+	}
+	var artifactErr *release.ArtifactError
+	if !errors.As(err, &artifactErr) {
+		log.Fatal(err)
+	}
+	// Each failing artifact is on a line of its own
+	fmt.Fprintf(os.Stderr, " %s does not match its artifacts:\n%v\n", *in, err)
+	os.Exit(1)
+}
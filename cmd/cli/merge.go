@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// sourceFlags collects repeated -source flags
+type sourceFlags []string
+
+func (s *sourceFlags) String() string { return strings.Join(*s, ",") }
+
+func (s *sourceFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// runMerge combines claims CSVs, each scaled by a multiplier or given a flat
+// amount per address, into one claims CSV
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	var sources sourceFlags
+	fs.Var(&sources, "source", "claims CSV as file:multiplier (2, 1.5, 3/2) or file:flat=amount (100e18); repeatable")
+	out := fs.String("out", "airdrop_data.csv", "output claims CSV")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	caseName := fs.String("address-case", "checksum", "address case in the output: checksum or lower")
+	fs.Parse(args)
+
+	addressCase, err := data.ParseAddressCase(*caseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	checkOutputs(*overwrite, *out)
+	if len(sources) == 0 {
+		log.Fatal("At least one -source is required")
+	}
+
+	claimSources := make([]data.ClaimSource, 0, len(sources))
+	for _, value := range sources {
+		source, err := parseClaimSource(value)
+		if err != nil {
+			log.Fatalf("Invalid -source %q: %v", value, err)
+		}
+		claimSources = append(claimSources, source)
+	}
+
+	claims, report, err := data.MergeClaimSources(claimSources)
+	if err != nil {
+		log.Fatal("Merge failed: ", err)
+	}
+	if err := data.SaveClaimsToCSV(claims, *out, addressCase); err != nil {
+		log.Fatal("Failed to save claims: ", err)
+	}
+
+	fmt.Println(" Sources:")
+	for _, source := range report.Sources {
+		fmt.Printf("   %s: %d addresses, %s in, %s out (%s dropped by rounding), %d overlapping\n",
+			source.Label, source.Addresses, source.Input, source.Output, source.Remainder.FloatString(6), source.Overlapping)
+	}
+	for count := 2; count <= len(report.Sources); count++ {
+		if report.Overlaps[count] > 0 {
+			fmt.Printf(" Addresses in %d sources: %d\n", count, report.Overlaps[count])
+		}
+	}
+	if report.Zero > 0 {
+		fmt.Printf(" Left out %d addresses with a merged amount of zero\n", report.Zero)
+	}
+	fmt.Printf(" Wrote %d claims (total %s) to %s\n", report.Addresses, report.Total, *out)
+}
+
+// parseClaimSource parses a -source value. The file name may itself contain
+// colons, so the spec is split at the last one.
+func parseClaimSource(value string) (data.ClaimSource, error) {
+	file, spec := value, "1"
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		file, spec = value[:i], value[i+1:]
+	}
+	if file == "" {
+		return data.ClaimSource{}, fmt.Errorf("missing file")
+	}
+	source := data.ClaimSource{
+		Label: file,
+		Load:  func() ([]merkle.AirdropClaim, error) { return data.LoadAirdropFromCSV(file) },
+	}
+
+	if amount, ok := strings.CutPrefix(spec, "flat="); ok {
+		flat, err := parseTokenAmount(amount)
+		if err != nil {
+			return data.ClaimSource{}, fmt.Errorf("flat amount: %w", err)
+		}
+		source.Flat = flat
+		return source, nil
+	}
+	multiplier, ok := new(big.Rat).SetString(spec)
+	if !ok {
+		return data.ClaimSource{}, fmt.Errorf("not a multiplier: %q", spec)
+	}
+	source.Multiplier = multiplier
+	return source, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/fsutil"
+)
+
+// runSchema writes the JSON Schema of the API's bodies, as /api/schema
+// serves it, and optionally TypeScript declarations generated from it
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	out := fs.String("out", "api.schema.json", "JSON Schema file to write")
+	tsOut := fs.String("ts", "", "also write TypeScript declarations to this .d.ts file")
+	overwrite := fs.Bool("overwrite", false, "replace existing output files")
+	fs.Parse(args)
+
+	outputs := []string{*out}
+	if *tsOut != "" {
+		outputs = append(outputs, *tsOut)
+	}
+	checkOutputs(*overwrite, outputs...)
+
+	doc := api.JSONSchema()
+	if err := saveToJSON(doc, *out); err != nil {
+		log.Fatal("Failed to save schema: ", err)
+	}
+	fmt.Printf(" Wrote the schemas of %d types to %s\n", len(doc["$defs"].(map[string]interface{})), *out)
+
+	if *tsOut != "" {
+		err := fsutil.AtomicWriteFile(*tsOut, func(w io.Writer) error {
+			_, err := io.WriteString(w, api.TypeScriptDefinitions())
+			return err
+		})
+		if err != nil {
+			log.Fatal("Failed to save TypeScript declarations: ", err)
+		}
+		fmt.Printf(" Wrote TypeScript declarations to %s\n", *tsOut)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// simulateResult is simulate's -json output
+type simulateResult struct {
+	Address     string              `json:"address"`
+	MerkleRoot  string              `json:"merkleRoot"`  // The root with the claim added
+	CurrentRoot string              `json:"currentRoot"` // The proofs file's
+	Proof       *merkle.MerkleProof `json:"proof"`
+}
+
+// runSimulate prints the root the tree would have with one more claim, and
+// that claim's proof, so a root update can be staged before the claim is
+// added. Nothing is written.
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	input := fs.String("input", "airdrop_data.csv", "claims CSV the tree was built from")
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs built from -input, whose leaf encoding is used")
+	address := fs.String("address", "", "address of the claim to add")
+	amount := fs.String("amount", "", "amount of the claim to add, in base units")
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	layout := csvLayoutFlags(fs)
+	fs.Parse(args)
+
+	if !common.IsHexAddress(*address) {
+		log.Fatalf("-address must be an address, got %q", *address)
+	}
+	claimed, err := parseTokenAmount(*amount)
+	if err != nil {
+		log.Fatalf("Invalid -amount: %v", err)
+	}
+
+	claims, err := data.LoadAirdropFromCSVWithOptions(*input, layout())
+	if err != nil {
+		log.Fatal("Failed to load data: ", err)
+	}
+	root, proofs, err := data.LoadProofsFile(*proofsFile)
+	if err != nil {
+		log.Fatal("Failed to load proofs: ", err)
+	}
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, proofs.Metadata.Options())
+	if err != nil {
+		log.Fatal("Failed to build tree: ", err)
+	}
+	if tree.GetRootHash() != root {
+		log.Fatalf("%s builds root %s, but %s has root %s", *input, tree.GetRootHash(), *proofsFile, root)
+	}
+
+	claim := merkle.AirdropClaim{Address: common.HexToAddress(*address), Amount: claimed}
+	_, proof, err := tree.SimulateAddClaim(claim)
+	if err != nil {
+		// An address with a claim already is reported with its amount
+		log.Fatal("Can't add the claim: ", err)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(simulateResult{Address: claim.Address.Hex(), MerkleRoot: proof.Root, CurrentRoot: root, Proof: proof})
+		return
+	}
+	fmt.Printf(" Address:      %s\n", claim.Address.Hex())
+	fmt.Printf(" Current root: %s\n", root)
+	fmt.Printf(" New root:     %s\n", proof.Root)
+	fmt.Printf(" Index:        %d\n", proof.Index)
+	fmt.Printf(" Amount:       %s\n", proof.Amount)
+	fmt.Printf(" Proof:        %d elements\n", len(proof.Proof))
+	for _, element := range proof.Proof {
+		fmt.Printf("   %s\n", element)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+	"merkle-airdrop/pkg/snapshot"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// runSnapshot writes claims for the holders of an ERC-20 token at a block
+func runSnapshot(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	token := fs.String("token", "", "ERC-20 token address")
+	block := fs.Uint64("block", 0, "snapshot block number")
+	total := fs.String("total", "", "amount split pro rata between holders, e.g. 1000000e18 (default: claim each balance)")
+	minBalance := fs.String("min-balance", "1", "smallest balance that receives a claim")
+	fromBlock := fs.Uint64("from", 0, "first block to scan, e.g. the token's deployment block")
+	blockRange := fs.Uint64("range", snapshot.DefaultOptions().BlockRange, "blocks per log query")
+	rpcURL := fs.String("rpc", "", "Ethereum RPC URL (default: rpc_url from -config)")
+	configFile := fs.String("config", "config.json", "configuration file with the RPC URL")
+	out := fs.String("out", "snapshot.csv", "output claims CSV")
+	overwrite := fs.Bool("overwrite", false, "replace -out if it exists")
+	caseName := fs.String("address-case", "checksum", "address case in the output: checksum or lower")
+	fs.Parse(args)
+
+	addressCase, err := data.ParseAddressCase(*caseName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	checkOutputs(*overwrite, *out)
+
+	if !common.IsHexAddress(*token) {
+		log.Fatalf("Invalid -token address %q", *token)
+	}
+	if *block == 0 {
+		log.Fatal("-block is required")
+	}
+
+	opts := snapshot.DefaultOptions()
+	opts.FromBlock = *fromBlock
+	opts.BlockRange = *blockRange
+	if *total != "" {
+		amount, err := parseTokenAmount(*total)
+		if err != nil {
+			log.Fatalf("Invalid -total: %v", err)
+		}
+		opts.TotalAllocation = amount
+	}
+	minimum, err := parseTokenAmount(*minBalance)
+	if err != nil {
+		log.Fatalf("Invalid -min-balance: %v", err)
+	}
+
+	if *rpcURL == "" {
+		cfg, err := config.LoadConfig(*configFile)
+		if err != nil {
+			log.Fatal("Failed to load config: ", err)
+		}
+		*rpcURL = cfg.Ethereum.RPCURL
+	}
+	client, err := ethclient.Dial(*rpcURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to %s: %v", *rpcURL, err)
+	}
+	defer client.Close()
+
+	fmt.Printf(" Snapshotting %s at block %d...\n", *token, *block)
+	start := time.Now()
+
+	claims, err := snapshot.TakeERC20SnapshotWithOptions(context.Background(), client, common.HexToAddress(*token), *block, minimum, opts)
+	if err != nil {
+		log.Fatal("Snapshot failed: ", err)
+	}
+
+	if err := data.SaveClaimsToCSV(claims, *out, addressCase); err != nil {
+		log.Fatal("Failed to save claims: ", err)
+	}
+	fmt.Printf(" Wrote %d claims (total %s) to %s in %v\n", len(claims), merkle.TotalAmount(claims), *out, time.Since(start))
+}
+
+// parseTokenAmount parses a whole token amount in base units, accepting
+// exponents such as 1000000e18
+func parseTokenAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("not a number: %q", s)
+	}
+	if !amount.IsInt() || amount.Sign() < 0 {
+		return nil, fmt.Errorf("not a whole non-negative amount: %q", s)
+	}
+	return amount.Num(), nil
+}
+
+// parseOptionalAmount is parseTokenAmount returning nil for an unset flag
+func parseOptionalAmount(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return parseTokenAmount(s)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"strconv"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// maxPrintedGaps bounds the unused indices stats lists
+const maxPrintedGaps = 20
+
+// runStats prints what a distributor will store for a proofs file: the
+// largest claim index, the claimed-bitmap words it touches and the unused
+// indices in between, and optionally how claims split into amount tiers
+func runStats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs file (.json, .bin or .bin.gz) or sharded export")
+	showBitmap := fs.Bool("bitmap", false, "also print the index bitmap, run-length encoded if large")
+	tierList := fs.String("tiers", "", "comma-separated amount boundaries in base units, e.g. 100e18,1000e18,10000e18, to count claims by amount tier")
+	tiersOut := fs.String("tiers-out", "", "also write the tiers to this CSV, in base units")
+	decimals := fs.Int("decimals", data.DefaultTokenDecimals, "token decimals for the tier table")
+	overwrite := fs.Bool("overwrite", false, "replace -tiers-out if it exists")
+	fs.Parse(args)
+
+	var boundaries []*big.Int
+	if *tierList != "" {
+		var err error
+		if boundaries, err = merkle.ParseTierBoundaries(*tierList); err != nil {
+			log.Fatalf("Invalid -tiers: %v", err)
+		}
+	} else if *tiersOut != "" {
+		log.Fatal("-tiers-out requires -tiers")
+	}
+	if *tiersOut != "" {
+		checkOutputs(*overwrite, *tiersOut)
+	}
+
+	rootHash, proofs, err := data.LoadProofsFile(*proofsFile)
+	if err != nil {
+		log.Fatal("Failed to load proofs: ", err)
+	}
+	indices := make([]uint32, 0, len(proofs.Proofs))
+	for _, proof := range proofs.Proofs {
+		indices = append(indices, proof.Index)
+	}
+	stats := merkle.IndexStatsOf(indices, maxPrintedGaps)
+
+	fmt.Printf(" Root: %s\n", rootHash)
+	fmt.Printf(" Claims: %d\n", len(proofs.Proofs))
+	fmt.Printf(" Max index: %d\n", stats.MaxIndex)
+	fmt.Printf(" Bitmap words: %d of %d (%d claims per word)\n", stats.Words, stats.MaxIndex/merkle.IndexWordBits+1, merkle.IndexWordBits)
+	if stats.GapCount == 0 {
+		fmt.Println(" Gaps: none")
+	} else {
+		fmt.Printf(" Gaps: %d unused indices", stats.GapCount)
+		if stats.GapCount > len(stats.Gaps) {
+			fmt.Printf(", the first %d", len(stats.Gaps))
+		}
+		fmt.Printf(": %v\n", stats.Gaps)
+	}
+	if *showBitmap {
+		fmt.Printf(" Bitmap: %s\n", merkle.EncodeIndexBitmap(merkle.IndexBitmapOf(indices)))
+	}
+
+	if boundaries == nil {
+		return
+	}
+	claims := make([]merkle.AirdropClaim, 0, len(proofs.Proofs))
+	for address, proof := range proofs.Proofs {
+		amount, ok := new(big.Int).SetString(proof.Amount, 10)
+		if !ok {
+			log.Fatalf("Invalid amount %q for %s", proof.Amount, address)
+		}
+		claims = append(claims, merkle.AirdropClaim{Amount: amount})
+	}
+	tiers, err := merkle.TierBreakdown(claims, boundaries)
+	if err != nil {
+		log.Fatal(err)
+	}
+	printTiers(tiers, merkle.TotalAmount(claims), *decimals)
+	if *tiersOut != "" {
+		if err := saveTiers(tiers, *tiersOut); err != nil {
+			log.Fatal("Failed to save tiers: ", err)
+		}
+		fmt.Printf(" Tiers saved to %s\n", *tiersOut)
+	}
+}
+
+// printTiers prints a table of tiers with amounts in tokens of decimals
+func printTiers(tiers []merkle.Tier, total *big.Int, decimals int) {
+	ranges := make([]string, len(tiers))
+	width := len("Tier")
+	for i, tier := range tiers {
+		if tier.Max == nil {
+			ranges[i] = ">= " + data.FormatUnits(tier.Min, decimals)
+		} else {
+			ranges[i] = data.FormatUnits(tier.Min, decimals) + " - <" + data.FormatUnits(tier.Max, decimals)
+		}
+		width = max(width, len(ranges[i]))
+	}
+
+	fmt.Printf(" %-*s  %10s  %s\n", width, "Tier", "Addresses", "Total (share)")
+	for i, tier := range tiers {
+		share := 0.0
+		if total.Sign() > 0 {
+			share, _ = new(big.Rat).SetFrac(new(big.Int).Mul(tier.Total, big.NewInt(100)), total).Float64()
+		}
+		fmt.Printf(" %-*s  %10d  %s (%.1f%%)\n", width, ranges[i], tier.Addresses, data.FormatUnits(tier.Total, decimals), share)
+	}
+}
+
+// saveTiers writes tiers as CSV, in base units with an empty max for the
+// last tier
+func saveTiers(tiers []merkle.Tier, filename string) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"min", "max", "addresses", "total"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+		for _, tier := range tiers {
+			upper := ""
+			if tier.Max != nil {
+				upper = tier.Max.String()
+			}
+			record := []string{tier.Min.String(), upper, strconv.Itoa(tier.Addresses), tier.Total.String()}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	})
+}
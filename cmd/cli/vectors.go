@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/merkle/testvectors"
+)
+
+// runVectors writes the canonical leaf, root and proof vectors other
+// implementations check their hashing against
+func runVectors(args []string) {
+	fs := flag.NewFlagSet("vectors", flag.ExitOnError)
+	out := fs.String("out", "vectors.json", "JSON vectors file to write")
+	fs.Parse(args)
+
+	if err := fsutil.AtomicWriteFile(*out, testvectors.Write); err != nil {
+		log.Fatal("Failed to save vectors:", err)
+	}
+	fmt.Printf(" Wrote test vectors for %d encodings to %s\n", len(testvectors.Encodings), *out)
+}
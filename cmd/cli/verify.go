@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// verifyErrorExit is verify's exit code when the check can't be run; the
+// outcomes exit with their data.ClaimStatus
+const verifyErrorExit = 3
+
+// runVerify checks one address's claim against a proofs file without the
+// server, exiting 0 when it is valid, 1 when it is not and 2 when the
+// address has no proof
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	proofsFile := fs.String("proofs", "merkle_proofs.json", "proofs file to check against (.json, .bin, .bin.gz or a shard directory)")
+	address := fs.String("address", "", "claimant address, in any case")
+	amount := fs.String("amount", "", "amount the user claims, in base units (default the amount in the proofs file)")
+	jsonOutput := fs.Bool("json", false, "print the result as JSON")
+	verbose := fs.Bool("v", false, "trace the check: the leaf, each intermediate hash and which step failed")
+	fs.Parse(args)
+
+	log.SetFlags(0)
+	fail := func(format string, args ...interface{}) {
+		log.Printf(format, args...)
+		os.Exit(verifyErrorExit)
+	}
+	if !common.IsHexAddress(*address) {
+		fail("-address must be an address, got %q", *address)
+	}
+	claimed, err := parseOptionalAmount(*amount)
+	if err != nil {
+		fail("Invalid -amount: %v", err)
+	}
+	root, proofs, err := data.LoadProofsFile(*proofsFile)
+	if err != nil {
+		fail("Failed to load proofs: %v", err)
+	}
+
+	check, err := data.CheckClaim(root, proofs, common.HexToAddress(*address), claimed)
+	if err != nil {
+		fail("Failed to check claim: %v", err)
+	}
+	if *verbose && check.Status != data.ClaimNotFound {
+		check.Trace, err = data.TraceClaim(root, proofs, common.HexToAddress(*address), claimed)
+		if err != nil {
+			fail("Failed to trace claim: %v", err)
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(check)
+	} else {
+		printClaimCheck(check)
+	}
+	os.Exit(int(check.Status))
+}
+
+// printClaimCheck prints a check for support staff
+func printClaimCheck(check *data.ClaimCheck) {
+	fmt.Printf(" Address: %s\n", check.Address)
+	fmt.Printf(" Root:    %s\n", check.MerkleRoot)
+	switch check.Status {
+	case data.ClaimNotFound:
+		fmt.Println(" Not found: the address has no proof in this file")
+		return
+	case data.ClaimValid:
+		fmt.Printf(" Valid: index %d, amount %s\n", *check.Index, check.Amount)
+	default:
+		fmt.Printf(" Invalid: %s\n", check.Reason)
+		if check.AmountMismatch {
+			fmt.Printf("   Supplied amount: %s\n", check.Amount)
+			fmt.Printf("   Tree amount:     %s\n", check.TreeAmount)
+		}
+	}
+	if check.Trace != nil {
+		printTrace(check.Trace)
+	}
+}
+
+// printTrace prints each step of a traced check
+func printTrace(trace *merkle.VerificationTrace) {
+	fmt.Println(" Trace:")
+	fmt.Printf("   Leaf:     %s\n", trace.Leaf)
+	for i, step := range trace.Steps {
+		side := "right"
+		if step.Left {
+			side = "left"
+		}
+		if step.Hash == "" {
+			fmt.Printf("   Step %2d:  %s (malformed)\n", i, step.Sibling)
+			continue
+		}
+		fmt.Printf("   Step %2d:  %s on the %s -> %s\n", i, step.Sibling, side, step.Hash)
+	}
+	if trace.ComputedRoot != "" {
+		fmt.Printf("   Computed: %s\n", trace.ComputedRoot)
+	}
+	fmt.Printf("   Expected: %s\n", trace.ExpectedRoot)
+	if trace.Failure != "" {
+		fmt.Printf("   Failure:  %s: %s\n", trace.Failure, trace.Reason)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// runVerifyFile checks an NDJSON file of proofs, such as one an exchange
+// sends back, against a root, writing one result line per entry. It exits
+// 0 when every entry is valid, 1 when one isn't and 3 when the file can't
+// be checked.
+func runVerifyFile(args []string) {
+	fs := flag.NewFlagSet("verify-file", flag.ExitOnError)
+	input := fs.String("in", "", "NDJSON file of {address, amount, index, proof} entries")
+	rootFlag := fs.String("root", "", "Merkle root to verify against")
+	output := fs.String("out", "", "file for the NDJSON results (default stdout)")
+	pairs := fs.String("pairs", "sorted", "how the tree's node pairs were hashed: sorted or positional")
+	domain := fs.String("domain", "", "campaign name the tree's leaves were separated with")
+	configFile := fs.String("config", "config.json", "configuration file with worker_count")
+	workers := fs.Int("workers", 0, "goroutines verifying entries, 0 for one per CPU (default worker_count from -config)")
+	fs.Parse(args)
+
+	log.SetFlags(0)
+	fail := func(format string, args ...interface{}) {
+		log.Printf(format, args...)
+		os.Exit(verifyErrorExit)
+	}
+	if *input == "" {
+		fail("-in is required")
+	}
+	root, err := merkle.DecodeHash(*rootFlag)
+	if err != nil {
+		fail("-root must be a 0x-prefixed 32-byte hash, got %q", *rootFlag)
+	}
+	if *pairs != "sorted" && *pairs != "positional" {
+		fail("Unknown pair hashing %q (expected sorted or positional)", *pairs)
+	}
+	workerCount, err := buildWorkers(fs, *workers, *configFile)
+	if err != nil {
+		fail("%v", err)
+	}
+	opts := merkle.DefaultTreeOptions()
+	opts.SortedPairs = *pairs == "sorted"
+	opts.DomainSeparator = domainSeparator(*domain)
+
+	in, err := os.Open(*input)
+	if err != nil {
+		fail("Failed to open entries: %v", err)
+	}
+	defer in.Close()
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fail("Failed to create results: %v", err)
+		}
+		defer out.Close()
+	}
+	buffered := bufio.NewWriter(out)
+
+	summary, err := data.VerifyBatch(in, buffered, root, opts, data.BatchOptions{Workers: workerCount})
+	if flushErr := buffered.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to write results: %w", flushErr)
+	}
+	if err != nil {
+		fail("Failed to verify %s: %v", *input, err)
+	}
+	fmt.Fprintf(os.Stderr, " Checked %d entries: %d valid, %d invalid\n", summary.Entries, summary.Valid, summary.Invalid)
+	if summary.Invalid > 0 {
+		os.Exit(1)
+	}
+}
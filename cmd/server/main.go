@@ -0,0 +1,637 @@
+// main.go
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/internal/reload"
+	"merkle-airdrop/pkg/archive"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/grpcapi"
+	"merkle-airdrop/pkg/indexer"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	configFile := flag.String("config", "config.json", "path to the configuration file")
+	dataFile := flag.String("data", "airdrop_data.csv", "claims CSV to build the tree from")
+	proofsFile := flag.String("proofs", "", "serve an exported proofs file (.json or .bin) or shard directory instead of building from -data")
+	format := flag.String("format", "native", "format of -proofs: native, or uniswap for a merkle-distributor claims.json")
+	root := flag.String("root", "", "Merkle root to verify proofs against with -verify-only")
+	verifyOnly := flag.Bool("verify-only", false, "hold no claims: serve only /api/root and /api/verify for -root")
+	flag.Parse()
+
+	if *format != "native" && *format != "uniswap" {
+		log.Fatalf("Unknown -format %q (expected native or uniswap)", *format)
+	}
+	if *format == "uniswap" && *proofsFile == "" {
+		log.Fatal("-format uniswap requires -proofs")
+	}
+	if *verifyOnly != (*root != "") {
+		log.Fatal("-verify-only and -root must be given together")
+	}
+	if *verifyOnly && *proofsFile != "" {
+		log.Fatal("-verify-only holds no claims; it cannot serve -proofs")
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		log.Fatal("Failed to load config: ", err)
+	}
+	if err := cfg.ValidateAll(); err != nil {
+		log.Fatalf("Invalid config %s:\n%v", *configFile, err)
+	}
+	for _, warning := range cfg.Warnings() {
+		log.Printf("Warning: %s", warning)
+	}
+
+	logger, level, err := newLogger(cfg.Logging)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// CORS, abuse thresholds, the cache TTL, the log level and the claim
+	// window follow config reloads; everything else is fixed until restart
+	tunables, err := reload.NewTunables(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reloadOpts := []reload.Option{reload.WithLogLevel(level), reload.WithLogger(logger)}
+	var lock *api.FinalizationLock
+	if cfg.Server.FinalizationFile != "" {
+		if lock, err = openFinalization(cfg); err != nil {
+			log.Fatal(err)
+		}
+		reloadOpts = append(reloadOpts, reload.WithFinalization(lock))
+	}
+	reloader := reload.New(*configFile, cfg, tunables, reloadOpts...)
+	watchReloadSignal(reloader, logger)
+
+	opts := []api.Option{api.WithAdminTokens(cfg.Server.AdminTokens), api.WithLogger(logger), api.WithMaxBodyBytes(cfg.Server.MaxBodyBytes), api.WithTokenDecimals(cfg.Ethereum.TokenDecimals), api.WithWorkers(cfg.Merkle.WorkerCount)}
+	opts = append(opts, api.WithTunables(tunables), api.WithConfigReloader(reloader))
+	if lock != nil {
+		opts = append(opts, api.WithFinalization(lock, cfg.Server.UnlockTokens))
+		if cfg.Ethereum.RPCURL != "" {
+			client, err := ethclient.Dial(cfg.Ethereum.RPCURL)
+			if err != nil {
+				log.Fatalf("Failed to connect to %s: %v", cfg.Ethereum.RPCURL, err)
+			}
+			opts = append(opts, api.WithRootReader(contract.NewRootReader(client)))
+		}
+	}
+	if cfg.Server.EligibilityOnly {
+		opts = append(opts, api.WithProofsDisabled())
+	}
+	if cfg.Server.Reservation {
+		var store api.ClaimStore = api.NewMemoryClaimStore()
+		if cfg.Server.ReservationStore != "" {
+			store, err = api.NewFileClaimStore(cfg.Server.ReservationStore)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		opts = append(opts, api.WithReservation(store))
+	}
+	if cfg.Server.PrivacyMode {
+		opts = append(opts, api.WithPrivacyMode(cfg.Server.PrivacyVerifyAttempts))
+	}
+	if cfg.Merkle.CacheEnabled {
+		opts = append(opts, api.WithResponseCache(cfg.Merkle.CacheSize, time.Duration(cfg.Merkle.CacheTTL)*time.Second))
+	}
+	if cfg.Server.StaticDir != "" {
+		opts = append(opts, api.WithStaticDir(cfg.Server.StaticDir, cfg.Ethereum.ContractAddress))
+	}
+	if cfg.Server.ClaimLinkURL != "" {
+		opts = append(opts, api.WithClaimLinkURL(cfg.Server.ClaimLinkURL))
+	}
+	if cfg.Ethereum.ContractAddress != "" && cfg.Ethereum.RPCURL != "" {
+		status, progress, err := dialClaimStatus(cfg, logger)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, api.WithIndexerProgress(progress))
+		opts = append(opts, api.WithClaimedCheck(status, common.HexToAddress(cfg.Ethereum.ContractAddress)))
+		if cfg.Server.Suggestions {
+			opts = append(opts, api.WithClaimStatus(status))
+		}
+	}
+	if cfg.Server.Suggestions {
+		opts = append(opts, api.WithSuggestions())
+	}
+	if cfg.Server.TraceLimit > 0 {
+		opts = append(opts, api.WithTraceLimit(cfg.Server.TraceLimit))
+	}
+	if cfg.Server.AbuseMaxNotFound > 0 {
+		abuse, err := reload.AbuseConfig(cfg.Server)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, api.WithAbuseDetection(abuse))
+	}
+	if len(cfg.Server.TrustedProxies) > 0 {
+		proxies := make([]netip.Prefix, len(cfg.Server.TrustedProxies))
+		for i, cidr := range cfg.Server.TrustedProxies {
+			if proxies[i], err = netip.ParsePrefix(cidr); err != nil {
+				log.Fatal(err)
+			}
+		}
+		opts = append(opts, api.WithTrustedProxies(proxies))
+	}
+	if cfg.Server.BloomFPR != 0 {
+		opts = append(opts, api.WithBloomFilter(cfg.Server.BloomFPR))
+	}
+	if cfg.Server.BasePath != "" {
+		opts = append(opts, api.WithBasePath(cfg.Server.BasePath))
+	}
+	if cfg.Server.Compression != "" {
+		mode, err := api.ParseCompression(cfg.Server.Compression)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, api.WithCompression(mode))
+	}
+	if cfg.Campaign.Enabled() {
+		campaign, err := loadCampaign(cfg.Campaign)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, api.WithCampaign(campaign, cfg.Campaign.File))
+	}
+	if cfg.Campaign.ProofFields != "" {
+		fields, err := data.ParseProofMarshaller(cfg.Campaign.ProofFields)
+		if err == nil {
+			err = api.ValidateProofFields(fields)
+		}
+		if err != nil {
+			log.Fatal("Invalid campaign proof_fields: ", err)
+		}
+		opts = append(opts, api.WithProofFields(fields))
+	}
+	if cfg.Archive.Enabled() {
+		store, err := openArchive(cfg.Archive)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, api.WithArchive(store, cfg.Archive.Campaign))
+	}
+
+	if *verifyOnly {
+		rootBytes, err := merkle.DecodeHash(*root)
+		if err != nil {
+			log.Fatalf("Invalid -root %q: expected a 0x-prefixed 32-byte hash", *root)
+		}
+		if cfg.Merkle.RebuildInterval != 0 || cfg.Server.LazyProofs || cfg.Server.AsyncProofs || cfg.Server.GRPCPort != 0 || cfg.Server.AppendClaims {
+			log.Fatal("-verify-only holds no claims; disable rebuild_interval, lazy_proofs, async_proofs, grpc_port and append_claims")
+		}
+		fmt.Printf(" Verifying proofs against root %s without claim data\n", *root)
+		server, err := api.NewVerifyOnlyServer(rootBytes, treeOptions(cfg.Merkle), opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		serve(cfg, server.SetupRoutes())
+		return
+	}
+
+	if cfg.Merkle.RebuildInterval != 0 {
+		if *proofsFile != "" {
+			log.Fatal("rebuild_interval needs claims to rebuild from; it cannot serve a proofs file")
+		}
+		scheduler, err := startRebuilds(cfg, *dataFile, lock, logger, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		serve(cfg, scheduler)
+		return
+	}
+
+	if cfg.Server.LazyProofs {
+		if *proofsFile != "" {
+			log.Fatal("lazy_proofs needs a tree; it cannot serve a proofs file")
+		}
+		if cfg.Server.GRPCPort != 0 {
+			log.Fatal("lazy_proofs is not supported with the gRPC API")
+		}
+
+		tree, err := loadTree(*dataFile, cfg.Merkle)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server, err := api.NewLazyAPIServer(tree, cfg.Server.ProofCacheSize, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Lazy servers come up regardless, with /readyz failing
+		if err := selfTest(server, cfg.Server); err != nil {
+			fmt.Printf(" %v; serving with /readyz failing\n", err)
+		}
+		fmt.Printf(" Serving proofs on demand (cache size %d)\n", cfg.Server.ProofCacheSize)
+		serve(cfg, server.SetupRoutes())
+		return
+	}
+
+	if cfg.Server.AsyncProofs {
+		if *proofsFile != "" {
+			log.Fatal("async_proofs needs a tree; it cannot serve a proofs file")
+		}
+		if cfg.Server.GRPCPort != 0 {
+			log.Fatal("async_proofs is not supported with the gRPC API")
+		}
+
+		tree, err := loadTree(*dataFile, cfg.Merkle)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server, err := api.NewPrecomputingAPIServer(tree, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := selfTest(server, cfg.Server); err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			if err := server.Precompute(); err != nil {
+				log.Fatal("Failed to generate proofs: ", err)
+			}
+			fmt.Println(" All proofs generated")
+		}()
+		fmt.Println(" Generating proofs in the background")
+		serve(cfg, server.SetupRoutes())
+		return
+	}
+
+	if cfg.Server.AppendClaims {
+		if *proofsFile != "" {
+			log.Fatal("append_claims needs claims to append to; it cannot serve a proofs file")
+		}
+		appender, err := startAppender(cfg, *dataFile, logger, opts...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(" Admins can append claims at /api/admin/claims")
+		if cfg.Server.VersionHistory {
+			fmt.Println(" Consistency proofs between versions at /api/consistency/{address}")
+		}
+		serve(cfg, appender)
+		return
+	}
+
+	server, grpcServer, err := loadServers(*dataFile, *proofsFile, *format, cfg.Merkle, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := selfTest(server, cfg.Server); err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.Server.GRPCPort != 0 {
+		// gRPC proof responses have no field for sibling positions
+		if !grpcServer.Options().SortedPairs {
+			log.Fatal("proofs without sorted pairs are not supported with the gRPC API")
+		}
+
+		listener, err := net.Listen("tcp", cfg.GetGRPCAddress())
+		if err != nil {
+			log.Fatal("Failed to listen for gRPC: ", err)
+		}
+
+		srv := grpc.NewServer()
+		grpcapi.RegisterAirdropServer(srv, grpcServer)
+
+		fmt.Printf(" gRPC server listening on %s\n", listener.Addr())
+		go func() {
+			log.Fatal(srv.Serve(listener))
+		}()
+	}
+
+	serve(cfg, server.SetupRoutes())
+}
+
+// selfTest verifies a sample of server's proofs as cfg configures
+func selfTest(server *api.APIServer, cfg config.ServerConfig) error {
+	if cfg.SelfTestSamples == 0 {
+		return nil
+	}
+	budget := time.Duration(cfg.SelfTestBudgetMS) * time.Millisecond
+	result, err := server.SelfTest(cfg.SelfTestSamples, budget)
+	if err != nil {
+		return fmt.Errorf("startup self-test failed: %w", err)
+	}
+	fmt.Printf(" Self-test verified %d sample proofs in %s\n", result.Checked, result.Duration)
+	return nil
+}
+
+// serve runs the HTTP API until it fails
+func serve(cfg *config.Config, handler http.Handler) {
+	httpServer := &http.Server{
+		Addr:         cfg.GetServerAddress(),
+		Handler:      handler,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+	}
+
+	fmt.Printf(" API server listening on %s\n", httpServer.Addr)
+	log.Fatal(httpServer.ListenAndServe())
+}
+
+// loadServers builds the HTTP and gRPC servers from a proofs file in format
+// or a claims CSV, building the tree and its proofs as cfg configures
+func loadServers(dataFile, proofsFile, format string, cfg config.MerkleConfig, opts ...api.Option) (*api.APIServer, *grpcapi.Server, error) {
+	if format == "uniswap" || proofsFile != "" {
+		root, proofs, err := loadProofSet(proofsFile, format)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.ProofStore == config.ProofStoreDisk {
+			// The proofs are read into memory once, then kept only on disk
+			store, err := copyToDiskStore(cfg.ProofStorePath, proofs)
+			if err != nil {
+				return nil, nil, err
+			}
+			server, err := api.NewAPIServerBuilder().ProofStore(store).Root(root, proofs.Metadata).Options(opts...).Build()
+			return server, nil, err
+		}
+		server, err := api.NewAPIServerBuilder().ProofSet(root, proofs).Options(opts...).Build()
+		if err != nil {
+			return nil, nil, err
+		}
+		grpcServer, err := grpcapi.NewServerFromProofs(root, proofs, grpcapi.WithClaimGate(server.ClaimWindowError))
+		if err != nil {
+			return nil, nil, err
+		}
+		return server, grpcServer, nil
+	}
+
+	tree, err := loadTree(dataFile, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.ProofStore == config.ProofStoreDisk {
+		store, err := merkle.CreateDiskProofStore(cfg.ProofStorePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := tree.GenerateAllProofsInto(store); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate proofs: %w", err)
+		}
+		if err := store.Flush(); err != nil {
+			return nil, nil, err
+		}
+		fmt.Printf(" Stored %d proofs in %s\n", store.Len(), cfg.ProofStorePath)
+		server, err := api.NewAPIServerBuilder().Tree(tree).ProofStore(store).Options(opts...).Build()
+		return server, nil, err
+	}
+
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate proofs: %w", err)
+	}
+
+	server, err := api.NewAPIServerBuilder().Tree(tree).Proofs(proofs).Options(opts...).Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	return server, grpcapi.NewServer(tree, proofs, grpcapi.WithClaimGate(server.ClaimWindowError)), nil
+}
+
+// loadProofSet loads a proofs file, or Uniswap-format claims when format
+// is "uniswap"
+func loadProofSet(proofsFile, format string) (string, *merkle.ProofSet, error) {
+	if format == "uniswap" {
+		file, err := os.Open(proofsFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open claims: %w", err)
+		}
+		defer file.Close()
+
+		root, _, imported, err := data.ImportUniswapFormat(file)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to import %s: %w", proofsFile, err)
+		}
+		proofs := &merkle.ProofSet{Proofs: imported, Metadata: data.UniswapTreeOptions().Metadata()}
+		fmt.Printf(" Imported %d Uniswap-format claims from %s (root %s)\n", proofs.Len(), proofsFile, root)
+		return root, proofs, nil
+	}
+
+	root, proofs, err := data.LoadProofsFile(proofsFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load proofs: %w", err)
+	}
+	fmt.Printf(" Loaded %d proofs from %s (root %s)\n", proofs.Len(), proofsFile, root)
+	return root, proofs, nil
+}
+
+// copyToDiskStore writes proofs, in claim order, to a new disk store at path
+func copyToDiskStore(path string, proofs *merkle.ProofSet) (*merkle.DiskProofStore, error) {
+	store, err := merkle.CreateDiskProofStore(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range proofs.Addresses() {
+		if err := store.Put(common.HexToAddress(address), proofs.Proofs[address]); err != nil {
+			return nil, fmt.Errorf("failed to store proof for %s: %w", address, err)
+		}
+	}
+	if err := store.Flush(); err != nil {
+		return nil, err
+	}
+	fmt.Printf(" Stored %d proofs in %s\n", store.Len(), path)
+	return store, nil
+}
+
+// loadTree builds the tree from a claims CSV. The tree's proofs are also
+// generated on cfg's worker_count goroutines, one per CPU when zero.
+func loadTree(dataFile string, cfg config.MerkleConfig) (*merkle.MerkleTree, error) {
+	opts := data.DefaultLoadOptions()
+	opts.MaxClaims = cfg.MaxClaims
+	claims, err := data.LoadAirdropFromCSVWithOptions(dataFile, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", maxClaimsError(err))
+	}
+	return buildTree(claims, cfg)
+}
+
+// treeOptions returns the encoding of trees built with cfg
+func treeOptions(cfg config.MerkleConfig) merkle.TreeOptions {
+	opts := merkle.DefaultTreeOptions()
+	opts.Workers = cfg.WorkerCount
+	opts.ProofBuffer = cfg.ProofBuffer
+	opts.MaxClaims = cfg.MaxClaims
+	if cfg.Domain != "" {
+		opts.DomainSeparator = merkle.DomainSeparatorFor(cfg.Domain)
+	}
+	return opts
+}
+
+// maxClaimsError points a TooManyClaimsError at the setting that limits
+// the claims
+func maxClaimsError(err error) error {
+	if errors.Is(err, merkle.ErrTooManyClaims) {
+		return fmt.Errorf("%w (max_claims in the merkle config section)", err)
+	}
+	return err
+}
+
+// buildTree builds the served tree from claims on cfg's worker_count
+// goroutines
+func buildTree(claims []merkle.AirdropClaim, cfg config.MerkleConfig) (*merkle.MerkleTree, error) {
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, treeOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tree: %w", maxClaimsError(err))
+	}
+	fmt.Printf(" Built tree with %d claims (root %s)\n", len(tree.Claims), tree.GetRootHash())
+
+	return tree, nil
+}
+
+// openArchive connects to the bucket of archived campaigns
+func openArchive(cfg config.ArchiveConfig) (*archive.S3Archive, error) {
+	var opts []archive.Option
+	if cfg.CacheShards != 0 {
+		opts = append(opts, archive.WithCache(cfg.CacheShards, archive.DefaultCacheTTL))
+	}
+	return archive.NewS3Archive(archive.S3Config{
+		Endpoint: cfg.Endpoint,
+		Bucket:   cfg.Bucket,
+		Region:   cfg.Region,
+		Credentials: archive.Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		},
+	}, opts...)
+}
+
+// loadCampaign reads the campaign file once it exists and the campaign
+// fields of cfg until then
+func loadCampaign(cfg config.CampaignConfig) (api.CampaignMeta, error) {
+	if cfg.File != "" {
+		if _, err := os.Stat(cfg.File); !os.IsNotExist(err) {
+			return api.LoadCampaignFile(cfg.File)
+		}
+	}
+	campaign := api.CampaignMeta{
+		Name:            cfg.Name,
+		TokenSymbol:     cfg.TokenSymbol,
+		ContractAddress: cfg.ContractAddress,
+		ChainID:         cfg.ChainID,
+		Deadline:        cfg.Deadline,
+	}
+	if cfg.ClaimStart != "" || cfg.ClaimEnd != "" {
+		campaign.ClaimWindow = &api.ClaimWindow{Start: cfg.ClaimStart, End: cfg.ClaimEnd}
+	}
+	if err := campaign.Validate(); err != nil {
+		return campaign, fmt.Errorf("invalid campaign config: %w", err)
+	}
+	return campaign, nil
+}
+
+// claimedCacheSize bounds the claimed flags kept by the server
+const claimedCacheSize = 100000
+
+// openFinalization opens the finalization lock at cfg's finalization_file,
+// reporting whether the campaign is finalized
+func openFinalization(cfg *config.Config) (*api.FinalizationLock, error) {
+	lock, err := api.NewFinalizationLock(cfg.Server.FinalizationFile)
+	if err != nil {
+		return nil, err
+	}
+	if record, finalized := lock.Finalization(); finalized {
+		fmt.Printf(" Campaign finalized with root %s at %s by %s; changes are locked\n", record.MerkleRoot, record.FinalizedAt.Format(time.RFC3339), record.Operator)
+	}
+	return lock, nil
+}
+
+// dialClaimStatus connects to the distributor configured in cfg and starts
+// indexing its Claimed events. Claimed flags come from the index once it
+// has caught up with the chain, and from the distributor until then.
+func dialClaimStatus(cfg *config.Config, logger *slog.Logger) (api.ClaimStatus, *indexer.Progress, error) {
+	if !common.IsHexAddress(cfg.Ethereum.ContractAddress) {
+		return nil, nil, fmt.Errorf("invalid contract address %q", cfg.Ethereum.ContractAddress)
+	}
+	contractAddr := common.HexToAddress(cfg.Ethereum.ContractAddress)
+
+	client, err := ethclient.Dial(cfg.Ethereum.RPCURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to %s: %w", cfg.Ethereum.RPCURL, err)
+	}
+
+	status, err := contract.NewClaimStatus(contractAddr, client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to bind distributor: %w", err)
+	}
+	// Claimed flags only ever change from false to true, so a short-lived
+	// cache at worst reports a fresh claim as unclaimed
+	cached := api.NewCachedClaimStatus(status, claimedCacheSize, time.Duration(cfg.Ethereum.ClaimedCacheTTL)*time.Second)
+
+	indexed := &indexedClaimStatus{store: indexer.NewMemoryStore(), progress: new(indexer.Progress), fallback: cached}
+	go func() {
+		err := indexer.WatchClaims(context.Background(), client, contractAddr, indexed.store,
+			indexer.WithStartBlock(cfg.Ethereum.IndexerStartBlock),
+			indexer.WithProgress(indexed.progress),
+			indexer.WithLogger(logger),
+		)
+		indexed.stopped.Store(true)
+		logger.Error("claims indexer stopped; reading claimed flags from the distributor", "error", err)
+	}()
+	return indexed, indexed.progress, nil
+}
+
+// indexedClaimStatus answers from the claims index while it is synced and
+// running, and from fallback otherwise. Like the cache, a dropped
+// connection can briefly report a fresh claim as unclaimed.
+type indexedClaimStatus struct {
+	store    *indexer.MemoryStore
+	progress *indexer.Progress
+	stopped  atomic.Bool
+	fallback api.ClaimStatus
+}
+
+func (s *indexedClaimStatus) IsClaimed(index uint32) (bool, error) {
+	if !s.progress.Synced() || s.stopped.Load() {
+		return s.fallback.IsClaimed(index)
+	}
+	return s.store.IsClaimed(index)
+}
+
+// newLogger writes structured logs to stdout and, when configured, the log
+// file, at a level that config reloads can change
+func newLogger(cfg config.LoggingConfig) (*slog.Logger, *slog.LevelVar, error) {
+	var out io.Writer = os.Stdout
+	if cfg.File != "" {
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		out = io.MultiWriter(os.Stdout, file)
+	}
+
+	level := new(slog.LevelVar)
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, nil, fmt.Errorf("invalid log level: %w", err)
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	if cfg.Format == "text" {
+		return slog.New(slog.NewTextHandler(out, opts)), level, nil
+	}
+	return slog.New(slog.NewJSONHandler(out, opts)), level, nil
+}
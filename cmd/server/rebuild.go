@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	_ "github.com/lib/pq" // Registers the "postgres" driver
+)
+
+// databaseDrivers maps config database types to database/sql driver names
+var databaseDrivers = map[string]string{
+	"postgres": "postgres",
+}
+
+// rebuildQueryTimeout bounds one rebuild's database query
+const rebuildQueryTimeout = 5 * time.Minute
+
+// startRebuilds builds the tree from the configured source and rebuilds it
+// every rebuild_interval, serving each tree with opts. Changed claims are
+// not served while lock, if set, holds.
+func startRebuilds(cfg *config.Config, dataFile string, lock *api.FinalizationLock, logger *slog.Logger, opts ...api.Option) (*rebuild.Scheduler, error) {
+	source, description, err := rebuildSource(cfg, dataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheduler *rebuild.Scheduler
+	build := func(claims []merkle.AirdropClaim) (http.Handler, error) {
+		tree, err := buildTree(claims, cfg.Merkle)
+		if err != nil {
+			return nil, err
+		}
+		proofs, err := tree.GenerateAllProofs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate proofs: %w", err)
+		}
+		server, err := api.NewAPIServer(tree, proofs, append(slices.Clip(opts), api.WithRebuildProgress(scheduler))...)
+		if err != nil {
+			return nil, err
+		}
+		return server.SetupRoutes(), nil
+	}
+
+	interval := time.Duration(cfg.Merkle.RebuildInterval) * time.Second
+	schedulerOpts := []rebuild.Option{rebuild.WithLogger(logger)}
+	if lock != nil {
+		schedulerOpts = append(schedulerOpts, rebuild.WithFinalization(lock))
+	}
+	scheduler = rebuild.New(source, build, interval, schedulerOpts...)
+	if err := scheduler.Start(context.Background()); err != nil {
+		return nil, err
+	}
+	fmt.Printf(" Rebuilding the tree from %s every %v\n", description, interval)
+	return scheduler, nil
+}
+
+// startAppender builds the tree from the claims CSV and serves it with
+// opts, serving a new tree with its proofs whenever admins append claims
+func startAppender(cfg *config.Config, dataFile string, logger *slog.Logger, opts ...api.Option) (*rebuild.Appender, error) {
+	tree, err := loadTree(dataFile, cfg.Merkle)
+	if err != nil {
+		return nil, err
+	}
+
+	var appender *rebuild.Appender
+	serveTree := func(tree *merkle.MerkleTree) (http.Handler, error) {
+		proofs, err := tree.GenerateAllProofs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate proofs: %w", err)
+		}
+		serverOpts := append(slices.Clip(opts), api.WithClaimAppender(appender))
+		if cfg.Server.VersionHistory {
+			serverOpts = append(serverOpts, api.WithTreeHistory(appender))
+		}
+		server, err := api.NewAPIServer(tree, proofs, serverOpts...)
+		if err != nil {
+			return nil, err
+		}
+		// A tree failing its self-test is never swapped in
+		if err := selfTest(server, cfg.Server); err != nil {
+			return nil, err
+		}
+		return server.SetupRoutes(), nil
+	}
+
+	var appenderOpts []rebuild.AppenderOption
+	if cfg.Server.VersionHistory {
+		appenderOpts = append(appenderOpts, rebuild.KeepTrees())
+	}
+	appender = rebuild.NewAppender(serveTree, logger, appenderOpts...)
+	if err := appender.Start(tree); err != nil {
+		return nil, err
+	}
+	return appender, nil
+}
+
+// rebuildSource returns the configured claims source and a description of
+// it: rebuild_query against the database, or the claims CSV
+func rebuildSource(cfg *config.Config, dataFile string) (rebuild.Source, string, error) {
+	if cfg.Merkle.RebuildQuery == "" {
+		opts := data.DefaultLoadOptions()
+		opts.MaxClaims = cfg.Merkle.MaxClaims
+		return func(context.Context) ([]merkle.AirdropClaim, error) {
+			claims, err := data.LoadAirdropFromCSVWithOptions(dataFile, opts)
+			return claims, maxClaimsError(err)
+		}, dataFile, nil
+	}
+
+	driver, ok := databaseDrivers[cfg.Database.Type]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported database type %q for rebuild_query", cfg.Database.Type)
+	}
+	db, err := sql.Open(driver, cfg.GetDatabaseURL())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open database: %w", err)
+	}
+	return func(ctx context.Context) ([]merkle.AirdropClaim, error) {
+		ctx, cancel := context.WithTimeout(ctx, rebuildQueryTimeout)
+		defer cancel()
+		claims, err := data.LoadAirdropFromDBWithLimit(ctx, db, cfg.Merkle.RebuildQuery, cfg.Merkle.MaxClaims)
+		return claims, maxClaimsError(err)
+	}, "the database", nil
+}
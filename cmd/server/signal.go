@@ -0,0 +1,25 @@
+//go:build unix
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"merkle-airdrop/internal/reload"
+)
+
+// watchReloadSignal reloads the config file on every SIGUSR1
+func watchReloadSignal(reloader *reload.Reloader, logger *slog.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for range signals {
+			if _, err := reloader.ReloadConfig(); err != nil {
+				logger.Error("config reload rejected", "error", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package main
+
+import (
+	"log/slog"
+
+	"merkle-airdrop/internal/reload"
+)
+
+// watchReloadSignal does nothing without SIGUSR1; reload the config with
+// POST /api/admin/config/reload instead
+func watchReloadSignal(*reload.Reloader, *slog.Logger) {}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import "@openzeppelin/contracts/token/ERC20/IERC20.sol";
+import "@openzeppelin/contracts/utils/cryptography/MerkleProof.sol";
+
+// MerkleDistributor verifies leaves built exactly like pkg/merkle HashLeaf:
+// keccak256(abi.encodePacked(uint256(uint160(account)), amount, uint32(index)))
+// with sorted-pair internal nodes. Claimed state is a bitmap keyed by index.
+contract MerkleDistributor {
+    IERC20 public immutable token;
+    bytes32 public immutable merkleRoot;
+
+    mapping(uint256 => uint256) private claimedBitMap;
+
+    event Claimed(uint256 index, address account, uint256 amount);
+
+    constructor(IERC20 _token, bytes32 _merkleRoot) {
+        token = _token;
+        merkleRoot = _merkleRoot;
+    }
+
+    function isClaimed(uint256 index) public view returns (bool) {
+        uint256 word = claimedBitMap[index / 256];
+        uint256 mask = 1 << (index % 256);
+        return word & mask == mask;
+    }
+
+    function claim(
+        uint256 index,
+        address account,
+        uint256 amount,
+        bytes32[] calldata merkleProof
+    ) external {
+        require(!isClaimed(index), "Already claimed");
+
+        bytes32 leaf = keccak256(
+            abi.encodePacked(uint256(uint160(account)), amount, uint32(index))
+        );
+        require(
+            MerkleProof.verify(merkleProof, merkleRoot, leaf),
+            "Invalid merkle proof"
+        );
+
+        claimedBitMap[index / 256] |= 1 << (index % 256);
+        require(token.transfer(account, amount), "Transfer failed");
+
+        emit Claimed(index, account, amount);
+    }
+}
@@ -0,0 +1,11 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import "@openzeppelin/contracts/token/ERC20/ERC20.sol";
+
+// TestToken mints its whole supply to the deployer; used by the Go e2e harness.
+contract TestToken is ERC20 {
+    constructor(uint256 initialSupply) ERC20("Test Token", "TEST") {
+        _mint(msg.sender, initialSupply);
+    }
+}
@@ -0,0 +1,378 @@
+// internal/api/abuse.go
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// sequentialProbeRun is the number of consecutive small address
+	// increments from one client that is logged as an enumeration
+	sequentialProbeRun = 3
+	// sequentialProbeStep is the largest increment counted as sequential
+	sequentialProbeStep = 256
+	// abusePruneThreshold is the number of tracked clients above which idle
+	// ones are dropped when a new one is seen
+	abusePruneThreshold = 10000
+)
+
+// AbuseAction is what happens to proof lookups from a throttled client
+type AbuseAction int
+
+const (
+	// AbuseDelay answers after AbuseConfig.Delay plus up to Jitter
+	AbuseDelay AbuseAction = iota
+	// AbuseReject answers 429 until the client's misses leave the window
+	AbuseReject
+)
+
+var abuseActionNames = map[AbuseAction]string{
+	AbuseDelay:  "delay",
+	AbuseReject: "reject",
+}
+
+func (a AbuseAction) String() string {
+	if name, ok := abuseActionNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("AbuseAction(%d)", int(a))
+}
+
+// ParseAbuseAction parses an abuse action name as returned by String
+func ParseAbuseAction(name string) (AbuseAction, error) {
+	for a, n := range abuseActionNames {
+		if n == name {
+			return a, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown abuse action: %q (expected delay or reject)", name)
+}
+
+// AbuseConfig sets when a client enumerating /api/proof is throttled: once
+// more than MaxNotFound of its lookups miss within Window
+type AbuseConfig struct {
+	Window      time.Duration
+	MaxNotFound int
+	Action      AbuseAction
+	Delay       time.Duration // Added to each throttled lookup with AbuseDelay
+	Jitter      time.Duration // Random extra delay, up to this much
+}
+
+// WithAbuseDetection runs /api/proof in strict mode: the zero address is
+// rejected, sequential address probes are logged, and clients whose lookups
+// keep missing are throttled as cfg sets. Clients are told apart by IP; see
+// WithTrustedProxies. The state is served at /api/admin/abuse.
+func WithAbuseDetection(cfg AbuseConfig) Option {
+	return func(s *APIServer) {
+		s.abuse = newAbuseDetector(cfg)
+	}
+}
+
+// abuseDetector tracks proof lookups per client IP
+type abuseDetector struct {
+	cfg  AbuseConfig
+	live *Tunables // Overrides cfg when set
+
+	mu      sync.Mutex
+	clients map[string]*abuseClient
+}
+
+// abuseClient is the recent lookup history of one IP
+type abuseClient struct {
+	misses   []time.Time // Not-found lookups, oldest first
+	lastSeen time.Time
+
+	last       *big.Int // Previous address looked up
+	run        int      // Consecutive sequential increments ending at last
+	probes     int      // Sequential or zero-address lookups flagged
+	limited    int      // Lookups delayed or rejected
+	flaggedRun bool     // The current run has been logged
+}
+
+func newAbuseDetector(cfg AbuseConfig) *abuseDetector {
+	return &abuseDetector{cfg: cfg, clients: make(map[string]*abuseClient)}
+}
+
+// config returns the detector's thresholds
+func (d *abuseDetector) config() AbuseConfig {
+	if d.live != nil {
+		return d.live.Abuse()
+	}
+	return d.cfg
+}
+
+// WithTrustedProxies takes client IPs from X-Forwarded-For on requests
+// whose remote address is in one of proxies, such as an ingress in front
+// of the server. Other requests are told apart by remote address, since
+// any client can set the header.
+func WithTrustedProxies(proxies []netip.Prefix) Option {
+	return func(s *APIServer) {
+		s.trustedProxies = proxies
+	}
+}
+
+// clientIP returns the IP of r's client: its remote address, or, when that
+// is a trusted proxy, the right-most X-Forwarded-For entry that isn't one.
+// Entries further left were written by the client and are ignored.
+func (s *APIServer) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !s.trustedProxy(host) {
+		return host
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break // The nearest trusted hop is the best known
+		}
+		host = hop
+		if !s.trustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trustedProxy reports whether ip is in one of the trusted proxy ranges
+func (s *APIServer) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// client returns ip's history, creating it. The caller holds d.mu.
+func (d *abuseDetector) client(ip string, now time.Time) *abuseClient {
+	c, ok := d.clients[ip]
+	if !ok {
+		if len(d.clients) >= abusePruneThreshold {
+			d.prune(now)
+		}
+		c = &abuseClient{}
+		d.clients[ip] = c
+	}
+	c.lastSeen = now
+	return c
+}
+
+// prune drops clients without misses in the window. The caller holds d.mu.
+func (d *abuseDetector) prune(now time.Time) {
+	window := d.config().Window
+	for ip, c := range d.clients {
+		if c.expire(now, window) == 0 && now.Sub(c.lastSeen) > window {
+			delete(d.clients, ip)
+		}
+	}
+}
+
+// expire drops misses older than window and returns the rest's count
+func (c *abuseClient) expire(now time.Time, window time.Duration) int {
+	cutoff := now.Add(-window)
+	i := sort.Search(len(c.misses), func(i int) bool { return c.misses[i].After(cutoff) })
+	c.misses = c.misses[i:]
+	return len(c.misses)
+}
+
+// throttle reports whether ip is over the not-found threshold, counting
+// the lookup as throttled if it is
+func (d *abuseDetector) throttle(ip string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cfg := d.config()
+	c := d.client(ip, now)
+	if c.expire(now, cfg.Window) <= cfg.MaxNotFound {
+		return false
+	}
+	c.limited++
+	return true
+}
+
+// delay returns how long a throttled lookup waits with AbuseDelay
+func (d *abuseDetector) delay() time.Duration {
+	cfg := d.config()
+	if cfg.Jitter <= 0 {
+		return cfg.Delay
+	}
+	return cfg.Delay + rand.N(cfg.Jitter)
+}
+
+// observe records a lookup of address by ip and reports whether it
+// completes a run of sequential addresses that should be logged
+func (d *abuseDetector) observe(ip string, address common.Address, found bool, now time.Time) (sequential bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c := d.client(ip, now)
+	if !found {
+		c.misses = append(c.misses, now)
+	}
+
+	value := new(big.Int).SetBytes(address.Bytes())
+	if c.last != nil {
+		step := new(big.Int).Sub(value, c.last)
+		if step.Sign() > 0 && step.Cmp(big.NewInt(sequentialProbeStep)) <= 0 {
+			c.run++
+		} else {
+			c.run, c.flaggedRun = 0, false
+		}
+	}
+	c.last = value
+
+	if c.run >= sequentialProbeRun && !c.flaggedRun {
+		c.flaggedRun = true
+		c.probes++
+		return true
+	}
+	return false
+}
+
+// flagZero records a zero-address lookup by ip
+func (d *abuseDetector) flagZero(ip string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.client(ip, now).probes++
+}
+
+// snapshot returns the clients with recent activity, most misses first
+func (d *abuseDetector) snapshot(now time.Time) []AbuseClientStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.prune(now)
+	cfg := d.config()
+	clients := make([]AbuseClientStatus, 0, len(d.clients))
+	for ip, c := range d.clients {
+		misses := c.expire(now, cfg.Window)
+		clients = append(clients, AbuseClientStatus{
+			IP:        ip,
+			NotFound:  misses,
+			Throttled: misses > cfg.MaxNotFound,
+			Probes:    c.probes,
+			Limited:   c.limited,
+			LastSeen:  c.lastSeen.UTC(),
+		})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if clients[i].NotFound != clients[j].NotFound {
+			return clients[i].NotFound > clients[j].NotFound
+		}
+		return clients[i].IP < clients[j].IP
+	})
+	return clients
+}
+
+// reset forgets ip, or every client when ip is empty, reporting how many
+// were dropped
+func (d *abuseDetector) reset(ip string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if ip == "" {
+		n := len(d.clients)
+		clear(d.clients)
+		return n
+	}
+	if _, ok := d.clients[ip]; !ok {
+		return 0
+	}
+	delete(d.clients, ip)
+	return 1
+}
+
+// checkAbuse throttles r's client if it is over the not-found threshold.
+// It returns false when the lookup should go no further: it was rejected,
+// or the client went away while delayed.
+func (s *APIServer) checkAbuse(w http.ResponseWriter, r *http.Request) bool {
+	ip := s.clientIP(r)
+	if !s.abuse.throttle(ip, s.now()) {
+		return true
+	}
+
+	if cfg := s.abuse.config(); cfg.Action == AbuseReject {
+		s.requestLogger(r).Warn("proof lookup rejected", "ip", ip)
+		w.Header().Set("Retry-After", strconv.Itoa(int(cfg.Window.Seconds())))
+		writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many lookups of addresses not in the airdrop")
+		return false
+	}
+
+	timer := time.NewTimer(s.abuse.delay())
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// checkZeroAddress answers 400 for lookups of the zero address, which no
+// claimant holds, counting them as probes. It reports whether the lookup
+// may go ahead.
+func (s *APIServer) checkZeroAddress(w http.ResponseWriter, r *http.Request, address common.Address) bool {
+	if address != (common.Address{}) {
+		return true
+	}
+	s.abuse.flagZero(s.clientIP(r), s.now())
+	s.requestLogger(r).Warn("zero address lookup", "ip", s.clientIP(r))
+	writeError(w, http.StatusBadRequest, CodeInvalidAddress, "The zero address cannot claim")
+	return false
+}
+
+// observeLookup records a lookup of address by r's client, logging it
+// when it extends a run of increasing addresses
+func (s *APIServer) observeLookup(r *http.Request, address common.Address, found bool) {
+	if s.abuse.observe(s.clientIP(r), address, found, s.now()) {
+		s.requestLogger(r).Warn("sequential address probe", "ip", s.clientIP(r), "address", address.Hex())
+	}
+}
+
+// GetAbuse lists the clients tracked by abuse detection, and DELETE
+// forgets them: all of them, or the one given by ?ip=
+func (s *APIServer) GetAbuse(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		cfg := s.abuse.config()
+		writeJSON(w, http.StatusOK, AbuseResponse{
+			Window:      cfg.Window.String(),
+			MaxNotFound: cfg.MaxNotFound,
+			Action:      cfg.Action.String(),
+			Clients:     s.abuse.snapshot(s.now()),
+			Success:     true,
+		})
+	case http.MethodDelete:
+		ip := r.URL.Query().Get("ip")
+		reset := s.abuse.reset(ip)
+		s.requestLogger(r).Info("abuse state reset", "ip", ip, "clients", reset)
+		writeJSON(w, http.StatusOK, AbuseResetResponse{Reset: reset, Success: true})
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
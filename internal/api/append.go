@@ -0,0 +1,181 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ClaimAppender adds claims to the served tree for POST /api/admin/claims
+type ClaimAppender interface {
+	// AppendClaims serves a tree of the served claims and added from then
+	// on. On error the served tree is kept and none of added is served.
+	AppendClaims(added []merkle.AirdropClaim) (AppendResult, error)
+	// TreeVersions lists the trees served since startup, oldest first
+	TreeVersions() []TreeVersion
+}
+
+// AppendResult is a tree a ClaimAppender swapped in
+type AppendResult struct {
+	Tree     *merkle.MerkleTree
+	Version  TreeVersion // The new tree's
+	Previous TreeVersion // The tree it replaced
+}
+
+// TreeVersion is one of the trees served since startup
+type TreeVersion struct {
+	Version     int       `json:"version"` // From 1 for the tree served at startup
+	MerkleRoot  string    `json:"merkleRoot"`
+	TotalClaims int       `json:"totalClaims"`
+	Added       int       `json:"added"` // Claims appended to the previous version
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// WithClaimAppender lets admins add claims with POST /api/admin/claims and
+// list the served trees at /api/admin/versions
+func WithClaimAppender(appender ClaimAppender) Option {
+	return func(s *APIServer) {
+		s.appender = appender
+	}
+}
+
+// AppendClaims adds the claims in the request body to the tree, all of
+// them or none, answering with the new root and their proofs
+func (s *APIServer) AppendClaims(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if !s.checkNotFinalized(w) {
+		return
+	}
+
+	var req AppendClaimsRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+	if len(req.Claims) == 0 {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "No claims to append")
+		return
+	}
+
+	added := make([]merkle.AirdropClaim, len(req.Claims))
+	seen := make(map[common.Address]bool, len(req.Claims))
+	for i, entry := range req.Claims {
+		claim, code, message := parseClaimEntry(entry)
+		if code != "" {
+			writeError(w, http.StatusBadRequest, code, fmt.Sprintf("Claim %d: %s", i, message))
+			return
+		}
+		if seen[claim.Address] {
+			writeError(w, http.StatusBadRequest, CodeDuplicateAddress, fmt.Sprintf("Claim %d: %s is given twice", i, claim.Address.Hex()))
+			return
+		}
+		seen[claim.Address] = true
+		added[i] = claim
+	}
+	if err := merkle.CheckAmountBits(added, s.options.MaxAmountBits); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidAmount, err.Error())
+		return
+	}
+
+	result, err := s.appender.AppendClaims(added)
+	if writeClaimExists(w, err) {
+		return
+	}
+	if errors.Is(err, merkle.ErrTooManyClaims) {
+		writeError(w, http.StatusUnprocessableEntity, CodeTooManyClaims, "Appending "+strconv.Itoa(len(added))+" claims would exceed the claim limit: "+err.Error())
+		return
+	}
+	if err != nil {
+		s.requestLogger(r).Error("claim append failed", "claims", len(added), "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to append claims")
+		return
+	}
+
+	response := AppendClaimsResponse{
+		MerkleRoot:   result.Version.MerkleRoot,
+		PreviousRoot: result.Previous.MerkleRoot,
+		Version:      result.Version.Version,
+		Claims:       make([]ProofResponse, len(added)),
+		Success:      true,
+	}
+	for i, claim := range added {
+		proof, err := result.Tree.GenerateProof(claim.Address)
+		if err != nil {
+			s.requestLogger(r).Error("proof generation failed", "address", claim.Address.Hex(), "error", err)
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Claims were appended but their proofs failed")
+			return
+		}
+		response.Claims[i] = ProofResponse{
+			Address:      claim.Address.Hex(),
+			Proof:        proof.Proof,
+			Amount:       proof.Amount,
+			Index:        proof.Index,
+			MerkleRoot:   response.MerkleRoot,
+			PaddingCount: proof.PaddingCount,
+			Success:      true,
+		}
+		if !s.options.SortedPairs {
+			response.Claims[i].Positions = &proof.Positions
+		}
+	}
+
+	s.requestLogger(r).Info("claims appended", "claims", len(added), "version", response.Version, "root", response.MerkleRoot)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseClaimEntry parses an entry of a request that adds a claim,
+// returning the error code and message to answer with when it is invalid
+func parseClaimEntry(entry AppendClaimEntry) (merkle.AirdropClaim, string, string) {
+	if !common.IsHexAddress(entry.Address) {
+		return merkle.AirdropClaim{}, CodeInvalidAddress, "invalid address format"
+	}
+	address := common.HexToAddress(entry.Address)
+	if address == (common.Address{}) {
+		return merkle.AirdropClaim{}, CodeInvalidAddress, "the zero address cannot claim"
+	}
+	amount, ok := new(big.Int).SetString(entry.Amount, 10)
+	if !ok || amount.Sign() <= 0 {
+		return merkle.AirdropClaim{}, CodeInvalidAmount, "amount must be a positive base-10 integer"
+	}
+	return merkle.AirdropClaim{Address: address, Amount: amount}, "", ""
+}
+
+// writeClaimExists answers 409 with the tree's claims when err is an
+// ExistingClaimError, reporting whether it did
+func writeClaimExists(w http.ResponseWriter, err error) bool {
+	var existingErr *merkle.ExistingClaimError
+	if !errors.As(err, &existingErr) {
+		return false
+	}
+	existing := make([]ExistingClaim, len(existingErr.Claims))
+	for i, claim := range existingErr.Claims {
+		existing[i] = ExistingClaim{Address: claim.Address.Hex(), Amount: claim.Amount.String(), Index: claim.Index}
+	}
+	writeJSON(w, http.StatusConflict, ClaimExistsResponse{
+		newErrorResponse(w, CodeClaimExists, existingErr.Error()),
+		existing,
+	})
+	return true
+}
+
+// GetTreeVersions lists the trees served since startup
+func (s *APIServer) GetTreeVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TreeVersionsResponse{
+		Versions: s.appender.TreeVersions(),
+		Success:  true,
+	})
+}
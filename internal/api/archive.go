@@ -0,0 +1,101 @@
+// internal/api/archive.go
+package api
+
+import (
+	"errors"
+	"math/big"
+	"net/http"
+
+	"merkle-airdrop/pkg/archive"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// WithArchive serves the campaigns in store alongside the one in memory:
+// /api/root and /api/proof/{address} take ?campaign=, answering for the
+// archived campaign of that name unless it is campaign, the one served.
+// Archived proofs are handed out without reservation.
+func WithArchive(store archive.ProofArchive, campaign string) Option {
+	return func(s *APIServer) {
+		s.archive = store
+		s.servedCampaign = campaign
+	}
+}
+
+// archivedCampaign returns the campaign r asks for when it is an archived
+// one rather than the served campaign
+func (s *APIServer) archivedCampaign(r *http.Request) (string, bool) {
+	if s.archive == nil {
+		return "", false
+	}
+	campaign := r.URL.Query().Get("campaign")
+	if campaign == "" || campaign == s.servedCampaign {
+		return "", false
+	}
+	return campaign, true
+}
+
+// writeArchiveError answers for an archive lookup that failed
+func (s *APIServer) writeArchiveError(w http.ResponseWriter, r *http.Request, campaign string, err error) {
+	switch {
+	case errors.Is(err, archive.ErrCampaignNotFound):
+		writeError(w, http.StatusNotFound, CodeCampaignNotFound, "Campaign not found")
+	case errors.Is(err, merkle.ErrAddressNotFound):
+		writeError(w, http.StatusNotFound, CodeAddressNotFound, "Address not found in airdrop")
+	default:
+		s.requestLogger(r).Error("archive lookup failed", "campaign", campaign, "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read the archive")
+	}
+}
+
+// getArchivedRoot answers GET /api/root for an archived campaign
+func (s *APIServer) getArchivedRoot(w http.ResponseWriter, r *http.Request, campaign string) {
+	root, err := s.archive.GetRoot(r.Context(), campaign)
+	if err != nil {
+		s.writeArchiveError(w, r, campaign, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, RootResponse{
+		MerkleRoot: root.MerkleRoot,
+		Metadata:   root.Metadata,
+		Campaign:   campaign,
+		Success:    true,
+	})
+}
+
+// getArchivedProof answers GET /api/proof/{address} for an archived
+// campaign
+func (s *APIServer) getArchivedProof(w http.ResponseWriter, r *http.Request, campaign string, address common.Address, outputCase data.AddressCase, unit data.AmountUnit, display bool) {
+	root, err := s.archive.GetRoot(r.Context(), campaign)
+	if err != nil {
+		s.writeArchiveError(w, r, campaign, err)
+		return
+	}
+	proof, err := s.archive.GetProof(r.Context(), campaign, address)
+	if err != nil {
+		s.writeArchiveError(w, r, campaign, err)
+		return
+	}
+
+	response := ProofResponse{
+		Address:    outputCase.Format(address),
+		Proof:      proof.Proof,
+		Amount:     proof.Amount,
+		Index:      proof.Index,
+		MerkleRoot: root.MerkleRoot,
+		Campaign:   campaign,
+		Success:    true,
+
+		PaddingCount: proof.PaddingCount,
+	}
+	if !root.Metadata.SortedPairs {
+		positions := proof.Positions
+		response.Positions = &positions
+	}
+	if amount, valid := new(big.Int).SetString(proof.Amount, 10); display && valid {
+		response.AmountDisplay = unit.Format(amount, s.tokenDecimals)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
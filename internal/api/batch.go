@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"merkle-airdrop/pkg/data"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxBatchEntries bounds the entries of one /api/verify/batch request
+const MaxBatchEntries = 10000
+
+// maxBatchBodyBytes bounds a /api/verify/batch body, well above what
+// MaxBatchEntries real entries take
+const maxBatchBodyBytes = 64 << 20
+
+// ndjsonContentType is the media type of /api/verify/batch bodies
+const ndjsonContentType = "application/x-ndjson"
+
+// WithWorkers sets the goroutines verifying /api/verify/batch entries,
+// one per CPU when zero
+func WithWorkers(workers int) Option {
+	return func(s *APIServer) {
+		s.workers = workers
+	}
+}
+
+// flushWriter flushes each write so results reach the client as they are
+// verified
+type flushWriter struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		f.rc.Flush()
+	}
+	return n, err
+}
+
+// VerifyBatch verifies an NDJSON stream of proofs against the served root,
+// streaming back one result line per entry in input order. A stream that
+// can't be read to the end gets a last line with the ErrorResponse.
+func (s *APIServer) VerifyBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	// Entries for one address could try amounts faster than /api/verify allows
+	if s.privacyDisabled(w, "Batch verifications") {
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != ndjsonContentType {
+		writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be "+ndjsonContentType)
+		return
+	}
+
+	// Results are written while the body is still being read
+	rc := http.NewResponseController(w)
+	rc.EnableFullDuplex()
+	body := http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)
+	w.Header().Set("Content-Type", ndjsonContentType)
+	w.WriteHeader(http.StatusOK)
+
+	out := flushWriter{w: w, rc: rc}
+	summary, err := data.VerifyBatch(body, out, s.rootBytes, s.options, data.BatchOptions{
+		Workers:    s.workers,
+		MaxEntries: MaxBatchEntries,
+		Lookup:     s.batchLookup(),
+	})
+	logger := s.requestLogger(r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		code := CodeInvalidRequest
+		if errors.Is(err, data.ErrTooManyEntries) || errors.As(err, &tooLarge) {
+			code = CodePayloadTooLarge
+		}
+		logger.Info("batch verification stopped", "entries", summary.Entries, "error", err)
+		json.NewEncoder(out).Encode(newErrorResponse(w, code, err.Error()))
+		return
+	}
+	logger.Info("batch verified", "entries", summary.Entries, "valid", summary.Valid, "invalid", summary.Invalid)
+}
+
+// batchLookup returns the recorded index and positions of addresses for
+// entries that leave them out, or nil without claims
+func (s *APIServer) batchLookup() func(common.Address) (uint32, uint64, bool) {
+	if s.verifyOnly {
+		return nil
+	}
+	return func(address common.Address) (uint32, uint64, bool) {
+		index, exists := s.findClaim(address)
+		if !exists || s.options.SortedPairs {
+			return index, 0, exists
+		}
+		proof, exists, err := s.lookupProof(address)
+		if err != nil || !exists {
+			return 0, 0, false
+		}
+		return proof.Index, proof.Positions, true
+	}
+}
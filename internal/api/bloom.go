@@ -0,0 +1,63 @@
+// internal/api/bloom.go
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// bloomMaxAge is how long clients may use a served filter before
+// revalidating it against its ETag
+const bloomMaxAge = "300"
+
+// bloomFilter is the encoded allowlist filter served at /api/bloom
+type bloomFilter struct {
+	encoded []byte
+	etag    string
+}
+
+// WithBloomFilter serves a Bloom filter of the airdrop's addresses at
+// /api/bloom, sized for falsePositiveRate when the server is created
+func WithBloomFilter(falsePositiveRate float64) Option {
+	return func(s *APIServer) {
+		s.bloomRate = falsePositiveRate
+	}
+}
+
+// buildBloomFilter builds the filter over addresses, leaving /api/bloom
+// disabled if it can't be built
+func (s *APIServer) buildBloomFilter(addresses []common.Address) {
+	claims := make([]merkle.AirdropClaim, len(addresses))
+	for i, address := range addresses {
+		claims[i].Address = address
+	}
+	filter, err := merkle.BuildBloomFilter(claims, s.bloomRate)
+	if err != nil {
+		s.logger.Error("bloom filter disabled", "error", err)
+		return
+	}
+	encoded, _ := filter.MarshalBinary()
+	sum := sha256.Sum256(encoded)
+	s.bloom = &bloomFilter{encoded: encoded, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+}
+
+// GetBloomFilter serves the encoded filter for client-side eligibility
+// pre-checks. Conditional requests with the ETag answer 304.
+func (s *APIServer) GetBloomFilter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "public, max-age="+bloomMaxAge)
+	w.Header().Set("ETag", s.bloom.etag)
+	http.ServeContent(w, r, "allowlist.bloom", time.Time{}, bytes.NewReader(s.bloom.encoded))
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// DefaultMaxBodyBytes bounds request bodies unless WithMaxBodyBytes is set
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// WithMaxBodyBytes bounds the JSON bodies of POST requests to limit bytes,
+// answering 413 beyond it
+func WithMaxBodyBytes(limit int64) Option {
+	return func(s *APIServer) {
+		s.maxBodyBytes = limit
+	}
+}
+
+// decodeJSONBody decodes the application/json body of r into v, rejecting
+// oversized bodies, unknown fields and data after the value. It writes the error response and
+// returns false when the body is unacceptable.
+func (s *APIServer) decodeJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	}
+
+	limit := s.maxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, limit))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		// Anything after the value, even another value, is rejected
+		var extra json.RawMessage
+		if err = decoder.Decode(&extra); errors.Is(err, io.EOF) {
+			return true
+		} else if err == nil {
+			err = errors.New("unexpected data after the JSON value")
+		}
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Request body exceeds the limit")
+		return false
+	}
+	writeError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid JSON: "+err.Error())
+	return false
+}
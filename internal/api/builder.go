@@ -0,0 +1,165 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+var (
+	// ErrNoTree is a server constructor given a nil tree or one without a root
+	ErrNoTree = errors.New("no tree to serve")
+	// ErrNoProofs is a server constructor given no proofs for its tree
+	ErrNoProofs = errors.New("no proofs to serve")
+)
+
+// notInitializedMessage explains the 503 of a server not made by a
+// constructor
+const notInitializedMessage = "Server not initialized: create it with NewAPIServer or APIServerBuilder"
+
+// checkTree rejects a tree a server can't be built around
+func checkTree(tree *merkle.MerkleTree) error {
+	if tree == nil {
+		return fmt.Errorf("%w: the tree is nil", ErrNoTree)
+	}
+	if tree.Root == nil || len(tree.Claims) == 0 {
+		return fmt.Errorf("%w: the tree has no root; build it with merkle.NewMerkleTree", ErrNoTree)
+	}
+	return nil
+}
+
+// parseRoot decodes a served root, which must be a 0x-prefixed 32-byte hash
+func parseRoot(root string) ([]byte, error) {
+	decoded, err := merkle.DecodeHash(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	return decoded, nil
+}
+
+// initialized reports whether s was made by a constructor; a server
+// declared as a literal has no root and answers every request with 503
+func (s *APIServer) initialized() bool {
+	return len(s.rootBytes) != 0
+}
+
+// notInitialized answers the requests of a server without a root
+func notInitialized(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusServiceUnavailable, CodeNotInitialized, notInitializedMessage)
+}
+
+// APIServerBuilder assembles a server from a tree, its proofs or proof
+// store, and the campaign, checking that they fit together when Build is
+// called. Without a tree it serves an exported proof set as
+// NewAPIServerFromStore does.
+type APIServerBuilder struct {
+	tree     *merkle.MerkleTree
+	store    merkle.ProofStore
+	root     string // Of the proofs without a tree
+	metadata *merkle.TreeMetadata
+
+	campaign     *CampaignMeta
+	campaignPath string
+
+	opts []Option
+}
+
+// NewAPIServerBuilder returns a builder with nothing set
+func NewAPIServerBuilder() *APIServerBuilder {
+	return &APIServerBuilder{}
+}
+
+// Tree sets the tree to serve
+func (b *APIServerBuilder) Tree(tree *merkle.MerkleTree) *APIServerBuilder {
+	b.tree = tree
+	return b
+}
+
+// Proofs sets the proofs to serve, by address
+func (b *APIServerBuilder) Proofs(proofs map[string]*merkle.MerkleProof) *APIServerBuilder {
+	if proofs == nil {
+		b.store = nil
+		return b
+	}
+	b.store = merkle.MapProofStore(proofs)
+	return b
+}
+
+// ProofStore sets the store of the proofs to serve, which may be on disk
+func (b *APIServerBuilder) ProofStore(store merkle.ProofStore) *APIServerBuilder {
+	b.store = store
+	return b
+}
+
+// ProofSet sets an exported proof set and its root. With a tree, they must
+// be the tree's.
+func (b *APIServerBuilder) ProofSet(root string, proofs *merkle.ProofSet) *APIServerBuilder {
+	b.root = root
+	if proofs == nil {
+		b.store, b.metadata = nil, nil
+		return b
+	}
+	b.store = merkle.MapProofStore(proofs.Proofs)
+	b.metadata = &proofs.Metadata
+	return b
+}
+
+// Root sets the root and leaf encoding of a proof store served without a
+// tree
+func (b *APIServerBuilder) Root(root string, metadata merkle.TreeMetadata) *APIServerBuilder {
+	b.root, b.metadata = root, &metadata
+	return b
+}
+
+// Campaign serves meta at /api/campaign as WithCampaign does, saving
+// updates to path unless it is empty
+func (b *APIServerBuilder) Campaign(meta CampaignMeta, path string) *APIServerBuilder {
+	b.campaign, b.campaignPath = &meta, path
+	return b
+}
+
+// Options adds options, applied in order after the campaign
+func (b *APIServerBuilder) Options(opts ...Option) *APIServerBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build checks the parts and creates the server
+func (b *APIServerBuilder) Build() (*APIServer, error) {
+	opts := b.opts
+	if b.campaign != nil {
+		if err := b.campaign.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid campaign: %w", err)
+		}
+		opts = append([]Option{WithCampaign(*b.campaign, b.campaignPath)}, opts...)
+	}
+	if b.store == nil {
+		return nil, fmt.Errorf("%w: set proofs, a proof set or a proof store", ErrNoProofs)
+	}
+	if b.store.Len() == 0 {
+		return nil, fmt.Errorf("%w: the proofs are empty", ErrNoProofs)
+	}
+
+	if b.tree == nil {
+		if b.root == "" || b.metadata == nil {
+			return nil, fmt.Errorf("%w: set a tree, or the root and metadata of the proofs", ErrNoTree)
+		}
+		return NewAPIServerFromStore(b.root, b.store, *b.metadata, opts...)
+	}
+	if err := checkTree(b.tree); err != nil {
+		return nil, err
+	}
+	if b.root != "" && !strings.EqualFold(b.root, b.tree.GetRootHash()) {
+		return nil, fmt.Errorf("the proofs are for root %s, not the tree's %s", b.root, b.tree.GetRootHash())
+	}
+	if b.metadata != nil && *b.metadata != b.tree.Metadata() {
+		return nil, fmt.Errorf("the proofs' metadata %+v is not the tree's %+v", *b.metadata, b.tree.Metadata())
+	}
+	if b.store.Len() != b.tree.AddressCount() {
+		return nil, fmt.Errorf("%d proofs for the tree's %d addresses", b.store.Len(), b.tree.AddressCount())
+	}
+	return NewAPIServerWithStore(b.tree, b.store, opts...)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"container/list"
+	"sync"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultProofCacheSize is the number of proofs a lazy server keeps when no
+// size is given
+const DefaultProofCacheSize = 10000
+
+// proofCache is a fixed-size LRU of generated proofs, safe for concurrent use
+type proofCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // Front is most recently used
+	entries  map[common.Address]*list.Element
+
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	address common.Address
+	proof   *merkle.MerkleProof
+}
+
+// CacheStats reports proof cache usage
+type CacheStats struct {
+	Size     int    `json:"size"`
+	Capacity int    `json:"capacity"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+}
+
+func newProofCache(capacity int) *proofCache {
+	if capacity <= 0 {
+		capacity = DefaultProofCacheSize
+	}
+	return &proofCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[common.Address]*list.Element, capacity),
+	}
+}
+
+// get returns the cached proof for address, counting a hit or miss
+func (c *proofCache) get(address common.Address) (*merkle.MerkleProof, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[address]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).proof, true
+}
+
+// add stores proof, evicting the least recently used entry when full
+func (c *proofCache) add(address common.Address, proof *merkle.MerkleProof) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[address]; ok {
+		c.order.MoveToFront(elem)
+		elem.Value.(*cacheEntry).proof = proof
+		return
+	}
+
+	c.entries[address] = c.order.PushFront(&cacheEntry{address: address, proof: proof})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).address)
+	}
+}
+
+func (c *proofCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Size:     c.order.Len(),
+		Capacity: c.capacity,
+		Hits:     c.hits,
+		Misses:   c.misses,
+	}
+}
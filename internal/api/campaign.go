@@ -0,0 +1,176 @@
+// internal/api/campaign.go
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"merkle-airdrop/internal/fsutil"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CampaignMeta is the campaign-level information frontends show alongside
+// proofs, served at /api/campaign
+type CampaignMeta struct {
+	Name            string `json:"name"` // Display name
+	TokenSymbol     string `json:"tokenSymbol,omitempty"`
+	ContractAddress string `json:"contractAddress,omitempty"` // Distributor
+	ChainID         uint64 `json:"chainId,omitempty"`
+	Deadline        string `json:"deadline,omitempty"` // Claim deadline, RFC3339
+
+	// ClaimWindow keeps proofs from being served outside it
+	ClaimWindow *ClaimWindow `json:"claimWindow,omitempty"`
+}
+
+// Validate checks that the campaign has a name, that its contract address,
+// deadline and claim window are well-formed, and that a contract comes with
+// its chain
+func (m CampaignMeta) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("campaign name is required")
+	}
+	if m.ContractAddress != "" {
+		if !common.IsHexAddress(m.ContractAddress) {
+			return fmt.Errorf("invalid campaign contract address: %s", m.ContractAddress)
+		}
+		if m.ChainID == 0 {
+			return fmt.Errorf("campaign chain ID is required with a contract address")
+		}
+	}
+	if m.Deadline != "" {
+		if _, err := time.Parse(time.RFC3339, m.Deadline); err != nil {
+			return fmt.Errorf("campaign deadline must be RFC3339: %w", err)
+		}
+	}
+	if m.ClaimWindow != nil {
+		if err := m.ClaimWindow.Validate(); err != nil {
+			return fmt.Errorf("campaign %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadCampaignFile reads and validates a campaign.json
+func LoadCampaignFile(path string) (CampaignMeta, error) {
+	var meta CampaignMeta
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return meta, fmt.Errorf("failed to read campaign file: %w", err)
+	}
+	if err := json.Unmarshal(content, &meta); err != nil {
+		return meta, fmt.Errorf("failed to decode campaign file %s: %w", path, err)
+	}
+	if err := meta.Validate(); err != nil {
+		return meta, fmt.Errorf("%s: %w", path, err)
+	}
+	return meta, nil
+}
+
+// SaveCampaignFile writes meta to path atomically
+func SaveCampaignFile(path string, meta CampaignMeta) error {
+	return fsutil.AtomicWriteFile(path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(meta)
+	})
+}
+
+// campaign is the served campaign, replaced by admin updates
+type campaign struct {
+	mu   sync.RWMutex
+	meta CampaignMeta
+	path string // Updates are saved here; kept in memory only when empty
+}
+
+func (c *campaign) load() CampaignMeta {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.meta
+}
+
+// update saves meta, then serves it. A failed save keeps the old campaign.
+func (c *campaign) update(meta CampaignMeta) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path != "" {
+		if err := SaveCampaignFile(c.path, meta); err != nil {
+			return err
+		}
+	}
+	c.meta = meta
+	return nil
+}
+
+// setClaimWindow replaces the campaign's claim window in memory
+func (c *campaign) setClaimWindow(window *ClaimWindow) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.meta.ClaimWindow = window
+}
+
+// WithCampaign serves meta at /api/campaign and lets admins replace it
+// with PUT /api/admin/campaign, saving updates to path when it is set.
+// Servers created with the same option share the campaign.
+func WithCampaign(meta CampaignMeta, path string) Option {
+	c := &campaign{meta: meta, path: path}
+	return func(s *APIServer) {
+		s.campaign = c
+	}
+}
+
+// GetCampaign returns the campaign with the tree's root and claim count
+func (s *APIServer) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CampaignResponse{
+		Campaign:    s.campaign.load(),
+		MerkleRoot:  s.root,
+		TotalClaims: s.totalClaims(),
+		BasePath:    s.basePath,
+
+		Finalization: s.finalized(),
+
+		Success: true,
+	})
+}
+
+// UpdateCampaign replaces the campaign with the one in the request body
+func (s *APIServer) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w, http.MethodPut)
+		return
+	}
+	if !s.checkNotFinalized(w) {
+		return
+	}
+
+	var meta CampaignMeta
+	if !s.decodeJSONBody(w, r, &meta) {
+		return
+	}
+	if err := meta.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidCampaign, err.Error())
+		return
+	}
+	if err := s.campaign.update(meta); err != nil {
+		s.requestLogger(r).Error("campaign update failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save campaign")
+		return
+	}
+
+	s.requestLogger(r).Info("campaign updated", "name", meta.Name)
+	writeJSON(w, http.StatusOK, CampaignUpdateResponse{
+		Campaign: meta,
+		Success:  true,
+	})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"time"
+
+	"merkle-airdrop/internal/cache"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// cachedClaimStatus remembers claimed flags for a while, so repeated
+// lookups of an index don't each cost an RPC call
+type cachedClaimStatus struct {
+	status  ClaimStatus
+	claimed *cache.LRU[uint32, bool]
+}
+
+// NewCachedClaimStatus wraps status, keeping up to size answers for ttl.
+// A claim made meanwhile shows up once its cached answer expires.
+func NewCachedClaimStatus(status ClaimStatus, size int, ttl time.Duration) ClaimStatus {
+	return &cachedClaimStatus{status: status, claimed: cache.New[uint32, bool](size, ttl)}
+}
+
+func (c *cachedClaimStatus) IsClaimed(index uint32) (bool, error) {
+	if claimed, ok := c.claimed.Get(index); ok {
+		return claimed, nil
+	}
+	claimed, err := c.status.IsClaimed(index)
+	if err != nil {
+		return false, err
+	}
+	c.claimed.Add(index, claimed)
+	return claimed, nil
+}
+
+// WithClaimedCheck makes /api/verify report whether a valid proof's index
+// was already claimed on the distributor at contractAddress, read through
+// status
+func WithClaimedCheck(status ClaimStatus, contractAddress common.Address) Option {
+	return func(s *APIServer) {
+		s.claimedCheck = status
+		s.claimedContract = contractAddress
+	}
+}
+
+// IndexerProgress reports how far a claims indexer, such as pkg/indexer's,
+// has read the chain
+type IndexerProgress interface {
+	IndexedBlock() uint64
+	HeadBlock() uint64
+	Lag() uint64
+	Synced() bool
+}
+
+// WithIndexerProgress adds the indexed block, head block and lag of the
+// claims indexer to /api/stats
+func WithIndexerProgress(progress IndexerProgress) Option {
+	return func(s *APIServer) {
+		s.indexer = progress
+	}
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how response bodies are compressed
+type Compression int
+
+const (
+	// CompressionOff sends every body as it is
+	CompressionOff Compression = iota
+	// CompressionGzip gzips bodies for clients accepting gzip
+	CompressionGzip
+	// CompressionAuto uses zstd or gzip, whichever the client prefers,
+	// zstd on a tie
+	CompressionAuto
+)
+
+var compressionNames = map[Compression]string{
+	CompressionOff:  "off",
+	CompressionGzip: "gzip",
+	CompressionAuto: "auto",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// ParseCompression parses a compression mode name as returned by String
+func ParseCompression(name string) (Compression, error) {
+	for c, n := range compressionNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown compression: %q (expected off, gzip or auto)", name)
+}
+
+// MarshalText encodes the mode by name
+func (c Compression) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText decodes a mode name
+func (c *Compression) UnmarshalText(text []byte) error {
+	parsed, err := ParseCompression(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// MinCompressBytes is the smallest body that is compressed; smaller ones
+// gain less than the encoding costs
+const MinCompressBytes = 1024
+
+// WithCompression compresses response bodies of at least MinCompressBytes
+// as mode allows and the client's Accept-Encoding asks for. Bodies that are
+// compressed already, by type or Content-Encoding, are sent as they are.
+func WithCompression(mode Compression) Option {
+	return func(s *APIServer) {
+		s.compression = mode
+	}
+}
+
+// incompressibleTypes are media types whose bodies are compressed already
+var incompressibleTypes = map[string]bool{
+	"application/gzip":    true,
+	"application/zip":     true,
+	"application/zstd":    true,
+	"application/x-xz":    true,
+	"application/x-bzip2": true,
+	"font/woff":           true,
+	"font/woff2":          true,
+}
+
+// compressible reports whether bodies of contentType are worth compressing
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType == ""
+	}
+	if incompressibleTypes[mediaType] {
+		return false
+	}
+	// Images other than SVG, audio and video are compressed formats
+	for _, prefix := range []string{"image/", "audio/", "video/"} {
+		if strings.HasPrefix(mediaType, prefix) && mediaType != "image/svg+xml" {
+			return false
+		}
+	}
+	return true
+}
+
+// negotiateEncoding picks the coding of a response to r under mode, empty
+// for none
+func negotiateEncoding(mode Compression, r *http.Request) string {
+	if mode == CompressionOff {
+		return ""
+	}
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[name] = q
+	}
+	quality := func(coding string) float64 {
+		if q, ok := qualities[coding]; ok {
+			return q
+		}
+		return qualities["*"]
+	}
+
+	gzipQ := quality("gzip")
+	if mode == CompressionAuto {
+		if zstdQ := quality("zstd"); zstdQ > 0 && zstdQ >= gzipQ {
+			return "zstd"
+		}
+	}
+	if gzipQ > 0 {
+		return "gzip"
+	}
+	return ""
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	zstdWriters = sync.Pool{New: func() interface{} {
+		encoder, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+		return encoder
+	}}
+)
+
+// encoder is a pooled gzip or zstd writer
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// newEncoder takes an encoder of coding writing to w from its pool
+func newEncoder(coding string, w io.Writer) encoder {
+	var enc encoder
+	if coding == "zstd" {
+		enc = zstdWriters.Get().(*zstd.Encoder)
+	} else {
+		enc = gzipWriters.Get().(*gzip.Writer)
+	}
+	enc.Reset(w)
+	return enc
+}
+
+// releaseEncoder returns enc to its pool
+func releaseEncoder(enc encoder) {
+	switch enc := enc.(type) {
+	case *zstd.Encoder:
+		zstdWriters.Put(enc)
+	case *gzip.Writer:
+		gzipWriters.Put(enc)
+	}
+}
+
+// compress wraps handler to compress its responses as s.compression allows
+func (s *APIServer) compress(handler http.Handler) http.Handler {
+	if s.compression == CompressionOff {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		coding := negotiateEncoding(s.compression, r)
+		if coding == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		// Compressed responses carry the ETag with the coding appended, so
+		// it differs from the identity response's. Handlers compare
+		// If-None-Match against their own ETags.
+		suffix := "-" + coding + `"`
+		revalidated := false
+		if match := r.Header.Get("If-None-Match"); strings.Contains(match, suffix) {
+			r = r.Clone(r.Context())
+			r.Header.Set("If-None-Match", strings.ReplaceAll(match, suffix, `"`))
+			revalidated = true
+		}
+
+		cw := &compressWriter{ResponseWriter: w, coding: coding, suffix: suffix, revalidated: revalidated}
+		defer cw.close()
+		handler.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter holds back the start of a body until it is known to be
+// long enough to compress, then compresses it or sends it as it is
+type compressWriter struct {
+	http.ResponseWriter
+	coding      string
+	suffix      string // Appended to the ETags of compressed responses
+	revalidated bool   // If-None-Match named the compressed ETag
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	enc     encoder // Set once the body is being compressed
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 || cw.decided {
+		return
+	}
+	if status < http.StatusOK {
+		// Informational responses pass straight through
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.decided {
+		if cw.enc != nil {
+			return cw.enc.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+	cw.buf.Write(p)
+	if cw.buf.Len() >= MinCompressBytes {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide sends the headers, compressing the body if long is set and the
+// response allows it, then writes out what was held back
+func (cw *compressWriter) decide(long bool) error {
+	cw.decided = true
+	header := cw.Header()
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	switch {
+	case cw.status == http.StatusNotModified && cw.revalidated:
+		// The client revalidated the compressed response
+		cw.tagETag()
+	case long && cw.status == http.StatusOK && header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" && compressible(header.Get("Content-Type")):
+		header.Set("Content-Encoding", cw.coding)
+		header.Del("Content-Length")
+		cw.tagETag()
+		cw.enc = newEncoder(cw.coding, cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	held := cw.buf.Bytes()
+	if len(held) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(held)
+	} else {
+		_, err = cw.ResponseWriter.Write(held)
+	}
+	cw.buf.Reset()
+	return err
+}
+
+// tagETag appends the coding to a strong ETag
+func (cw *compressWriter) tagETag() {
+	etag := cw.Header().Get("ETag")
+	if strings.HasSuffix(etag, `"`) && !strings.HasPrefix(etag, "W/") && !strings.HasSuffix(etag, cw.suffix) {
+		cw.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+cw.suffix)
+	}
+}
+
+// Flush sends what was written so far, uncompressed if the body hasn't
+// reached MinCompressBytes, so streamed responses aren't held back
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close finishes the response once the handler has returned
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 {
+			// Nothing was written; let the server send its default response
+			return
+		}
+		cw.decide(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		releaseEncoder(cw.enc)
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TreeHistory holds the trees of past versions for
+// GET /api/consistency/{address}
+type TreeHistory interface {
+	// TreeAt returns the tree served as version, false when it isn't held
+	TreeAt(version int) (*merkle.MerkleTree, bool)
+}
+
+// WithTreeHistory lets callers prove at /api/consistency/{address} that an
+// address's claim is the same in two versions of the tree, or how it differs
+func WithTreeHistory(history TreeHistory) Option {
+	return func(s *APIServer) {
+		s.history = history
+	}
+}
+
+// GetConsistency proves that an address's claim did not change between the
+// from and to versions, or shows both claims when it did
+func (s *APIServer) GetConsistency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// The proofs are the address's, so they follow the proof endpoint's restrictions
+	if s.proofsDisabled || s.reservation != nil || s.privacy != nil {
+		writeError(w, http.StatusForbidden, CodeEndpointDisabled, "Consistency proofs are disabled")
+		return
+	}
+
+	address := r.PathValue("address")
+	if !common.IsHexAddress(address) {
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
+		return
+	}
+	trees := make([]*merkle.MerkleTree, 2)
+	versions := make([]int, 2)
+	for i, name := range []string{"from", "to"} {
+		version, err := strconv.Atoi(r.URL.Query().Get(name))
+		if err != nil || version < 1 {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, name+" must be a tree version from 1")
+			return
+		}
+		tree, ok := s.history.TreeAt(version)
+		if !ok {
+			writeError(w, http.StatusNotFound, CodeVersionNotFound, fmt.Sprintf("No tree version %d", version))
+			return
+		}
+		trees[i], versions[i] = tree, version
+	}
+
+	response := ConsistencyResponse{From: versions[0], To: versions[1], Success: true}
+	proof, err := merkle.ProveConsistency(trees[0], trees[1], common.HexToAddress(address))
+	var changed *merkle.AllocationChangedError
+	switch {
+	case errors.As(err, &changed):
+		response.Changed = true
+		response.Difference = changed.Difference
+	case errors.Is(err, merkle.ErrAddressNotFound):
+		writeError(w, http.StatusNotFound, CodeAddressNotFound, "Address not found in either version")
+		return
+	case err != nil:
+		s.requestLogger(r).Error("consistency proof failed", "address", common.HexToAddress(address).Hex(), "from", versions[0], "to", versions[1], "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to generate proofs")
+		return
+	default:
+		response.Consistency = proof
+	}
+	writeJSON(w, http.StatusOK, response)
+}
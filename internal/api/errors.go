@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// Error codes returned in the code field of API error responses
+const (
+	CodeInvalidAddress       = "INVALID_ADDRESS"        // Malformed address in the path or body
+	CodeInvalidAmount        = "INVALID_AMOUNT"         // Amount is not a base-10 integer
+	CodeInvalidProof         = "INVALID_PROOF"          // Malformed proof element
+	CodeInvalidRequest       = "INVALID_REQUEST"        // Body is not valid JSON or has unknown fields
+	CodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"      // Body exceeds the server's limit
+	CodeInvalidParameter     = "INVALID_PARAMETER"      // Unknown query parameter value
+	CodeInvalidCampaign      = "INVALID_CAMPAIGN"       // Campaign update fails validation
+	CodeAddressNotFound      = "ADDRESS_NOT_FOUND"      // Address is not in the airdrop
+	CodeCampaignNotFound     = "CAMPAIGN_NOT_FOUND"     // No archived campaign of that name
+	CodeEndpointDisabled     = "ENDPOINT_DISABLED"      // Endpoint turned off by server config
+	CodeNoClaimData          = "NO_CLAIM_DATA"          // Verify-only server holds no claims to answer from
+	CodeUnauthorized         = "UNAUTHORIZED"           // Missing or wrong admin token
+	CodeInvalidSignature     = "INVALID_SIGNATURE"      // Missing or wrong nonce signature in reservation or privacy mode
+	CodeAlreadyIssued        = "ALREADY_ISSUED"         // Proof was already handed out in reservation mode
+	CodeNotIssued            = "NOT_ISSUED"             // No issuance to reset
+	CodeRateLimited          = "RATE_LIMITED"           // Client is throttled by abuse detection
+	CodeNotFound             = "NOT_FOUND"              // No such route
+	CodeMethodNotAllowed     = "METHOD_NOT_ALLOWED"     // Route exists for other methods
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE" // Body is not application/json
+	CodeInternal             = "INTERNAL_ERROR"         // Server failed to build the response
+	CodeSelfTestFailed       = "SELF_TEST_FAILED"       // Startup self-test found proofs that don't verify
+	CodeClaimNotOpen         = "CLAIM_NOT_OPEN"         // Claim window hasn't opened yet
+	CodeClaimClosed          = "CLAIM_CLOSED"           // Claim window has closed
+	CodeDuplicateAddress     = "DUPLICATE_ADDRESS"      // Address given twice in one request
+	CodeClaimExists          = "CLAIM_EXISTS"           // Appended address already has a claim
+	CodeInvalidConfig        = "INVALID_CONFIG"         // Reloaded config can't be read or fails validation
+	CodeRootMismatch         = "ROOT_MISMATCH"          // Proof was generated against another root
+	CodeVersionNotFound      = "VERSION_NOT_FOUND"      // No tree of that version is held
+	CodeFinalized            = "CAMPAIGN_FINALIZED"     // Campaign is locked since its root was deployed
+	CodeNotFinalized         = "NOT_FINALIZED"          // No finalization to unlock
+	CodeChainUnavailable     = "CHAIN_UNAVAILABLE"      // Contract state couldn't be read from the node
+	CodeTooManyClaims        = "TOO_MANY_CLAIMS"        // Appended claims would exceed the tree's claim limit
+	CodeNotInitialized       = "NOT_INITIALIZED"        // Server was not made by a constructor
+)
+
+// errorCodes describes every code above, for the schemas of /api/schema
+var errorCodes = []struct{ code, description string }{
+	{CodeInvalidAddress, "Malformed address in the path or body"},
+	{CodeInvalidAmount, "Amount is not a base-10 integer"},
+	{CodeInvalidProof, "Malformed proof element"},
+	{CodeInvalidRequest, "Body is not valid JSON or has unknown fields"},
+	{CodePayloadTooLarge, "Body exceeds the server's limit"},
+	{CodeInvalidParameter, "Unknown query parameter value"},
+	{CodeInvalidCampaign, "Campaign update fails validation"},
+	{CodeAddressNotFound, "Address is not in the airdrop"},
+	{CodeCampaignNotFound, "No archived campaign of that name"},
+	{CodeEndpointDisabled, "Endpoint turned off by server config"},
+	{CodeNoClaimData, "Verify-only server holds no claims to answer from"},
+	{CodeUnauthorized, "Missing or wrong admin token"},
+	{CodeInvalidSignature, "Missing or wrong nonce signature in reservation or privacy mode"},
+	{CodeAlreadyIssued, "Proof was already handed out in reservation mode"},
+	{CodeNotIssued, "No issuance to reset"},
+	{CodeRateLimited, "Client is throttled by abuse detection"},
+	{CodeNotFound, "No such route"},
+	{CodeMethodNotAllowed, "Route exists for other methods"},
+	{CodeUnsupportedMediaType, "Body is not application/json"},
+	{CodeInternal, "Server failed to build the response"},
+	{CodeSelfTestFailed, "Startup self-test found proofs that don't verify"},
+	{CodeClaimNotOpen, "Claim window hasn't opened yet"},
+	{CodeClaimClosed, "Claim window has closed"},
+	{CodeDuplicateAddress, "Address given twice in one request"},
+	{CodeClaimExists, "Appended address already has a claim"},
+	{CodeInvalidConfig, "Reloaded config can't be read or fails validation"},
+	{CodeRootMismatch, "Proof was generated against another root"},
+	{CodeVersionNotFound, "No tree of that version is held"},
+	{CodeFinalized, "Campaign is locked since its root was deployed"},
+	{CodeNotFinalized, "No finalization to unlock"},
+	{CodeChainUnavailable, "Contract state couldn't be read from the node"},
+	{CodeTooManyClaims, "Appended claims would exceed the tree's claim limit"},
+	{CodeNotInitialized, "Server was not made by a constructor"},
+}
+
+// APIError is the error object of an API error response
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// ErrorResponse is the body of every API error response:
+// {"success": false, "error": {"code": "...", "message": "..."}, "requestId": "..."}
+type ErrorResponse struct {
+	Success   bool     `json:"success"`
+	Error     APIError `json:"error"`
+	RequestID string   `json:"requestId,omitempty"`
+}
+
+// newErrorResponse builds an error body carrying the request ID that
+// withRequestID set on w
+func newErrorResponse(w http.ResponseWriter, code, message string) ErrorResponse {
+	return ErrorResponse{
+		Error:     APIError{Code: code, Message: message},
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+}
+
+// writeJSON writes v as the JSON response body with status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error envelope with status
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, newErrorResponse(w, code, message))
+}
+
+// writeClaimError writes the client error that err from pkg/merkle stands
+// for, reporting false when err isn't the client's fault
+func writeClaimError(w http.ResponseWriter, err error) bool {
+	var amountErr *merkle.InvalidAmountError
+	var proofErr *merkle.InvalidProofError
+	var rootErr *merkle.RootMismatchError
+	switch {
+	case errors.Is(err, merkle.ErrAddressNotFound):
+		writeError(w, http.StatusNotFound, CodeAddressNotFound, "Address not found in airdrop")
+	case errors.As(err, &amountErr):
+		writeError(w, http.StatusBadRequest, CodeInvalidAmount, "Invalid amount: "+amountErr.Reason)
+	case errors.As(err, &proofErr):
+		writeError(w, http.StatusBadRequest, CodeInvalidProof, "Invalid proof: "+proofErr.Reason)
+	case errors.As(err, &rootErr):
+		writeError(w, http.StatusConflict, CodeRootMismatch, "Proof is for root "+rootErr.ProofRoot+", not the served "+rootErr.Root+"; it is likely from an old export or another campaign")
+	default:
+		return false
+	}
+	return true
+}
+
+// writeMethodNotAllowed rejects a request whose method the route doesn't
+// accept
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+}
+
+// notFound answers requests for unknown routes
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNotFound, "No route for "+r.URL.Path)
+}
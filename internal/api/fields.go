@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+
+	"merkle-airdrop/pkg/data"
+)
+
+// proofResponseKeys are the keys of a ProofResponse that proof fields may
+// not be renamed to
+var proofResponseKeys = []string{"address", "merkleRoot", "amountDisplay", "issuedAt", "campaign", "success"}
+
+// ValidateProofFields checks that fields can rename the proof fields of
+// /api/proof responses
+func ValidateProofFields(fields data.ProofMarshaller) error {
+	return fields.Validate(proofResponseKeys...)
+}
+
+// WithProofFields names the proof, index and amount of the served
+// campaign's /api/proof responses as fields does, for frontends that expect
+// other names. Archived campaigns keep the default names, as does the
+// OpenAPI document. Check fields with ValidateProofFields first.
+func WithProofFields(fields data.ProofMarshaller) Option {
+	return func(s *APIServer) {
+		s.proofFields = fields
+	}
+}
+
+// writeProof writes a proof response with the served campaign's field names
+func (s *APIServer) writeProof(w http.ResponseWriter, response ProofResponse) {
+	encoded, err := s.proofFields.Rename(response)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode proof")
+		return
+	}
+	writeJSON(w, http.StatusOK, encoded)
+}
@@ -0,0 +1,295 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"merkle-airdrop/internal/fsutil"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Finalization records that the served root was deployed to a distributor.
+// A finalized campaign can't be changed until it is force-unlocked.
+type Finalization struct {
+	MerkleRoot      string    `json:"merkleRoot"`
+	ContractAddress string    `json:"contractAddress"`
+	TxHash          string    `json:"txHash"` // Of the deployment
+	FinalizedAt     time.Time `json:"finalizedAt"`
+	Operator        string    `json:"operator"`     // Who finalized, as given in the request
+	RootVerified    bool      `json:"rootVerified"` // The contract's root was read and matched
+}
+
+// FinalizedError is returned for a change to a finalized campaign
+type FinalizedError struct {
+	Finalization Finalization
+}
+
+func (e *FinalizedError) Error() string {
+	return fmt.Sprintf("campaign was finalized with root %s at %s; it can't be changed until force-unlocked",
+		e.Finalization.MerkleRoot, e.Finalization.FinalizedAt.Format(time.RFC3339))
+}
+
+// ErrNotFinalized is returned when unlocking a campaign that isn't finalized
+var ErrNotFinalized = errors.New("campaign is not finalized")
+
+// FinalizationLock holds the campaign's finalization, saved to a JSON file
+// so that it survives restarts
+type FinalizationLock struct {
+	mu     sync.RWMutex
+	path   string        // Kept in memory only when empty
+	record *Finalization // Nil until finalized
+}
+
+// NewFinalizationLock opens the lock saved at path, unlocked if the file
+// doesn't exist. With an empty path the lock is kept in memory only.
+func NewFinalizationLock(path string) (*FinalizationLock, error) {
+	lock := &FinalizationLock{path: path}
+	if path == "" {
+		return lock, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read finalization: %w", err)
+	}
+	lock.record = new(Finalization)
+	if err := json.Unmarshal(content, lock.record); err != nil {
+		return nil, fmt.Errorf("failed to decode finalization %s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// Finalization returns the campaign's finalization, false if it isn't
+// finalized
+func (l *FinalizationLock) Finalization() (Finalization, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.record == nil {
+		return Finalization{}, false
+	}
+	return *l.record, true
+}
+
+// Check returns a FinalizedError if the campaign is finalized
+func (l *FinalizationLock) Check() error {
+	if record, finalized := l.Finalization(); finalized {
+		return &FinalizedError{Finalization: record}
+	}
+	return nil
+}
+
+// Finalize saves record, then locks the campaign. It fails with a
+// FinalizedError if the campaign is already finalized.
+func (l *FinalizationLock) Finalize(record Finalization) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.record != nil {
+		return &FinalizedError{Finalization: *l.record}
+	}
+	if l.path != "" {
+		err := fsutil.AtomicWriteFile(l.path, func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(record)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save finalization: %w", err)
+		}
+	}
+	l.record = &record
+	return nil
+}
+
+// Unlock deletes the saved finalization, returning it
+func (l *FinalizationLock) Unlock() (Finalization, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.record == nil {
+		return Finalization{}, ErrNotFinalized
+	}
+	if l.path != "" {
+		if err := os.Remove(l.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return Finalization{}, fmt.Errorf("failed to delete finalization: %w", err)
+		}
+	}
+	record := *l.record
+	l.record = nil
+	return record, nil
+}
+
+// RootReader reads the root a deployed distributor holds, checked against
+// the served root when finalizing
+type RootReader interface {
+	MerkleRoot(contract common.Address) ([32]byte, error)
+}
+
+// WithFinalization lets admins finalize the campaign with
+// POST /api/admin/finalize once its root is deployed. From then on appended
+// claims, campaign updates, issuance resets and config reloads answer 423
+// until POST /api/admin/finalize/force-unlock, which takes one of
+// unlockTokens instead of an admin token; without unlockTokens the lock
+// can't be lifted through the API. Servers created with the same option
+// share the lock.
+func WithFinalization(lock *FinalizationLock, unlockTokens []string) Option {
+	auth := newAdminAuth(unlockTokens)
+	return func(s *APIServer) {
+		s.finalization = lock
+		s.unlockAuth = auth
+	}
+}
+
+// WithRootReader makes POST /api/admin/finalize check that the contract
+// holds the served root
+func WithRootReader(reader RootReader) Option {
+	return func(s *APIServer) {
+		s.rootReader = reader
+	}
+}
+
+// finalized returns the campaign's finalization for /api/campaign and
+// /api/stats, nil if it isn't finalized
+func (s *APIServer) finalized() *Finalization {
+	if s.finalization == nil {
+		return nil
+	}
+	if record, ok := s.finalization.Finalization(); ok {
+		return &record
+	}
+	return nil
+}
+
+// checkNotFinalized answers 423 with the finalization when the campaign is
+// finalized, reporting whether the request may change it
+func (s *APIServer) checkNotFinalized(w http.ResponseWriter) bool {
+	if s.finalization == nil {
+		return true
+	}
+	record, finalized := s.finalization.Finalization()
+	if finalized {
+		writeFinalized(w, record)
+	}
+	return !finalized
+}
+
+// writeFinalized rejects a change to the campaign finalized as record
+func writeFinalized(w http.ResponseWriter, record Finalization) {
+	writeJSON(w, http.StatusLocked, FinalizedResponse{
+		newErrorResponse(w, CodeFinalized, (&FinalizedError{Finalization: record}).Error()),
+		record,
+	})
+}
+
+// Finalize records that the served root was deployed at the contract in
+// the request body, locking the campaign
+func (s *APIServer) Finalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var req FinalizeRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !common.IsHexAddress(req.ContractAddress) {
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid contract address format")
+		return
+	}
+	if tx, err := hexutil.Decode(req.TxHash); err != nil || len(tx) != common.HashLength {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "txHash must be a 0x-prefixed 32-byte hash")
+		return
+	}
+	if req.Operator == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "operator is required")
+		return
+	}
+	if !s.checkNotFinalized(w) {
+		return
+	}
+
+	contract := common.HexToAddress(req.ContractAddress)
+	record := Finalization{
+		MerkleRoot:      s.root,
+		ContractAddress: contract.Hex(),
+		TxHash:          common.HexToHash(req.TxHash).Hex(),
+		FinalizedAt:     s.now().UTC(),
+		Operator:        req.Operator,
+	}
+	if s.rootReader != nil {
+		root, err := s.rootReader.MerkleRoot(contract)
+		if err != nil {
+			s.requestLogger(r).Error("contract root read failed", "contract", contract.Hex(), "error", err)
+			writeError(w, http.StatusBadGateway, CodeChainUnavailable, "Failed to read the contract's root")
+			return
+		}
+		if !bytes.Equal(root[:], s.rootBytes) {
+			writeError(w, http.StatusConflict, CodeRootMismatch, "Contract "+contract.Hex()+" holds root "+hexutil.Encode(root[:])+", not the served "+s.root)
+			return
+		}
+		record.RootVerified = true
+	}
+
+	err := s.finalization.Finalize(record)
+	var finalizedErr *FinalizedError
+	if errors.As(err, &finalizedErr) {
+		writeFinalized(w, finalizedErr.Finalization)
+		return
+	}
+	if err != nil {
+		s.requestLogger(r).Error("finalization failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to save finalization")
+		return
+	}
+
+	s.InvalidateResponseCache()
+	s.requestLogger(r).Info("campaign finalized", "root", record.MerkleRoot, "contract", record.ContractAddress, "tx", record.TxHash, "operator", record.Operator, "verified", record.RootVerified)
+	writeJSON(w, http.StatusOK, FinalizeResponse{Finalization: record, Success: true})
+}
+
+// ForceUnlock lifts the finalization lock, letting the campaign change
+// again. Its route takes an unlock token, not an admin token.
+func (s *APIServer) ForceUnlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var req UnlockRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+	if req.Operator == "" || req.Reason == "" {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "operator and reason are required")
+		return
+	}
+
+	record, err := s.finalization.Unlock()
+	if errors.Is(err, ErrNotFinalized) {
+		writeError(w, http.StatusConflict, CodeNotFinalized, "Campaign is not finalized")
+		return
+	}
+	if err != nil {
+		s.requestLogger(r).Error("force unlock failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete finalization")
+		return
+	}
+
+	s.InvalidateResponseCache()
+	s.requestLogger(r).Warn("campaign force-unlocked", "root", record.MerkleRoot, "contract", record.ContractAddress, "operator", req.Operator, "reason", req.Reason)
+	writeJSON(w, http.StatusOK, UnlockResponse{Unlocked: record, Success: true})
+}
@@ -2,162 +2,1249 @@
 package api
 
 import (
-	"encoding/json"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
 	"net/http"
-	"strings"
+	"net/netip"
+	"sort"
+	"sync/atomic"
 
+	"merkle-airdrop/internal/cache"
+	"merkle-airdrop/pkg/archive"
+	"merkle-airdrop/pkg/data"
 	"merkle-airdrop/pkg/merkle"
 
 	"github.com/ethereum/go-ethereum/common"
 )
 
 type APIServer struct {
-	tree   *merkle.MerkleTree
-	proofs map[string]*merkle.MerkleProof
+	tree   *merkle.MerkleTree // nil when serving an exported proof set
+	proofs merkle.ProofStore
+	root   string
+	cache  *proofCache // Set in lazy mode, where proofs is nil
+
+	precompute *precompute // Set while proofs generate in the background, where proofs is nil
+
+	responses *cache.LRU[string, cachedResponse] // Encoded responses of expensive endpoints; nil when disabled
+
+	rootBytes []byte
+	options   merkle.TreeOptions // Leaf encoding used to verify proofs
+
+	adminTokens    []string
+	adminAuth      *adminAuth // Checks admin tokens outside admin routes; nil without tokens
+	proofsDisabled bool       // Only eligibility checks are served
+	verifyOnly     bool       // Only the root is held; see NewVerifyOnlyServer
+
+	suggestEnabled bool
+	suggestions    *suggestionIndex // Built at construction when suggestEnabled
+	claimStatus    ClaimStatus
+
+	claimedCheck    ClaimStatus // Set to report the claimed state of verified proofs
+	claimedContract common.Address
+
+	indexer IndexerProgress // Claims indexer reported by /api/stats; nil when none runs
+
+	rebuild RebuildProgress // Rebuild scheduler reported by /api/stats; nil when none runs
+
+	claimLinkURL string // Claim site for /api/link; links are disabled when empty
+
+	campaign *campaign // Served at /api/campaign; the endpoint is disabled when nil
+	clock    Clock     // Claim windows, nonces and abuse detection go by it; nil for the system clock
+
+	appender ClaimAppender // Adds claims for POST /api/admin/claims; the endpoint is disabled when nil
+	history  TreeHistory   // Past trees for /api/consistency; the endpoint is disabled when nil
+
+	finalization *FinalizationLock // Set to let admins lock the campaign; see WithFinalization
+	unlockAuth   *adminAuth        // Checks the tokens of /api/admin/finalize/force-unlock
+	rootReader   RootReader        // Checks finalized contracts' roots; nil to skip the check
+
+	proofFields data.ProofMarshaller // Field names of the served campaign's proof responses
+
+	bloomRate float64      // False positive rate of the /api/bloom filter; disabled when zero
+	bloom     *bloomFilter // Built at construction when bloomRate is set
+
+	basePath string // Prefix of every route, for serving behind a proxy; see WithBasePath
+
+	staticDir       string // Claim site served under /; disabled when empty
+	contractAddress string // Substituted into the claim site's index.html
+
+	logger *slog.Logger
+
+	maxBodyBytes int64 // Bound on POST bodies; DefaultMaxBodyBytes when zero
+
+	compression Compression // Of response bodies; see WithCompression
+
+	workers int // Goroutines verifying /api/verify/batch entries; one per CPU when zero
+
+	tokenDecimals int // Decimals of ?unit=token amounts
+
+	reservation *reservation // Set when each proof is handed out only once
+
+	privacy *privacy // Set to serve amounts only to their address's key holder; see WithPrivacyMode
+
+	abuse          *abuseDetector // Set to throttle clients enumerating /api/proof
+	trustedProxies []netip.Prefix // Remote addresses whose X-Forwarded-For is believed
+
+	traceLimit *windowLimiter // Caps POST /api/verify?trace=true per client; see WithTraceLimit
+
+	tunables *Tunables      // Settings a config reload can change; see WithTunables
+	reloader ConfigReloader // Reloads the config for /api/admin/config/reload; nil when disabled
+
+	archive        archive.ProofArchive // Past campaigns served with ?campaign=; nil when none
+	servedCampaign string               // Name of the campaign in memory among the archived ones
+
+	totalAmount *big.Int // Sum of all claim amounts; nil if a stored amount is invalid
+	indexStats  merkle.IndexStats
+
+	selfTestResult atomic.Pointer[SelfTestResult] // Set once SelfTest has run
+}
+
+// maxStatsGaps bounds the unused claim indices listed by /api/stats
+const maxStatsGaps = 100
+
+// Option configures an APIServer
+type Option func(*APIServer)
+
+// WithAdminTokens sets the bearer tokens accepted by admin routes
+func WithAdminTokens(tokens []string) Option {
+	return func(s *APIServer) {
+		s.adminTokens = tokens
+		s.adminAuth = newAdminAuth(tokens)
+	}
+}
+
+// WithProofsDisabled makes /api/proof answer 403 so the server only tells
+// callers whether they are eligible
+func WithProofsDisabled() Option {
+	return func(s *APIServer) {
+		s.proofsDisabled = true
+	}
+}
+
+// WithSuggestions lets /api/proof/{address}?suggest=true list similar
+// airdrop addresses when address is not found. The index behind it is built
+// when the server is created.
+func WithSuggestions() Option {
+	return func(s *APIServer) {
+		s.suggestEnabled = true
+	}
+}
+
+// WithClaimStatus reports whether suggested addresses have been claimed
+func WithClaimStatus(status ClaimStatus) Option {
+	return func(s *APIServer) {
+		s.claimStatus = status
+	}
+}
+
+// WithClaimLinkURL enables /api/link, building links that open baseURL
+func WithClaimLinkURL(baseURL string) Option {
+	return func(s *APIServer) {
+		s.claimLinkURL = baseURL
+	}
+}
+
+// WithBasePath serves every route under base, e.g. "/airdrop" for
+// /airdrop/api/proof/{address}, for a proxy that forwards a path prefix
+// as it is. The root then redirects to the docs page, unless a claim site
+// is served there.
+func WithBasePath(base string) Option {
+	return func(s *APIServer) {
+		s.basePath = CleanBasePath(base)
+	}
+}
+
+// WithLogger sets the logger for access logs and handler messages, which
+// defaults to slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *APIServer) {
+		s.logger = logger
+	}
+}
+
+// WithTokenDecimals sets the token's decimals for amounts displayed with
+// ?unit=token, data.DefaultTokenDecimals unless set
+func WithTokenDecimals(decimals int) Option {
+	return func(s *APIServer) {
+		s.tokenDecimals = decimals
+	}
+}
+
+// WithReservation hands each proof out once: /api/proof requires a signed
+// nonce from /api/nonce, and issuances are recorded in store. Servers
+// created with the same option share outstanding nonces.
+func WithReservation(store ClaimStore) Option {
+	res := newReservation(store)
+	return func(s *APIServer) {
+		s.reservation = res
+	}
+}
+
+// NewAPIServer creates a server for tree that serves proofs, which must
+// hold the tree's proofs; use NewLazyAPIServer to generate them on demand
+func NewAPIServer(tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof, opts ...Option) (*APIServer, error) {
+	if len(proofs) == 0 {
+		return nil, fmt.Errorf("%w: the proofs map is empty", ErrNoProofs)
+	}
+	return NewAPIServerWithStore(tree, merkle.MapProofStore(proofs), opts...)
+}
+
+// MustNewAPIServer is NewAPIServer panicking on error, for tests and
+// programs whose tree and proofs are known to be valid
+func MustNewAPIServer(tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof, opts ...Option) *APIServer {
+	s, err := NewAPIServer(tree, proofs, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// NewAPIServerWithStore creates a server for tree that serves the proofs
+// in store, which may be on disk
+func NewAPIServerWithStore(tree *merkle.MerkleTree, store merkle.ProofStore, opts ...Option) (*APIServer, error) {
+	if store == nil {
+		return nil, fmt.Errorf("%w: the proof store is nil", ErrNoProofs)
+	}
+	return newTreeServer(tree, store, opts)
+}
+
+// newTreeServer creates a server for tree serving the proofs in store,
+// which lazy and precomputing servers leave empty
+func newTreeServer(tree *merkle.MerkleTree, store merkle.ProofStore, opts []Option) (*APIServer, error) {
+	if err := checkTree(tree); err != nil {
+		return nil, err
+	}
+	s := &APIServer{
+		tree:      tree,
+		proofs:    store,
+		root:      tree.GetRootHash(),
+		rootBytes: tree.Root.Hash,
+		options:   tree.Options(),
+		logger:    slog.Default(),
+
+		tokenDecimals: data.DefaultTokenDecimals,
+		traceLimit:    newWindowLimiter(defaultTraceLimit, traceWindow),
+		totalAmount:   merkle.TotalAmount(tree.Claims),
+	}
+	indices := make([]uint32, len(tree.Claims))
+	for i, claim := range tree.Claims {
+		indices[i] = claim.Index
+	}
+	s.indexStats = merkle.IndexStatsOf(indices, maxStatsGaps)
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.linkTunables()
+	if s.suggestEnabled {
+		addresses := make([]common.Address, len(tree.Claims))
+		for i, claim := range tree.Claims {
+			addresses[i] = claim.Address
+		}
+		s.suggestions = newSuggestionIndex(addresses)
+	}
+	if s.bloomRate != 0 {
+		addresses := make([]common.Address, len(tree.Claims))
+		for i, claim := range tree.Claims {
+			addresses[i] = claim.Address
+		}
+		s.buildBloomFilter(addresses)
+	}
+	return s, nil
 }
 
-func NewAPIServer(tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof) *APIServer {
-	return &APIServer{
-		tree:   tree,
-		proofs: proofs,
+// NewLazyAPIServer creates a server that generates proofs from tree on
+// demand, keeping up to cacheSize of them in an LRU cache
+func NewLazyAPIServer(tree *merkle.MerkleTree, cacheSize int, opts ...Option) (*APIServer, error) {
+	s, err := newTreeServer(tree, merkle.MapProofStore(nil), opts)
+	if err != nil {
+		return nil, err
 	}
+	s.cache = newProofCache(cacheSize)
+	return s, nil
+}
+
+// NewAPIServerFromProofs creates a server for an exported proof set without
+// rebuilding the tree
+func NewAPIServerFromProofs(root string, proofs *merkle.ProofSet, opts ...Option) (*APIServer, error) {
+	if proofs == nil || len(proofs.Proofs) == 0 {
+		return nil, fmt.Errorf("%w: the proof set is empty", ErrNoProofs)
+	}
+	return NewAPIServerFromStore(root, merkle.MapProofStore(proofs.Proofs), proofs.Metadata, opts...)
+}
+
+// NewAPIServerFromStore creates a server for the proofs in store, built
+// with the tree described by metadata, without rebuilding the tree. It
+// reads store through once, failing if that does.
+func NewAPIServerFromStore(root string, store merkle.ProofStore, metadata merkle.TreeMetadata, opts ...Option) (*APIServer, error) {
+	if store == nil {
+		return nil, fmt.Errorf("%w: the proof store is nil", ErrNoProofs)
+	}
+	rootBytes, err := parseRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	s := &APIServer{
+		proofs:    store,
+		root:      root,
+		rootBytes: rootBytes,
+		options:   metadata.Options(),
+		logger:    slog.Default(),
+
+		tokenDecimals: data.DefaultTokenDecimals,
+		traceLimit:    newWindowLimiter(defaultTraceLimit, traceWindow),
+		totalAmount:   new(big.Int),
+	}
+	indices := make([]uint32, 0, store.Len())
+	addresses := make([]common.Address, 0, store.Len())
+	err = store.Iterate(func(address common.Address, proof *merkle.MerkleProof) bool {
+		indices = append(indices, proof.Index)
+		addresses = append(addresses, address)
+		// A total that doesn't parse is left out of stats
+		if amount, ok := new(big.Int).SetString(proof.Amount, 10); ok && s.totalAmount != nil {
+			s.totalAmount.Add(s.totalAmount, amount)
+		} else {
+			s.totalAmount = nil
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proofs: %w", err)
+	}
+	s.indexStats = merkle.IndexStatsOf(indices, maxStatsGaps)
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.linkTunables()
+	if s.suggestEnabled {
+		sort.Slice(addresses, func(i, j int) bool {
+			return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+		})
+		s.suggestions = newSuggestionIndex(addresses)
+	}
+	if s.bloomRate != 0 {
+		s.buildBloomFilter(addresses)
+	}
+	return s, nil
+}
+
+// totalClaims returns the number of claims in the airdrop
+func (s *APIServer) totalClaims() int {
+	if s.tree != nil {
+		return len(s.tree.Claims)
+	}
+	return s.proofs.Len()
+}
+
+// amountBits returns the width of the distributor's amount type the tree
+// was checked against
+func (s *APIServer) amountBits() int {
+	if s.options.MaxAmountBits == 0 {
+		return merkle.DefaultMaxAmountBits
+	}
+	return s.options.MaxAmountBits
+}
+
+// tierStats breaks the airdrop's claims down by the tiers boundaries split
+// amounts into
+func (s *APIServer) tierStats(boundaries []*big.Int) ([]TierStats, error) {
+	var claims []merkle.AirdropClaim
+	if s.tree != nil {
+		claims = s.tree.Claims
+	} else {
+		claims = make([]merkle.AirdropClaim, 0, s.proofs.Len())
+		var invalid error
+		err := s.proofs.Iterate(func(address common.Address, proof *merkle.MerkleProof) bool {
+			amount, ok := new(big.Int).SetString(proof.Amount, 10)
+			if !ok {
+				invalid = fmt.Errorf("invalid amount %q for %s", proof.Amount, address.Hex())
+				return false
+			}
+			claims = append(claims, merkle.AirdropClaim{Amount: amount})
+			return true
+		})
+		if err = errors.Join(err, invalid); err != nil {
+			return nil, err
+		}
+	}
+
+	tiers, err := merkle.TierBreakdown(claims, boundaries)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]TierStats, len(tiers))
+	for i, tier := range tiers {
+		stats[i] = TierStats{Min: tier.Min.String(), Addresses: tier.Addresses, Total: tier.Total.String()}
+		if tier.Max != nil {
+			stats[i].Max = tier.Max.String()
+		}
+	}
+	return stats, nil
+}
+
+// totalProofs returns the number of addresses a proof can be served for
+func (s *APIServer) totalProofs() int {
+	if s.cache != nil || s.precompute != nil {
+		return len(s.tree.Claims)
+	}
+	return s.proofs.Len()
+}
+
+// findClaim reports whether address is in the airdrop and its claim index
+func (s *APIServer) findClaim(address common.Address) (uint32, bool) {
+	if s.cache != nil || s.precompute != nil {
+		claim, exists := s.tree.FindClaim(address)
+		return claim.Index, exists
+	}
+	proof, exists, err := s.proofs.Get(address)
+	if err != nil {
+		// Answered as a miss, like the lookups that fall back on it
+		s.logger.Error("Failed to read proof", "address", address.Hex(), "error", err)
+		return 0, false
+	}
+	if !exists {
+		return 0, false
+	}
+	return proof.Index, true
+}
+
+// lookupProof returns the proof for address, generating and caching it in
+// lazy mode and generating it if not yet ready while precomputing
+func (s *APIServer) lookupProof(address common.Address) (*merkle.MerkleProof, bool, error) {
+	if s.cache == nil && s.precompute == nil {
+		return s.proofs.Get(address)
+	}
+
+	if _, exists := s.tree.FindClaim(address); !exists {
+		return nil, false, nil
+	}
+	if s.precompute != nil {
+		if proof, ok := s.precompute.load(address); ok {
+			return proof, true, nil
+		}
+	} else if proof, ok := s.cache.get(address); ok {
+		return proof, true, nil
+	}
+
+	proof, err := s.tree.GenerateProof(address)
+	if errors.Is(err, merkle.ErrAddressNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if s.precompute != nil {
+		return s.precompute.store(address, proof), true, nil
+	}
+	s.cache.add(address, proof)
+	return proof, true, nil
+}
+
+// suggest lists airdrop addresses similar to address in outputCase, with
+// their claimed state when a ClaimStatus is configured
+func (s *APIServer) suggest(address common.Address, outputCase data.AddressCase) []Suggestion {
+	suggestions := []Suggestion{}
+	for _, similar := range s.suggestions.suggest(address) {
+		suggestion := Suggestion{Address: outputCase.Format(similar)}
+		if s.claimStatus != nil {
+			if index, exists := s.findClaim(similar); exists {
+				if claimed, err := s.claimStatus.IsClaimed(index); err == nil {
+					suggestion.Claimed = &claimed
+				}
+			}
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// addressCase reads the case query parameter selecting how response
+// addresses are written; checksummed unless ?case=lower
+func addressCase(r *http.Request) (data.AddressCase, error) {
+	name := r.URL.Query().Get("case")
+	if name == "" {
+		return data.AddressChecksum, nil
+	}
+	return data.ParseAddressCase(name)
+}
+
+// amountUnit reads the unit query parameter that adds amountDisplay to
+// responses; ok is false when there is none
+func amountUnit(r *http.Request) (unit data.AmountUnit, ok bool, err error) {
+	name := r.URL.Query().Get("unit")
+	if name == "" {
+		return 0, false, nil
+	}
+	unit, err = data.ParseAmountUnit(name)
+	return unit, err == nil, err
 }
 
 // GetRootHash returns the Merkle root hash
 func (s *APIServer) GetRootHash(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet)
 		return
 	}
-
-	response := map[string]interface{}{
-		"merkleRoot": s.tree.GetRootHash(),
-		"success":    true,
+	if campaign, ok := s.archivedCampaign(r); ok {
+		s.getArchivedRoot(w, r, campaign)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, RootResponse{
+		MerkleRoot: s.root,
+		Metadata:   s.options.Metadata(),
+		Success:    true,
+	})
 }
 
 // GetProof returns the Merkle proof for a specific address
 func (s *APIServer) GetProof(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	if s.proofsDisabled {
+		writeError(w, http.StatusForbidden, CodeEndpointDisabled, "Proof endpoint is disabled")
+		return
+	}
+	if s.abuse != nil && !s.checkAbuse(w, r) {
 		return
 	}
 
-	address := strings.TrimPrefix(r.URL.Path, "/api/proof/")
+	address := r.PathValue("address")
 	if !common.IsHexAddress(address) {
-		http.Error(w, "Invalid address format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
+		return
+	}
+	if s.abuse != nil && !s.checkZeroAddress(w, r, common.HexToAddress(address)) {
+		return
+	}
+	outputCase, err := addressCase(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "case must be checksum or lower")
+		return
+	}
+	unit, display, err := amountUnit(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "unit must be wei, gwei, ether or token")
+		return
+	}
+
+	if campaign, ok := s.archivedCampaign(r); ok {
+		if s.privacy != nil && !s.checkSignature(w, r, common.HexToAddress(address)) {
+			return
+		}
+		s.getArchivedProof(w, r, campaign, common.HexToAddress(address), outputCase, unit, display)
+		return
+	}
+	if !s.checkClaimWindow(w, r) {
 		return
 	}
 
 	// Normalize address
 	normalizedAddr := common.HexToAddress(address).Hex()
 
-	proof, exists := s.proofs[normalizedAddr]
+	if s.signedNonces() != nil && !s.checkSignature(w, r, common.HexToAddress(address)) {
+		return
+	}
+
+	// While precomputing, ask again later for proofs that aren't ready yet,
+	// generating them ahead of the rest. A 202 confirms membership, so it
+	// comes after the signature check
+	if s.precompute != nil {
+		if _, ready := s.precompute.load(common.HexToAddress(address)); !ready {
+			if _, exists := s.tree.FindClaim(common.HexToAddress(address)); exists {
+				s.precompute.request(s.tree, common.HexToAddress(address), s.requestLogger(r))
+				done, total := s.precompute.progress()
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusAccepted, ProofPendingResponse{
+					Address:  outputCase.Format(common.HexToAddress(address)),
+					Progress: percent(done, total),
+					Success:  true,
+				})
+				return
+			}
+		}
+	}
+
+	proof, exists, err := s.lookupProof(common.HexToAddress(address))
+	if err != nil {
+		s.requestLogger(r).Error("proof generation failed", "address", normalizedAddr, "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to generate proof")
+		return
+	}
+	if s.abuse != nil {
+		s.observeLookup(r, common.HexToAddress(address), exists)
+	}
 	if !exists {
-		response := map[string]interface{}{
-			"error":   "Address not found in airdrop",
-			"success": false,
+		s.requestLogger(r).Debug("address not found", "address", normalizedAddr)
+
+		// Help with typos by naming similar addresses, never their proofs
+		if s.suggestions != nil && r.URL.Query().Get("suggest") == "true" {
+			writeJSON(w, http.StatusNotFound, AddressNotFoundResponse{
+				newErrorResponse(w, CodeAddressNotFound, "Address not found in airdrop"),
+				s.suggest(common.HexToAddress(address), outputCase),
+			})
+			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(response)
+		writeError(w, http.StatusNotFound, CodeAddressNotFound, "Address not found in airdrop")
 		return
 	}
 
-	response := map[string]interface{}{
-		"address":    normalizedAddr,
-		"proof":      proof.Proof,
-		"amount":     proof.Amount,
-		"index":      proof.Index,
-		"merkleRoot": s.tree.GetRootHash(),
-		"success":    true,
+	response := ProofResponse{
+		Address:    outputCase.Format(common.HexToAddress(address)),
+		Proof:      proof.Proof,
+		Amount:     proof.Amount,
+		Index:      proof.Index,
+		MerkleRoot: s.root,
+		Success:    true,
+
+		PaddingCount: proof.PaddingCount,
+	}
+	if !s.options.SortedPairs {
+		response.Positions = &proof.Positions
+	}
+	if amount, valid := new(big.Int).SetString(proof.Amount, 10); display && valid {
+		response.AmountDisplay = unit.Format(amount, s.tokenDecimals)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	if s.reservation != nil {
+		issuedAt, first, err := s.reservation.store.MarkIssued(common.HexToAddress(address), s.now().UTC())
+		if err != nil {
+			s.requestLogger(r).Error("issuance record failed", "address", normalizedAddr, "error", err)
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to record issuance")
+			return
+		}
+		if !first {
+			writeJSON(w, http.StatusConflict, AlreadyIssuedResponse{
+				newErrorResponse(w, CodeAlreadyIssued, "Proof was already issued"),
+				issuedAt,
+			})
+			return
+		}
+		s.requestLogger(r).Info("proof issued", "address", normalizedAddr)
+		response.IssuedAt = &issuedAt
+	}
+	if s.privacy != nil {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+
+	s.writeProof(w, response)
+}
+
+// GetEligibility reports whether an address is in the airdrop without
+// revealing its proof, amount or index
+func (s *APIServer) GetEligibility(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	if s.abuse != nil && !s.checkAbuse(w, r) {
+		return
+	}
+
+	address := r.PathValue("address")
+	if !common.IsHexAddress(address) {
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
+		return
+	}
+	if s.abuse != nil && !s.checkZeroAddress(w, r, common.HexToAddress(address)) {
+		return
+	}
+
+	// Both outcomes take the same path and produce the same response shape
+	_, eligible := s.findClaim(common.HexToAddress(address))
+	if s.abuse != nil {
+		s.observeLookup(r, common.HexToAddress(address), eligible)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, EligibilityResponse{Eligible: eligible})
+}
+
+// GetClaimLink returns a claim site link with the proof for an address
+// pre-filled
+func (s *APIServer) GetClaimLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// Links carry proofs, so they follow the proof endpoint's restrictions
+	if s.proofsDisabled || s.claimLinkURL == "" || s.reservation != nil || s.privacy != nil {
+		writeError(w, http.StatusForbidden, CodeEndpointDisabled, "Claim links are disabled")
+		return
+	}
+	if s.abuse != nil && !s.checkAbuse(w, r) {
+		return
+	}
+
+	address := r.PathValue("address")
+	if !common.IsHexAddress(address) {
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
+		return
+	}
+	if s.abuse != nil && !s.checkZeroAddress(w, r, common.HexToAddress(address)) {
+		return
+	}
+	if !s.checkClaimWindow(w, r) {
+		return
+	}
+	outputCase, err := addressCase(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidParameter, "case must be checksum or lower")
+		return
+	}
+
+	proof, exists, err := s.lookupProof(common.HexToAddress(address))
+	if err != nil {
+		s.requestLogger(r).Error("proof generation failed", "address", common.HexToAddress(address).Hex(), "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to generate proof")
+		return
+	}
+	if s.abuse != nil {
+		s.observeLookup(r, common.HexToAddress(address), exists)
+	}
+	if !exists {
+		writeError(w, http.StatusNotFound, CodeAddressNotFound, "Address not found in airdrop")
+		return
+	}
+
+	amount, ok := new(big.Int).SetString(proof.Amount, 10)
+	if !ok {
+		s.requestLogger(r).Error("invalid stored amount", "address", common.HexToAddress(address).Hex(), "amount", proof.Amount)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Invalid stored amount")
+		return
+	}
+	claim := merkle.AirdropClaim{
+		Address: common.HexToAddress(address),
+		Amount:  amount,
+		Index:   proof.Index,
+	}
+
+	link, err := merkle.EncodeClaimLinkWithOptions(s.claimLinkURL, claim, proof, s.options)
+	if err != nil {
+		s.requestLogger(r).Error("claim link encoding failed", "address", claim.Address.Hex(), "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to encode claim link")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ClaimLinkResponse{
+		Address:    outputCase.Format(claim.Address),
+		Link:       link,
+		MerkleRoot: s.root,
+		Success:    true,
+	})
 }
 
 // GetStats returns airdrop statistics
 func (s *APIServer) GetStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodGet)
 		return
 	}
 
-	response := map[string]interface{}{
-		"totalClaims": len(s.tree.Claims),
-		"totalProofs": len(s.proofs),
-		"merkleRoot":  s.tree.GetRootHash(),
-		"proofDepth":  calculateTreeDepth(len(s.tree.Claims)),
-		"success":     true,
+	var boundaries []*big.Int
+	if tiers := r.URL.Query().Get("tiers"); tiers != "" {
+		// Narrow tiers would single out claims
+		if s.privacyDisabled(w, "Tier stats") {
+			return
+		}
+		var err error
+		if boundaries, err = merkle.ParseTierBoundaries(tiers); err != nil {
+			writeError(w, http.StatusBadRequest, CodeInvalidParameter, "Invalid tiers: "+err.Error())
+			return
+		}
+	}
+
+	response := StatsResponse{
+		TotalClaims: s.totalClaims(),
+		TotalProofs: s.totalProofs(),
+		MerkleRoot:  s.root,
+		ProofDepth:  s.proofDepth(),
+		Indices:     s.indexStats,
+		AmountBits:  s.amountBits(),
+		ClaimWindow: s.claimWindowStatus(),
+
+		Finalization: s.finalized(),
+
+		Success: true,
+	}
+	if s.totalAmount != nil {
+		response.TotalAmount = s.totalAmount.String()
+	}
+	if s.cache != nil {
+		stats := s.cache.stats()
+		response.ProofCache = &stats
+	}
+	if s.indexer != nil {
+		response.Indexer = &IndexerStatus{
+			IndexedBlock: s.indexer.IndexedBlock(),
+			HeadBlock:    s.indexer.HeadBlock(),
+			LagBlocks:    s.indexer.Lag(),
+			Synced:       s.indexer.Synced(),
+		}
+	}
+	if s.rebuild != nil {
+		if status, ok := s.rebuild.RebuildStatus(); ok {
+			response.Rebuild = &status
+		}
+	}
+	response.SelfTest = s.selfTestResult.Load()
+	if boundaries != nil {
+		tiers, err := s.tierStats(boundaries)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to compute tiers: "+err.Error())
+			return
+		}
+		response.Tiers = tiers
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 // VerifyProof verifies a Merkle proof
 func (s *APIServer) VerifyProof(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, http.MethodPost)
 		return
 	}
 
-	var req struct {
-		Address string   `json:"address"`
-		Amount  string   `json:"amount"`
-		Proof   []string `json:"proof"`
+	traced := r.URL.Query().Get("trace") == "true"
+	if traced && !s.checkTraceLimit(w, r) {
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	var req VerifyRequest
+	if !s.decodeJSONBody(w, r, &req) {
 		return
 	}
 
 	if !common.IsHexAddress(req.Address) {
-		http.Error(w, "Invalid address format", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
 		return
 	}
 
-	// TODO: Implement actual proof verification logic
-	isValid := len(req.Proof) > 0 // Simplified verification
+	amount, ok := new(big.Int).SetString(req.Amount, 10)
+	if !ok {
+		writeError(w, http.StatusBadRequest, CodeInvalidAmount, "Invalid amount")
+		return
+	}
 
-	response := map[string]interface{}{
-		"valid":      isValid,
-		"address":    req.Address,
-		"amount":     req.Amount,
-		"merkleRoot": s.tree.GetRootHash(),
-		"success":    true,
+	claim := merkle.AirdropClaim{
+		Address: common.HexToAddress(req.Address),
+		Amount:  amount,
+	}
+	if !s.checkVerifyAttempts(w, r, claim.Address) {
+		return
+	}
+
+	// Without claims, nothing the proof was built with can be looked up
+	if s.verifyOnly && s.options.IncludeIndex && req.Index == nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "index is required: this server holds no claim data")
+		return
+	}
+	if s.verifyOnly && !s.options.SortedPairs && req.Positions == nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidRequest, "positions are required: this server holds no claim data")
+		return
+	}
+
+	// The index defaults to the one recorded for the address
+	if req.Index != nil {
+		claim.Index = *req.Index
+	} else if index, exists := s.findClaim(claim.Address); exists {
+		claim.Index = index
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// Positions matter only without sorted pairs, and likewise default to
+	// the recorded ones
+	var positions uint64
+	if req.Positions != nil {
+		positions = *req.Positions
+	} else if !s.options.SortedPairs {
+		if proof, exists, err := s.lookupProof(claim.Address); err == nil && exists {
+			positions = proof.Positions
+		}
+	}
+
+	// The root the proof records tells a stale proof from a corrupt one
+	proof := &merkle.MerkleProof{Proof: req.Proof, Positions: positions, Root: req.Root}
+	if proof.Root == "" {
+		proof.Root = req.MerkleRoot
+	}
+	var isValid bool
+	var trace *merkle.VerificationTrace
+	var err error
+	if traced {
+		// A malformed or stale proof is explained in the trace, not as an error
+		trace, err = merkle.TraceMerkleProof(s.rootBytes, claim, proof, s.options, s.traceReference(r, claim))
+		isValid = err == nil && trace.Valid
+	} else {
+		isValid, err = merkle.VerifyMerkleProof(s.rootBytes, claim, proof, s.options)
+	}
+	if err != nil {
+		if writeClaimError(w, err) {
+			s.requestLogger(r).Info("malformed claim", "address", claim.Address.Hex(), "error", err)
+			return
+		}
+		s.requestLogger(r).Error("proof verification failed", "address", claim.Address.Hex(), "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to verify proof")
+		return
+	}
+	fields := []any{
+		"address", claim.Address.Hex(),
+		"index", claim.Index,
+		"proof_length", len(req.Proof),
+		"valid", isValid,
+		"traced", traced,
+	}
+	if s.privacy == nil {
+		// Privacy mode keeps amounts out of the logs too
+		fields = append(fields, "amount", req.Amount)
+	}
+	s.requestLogger(r).Info("proof verified", fields...)
+
+	response := VerifyResponse{
+		Valid:      isValid,
+		Address:    req.Address,
+		Amount:     req.Amount,
+		MerkleRoot: s.root,
+		Trace:      trace,
+		Success:    true,
+	}
+
+	// Spare users a claim transaction that would revert. An unreachable
+	// node leaves the claimed state out rather than failing the request.
+	if isValid && s.claimedCheck != nil {
+		claimed, err := s.claimedCheck.IsClaimed(claim.Index)
+		if err != nil {
+			s.requestLogger(r).Warn("claimed state lookup failed", "index", claim.Index, "error", err)
+		} else {
+			response.AlreadyClaimed = &claimed
+			response.ContractAddress = s.claimedContract.Hex()
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
 }
 
-// SetupRoutes configures HTTP routes
-func (s *APIServer) SetupRoutes() *http.ServeMux {
-	mux := http.NewServeMux()
+// SetupRoutes configures HTTP routes behind the CORS, request ID and access
+// log middleware. A server not made by a constructor answers every request
+// with 503.
+func (s *APIServer) SetupRoutes() http.Handler {
+	if !s.initialized() {
+		return withRequestID(accessLog(slog.Default(), http.HandlerFunc(notInitialized)))
+	}
+	router := NewRouter(s.adminTokens)
+	router.SetBasePath(s.basePath)
+	caseParam := QueryParam{Name: "case", Description: "address case in the response: checksum (default) or lower"}
+	ok := func(body interface{}) map[int]interface{} {
+		return map[int]interface{}{http.StatusOK: body}
+	}
 
-	mux.HandleFunc("/api/root", s.GetRootHash)
-	mux.HandleFunc("/api/proof/", s.GetProof)
-	mux.HandleFunc("/api/stats", s.GetStats)
-	mux.HandleFunc("/api/verify", s.VerifyProof)
+	var rootQuery []QueryParam
+	proofQuery := []QueryParam{
+		caseParam,
+		{Name: "unit", Description: "add amountDisplay in wei, gwei, ether or token units"},
+		{Name: "suggest", Description: "true to list similar airdrop addresses when the address isn't found"},
+	}
+	if s.archive != nil {
+		campaignParam := QueryParam{Name: "campaign", Description: "answer for this archived campaign instead of the served one"}
+		rootQuery = append(rootQuery, campaignParam)
+		proofQuery = append(proofQuery, campaignParam)
+	}
+	router.Handle(Endpoint{
+		Path:      "/api/root",
+		Methods:   []string{http.MethodGet},
+		Summary:   "The Merkle root and how its leaves are encoded",
+		Query:     rootQuery,
+		Responses: ok(RootResponse{}),
+		Handler:   s.GetRootHash,
+	})
+	proofResponses := map[int]interface{}{
+		http.StatusOK:        ProofResponse{},
+		http.StatusAccepted:  ProofPendingResponse{},
+		http.StatusForbidden: ClaimNotOpenResponse{},
+		http.StatusNotFound:  AddressNotFoundResponse{},
+		http.StatusGone:      ClaimClosedResponse{},
+	}
+	if s.signedNonces() != nil {
+		proofQuery = append(proofQuery, QueryParam{Name: "signature", Description: "signature of the message from /api/nonce"})
+	}
+	if s.reservation != nil {
+		proofResponses[http.StatusConflict] = AlreadyIssuedResponse{}
+	}
+	router.Handle(s.claimDataEndpoint(Endpoint{
+		Path:      "/api/proof/",
+		Param:     "address",
+		Methods:   []string{http.MethodGet},
+		Summary:   "The proof for an address",
+		Query:     proofQuery,
+		Responses: proofResponses,
+		Handler:   s.GetProof,
+	}))
+	router.Handle(s.claimDataEndpoint(Endpoint{
+		Path:      "/api/eligible/",
+		Param:     "address",
+		Methods:   []string{http.MethodGet},
+		Summary:   "Whether an address is in the airdrop, without its proof",
+		Responses: ok(EligibilityResponse{}),
+		Handler:   s.GetEligibility,
+	}))
+	router.Handle(s.claimDataEndpoint(Endpoint{
+		Path:    "/api/link/",
+		Param:   "address",
+		Methods: []string{http.MethodGet},
+		Summary: "A claim site link with the address's proof filled in",
+		Query:   []QueryParam{caseParam},
+		Responses: map[int]interface{}{
+			http.StatusOK:        ClaimLinkResponse{},
+			http.StatusForbidden: ClaimNotOpenResponse{},
+			http.StatusGone:      ClaimClosedResponse{},
+		},
+		Handler: s.GetClaimLink,
+	}))
+	router.Handle(s.claimDataEndpoint(Endpoint{
+		Path:      "/api/stats",
+		Methods:   []string{http.MethodGet},
+		Summary:   "Airdrop statistics",
+		Query:     []QueryParam{{Name: "tiers", Description: "comma-separated amount boundaries in base units, e.g. 100e18,1000e18, to count claims by amount tier"}},
+		Responses: ok(StatsResponse{}),
+		Handler:   s.cached(s.GetStats),
+	}))
+	router.Handle(Endpoint{
+		Path:      "/api/verify",
+		Methods:   []string{http.MethodPost},
+		Summary:   "Check a proof against the served root",
+		Query:     []QueryParam{{Name: "trace", Description: "true to explain the check step by step, classifying a failure as LEAF_MISMATCH, PATH_CORRUPT or ROOT_MISMATCH; limited per client"}},
+		Request:   VerifyRequest{},
+		Responses: map[int]interface{}{http.StatusOK: VerifyResponse{}, http.StatusConflict: ErrorResponse{}, http.StatusTooManyRequests: ErrorResponse{}},
+		Handler:   s.VerifyProof,
+	})
+	router.Handle(Endpoint{
+		Path:      "/api/verify/batch",
+		Methods:   []string{http.MethodPost},
+		Summary:   "Check up to 10000 proofs against the served root: an application/x-ndjson body of entries, answered with one result line per entry in order",
+		Request:   data.BatchEntry{},
+		Responses: ok(data.BatchResult{}),
+		Handler:   s.VerifyBatch,
+	})
+	router.Handle(s.claimDataEndpoint(Endpoint{
+		Path:      "/api/progress",
+		Methods:   []string{http.MethodGet},
+		Summary:   "How many proofs are ready to be served",
+		Responses: ok(ProgressResponse{}),
+		Handler:   s.GetProgress,
+	}))
+	router.Handle(Endpoint{
+		Path:      "/healthz",
+		Methods:   []string{http.MethodGet},
+		Summary:   "Liveness check",
+		Responses: ok(HealthResponse{}),
+		Handler:   s.Health,
+	})
+	router.Handle(Endpoint{
+		Path:    "/readyz",
+		Methods: []string{http.MethodGet},
+		Summary: "Readiness check, failing once the startup self-test has failed",
+		Responses: map[int]interface{}{
+			http.StatusOK:                 HealthResponse{},
+			http.StatusServiceUnavailable: ErrorResponse{},
+		},
+		Handler: s.Ready,
+	})
+	if s.signedNonces() != nil {
+		router.Handle(Endpoint{
+			Path:      "/api/nonce/",
+			Param:     "address",
+			Methods:   []string{http.MethodGet},
+			Summary:   "A nonce to sign before fetching the address's proof",
+			Responses: ok(NonceResponse{}),
+			Handler:   s.GetNonce,
+		})
+	}
+	if s.reservation != nil {
+		router.Handle(Endpoint{
+			Path:      "/api/admin/issuance/",
+			Param:     "address",
+			Methods:   []string{http.MethodDelete},
+			Summary:   "Let an address fetch its proof again",
+			Responses: ok(IssuanceResetResponse{}),
+			Admin:     true,
+			Handler:   s.ResetIssuance,
+		})
+	}
+	if s.abuse != nil {
+		router.Handle(Endpoint{
+			Path:      "/api/admin/abuse",
+			Methods:   []string{http.MethodGet, http.MethodDelete},
+			Summary:   "Clients throttled for enumerating addresses; DELETE forgets them",
+			Query:     []QueryParam{{Name: "ip", Description: "with DELETE, forget only this client"}},
+			Responses: ok(AbuseResponse{}),
+			Admin:     true,
+			Handler:   s.GetAbuse,
+		})
+	}
+	if s.campaign != nil {
+		router.Handle(Endpoint{
+			Path:      "/api/campaign",
+			Methods:   []string{http.MethodGet},
+			Summary:   "The campaign with the root and claim count",
+			Responses: ok(CampaignResponse{}),
+			Handler:   s.GetCampaign,
+		})
+		router.Handle(Endpoint{
+			Path:      "/api/admin/campaign",
+			Methods:   []string{http.MethodPut},
+			Summary:   "Replace the campaign",
+			Request:   CampaignMeta{},
+			Responses: ok(CampaignUpdateResponse{}),
+			Admin:     true,
+			Handler:   s.UpdateCampaign,
+		})
+	}
+	if s.tree != nil {
+		router.Handle(Endpoint{
+			Path:    "/api/admin/simulate",
+			Methods: []string{http.MethodPost},
+			Summary: "The root and proof a claim would have if added, adding nothing",
+			Request: SimulateClaimRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  SimulateClaimResponse{},
+				http.StatusConflict:            ClaimExistsResponse{},
+				http.StatusUnprocessableEntity: ErrorResponse{},
+			},
+			Admin:   true,
+			Handler: s.SimulateClaim,
+		})
+	}
+	if s.appender != nil {
+		router.Handle(Endpoint{
+			Path:    "/api/admin/claims",
+			Methods: []string{http.MethodPost},
+			Summary: "Add claims, serving a new tree with them",
+			Request: AppendClaimsRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:                  AppendClaimsResponse{},
+				http.StatusConflict:            ClaimExistsResponse{},
+				http.StatusUnprocessableEntity: ErrorResponse{},
+			},
+			Admin:   true,
+			Handler: s.AppendClaims,
+		})
+		router.Handle(Endpoint{
+			Path:      "/api/admin/versions",
+			Methods:   []string{http.MethodGet},
+			Summary:   "The trees served since startup",
+			Responses: ok(TreeVersionsResponse{}),
+			Admin:     true,
+			Handler:   s.GetTreeVersions,
+		})
+	}
+	if s.finalization != nil {
+		router.Handle(Endpoint{
+			Path:    "/api/admin/finalize",
+			Methods: []string{http.MethodPost},
+			Summary: "Lock the campaign once its root is deployed",
+			Request: FinalizeRequest{},
+			Responses: map[int]interface{}{
+				http.StatusOK:     FinalizeResponse{},
+				http.StatusLocked: FinalizedResponse{},
+			},
+			Admin:   true,
+			Handler: s.Finalize,
+		})
+		router.Handle(Endpoint{
+			Path:      "/api/admin/finalize/force-unlock",
+			Methods:   []string{http.MethodPost},
+			Summary:   "Lift the finalization lock; takes an unlock token, not an admin token",
+			Request:   UnlockRequest{},
+			Responses: ok(UnlockResponse{}),
+			Handler:   s.unlockAuth.require(http.HandlerFunc(s.ForceUnlock)).ServeHTTP,
+		})
+	}
+	if s.history != nil {
+		router.Handle(Endpoint{
+			Path:    "/api/consistency/",
+			Param:   "address",
+			Methods: []string{http.MethodGet},
+			Summary: "Proof that the address's claim is the same in two tree versions, or how it differs",
+			Query: []QueryParam{
+				{Name: "from", Description: "the earlier tree version"},
+				{Name: "to", Description: "the later tree version"},
+			},
+			Responses: ok(ConsistencyResponse{}),
+			Handler:   s.GetConsistency,
+		})
+	}
+	if s.bloom != nil {
+		router.Handle(Endpoint{
+			Path:      "/api/bloom",
+			Methods:   []string{http.MethodGet, http.MethodHead},
+			Summary:   "A Bloom filter of the airdrop's addresses",
+			Responses: ok(RawBody("application/octet-stream")),
+			Handler:   s.GetBloomFilter,
+		})
+	}
+	if s.reloader != nil {
+		router.Handle(Endpoint{
+			Path:      "/api/admin/config",
+			Methods:   []string{http.MethodGet},
+			Summary:   "The running configuration, secrets redacted",
+			Responses: ok(ConfigResponse{}),
+			Admin:     true,
+			Handler:   s.GetConfig,
+		})
+		router.Handle(Endpoint{
+			Path:    "/api/admin/config/reload",
+			Methods: []string{http.MethodPost},
+			Summary: "Re-read the config file, applying the settings that can change without a restart",
+			Responses: map[int]interface{}{
+				http.StatusOK:                  ConfigReloadResponse{},
+				http.StatusUnprocessableEntity: ErrorResponse{},
+			},
+			Admin:   true,
+			Handler: s.ReloadConfig,
+		})
+	}
+	router.Handle(Endpoint{
+		Path:      "/api/schema",
+		Methods:   []string{http.MethodGet},
+		Summary:   "JSON Schema of every request and response body, with the error codes",
+		Responses: ok(map[string]interface{}{}),
+		Handler:   s.GetSchema,
+	})
+	router.HandleDocs("/api/openapi.json", "/api/docs")
+	if s.staticDir != "" {
+		router.HandleFallback(s.ServeStatic)
+	}
 
-	// CORS middleware
-	return addCORS(mux)
+	return withRequestID(accessLog(s.logger, s.compress(s.addCORS(router))))
 }
 
-// addCORS adds CORS headers
-func addCORS(handler http.Handler) *http.ServeMux {
+// addCORS adds CORS headers as the server's policy allows
+func (s *APIServer) addCORS(handler http.Handler) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
+		policy := s.cors()
+		if policy.Disabled {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		allowed := policy.allowOrigin(r.Header.Get("Origin"))
+		if allowed != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+RequestIDHeader)
+		w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
 
 		if r.Method == "OPTIONS" {
 			return
@@ -169,6 +1256,16 @@ func addCORS(handler http.Handler) *http.ServeMux {
 	return mux
 }
 
+// proofDepth returns the length of the longest proof served: the fixed
+// depth when proofs are padded, and otherwise the tree's depth, zero for a
+// single claim
+func (s *APIServer) proofDepth() int {
+	if s.options.FixedDepth != 0 {
+		return s.options.FixedDepth
+	}
+	return calculateTreeDepth(s.totalClaims())
+}
+
 func calculateTreeDepth(leaves int) int {
 	if leaves <= 1 {
 		return 0
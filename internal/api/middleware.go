@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// adminAuth checks bearer tokens against a set of configured admin tokens.
+// Only SHA-256 digests of the tokens are kept in memory.
+type adminAuth struct {
+	digests [][sha256.Size]byte
+}
+
+func newAdminAuth(tokens []string) *adminAuth {
+	auth := &adminAuth{}
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		auth.digests = append(auth.digests, sha256.Sum256([]byte(token)))
+	}
+	return auth
+}
+
+// enabled reports whether any admin token is configured
+func (a *adminAuth) enabled() bool {
+	return len(a.digests) > 0
+}
+
+// authorized reports whether r carries a valid bearer token
+func (a *adminAuth) authorized(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+
+	// Compare against every digest so timing doesn't reveal which one matched
+	digest := sha256.Sum256([]byte(token))
+	match := 0
+	for i := range a.digests {
+		match |= subtle.ConstantTimeCompare(digest[:], a.digests[i][:])
+	}
+	return match == 1
+}
+
+// require wraps handler so it only runs for authorized requests. With no
+// tokens configured the route behaves as if it doesn't exist.
+func (a *adminAuth) require(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled() {
+			notFound(w, r)
+			return
+		}
+
+		if !a.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,374 @@
+// internal/api/openapi.go
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OpenAPI document metadata
+const (
+	openAPIVersion = "3.0.3"
+	openAPITitle   = "Merkle Airdrop API"
+	apiVersion     = "1.0.0"
+)
+
+// adminScheme names the bearer token security scheme of admin endpoints
+const adminScheme = "adminToken"
+
+// HandleDocs serves the OpenAPI document of the registered endpoints at
+// specPath and an HTML rendering of it at docsPath, which the root then
+// redirects to. Both are generated on the first request, so endpoints must
+// be registered first. The document's server URL is the base path.
+func (rt *Router) HandleDocs(specPath, docsPath string) {
+	rt.docsPath = docsPath
+	rt.Handle(Endpoint{
+		Path:      specPath,
+		Methods:   []string{http.MethodGet},
+		Summary:   "This OpenAPI document",
+		Responses: map[int]interface{}{http.StatusOK: map[string]interface{}{}},
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			rt.serveDocs(w, r, specPath, "application/json", func() []byte { return rt.spec })
+		},
+	})
+	rt.Handle(Endpoint{
+		Path:      docsPath,
+		Methods:   []string{http.MethodGet},
+		Summary:   "The OpenAPI document rendered as HTML",
+		Responses: map[int]interface{}{http.StatusOK: RawBody("text/html")},
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			rt.serveDocs(w, r, specPath, "text/html; charset=utf-8", func() []byte { return rt.page })
+		},
+	})
+}
+
+// serveDocs renders the documents once and serves one of them
+func (rt *Router) serveDocs(w http.ResponseWriter, r *http.Request, specPath, contentType string, body func() []byte) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	rt.docs.Do(func() {
+		doc := OpenAPIDocument(rt.endpoints)
+		server := rt.base
+		if server == "" {
+			server = "/"
+		}
+		doc["servers"] = []interface{}{map[string]interface{}{"url": server}}
+		rt.spec, _ = json.MarshalIndent(doc, "", "  ")
+
+		var page bytes.Buffer
+		docsPage.Execute(&page, docsPageData{SpecPath: rt.base + specPath, Operations: docsOperations(rt.base, rt.endpoints)})
+		rt.page = page.Bytes()
+	})
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body())
+}
+
+// OpenAPIDocument describes endpoints as an OpenAPI 3.0 document, with
+// their bodies as component schemas
+func OpenAPIDocument(endpoints []Endpoint) map[string]interface{} {
+	schemas := &schemaBuilder{components: map[string]interface{}{}, refPrefix: "#/components/schemas/"}
+	errorSchema := schemas.of(reflect.TypeOf(ErrorResponse{}))
+
+	paths := map[string]interface{}{}
+	for _, e := range endpoints {
+		var parameters []interface{}
+		if e.Param != "" {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     e.Param,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, query := range e.Query {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        query.Name,
+				"in":          "query",
+				"description": query.Description,
+				"schema":      map[string]interface{}{"type": "string"},
+			})
+		}
+
+		responses := map[string]interface{}{
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content":     jsonContent(errorSchema),
+			},
+		}
+		for status, body := range e.Responses {
+			response := map[string]interface{}{"description": http.StatusText(status)}
+			if raw, ok := body.(RawBody); ok {
+				response["content"] = map[string]interface{}{
+					string(raw): map[string]interface{}{
+						"schema": map[string]interface{}{"type": "string", "format": "binary"},
+					},
+				}
+			} else if body != nil {
+				response["content"] = jsonContent(schemas.of(reflect.TypeOf(body)))
+			}
+			responses[strconv.Itoa(status)] = response
+		}
+
+		operation := map[string]interface{}{
+			"summary":   e.Summary,
+			"responses": responses,
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+		if e.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(schemas.of(reflect.TypeOf(e.Request))),
+			}
+		}
+		if e.Admin {
+			operation["security"] = []interface{}{map[string]interface{}{adminScheme: []string{}}}
+		}
+
+		path := openAPIPath(e)
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		for _, method := range e.Methods {
+			item[strings.ToLower(method)] = operation
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   openAPITitle,
+			"version": apiVersion,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				adminScheme: map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// openAPIPath writes an endpoint's pattern in OpenAPI's templated form
+func openAPIPath(e Endpoint) string {
+	if e.Param == "" {
+		return e.Path
+	}
+	return e.Path + "{" + e.Param + "}"
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaBuilder derives JSON schemas from Go types the way encoding/json
+// encodes them, collecting named structs as components
+type schemaBuilder struct {
+	components map[string]interface{}
+	refPrefix  string // Where references to components point
+}
+
+// of returns the schema of t, a reference for named structs
+func (b *schemaBuilder) of(t reflect.Type) interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": intFormat(t)}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": intFormat(t), "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			b.components[t.Name()] = nil // Placeholder against recursion
+			b.components[t.Name()] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": b.refPrefix + t.Name()}
+	default:
+		return map[string]interface{}{} // Any value
+	}
+}
+
+// intFormat names the OpenAPI format of an integer type, int64 for
+// unsigned types that int32 can't hold
+func intFormat(t reflect.Type) string {
+	bits := t.Bits()
+	if t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 {
+		bits++
+	}
+	if bits <= 32 {
+		return "int32"
+	}
+	return "int64"
+}
+
+// object returns the schema of a struct's JSON fields; fields without
+// omitempty are required
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	b.fields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields adds the JSON fields of t to properties, flattening embedded
+// structs as encoding/json does
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.fields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = b.of(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// docsOperation is one method of an endpoint on the docs page
+type docsOperation struct {
+	Method, Path, Summary string
+	Admin                 bool
+	Query                 []QueryParam
+	Request               string
+	Responses             []string
+}
+
+// docsPageData is what the docs page renders
+type docsPageData struct {
+	SpecPath   string
+	Operations []docsOperation
+}
+
+// docsOperations lists the endpoints' operations for the docs page, with
+// their paths under base
+func docsOperations(base string, endpoints []Endpoint) []docsOperation {
+	var operations []docsOperation
+	for _, e := range endpoints {
+		var responses []string
+		for status, body := range e.Responses {
+			responses = append(responses, strconv.Itoa(status)+" "+bodyName(body))
+		}
+		sort.Strings(responses)
+		for _, method := range e.Methods {
+			operations = append(operations, docsOperation{
+				Method:    method,
+				Path:      base + openAPIPath(e),
+				Summary:   e.Summary,
+				Admin:     e.Admin,
+				Query:     e.Query,
+				Request:   bodyName(e.Request),
+				Responses: responses,
+			})
+		}
+	}
+	return operations
+}
+
+// bodyName names a body's schema on the docs page
+func bodyName(body interface{}) string {
+	switch body := body.(type) {
+	case nil:
+		return ""
+	case RawBody:
+		return string(body)
+	}
+	t := reflect.TypeOf(body)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return "JSON"
+	}
+	return t.Name()
+}
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>` + openAPITitle + `</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; }
+code { background: #f4f4f4; padding: 0 .2em; }
+.method { font-weight: bold; display: inline-block; min-width: 5em; }
+section { border-bottom: 1px solid #ddd; padding: .5em 0; }
+</style>
+</head>
+<body>
+<h1>` + openAPITitle + `</h1>
+<p>Machine-readable schemas: <a href="{{.SpecPath}}">{{.SpecPath}}</a>. Errors use the <code>ErrorResponse</code> envelope.</p>
+{{range .Operations}}<section>
+<h3><span class="method">{{.Method}}</span> <code>{{.Path}}</code>{{if .Admin}} (admin token){{end}}</h3>
+<p>{{.Summary}}</p>
+{{if .Query}}<p>Query: {{range $i, $q := .Query}}{{if $i}}, {{end}}<code>{{$q.Name}}</code> {{$q.Description}}{{end}}</p>
+{{end}}{{if .Request}}<p>Request body: <code>{{.Request}}</code></p>
+{{end}}<p>Responses: {{range $i, $r := .Responses}}{{if $i}}, {{end}}<code>{{$r}}</code>{{end}}</p>
+</section>
+{{end}}</body>
+</html>
+`))
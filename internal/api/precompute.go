@@ -0,0 +1,125 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// precompute holds the proofs of a server that serves while they are still
+// being generated. Proofs land in a sync.Map, written by the background
+// generation and by on-demand fallbacks alike.
+type precompute struct {
+	proofs    sync.Map // common.Address -> *merkle.MerkleProof
+	requested sync.Map // Addresses with an on-demand generation in flight
+	done      atomic.Int64
+	total     int
+}
+
+// store records proof for address unless it already has one
+func (p *precompute) store(address common.Address, proof *merkle.MerkleProof) *merkle.MerkleProof {
+	if existing, loaded := p.proofs.LoadOrStore(address, proof); loaded {
+		return existing.(*merkle.MerkleProof)
+	}
+	p.done.Add(1)
+	return proof
+}
+
+func (p *precompute) load(address common.Address) (*merkle.MerkleProof, bool) {
+	proof, ok := p.proofs.Load(address)
+	if !ok {
+		return nil, false
+	}
+	return proof.(*merkle.MerkleProof), true
+}
+
+// request generates the proof of address in the background unless that is
+// already under way
+func (p *precompute) request(tree *merkle.MerkleTree, address common.Address, logger *slog.Logger) {
+	if _, inFlight := p.requested.LoadOrStore(address, struct{}{}); inFlight {
+		return
+	}
+	go func() {
+		defer p.requested.Delete(address)
+		proof, err := tree.GenerateProof(address)
+		if err != nil {
+			logger.Error("on-demand proof generation failed", "address", address.Hex(), "error", err)
+			return
+		}
+		p.store(address, proof)
+	}()
+}
+
+// progress reports how many of the proofs are ready
+func (p *precompute) progress() (done, total int) {
+	return int(p.done.Load()), p.total
+}
+
+// NewPrecomputingAPIServer creates a server for tree that answers before its
+// proofs exist. Call Precompute, typically in a goroutine, to generate
+// them; until a proof is ready /api/proof answers 202 with the progress and
+// generates that proof first.
+func NewPrecomputingAPIServer(tree *merkle.MerkleTree, opts ...Option) (*APIServer, error) {
+	s, err := newTreeServer(tree, merkle.MapProofStore(nil), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[common.Address]struct{}, len(tree.Claims))
+	for _, claim := range tree.Claims {
+		addresses[claim.Address] = struct{}{}
+	}
+	s.precompute = &precompute{total: len(addresses)}
+	return s, nil
+}
+
+// Precompute generates every proof of a server made by
+// NewPrecomputingAPIServer, which keeps serving meanwhile
+func (s *APIServer) Precompute() error {
+	return s.tree.ForEachProof(func(address common.Address, proof *merkle.MerkleProof) {
+		s.precompute.store(address, proof)
+	})
+}
+
+// GetProgress reports how many proofs are ready to be served
+func (s *APIServer) GetProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	done, total := s.totalProofs(), s.totalProofs()
+	if s.precompute != nil {
+		done, total = s.precompute.progress()
+	}
+	writeJSON(w, http.StatusOK, ProgressResponse{
+		Done:     done,
+		Total:    total,
+		Percent:  percent(done, total),
+		Complete: done == total,
+		Success:  true,
+	})
+}
+
+// Health reports that the server is up, whether or not its proofs are ready
+func (s *APIServer) Health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// percent returns done as a percentage of total, to one decimal place
+func percent(done, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(done*1000/total) / 10
+}
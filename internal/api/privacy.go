@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// defaultVerifyAttempts is the number of verifications of one address
+	// allowed per verifyAttemptWindow in privacy mode without a limit set
+	defaultVerifyAttempts = 5
+	verifyAttemptWindow   = time.Hour
+)
+
+// privacy is the state of privacy mode, where only the holder of an
+// address's key learns its amount
+type privacy struct {
+	nonces   *reservation   // Signed nonces of /api/proof; its store is unused
+	attempts *windowLimiter // Verifications per address, so /api/verify can't be used to guess amounts
+}
+
+// WithPrivacyMode serves an address's amount only to whoever signs a nonce
+// from /api/nonce with its key, as reservation mode does but without
+// limiting how often the proof is fetched. Claim links, consistency proofs,
+// tier stats and batch verification are disabled, traces don't compare a
+// claim with the tree's, and /api/verify checks each address at most
+// verifyAttempts times an hour, 5 when zero. Servers created with the same
+// option share nonces and attempt counts.
+func WithPrivacyMode(verifyAttempts int) Option {
+	if verifyAttempts <= 0 {
+		verifyAttempts = defaultVerifyAttempts
+	}
+	p := &privacy{
+		nonces:   newReservation(nil),
+		attempts: newWindowLimiter(verifyAttempts, verifyAttemptWindow),
+	}
+	return func(s *APIServer) {
+		s.privacy = p
+	}
+}
+
+// signedNonces returns the nonces /api/proof requires a signature of, nil
+// when it serves proofs without one. Reservation mode's are used when it
+// is on too, so one signature does for both.
+func (s *APIServer) signedNonces() *reservation {
+	if s.reservation != nil {
+		return s.reservation
+	}
+	if s.privacy != nil {
+		return s.privacy.nonces
+	}
+	return nil
+}
+
+// checkSignature answers 401 unless r carries a signature of address's
+// outstanding nonce, reporting whether the proof may be served
+func (s *APIServer) checkSignature(w http.ResponseWriter, r *http.Request, address common.Address) bool {
+	if err := s.signedNonces().consumeNonce(address, r.URL.Query().Get("signature"), s.now()); err != nil {
+		writeError(w, http.StatusUnauthorized, CodeInvalidSignature, err.Error())
+		return false
+	}
+	return true
+}
+
+// checkVerifyAttempts answers 429 in privacy mode once address has been
+// verified too often, reporting whether the verification may go ahead
+func (s *APIServer) checkVerifyAttempts(w http.ResponseWriter, r *http.Request, address common.Address) bool {
+	if s.privacy == nil {
+		return true
+	}
+	wait, ok := s.privacy.attempts.allow(address.Hex(), s.now())
+	if ok {
+		return true
+	}
+	s.requestLogger(r).Warn("verification attempt limit reached", "address", address.Hex(), "ip", s.clientIP(r))
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many verifications of this address; at most "+strconv.Itoa(s.privacy.attempts.limit)+" an hour")
+	return false
+}
+
+// privacyDisabled answers 403 for a feature that reveals amounts in
+// privacy mode, reporting whether it did
+func (s *APIServer) privacyDisabled(w http.ResponseWriter, feature string) bool {
+	if s.privacy == nil {
+		return false
+	}
+	writeError(w, http.StatusForbidden, CodeEndpointDisabled, feature+" are disabled in privacy mode")
+	return true
+}
@@ -0,0 +1,27 @@
+// internal/api/rebuild.go
+package api
+
+import "time"
+
+// RebuildStatus describes the last scheduled rebuild of the served tree
+type RebuildStatus struct {
+	LastRebuild time.Time `json:"lastRebuild"` // When the attempt started
+	Duration    string    `json:"duration"`
+	Outcome     string    `json:"outcome"`         // rebuilt, unchanged, failed or locked
+	Error       string    `json:"error,omitempty"` // Why the attempt failed or was locked
+	Rebuilds    int       `json:"rebuilds"`        // Trees swapped in since startup
+	Failures    int       `json:"failures"`        // Failed attempts in a row
+}
+
+// RebuildProgress reports a rebuild scheduler's last attempt, with ok false
+// before the first
+type RebuildProgress interface {
+	RebuildStatus() (status RebuildStatus, ok bool)
+}
+
+// WithRebuildProgress adds the last scheduled rebuild to /api/stats
+func WithRebuildProgress(progress RebuildProgress) Option {
+	return func(s *APIServer) {
+		s.rebuild = progress
+	}
+}
@@ -0,0 +1,305 @@
+// internal/api/reservation.go
+package api
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"merkle-airdrop/internal/fsutil"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// nonceTTL is how long a nonce from /api/nonce can be signed
+	nonceTTL = 5 * time.Minute
+	// maxNoncesPerAddress is how many nonces of one address can be
+	// outstanding, so fetching nonces for someone else's address doesn't
+	// void theirs; issuing another drops the address's oldest
+	maxNoncesPerAddress = 8
+	// maxOutstandingNonces bounds the nonces held for every address
+	// together; issuing another drops the oldest
+	maxOutstandingNonces = 1 << 16
+)
+
+// ClaimStore records which addresses have been handed their proof in
+// reservation mode
+type ClaimStore interface {
+	// MarkIssued records address as issued at t. If it already was, it
+	// returns the original time and false.
+	MarkIssued(address common.Address, t time.Time) (time.Time, bool, error)
+	// Reset forgets the issuance of address, reporting whether there was one
+	Reset(address common.Address) (bool, error)
+}
+
+// MemoryClaimStore is a ClaimStore that forgets issuances on restart
+type MemoryClaimStore struct {
+	mu     sync.Mutex
+	issued map[common.Address]time.Time
+}
+
+// NewMemoryClaimStore creates an empty in-memory ClaimStore
+func NewMemoryClaimStore() *MemoryClaimStore {
+	return &MemoryClaimStore{issued: make(map[common.Address]time.Time)}
+}
+
+func (m *MemoryClaimStore) MarkIssued(address common.Address, t time.Time) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if issuedAt, exists := m.issued[address]; exists {
+		return issuedAt, false, nil
+	}
+	m.issued[address] = t
+	return t, true, nil
+}
+
+func (m *MemoryClaimStore) Reset(address common.Address) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, exists := m.issued[address]
+	delete(m.issued, address)
+	return exists, nil
+}
+
+// FileClaimStore is a ClaimStore kept in a JSON file mapping addresses to
+// issuance times. The whole file is rewritten atomically on every change.
+type FileClaimStore struct {
+	mu     sync.Mutex
+	path   string
+	issued map[common.Address]time.Time
+}
+
+// NewFileClaimStore opens the store at path, which is created on the first
+// issuance if it doesn't exist
+func NewFileClaimStore(path string) (*FileClaimStore, error) {
+	store := &FileClaimStore{path: path, issued: make(map[common.Address]time.Time)}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claim store: %w", err)
+	}
+	if err := json.Unmarshal(content, &store.issued); err != nil {
+		return nil, fmt.Errorf("failed to decode claim store %s: %w", path, err)
+	}
+	return store, nil
+}
+
+func (f *FileClaimStore) MarkIssued(address common.Address, t time.Time) (time.Time, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if issuedAt, exists := f.issued[address]; exists {
+		return issuedAt, false, nil
+	}
+	f.issued[address] = t
+	if err := f.save(); err != nil {
+		delete(f.issued, address)
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+func (f *FileClaimStore) Reset(address common.Address) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	issuedAt, exists := f.issued[address]
+	if !exists {
+		return false, nil
+	}
+	delete(f.issued, address)
+	if err := f.save(); err != nil {
+		f.issued[address] = issuedAt
+		return false, err
+	}
+	return true, nil
+}
+
+// save writes the issuances to the store's file; the caller holds mu
+func (f *FileClaimStore) save() error {
+	return fsutil.AtomicWriteFile(f.path, func(w io.Writer) error {
+		if err := json.NewEncoder(w).Encode(f.issued); err != nil {
+			return fmt.Errorf("failed to encode claim store: %w", err)
+		}
+		return nil
+	})
+}
+
+// reservation hands each proof out once, to a caller that signs a fresh
+// nonce with the address's key
+type reservation struct {
+	store ClaimStore
+
+	mu     sync.Mutex
+	order  *list.List // Outstanding nonces, oldest first
+	nonces map[common.Address][]*list.Element
+}
+
+type pendingNonce struct {
+	address common.Address
+	value   string
+	expires time.Time
+}
+
+func newReservation(store ClaimStore) *reservation {
+	return &reservation{store: store, order: list.New(), nonces: make(map[common.Address][]*list.Element)}
+}
+
+// NonceMessage is the text a wallet signs with personal_sign to fetch the
+// proof of address in reservation mode
+func NonceMessage(address common.Address, nonce string) string {
+	return fmt.Sprintf("Sign this message to receive your airdrop proof.\n\nAddress: %s\nNonce: %s", address.Hex(), nonce)
+}
+
+// issueNonce adds a new nonce to those outstanding for address
+func (res *reservation) issueNonce(address common.Address, now time.Time) (pendingNonce, error) {
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		return pendingNonce{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := pendingNonce{address: address, value: hex.EncodeToString(raw[:]), expires: now.Add(nonceTTL)}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	// Every nonce lives for nonceTTL, so the expired ones are the oldest
+	for oldest := res.order.Front(); oldest != nil; oldest = res.order.Front() {
+		if !now.After(oldest.Value.(*pendingNonce).expires) && res.order.Len() < maxOutstandingNonces {
+			break
+		}
+		res.remove(oldest)
+	}
+	if pending := res.nonces[address]; len(pending) >= maxNoncesPerAddress {
+		res.remove(pending[0])
+	}
+	res.nonces[address] = append(res.nonces[address], res.order.PushBack(&nonce))
+	return nonce, nil
+}
+
+// remove drops an outstanding nonce; the caller holds res.mu
+func (res *reservation) remove(elem *list.Element) {
+	address := res.order.Remove(elem).(*pendingNonce).address
+	pending := slices.DeleteFunc(res.nonces[address], func(e *list.Element) bool { return e == elem })
+	if len(pending) == 0 {
+		delete(res.nonces, address)
+		return
+	}
+	res.nonces[address] = pending
+}
+
+// consumeNonce checks that signature is an EIP-191 signature by address of
+// one of its outstanding nonces, which can then not be used again
+func (res *reservation) consumeNonce(address common.Address, signature string, now time.Time) error {
+	sig, err := hex.DecodeString(strings.TrimPrefix(signature, "0x"))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return errors.New("signature must be 65 hex-encoded bytes")
+	}
+	// Wallets return v as 27 or 28; SigToPub expects 0 or 1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+
+	res.mu.Lock()
+	defer res.mu.Unlock()
+
+	valid := false
+	for _, elem := range res.nonces[address] {
+		nonce := elem.Value.(*pendingNonce)
+		if now.After(nonce.expires) {
+			continue
+		}
+		valid = true
+		pub, err := crypto.SigToPub(accounts.TextHash([]byte(NonceMessage(address, nonce.value))), sig)
+		if err == nil && crypto.PubkeyToAddress(*pub) == address {
+			res.remove(elem)
+			return nil
+		}
+	}
+	if !valid {
+		return errors.New("no valid nonce; request one from /api/nonce")
+	}
+	return errors.New("signature does not match the address")
+}
+
+// GetNonce issues a nonce to sign before fetching a proof in reservation
+// or privacy mode. Addresses outside the airdrop get one too, so it reveals nothing.
+func (s *APIServer) GetNonce(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	address := r.PathValue("address")
+	if !common.IsHexAddress(address) {
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
+		return
+	}
+
+	nonce, err := s.signedNonces().issueNonce(common.HexToAddress(address), s.now())
+	if err != nil {
+		s.requestLogger(r).Error("nonce generation failed", "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to generate nonce")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, NonceResponse{
+		Nonce:     nonce.value,
+		Message:   NonceMessage(common.HexToAddress(address), nonce.value),
+		ExpiresAt: nonce.expires.Unix(),
+		Success:   true,
+	})
+}
+
+// ResetIssuance lets an address fetch its proof again in reservation mode
+func (s *APIServer) ResetIssuance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeMethodNotAllowed(w, http.MethodDelete)
+		return
+	}
+	if !s.checkNotFinalized(w) {
+		return
+	}
+
+	address := r.PathValue("address")
+	if !common.IsHexAddress(address) {
+		writeError(w, http.StatusBadRequest, CodeInvalidAddress, "Invalid address format")
+		return
+	}
+
+	reset, err := s.reservation.store.Reset(common.HexToAddress(address))
+	if err != nil {
+		s.requestLogger(r).Error("issuance reset failed", "address", common.HexToAddress(address).Hex(), "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to reset issuance")
+		return
+	}
+	if !reset {
+		writeError(w, http.StatusNotFound, CodeNotIssued, "No proof has been issued to address")
+		return
+	}
+
+	s.requestLogger(r).Info("issuance reset", "address", common.HexToAddress(address).Hex())
+	writeJSON(w, http.StatusOK, IssuanceResetResponse{
+		Address: common.HexToAddress(address).Hex(),
+		Success: true,
+	})
+}
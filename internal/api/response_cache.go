@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"merkle-airdrop/internal/cache"
+)
+
+// cachedResponse is a successful response body kept by the response cache
+type cachedResponse struct {
+	contentType string
+	body        []byte
+}
+
+// WithResponseCache keeps up to size successful GET responses of the
+// expensive read-only endpoints for ttl, keyed on path and query
+func WithResponseCache(size int, ttl time.Duration) Option {
+	return func(s *APIServer) {
+		s.responses = cache.New[string, cachedResponse](size, ttl)
+	}
+}
+
+// InvalidateResponseCache drops every cached response; call it whenever the
+// served tree changes
+func (s *APIServer) InvalidateResponseCache() {
+	if s.responses != nil {
+		s.responses.Purge()
+	}
+}
+
+// cached serves GET requests for handler from the response cache when it is
+// enabled, marking them with X-Cache: HIT or MISS
+func (s *APIServer) cached(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.responses == nil || r.Method != http.MethodGet {
+			handler(w, r)
+			return
+		}
+
+		key := r.URL.Path + "?" + r.URL.RawQuery
+		if response, ok := s.responses.Get(key); ok {
+			w.Header().Set("Content-Type", response.contentType)
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(http.StatusOK)
+			w.Write(response.body)
+			return
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		recorder := &teeWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(recorder, r)
+		if recorder.status == http.StatusOK {
+			response := cachedResponse{
+				contentType: w.Header().Get("Content-Type"),
+				body:        recorder.body.Bytes(),
+			}
+			if s.tunables != nil {
+				s.responses.AddWithTTL(key, response, s.tunables.CacheTTL())
+			} else {
+				s.responses.Add(key, response)
+			}
+		}
+	}
+}
+
+// teeWriter passes a response through while keeping a copy of its body
+type teeWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (t *teeWriter) WriteHeader(status int) {
+	t.status = status
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *teeWriter) Write(b []byte) (int, error) {
+	t.body.Write(b)
+	return t.ResponseWriter.Write(b)
+}
@@ -0,0 +1,349 @@
+// internal/api/responses.go
+package api
+
+import (
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// The request and response bodies of the endpoints. Each is the schema its
+// endpoint declares, so /api/openapi.json describes exactly what is sent.
+
+// RootResponse is the body of GET /api/root
+type RootResponse struct {
+	MerkleRoot string              `json:"merkleRoot"`
+	Metadata   merkle.TreeMetadata `json:"metadata"`
+	Campaign   string              `json:"campaign,omitempty"` // Set for archived campaigns
+	Success    bool                `json:"success"`
+}
+
+// ProofResponse is the body of GET /api/proof/{address}
+type ProofResponse struct {
+	Address    string   `json:"address"`
+	Proof      []string `json:"proof"`
+	Amount     string   `json:"amount"`
+	Index      uint32   `json:"index"`
+	MerkleRoot string   `json:"merkleRoot"`
+
+	Positions     *uint64    `json:"positions,omitempty"`     // Set for trees without sorted pairs
+	AmountDisplay string     `json:"amountDisplay,omitempty"` // Set with ?unit=
+	IssuedAt      *time.Time `json:"issuedAt,omitempty"`      // Set in reservation mode
+	PaddingCount  int        `json:"paddingCount,omitempty"`  // Set for fixed-depth trees
+	Campaign      string     `json:"campaign,omitempty"`      // Set for archived campaigns
+
+	Success bool `json:"success"`
+}
+
+// ProofPendingResponse is the 202 body of GET /api/proof/{address} while
+// proofs are precomputed and the address's isn't ready
+type ProofPendingResponse struct {
+	Address  string  `json:"address"`
+	Ready    bool    `json:"ready"`
+	Progress float64 `json:"progress"` // Percent of proofs ready
+	Success  bool    `json:"success"`
+}
+
+// AddressNotFoundResponse is the 404 body of GET /api/proof/{address} with
+// ?suggest=true
+type AddressNotFoundResponse struct {
+	ErrorResponse
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// AlreadyIssuedResponse is the 409 body of GET /api/proof/{address} in
+// reservation mode
+type AlreadyIssuedResponse struct {
+	ErrorResponse
+	IssuedAt time.Time `json:"issuedAt"`
+}
+
+// ClaimNotOpenResponse is the 403 body of GET /api/proof/{address} and
+// /api/link/{address} before the claim window opens
+type ClaimNotOpenResponse struct {
+	ErrorResponse
+	OpensAt          time.Time `json:"opensAt"`
+	CountdownSeconds int64     `json:"countdownSeconds"` // Rounded up
+}
+
+// ClaimClosedResponse is the 410 body of GET /api/proof/{address} and
+// /api/link/{address} after the claim window closes
+type ClaimClosedResponse struct {
+	ErrorResponse
+	ClosedAt time.Time `json:"closedAt"`
+}
+
+// EligibilityResponse is the body of GET /api/eligible/{address}
+type EligibilityResponse struct {
+	Eligible bool `json:"eligible"`
+}
+
+// ClaimLinkResponse is the body of GET /api/link/{address}
+type ClaimLinkResponse struct {
+	Address    string `json:"address"`
+	Link       string `json:"link"`
+	MerkleRoot string `json:"merkleRoot"`
+	Success    bool   `json:"success"`
+}
+
+// StatsResponse is the body of GET /api/stats
+type StatsResponse struct {
+	TotalClaims int               `json:"totalClaims"`
+	TotalProofs int               `json:"totalProofs"`
+	TotalAmount string            `json:"totalAmount,omitempty"` // Omitted if a stored amount is invalid
+	MerkleRoot  string            `json:"merkleRoot"`
+	ProofDepth  int               `json:"proofDepth"`
+	Indices     merkle.IndexStats `json:"indices"`
+	AmountBits  int               `json:"amountBits"`
+
+	ProofCache *CacheStats     `json:"proofCache,omitempty"` // Set in lazy mode
+	Indexer    *IndexerStatus  `json:"indexer,omitempty"`    // Set while the claims indexer runs
+	Rebuild    *RebuildStatus  `json:"rebuild,omitempty"`    // Set after the first scheduled rebuild
+	SelfTest   *SelfTestResult `json:"selfTest,omitempty"`   // Set once the startup self-test has run
+	Tiers      []TierStats     `json:"tiers,omitempty"`      // Set with ?tiers=
+
+	ClaimWindow ClaimWindowStatus `json:"claimWindow"`
+
+	Finalization *Finalization `json:"finalization,omitempty"` // Set once the campaign is finalized
+
+	Success bool `json:"success"`
+}
+
+// ClaimWindowStatus is the campaign's claim window in /api/stats, with
+// open ends omitted
+type ClaimWindowStatus struct {
+	Start string     `json:"start,omitempty"`
+	End   string     `json:"end,omitempty"`
+	Phase ClaimPhase `json:"phase"`
+}
+
+// TierStats is one amount tier in /api/stats?tiers=: the claims with
+// amounts from Min up to, but not including, Max
+type TierStats struct {
+	Min       string `json:"min"`
+	Max       string `json:"max,omitempty"` // Omitted for the last tier
+	Addresses int    `json:"addresses"`
+	Total     string `json:"total"`
+}
+
+// IndexerStatus is the claims indexer's progress in /api/stats
+type IndexerStatus struct {
+	IndexedBlock uint64 `json:"indexedBlock"`
+	HeadBlock    uint64 `json:"headBlock"`
+	LagBlocks    uint64 `json:"lagBlocks"`
+	Synced       bool   `json:"synced"`
+}
+
+// VerifyRequest is the body of POST /api/verify
+type VerifyRequest struct {
+	Address string   `json:"address"`
+	Amount  string   `json:"amount"`
+	Index   *uint32  `json:"index,omitempty"` // Defaults to the address's
+	Proof   []string `json:"proof"`
+
+	Positions *uint64 `json:"positions,omitempty"` // Defaults to the address's
+
+	// The root the proof was generated against, as proofs files record it
+	// (root) or /api/proof returns it (merkleRoot). Proofs are always
+	// checked against the served root; one naming another root is a 409
+	// ROOT_MISMATCH.
+	Root       string `json:"root,omitempty"`
+	MerkleRoot string `json:"merkleRoot,omitempty"`
+}
+
+// VerifyResponse is the body of POST /api/verify
+type VerifyResponse struct {
+	Valid      bool   `json:"valid"`
+	Address    string `json:"address"`
+	Amount     string `json:"amount"`
+	MerkleRoot string `json:"merkleRoot"`
+
+	// Set for valid proofs when the server checks the claimed state
+	AlreadyClaimed  *bool  `json:"alreadyClaimed,omitempty"`
+	ContractAddress string `json:"contractAddress,omitempty"`
+
+	Trace *merkle.VerificationTrace `json:"trace,omitempty"` // With ?trace=true
+
+	Success bool `json:"success"`
+}
+
+// ProgressResponse is the body of GET /api/progress
+type ProgressResponse struct {
+	Done     int     `json:"done"`
+	Total    int     `json:"total"`
+	Percent  float64 `json:"percent"`
+	Complete bool    `json:"complete"`
+	Success  bool    `json:"success"`
+}
+
+// HealthResponse is the body of GET /healthz
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// NonceResponse is the body of GET /api/nonce/{address}
+type NonceResponse struct {
+	Nonce     string `json:"nonce"`
+	Message   string `json:"message"`   // What to sign
+	ExpiresAt int64  `json:"expiresAt"` // Unix seconds
+	Success   bool   `json:"success"`
+}
+
+// IssuanceResetResponse is the body of DELETE /api/admin/issuance/{address}
+type IssuanceResetResponse struct {
+	Address string `json:"address"`
+	Success bool   `json:"success"`
+}
+
+// AbuseClientStatus is one client tracked by abuse detection
+type AbuseClientStatus struct {
+	IP        string    `json:"ip"`
+	NotFound  int       `json:"notFound"`  // Misses in the window
+	Throttled bool      `json:"throttled"` // Over the threshold
+	Probes    int       `json:"probes"`    // Sequential runs and zero-address lookups flagged
+	Limited   int       `json:"limited"`   // Lookups delayed or rejected
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// AbuseResponse is the body of GET /api/admin/abuse
+type AbuseResponse struct {
+	Window      string              `json:"window"`
+	MaxNotFound int                 `json:"maxNotFound"`
+	Action      string              `json:"action"`
+	Clients     []AbuseClientStatus `json:"clients"`
+	Success     bool                `json:"success"`
+}
+
+// AbuseResetResponse is the body of DELETE /api/admin/abuse
+type AbuseResetResponse struct {
+	Reset   int  `json:"reset"` // Clients forgotten
+	Success bool `json:"success"`
+}
+
+// CampaignResponse is the body of GET /api/campaign
+type CampaignResponse struct {
+	Campaign    CampaignMeta `json:"campaign"`
+	MerkleRoot  string       `json:"merkleRoot"`
+	TotalClaims int          `json:"totalClaims"`
+	BasePath    string       `json:"basePath"` // Prefix of the API's paths, empty for none
+
+	Finalization *Finalization `json:"finalization,omitempty"` // Set once the campaign is finalized
+
+	Success bool `json:"success"`
+}
+
+// CampaignUpdateResponse is the body of PUT /api/admin/campaign
+type CampaignUpdateResponse struct {
+	Campaign CampaignMeta `json:"campaign"`
+	Success  bool         `json:"success"`
+}
+
+// AppendClaimEntry is one claim of an AppendClaimsRequest
+type AppendClaimEntry struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// AppendClaimsRequest is the body of POST /api/admin/claims
+type AppendClaimsRequest struct {
+	Claims []AppendClaimEntry `json:"claims"`
+}
+
+// AppendClaimsResponse is the body of POST /api/admin/claims
+type AppendClaimsResponse struct {
+	MerkleRoot   string          `json:"merkleRoot"`
+	PreviousRoot string          `json:"previousRoot"`
+	Version      int             `json:"version"`
+	Claims       []ProofResponse `json:"claims"` // Proofs of the added claims
+	Success      bool            `json:"success"`
+}
+
+// SimulateClaimRequest is the body of POST /api/admin/simulate
+type SimulateClaimRequest struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// SimulateClaimResponse is the body of POST /api/admin/simulate
+type SimulateClaimResponse struct {
+	MerkleRoot  string        `json:"merkleRoot"`  // The root with the claim added
+	CurrentRoot string        `json:"currentRoot"` // The served root, unchanged
+	Claim       ProofResponse `json:"claim"`       // The claim's proof under MerkleRoot
+	Success     bool          `json:"success"`
+}
+
+// ExistingClaim is a claim an appended address already has
+type ExistingClaim struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+	Index   uint32 `json:"index"`
+}
+
+// ClaimExistsResponse is the 409 body of POST /api/admin/claims and
+// /api/admin/simulate
+type ClaimExistsResponse struct {
+	ErrorResponse
+	Existing []ExistingClaim `json:"existing"`
+}
+
+// ConfigResponse is the body of GET /api/admin/config
+type ConfigResponse struct {
+	Config  interface{} `json:"config"`
+	Success bool        `json:"success"`
+}
+
+// ConfigReloadResponse is the body of POST /api/admin/config/reload
+type ConfigReloadResponse struct {
+	ReloadResult
+	Success bool `json:"success"`
+}
+
+// ConsistencyResponse is the body of GET /api/consistency/{address}: a
+// consistency proof when the address's claim is the same in both versions,
+// otherwise a difference proof
+type ConsistencyResponse struct {
+	From        int                      `json:"from"`
+	To          int                      `json:"to"`
+	Changed     bool                     `json:"changed"`
+	Consistency *merkle.ConsistencyProof `json:"consistency,omitempty"`
+	Difference  *merkle.DifferenceProof  `json:"difference,omitempty"`
+	Success     bool                     `json:"success"`
+}
+
+// TreeVersionsResponse is the body of GET /api/admin/versions
+type TreeVersionsResponse struct {
+	Versions []TreeVersion `json:"versions"`
+	Success  bool          `json:"success"`
+}
+
+// FinalizeRequest is the body of POST /api/admin/finalize
+type FinalizeRequest struct {
+	ContractAddress string `json:"contractAddress"` // Distributor deployed with the served root
+	TxHash          string `json:"txHash"`          // Of the deployment
+	Operator        string `json:"operator"`        // Who is finalizing, for the record
+}
+
+// FinalizeResponse is the body of POST /api/admin/finalize
+type FinalizeResponse struct {
+	Finalization Finalization `json:"finalization"`
+	Success      bool         `json:"success"`
+}
+
+// FinalizedResponse is the 423 body of requests that would change a
+// finalized campaign
+type FinalizedResponse struct {
+	ErrorResponse
+	Finalization Finalization `json:"finalization"`
+}
+
+// UnlockRequest is the body of POST /api/admin/finalize/force-unlock
+type UnlockRequest struct {
+	Operator string `json:"operator"`
+	Reason   string `json:"reason"` // Why the deployed campaign may change, for the log
+}
+
+// UnlockResponse is the body of POST /api/admin/finalize/force-unlock
+type UnlockResponse struct {
+	Unlocked Finalization `json:"unlocked"` // The lifted finalization
+	Success  bool         `json:"success"`
+}
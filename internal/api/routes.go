@@ -0,0 +1,162 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Router registers public and admin API routes on a ServeMux
+type Router struct {
+	mux      *http.ServeMux
+	auth     *adminAuth
+	base     string           // Prefix of every route, empty for none
+	fallback http.HandlerFunc // Serves requests no route matches; nil for the JSON 404
+	docsPath string           // The root redirects here when there is no fallback
+
+	endpoints []Endpoint
+	docs      sync.Once // Renders the OpenAPI document and docs page
+	spec      []byte
+	page      []byte
+}
+
+// NewRouter creates a router whose admin routes accept the given bearer
+// tokens. With no tokens, admin routes respond 404. Unknown routes get a
+// JSON 404.
+func NewRouter(adminTokens []string) *Router {
+	rt := &Router{
+		mux:  http.NewServeMux(),
+		auth: newAdminAuth(adminTokens),
+	}
+	rt.mux.HandleFunc("/", rt.serveUnmatched)
+	return rt
+}
+
+// CleanBasePath normalizes a route prefix: one leading slash, no trailing
+// slash and no repeated slashes. "" and "/" give "", for no prefix.
+func CleanBasePath(base string) string {
+	var parts []string
+	for _, part := range strings.Split(base, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// SetBasePath serves every route under base, e.g. "/airdrop" for
+// /airdrop/api/proof/; see CleanBasePath. It must be called before any
+// route is registered.
+func (rt *Router) SetBasePath(base string) {
+	if len(rt.endpoints) > 0 {
+		panic("api: base path set after routes were registered")
+	}
+	rt.base = CleanBasePath(base)
+}
+
+// BasePath returns the prefix of every route, empty for none
+func (rt *Router) BasePath() string {
+	return rt.base
+}
+
+// Endpoint is a route with the description /api/openapi.json publishes
+// for it. Routes are only registered as endpoints, so the document lists
+// every one.
+type Endpoint struct {
+	// Path is the ServeMux pattern. One ending in a slash takes the rest
+	// of the path as the parameter named Param.
+	Path  string
+	Param string
+
+	Methods []string // Accepted methods; the handler rejects the others
+	Summary string
+	Query   []QueryParam
+
+	Request   interface{}         // Zero value of the JSON body, or nil
+	Responses map[int]interface{} // Zero value of the body by status; errors are ErrorResponse
+
+	Admin   bool // Requires an admin bearer token
+	Handler http.HandlerFunc
+}
+
+// QueryParam is an optional query parameter of an endpoint
+type QueryParam struct {
+	Name        string
+	Description string
+}
+
+// RawBody is a response body that isn't JSON, naming its content type
+type RawBody string
+
+// Handle registers an endpoint, panicking like ServeMux on one it can't
+// describe
+func (rt *Router) Handle(e Endpoint) {
+	if len(e.Methods) == 0 || e.Handler == nil {
+		panic("api: endpoint " + e.Path + " needs methods and a handler")
+	}
+	if strings.HasSuffix(e.Path, "/") != (e.Param != "") {
+		panic("api: endpoint " + e.Path + " must end in a slash exactly when it has a path parameter")
+	}
+
+	var handler http.Handler = e.Handler
+	if e.Admin {
+		handler = rt.auth.require(handler)
+	}
+	if e.Param != "" {
+		// Handlers read the parameter with r.PathValue, whatever the prefix
+		prefix, inner := rt.base+e.Path, handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.SetPathValue(e.Param, strings.TrimPrefix(r.URL.Path, prefix))
+			inner.ServeHTTP(w, r)
+		})
+	}
+	rt.mux.Handle(rt.base+e.Path, handler)
+	rt.endpoints = append(rt.endpoints, e)
+}
+
+// Endpoints returns the registered endpoints in registration order
+func (rt *Router) Endpoints() []Endpoint {
+	return slices.Clone(rt.endpoints)
+}
+
+// HandleFallback serves requests under the base path that match no route
+// with handler instead of the JSON 404. It sees paths with the base path
+// taken off.
+func (rt *Router) HandleFallback(handler http.HandlerFunc) {
+	rt.fallback = handler
+}
+
+// serveUnmatched answers requests no route matches. The bare base path
+// gets a trailing slash, and without a fallback the root redirects to the
+// docs page.
+func (rt *Router) serveUnmatched(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, rt.base)
+	switch {
+	case !ok || (rest != "" && rest[0] != '/'):
+		notFound(w, r)
+	case rest == "":
+		http.Redirect(w, r, rt.base+"/", http.StatusMovedPermanently)
+	case rt.fallback != nil:
+		stripped := new(http.Request)
+		*stripped = *r
+		stripped.URL = new(url.URL)
+		*stripped.URL = *r.URL
+		stripped.URL.Path = rest
+		stripped.URL.RawPath = ""
+		rt.fallback(w, stripped)
+	case rest == "/" && rt.docsPath != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead):
+		http.Redirect(w, r, rt.base+rt.docsPath, http.StatusFound)
+	default:
+		notFound(w, r)
+	}
+}
+
+// ServeHTTP dispatches the request to the registered routes
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"merkle-airdrop/pkg/data"
+)
+
+// jsonSchemaDialect is the JSON Schema version /api/schema is written in
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaBodies are the request and response bodies of every endpoint,
+// whichever options enable it, so /api/schema describes them all
+var schemaBodies = []interface{}{
+	ErrorResponse{},
+	RootResponse{},
+	ProofResponse{},
+	ProofPendingResponse{},
+	AddressNotFoundResponse{},
+	AlreadyIssuedResponse{},
+	ClaimNotOpenResponse{},
+	ClaimClosedResponse{},
+	EligibilityResponse{},
+	ClaimLinkResponse{},
+	StatsResponse{},
+	VerifyRequest{},
+	VerifyResponse{},
+	data.BatchEntry{},
+	data.BatchResult{},
+	ProgressResponse{},
+	HealthResponse{},
+	NonceResponse{},
+	IssuanceResetResponse{},
+	AbuseResponse{},
+	AbuseResetResponse{},
+	CampaignMeta{},
+	CampaignResponse{},
+	CampaignUpdateResponse{},
+	SimulateClaimRequest{},
+	SimulateClaimResponse{},
+	AppendClaimsRequest{},
+	AppendClaimsResponse{},
+	ClaimExistsResponse{},
+	TreeVersionsResponse{},
+	FinalizeRequest{},
+	FinalizeResponse{},
+	FinalizedResponse{},
+	UnlockRequest{},
+	UnlockResponse{},
+	ConsistencyResponse{},
+	ConfigResponse{},
+	ConfigReloadResponse{},
+}
+
+var (
+	schemaOnce     sync.Once
+	schemaDocument []byte
+)
+
+// JSONSchema describes every request and response body of the API as one
+// JSON Schema document, each type under $defs by its Go name. The code of
+// APIError lists every error code with its meaning.
+func JSONSchema() map[string]interface{} {
+	schemas := &schemaBuilder{components: map[string]interface{}{}, refPrefix: "#/$defs/"}
+	for _, body := range schemaBodies {
+		schemas.of(reflect.TypeOf(body))
+	}
+
+	codes := make([]interface{}, len(errorCodes))
+	for i, code := range errorCodes {
+		codes[i] = map[string]interface{}{"const": code.code, "description": code.description}
+	}
+	apiError := schemas.components["APIError"].(map[string]interface{})
+	apiError["properties"].(map[string]interface{})["code"] = map[string]interface{}{"type": "string", "oneOf": codes}
+
+	return map[string]interface{}{
+		"$schema":  jsonSchemaDialect,
+		"title":    openAPITitle + " bodies",
+		"$comment": "Generated from the structs of internal/api; regenerate with the CLI's schema command",
+		"$defs":    schemas.components,
+	}
+}
+
+// GetSchema serves JSONSchema
+func (s *APIServer) GetSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	schemaOnce.Do(func() {
+		schemaDocument, _ = json.MarshalIndent(JSONSchema(), "", "  ")
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(schemaDocument)
+}
+
+// TypeScriptDefinitions renders JSONSchema as a TypeScript declaration
+// file: an interface per type and an ErrorCode union of the error codes
+func TypeScriptDefinitions() string {
+	doc := JSONSchema()
+	defs := doc["$defs"].(map[string]interface{})
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("// Types of the " + openAPITitle + " bodies, generated from the structs of\n")
+	b.WriteString("// internal/api by the CLI's schema command. Do not edit.\n\n")
+	b.WriteString("/** Codes of APIError.code */\nexport type ErrorCode =\n")
+	for _, code := range errorCodes {
+		b.WriteString("  /** " + code.description + " */\n  | " + tsString(code.code) + "\n")
+	}
+	b.WriteString(";\n")
+	for _, name := range names {
+		b.WriteString("\nexport interface " + name + " " + tsObject(defs[name].(map[string]interface{}), "") + "\n")
+	}
+	return b.String()
+}
+
+// tsType returns the TypeScript type of a schema, indenting nested
+// objects by indent
+func tsType(schema map[string]interface{}, indent string) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return strings.TrimPrefix(ref, "#/$defs/")
+	}
+	if _, ok := schema["oneOf"]; ok {
+		return "ErrorCode" // The only union is APIError's code
+	}
+	switch schema["type"] {
+	case "string":
+		return "string"
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		item := tsType(schema["items"].(map[string]interface{}), indent)
+		if strings.ContainsAny(item, " {") {
+			return "Array<" + item + ">"
+		}
+		return item + "[]"
+	case "object":
+		if values, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			return "Record<string, " + tsType(values, indent) + ">"
+		}
+		return tsObject(schema, indent)
+	}
+	return "unknown"
+}
+
+// tsObject returns the TypeScript object type of an object schema, its
+// properties in name order and optional unless required
+func tsObject(schema map[string]interface{}, indent string) string {
+	properties, _ := schema["properties"].(map[string]interface{})
+	if len(properties) == 0 {
+		return "{}"
+	}
+	required := map[string]bool{}
+	if names, ok := schema["required"].([]string); ok {
+		for _, name := range names {
+			required[name] = true
+		}
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range names {
+		optional := "?"
+		if required[name] {
+			optional = ""
+		}
+		b.WriteString(indent + "  " + tsKey(name) + optional + ": " + tsType(properties[name].(map[string]interface{}), indent+"  ") + ";\n")
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// tsIdentifier matches property names TypeScript takes unquoted
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsKey writes a property name, quoted unless it is an identifier
+func tsKey(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return tsString(name)
+}
+
+// tsString quotes s as a TypeScript string literal
+func tsString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
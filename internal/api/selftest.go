@@ -0,0 +1,174 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultSelfTestSamples is the number of proofs a startup self-test
+// verifies unless configured otherwise
+const DefaultSelfTestSamples = 100
+
+// DefaultSelfTestBudget bounds a startup self-test unless configured
+// otherwise
+const DefaultSelfTestBudget = 5 * time.Second
+
+// SelfTestResult is the outcome of SelfTest, reported in /api/stats
+type SelfTestResult struct {
+	Passed   bool      `json:"passed"`
+	Checked  int       `json:"checked"` // Proofs verified, not counting the corrupted one
+	Duration string    `json:"duration"`
+	At       time.Time `json:"at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// SelfTest verifies the proofs of up to samples random claims against the
+// served root, then checks that a corrupted proof fails, so a proofs file
+// that doesn't match its root or a broken verifier is caught before
+// serving. It fails if it takes longer than budget, when budget is
+// non-zero. The result is reported in /api/stats, and /readyz answers 503
+// once a self-test has failed.
+func (s *APIServer) SelfTest(samples int, budget time.Duration) (SelfTestResult, error) {
+	start := time.Now()
+	checked, err := s.selfTest(samples, budget, start)
+
+	result := SelfTestResult{
+		Passed:   err == nil,
+		Checked:  checked,
+		Duration: time.Since(start).String(),
+		At:       start.UTC(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		s.logger.Error("self-test failed", "checked", checked, "error", err)
+	} else {
+		s.logger.Info("self-test passed", "checked", checked, "duration", result.Duration)
+	}
+	s.selfTestResult.Store(&result)
+	return result, err
+}
+
+// selfTest runs SelfTest, returning the number of proofs verified
+func (s *APIServer) selfTest(samples int, budget time.Duration, start time.Time) (int, error) {
+	if s.verifyOnly {
+		return 0, fmt.Errorf("a verify-only server has no proofs to check")
+	}
+
+	addresses, err := s.sampleAddresses(samples)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sample proofs: %w", err)
+	}
+	var first *merkle.AirdropClaim
+	var firstProof *merkle.MerkleProof
+	for i, address := range addresses {
+		if budget > 0 && time.Since(start) > budget {
+			return i, fmt.Errorf("checked %d of %d proofs before running out of the %v budget", i, len(addresses), budget)
+		}
+
+		proof, exists, err := s.selfTestProof(address)
+		if err != nil {
+			return i, fmt.Errorf("failed to get the proof for %s: %w", address.Hex(), err)
+		}
+		if !exists {
+			return i, fmt.Errorf("claim %s has no proof", address.Hex())
+		}
+		amount, ok := new(big.Int).SetString(proof.Amount, 10)
+		if !ok {
+			return i, fmt.Errorf("proof for %s has invalid amount %q", address.Hex(), proof.Amount)
+		}
+		claim := merkle.AirdropClaim{Address: address, Amount: amount, Index: proof.Index}
+		valid, err := merkle.VerifyProofWithPositions(s.rootBytes, claim, proof.Proof, proof.Positions, s.options)
+		if err != nil {
+			return i, fmt.Errorf("proof for %s: %w", address.Hex(), err)
+		}
+		if !valid {
+			return i, fmt.Errorf("proof for %s (index %d, amount %s) does not verify against root %s", address.Hex(), proof.Index, proof.Amount, s.root)
+		}
+		if first == nil {
+			first, firstProof = &claim, proof
+		}
+	}
+	if first == nil {
+		return 0, nil // No claims to check
+	}
+
+	// A verifier that accepts anything would pass every check above
+	corrupted := *first
+	elements := append([]string(nil), firstProof.Proof...)
+	if len(elements) > 0 {
+		hash := common.HexToHash(elements[0])
+		hash[common.HashLength-1] ^= 1
+		elements[0] = hash.Hex()
+	} else {
+		corrupted.Amount = new(big.Int).Add(first.Amount, big.NewInt(1))
+	}
+	if valid, _ := merkle.VerifyProofWithPositions(s.rootBytes, corrupted, elements, firstProof.Positions, s.options); valid {
+		return len(addresses), fmt.Errorf("a corrupted proof for %s verified; the verifier is broken", first.Address.Hex())
+	}
+	return len(addresses), nil
+}
+
+// selfTestProof returns the proof served for address. In lazy mode it is
+// generated without going through the cache, leaving its stats alone.
+func (s *APIServer) selfTestProof(address common.Address) (*merkle.MerkleProof, bool, error) {
+	if s.cache == nil {
+		return s.lookupProof(address)
+	}
+	proof, err := s.tree.GenerateProof(address)
+	if errors.Is(err, merkle.ErrAddressNotFound) {
+		return nil, false, nil
+	}
+	return proof, err == nil, err
+}
+
+// sampleAddresses picks up to n random airdrop addresses
+func (s *APIServer) sampleAddresses(n int) ([]common.Address, error) {
+	var sample []common.Address
+	seen := 0
+	pick := func(address common.Address) {
+		// Reservoir sampling keeps each address with the same chance
+		seen++
+		if len(sample) < n {
+			sample = append(sample, address)
+		} else if j := rand.Intn(seen); j < n {
+			sample[j] = address
+		}
+	}
+	if s.cache != nil || s.precompute != nil {
+		for _, claim := range s.tree.Claims {
+			pick(claim.Address)
+		}
+	} else {
+		err := s.proofs.Iterate(func(address common.Address, _ *merkle.MerkleProof) bool {
+			pick(address)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sample, nil
+}
+
+// Ready reports whether the server should receive traffic: 503 once a
+// self-test has failed, 200 otherwise
+func (s *APIServer) Ready(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	if result := s.selfTestResult.Load(); result != nil && !result.Passed {
+		writeError(w, http.StatusServiceUnavailable, CodeSelfTestFailed, "Self-test failed: "+result.Error)
+		return
+	}
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
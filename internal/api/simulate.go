@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// SimulateClaim answers with the root the tree would have with the claim
+// in the request body added, and the claim's proof under it, without
+// adding it
+func (s *APIServer) SimulateClaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var req SimulateClaimRequest
+	if !s.decodeJSONBody(w, r, &req) {
+		return
+	}
+	claim, code, message := parseClaimEntry(AppendClaimEntry(req))
+	if code != "" {
+		writeError(w, http.StatusBadRequest, code, "Claim: "+message)
+		return
+	}
+	if err := merkle.CheckAmountBits([]merkle.AirdropClaim{claim}, s.options.MaxAmountBits); err != nil {
+		writeError(w, http.StatusBadRequest, CodeInvalidAmount, err.Error())
+		return
+	}
+
+	root, proof, err := s.tree.SimulateAddClaim(claim)
+	if writeClaimExists(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, merkle.ErrTooManyClaims):
+		writeError(w, http.StatusUnprocessableEntity, CodeTooManyClaims, "Adding the claim would exceed the claim limit: "+err.Error())
+		return
+	case err != nil:
+		s.requestLogger(r).Error("claim simulation failed", "address", claim.Address.Hex(), "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to simulate the claim")
+		return
+	}
+
+	response := SimulateClaimResponse{
+		MerkleRoot:  proof.Root,
+		CurrentRoot: s.root,
+		Claim: ProofResponse{
+			Address:      claim.Address.Hex(),
+			Proof:        proof.Proof,
+			Amount:       proof.Amount,
+			Index:        proof.Index,
+			MerkleRoot:   merkle.EncodeHash(root),
+			PaddingCount: proof.PaddingCount,
+			Success:      true,
+		},
+		Success: true,
+	}
+	if !s.options.SortedPairs {
+		response.Claim.Positions = &proof.Positions
+	}
+
+	s.requestLogger(r).Info("claim simulated", "address", response.Claim.Address, "root", response.MerkleRoot)
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// staticIndex is served for the site root and for client-side routes
+const staticIndex = "index.html"
+
+// WithStaticDir serves the claim site in dir under / or the base path,
+// next to the API.
+// Paths without a file fall back to index.html for client-side routing, and
+// the {{MERKLE_ROOT}} and {{CONTRACT_ADDRESS}} placeholders in index.html are
+// filled in from the tree and contractAddress as it is served.
+func WithStaticDir(dir, contractAddress string) Option {
+	return func(s *APIServer) {
+		s.staticDir = dir
+		s.contractAddress = contractAddress
+	}
+}
+
+// ServeStatic serves a file of the claim site. Unknown /api/ paths keep the
+// JSON 404; so do missing files with an extension, which are assets rather
+// than client-side routes.
+func (s *APIServer) ServeStatic(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+		notFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	// os.Root refuses names that resolve outside the directory, symlinks
+	// included
+	root, err := os.OpenRoot(s.staticDir)
+	if err != nil {
+		s.requestLogger(r).Error("static directory unavailable", "dir", s.staticDir, "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Static files are unavailable")
+		return
+	}
+	defer root.Close()
+
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	if name == "" {
+		name = staticIndex
+	}
+	info, err := fs.Stat(root.FS(), name)
+	if err == nil && info.IsDir() {
+		name = path.Join(name, staticIndex)
+		info, err = fs.Stat(root.FS(), name)
+	}
+	if err != nil {
+		if path.Ext(name) != "" && path.Base(name) != staticIndex {
+			notFound(w, r)
+			return
+		}
+		name = staticIndex
+	}
+
+	if path.Base(name) == staticIndex {
+		s.serveIndex(w, r, root, name)
+		return
+	}
+
+	file, err := root.Open(name)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+	defer file.Close()
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// serveIndex serves an index.html with its placeholders filled in. It is
+// read on every request, so a redeployed site is picked up without a
+// restart.
+func (s *APIServer) serveIndex(w http.ResponseWriter, r *http.Request, root *os.Root, name string) {
+	content, err := fs.ReadFile(root.FS(), name)
+	if errors.Is(err, fs.ErrNotExist) {
+		notFound(w, r)
+		return
+	}
+	if err != nil {
+		s.requestLogger(r).Error("failed to read index", "name", name, "error", err)
+		writeError(w, http.StatusInternalServerError, CodeInternal, "Failed to read index.html")
+		return
+	}
+
+	content = []byte(strings.NewReplacer(
+		"{{MERKLE_ROOT}}", s.root,
+		"{{CONTRACT_ADDRESS}}", s.contractAddress,
+	).Replace(string(content)))
+
+	// The placeholders change with the tree, so clients must revalidate
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, staticIndex, time.Time{}, bytes.NewReader(content))
+}
@@ -0,0 +1,149 @@
+// internal/api/suggest.go
+package api
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// maxSuggestions is the most addresses returned for a missed lookup
+	maxSuggestions = 3
+	// maxSuggestionDistance is the largest edit distance still considered a typo
+	maxSuggestionDistance = 2
+	// maxSuggestionCandidates bounds the work per lookup when many addresses
+	// share a bucket, e.g. vanity prefixes
+	maxSuggestionCandidates = 256
+
+	affixLen = 8 // 4 bytes of hex
+)
+
+// segmentBounds splits the 40 hex characters of an address into three
+// parts. Two edits leave at least one part intact, shifted by at most one
+// position, so indexing each part finds every address within distance 2.
+var segmentBounds = [3][2]int{{0, 13}, {13, 26}, {26, 40}}
+
+// ClaimStatus reports whether a claim index has already been claimed on chain
+type ClaimStatus interface {
+	IsClaimed(index uint32) (bool, error)
+}
+
+// Suggestion is an airdrop address similar to one that was not found
+type Suggestion struct {
+	Address string `json:"address"`
+	Claimed *bool  `json:"claimed,omitempty"` // Omitted when unknown
+}
+
+// suggestionIndex finds airdrop addresses that look like a mistyped one.
+// Addresses are bucketed by their first and last 4 bytes and by each
+// segment, so a lookup only compares against a few candidates.
+type suggestionIndex struct {
+	addresses []common.Address
+	prefixes  map[string][]int
+	suffixes  map[string][]int
+	segments  [3]map[string][]int
+}
+
+func newSuggestionIndex(addresses []common.Address) *suggestionIndex {
+	idx := &suggestionIndex{
+		addresses: addresses,
+		prefixes:  make(map[string][]int, len(addresses)),
+		suffixes:  make(map[string][]int, len(addresses)),
+	}
+	for i := range idx.segments {
+		idx.segments[i] = make(map[string][]int, len(addresses))
+	}
+
+	for i, address := range addresses {
+		h := hex.EncodeToString(address.Bytes())
+		idx.prefixes[h[:affixLen]] = append(idx.prefixes[h[:affixLen]], i)
+		idx.suffixes[h[len(h)-affixLen:]] = append(idx.suffixes[h[len(h)-affixLen:]], i)
+		for s, b := range segmentBounds {
+			key := h[b[0]:b[1]]
+			idx.segments[s][key] = append(idx.segments[s][key], i)
+		}
+	}
+	return idx
+}
+
+// suggest returns up to maxSuggestions addresses sharing the first or last
+// 4 bytes of address or within edit distance 2 of it, closest first
+func (idx *suggestionIndex) suggest(address common.Address) []common.Address {
+	h := hex.EncodeToString(address.Bytes())
+
+	type candidate struct {
+		index    int
+		distance int
+	}
+	seen := make(map[int]bool)
+	var candidates []candidate
+
+	consider := func(bucket []int, affix bool) {
+		for _, i := range bucket {
+			if len(seen) >= maxSuggestionCandidates {
+				return
+			}
+			if seen[i] || idx.addresses[i] == address {
+				continue
+			}
+			seen[i] = true
+
+			distance := levenshtein(h, hex.EncodeToString(idx.addresses[i].Bytes()))
+			if affix || distance <= maxSuggestionDistance {
+				candidates = append(candidates, candidate{i, distance})
+			}
+		}
+	}
+
+	consider(idx.prefixes[h[:affixLen]], true)
+	consider(idx.suffixes[h[len(h)-affixLen:]], true)
+	for s, b := range segmentBounds {
+		for shift := -1; shift <= 1; shift++ {
+			start, end := b[0]+shift, b[1]+shift
+			if start < 0 || end > len(h) {
+				continue
+			}
+			consider(idx.segments[s][h[start:end]], false)
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].distance != candidates[b].distance {
+			return candidates[a].distance < candidates[b].distance
+		}
+		return candidates[a].index < candidates[b].index
+	})
+	if len(candidates) > maxSuggestions {
+		candidates = candidates[:maxSuggestions]
+	}
+
+	suggestions := make([]common.Address, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = idx.addresses[c.index]
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+const (
+	// defaultTraceLimit is the number of traced verifications a client IP
+	// may request per traceWindow without WithTraceLimit
+	defaultTraceLimit = 10
+	traceWindow       = time.Minute
+)
+
+// windowLimiter caps events per key within a sliding window. It caps
+// traced verifications per client IP: traces hash the whole proof and look
+// up the tree's, so they are limited harder than plain ones.
+type windowLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu      sync.Mutex
+	clients map[string][]time.Time // Events within the window, oldest first
+}
+
+func newWindowLimiter(limit int, window time.Duration) *windowLimiter {
+	return &windowLimiter{limit: limit, window: window, clients: make(map[string][]time.Time)}
+}
+
+// allow records an event for key, reporting how long it must wait instead
+// if it is over the limit
+func (l *windowLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.clients[key]; !ok && len(l.clients) >= abusePruneThreshold {
+		for other, times := range l.clients {
+			if len(l.expire(times, now)) == 0 {
+				delete(l.clients, other)
+			}
+		}
+	}
+	times := l.expire(l.clients[key], now)
+	if len(times) >= l.limit {
+		l.clients[key] = times
+		return times[0].Add(l.window).Sub(now), false
+	}
+	l.clients[key] = append(times, now)
+	return 0, true
+}
+
+// expire drops the times that have left the window
+func (l *windowLimiter) expire(times []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-l.window)
+	return times[sort.Search(len(times), func(i int) bool { return times[i].After(cutoff) }):]
+}
+
+// WithTraceLimit lets each client IP request limit traced verifications,
+// POST /api/verify?trace=true, per minute instead of the default 10.
+// Servers created with the same option share the count.
+func WithTraceLimit(limit int) Option {
+	limiter := newWindowLimiter(limit, traceWindow)
+	return func(s *APIServer) {
+		s.traceLimit = limiter
+	}
+}
+
+// checkTraceLimit answers 429 when r's client has used up its traces,
+// reporting whether the trace may go ahead
+func (s *APIServer) checkTraceLimit(w http.ResponseWriter, r *http.Request) bool {
+	ip := s.clientIP(r)
+	wait, ok := s.traceLimit.allow(ip, s.now())
+	if ok {
+		return true
+	}
+	s.requestLogger(r).Warn("verification trace rejected", "ip", ip)
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+	writeError(w, http.StatusTooManyRequests, CodeRateLimited, "Too many traced verifications; at most "+strconv.Itoa(s.traceLimit.limit)+" a minute")
+	return false
+}
+
+// traceReference returns what the served tree holds for address, nil when
+// there is nothing to compare with or it may not be revealed
+func (s *APIServer) traceReference(r *http.Request, claim merkle.AirdropClaim) *merkle.TraceReference {
+	// A LEAF_MISMATCH would name the tree's amount
+	if s.verifyOnly || s.privacy != nil {
+		return nil
+	}
+	proof, exists, err := s.lookupProof(claim.Address)
+	if err != nil {
+		s.requestLogger(r).Error("proof lookup for trace failed", "address", claim.Address.Hex(), "error", err)
+		return nil
+	}
+	if !exists {
+		return &merkle.TraceReference{}
+	}
+	return &merkle.TraceReference{Proof: proof}
+}
@@ -0,0 +1,213 @@
+// internal/api/tunables.go
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CORSPolicy sets the CORS headers of responses. The zero policy allows
+// any origin.
+type CORSPolicy struct {
+	Disabled bool     // No CORS headers are sent and preflights are not answered
+	Origins  []string // Origins allowed to read responses; any when empty
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, empty when it is not allowed
+func (p CORSPolicy) allowOrigin(origin string) string {
+	if len(p.Origins) == 0 || slices.Contains(p.Origins, "*") {
+		return "*"
+	}
+	if slices.Contains(p.Origins, origin) {
+		return origin
+	}
+	return ""
+}
+
+// Tunables are the settings of a running server that a config reload can
+// change: its CORS policy, abuse detection thresholds, response cache TTL
+// and the served campaign's claim window. Servers created with the same
+// WithTunables share them, so a rebuilt tree is served with the current
+// values.
+type Tunables struct {
+	mu       sync.RWMutex
+	cors     CORSPolicy
+	abuse    AbuseConfig
+	cacheTTL time.Duration
+	campaign *campaign // The servers' campaign, for SetClaimWindow
+}
+
+// NewTunables creates tunables with the settings a server starts with.
+// abuse replaces the config of WithAbuseDetection and cacheTTL the TTL of
+// WithResponseCache; neither turns its feature on.
+func NewTunables(cors CORSPolicy, abuse AbuseConfig, cacheTTL time.Duration) *Tunables {
+	return &Tunables{cors: cors, abuse: abuse, cacheTTL: cacheTTL}
+}
+
+// WithTunables serves with the settings in t, which may change while the
+// server runs
+func WithTunables(t *Tunables) Option {
+	return func(s *APIServer) {
+		s.tunables = t
+	}
+}
+
+// linkTunables points the server's abuse detector and campaign at its
+// tunables once every option has been applied
+func (s *APIServer) linkTunables() {
+	if s.tunables == nil {
+		return
+	}
+	if s.abuse != nil {
+		s.abuse.live = s.tunables
+	}
+	if s.campaign != nil {
+		s.tunables.mu.Lock()
+		s.tunables.campaign = s.campaign
+		s.tunables.mu.Unlock()
+	}
+}
+
+// CORS returns the CORS policy
+func (t *Tunables) CORS() CORSPolicy {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cors
+}
+
+// SetCORS replaces the CORS policy
+func (t *Tunables) SetCORS(policy CORSPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cors = CORSPolicy{Disabled: policy.Disabled, Origins: slices.Clone(policy.Origins)}
+}
+
+// Abuse returns the abuse detection thresholds
+func (t *Tunables) Abuse() AbuseConfig {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.abuse
+}
+
+// SetAbuse replaces the abuse detection thresholds. Clients' recorded
+// misses are kept and counted against the new window.
+func (t *Tunables) SetAbuse(cfg AbuseConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.abuse = cfg
+}
+
+// CacheTTL returns how long responses are cached
+func (t *Tunables) CacheTTL() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.cacheTTL
+}
+
+// SetCacheTTL sets how long responses cached from now on are kept;
+// responses already cached keep their expiry
+func (t *Tunables) SetCacheTTL(ttl time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cacheTTL = ttl
+}
+
+// SetClaimWindow replaces the served campaign's claim window, or removes
+// it when window is nil. The change is kept in memory only, like the
+// campaign settings it comes from.
+func (t *Tunables) SetClaimWindow(window *ClaimWindow) error {
+	t.mu.RLock()
+	c := t.campaign
+	t.mu.RUnlock()
+	if c == nil {
+		return fmt.Errorf("no campaign is served")
+	}
+	if window != nil {
+		if err := window.Validate(); err != nil {
+			return err
+		}
+	}
+	c.setClaimWindow(window)
+	return nil
+}
+
+// cors returns the server's CORS policy
+func (s *APIServer) cors() CORSPolicy {
+	if s.tunables == nil {
+		return CORSPolicy{}
+	}
+	return s.tunables.CORS()
+}
+
+// ConfigReloader re-reads the server's configuration for
+// POST /api/admin/config/reload
+type ConfigReloader interface {
+	// ReloadConfig applies the changed settings that can change while the
+	// server runs, reporting the others as skipped. A config that fails
+	// validation is rejected whole.
+	ReloadConfig() (ReloadResult, error)
+	// RunningConfig returns the applied configuration with its secrets
+	// redacted
+	RunningConfig() interface{}
+}
+
+// ReloadResult is what a config reload changed
+type ReloadResult struct {
+	Applied []SettingChange `json:"applied"`
+	Skipped []SettingChange `json:"skipped"`
+}
+
+// SettingChange is a setting that differs between the running and the
+// reloaded config
+type SettingChange struct {
+	Setting string `json:"setting"`          // Section and key, e.g. server.cors_origins
+	Reason  string `json:"reason,omitempty"` // Why a skipped change was not applied
+}
+
+// WithConfigReloader lets admins reload the configuration with
+// POST /api/admin/config/reload and read it at /api/admin/config
+func WithConfigReloader(reloader ConfigReloader) Option {
+	return func(s *APIServer) {
+		s.reloader = reloader
+	}
+}
+
+// GetConfig returns the running configuration with secrets redacted
+func (s *APIServer) GetConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJSON(w, http.StatusOK, ConfigResponse{Config: s.reloader.RunningConfig(), Success: true})
+}
+
+// ReloadConfig re-reads the configuration file, applying what can change
+// without a restart
+func (s *APIServer) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+	if !s.checkNotFinalized(w) {
+		return
+	}
+
+	result, err := s.reloader.ReloadConfig()
+	var finalizedErr *FinalizedError
+	if errors.As(err, &finalizedErr) {
+		writeFinalized(w, finalizedErr.Finalization)
+		return
+	}
+	if err != nil {
+		s.requestLogger(r).Warn("config reload rejected", "error", err)
+		writeError(w, http.StatusUnprocessableEntity, CodeInvalidConfig, err.Error())
+		return
+	}
+	s.requestLogger(r).Info("config reloaded", "applied", len(result.Applied), "skipped", len(result.Skipped))
+	writeJSON(w, http.StatusOK, ConfigReloadResponse{ReloadResult: result, Success: true})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// noClaimDataMessage explains the 404 of endpoints a verify-only server
+// cannot answer
+const noClaimDataMessage = "This server holds only the Merkle root: proofs, eligibility and claim statistics are not available. Use /api/root and POST /api/verify."
+
+// NewVerifyOnlyServer creates a server that holds no claims, only root and
+// the leaf encoding its proofs verify with. It serves /api/root and
+// /api/verify, which then needs the index (and the positions without sorted
+// pairs) in the request; endpoints that need claim data answer 404. Options
+// that serve claim data, such as suggestions, Bloom filters, reservation
+// archives and campaigns, are ignored. root must be 32 bytes.
+func NewVerifyOnlyServer(root []byte, encoding merkle.TreeOptions, opts ...Option) (*APIServer, error) {
+	if len(root) != 32 {
+		return nil, fmt.Errorf("invalid root 0x%x: expected 32 bytes", root)
+	}
+	s := &APIServer{
+		proofs:     merkle.MapProofStore{},
+		root:       merkle.EncodeHash(root),
+		rootBytes:  common.CopyBytes(root),
+		options:    encoding,
+		logger:     slog.Default(),
+		verifyOnly: true,
+
+		tokenDecimals: data.DefaultTokenDecimals,
+		traceLimit:    newWindowLimiter(defaultTraceLimit, traceWindow),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.suggestEnabled, s.bloomRate = false, 0
+	s.reservation, s.abuse, s.archive, s.campaign = nil, nil, nil, nil
+	return s, nil
+}
+
+// noClaimData answers requests for claim data a verify-only server lacks
+func (s *APIServer) noClaimData(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, CodeNoClaimData, noClaimDataMessage)
+}
+
+// claimDataEndpoint replaces e's handler with noClaimData on a verify-only
+// server
+func (s *APIServer) claimDataEndpoint(e Endpoint) Endpoint {
+	if !s.verifyOnly {
+		return e
+	}
+	e.Summary += " (not served: the server holds no claim data)"
+	e.Query = nil
+	e.Responses = map[int]interface{}{http.StatusNotFound: ErrorResponse{}}
+	e.Handler = s.noClaimData
+	return e
+}
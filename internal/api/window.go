@@ -0,0 +1,193 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Clock is the time source of an APIServer for claim windows, nonces and
+// abuse detection
+type Clock interface {
+	Now() time.Time
+}
+
+// WithClock makes the server read the time from clock instead of the
+// system clock, as tests do to step through a claim window
+func WithClock(clock Clock) Option {
+	return func(s *APIServer) {
+		s.clock = clock
+	}
+}
+
+// now returns the current time on the server's clock
+func (s *APIServer) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock.Now()
+}
+
+// ClaimWindow bounds when a campaign's proofs are served, as RFC3339
+// times. Either end may be left open.
+type ClaimWindow struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Validate checks that the window's times are RFC3339 and in order
+func (cw ClaimWindow) Validate() error {
+	start, end, err := cw.bounds()
+	if err != nil {
+		return err
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return fmt.Errorf("claim window start %s must be before its end %s", cw.Start, cw.End)
+	}
+	return nil
+}
+
+// bounds parses the window's times, leaving an open end zero
+func (cw ClaimWindow) bounds() (start, end time.Time, err error) {
+	if cw.Start != "" {
+		if start, err = time.Parse(time.RFC3339, cw.Start); err != nil {
+			return start, end, fmt.Errorf("claim window start must be RFC3339: %w", err)
+		}
+	}
+	if cw.End != "" {
+		if end, err = time.Parse(time.RFC3339, cw.End); err != nil {
+			return start, end, fmt.Errorf("claim window end must be RFC3339: %w", err)
+		}
+	}
+	return start, end, nil
+}
+
+// Phase returns where now falls in the window. The start is part of the
+// window and the end is not.
+func (cw ClaimWindow) Phase(now time.Time) ClaimPhase {
+	start, end, err := cw.bounds()
+	switch {
+	case err != nil:
+		return PhaseOpen // Validated windows always parse
+	case !start.IsZero() && now.Before(start):
+		return PhaseUpcoming
+	case !end.IsZero() && !now.Before(end):
+		return PhaseClosed
+	}
+	return PhaseOpen
+}
+
+// ClaimPhase is where a campaign is in its claim window
+type ClaimPhase int
+
+const (
+	// PhaseOpen serves proofs; campaigns without a window are always open
+	PhaseOpen ClaimPhase = iota
+	// PhaseUpcoming is before the window starts
+	PhaseUpcoming
+	// PhaseClosed is after the window ends
+	PhaseClosed
+)
+
+var claimPhaseNames = map[ClaimPhase]string{
+	PhaseOpen:     "open",
+	PhaseUpcoming: "upcoming",
+	PhaseClosed:   "closed",
+}
+
+func (p ClaimPhase) String() string {
+	if name, ok := claimPhaseNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ClaimPhase(%d)", int(p))
+}
+
+// ParseClaimPhase parses a phase name as returned by String
+func ParseClaimPhase(name string) (ClaimPhase, error) {
+	for p, n := range claimPhaseNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown claim phase: %q (expected open, upcoming or closed)", name)
+}
+
+// MarshalText encodes the phase by name
+func (p ClaimPhase) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a phase name
+func (p *ClaimPhase) UnmarshalText(text []byte) error {
+	parsed, err := ParseClaimPhase(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// claimWindow returns the served campaign's window, open when there is
+// no campaign or it sets none
+func (s *APIServer) claimWindow() ClaimWindow {
+	if s.campaign == nil {
+		return ClaimWindow{}
+	}
+	if window := s.campaign.load().ClaimWindow; window != nil {
+		return *window
+	}
+	return ClaimWindow{}
+}
+
+// ClaimWindowError returns why the served campaign's proofs aren't served
+// now, nil while its claim window is open, for other APIs serving the same
+// proofs
+func (s *APIServer) ClaimWindowError() error {
+	window := s.claimWindow()
+	switch window.Phase(s.now()) {
+	case PhaseUpcoming:
+		return fmt.Errorf("claims open at %s", window.Start)
+	case PhaseClosed:
+		return fmt.Errorf("claims closed at %s", window.End)
+	}
+	return nil
+}
+
+// claimWindowStatus reports the claim window for /api/stats
+func (s *APIServer) claimWindowStatus() ClaimWindowStatus {
+	window := s.claimWindow()
+	return ClaimWindowStatus{Start: window.Start, End: window.End, Phase: window.Phase(s.now())}
+}
+
+// checkClaimWindow answers 403 before the claim window opens and 410 after
+// it closes, returning whether the request may go on. Admin tokens bypass
+// the window so a campaign can be tried out before launch.
+func (s *APIServer) checkClaimWindow(w http.ResponseWriter, r *http.Request) bool {
+	window := s.claimWindow()
+	now := s.now()
+	phase := window.Phase(now)
+	if phase == PhaseOpen {
+		return true
+	}
+	if s.adminAuth != nil && s.adminAuth.authorized(r) {
+		s.requestLogger(r).Info("claim window bypassed", "phase", phase.String())
+		return true
+	}
+
+	start, end, _ := window.bounds()
+	if phase == PhaseUpcoming {
+		countdown := int64((start.Sub(now) + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", fmt.Sprint(countdown))
+		writeJSON(w, http.StatusForbidden, ClaimNotOpenResponse{
+			newErrorResponse(w, CodeClaimNotOpen, "Claims open at "+window.Start),
+			start,
+			countdown,
+		})
+		return false
+	}
+	writeJSON(w, http.StatusGone, ClaimClosedResponse{
+		newErrorResponse(w, CodeClaimClosed, "Claims closed at "+window.End),
+		end,
+	})
+	return false
+}
@@ -0,0 +1,166 @@
+// Package benchcmp compares go test -bench output against a checked-in
+// baseline and reports metrics that regressed.
+package benchcmp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"merkle-airdrop/internal/fsutil"
+)
+
+// DefaultMetric is the higher-is-better metric the regression gate watches
+const DefaultMetric = "proofs/sec"
+
+// DefaultTolerance is the largest fractional drop allowed before failing
+const DefaultTolerance = 0.2
+
+// gomaxprocsSuffix matches the -N that go test appends to benchmark names
+var gomaxprocsSuffix = regexp.MustCompile(`-\d+$`)
+
+// Results maps benchmark name to metric unit to value
+type Results map[string]map[string]float64
+
+// Baseline is the checked-in reference for a metric
+type Baseline struct {
+	Metric     string             `json:"metric"`
+	Tolerance  float64            `json:"tolerance"`
+	Benchmarks map[string]float64 `json:"benchmarks"`
+}
+
+// Regression is a benchmark whose metric fell below the tolerated value
+type Regression struct {
+	Name     string
+	Baseline float64
+	Current  float64
+}
+
+// Change is the fractional difference from the baseline, negative for drops
+func (r Regression) Change() float64 {
+	return (r.Current - r.Baseline) / r.Baseline
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s: %.0f -> %.0f (%+.1f%%)", r.Name, r.Baseline, r.Current, r.Change()*100)
+}
+
+// Parse reads go test -bench output. Runs of the same benchmark, e.g. from
+// -count, are averaged.
+func Parse(r io.Reader) (Results, error) {
+	sums := make(Results)
+	counts := make(map[string]map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue // Not a result line, e.g. a log message
+		}
+
+		name := gomaxprocsSuffix.ReplaceAllString(fields[0], "")
+		if sums[name] == nil {
+			sums[name] = make(map[string]float64)
+			counts[name] = make(map[string]int)
+		}
+		// Measurements follow the iteration count as value-unit pairs
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", fields[i], name, err)
+			}
+			sums[name][fields[i+1]] += value
+			counts[name][fields[i+1]]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read benchmark output: %w", err)
+	}
+
+	for name, metrics := range sums {
+		for unit := range metrics {
+			metrics[unit] /= float64(counts[name][unit])
+		}
+	}
+	return sums, nil
+}
+
+// NewBaseline records metric from results, rounded to whole units
+func NewBaseline(results Results, metric string, tolerance float64) (*Baseline, error) {
+	baseline := &Baseline{Metric: metric, Tolerance: tolerance, Benchmarks: make(map[string]float64)}
+	for name, metrics := range results {
+		if value, ok := metrics[metric]; ok {
+			baseline.Benchmarks[name] = math.Round(value)
+		}
+	}
+	if len(baseline.Benchmarks) == 0 {
+		return nil, fmt.Errorf("no benchmarks report %s", metric)
+	}
+	return baseline, nil
+}
+
+// Compare returns the benchmarks whose metric dropped by more than the
+// baseline's tolerance, ordered by name. Benchmarks missing from either side
+// are skipped, but at least one must be in both.
+func (b *Baseline) Compare(results Results) ([]Regression, error) {
+	var regressions []Regression
+	matched := 0
+	for name, baseline := range b.Benchmarks {
+		current, ok := results[name][b.Metric]
+		if !ok {
+			continue
+		}
+		matched++
+		if current < baseline*(1-b.Tolerance) {
+			regressions = append(regressions, Regression{Name: name, Baseline: baseline, Current: current})
+		}
+	}
+	if matched == 0 {
+		return nil, fmt.Errorf("no benchmarks in the output match the %s baseline", b.Metric)
+	}
+
+	sort.Slice(regressions, func(i, j int) bool { return regressions[i].Name < regressions[j].Name })
+	return regressions, nil
+}
+
+// LoadBaseline reads a baseline JSON file
+func LoadBaseline(filename string) (*Baseline, error) {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %w", err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(file, &baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	if baseline.Metric == "" || len(baseline.Benchmarks) == 0 {
+		return nil, fmt.Errorf("baseline %s has no %q benchmarks", filename, baseline.Metric)
+	}
+	if baseline.Tolerance < 0 || baseline.Tolerance >= 1 {
+		return nil, fmt.Errorf("baseline tolerance %v must be in [0, 1)", baseline.Tolerance)
+	}
+	return &baseline, nil
+}
+
+// Save writes the baseline as indented JSON
+func (b *Baseline) Save(filename string) error {
+	file, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode baseline: %w", err)
+	}
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		_, err := w.Write(append(file, '\n'))
+		return err
+	})
+}
@@ -3,8 +3,22 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/archive"
+	"merkle-airdrop/pkg/data"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // Config holds all configuration for the application
@@ -14,6 +28,8 @@ type Config struct {
 	Merkle   MerkleConfig   `json:"merkle"`
 	Database DatabaseConfig `json:"database"`
 	Logging  LoggingConfig  `json:"logging"`
+	Campaign CampaignConfig `json:"campaign"`
+	Archive  ArchiveConfig  `json:"archive"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -23,6 +39,120 @@ type ServerConfig struct {
 	ReadTimeout  int    `json:"read_timeout"`
 	WriteTimeout int    `json:"write_timeout"`
 	CORS         bool   `json:"cors"`
+
+	// CORSOrigins are the origins CORS lets read responses, such as
+	// "https://claim.example.org"; any origin when empty
+	CORSOrigins []string `json:"cors_origins,omitempty"`
+
+	// MaxBodyBytes bounds the JSON bodies of POST requests
+	MaxBodyBytes int64 `json:"max_body_bytes"`
+
+	// AdminTokens are the bearer tokens accepted by admin endpoints.
+	// Admin endpoints are disabled when empty.
+	AdminTokens []string `json:"admin_tokens,omitempty"`
+
+	// EligibilityOnly disables the proof endpoint, leaving /api/eligible
+	// to answer whether an address is in the airdrop
+	EligibilityOnly bool `json:"eligibility_only,omitempty"`
+
+	// LazyProofs generates proofs on request instead of at startup,
+	// caching up to ProofCacheSize of them
+	LazyProofs     bool `json:"lazy_proofs,omitempty"`
+	ProofCacheSize int  `json:"proof_cache_size,omitempty"`
+
+	// AsyncProofs starts serving before the proofs are generated; their
+	// progress is reported at /api/progress
+	AsyncProofs bool `json:"async_proofs,omitempty"`
+
+	// Suggestions lists similar airdrop addresses when a proof lookup with
+	// ?suggest=true misses. Their claimed state is read from
+	// Ethereum.ContractAddress when set.
+	Suggestions bool `json:"suggestions,omitempty"`
+
+	// ClaimLinkURL is the claim site opened by links from /api/link.
+	// The endpoint is disabled when empty.
+	ClaimLinkURL string `json:"claim_link_url,omitempty"`
+
+	// BasePath prefixes every route, e.g. "/airdrop" to serve
+	// /airdrop/api/proof/{address} behind a proxy that forwards the prefix
+	BasePath string `json:"base_path,omitempty"`
+
+	// StaticDir serves the claim site from this directory under /, with
+	// {{MERKLE_ROOT}} and {{CONTRACT_ADDRESS}} filled in in its index.html
+	StaticDir string `json:"static_dir,omitempty"`
+
+	// GRPCPort enables the gRPC API on the same host when non-zero
+	GRPCPort int `json:"grpc_port,omitempty"`
+
+	// Reservation hands each proof out once, to a caller that signs a nonce
+	// from /api/nonce with the address's key. Issuances are recorded in the
+	// ReservationStore JSON file, or only in memory when it is empty.
+	Reservation      bool   `json:"reservation,omitempty"`
+	ReservationStore string `json:"reservation_store,omitempty"`
+
+	// PrivacyMode serves an address's amount only to a caller that signs a
+	// nonce from /api/nonce with its key, disables the endpoints that would
+	// reveal other amounts, and lets /api/verify check each address at
+	// most PrivacyVerifyAttempts times an hour; the server's default of 5
+	// applies when zero.
+	PrivacyMode           bool `json:"privacy_mode,omitempty"`
+	PrivacyVerifyAttempts int  `json:"privacy_verify_attempts,omitempty"`
+
+	// AppendClaims lets admins add claims with POST /api/admin/claims,
+	// serving a rebuilt tree for each addition. Appended claims are held in
+	// memory only, so add them to the claims CSV before restarting.
+	AppendClaims bool `json:"append_claims,omitempty"`
+
+	// VersionHistory keeps the tree of every appended version in memory,
+	// for proving at GET /api/consistency/{address} that a claim did not
+	// change between two of them. It requires AppendClaims.
+	VersionHistory bool `json:"version_history,omitempty"`
+
+	// FinalizationFile enables POST /api/admin/finalize, recording in this
+	// JSON file that the campaign's root was deployed. A finalized campaign
+	// refuses changes, across restarts, until it is unlocked with one of
+	// UnlockTokens at POST /api/admin/finalize/force-unlock. The root is
+	// checked against the contract when Ethereum.RPCURL is set.
+	FinalizationFile string   `json:"finalization_file,omitempty"`
+	UnlockTokens     []string `json:"unlock_tokens,omitempty"`
+
+	// BloomFPR serves a Bloom filter of the airdrop's addresses at
+	// /api/bloom with this false positive rate. It is disabled when zero.
+	BloomFPR float64 `json:"bloom_fpr,omitempty"`
+
+	// Compression compresses response bodies of 1 KiB or more for clients
+	// that accept it: "gzip" with gzip, "auto" with zstd or gzip. Off when
+	// empty or "off".
+	Compression string `json:"compression,omitempty"`
+
+	// AbuseMaxNotFound turns on strict mode for /api/proof: the zero
+	// address is rejected, sequential address probes are logged, and an IP
+	// with more than this many lookups that miss within AbuseWindow seconds
+	// is throttled. AbuseAction "delay" answers it after AbuseDelayMS plus
+	// up to AbuseJitterMS; "reject" answers 429. Disabled when zero.
+	AbuseMaxNotFound int    `json:"abuse_max_not_found,omitempty"`
+	AbuseWindow      int    `json:"abuse_window,omitempty"`
+	AbuseAction      string `json:"abuse_action,omitempty"`
+	AbuseDelayMS     int    `json:"abuse_delay_ms,omitempty"`
+	AbuseJitterMS    int    `json:"abuse_jitter_ms,omitempty"`
+
+	// TrustedProxies lists the CIDRs of proxies in front of the server,
+	// such as an ingress. Requests from them are attributed to the
+	// right-most X-Forwarded-For entry outside the list, for abuse
+	// detection and per-client limits; others to their remote address.
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+
+	// TraceLimit is the number of traced verifications,
+	// POST /api/verify?trace=true, each IP may request per minute. The
+	// server's default of 10 applies when zero.
+	TraceLimit int `json:"trace_limit,omitempty"`
+
+	// SelfTestSamples is the number of random proofs verified against the
+	// root at startup, failing startup if any doesn't verify or the check
+	// takes over SelfTestBudgetMS. In lazy mode the server starts anyway
+	// with /readyz failing. Disabled when zero.
+	SelfTestSamples  int `json:"self_test_samples"`
+	SelfTestBudgetMS int `json:"self_test_budget_ms"`
 }
 
 // EthereumConfig holds Ethereum-related configuration
@@ -31,19 +161,78 @@ type EthereumConfig struct {
 	PrivateKey      string `json:"private_key"`
 	ContractAddress string `json:"contract_address"`
 	TokenAddress    string `json:"token_address"`
+	TokenDecimals   int    `json:"token_decimals"` // For amounts the API displays with ?unit=token
 	GasLimit        uint64 `json:"gas_limit"`
 	GasPrice        int64  `json:"gas_price"`
+
+	// KeystoreFile is an encrypted keystore used instead of PrivateKey. Its
+	// passphrase comes from KeystorePassword or the KeystorePasswordEnv
+	// environment variable.
+	KeystoreFile     string `json:"keystore_file,omitempty"`
+	KeystorePassword string `json:"keystore_password,omitempty"`
+
+	// SignerURL is a remote signing service used instead of a local key,
+	// signing as SignerAddress
+	SignerURL     string `json:"signer_url,omitempty"`
+	SignerAddress string `json:"signer_address,omitempty"`
+
+	// ClaimedCacheTTL is how many seconds the server keeps claimed flags
+	// read from ContractAddress; 0 reads them on every request
+	ClaimedCacheTTL int `json:"claimed_cache_ttl"`
+
+	// IndexerStartBlock is the block the server's claims indexer backfills
+	// ContractAddress's Claimed events from, normally its deployment block
+	IndexerStartBlock uint64 `json:"indexer_start_block,omitempty"`
 }
 
+// KeystorePasswordEnv is the environment variable read for the keystore
+// passphrase when none is configured
+const KeystorePasswordEnv = "AIRDROP_KEYSTORE_PASSWORD"
+
 // MerkleConfig holds Merkle tree configuration
 type MerkleConfig struct {
+	// MaxClaims fails loading more claims than this, stopping at the first
+	// row over it, and appending claims beyond it. Unlimited when zero.
 	MaxClaims    int    `json:"max_claims"`
 	WorkerCount  int    `json:"worker_count"`
 	BatchSize    int    `json:"batch_size"`
 	CacheEnabled bool   `json:"cache_enabled"`
 	OutputFormat string `json:"output_format"`
+
+	// CacheSize and CacheTTL (in seconds) bound the API's cache of encoded
+	// responses, used when CacheEnabled
+	CacheSize int `json:"cache_size"`
+	CacheTTL  int `json:"cache_ttl"`
+
+	// RebuildInterval rebuilds the served tree every this many seconds from
+	// RebuildQuery against the database, or from the server's -data CSV
+	// when the query is empty. Unchanged claims are not rebuilt. Rebuilds
+	// are disabled when zero.
+	RebuildInterval int    `json:"rebuild_interval,omitempty"`
+	RebuildQuery    string `json:"rebuild_query,omitempty"`
+
+	// Domain is the campaign name separating the leaves of trees the server
+	// builds from claims, and of the root it serves with -verify-only; see
+	// merkle.DomainSeparatorFor. Proofs files carry their own.
+	Domain string `json:"domain,omitempty"`
+
+	// ProofStore is where the server keeps the proofs it serves: "memory",
+	// or "disk" for a file at ProofStorePath that is rewritten at startup
+	ProofStore     string `json:"proof_store,omitempty"`
+	ProofStorePath string `json:"proof_store_path,omitempty"`
+
+	// ProofBuffer bounds the proofs held at once while they are generated
+	// into the disk proof store; see merkle.TreeOptions.ProofBuffer. Zero
+	// uses merkle.DefaultProofBuffer.
+	ProofBuffer int `json:"proof_buffer,omitempty"`
 }
 
+// The proof stores of MerkleConfig.ProofStore
+const (
+	ProofStoreMemory = "memory"
+	ProofStoreDisk   = "disk"
+)
+
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	Type     string `json:"type"`
@@ -55,6 +244,60 @@ type DatabaseConfig struct {
 	SSLMode  string `json:"ssl_mode"`
 }
 
+// CampaignConfig describes the campaign to frontends at /api/campaign,
+// which is disabled unless Name or File is set
+type CampaignConfig struct {
+	Name            string `json:"name,omitempty"`
+	TokenSymbol     string `json:"token_symbol,omitempty"`
+	ContractAddress string `json:"contract_address,omitempty"`
+	ChainID         uint64 `json:"chain_id,omitempty"`
+	Deadline        string `json:"deadline,omitempty"` // RFC3339
+
+	// ClaimStart and ClaimEnd (RFC3339) bound when /api/proof serves
+	// proofs; admin tokens bypass them. Either may be left open.
+	ClaimStart string `json:"claim_start,omitempty"`
+	ClaimEnd   string `json:"claim_end,omitempty"`
+
+	// File is a campaign.json that takes the place of the fields above
+	// once it exists. Admin updates are saved to it, or kept only in
+	// memory when it is empty.
+	File string `json:"file,omitempty"`
+
+	// ProofFields renames or leaves out the proof, index and amount of
+	// /api/proof responses, as in "proof=merkleProof,amount=value,-index".
+	// It applies whether or not the campaign is enabled.
+	ProofFields string `json:"proof_fields,omitempty"`
+}
+
+// Enabled reports whether a campaign is configured
+func (c CampaignConfig) Enabled() bool {
+	return c.Name != "" || c.File != ""
+}
+
+// ArchiveConfig locates the S3-compatible bucket of past campaigns, served
+// with ?campaign= in addition to the campaign in memory. It is disabled
+// unless Endpoint is set.
+type ArchiveConfig struct {
+	Endpoint        string `json:"endpoint,omitempty"`
+	Bucket          string `json:"bucket,omitempty"`
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+
+	// CacheShards bounds the shards kept in memory, archive.DefaultCacheSize
+	// when zero
+	CacheShards int `json:"cache_shards,omitempty"`
+
+	// Campaign names the served campaign, which ?campaign= selects without
+	// going to the archive
+	Campaign string `json:"campaign,omitempty"`
+}
+
+// Enabled reports whether an archive is configured
+func (c ArchiveConfig) Enabled() bool {
+	return c.Endpoint != ""
+}
+
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
 	Level  string `json:"level"`
@@ -66,16 +309,29 @@ type LoggingConfig struct {
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host:         "localhost",
-			Port:         8080,
-			ReadTimeout:  30,
-			WriteTimeout: 30,
-			CORS:         true,
+			Host:           "localhost",
+			Port:           8080,
+			ReadTimeout:    30,
+			WriteTimeout:   30,
+			CORS:           true,
+			MaxBodyBytes:   1 << 20,
+			ProofCacheSize: 10000,
+
+			AbuseWindow:   60,
+			AbuseAction:   "delay",
+			AbuseDelayMS:  1000,
+			AbuseJitterMS: 1000,
+
+			SelfTestSamples:  100,
+			SelfTestBudgetMS: 5000,
 		},
 		Ethereum: EthereumConfig{
-			RPCURL:   "http://localhost:8545",
-			GasLimit: 3000000,
-			GasPrice: 20000000000, // 20 gwei
+			RPCURL:        "http://localhost:8545",
+			TokenDecimals: 18,
+			GasLimit:      3000000,
+			GasPrice:      20000000000, // 20 gwei
+
+			ClaimedCacheTTL: 15,
 		},
 		Merkle: MerkleConfig{
 			MaxClaims:    1000000,
@@ -83,6 +339,9 @@ func DefaultConfig() *Config {
 			BatchSize:    1000,
 			CacheEnabled: true,
 			OutputFormat: "json",
+			CacheSize:    256,
+			CacheTTL:     30,
+			ProofStore:   ProofStoreMemory,
 		},
 		Database: DatabaseConfig{
 			Type:    "sqlite",
@@ -111,58 +370,456 @@ func LoadConfig(filename string) (*Config, error) {
 	}
 	defer file.Close()
 
-	var config Config
-	if err := json.NewDecoder(file).Decode(&config); err != nil {
+	// Settings missing from the file keep their defaults
+	config := DefaultConfig()
+	if err := json.NewDecoder(file).Decode(config); err != nil {
 		return nil, fmt.Errorf("failed to decode config: %w", err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 // SaveConfig saves configuration to a file
 func SaveConfig(config *Config, filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(config); err != nil {
-		return fmt.Errorf("failed to encode config: %w", err)
-	}
-
-	return nil
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(config); err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+		return nil
+	})
 }
 
+// LargeCacheClaims is the max_claims above which an enabled cache draws a
+// warning
+const LargeCacheClaims = 1000000
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
+	return c.ValidateAll()
+}
+
+// ValidateAll checks the whole configuration and returns every problem
+// found, joined with errors.Join
+func (c *Config) ValidateAll() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Server
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
-		return fmt.Errorf("invalid server port: %d", c.Server.Port)
+		fail("invalid server port: %d", c.Server.Port)
+	}
+	if c.Server.GRPCPort < 0 || c.Server.GRPCPort > 65535 {
+		fail("invalid grpc port: %d", c.Server.GRPCPort)
+	} else if c.Server.GRPCPort != 0 && c.Server.GRPCPort == c.Server.Port {
+		fail("grpc port must differ from server port")
+	}
+	if c.Server.ReadTimeout < 0 {
+		fail("read_timeout must not be negative")
+	}
+	if c.Server.WriteTimeout < 0 {
+		fail("write_timeout must not be negative")
+	}
+	if c.Server.MaxBodyBytes <= 0 {
+		fail("max_body_bytes must be positive")
+	}
+	if c.Server.LazyProofs && c.Server.GRPCPort != 0 {
+		fail("lazy_proofs is not supported with the gRPC API")
+	}
+	if c.Server.AsyncProofs && c.Server.GRPCPort != 0 {
+		fail("async_proofs is not supported with the gRPC API")
+	}
+	if c.Server.AsyncProofs && c.Server.LazyProofs {
+		fail("async_proofs and lazy_proofs are mutually exclusive")
+	}
+	if c.Server.Reservation && c.Server.GRPCPort != 0 {
+		fail("reservation is not supported with the gRPC API")
+	}
+	if c.Server.PrivacyMode && c.Server.GRPCPort != 0 {
+		fail("privacy_mode is not supported with the gRPC API")
+	}
+	if c.Server.EligibilityOnly && c.Server.GRPCPort != 0 {
+		fail("eligibility_only is not supported with the gRPC API")
+	}
+	if c.Server.PrivacyVerifyAttempts < 0 {
+		fail("privacy_verify_attempts must not be negative")
+	} else if c.Server.PrivacyVerifyAttempts != 0 && !c.Server.PrivacyMode {
+		fail("privacy_verify_attempts requires privacy_mode")
+	}
+	if c.Server.AppendClaims {
+		// The other modes hold or rebuild the tree themselves
+		switch {
+		case len(c.Server.AdminTokens) == 0:
+			fail("append_claims requires admin_tokens")
+		case c.Server.LazyProofs:
+			fail("append_claims is not supported with lazy_proofs")
+		case c.Server.AsyncProofs:
+			fail("append_claims is not supported with async_proofs")
+		case c.Merkle.RebuildInterval != 0:
+			fail("append_claims is not supported with rebuild_interval")
+		case c.Server.GRPCPort != 0:
+			fail("append_claims is not supported with the gRPC API")
+		case c.Merkle.ProofStore == ProofStoreDisk:
+			fail("append_claims is not supported with the disk proof store")
+		}
+	}
+	if c.Server.VersionHistory && !c.Server.AppendClaims {
+		fail("version_history requires append_claims")
+	}
+	if c.Server.FinalizationFile != "" {
+		if len(c.Server.AdminTokens) == 0 {
+			fail("finalization_file requires admin_tokens")
+		}
+		if err := checkWritableDir(filepath.Dir(c.Server.FinalizationFile)); err != nil {
+			fail("finalization_file directory is not writable: %w", err)
+		}
+	}
+	if len(c.Server.UnlockTokens) > 0 {
+		if c.Server.FinalizationFile == "" {
+			fail("unlock_tokens requires finalization_file")
+		}
+		for _, token := range c.Server.UnlockTokens {
+			if slices.Contains(c.Server.AdminTokens, token) {
+				fail("unlock_tokens must differ from admin_tokens")
+				break
+			}
+		}
+	}
+	if c.Server.StaticDir != "" {
+		if info, err := os.Stat(c.Server.StaticDir); err != nil {
+			fail("static_dir is not readable: %w", err)
+		} else if !info.IsDir() {
+			fail("static_dir is not a directory: %s", c.Server.StaticDir)
+		}
+	}
+	if c.Server.ReservationStore != "" {
+		if !c.Server.Reservation {
+			fail("reservation_store requires reservation")
+		} else if err := checkWritableDir(filepath.Dir(c.Server.ReservationStore)); err != nil {
+			fail("reservation_store directory is not writable: %w", err)
+		}
+	}
+	if c.Server.ProofCacheSize < 0 {
+		fail("proof_cache_size must not be negative")
+	}
+	if c.Server.BloomFPR < 0 || c.Server.BloomFPR >= 1 {
+		fail("bloom_fpr must be between 0 and 1")
+	}
+	if c.Server.AbuseMaxNotFound < 0 {
+		fail("abuse_max_not_found must not be negative")
+	}
+	if c.Server.TraceLimit < 0 {
+		fail("trace_limit must not be negative")
+	}
+	for _, origin := range c.Server.CORSOrigins {
+		if !validOrigin(origin) {
+			fail("invalid cors_origins entry: %q (expected * or an origin like https://claim.example.org)", origin)
+		}
+	}
+	if c.Server.BasePath != "" && !validBasePath(c.Server.BasePath) {
+		fail("invalid base_path: %q (expected a path like /airdrop)", c.Server.BasePath)
+	}
+	switch c.Server.Compression {
+	case "", "off", "gzip", "auto":
+	default:
+		fail("invalid compression: %q (expected off, gzip or auto)", c.Server.Compression)
+	}
+	for _, cidr := range c.Server.TrustedProxies {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			fail("invalid trusted_proxies entry: %q (expected a CIDR like 10.0.0.0/8)", cidr)
+		}
+	}
+	if c.Server.AbuseMaxNotFound > 0 {
+		if c.Server.AbuseWindow <= 0 {
+			fail("abuse_window must be positive with abuse_max_not_found")
+		}
+		if c.Server.AbuseAction != "delay" && c.Server.AbuseAction != "reject" {
+			fail("invalid abuse_action: %q (expected delay or reject)", c.Server.AbuseAction)
+		}
+		if c.Server.AbuseDelayMS < 0 || c.Server.AbuseJitterMS < 0 {
+			fail("abuse_delay_ms and abuse_jitter_ms must not be negative")
+		}
+	}
+	if c.Server.SelfTestSamples < 0 || c.Server.SelfTestBudgetMS < 0 {
+		fail("self_test_samples and self_test_budget_ms must not be negative")
+	}
+	if c.Server.ClaimLinkURL != "" {
+		if u, err := url.Parse(c.Server.ClaimLinkURL); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("invalid claim_link_url: %s", c.Server.ClaimLinkURL)
+		}
 	}
 
-	if c.Merkle.MaxClaims <= 0 {
-		return fmt.Errorf("max_claims must be positive")
+	// Ethereum
+	if c.Ethereum.RPCURL != "" && !validRPCURL(c.Ethereum.RPCURL) {
+		fail("invalid rpc_url: %s", c.Ethereum.RPCURL)
+	}
+	if c.Ethereum.GasPrice <= 0 {
+		fail("gas_price must be positive")
+	}
+	if c.Ethereum.ContractAddress != "" && !common.IsHexAddress(c.Ethereum.ContractAddress) {
+		fail("invalid contract_address: %s", c.Ethereum.ContractAddress)
+	}
+	if c.Ethereum.ClaimedCacheTTL < 0 {
+		fail("claimed_cache_ttl must not be negative")
+	}
+	if c.Ethereum.TokenAddress != "" && !common.IsHexAddress(c.Ethereum.TokenAddress) {
+		fail("invalid token_address: %s", c.Ethereum.TokenAddress)
+	}
+	if c.Ethereum.TokenDecimals < 0 || c.Ethereum.TokenDecimals > 77 {
+		fail("token_decimals must be between 0 and 77")
+	}
+	if c.Ethereum.SignerURL != "" && c.Ethereum.SignerAddress == "" {
+		fail("signer_address is required with signer_url")
+	}
+	// Reading claim state needs no key, but deploying for a token does
+	if c.Ethereum.TokenAddress != "" && !c.HasSigner() {
+		fail("private_key, keystore_file or signer_url is required with token_address")
 	}
 
+	// Merkle
+	if c.Merkle.MaxClaims < 0 {
+		fail("max_claims must not be negative")
+	}
+	if c.Merkle.WorkerCount < 0 {
+		fail("worker_count must not be negative")
+	}
+	if c.Merkle.ProofBuffer < 0 {
+		fail("proof_buffer must not be negative")
+	}
 	if c.Merkle.BatchSize <= 0 {
-		return fmt.Errorf("batch_size must be positive")
+		fail("batch_size must be positive")
+	}
+	if c.Merkle.CacheEnabled && c.Merkle.CacheSize <= 0 {
+		fail("cache_size must be positive with cache_enabled")
+	}
+	if c.Merkle.CacheEnabled && c.Merkle.CacheTTL <= 0 {
+		fail("cache_ttl must be positive with cache_enabled")
 	}
-
 	validFormats := map[string]bool{"json": true, "csv": true}
+	if c.Merkle.RebuildInterval < 0 {
+		fail("rebuild_interval must not be negative")
+	}
+	if c.Merkle.RebuildQuery != "" && c.Merkle.RebuildInterval == 0 {
+		fail("rebuild_query requires rebuild_interval")
+	}
+	if c.Merkle.RebuildInterval != 0 {
+		switch {
+		case c.Server.LazyProofs:
+			fail("rebuild_interval is not supported with lazy_proofs")
+		case c.Server.AsyncProofs:
+			fail("rebuild_interval is not supported with async_proofs")
+		case c.Server.GRPCPort != 0:
+			fail("rebuild_interval is not supported with the gRPC API")
+		}
+	}
 	if !validFormats[c.Merkle.OutputFormat] {
-		return fmt.Errorf("invalid output format: %s", c.Merkle.OutputFormat)
+		fail("invalid output format: %s", c.Merkle.OutputFormat)
+	}
+	switch c.Merkle.ProofStore {
+	case ProofStoreMemory:
+	case ProofStoreDisk:
+		if c.Merkle.ProofStorePath == "" {
+			fail("proof_store_path is required with the disk proof store")
+		}
+		// The other modes hold or rebuild proofs themselves
+		switch {
+		case c.Server.LazyProofs:
+			fail("the disk proof store is not supported with lazy_proofs")
+		case c.Server.AsyncProofs:
+			fail("the disk proof store is not supported with async_proofs")
+		case c.Merkle.RebuildInterval != 0:
+			fail("the disk proof store is not supported with rebuild_interval")
+		case c.Server.GRPCPort != 0:
+			fail("the disk proof store is not supported with the gRPC API")
+		}
+	default:
+		fail("unknown proof store: %q (expected memory or disk)", c.Merkle.ProofStore)
 	}
 
+	// Database
+	validDatabases := map[string]bool{"postgres": true, "sqlite": true}
+	if !validDatabases[c.Database.Type] {
+		fail("unknown database type: %q", c.Database.Type)
+	}
+
+	// Logging
 	validLogLevels := map[string]bool{
 		"debug": true, "info": true, "warn": true, "error": true,
 	}
 	if !validLogLevels[c.Logging.Level] {
-		return fmt.Errorf("invalid log level: %s", c.Logging.Level)
+		fail("invalid log level: %s", c.Logging.Level)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		fail("invalid log format: %s (expected json or text)", c.Logging.Format)
+	}
+	if c.Logging.File != "" {
+		if err := checkWritableDir(filepath.Dir(c.Logging.File)); err != nil {
+			fail("log file directory is not writable: %w", err)
+		}
+	}
+
+	// Campaign
+	campaign := c.Campaign
+	if campaign.Name == "" && campaign.File == "" && (campaign.TokenSymbol != "" || campaign.ContractAddress != "" || campaign.ChainID != 0 || campaign.Deadline != "" || campaign.ClaimStart != "" || campaign.ClaimEnd != "") {
+		fail("campaign name is required")
+	}
+	if campaign.ContractAddress != "" && !common.IsHexAddress(campaign.ContractAddress) {
+		fail("invalid campaign contract_address: %s", campaign.ContractAddress)
+	} else if campaign.ContractAddress != "" && campaign.ChainID == 0 {
+		fail("campaign chain_id is required with contract_address")
+	}
+	if campaign.Deadline != "" {
+		if _, err := time.Parse(time.RFC3339, campaign.Deadline); err != nil {
+			fail("campaign deadline must be RFC3339: %s", campaign.Deadline)
+		}
+	}
+	var claimStart, claimEnd time.Time
+	if campaign.ClaimStart != "" {
+		var err error
+		if claimStart, err = time.Parse(time.RFC3339, campaign.ClaimStart); err != nil {
+			fail("campaign claim_start must be RFC3339: %s", campaign.ClaimStart)
+		}
+	}
+	if campaign.ClaimEnd != "" {
+		var err error
+		if claimEnd, err = time.Parse(time.RFC3339, campaign.ClaimEnd); err != nil {
+			fail("campaign claim_end must be RFC3339: %s", campaign.ClaimEnd)
+		}
 	}
+	if !claimStart.IsZero() && !claimEnd.IsZero() && !claimStart.Before(claimEnd) {
+		fail("campaign claim_start must be before claim_end")
+	}
+	if campaign.File != "" {
+		if err := checkWritableDir(filepath.Dir(campaign.File)); err != nil {
+			fail("campaign file directory is not writable: %w", err)
+		}
+	}
+	if _, err := data.ParseProofMarshaller(campaign.ProofFields); err != nil {
+		fail("campaign proof_fields: %w", err)
+	}
+
+	// Archive
+	store := c.Archive
+	if store.Enabled() {
+		if u, err := url.Parse(store.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("invalid archive endpoint: %s", store.Endpoint)
+		}
+		if store.Bucket == "" {
+			fail("archive bucket is required with endpoint")
+		}
+		if (store.AccessKeyID == "") != (store.SecretAccessKey == "") {
+			fail("archive access_key_id and secret_access_key must be set together")
+		}
+		if store.CacheShards < 0 {
+			fail("archive cache_shards must not be negative")
+		}
+		if store.Campaign != "" {
+			if err := archive.ValidateCampaign(store.Campaign); err != nil {
+				fail("archive campaign: %w", err)
+			}
+		}
+	} else if store.Bucket != "" || store.Campaign != "" {
+		fail("archive endpoint is required")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings returns settings that are valid but likely unintended
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Merkle.CacheEnabled && c.Merkle.MaxClaims > LargeCacheClaims {
+		warnings = append(warnings, fmt.Sprintf(
+			"cache_enabled with max_claims %d (over %d) may need a lot of memory",
+			c.Merkle.MaxClaims, LargeCacheClaims))
+	}
+	if c.Merkle.CacheEnabled && c.Merkle.MaxClaims == 0 {
+		warnings = append(warnings, "cache_enabled with unlimited max_claims may need a lot of memory")
+	}
+	if c.Server.Reservation && c.Server.ReservationStore == "" {
+		warnings = append(warnings, "reservation without reservation_store forgets issued proofs on restart")
+	}
+	if c.Server.Reservation && len(c.Server.AdminTokens) == 0 {
+		warnings = append(warnings, "reservation without admin_tokens cannot reset issued proofs")
+	}
+	if c.Campaign.Enabled() && c.Campaign.File == "" {
+		warnings = append(warnings, "campaign without a file forgets admin updates on restart")
+	}
+	if c.Server.FinalizationFile != "" && len(c.Server.UnlockTokens) == 0 {
+		warnings = append(warnings, "finalization_file without unlock_tokens can only be unlocked by deleting the file")
+	}
+	if c.Campaign.Enabled() && len(c.Server.AdminTokens) == 0 {
+		warnings = append(warnings, "campaign without admin_tokens cannot be updated")
+	}
+	if c.Server.AppendClaims {
+		warnings = append(warnings, "append_claims forgets appended claims on restart unless they are added to the claims CSV")
+	}
+	if c.Ethereum.ContractAddress != "" && c.Ethereum.IndexerStartBlock == 0 {
+		warnings = append(warnings, "contract_address without indexer_start_block indexes claims from genesis")
+	}
+	return warnings
+}
 
-	return nil
+// HasSigner reports whether a transaction signer is configured
+func (c *Config) HasSigner() bool {
+	return c.Ethereum.PrivateKey != "" || c.Ethereum.KeystoreFile != "" || c.Ethereum.SignerURL != ""
+}
+
+// validRPCURL accepts the endpoints go-ethereum can dial: http(s) and
+// ws(s) URLs or an IPC socket path
+func validRPCURL(rawURL string) bool {
+	if strings.HasSuffix(rawURL, ".ipc") {
+		return true
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+		return true
+	default:
+		return false
+	}
+}
+
+// validOrigin accepts "*" and scheme://host[:port] origins without a path
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// validBasePath accepts an absolute path of unreserved URL characters,
+// which route patterns take literally
+func validBasePath(base string) bool {
+	if !strings.HasPrefix(base, "/") {
+		return false
+	}
+	for _, r := range base {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("/-._~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkWritableDir checks that a file can be created in dir
+func checkWritableDir(dir string) error {
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
 }
 
 // GetDatabaseURL returns the database connection URL
@@ -183,3 +840,17 @@ func (c *Config) GetDatabaseURL() string {
 func (c *Config) GetServerAddress() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
+
+// GetGRPCAddress returns the gRPC server address
+func (c *Config) GetGRPCAddress() string {
+	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.GRPCPort)
+}
+
+// GetKeystorePassword returns the keystore passphrase from the config or,
+// failing that, the environment
+func (c *Config) GetKeystorePassword() string {
+	if c.Ethereum.KeystorePassword != "" {
+		return c.Ethereum.KeystorePassword
+	}
+	return os.Getenv(KeystorePasswordEnv)
+}
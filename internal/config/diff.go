@@ -0,0 +1,88 @@
+// internal/config/diff.go
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// RedactedValue replaces secrets in Redacted configs
+const RedactedValue = "[redacted]"
+
+// Redacted returns a copy of the config with admin and unlock tokens, keys,
+// passwords and the archive secret replaced by RedactedValue, for showing
+// to admins
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redact := func(secret *string) {
+		if *secret != "" {
+			*secret = RedactedValue
+		}
+	}
+	if c.Server.AdminTokens != nil {
+		redacted.Server.AdminTokens = make([]string, len(c.Server.AdminTokens))
+		for i := range redacted.Server.AdminTokens {
+			redacted.Server.AdminTokens[i] = RedactedValue
+		}
+	}
+	if c.Server.UnlockTokens != nil {
+		redacted.Server.UnlockTokens = make([]string, len(c.Server.UnlockTokens))
+		for i := range redacted.Server.UnlockTokens {
+			redacted.Server.UnlockTokens[i] = RedactedValue
+		}
+	}
+	redacted.Server.CORSOrigins = append([]string(nil), c.Server.CORSOrigins...)
+	redact(&redacted.Ethereum.PrivateKey)
+	redact(&redacted.Ethereum.KeystorePassword)
+	redact(&redacted.Database.Password)
+	redact(&redacted.Archive.SecretAccessKey)
+	return &redacted
+}
+
+// Diff lists the settings that differ between old and new as section.key
+// paths of the JSON config, such as "server.port", in order
+func Diff(old, new *Config) ([]string, error) {
+	oldSettings, err := settings(old)
+	if err != nil {
+		return nil, err
+	}
+	newSettings, err := settings(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for path, value := range newSettings {
+		if previous, ok := oldSettings[path]; !ok || !bytes.Equal(previous, value) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range oldSettings {
+		if _, ok := newSettings[path]; !ok {
+			changed = append(changed, path) // Left out with omitempty
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// settings flattens the config's JSON to encoded values by section.key
+func settings(c *Config) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	var sections map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &sections); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	flat := make(map[string]json.RawMessage)
+	for section, keys := range sections {
+		for key, value := range keys {
+			flat[section+"."+key] = value
+		}
+	}
+	return flat, nil
+}
@@ -0,0 +1,89 @@
+// Package fsutil writes output files so readers never see them half written.
+package fsutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrExists is returned by CheckOverwrite for an existing path
+var ErrExists = errors.New("already exists")
+
+// defaultPerm is the mode of newly created files
+const defaultPerm = 0644
+
+// AtomicWriteFile writes path with the output of fn. The data goes to a
+// temporary file in the same directory, which is synced and renamed over
+// path only if fn succeeds, so path holds either its old or its new
+// contents. An existing file's permissions are kept.
+func AtomicWriteFile(path string, fn func(io.Writer) error) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	perm := fs.FileMode(defaultPerm)
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	buf := bufio.NewWriter(tmp)
+	if err := fn(buf); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	// Persist the rename; not every platform can sync a directory
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// CheckOverwrite returns an error wrapping ErrExists when path exists and
+// overwrite is false
+func CheckOverwrite(path string, overwrite bool) error {
+	if overwrite {
+		return nil
+	}
+	_, err := os.Lstat(path)
+	switch {
+	case err == nil:
+		return fmt.Errorf("%s %w", path, ErrExists)
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	default:
+		return fmt.Errorf("failed to check %s: %w", path, err)
+	}
+}
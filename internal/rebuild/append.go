@@ -0,0 +1,153 @@
+package rebuild
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// TreeServer builds the handler serving a tree. It runs on the appending
+// request's goroutine while the previous handler keeps serving.
+type TreeServer func(tree *merkle.MerkleTree) (http.Handler, error)
+
+// Appender serves the latest of a tree and the trees that claims appended
+// by admins grew it into, implementing api.ClaimAppender. The history of
+// served roots is kept in memory, as are the appended claims, so they must
+// also be added to the claims source before a restart.
+type Appender struct {
+	serve     TreeServer
+	logger    *slog.Logger
+	keepTrees bool // Keep every version's tree for TreeAt
+
+	handler atomic.Pointer[http.Handler]
+
+	mu       sync.Mutex // Held while a tree is appended to, so appends are serialized
+	tree     *merkle.MerkleTree
+	versions []api.TreeVersion
+	trees    []*merkle.MerkleTree // By version, from 1; empty unless keepTrees
+}
+
+// AppenderOption configures an Appender
+type AppenderOption func(*Appender)
+
+// KeepTrees keeps the tree of every version in memory, so TreeAt can
+// answer for past versions as well as the served one
+func KeepTrees() AppenderOption {
+	return func(a *Appender) {
+		a.keepTrees = true
+	}
+}
+
+// NewAppender creates an appender serving trees with serve, reporting
+// appends to logger, or slog.Default() when it is nil. Start serves the
+// first tree.
+func NewAppender(serve TreeServer, logger *slog.Logger, opts ...AppenderOption) *Appender {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	a := &Appender{serve: serve, logger: logger}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Start serves tree as version 1
+func (a *Appender) Start(tree *merkle.MerkleTree) error {
+	handler, err := a.safeServe(tree)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.handler.Store(&handler)
+	a.tree = tree
+	a.versions = []api.TreeVersion{{
+		Version:     1,
+		MerkleRoot:  tree.GetRootHash(),
+		TotalClaims: len(tree.Claims),
+		CreatedAt:   time.Now().UTC(),
+	}}
+	if a.keepTrees {
+		a.trees = []*merkle.MerkleTree{tree}
+	}
+	return nil
+}
+
+// ServeHTTP serves the request with the latest tree's handler
+func (a *Appender) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*a.handler.Load()).ServeHTTP(w, r)
+}
+
+// AppendClaims builds and serves the served tree with added, implementing
+// api.ClaimAppender. Either every claim is served from then on or, on
+// error, none is.
+func (a *Appender) AppendClaims(added []merkle.AirdropClaim) (api.AppendResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	tree, err := a.tree.AddClaims(added)
+	if err != nil {
+		return api.AppendResult{}, err
+	}
+	handler, err := a.safeServe(tree)
+	if err != nil {
+		return api.AppendResult{}, err
+	}
+
+	previous := a.versions[len(a.versions)-1]
+	version := api.TreeVersion{
+		Version:     previous.Version + 1,
+		MerkleRoot:  tree.GetRootHash(),
+		TotalClaims: len(tree.Claims),
+		Added:       len(added),
+		CreatedAt:   time.Now().UTC(),
+	}
+	a.handler.Store(&handler)
+	a.tree = tree
+	a.versions = append(a.versions, version)
+	if a.keepTrees {
+		a.trees = append(a.trees, tree)
+	}
+	a.logger.Info("tree version served", "version", version.Version, "root", version.MerkleRoot, "previousRoot", previous.MerkleRoot, "added", len(added))
+	return api.AppendResult{Tree: tree, Version: version, Previous: previous}, nil
+}
+
+// TreeVersions lists the served trees, oldest first, implementing
+// api.ClaimAppender
+func (a *Appender) TreeVersions() []api.TreeVersion {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.versions)
+}
+
+// TreeAt returns the tree served as version, implementing api.TreeHistory.
+// Without KeepTrees only the served tree is known.
+func (a *Appender) TreeAt(version int) (*merkle.MerkleTree, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if version >= 1 && version == len(a.versions) {
+		return a.tree, true
+	}
+	if version < 1 || version > len(a.trees) {
+		return nil, false
+	}
+	return a.trees[version-1], true
+}
+
+// safeServe runs serve, turning a panic into an error as safeBuild does
+func (a *Appender) safeServe(tree *merkle.MerkleTree) (handler http.Handler, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("build panicked: %v", r)
+		}
+	}()
+	return a.serve(tree)
+}
@@ -0,0 +1,248 @@
+// Package rebuild periodically rebuilds the served tree from its claims
+// source, swapping the new tree in without restarting the server
+package rebuild
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// Source loads the claims a tree is built from, such as a CSV or a query
+type Source func(ctx context.Context) ([]merkle.AirdropClaim, error)
+
+// Builder builds the handler serving a tree of claims. It runs on the
+// scheduler's goroutine while the previous handler keeps serving.
+type Builder func(claims []merkle.AirdropClaim) (http.Handler, error)
+
+// Clock is the time source of a Scheduler
+type Clock interface {
+	Now() time.Time
+	// NewTicker returns a channel receiving the time every d and a function
+	// stopping it
+	NewTicker(d time.Duration) (<-chan time.Time, func())
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTicker(d time.Duration) (<-chan time.Time, func()) {
+	ticker := time.NewTicker(d)
+	return ticker.C, ticker.Stop
+}
+
+// Outcomes recorded in api.RebuildStatus
+const (
+	OutcomeRebuilt   = "rebuilt"
+	OutcomeUnchanged = "unchanged"
+	OutcomeFailed    = "failed"
+	OutcomeLocked    = "locked" // The claims changed but the campaign is finalized
+)
+
+// Scheduler serves the handler of the latest tree, rebuilding it from its
+// source every interval. A tick whose claims hash like the served ones is
+// skipped; a failed one keeps the served tree and is retried on the next.
+type Scheduler struct {
+	source   Source
+	build    Builder
+	interval time.Duration
+	clock    Clock
+	logger   *slog.Logger
+	lock     *api.FinalizationLock // Changed claims aren't served while it holds; nil for none
+
+	handler atomic.Pointer[http.Handler]
+
+	mu     sync.Mutex
+	hash   []byte // Of the served claims
+	status api.RebuildStatus
+	ticked bool
+}
+
+// Option configures a Scheduler
+type Option func(*Scheduler)
+
+// WithClock sets the scheduler's time source, the system clock unless set
+func WithClock(clock Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = clock
+	}
+}
+
+// WithLogger sets the logger rebuilds are reported to, which defaults to
+// slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Scheduler) {
+		s.logger = logger
+	}
+}
+
+// WithFinalization keeps the served tree while the campaign is finalized,
+// recording ticks whose claims changed as locked
+func WithFinalization(lock *api.FinalizationLock) Option {
+	return func(s *Scheduler) {
+		s.lock = lock
+	}
+}
+
+// New creates a scheduler rebuilding from source every interval. Start
+// builds the first tree.
+func New(source Source, build Builder, interval time.Duration, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		source:   source,
+		build:    build,
+		interval: interval,
+		clock:    systemClock{},
+		logger:   slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start builds the first tree, whose errors are returned rather than
+// retried, then rebuilds every interval until ctx is done
+func (s *Scheduler) Start(ctx context.Context) error {
+	claims, err := s.source(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load claims: %w", err)
+	}
+	handler, err := s.safeBuild(claims)
+	if err != nil {
+		return err
+	}
+	s.handler.Store(&handler)
+	s.mu.Lock()
+	s.hash = hashClaims(claims)
+	s.mu.Unlock()
+
+	ticks, stop := s.clock.NewTicker(s.interval)
+	go func() {
+		defer stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticks:
+				s.tick(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// ServeHTTP serves the request with the latest tree's handler
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.handler.Load()).ServeHTTP(w, r)
+}
+
+// RebuildStatus reports the last tick, implementing api.RebuildProgress
+func (s *Scheduler) RebuildStatus() (api.RebuildStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status, s.ticked
+}
+
+// tick reloads the source and swaps in a new tree if its claims changed
+func (s *Scheduler) tick(ctx context.Context) {
+	start := s.clock.Now()
+	outcome, err := s.rebuild(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ticked = true
+	s.status.LastRebuild = start
+	s.status.Duration = s.clock.Now().Sub(start).String()
+	s.status.Outcome = outcome
+	s.status.Error = ""
+	switch outcome {
+	case OutcomeFailed:
+		s.status.Error = err.Error()
+		s.status.Failures++
+		s.logger.Error("tree rebuild failed", "error", err, "failures", s.status.Failures)
+	case OutcomeLocked:
+		s.status.Error = err.Error()
+		s.logger.Warn("claims changed but the campaign is finalized; keeping the served tree")
+	case OutcomeRebuilt:
+		s.status.Rebuilds++
+		s.status.Failures = 0
+		s.logger.Info("tree rebuilt", "duration", s.status.Duration)
+	default:
+		s.status.Failures = 0
+	}
+}
+
+// rebuild runs one tick's load and build
+func (s *Scheduler) rebuild(ctx context.Context) (string, error) {
+	claims, err := s.source(ctx)
+	if err != nil {
+		return OutcomeFailed, fmt.Errorf("failed to load claims: %w", err)
+	}
+	hash := hashClaims(claims)
+	s.mu.Lock()
+	unchanged := bytes.Equal(hash, s.hash)
+	s.mu.Unlock()
+	if unchanged {
+		return OutcomeUnchanged, nil
+	}
+	if s.lock != nil {
+		if err := s.lock.Check(); err != nil {
+			return OutcomeLocked, err
+		}
+	}
+
+	handler, err := s.safeBuild(claims)
+	if err != nil {
+		return OutcomeFailed, err
+	}
+	s.handler.Store(&handler)
+	s.mu.Lock()
+	s.hash = hash
+	s.mu.Unlock()
+	return OutcomeRebuilt, nil
+}
+
+// safeBuild runs the builder, turning a panic into an error so a bad
+// rebuild can't take the server down
+func (s *Scheduler) safeBuild(claims []merkle.AirdropClaim) (handler http.Handler, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("build panicked: %v", r)
+		}
+	}()
+	return s.build(claims)
+}
+
+// hashClaims digests the claims in source order, so an unchanged source
+// hashes the same between ticks
+func hashClaims(claims []merkle.AirdropClaim) []byte {
+	h := sha256.New()
+	var buf [4]byte
+	for _, claim := range claims {
+		h.Write(claim.Address[:])
+		amount := []byte{}
+		if claim.Amount != nil {
+			amount = claim.Amount.Bytes()
+			if claim.Amount.Sign() < 0 {
+				h.Write([]byte{'-'})
+			}
+		}
+		binary.BigEndian.PutUint32(buf[:], uint32(len(amount)))
+		h.Write(buf[:])
+		h.Write(amount)
+		binary.BigEndian.PutUint32(buf[:], claim.Index)
+		h.Write(buf[:])
+	}
+	return h.Sum(nil)
+}
@@ -0,0 +1,270 @@
+// Package reload re-reads the server's config file while it runs, applying
+// the settings that can change without a restart and reporting the rest
+package reload
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+)
+
+// Reloader applies changes to a config file to a running server through
+// its api.Tunables and log level, implementing api.ConfigReloader
+type Reloader struct {
+	path     string
+	tunables *api.Tunables
+	level    *slog.LevelVar // Nil when the log level can't change
+	logger   *slog.Logger
+	lock     *api.FinalizationLock // Reloads are refused while it holds; nil for none
+
+	mu      sync.Mutex // Held while reloading, so reloads are serialized
+	running *config.Config
+}
+
+// Option configures a Reloader
+type Option func(*Reloader)
+
+// WithLogLevel lets reloads change logging.level by setting level, the
+// level of the server's log handler
+func WithLogLevel(level *slog.LevelVar) Option {
+	return func(r *Reloader) {
+		r.level = level
+	}
+}
+
+// WithLogger reports reloads to logger instead of slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Reloader) {
+		r.logger = logger
+	}
+}
+
+// WithFinalization refuses reloads with an api.FinalizedError while the
+// campaign is finalized
+func WithFinalization(lock *api.FinalizationLock) Option {
+	return func(r *Reloader) {
+		r.lock = lock
+	}
+}
+
+// New creates a reloader for the config file at path, of which running is
+// the config the server started with and tunables its live settings
+func New(path string, running *config.Config, tunables *api.Tunables, opts ...Option) *Reloader {
+	r := &Reloader{path: path, tunables: tunables, running: running, logger: slog.Default()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// group is settings applied together, such as an allowed origin list and
+// the switch turning CORS on
+type group struct {
+	settings []string
+	// apply copies the group from next into running and applies it, or
+	// returns why it can't be applied without a restart
+	apply func(r *Reloader, running, next *config.Config) (skip string)
+}
+
+var groups = []group{
+	{
+		settings: []string{"server.cors", "server.cors_origins"},
+		apply: func(r *Reloader, running, next *config.Config) string {
+			running.Server.CORS = next.Server.CORS
+			running.Server.CORSOrigins = next.Server.CORSOrigins
+			r.tunables.SetCORS(CORSPolicy(next.Server))
+			return ""
+		},
+	},
+	{
+		settings: []string{"server.abuse_max_not_found", "server.abuse_window", "server.abuse_action", "server.abuse_delay_ms", "server.abuse_jitter_ms"},
+		apply: func(r *Reloader, running, next *config.Config) string {
+			if (running.Server.AbuseMaxNotFound > 0) != (next.Server.AbuseMaxNotFound > 0) {
+				return "turning abuse detection on or off requires a restart"
+			}
+			abuse, err := AbuseConfig(next.Server)
+			if err != nil {
+				return err.Error()
+			}
+			running.Server.AbuseMaxNotFound = next.Server.AbuseMaxNotFound
+			running.Server.AbuseWindow = next.Server.AbuseWindow
+			running.Server.AbuseAction = next.Server.AbuseAction
+			running.Server.AbuseDelayMS = next.Server.AbuseDelayMS
+			running.Server.AbuseJitterMS = next.Server.AbuseJitterMS
+			r.tunables.SetAbuse(abuse)
+			return ""
+		},
+	},
+	{
+		settings: []string{"merkle.cache_ttl"},
+		apply: func(r *Reloader, running, next *config.Config) string {
+			running.Merkle.CacheTTL = next.Merkle.CacheTTL
+			r.tunables.SetCacheTTL(time.Duration(next.Merkle.CacheTTL) * time.Second)
+			return ""
+		},
+	},
+	{
+		settings: []string{"logging.level"},
+		apply: func(r *Reloader, running, next *config.Config) string {
+			if r.level == nil {
+				return "the log level is fixed for this server"
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(next.Logging.Level)); err != nil {
+				return err.Error()
+			}
+			running.Logging.Level = next.Logging.Level
+			r.level.Set(level)
+			return ""
+		},
+	},
+	{
+		settings: []string{"campaign.claim_start", "campaign.claim_end"},
+		apply: func(r *Reloader, running, next *config.Config) string {
+			if file := running.Campaign.File; file != "" {
+				if _, err := os.Stat(file); err == nil {
+					return fmt.Sprintf("the campaign is served from %s; update it with PUT /api/admin/campaign", file)
+				}
+			}
+			var window *api.ClaimWindow
+			if next.Campaign.ClaimStart != "" || next.Campaign.ClaimEnd != "" {
+				window = &api.ClaimWindow{Start: next.Campaign.ClaimStart, End: next.Campaign.ClaimEnd}
+			}
+			if err := r.tunables.SetClaimWindow(window); err != nil {
+				return err.Error()
+			}
+			running.Campaign.ClaimStart = next.Campaign.ClaimStart
+			running.Campaign.ClaimEnd = next.Campaign.ClaimEnd
+			return ""
+		},
+	},
+}
+
+// ReloadConfig reads and validates the config file, then applies the
+// settings of it that changed and can change while the server runs.
+// Settings that need a restart are left as they are and reported skipped.
+func (r *Reloader) ReloadConfig() (api.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := api.ReloadResult{Applied: []api.SettingChange{}, Skipped: []api.SettingChange{}}
+	if r.lock != nil {
+		if err := r.lock.Check(); err != nil {
+			return result, err
+		}
+	}
+	next, err := config.LoadConfig(r.path)
+	if err != nil {
+		return result, err
+	}
+	if err := next.ValidateAll(); err != nil {
+		return result, fmt.Errorf("invalid config %s: %w", r.path, err)
+	}
+	changed, err := config.Diff(r.running, next)
+	if err != nil {
+		return result, err
+	}
+
+	running := *r.running
+	handled := make(map[string]bool, len(changed))
+	for _, g := range groups {
+		var settings []string
+		for _, setting := range g.settings {
+			if slices.Contains(changed, setting) {
+				settings = append(settings, setting)
+				handled[setting] = true
+			}
+		}
+		if len(settings) == 0 {
+			continue
+		}
+		skip := g.apply(r, &running, next)
+		for _, setting := range settings {
+			if skip != "" {
+				result.Skipped = append(result.Skipped, api.SettingChange{Setting: setting, Reason: skip})
+			} else {
+				result.Applied = append(result.Applied, api.SettingChange{Setting: setting})
+			}
+		}
+	}
+	for _, setting := range changed {
+		if !handled[setting] {
+			result.Skipped = append(result.Skipped, api.SettingChange{Setting: setting, Reason: restartReason(setting)})
+		}
+	}
+	r.running = &running
+
+	for _, change := range result.Skipped {
+		r.logger.Warn("config change needs a restart", "setting", change.Setting, "reason", change.Reason)
+	}
+	r.logger.Info("config reloaded", "file", r.path, "applied", settingNames(result.Applied), "skipped", settingNames(result.Skipped))
+	return result, nil
+}
+
+// RunningConfig returns the config the server runs with, started from and
+// changed by reloads, with secrets redacted
+func (r *Reloader) RunningConfig() interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running.Redacted()
+}
+
+// restartReason explains why setting can't change while the server runs
+func restartReason(setting string) string {
+	switch section, key, _ := strings.Cut(setting, "."); {
+	case section == "server" && (key == "host" || key == "port" || key == "grpc_port"):
+		return "the listener keeps its address until a restart"
+	case section == "database":
+		return "the claims source is connected at startup; restart to change it"
+	case section == "merkle":
+		return "the served tree is built at startup; restart to change it"
+	default:
+		return "requires a restart"
+	}
+}
+
+// settingNames lists the settings of changes
+func settingNames(changes []api.SettingChange) []string {
+	names := make([]string, len(changes))
+	for i, change := range changes {
+		names[i] = change.Setting
+	}
+	return names
+}
+
+// CORSPolicy returns the CORS policy cfg configures
+func CORSPolicy(cfg config.ServerConfig) api.CORSPolicy {
+	return api.CORSPolicy{Disabled: !cfg.CORS, Origins: cfg.CORSOrigins}
+}
+
+// AbuseConfig returns the abuse detection thresholds cfg configures
+func AbuseConfig(cfg config.ServerConfig) (api.AbuseConfig, error) {
+	action, err := api.ParseAbuseAction(cfg.AbuseAction)
+	if err != nil {
+		return api.AbuseConfig{}, err
+	}
+	return api.AbuseConfig{
+		Window:      time.Duration(cfg.AbuseWindow) * time.Second,
+		MaxNotFound: cfg.AbuseMaxNotFound,
+		Action:      action,
+		Delay:       time.Duration(cfg.AbuseDelayMS) * time.Millisecond,
+		Jitter:      time.Duration(cfg.AbuseJitterMS) * time.Millisecond,
+	}, nil
+}
+
+// NewTunables returns the live settings a server configured by cfg starts
+// with
+func NewTunables(cfg *config.Config) (*api.Tunables, error) {
+	abuse, err := AbuseConfig(cfg.Server)
+	if err != nil && cfg.Server.AbuseMaxNotFound > 0 {
+		return nil, err
+	}
+	return api.NewTunables(CORSPolicy(cfg.Server), abuse, time.Duration(cfg.Merkle.CacheTTL)*time.Second), nil
+}
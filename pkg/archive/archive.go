@@ -0,0 +1,55 @@
+// Package archive keeps the proof sets of finished campaigns in object
+// storage, so they stay claimable without being held in server memory.
+// Campaigns are stored as the sharded export of data.ExportProofsSharded,
+// and a proof lookup fetches only the shard of its address.
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrCampaignNotFound is returned for a campaign the archive doesn't hold
+var ErrCampaignNotFound = errors.New("campaign not found in archive")
+
+// ProofArchive stores campaigns and serves their proofs on demand.
+// Implementations are safe for concurrent use.
+type ProofArchive interface {
+	// PutCampaign stores the sharded proofs export in dir as campaign,
+	// replacing any campaign of that name
+	PutCampaign(ctx context.Context, campaign, dir string) error
+
+	// GetProof returns address's proof in campaign, or an error wrapping
+	// merkle.ErrAddressNotFound when the campaign has no claim for it.
+	// The proof may be shared and must not be modified.
+	GetProof(ctx context.Context, campaign string, address common.Address) (*merkle.MerkleProof, error)
+
+	// GetRoot returns campaign's root and the encoding of its proofs
+	GetRoot(ctx context.Context, campaign string) (*Root, error)
+}
+
+// Root is an archived campaign's tree root and the encoding its proofs use
+type Root struct {
+	MerkleRoot  string              `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata `json:"metadata"`
+	TotalClaims int                 `json:"totalClaims"`
+}
+
+// campaignName matches campaign names, which become object key prefixes
+var campaignName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// ValidateCampaign checks that campaign can name an archived campaign:
+// up to 128 letters, digits, dots, dashes and underscores, starting with a
+// letter or digit
+func ValidateCampaign(campaign string) error {
+	if !campaignName.MatchString(campaign) {
+		return fmt.Errorf("invalid campaign name %q: expected up to 128 letters, digits, '.', '-' or '_', starting with a letter or digit", campaign)
+	}
+	return nil
+}
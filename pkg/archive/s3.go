@@ -0,0 +1,319 @@
+// pkg/archive/s3.go
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"merkle-airdrop/internal/cache"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Defaults for an S3Archive
+const (
+	DefaultRegion     = "us-east-1"
+	DefaultCacheSize  = 64 // Shards
+	DefaultCacheTTL   = time.Hour
+	maxObjectSize     = 1 << 30
+	maxErrorBodyBytes = 512
+)
+
+// S3Config locates a bucket on an S3-compatible service
+type S3Config struct {
+	Endpoint string // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Bucket   string
+	Region   string // DefaultRegion when empty
+
+	// Credentials sign every request; requests are anonymous without them
+	Credentials Credentials
+}
+
+// S3Archive is a ProofArchive in an S3-compatible bucket, addressed path
+// style. Campaign c's shard index is the object c/index.json and its shards
+// sit next to it. Indexes and shards read back are kept in an LRU cache.
+type S3Archive struct {
+	endpoint   *url.URL
+	bucket     string
+	region     string
+	creds      Credentials
+	httpClient *http.Client
+
+	cacheSize int
+	cacheTTL  time.Duration
+	indexes   *cache.LRU[string, *data.ShardIndex]
+	shards    *cache.LRU[string, *merkle.ProofSet] // Keyed by object key
+}
+
+// Option configures an S3Archive
+type Option func(*S3Archive)
+
+// WithHTTPClient makes requests with httpClient instead of
+// http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(a *S3Archive) {
+		a.httpClient = httpClient
+	}
+}
+
+// WithCache keeps up to size shards, and as many indexes, for ttl,
+// DefaultCacheSize and DefaultCacheTTL unless set. A campaign replaced by
+// PutCampaign elsewhere is seen once its entries expire.
+func WithCache(size int, ttl time.Duration) Option {
+	return func(a *S3Archive) {
+		a.cacheSize = size
+		a.cacheTTL = ttl
+	}
+}
+
+// NewS3Archive returns an archive in the bucket cfg describes
+func NewS3Archive(cfg S3Config, opts ...Option) (*S3Archive, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive endpoint: %w", err)
+	}
+	if (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid archive endpoint %q: expected an http or https URL", cfg.Endpoint)
+	}
+	endpoint.Path = strings.TrimSuffix(endpoint.Path, "/")
+	if cfg.Bucket == "" || strings.Contains(cfg.Bucket, "/") {
+		return nil, fmt.Errorf("invalid archive bucket %q", cfg.Bucket)
+	}
+	if (cfg.Credentials.AccessKeyID == "") != (cfg.Credentials.SecretAccessKey == "") {
+		return nil, fmt.Errorf("archive credentials need both an access key ID and a secret access key")
+	}
+	region := cfg.Region
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	a := &S3Archive{
+		endpoint:   endpoint,
+		bucket:     cfg.Bucket,
+		region:     region,
+		creds:      cfg.Credentials,
+		httpClient: http.DefaultClient,
+		cacheSize:  DefaultCacheSize,
+		cacheTTL:   DefaultCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.indexes = cache.New[string, *data.ShardIndex](a.cacheSize, a.cacheTTL)
+	a.shards = cache.New[string, *merkle.ProofSet](a.cacheSize, a.cacheTTL)
+	return a, nil
+}
+
+// errNoSuchKey is returned by get for objects that don't exist
+var errNoSuchKey = errors.New("no such key")
+
+// PutCampaign uploads the shards in dir, then the index, so readers never
+// see an index whose shards are missing. The index is checked first the
+// way data.LoadShardedProofs checks it.
+func (a *S3Archive) PutCampaign(ctx context.Context, campaign, dir string) error {
+	if err := ValidateCampaign(campaign); err != nil {
+		return err
+	}
+	indexBytes, err := os.ReadFile(filepath.Join(dir, data.ShardIndexFile))
+	if err != nil {
+		return fmt.Errorf("failed to read shard index: %w", err)
+	}
+	index, err := decodeIndex(indexBytes)
+	if err != nil {
+		return err
+	}
+
+	for prefix, name := range index.Shards {
+		shard, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read shard %s: %w", prefix, err)
+		}
+		if err := a.put(ctx, campaign+"/"+name, shard); err != nil {
+			return fmt.Errorf("failed to upload shard %s: %w", prefix, err)
+		}
+	}
+	if err := a.put(ctx, campaign+"/"+data.ShardIndexFile, indexBytes); err != nil {
+		return fmt.Errorf("failed to upload shard index: %w", err)
+	}
+
+	// Drop what this process cached of a replaced campaign
+	a.indexes.Remove(campaign)
+	for _, name := range index.Shards {
+		a.shards.Remove(campaign + "/" + name)
+	}
+	return nil
+}
+
+// GetProof fetches the shard holding address's proof unless it is cached
+func (a *S3Archive) GetProof(ctx context.Context, campaign string, address common.Address) (*merkle.MerkleProof, error) {
+	index, err := a.index(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	prefix := data.ShardPrefix(address.Hex(), index.ShardBits)
+	name, ok := index.Shards[prefix]
+	if !ok {
+		return nil, fmt.Errorf("campaign %s has no shard %s", campaign, prefix)
+	}
+
+	key := campaign + "/" + name
+	shard, ok := a.shards.Get(key)
+	if !ok {
+		content, err := a.get(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch shard %s of campaign %s: %w", prefix, campaign, err)
+		}
+		root, proofs, err := data.LoadProofsJSON(bytes.NewReader(content))
+		if err != nil {
+			return nil, fmt.Errorf("shard %s of campaign %s: %w", prefix, campaign, err)
+		}
+		if root != index.MerkleRoot {
+			return nil, fmt.Errorf("shard %s of campaign %s: root %s does not match index root %s", prefix, campaign, root, index.MerkleRoot)
+		}
+		if proofs.Metadata != index.Metadata {
+			return nil, fmt.Errorf("shard %s of campaign %s: metadata does not match the index", prefix, campaign)
+		}
+		shard = proofs
+		a.shards.Add(key, shard)
+	}
+
+	proof, ok := shard.Get(address)
+	if !ok {
+		return nil, fmt.Errorf("%s in campaign %s: %w", address.Hex(), campaign, merkle.ErrAddressNotFound)
+	}
+	return proof, nil
+}
+
+// GetRoot reads campaign's root from its index
+func (a *S3Archive) GetRoot(ctx context.Context, campaign string) (*Root, error) {
+	index, err := a.index(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	return &Root{MerkleRoot: index.MerkleRoot, Metadata: index.Metadata, TotalClaims: index.TotalClaims}, nil
+}
+
+// index returns campaign's shard index, fetching it unless it is cached
+func (a *S3Archive) index(ctx context.Context, campaign string) (*data.ShardIndex, error) {
+	if err := ValidateCampaign(campaign); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCampaignNotFound, err)
+	}
+	if index, ok := a.indexes.Get(campaign); ok {
+		return index, nil
+	}
+
+	content, err := a.get(ctx, campaign+"/"+data.ShardIndexFile)
+	if errors.Is(err, errNoSuchKey) {
+		return nil, fmt.Errorf("%s: %w", campaign, ErrCampaignNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch index of campaign %s: %w", campaign, err)
+	}
+	index, err := decodeIndex(content)
+	if err != nil {
+		return nil, fmt.Errorf("campaign %s: %w", campaign, err)
+	}
+	a.indexes.Add(campaign, index)
+	return index, nil
+}
+
+// decodeIndex decodes and checks a shard index
+func decodeIndex(content []byte) (*data.ShardIndex, error) {
+	index := data.ShardIndex{Metadata: merkle.DefaultMetadata()}
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("failed to decode shard index: %w", err)
+	}
+	if index.MerkleRoot == "" {
+		return nil, fmt.Errorf("shard index has no merkleRoot")
+	}
+	if err := data.ValidateShardBits(index.ShardBits); err != nil {
+		return nil, fmt.Errorf("invalid shard index: %w", err)
+	}
+	for prefix, name := range index.Shards {
+		if filepath.Base(name) != name || name == data.ShardIndexFile {
+			return nil, fmt.Errorf("shard %s: invalid file name %q", prefix, name)
+		}
+	}
+	return &index, nil
+}
+
+// objectURL returns the path-style URL of key
+func (a *S3Archive) objectURL(key string) string {
+	u := *a.endpoint
+	u.Path = a.endpoint.Path + "/" + a.bucket + "/" + key
+	return u.String()
+}
+
+// put uploads content as key
+func (a *S3Archive) put(ctx context.Context, key string, content []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, a.objectURL(key), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	digest := sha256.Sum256(content)
+	resp, err := a.do(req, hex.EncodeToString(digest[:]))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// get downloads key, returning errNoSuchKey if it doesn't exist
+func (a *S3Archive) get(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	emptyDigest := sha256.Sum256(nil)
+	resp, err := a.do(req, hex.EncodeToString(emptyDigest[:]))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxObjectSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if len(content) > maxObjectSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", key, maxObjectSize)
+	}
+	return content, nil
+}
+
+// do signs and sends req, turning error statuses into errors
+func (a *S3Archive) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	if a.creds.AccessKeyID != "" {
+		SignRequest(req, payloadHash, a.creds, a.region, time.Now())
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("archive request failed: %w", err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && req.Method == http.MethodGet {
+		return nil, errNoSuchKey
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	return nil, fmt.Errorf("archive %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+}
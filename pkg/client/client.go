@@ -0,0 +1,305 @@
+// Package client is a Go client for the proof API served by internal/api
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Defaults for a Client
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 3
+	DefaultBackoff    = 200 * time.Millisecond
+)
+
+// maxBackoff caps the wait between retries, including a server-sent
+// Retry-After
+const maxBackoff = 30 * time.Second
+
+// Client calls the proof API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	timeout    time.Duration
+	token      string
+	maxRetries int
+	backoff    time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithTimeout bounds each HTTP attempt, DefaultTimeout unless set. Zero
+// leaves attempts bounded only by the context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithAuthToken sends token as a bearer token with every request
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient makes requests with httpClient instead of
+// http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries retries 5xx, 429 and proof-not-ready (202) responses up to
+// maxRetries times, waiting backoff before the first retry and doubling it
+// each time after, or as long as the server's Retry-After asks. Zero
+// disables retries.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// NewClient returns a client for the API at baseURL, such as
+// "https://airdrop.example.com"
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: expected http or https", baseURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		timeout:    DefaultTimeout,
+		maxRetries: DefaultMaxRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Root is the tree root and the encoding its proofs use
+type Root struct {
+	MerkleRoot string              `json:"merkleRoot"`
+	Metadata   merkle.TreeMetadata `json:"metadata"`
+}
+
+// Stats are the airdrop's statistics
+type Stats struct {
+	TotalClaims int               `json:"totalClaims"`
+	TotalProofs int               `json:"totalProofs"`
+	MerkleRoot  string            `json:"merkleRoot"`
+	ProofDepth  int               `json:"proofDepth"`
+	TotalAmount string            `json:"totalAmount,omitempty"` // Decimal base units, when known
+	Indices     merkle.IndexStats `json:"indices"`
+}
+
+// Verification is the result of checking a proof
+type Verification struct {
+	Valid bool `json:"valid"`
+
+	// AlreadyClaimed is set when the server checks the distributor, which
+	// is ContractAddress
+	AlreadyClaimed  *bool  `json:"alreadyClaimed,omitempty"`
+	ContractAddress string `json:"contractAddress,omitempty"`
+}
+
+// GetRoot returns the tree root
+func (c *Client) GetRoot(ctx context.Context) (*Root, error) {
+	var root Root
+	if err := c.do(ctx, http.MethodGet, "/api/root", nil, &root); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// GetProof returns the proof for address. Addresses not in the airdrop
+// give ErrNotFound; a server still precomputing proofs gives ErrNotReady
+// once the retries run out.
+func (c *Client) GetProof(ctx context.Context, address common.Address) (*merkle.MerkleProof, error) {
+	var response struct {
+		merkle.MerkleProof
+		MerkleRoot string `json:"merkleRoot"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/proof/"+address.Hex(), nil, &response); err != nil {
+		return nil, err
+	}
+	proof := response.MerkleProof
+	proof.Root = response.MerkleRoot
+	return &proof, nil
+}
+
+// VerifyProof asks the server whether proof shows claim is in the airdrop.
+// A proof that doesn't verify is not an error; a malformed one is, and one
+// for another root than the served one matches merkle.ErrRootMismatch.
+func (c *Client) VerifyProof(ctx context.Context, claim merkle.AirdropClaim, proof *merkle.MerkleProof) (*Verification, error) {
+	if claim.Amount == nil || proof == nil {
+		return nil, fmt.Errorf("claim amount and proof are required")
+	}
+	request := map[string]interface{}{
+		"address":   claim.Address.Hex(),
+		"amount":    claim.Amount.String(),
+		"index":     claim.Index,
+		"proof":     proof.Proof,
+		"positions": proof.Positions,
+	}
+	if proof.Root != "" {
+		request["root"] = proof.Root
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var verification Verification
+	if err := c.do(ctx, http.MethodPost, "/api/verify", body, &verification); err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// GetStats returns the airdrop's statistics
+func (c *Client) GetStats(ctx context.Context) (*Stats, error) {
+	var stats Stats
+	if err := c.do(ctx, http.MethodGet, "/api/stats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// Eligible reports whether address is in the airdrop
+func (c *Client) Eligible(ctx context.Context, address common.Address) (bool, error) {
+	var response struct {
+		Eligible bool `json:"eligible"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/eligible/"+address.Hex(), nil, &response); err != nil {
+		return false, err
+	}
+	return response.Eligible, nil
+}
+
+// do sends a request, retrying retryable responses, and decodes a
+// successful response body into out
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out interface{}) error {
+	endpoint := c.baseURL.JoinPath(path).String()
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, endpoint, body, out)
+		apiErr, ok := err.(*APIError)
+		if !ok || !retryable(apiErr) || attempt >= c.maxRetries {
+			return err
+		}
+
+		wait := backoff
+		if apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+		timer := time.NewTimer(min(wait, maxBackoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// attempt sends one request; error responses come back as *APIError
+func (c *Client) attempt(ctx context.Context, method, endpoint string, body []byte, out interface{}) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("%s %s: failed to decode response: %w", method, endpoint, err)
+		}
+		return nil
+	}
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	var envelope struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		RequestID string `json:"requestId"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&envelope) == nil {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+		apiErr.RequestID = envelope.RequestID
+	}
+	return apiErr
+}
+
+// retryable reports whether a request that got err may succeed if repeated
+func retryable(err *APIError) bool {
+	return err.StatusCode >= 500 || err.StatusCode == http.StatusTooManyRequests || err.StatusCode == http.StatusAccepted
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
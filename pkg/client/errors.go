@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// Errors the API's error codes map to, along with merkle.ErrRootMismatch.
+// Match them with errors.Is; use errors.As with *APIError for the details,
+// such as RetryAfter.
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrInvalidAddress = errors.New("invalid address")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrNotReady       = errors.New("proof not ready")
+)
+
+// Error codes of the API's error envelope, as internal/api defines them
+const (
+	codeInvalidAddress  = "INVALID_ADDRESS"
+	codeAddressNotFound = "ADDRESS_NOT_FOUND"
+	codeNotFound        = "NOT_FOUND"
+	codeRootMismatch    = "ROOT_MISMATCH"
+)
+
+// APIError is an error response from the API
+type APIError struct {
+	StatusCode int
+	Code       string // The envelope's error code, empty when the body had none
+	Message    string
+	RequestID  string
+
+	// RetryAfter is the server's Retry-After, for rate limited and
+	// not-yet-ready responses
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("api: HTTP %d", e.StatusCode)
+	}
+	return fmt.Sprintf("api: HTTP %d: %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is reports whether e is one of the package's sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Code == codeAddressNotFound || e.Code == codeNotFound
+	case ErrInvalidAddress:
+		return e.Code == codeInvalidAddress
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrNotReady:
+		return e.StatusCode == http.StatusAccepted
+	case merkle.ErrRootMismatch:
+		return e.Code == codeRootMismatch
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package contract
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// AttestationVersion is the version of the attestation payload schema
+const AttestationVersion = 1
+
+// registryABI is the attestation registry published digests are sent to
+const registryABI = `[{"type":"function","name":"attest","stateMutability":"nonpayable","inputs":[{"name":"digest","type":"bytes32"}],"outputs":[]}]`
+
+// CampaignMeta is what an attestation records about the campaign besides
+// its tree
+type CampaignMeta struct {
+	Name        string
+	InputSHA256 string    // Hex SHA-256 of the claims file the tree was built from
+	Timestamp   time.Time // Time of the attestation; the current time when zero
+}
+
+// AttestationPayload is the attested statement. It is encoded with its
+// fields in this order, so the same campaign always gives the same bytes.
+type AttestationPayload struct {
+	Version      int                 `json:"version"`
+	Campaign     string              `json:"campaign,omitempty"`
+	MerkleRoot   string              `json:"merkleRoot"`
+	TotalClaims  int                 `json:"totalClaims"`
+	TotalAmount  string              `json:"totalAmount"`
+	InputSHA256  string              `json:"inputSha256"`
+	LeafEncoding merkle.TreeMetadata `json:"leafEncoding"`
+	Timestamp    string              `json:"timestamp"` // RFC3339, UTC, whole seconds
+}
+
+// Attestation is a root attestation ready for signing and publishing
+type Attestation struct {
+	Payload AttestationPayload
+	Encoded []byte      // Canonical JSON of Payload, the signed message
+	Digest  common.Hash // keccak256 of Encoded, the value published on chain
+
+	// Set by Sign
+	Signer    common.Address
+	Signature []byte // EIP-191 personal_sign signature of Encoded, v as 27 or 28
+}
+
+// MessageSigner is a Signer that can also sign messages. KeySigner is one;
+// RemoteSigner only signs transactions.
+type MessageSigner interface {
+	Signer
+	// SignMessage returns the EIP-191 personal_sign signature of message
+	SignMessage(message []byte) ([]byte, error)
+}
+
+// SignMessage signs message as personal_sign does, so it verifies in
+// wallets and Etherscan's signature checker
+func (s *KeySigner) SignMessage(message []byte) ([]byte, error) {
+	sig, err := crypto.Sign(accounts.TextHash(message), s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// BuildRootAttestation describes tree and meta in a canonical payload and
+// computes its digest
+func BuildRootAttestation(tree *merkle.MerkleTree, meta CampaignMeta) (Attestation, error) {
+	var att Attestation
+	if tree == nil || tree.Root == nil {
+		return att, fmt.Errorf("attestation needs a built tree")
+	}
+	inputHash := strings.ToLower(strings.TrimPrefix(meta.InputSHA256, "0x"))
+	if decoded, err := hex.DecodeString(inputHash); err != nil || len(decoded) != 32 {
+		return att, fmt.Errorf("invalid input SHA-256 %q", meta.InputSHA256)
+	}
+	timestamp := meta.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	att.Payload = AttestationPayload{
+		Version:      AttestationVersion,
+		Campaign:     meta.Name,
+		MerkleRoot:   tree.GetRootHash(),
+		TotalClaims:  len(tree.Claims),
+		TotalAmount:  merkle.TotalAmount(tree.Claims).String(),
+		InputSHA256:  inputHash,
+		LeafEncoding: tree.Metadata(),
+		Timestamp:    timestamp.UTC().Truncate(time.Second).Format(time.RFC3339),
+	}
+	encoded, err := json.Marshal(att.Payload)
+	if err != nil {
+		return att, fmt.Errorf("failed to encode attestation: %w", err)
+	}
+	att.Encoded = encoded
+	att.Digest = crypto.Keccak256Hash(encoded)
+	return att, nil
+}
+
+// Sign signs the encoded payload with signer
+func (a *Attestation) Sign(signer Signer) error {
+	messageSigner, ok := signer.(MessageSigner)
+	if !ok {
+		return fmt.Errorf("signer %s cannot sign messages", signer.Address().Hex())
+	}
+	sig, err := messageSigner.SignMessage(a.Encoded)
+	if err != nil {
+		return fmt.Errorf("failed to sign attestation: %w", err)
+	}
+	a.Signer, a.Signature = signer.Address(), sig
+	return nil
+}
+
+// RecoverAttestationSigner returns the account whose personal_sign
+// signature of encoded is sig
+func RecoverAttestationSigner(encoded, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes", crypto.SignatureLength)
+	}
+	sig = append([]byte(nil), sig...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(encoded), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("invalid signature: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// SetAttestationRegistry sends later published attestations to the
+// registry at address, whose attest(bytes32) records them
+func (cc *ContractClient) SetAttestationRegistry(address common.Address) {
+	cc.registry = &address
+}
+
+// PublishAttestation records payloadHash on chain: as a call to the
+// attestation registry if one is set, otherwise as the calldata of a
+// zero-value transaction to the client's own account
+func (cc *ContractClient) PublishAttestation(ctx context.Context, payloadHash [32]byte) (*types.Transaction, error) {
+	auth, err := cc.transactor()
+	if err != nil {
+		return nil, err
+	}
+	auth.Context = ctx
+
+	if cc.registry != nil {
+		parsed, err := abi.JSON(strings.NewReader(registryABI))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse registry ABI: %w", err)
+		}
+		registry := bind.NewBoundContract(*cc.registry, parsed, cc.client, cc.client, cc.client)
+		return registry.Transact(auth, "attest", payloadHash)
+	}
+
+	nonce, err := cc.client.PendingNonceAt(ctx, auth.From)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce: %w", err)
+	}
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    nonce,
+		GasPrice: auth.GasPrice,
+		Gas:      auth.GasLimit,
+		To:       &auth.From,
+		Data:     payloadHash[:],
+	})
+	signed, err := auth.Signer(auth.From, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign attestation transaction: %w", err)
+	}
+	if err := cc.client.SendTransaction(ctx, signed); err != nil {
+		return nil, fmt.Errorf("failed to send attestation transaction: %w", err)
+	}
+	return signed, nil
+}
@@ -0,0 +1,135 @@
+package contract
+
+import (
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ABI and bytecode produced by scripts/compile-bindings.js from
+// contracts/MerkleDistributor.sol
+var (
+	//go:embed MerkleDistributor.abi
+	merkleDistributorABI string
+
+	//go:embed MerkleDistributor.bin
+	merkleDistributorBin string
+)
+
+// MerkleDistributor is a binding to a deployed MerkleDistributor contract
+type MerkleDistributor struct {
+	Address  common.Address
+	contract *bind.BoundContract
+}
+
+// parseDistributorABI parses the embedded distributor ABI
+func parseDistributorABI() (abi.ABI, error) {
+	parsed, err := abi.JSON(strings.NewReader(merkleDistributorABI))
+	if err != nil {
+		return abi.ABI{}, fmt.Errorf("failed to parse distributor ABI: %w", err)
+	}
+	return parsed, nil
+}
+
+// distributorABI is the parsed distributor ABI, for decoding many logs
+var distributorABI = sync.OnceValues(parseDistributorABI)
+
+// DeployMerkleDistributor deploys a new MerkleDistributor for token and root
+func DeployMerkleDistributor(auth *bind.TransactOpts, backend bind.ContractBackend, token common.Address, merkleRoot [32]byte) (common.Address, *types.Transaction, *MerkleDistributor, error) {
+	parsed, err := parseDistributorABI()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+
+	bytecode := common.FromHex(strings.TrimSpace(merkleDistributorBin))
+	address, tx, contract, err := bind.DeployContract(auth, parsed, bytecode, backend, token, merkleRoot)
+	if err != nil {
+		return common.Address{}, nil, nil, fmt.Errorf("failed to deploy distributor: %w", err)
+	}
+
+	return address, tx, &MerkleDistributor{Address: address, contract: contract}, nil
+}
+
+// NewMerkleDistributor binds to a distributor already deployed at address
+func NewMerkleDistributor(address common.Address, backend bind.ContractBackend) (*MerkleDistributor, error) {
+	parsed, err := parseDistributorABI()
+	if err != nil {
+		return nil, err
+	}
+
+	contract := bind.NewBoundContract(address, parsed, backend, backend, backend)
+	return &MerkleDistributor{Address: address, contract: contract}, nil
+}
+
+// MerkleRoot returns the root the distributor verifies claims against
+func (d *MerkleDistributor) MerkleRoot(opts *bind.CallOpts) ([32]byte, error) {
+	var out []interface{}
+	if err := d.contract.Call(opts, &out, "merkleRoot"); err != nil {
+		return [32]byte{}, err
+	}
+	return *abi.ConvertType(out[0], new([32]byte)).(*[32]byte), nil
+}
+
+// Token returns the address of the token being distributed
+func (d *MerkleDistributor) Token(opts *bind.CallOpts) (common.Address, error) {
+	var out []interface{}
+	if err := d.contract.Call(opts, &out, "token"); err != nil {
+		return common.Address{}, err
+	}
+	return *abi.ConvertType(out[0], new(common.Address)).(*common.Address), nil
+}
+
+// IsClaimed reports whether the claim at index has been made
+func (d *MerkleDistributor) IsClaimed(opts *bind.CallOpts, index *big.Int) (bool, error) {
+	var out []interface{}
+	if err := d.contract.Call(opts, &out, "isClaimed", index); err != nil {
+		return false, err
+	}
+	return *abi.ConvertType(out[0], new(bool)).(*bool), nil
+}
+
+// Claim submits a claim transaction for account
+func (d *MerkleDistributor) Claim(auth *bind.TransactOpts, index *big.Int, account common.Address, amount *big.Int, proof [][32]byte) (*types.Transaction, error) {
+	return d.contract.Transact(auth, "claim", index, account, amount, proof)
+}
+
+// ClaimedTopic is the topic of the distributor's
+// Claimed(uint256 index, address account, uint256 amount) event
+var ClaimedTopic = crypto.Keccak256Hash([]byte("Claimed(uint256,address,uint256)"))
+
+// ClaimedEvent is a Claimed event emitted by a distributor
+type ClaimedEvent struct {
+	Index   *big.Int
+	Account common.Address
+	Amount  *big.Int
+	Raw     types.Log
+}
+
+// ParseClaimed decodes a Claimed event log
+func ParseClaimed(log types.Log) (*ClaimedEvent, error) {
+	if len(log.Topics) == 0 || log.Topics[0] != ClaimedTopic {
+		return nil, fmt.Errorf("log is not a Claimed event")
+	}
+	parsed, err := distributorABI()
+	if err != nil {
+		return nil, err
+	}
+	values, err := parsed.Events["Claimed"].Inputs.Unpack(log.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Claimed event: %w", err)
+	}
+	return &ClaimedEvent{
+		Index:   *abi.ConvertType(values[0], new(*big.Int)).(**big.Int),
+		Account: *abi.ConvertType(values[1], new(common.Address)).(*common.Address),
+		Amount:  *abi.ConvertType(values[2], new(*big.Int)).(**big.Int),
+		Raw:     log,
+	}, nil
+}
@@ -1,22 +1,57 @@
-// pkg/contract/client.go
 package contract
 
 import (
 	"context"
 	"crypto/ecdsa"
+	"fmt"
 	"math/big"
 
+	"merkle-airdrop/pkg/data"
+
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// Backend is the subset of an Ethereum client needed for contract interactions
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+}
+
+// Default gas settings for transactions sent by a ContractClient
+const (
+	DefaultGasLimit = 3000000
+	DefaultGasPrice = 20000000000 // 20 gwei
+)
+
+// mainnetChainIDs are chains where funds are real: Ethereum, OP Mainnet,
+// BNB Smart Chain, Polygon, Base and Arbitrum One
+var mainnetChainIDs = map[uint64]bool{1: true, 10: true, 56: true, 137: true, 8453: true, 42161: true}
+
+// TestKeyError reports a transaction refused because it would be sent to a
+// mainnet from a key seeded by data.GenerateTestDataWithKeys
+type TestKeyError struct {
+	Address common.Address
+	ChainID uint64
+}
+
+func (e *TestKeyError) Error() string {
+	return fmt.Sprintf("refusing to send from %s on chain %d: it is a seeded test key", e.Address.Hex(), e.ChainID)
+}
+
 // ContractClient handles Ethereum contract interactions
 type ContractClient struct {
-	client     *ethclient.Client
-	privateKey *ecdsa.PrivateKey
-	chainID    *big.Int
+	client  Backend
+	signer  Signer
+	chainID *big.Int
+
+	gasLimit uint64
+	gasPrice *big.Int
+
+	registry *common.Address // Attestation registry; nil to self-send digests
 }
 
 // NewContractClient creates a new contract client
@@ -26,7 +61,7 @@ func NewContractClient(rpcURL, privateKeyHex string) (*ContractClient, error) {
 		return nil, err
 	}
 
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	signer, err := NewKeySignerFromHex(privateKeyHex)
 	if err != nil {
 		return nil, err
 	}
@@ -36,27 +71,126 @@ func NewContractClient(rpcURL, privateKeyHex string) (*ContractClient, error) {
 		return nil, err
 	}
 
+	return NewContractClientWithSigner(client, signer, chainID), nil
+}
+
+// NewContractClientWithBackend creates a contract client on an existing backend
+// (e.g. a simulated chain in tests)
+func NewContractClientWithBackend(backend Backend, privateKey *ecdsa.PrivateKey, chainID *big.Int) *ContractClient {
+	return NewContractClientWithSigner(backend, NewKeySigner(privateKey), chainID)
+}
+
+// NewContractClientWithSigner creates a contract client that signs through
+// signer, e.g. a keystore or remote signing service
+func NewContractClientWithSigner(backend Backend, signer Signer, chainID *big.Int) *ContractClient {
 	return &ContractClient{
-		client:     client,
-		privateKey: privateKey,
-		chainID:    chainID,
-	}, nil
+		client:  backend,
+		signer:  signer,
+		chainID: chainID,
+
+		gasLimit: DefaultGasLimit,
+		gasPrice: big.NewInt(DefaultGasPrice),
+	}
+}
+
+// SetGas overrides the gas limit and price of later transactions; zero
+// values keep the current setting
+func (cc *ContractClient) SetGas(gasLimit uint64, gasPrice *big.Int) {
+	if gasLimit != 0 {
+		cc.gasLimit = gasLimit
+	}
+	if gasPrice != nil && gasPrice.Sign() > 0 {
+		cc.gasPrice = new(big.Int).Set(gasPrice)
+	}
+}
+
+// Backend returns the underlying chain backend
+func (cc *ContractClient) Backend() Backend {
+	return cc.client
+}
+
+// Address returns the account transactions are sent from
+func (cc *ContractClient) Address() common.Address {
+	return cc.signer.Address()
 }
 
-// DeployAirdrop deploys the airdrop contract
+// transactor builds transaction options signed by the client's signer
+func (cc *ContractClient) transactor() (*bind.TransactOpts, error) {
+	if cc.chainID == nil {
+		return nil, bind.ErrNoChainID
+	}
+
+	from := cc.signer.Address()
+	if cc.chainID.IsUint64() && mainnetChainIDs[cc.chainID.Uint64()] && data.IsTestKeyAddress(from) {
+		return nil, &TestKeyError{Address: from, ChainID: cc.chainID.Uint64()}
+	}
+	auth := &bind.TransactOpts{
+		From: from,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != from {
+				return nil, bind.ErrNotAuthorized
+			}
+			return cc.signer.SignTx(tx, cc.chainID)
+		},
+		Context: context.Background(),
+	}
+
+	// Set gas limit and price
+	auth.GasLimit = cc.gasLimit
+	auth.GasPrice = new(big.Int).Set(cc.gasPrice)
+
+	return auth, nil
+}
+
+// DeployAirdrop deploys the airdrop contract. The returned address is final
+// once the deployment transaction is mined.
 func (cc *ContractClient) DeployAirdrop(tokenAddress common.Address, merkleRoot [32]byte) (common.Address, error) {
-	auth, err := bind.NewKeyedTransactorWithChainID(cc.privateKey, cc.chainID)
+	address, _, err := cc.deployAirdrop(tokenAddress, merkleRoot)
+	return address, err
+}
+
+// deployAirdrop is DeployAirdrop also returning the deployment transaction
+func (cc *ContractClient) deployAirdrop(tokenAddress common.Address, merkleRoot [32]byte) (common.Address, *types.Transaction, error) {
+	auth, err := cc.transactor()
 	if err != nil {
-		return common.Address{}, err
+		return common.Address{}, nil, err
 	}
 
-	// Set gas limit and price
-	auth.GasLimit = uint64(3000000)
-	auth.GasPrice = big.NewInt(20000000000) // 20 gwei
+	address, tx, _, err := DeployMerkleDistributor(auth, cc.client, tokenAddress, merkleRoot)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
 
-	// Deploy contract (you'll need to generate Go bindings from the Solidity contract)
-	// This is a simplified example
-	address := common.HexToAddress("0x...") // Contract address after deployment
+	return address, tx, nil
+}
 
-	return address, nil
+// Claim submits a claim on the distributor at contractAddress
+func (cc *ContractClient) Claim(contractAddress common.Address, index uint32, account common.Address, amount *big.Int, proof [][32]byte) (*types.Transaction, error) {
+	auth, err := cc.transactor()
+	if err != nil {
+		return nil, err
+	}
+
+	distributor, err := NewMerkleDistributor(contractAddress, cc.client)
+	if err != nil {
+		return nil, err
+	}
+
+	return distributor.Claim(auth, new(big.Int).SetUint64(uint64(index)), account, amount, proof)
+}
+
+// DecodeProof converts hex-encoded proof hashes into contract arguments
+func DecodeProof(proof []string) ([][32]byte, error) {
+	decoded := make([][32]byte, len(proof))
+	for i, h := range proof {
+		b, err := hexutil.Decode(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proof element %d: %w", i, err)
+		}
+		if len(b) != 32 {
+			return nil, fmt.Errorf("invalid proof element %d: expected 32 bytes, got %d", i, len(b))
+		}
+		copy(decoded[i][:], b)
+	}
+	return decoded, nil
 }
@@ -0,0 +1,235 @@
+package contract
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"merkle-airdrop/internal/fsutil"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// DefaultDeployAttempts is how often a chain deployment is tried when its
+// ChainConfig doesn't say
+const DefaultDeployAttempts = 3
+
+// deployRetryDelay is the wait before the second attempt; it doubles after
+// every failure
+const deployRetryDelay = 2 * time.Second
+
+// ChainBackend is a Backend that can report its chain ID
+type ChainBackend interface {
+	Backend
+	ethereum.ChainIDReader
+}
+
+// ChainConfig describes one chain to deploy the distributor to
+type ChainConfig struct {
+	Name    string `json:"name"`
+	RPCURL  string `json:"rpc_url"`
+	ChainID uint64 `json:"chain_id"`
+
+	// GasLimit and GasPrice (in wei) override the client defaults; a zero
+	// GasPrice uses the node's suggested price
+	GasLimit uint64 `json:"gas_limit,omitempty"`
+	GasPrice int64  `json:"gas_price,omitempty"`
+
+	// Attempts bounds the tries of a failing deployment, DefaultDeployAttempts
+	// when zero. A deployment is never resent once its transaction is out.
+	Attempts int `json:"attempts,omitempty"`
+
+	// Signer sends the deployment. Backend is used instead of dialing RPCURL
+	// when set, e.g. for a simulated chain.
+	Signer  Signer       `json:"-"`
+	Backend ChainBackend `json:"-"`
+}
+
+// DeployResult records the deployment to one chain. Error is set when it
+// failed; Contract and TxHash are still set if the transaction was sent.
+type DeployResult struct {
+	ChainID     uint64         `json:"chainId"`
+	Token       common.Address `json:"token"`
+	MerkleRoot  string         `json:"merkleRoot"`
+	Contract    common.Address `json:"contract"`
+	TxHash      common.Hash    `json:"txHash"`
+	BlockNumber uint64         `json:"blockNumber"`
+	Attempts    int            `json:"attempts"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// DeployToChains deploys a distributor for root to every chain concurrently,
+// retrying each on failure. tokenAddrs maps chain names to the token the
+// distributor pays out there. Results are keyed by chain name; a failed
+// chain is reported in its result, and the error is only set for invalid
+// input.
+func DeployToChains(ctx context.Context, chains []ChainConfig, tokenAddrs map[string]common.Address, root [32]byte) (map[string]DeployResult, error) {
+	if len(chains) == 0 {
+		return nil, errors.New("no chains to deploy to")
+	}
+	seen := make(map[string]bool, len(chains))
+	for _, chain := range chains {
+		switch {
+		case chain.Name == "":
+			return nil, errors.New("chain without a name")
+		case seen[chain.Name]:
+			return nil, fmt.Errorf("chain %s is listed twice", chain.Name)
+		case chain.Signer == nil:
+			return nil, fmt.Errorf("chain %s has no signer", chain.Name)
+		case chain.Backend == nil && chain.RPCURL == "":
+			return nil, fmt.Errorf("chain %s has no rpc_url", chain.Name)
+		}
+		if _, ok := tokenAddrs[chain.Name]; !ok {
+			return nil, fmt.Errorf("no token address for chain %s", chain.Name)
+		}
+		seen[chain.Name] = true
+	}
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]DeployResult, len(chains))
+	)
+	for _, chain := range chains {
+		wg.Add(1)
+		go func(chain ChainConfig) {
+			defer wg.Done()
+			result := deployWithRetries(ctx, chain, tokenAddrs[chain.Name], root)
+
+			mu.Lock()
+			results[chain.Name] = result
+			mu.Unlock()
+		}(chain)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// deployWithRetries deploys to chain, retrying with a doubling delay until it
+// succeeds, a transaction has been sent, or the attempts run out
+func deployWithRetries(ctx context.Context, chain ChainConfig, token common.Address, root [32]byte) DeployResult {
+	result := DeployResult{ChainID: chain.ChainID, Token: token, MerkleRoot: hexutil.Encode(root[:])}
+	attempts := chain.Attempts
+	if attempts <= 0 {
+		attempts = DefaultDeployAttempts
+	}
+
+	delay := deployRetryDelay
+	for {
+		result.Attempts++
+		retry, err := deployOnce(ctx, chain, token, root, &result)
+		if err == nil {
+			result.Error = ""
+			return result
+		}
+		result.Error = err.Error()
+		if !retry || result.Attempts >= attempts {
+			return result
+		}
+
+		select {
+		case <-ctx.Done():
+			result.Error = fmt.Sprintf("%s (gave up: %v)", result.Error, ctx.Err())
+			return result
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// deployOnce makes one deployment attempt, filling in result as it goes. It
+// reports whether a failure may be retried, which it may not once the
+// transaction was sent or the chain is misconfigured.
+func deployOnce(ctx context.Context, chain ChainConfig, token common.Address, root [32]byte, result *DeployResult) (bool, error) {
+	backend := chain.Backend
+	if backend == nil {
+		client, err := ethclient.DialContext(ctx, chain.RPCURL)
+		if err != nil {
+			return true, fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Close()
+		backend = client
+	}
+
+	chainID, err := backend.ChainID(ctx)
+	if err != nil {
+		return true, fmt.Errorf("failed to read chain ID: %w", err)
+	}
+	if chainID.Uint64() != chain.ChainID {
+		return false, fmt.Errorf("rpc_url serves chain %s, expected %d", chainID, chain.ChainID)
+	}
+
+	gasPrice := big.NewInt(chain.GasPrice)
+	if chain.GasPrice == 0 {
+		if gasPrice, err = backend.SuggestGasPrice(ctx); err != nil {
+			return true, fmt.Errorf("failed to read gas price: %w", err)
+		}
+	}
+	client := NewContractClientWithSigner(backend, chain.Signer, chainID)
+	client.SetGas(chain.GasLimit, gasPrice)
+
+	address, tx, err := client.deployAirdrop(token, root)
+	if err != nil {
+		return true, fmt.Errorf("failed to send deployment: %w", err)
+	}
+	result.Contract = address
+	result.TxHash = tx.Hash()
+
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		return false, fmt.Errorf("failed waiting for %s: %w", tx.Hash().Hex(), err)
+	}
+	result.BlockNumber = receipt.BlockNumber.Uint64()
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, fmt.Errorf("deployment %s reverted", tx.Hash().Hex())
+	}
+
+	distributor, err := NewMerkleDistributor(address, backend)
+	if err != nil {
+		return false, err
+	}
+	onChainRoot, err := distributor.MerkleRoot(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return false, fmt.Errorf("failed to read deployed root: %w", err)
+	}
+	if onChainRoot != root {
+		return false, fmt.Errorf("deployed root 0x%x does not match 0x%x", onChainRoot, root)
+	}
+	return false, nil
+}
+
+// FailedChains returns the sorted names of the chains whose deployment failed
+func FailedChains(results map[string]DeployResult) []string {
+	var failed []string
+	for name, result := range results {
+		if result.Error != "" {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// SaveDeployments writes results to filename as JSON keyed by chain name
+func SaveDeployments(results map[string]DeployResult, filename string) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return fmt.Errorf("failed to encode deployments: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,25 @@
+package contract
+
+import (
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RootReader reads the roots of deployed distributors
+type RootReader struct {
+	backend bind.ContractBackend
+}
+
+// NewRootReader reads distributor roots through backend
+func NewRootReader(backend bind.ContractBackend) *RootReader {
+	return &RootReader{backend: backend}
+}
+
+// MerkleRoot returns the root of the distributor at contract
+func (r *RootReader) MerkleRoot(contract common.Address) ([32]byte, error) {
+	distributor, err := NewMerkleDistributor(contract, r.backend)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return distributor.MerkleRoot(&bind.CallOpts{})
+}
@@ -0,0 +1,161 @@
+package contract
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer signs transactions for a single account
+type Signer interface {
+	Address() common.Address
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// KeySigner signs with an in-memory private key
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner creates a signer for privateKey
+func NewKeySigner(privateKey *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:     privateKey,
+		address: crypto.PubkeyToAddress(privateKey.PublicKey),
+	}
+}
+
+// NewKeySignerFromHex creates a signer from a hex-encoded private key
+func NewKeySignerFromHex(privateKeyHex string) (*KeySigner, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	return NewKeySigner(privateKey), nil
+}
+
+// NewKeystoreSigner decrypts an encrypted go-ethereum keystore file
+func NewKeystoreSigner(path, passphrase string) (*KeySigner, error) {
+	keyJSON, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore: %w", err)
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+// Address returns the signing account
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx signs tx for chainID
+func (s *KeySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// RemoteSigner asks an external signing service to sign transactions.
+//
+// It POSTs {"address", "chainId", "tx"} with the unsigned transaction in its
+// binary encoding and expects {"signedTx"} back. The returned transaction
+// must be the one sent and be signed by address.
+type RemoteSigner struct {
+	url     string
+	address common.Address
+	client  *http.Client
+}
+
+// NewRemoteSigner creates a signer for address backed by the service at url
+func NewRemoteSigner(url string, address common.Address) *RemoteSigner {
+	return &RemoteSigner{
+		url:     url,
+		address: address,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Address returns the signing account
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx sends tx to the signing service and checks the result
+func (s *RemoteSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"address": s.address.Hex(),
+		"chainId": chainID.String(),
+		"tx":      hexutil.Encode(unsigned),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signing request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("signing request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signing service returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		SignedTx string `json:"signedTx"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode signing response: %w", err)
+	}
+
+	raw, err := hexutil.Decode(result.SignedTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signed transaction: %w", err)
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("invalid signed transaction: %w", err)
+	}
+
+	// The service must sign exactly what was asked, as the expected account
+	signer := types.LatestSignerForChainID(chainID)
+	if signer.Hash(signed) != signer.Hash(tx) {
+		return nil, fmt.Errorf("signing service returned a different transaction")
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature from signing service: %w", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("signing service signed as %s, expected %s", sender.Hex(), s.address.Hex())
+	}
+
+	return signed, nil
+}
@@ -0,0 +1,27 @@
+package contract
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ClaimStatus reads claimed flags from a deployed distributor
+type ClaimStatus struct {
+	distributor *MerkleDistributor
+}
+
+// NewClaimStatus reads claimed flags from the distributor at address
+func NewClaimStatus(address common.Address, backend bind.ContractBackend) (*ClaimStatus, error) {
+	distributor, err := NewMerkleDistributor(address, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &ClaimStatus{distributor: distributor}, nil
+}
+
+// IsClaimed reports whether the claim at index has been made
+func (s *ClaimStatus) IsClaimed(index uint32) (bool, error) {
+	return s.distributor.IsClaimed(&bind.CallOpts{}, new(big.Int).SetUint64(uint64(index)))
+}
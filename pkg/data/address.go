@@ -0,0 +1,67 @@
+// pkg/data/address.go
+package data
+
+import (
+	"fmt"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AddressCase selects how exporters write addresses. Claims and proofs are
+// always keyed by common.Address or its checksummed hex internally; the case
+// only applies to output.
+type AddressCase int
+
+const (
+	// AddressChecksum writes EIP-55 checksummed hex
+	AddressChecksum AddressCase = iota
+	// AddressLower writes lowercase hex, for tools that compare addresses
+	// as plain strings
+	AddressLower
+)
+
+var addressCaseNames = map[AddressCase]string{
+	AddressChecksum: "checksum",
+	AddressLower:    "lower",
+}
+
+func (c AddressCase) String() string {
+	if name, ok := addressCaseNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("AddressCase(%d)", int(c))
+}
+
+// ParseAddressCase parses an address case name as returned by String
+func ParseAddressCase(name string) (AddressCase, error) {
+	for c, n := range addressCaseNames {
+		if n == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown address case: %q (expected checksum or lower)", name)
+}
+
+// Format writes address in case c
+func (c AddressCase) Format(address common.Address) string {
+	if c == AddressLower {
+		return strings.ToLower(address.Hex())
+	}
+	return address.Hex()
+}
+
+// FormatProofs returns proofs keyed by address in case c. The proofs
+// themselves are shared, not copied.
+func (c AddressCase) FormatProofs(proofs map[string]*merkle.MerkleProof) map[string]*merkle.MerkleProof {
+	if c == AddressChecksum {
+		return proofs
+	}
+	formatted := make(map[string]*merkle.MerkleProof, len(proofs))
+	for address, proof := range proofs {
+		formatted[c.Format(common.HexToAddress(address))] = proof
+	}
+	return formatted
+}
@@ -0,0 +1,135 @@
+// pkg/data/allocate.go
+package data
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AllocateProRata splits total between the addresses in weights in
+// proportion to their weights. Each share is rounded down, then the units
+// left over go one each to the shares with the largest fractional
+// remainders, lower addresses first on ties, so the amounts always add up
+// to exactly total. Claims are ordered by address and indexed from zero;
+// addresses whose share is zero get no claim.
+func AllocateProRata(weights map[common.Address]*big.Int, total *big.Int) ([]merkle.AirdropClaim, error) {
+	if err := checkAllocationTotal(total); err != nil {
+		return nil, err
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no weights provided")
+	}
+
+	addresses := make([]common.Address, 0, len(weights))
+	sum := new(big.Int)
+	for address, weight := range weights {
+		if weight == nil || weight.Sign() < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %v", address.Hex(), weight)
+		}
+		addresses = append(addresses, address)
+		sum.Add(sum, weight)
+	}
+	if sum.Sign() == 0 {
+		return nil, fmt.Errorf("weights add up to zero")
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+
+	// share = total*weight / sum, keeping what the division drops
+	shares := make([]*big.Int, len(addresses))
+	remainders := make([]*big.Int, len(addresses))
+	left := new(big.Int).Set(total)
+	for i, address := range addresses {
+		shares[i], remainders[i] = new(big.Int).QuoRem(new(big.Int).Mul(total, weights[address]), sum, new(big.Int))
+		left.Sub(left, shares[i])
+	}
+
+	// left is below the number of non-zero remainders, so dust never
+	// reaches a zero weight
+	order := make([]int, len(addresses))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for _, i := range order[:left.Int64()] {
+		shares[i].Add(shares[i], big.NewInt(1))
+	}
+
+	claims := make([]merkle.AirdropClaim, 0, len(addresses))
+	for i, address := range addresses {
+		if shares[i].Sign() == 0 {
+			continue // Share rounded down to nothing
+		}
+		claims = append(claims, merkle.AirdropClaim{
+			Address: address,
+			Amount:  shares[i],
+			Index:   uint32(len(claims)),
+		})
+	}
+	return claims, nil
+}
+
+// checkAllocationTotal rejects totals a distributor can't hold
+func checkAllocationTotal(total *big.Int) error {
+	if total == nil || total.Sign() <= 0 {
+		return fmt.Errorf("total allocation must be positive")
+	}
+	if !merkle.ValidAmount(total) {
+		return fmt.Errorf("total allocation %s exceeds 2^256-1", total)
+	}
+	return nil
+}
+
+// LoadWeightsFromCSV loads allocation weights from a CSV file with an
+// address,weight header. Weights are non-negative integers, such as
+// balances or points; an address may appear only once.
+func LoadWeightsFromCSV(filename string) (map[common.Address]*big.Int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	weights := make(map[common.Address]*big.Int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		address, weight, err := parseClaimFields(len(weights), record[0], record[1])
+		if err != nil {
+			return nil, &RowError{Row: line, Err: err}
+		}
+		if _, exists := weights[address]; exists {
+			return nil, &RowError{Row: line, Err: &DuplicateAddressError{Index: len(weights), Address: address}}
+		}
+		weights[address] = weight
+	}
+
+	return weights, nil
+}
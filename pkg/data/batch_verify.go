@@ -0,0 +1,214 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// MaxBatchLineBytes bounds one line of VerifyBatch input, enough for a
+// proof of any depth a uint32 index allows
+const MaxBatchLineBytes = 64 << 10
+
+// ErrTooManyEntries is returned by VerifyBatch when the input has more
+// entries than BatchOptions.MaxEntries
+var ErrTooManyEntries = errors.New("too many entries")
+
+// BatchEntry is one line of VerifyBatch's NDJSON input
+type BatchEntry struct {
+	Address   string   `json:"address"`
+	Amount    string   `json:"amount"`
+	Index     *uint32  `json:"index,omitempty"`
+	Proof     []string `json:"proof"`
+	Positions *uint64  `json:"positions,omitempty"`
+	Root      string   `json:"root,omitempty"` // The proof's root, checked when set
+}
+
+// BatchResult is one line of VerifyBatch's NDJSON output, for the input
+// line numbered Line (from 1)
+type BatchResult struct {
+	Line    int    `json:"line"`
+	Address string `json:"address,omitempty"`
+	Valid   bool   `json:"valid"`
+	Reason  string `json:"reason,omitempty"` // Why the entry is invalid
+}
+
+// BatchSummary counts the results VerifyBatch wrote
+type BatchSummary struct {
+	Entries int `json:"entries"`
+	Valid   int `json:"valid"`
+	Invalid int `json:"invalid"`
+}
+
+// BatchOptions configures VerifyBatch
+type BatchOptions struct {
+	// Workers verifying entries, one per CPU when zero
+	Workers int
+	// MaxEntries stops reading with ErrTooManyEntries past this many
+	// entries; no limit when zero
+	MaxEntries int
+	// Lookup fills in the index and positions of entries without them, as
+	// a served tree records them. Without it such entries are invalid
+	// when the encoding needs them.
+	Lookup func(address common.Address) (index uint32, positions uint64, ok bool)
+}
+
+// VerifyBatch reads NDJSON BatchEntry lines from r, verifies each against
+// root with encoding on parallel workers and writes a BatchResult line for
+// each to w in input order. Blank lines are skipped, and lines that aren't
+// entries are reported as invalid. Only a bounded window of entries is in
+// memory at a time. Results written before an error stay valid.
+func VerifyBatch(r io.Reader, w io.Writer, root []byte, encoding merkle.TreeOptions, opts BatchOptions) (BatchSummary, error) {
+	var summary BatchSummary
+	if opts.Workers < 0 {
+		return summary, fmt.Errorf("workers must not be negative: %d", opts.Workers)
+	}
+	workers := opts.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	type job struct {
+		line   int
+		raw    []byte
+		result chan BatchResult
+	}
+	jobs := make(chan job, workers)
+	pending := make(chan chan BatchResult, 4*workers) // Results in input order
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.result <- verifyBatchEntry(j.line, j.raw, root, encoding, opts.Lookup)
+			}
+		}()
+	}
+
+	var writeFailed atomic.Bool
+	written := make(chan error, 1)
+	go func() {
+		encoder := json.NewEncoder(w)
+		var err error
+		for result := range pending {
+			res := <-result
+			if err != nil {
+				continue // Drain the workers
+			}
+			if err = encoder.Encode(res); err != nil {
+				writeFailed.Store(true)
+				continue
+			}
+			summary.Entries++
+			if res.Valid {
+				summary.Valid++
+			} else {
+				summary.Invalid++
+			}
+		}
+		written <- err
+	}()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), MaxBatchLineBytes)
+	var readErr error
+	line, entries := 0, 0
+	for !writeFailed.Load() && scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if opts.MaxEntries > 0 && entries == opts.MaxEntries {
+			readErr = fmt.Errorf("line %d: %w (max %d)", line, ErrTooManyEntries, opts.MaxEntries)
+			break
+		}
+		entries++
+		result := make(chan BatchResult, 1)
+		pending <- result
+		jobs <- job{line: line, raw: bytes.Clone(raw), result: result}
+	}
+	if err := scanner.Err(); err != nil && readErr == nil {
+		readErr = fmt.Errorf("line %d: %w", line+1, err)
+	}
+	close(jobs)
+	close(pending)
+	wg.Wait()
+
+	if err := <-written; err != nil {
+		return summary, fmt.Errorf("failed to write results: %w", err)
+	}
+	return summary, readErr
+}
+
+// verifyBatchEntry checks the entry on one input line
+func verifyBatchEntry(line int, raw []byte, root []byte, encoding merkle.TreeOptions, lookup func(common.Address) (uint32, uint64, bool)) BatchResult {
+	result := BatchResult{Line: line}
+	var entry BatchEntry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		result.Reason = "malformed entry: " + err.Error()
+		return result
+	}
+	result.Address = entry.Address
+	if !common.IsHexAddress(entry.Address) {
+		result.Reason = "invalid address"
+		return result
+	}
+	amount, ok := new(big.Int).SetString(entry.Amount, 10)
+	if !ok {
+		result.Reason = "invalid amount"
+		return result
+	}
+	claim := merkle.AirdropClaim{Address: common.HexToAddress(entry.Address), Amount: amount}
+
+	var known bool
+	var index uint32
+	var positions uint64
+	if lookup != nil && (entry.Index == nil || entry.Positions == nil) {
+		index, positions, known = lookup(claim.Address)
+	}
+	switch {
+	case entry.Index != nil:
+		claim.Index = *entry.Index
+	case known:
+		claim.Index = index
+	case encoding.IncludeIndex:
+		result.Reason = "missing index"
+		return result
+	}
+	switch {
+	case entry.Positions != nil:
+		positions = *entry.Positions
+	case !known && !encoding.SortedPairs:
+		result.Reason = "missing positions"
+		return result
+	}
+
+	if err := merkle.CheckProofRoot(root, entry.Root); err != nil {
+		result.Reason = err.Error()
+		return result
+	}
+	valid, err := merkle.VerifyProofWithPositions(root, claim, entry.Proof, positions, encoding)
+	switch {
+	case err != nil:
+		result.Reason = err.Error()
+	case !valid:
+		result.Reason = "proof does not verify against the root"
+	default:
+		result.Valid = true
+	}
+	return result
+}
@@ -0,0 +1,325 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Binary proofs layout (all integers big-endian):
+//
+//	magic "MKPF" | version uint8 | root [32]byte |
+//	metadata length uvarint | metadata JSON | count uint32
+//	per entry: address [20]byte | amount length uvarint | amount bytes |
+//	           index uint32 | proof count uvarint | proof [count][32]byte |
+//	           positions uvarint | padding count uvarint
+//
+// Version 1 files have no metadata and use the default tree encoding.
+// Entries before version 3 have no positions, which only trees without
+// sorted pairs use, and entries before version 4 have no padding count,
+// which only fixed-depth trees use.
+// ExportProofsBinary writes entries in claim index order and
+// ExportTreeProofsBinary in leaf order. Files may be gzip-compressed;
+// the loader detects this from the gzip header.
+const (
+	binaryProofsMagic   = "MKPF"
+	binaryProofsVersion = 4
+)
+
+// ExportProofsBinary writes proofs and root in the compact binary format
+func ExportProofsBinary(proofs *merkle.ProofSet, root []byte, w io.Writer) error {
+	pw, err := NewBinaryProofWriter(w, root, proofs.Metadata, proofs.Len())
+	if err != nil {
+		return err
+	}
+	for _, addrHex := range proofs.Addresses() {
+		if err := pw.WriteProof(addrHex, proofs.Proofs[addrHex]); err != nil {
+			return err
+		}
+	}
+	return pw.Close()
+}
+
+// BinaryProofWriter writes a binary proofs file one entry at a time, so
+// proofs can be spilled to disk as they are generated instead of held in
+// a ProofSet. Its WriteProof is a sink for GenerateAllProofsTo.
+type BinaryProofWriter struct {
+	bw      *bufio.Writer
+	root    []byte
+	count   int // Entries the header announces
+	written int
+	entry   []byte
+}
+
+// NewBinaryProofWriter writes the header of a binary proofs file of count
+// entries under root to w
+func NewBinaryProofWriter(w io.Writer, root []byte, metadata merkle.TreeMetadata, count int) (*BinaryProofWriter, error) {
+	if len(root) != 32 {
+		return nil, fmt.Errorf("invalid root length: %d", len(root))
+	}
+	if count < 0 || uint64(count) > math.MaxUint32 {
+		return nil, fmt.Errorf("invalid entry count: %d", count)
+	}
+
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	pw := &BinaryProofWriter{bw: bufio.NewWriter(w), root: root, count: count, entry: make([]byte, 0, 128)}
+	header := make([]byte, 0, len(binaryProofsMagic)+1+32+binary.MaxVarintLen64+len(encoded)+4)
+	header = append(header, binaryProofsMagic...)
+	header = append(header, binaryProofsVersion)
+	header = append(header, root...)
+	header = binary.AppendUvarint(header, uint64(len(encoded)))
+	header = append(header, encoded...)
+	header = binary.BigEndian.AppendUint32(header, uint32(count))
+	if _, err := pw.bw.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	return pw, nil
+}
+
+// WriteProof writes the entry of address, which must record the writer's
+// root if it records one
+func (pw *BinaryProofWriter) WriteProof(addrHex string, proof *merkle.MerkleProof) error {
+	if pw.written == pw.count {
+		return fmt.Errorf("more than the %d entries of the header", pw.count)
+	}
+	if !common.IsHexAddress(addrHex) {
+		return fmt.Errorf("invalid address: %s", addrHex)
+	}
+	if err := merkle.CheckProofRoot(pw.root, proof.Root); err != nil {
+		return fmt.Errorf("%s: %w", addrHex, err)
+	}
+
+	var err error
+	pw.entry = append(pw.entry[:0], common.HexToAddress(addrHex).Bytes()...)
+	if pw.entry, err = merkle.AppendProofBinary(pw.entry, proof); err != nil {
+		return fmt.Errorf("%s: %w", addrHex, err)
+	}
+	if _, err := pw.bw.Write(pw.entry); err != nil {
+		return fmt.Errorf("failed to write entry: %w", err)
+	}
+	pw.written++
+	return nil
+}
+
+// Close flushes the file, failing if fewer entries were written than the
+// header announces. It does not close the underlying writer.
+func (pw *BinaryProofWriter) Close() error {
+	if pw.written != pw.count {
+		return fmt.Errorf("wrote %d of the header's %d entries", pw.written, pw.count)
+	}
+	return pw.bw.Flush()
+}
+
+// ExportTreeProofsBinary generates the proof of every address of tree
+// straight into a binary proofs file written to w, in leaf order, holding
+// no more proofs in memory than the tree's ProofBuffer
+func ExportTreeProofsBinary(tree *merkle.MerkleTree, w io.Writer) error {
+	pw, err := NewBinaryProofWriter(w, tree.Root.Hash, tree.Metadata(), tree.AddressCount())
+	if err != nil {
+		return err
+	}
+	if err := tree.GenerateAllProofsTo(pw.WriteProof); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// SaveTreeProofsBinaryFile is SaveProofsBinaryFile generating the tree's
+// proofs as it writes them, with ExportTreeProofsBinary
+func SaveTreeProofsBinaryFile(tree *merkle.MerkleTree, filename string, compress bool) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		if !compress {
+			return ExportTreeProofsBinary(tree, w)
+		}
+
+		gz := gzip.NewWriter(w)
+		if err := ExportTreeProofsBinary(tree, gz); err != nil {
+			return err
+		}
+		return gz.Close()
+	})
+}
+
+// LoadProofsBinary reads proofs written by ExportProofsBinary, gzip or not.
+// Every proof records the file's root.
+func LoadProofsBinary(r io.Reader) (*merkle.ProofSet, []byte, error) {
+	br := bufio.NewReader(r)
+
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	header := make([]byte, len(binaryProofsMagic)+1+32)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(header[:4]) != binaryProofsMagic {
+		return nil, nil, fmt.Errorf("not a binary proofs file")
+	}
+	version := header[4]
+	if version < 1 || version > binaryProofsVersion {
+		return nil, nil, fmt.Errorf("unsupported binary proofs version: %d", version)
+	}
+	root := append([]byte(nil), header[5:37]...)
+
+	metadata := merkle.DefaultMetadata()
+	if version >= 2 {
+		metaLen, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read metadata length: %w", err)
+		}
+		if metaLen > 1<<16 {
+			return nil, nil, fmt.Errorf("metadata too long (%d bytes)", metaLen)
+		}
+		metaBytes := make([]byte, metaLen)
+		if _, err := io.ReadFull(br, metaBytes); err != nil {
+			return nil, nil, fmt.Errorf("failed to read metadata: %w", err)
+		}
+		if err := json.Unmarshal(metaBytes, &metadata); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode metadata: %w", err)
+		}
+		if err := metadata.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("invalid metadata: %w", err)
+		}
+	}
+
+	countBytes := make([]byte, 4)
+	if _, err := io.ReadFull(br, countBytes); err != nil {
+		return nil, nil, fmt.Errorf("failed to read entry count: %w", err)
+	}
+	count := binary.BigEndian.Uint32(countBytes)
+
+	proofs := make(map[string]*merkle.MerkleProof, count)
+	rootHex := merkle.EncodeHash(root) // Recorded by every proof
+	addr := make([]byte, common.AddressLength)
+	fixed := make([]byte, 4)
+	hash := make([]byte, 32)
+
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(br, addr); err != nil {
+			return nil, nil, fmt.Errorf("entry %d: failed to read address: %w", i, err)
+		}
+
+		amountLen, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: failed to read amount length: %w", i, err)
+		}
+		if amountLen > 32 {
+			return nil, nil, fmt.Errorf("entry %d: amount too long (%d bytes)", i, amountLen)
+		}
+		amountBytes := make([]byte, amountLen)
+		if _, err := io.ReadFull(br, amountBytes); err != nil {
+			return nil, nil, fmt.Errorf("entry %d: failed to read amount: %w", i, err)
+		}
+
+		if _, err := io.ReadFull(br, fixed); err != nil {
+			return nil, nil, fmt.Errorf("entry %d: failed to read index: %w", i, err)
+		}
+		index := binary.BigEndian.Uint32(fixed)
+
+		proofLen, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("entry %d: failed to read proof length: %w", i, err)
+		}
+		if proofLen > 256 {
+			return nil, nil, fmt.Errorf("entry %d: proof too long (%d hashes)", i, proofLen)
+		}
+		proof := make([]string, proofLen)
+		for j := range proof {
+			if _, err := io.ReadFull(br, hash); err != nil {
+				return nil, nil, fmt.Errorf("entry %d: failed to read proof hash: %w", i, err)
+			}
+			proof[j] = merkle.EncodeHash(hash)
+		}
+
+		var positions uint64
+		if version >= 3 {
+			if positions, err = binary.ReadUvarint(br); err != nil {
+				return nil, nil, fmt.Errorf("entry %d: failed to read positions: %w", i, err)
+			}
+		}
+
+		var padding uint64
+		if version >= 4 {
+			if padding, err = binary.ReadUvarint(br); err != nil {
+				return nil, nil, fmt.Errorf("entry %d: failed to read padding count: %w", i, err)
+			}
+			if padding > proofLen {
+				return nil, nil, fmt.Errorf("entry %d: padding count %d exceeds proof length %d", i, padding, proofLen)
+			}
+		}
+
+		proofs[common.BytesToAddress(addr).Hex()] = &merkle.MerkleProof{
+			Proof:     proof,
+			Index:     index,
+			Amount:    new(big.Int).SetBytes(amountBytes).String(),
+			Positions: positions,
+
+			PaddingCount: int(padding),
+			Root:         rootHex,
+		}
+	}
+
+	return &merkle.ProofSet{Proofs: proofs, Metadata: metadata}, root, nil
+}
+
+// SaveProofsBinaryFile writes proofs to filename, optionally gzip-compressed
+func SaveProofsBinaryFile(proofs *merkle.ProofSet, root []byte, filename string, compress bool) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		if !compress {
+			return ExportProofsBinary(proofs, root, w)
+		}
+
+		gz := gzip.NewWriter(w)
+		if err := ExportProofsBinary(proofs, root, gz); err != nil {
+			return err
+		}
+		return gz.Close()
+	})
+}
+
+// LoadProofsFile loads a proofs file written by the CLI, choosing the
+// binary or JSON decoder from the file contents. A directory is loaded as a
+// sharded export.
+func LoadProofsFile(filename string) (string, *merkle.ProofSet, error) {
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		return LoadShardedProofs(filename)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read proofs file: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return LoadProofsJSON(bytes.NewReader(content))
+	}
+
+	proofs, root, err := LoadProofsBinary(bytes.NewReader(content))
+	if err != nil {
+		return "", nil, err
+	}
+	return merkle.EncodeHash(root), proofs, nil
+}
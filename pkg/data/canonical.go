@@ -0,0 +1,103 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CanonicalMetadataFile is the sidecar the CLI writes next to a canonical
+// export, holding the timings and timestamps left out of it
+const CanonicalMetadataFile = "metadata.json"
+
+// canonicalFile is the layout of a canonical export. Fields are encoded in
+// declaration order and encoding/json sorts map keys, so the proofs come
+// out ordered by address.
+type canonicalFile struct {
+	MerkleRoot  string                    `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata       `json:"metadata"`
+	ProofFields *ProofMarshaller          `json:"proofFields,omitempty"`
+	TotalClaims int                       `json:"totalClaims"`
+	IndexBitmap string                    `json:"indexBitmap"`
+	Proofs      map[string]json.Marshaler `json:"proofs"`
+}
+
+// ExportProofsCanonical writes proofs as JSON that depends only on the
+// proofs and root: no timestamps or timings, proofs keyed by address in
+// addressCase in lexicographic order, hashes as lowercase 0x-prefixed hex,
+// amounts as plain decimals and two-space indentation, and proof fields
+// named by fields. Identical inputs give byte-identical output, readable by
+// LoadProofsJSON.
+func ExportProofsCanonical(proofs *merkle.ProofSet, root string, w io.Writer, addressCase AddressCase, fields ProofMarshaller) error {
+	canonicalRoot, err := canonicalHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+	rootBytes, _ := merkle.DecodeHash(canonicalRoot) // Checked by canonicalHash
+
+	file := canonicalFile{
+		MerkleRoot:  canonicalRoot,
+		Metadata:    proofs.Metadata,
+		ProofFields: fields.fileFields(),
+		TotalClaims: proofs.Len(),
+	}
+	canonical := make(map[string]*merkle.MerkleProof, len(proofs.Proofs))
+	indices := make([]uint32, 0, len(proofs.Proofs))
+	for address, proof := range proofs.Proofs {
+		if !common.IsHexAddress(address) {
+			return fmt.Errorf("invalid address: %s", address)
+		}
+		amount, err := parseAmount(int(proof.Index), proof.Amount)
+		if err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+
+		elements := make([]string, len(proof.Proof))
+		for i, element := range proof.Proof {
+			if elements[i], err = canonicalHash(element); err != nil {
+				return fmt.Errorf("invalid proof element %d for %s: %w", i, address, err)
+			}
+		}
+
+		if err := merkle.CheckProofRoot(rootBytes, proof.Root); err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+
+		key := addressCase.Format(common.HexToAddress(address))
+		if _, exists := canonical[key]; exists {
+			return &DuplicateAddressError{Index: len(indices), Address: common.HexToAddress(address)}
+		}
+		canonical[key] = &merkle.MerkleProof{
+			Proof:     elements,
+			Index:     proof.Index,
+			Amount:    amount.String(),
+			Positions: proof.Positions,
+
+			PaddingCount: proof.PaddingCount,
+			Root:         canonicalRoot,
+		}
+		indices = append(indices, proof.Index)
+	}
+	file.Proofs = fields.Proofs(canonical)
+	file.IndexBitmap = merkle.EncodeIndexBitmap(merkle.IndexBitmapOf(indices))
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode proofs: %w", err)
+	}
+	return nil
+}
+
+// canonicalHash formats a 32-byte hash as lowercase 0x-prefixed hex
+func canonicalHash(h string) (string, error) {
+	decoded, err := merkle.DecodeHash(h)
+	if err != nil {
+		return "", err
+	}
+	return merkle.EncodeHash(decoded), nil
+}
@@ -0,0 +1,204 @@
+// pkg/data/custodian.go
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// AggregateByCustodian replaces the claims of users held at a custodian,
+// such as an exchange, with one claim per custodian wallet for the sum of
+// their amounts. custodians maps user addresses to the custodian's wallet;
+// claims of unmapped addresses pass through as they are, and a custodian
+// listing its own address keeps that claim as part of its sum.
+//
+// The second result lists each custodian's users with their own amounts,
+// so the custodian can credit them after claiming; it always adds up to
+// the custodian's aggregated amount. Claims keep the position of their
+// first contributing claim and are indexed from zero.
+func AggregateByCustodian(claims []merkle.AirdropClaim, custodians map[common.Address]common.Address) ([]merkle.AirdropClaim, map[common.Address][]merkle.AirdropClaim, error) {
+	wallets := make(map[common.Address]bool)
+	for user, custodian := range custodians {
+		wallets[custodian] = true
+		if user == custodian {
+			return nil, nil, fmt.Errorf("%s is mapped to itself", user.Hex())
+		}
+		if parent, ok := custodians[custodian]; ok {
+			return nil, nil, fmt.Errorf("custodian %s of %s is itself mapped to %s", custodian.Hex(), user.Hex(), parent.Hex())
+		}
+	}
+
+	positions := make(map[common.Address]int) // Aggregated position of each claim address
+	seen := make(map[common.Address]bool, len(claims))
+	users := make(map[common.Address][]merkle.AirdropClaim)
+	var aggregated []merkle.AirdropClaim
+	for i, claim := range claims {
+		if seen[claim.Address] {
+			return nil, nil, &DuplicateAddressError{Index: i, Address: claim.Address}
+		}
+		seen[claim.Address] = true
+
+		holder, held := custodians[claim.Address]
+		if !held {
+			if !wallets[claim.Address] {
+				claim.Index = uint32(len(aggregated))
+				aggregated = append(aggregated, claim)
+				continue
+			}
+			holder = claim.Address // The custodian's own claim
+		}
+
+		users[holder] = append(users[holder], claim)
+		if p, ok := positions[holder]; ok {
+			aggregated[p].Amount.Add(aggregated[p].Amount, claim.Amount)
+			continue
+		}
+		positions[holder] = len(aggregated)
+		aggregated = append(aggregated, merkle.AirdropClaim{
+			Address: holder,
+			Amount:  new(big.Int).Set(claim.Amount), // Don't add into the caller's amount
+			Index:   uint32(len(aggregated)),
+		})
+	}
+
+	if err := CheckCustodianAllocations(aggregated, users); err != nil {
+		return nil, nil, err
+	}
+	return aggregated, users, nil
+}
+
+// CheckCustodianAllocations checks that every custodian in users has
+// exactly one claim in claims, for exactly the sum of its users' amounts
+func CheckCustodianAllocations(claims []merkle.AirdropClaim, users map[common.Address][]merkle.AirdropClaim) error {
+	amounts := make(map[common.Address]*big.Int, len(users))
+	for _, claim := range claims {
+		if _, ok := users[claim.Address]; !ok {
+			continue
+		}
+		if _, repeated := amounts[claim.Address]; repeated {
+			return fmt.Errorf("custodian %s has more than one claim", claim.Address.Hex())
+		}
+		amounts[claim.Address] = claim.Amount
+	}
+	for custodian, allocations := range users {
+		amount, ok := amounts[custodian]
+		if !ok {
+			return fmt.Errorf("custodian %s has no claim", custodian.Hex())
+		}
+		if sum := merkle.TotalAmount(allocations); sum.Cmp(amount) != 0 {
+			return fmt.Errorf("custodian %s: users add up to %s, not the claimed %s", custodian.Hex(), sum, amount)
+		}
+	}
+	return nil
+}
+
+// CustodianUser is one user's share of a custodian's claim
+type CustodianUser struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// custodianFile is the layout of ExportCustodianAllocation
+type custodianFile struct {
+	MerkleRoot  string              `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata `json:"metadata"`
+	Custodian   string              `json:"custodian"`
+	Claim       *merkle.MerkleProof `json:"claim"`
+	Users       []CustodianUser     `json:"users"`
+	TotalUsers  int                 `json:"totalUsers"`
+	GeneratedAt int64               `json:"generatedAt"`
+}
+
+// ExportCustodianAllocation writes what a custodian needs to claim for its
+// users and credit them: its proof from proofs and the users' breakdown
+// from AggregateByCustodian, in claim order. It fails if the users don't
+// add up to exactly the proof's amount.
+func ExportCustodianAllocation(proofs *merkle.ProofSet, root string, custodian common.Address, users []merkle.AirdropClaim, w io.Writer) error {
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+	proof, ok := proofsByAddress(proofs)[custodian]
+	if !ok {
+		return fmt.Errorf("custodian %s has no proof", custodian.Hex())
+	}
+	if proof, err = withRoot(proof, rootBytes); err != nil {
+		return fmt.Errorf("custodian %s: %w", custodian.Hex(), err)
+	}
+	if sum := merkle.TotalAmount(users); sum.String() != proof.Amount {
+		return fmt.Errorf("custodian %s: users add up to %s, not the claimed %s", custodian.Hex(), sum, proof.Amount)
+	}
+
+	file := custodianFile{
+		MerkleRoot:  root,
+		Metadata:    proofs.Metadata,
+		Custodian:   custodian.Hex(),
+		Claim:       proof,
+		Users:       make([]CustodianUser, len(users)),
+		TotalUsers:  len(users),
+		GeneratedAt: time.Now().Unix(),
+	}
+	for i, user := range users {
+		file.Users[i] = CustodianUser{Address: user.Address.Hex(), Amount: user.Amount.String()}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode allocation: %w", err)
+	}
+	return nil
+}
+
+// LoadCustodiansFromCSV loads a user,custodian CSV mapping user addresses
+// to the custodian wallets holding their claims. A user may appear only
+// once.
+func LoadCustodiansFromCSV(filename string) (map[common.Address]common.Address, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	custodians := make(map[common.Address]common.Address)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		for _, field := range record {
+			if !common.IsHexAddress(field) {
+				return nil, &RowError{Row: line, Err: fmt.Errorf("invalid address: %s", field)}
+			}
+		}
+		user := common.HexToAddress(record[0])
+		if _, exists := custodians[user]; exists {
+			return nil, &RowError{Row: line, Err: &DuplicateAddressError{Index: len(custodians), Address: user}}
+		}
+		custodians[user] = common.HexToAddress(record[1])
+	}
+
+	return custodians, nil
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// LoadAirdropFromDB loads airdrop data by running query on db.
+// The query must return (address text, amount text) rows; claims are
+// indexed in result order.
+func LoadAirdropFromDB(ctx context.Context, db *sql.DB, query string) ([]merkle.AirdropClaim, error) {
+	return LoadAirdropFromDBWithLimit(ctx, db, query, 0)
+}
+
+// LoadAirdropFromDBWithLimit is LoadAirdropFromDB stopping at the first row
+// over maxClaims claims, which is unlimited when zero. The rest of the
+// result is not read.
+func LoadAirdropFromDBWithLimit(ctx context.Context, db *sql.DB, query string, maxClaims int) ([]merkle.AirdropClaim, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	if len(columns) != 2 {
+		return nil, fmt.Errorf("query must return 2 columns (address, amount), got %d", len(columns))
+	}
+
+	var claims []merkle.AirdropClaim
+
+	row := 0
+	for rows.Next() {
+		row++
+		if err := checkClaimLimit(len(claims), maxClaims, row); err != nil {
+			return nil, err
+		}
+
+		var addressField, amountField sql.NullString
+		if err := rows.Scan(&addressField, &amountField); err != nil {
+			return nil, &RowError{Row: row, Query: true, Err: fmt.Errorf("failed to scan: %w", err)}
+		}
+		if !addressField.Valid || !amountField.Valid {
+			return nil, &RowError{Row: row, Query: true, Err: fmt.Errorf("address and amount must not be NULL")}
+		}
+
+		address, amount, err := parseClaimFields(len(claims), addressField.String, amountField.String)
+		if err != nil {
+			return nil, &RowError{Row: row, Query: true, Err: err}
+		}
+
+		claims = append(claims, merkle.AirdropClaim{
+			Address: address,
+			Amount:  amount,
+			Index:   uint32(row - 1),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// deltaSampleSize is how many carried-over and how many delta proofs
+// ApplyProofsDelta checks against the new root
+const deltaSampleSize = 128
+
+// deltaFile is the layout of a proofs delta: the new root and encoding,
+// the proofs that are new or differ from the base, and the addresses the
+// base has that the new tree doesn't
+type deltaFile struct {
+	BaseRoot    string                         `json:"baseRoot"`
+	MerkleRoot  string                         `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata            `json:"metadata"`
+	Proofs      map[string]*merkle.MerkleProof `json:"proofs"`
+	Removed     []string                       `json:"removed"`
+	TotalClaims int                            `json:"totalClaims"`
+}
+
+// ExportProofsDelta writes the changes from oldProofs, under oldRoot, to
+// newProofs, under newRoot: the added and changed proofs in full and the
+// removed addresses. ApplyProofsDelta turns oldProofs and the delta back
+// into newProofs. Every proof holds a sibling from the path of any changed
+// leaf, so a delta is only smaller than the full set when proofs are
+// unchanged, not merely their claims.
+func ExportProofsDelta(oldProofs, newProofs *merkle.ProofSet, oldRoot, newRoot string, w io.Writer) error {
+	if _, err := merkle.DecodeHash(oldRoot); err != nil {
+		return fmt.Errorf("invalid base root: %w", err)
+	}
+	root, err := merkle.DecodeHash(newRoot)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+
+	old := proofsByAddress(oldProofs)
+	current := proofsByAddress(newProofs)
+	file := deltaFile{
+		BaseRoot:    oldRoot,
+		MerkleRoot:  newRoot,
+		Metadata:    newProofs.Metadata,
+		Proofs:      make(map[string]*merkle.MerkleProof),
+		Removed:     []string{},
+		TotalClaims: len(current),
+	}
+	for address, proof := range current {
+		if base, ok := old[address]; !ok || !sameProof(base, proof) {
+			if file.Proofs[address.Hex()], err = withRoot(proof, root); err != nil {
+				return fmt.Errorf("%s: %w", address.Hex(), err)
+			}
+		}
+	}
+	for address := range old {
+		if _, ok := current[address]; !ok {
+			file.Removed = append(file.Removed, address.Hex())
+		}
+	}
+	sort.Strings(file.Removed)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode delta: %w", err)
+	}
+	return nil
+}
+
+// ApplyProofsDelta applies a delta from ExportProofsDelta to base, the proof
+// set it was made against, returning the new proof set and root. A sample
+// of the carried-over and the delta's proofs is verified against the new
+// root, so a delta applied to the wrong base is an error.
+func ApplyProofsDelta(base *merkle.ProofSet, delta io.Reader) (*merkle.ProofSet, string, error) {
+	var file deltaFile
+	if err := json.NewDecoder(delta).Decode(&file); err != nil {
+		return nil, "", fmt.Errorf("failed to decode delta: %w", err)
+	}
+	root, err := merkle.DecodeHash(file.MerkleRoot)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid delta root: %w", err)
+	}
+
+	proofs := make(map[string]*merkle.MerkleProof, file.TotalClaims)
+	for address, proof := range proofsByAddress(base) {
+		// Carried-over proofs are checked against the new root below
+		carried := *proof
+		carried.Root = file.MerkleRoot
+		proofs[address.Hex()] = &carried
+	}
+	for _, address := range file.Removed {
+		if !common.IsHexAddress(address) {
+			return nil, "", fmt.Errorf("invalid removed address: %s", address)
+		}
+		key := common.HexToAddress(address).Hex()
+		if _, ok := proofs[key]; !ok {
+			return nil, "", fmt.Errorf("removed address %s is not in the base proofs", key)
+		}
+		delete(proofs, key)
+	}
+	var carried []string
+	for key := range proofs {
+		carried = append(carried, key)
+	}
+	var changed []string
+	for address, proof := range file.Proofs {
+		if !common.IsHexAddress(address) {
+			return nil, "", fmt.Errorf("invalid address: %s", address)
+		}
+		key := common.HexToAddress(address).Hex()
+		proofs[key] = proof
+		changed = append(changed, key)
+	}
+	if len(proofs) != file.TotalClaims {
+		return nil, "", fmt.Errorf("delta gives %d proofs, expected %d; is base the proof set of %s?", len(proofs), file.TotalClaims, file.BaseRoot)
+	}
+
+	opts := file.Metadata.Options()
+	for _, key := range append(sample(carried, deltaSampleSize), sample(changed, deltaSampleSize)...) {
+		if err := verifyStoredProof(root, key, proofs[key], opts); err != nil {
+			return nil, "", fmt.Errorf("%w; is base the proof set of %s?", err, file.BaseRoot)
+		}
+	}
+
+	return &merkle.ProofSet{Proofs: proofs, Metadata: file.Metadata}, file.MerkleRoot, nil
+}
+
+// sameProof reports whether two proofs are identical but for their roots
+func sameProof(a, b *merkle.MerkleProof) bool {
+	return a.Index == b.Index && a.Amount == b.Amount && a.Positions == b.Positions &&
+		a.PaddingCount == b.PaddingCount && slices.Equal(a.Proof, b.Proof)
+}
+
+// sample returns up to n of keys, spread evenly over their sorted order
+func sample(keys []string, n int) []string {
+	sort.Strings(keys)
+	if len(keys) <= n {
+		return keys
+	}
+	picked := make([]string, n)
+	for i := range picked {
+		picked[i] = keys[i*len(keys)/n]
+	}
+	return picked
+}
+
+// verifyStoredProof checks a stored proof for address against root
+func verifyStoredProof(root []byte, address string, proof *merkle.MerkleProof, opts merkle.TreeOptions) error {
+	amount, err := parseAmount(int(proof.Index), proof.Amount)
+	if err != nil {
+		return fmt.Errorf("%s: %w", address, err)
+	}
+	claim := merkle.AirdropClaim{Address: common.HexToAddress(address), Amount: amount, Index: proof.Index}
+	valid, err := merkle.VerifyMerkleProof(root, claim, proof, opts)
+	if err != nil {
+		return fmt.Errorf("proof for %s: %w", address, err)
+	}
+	if !valid {
+		return fmt.Errorf("proof for %s does not verify against the delta root 0x%x", address, root)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// pkg/data/errors.go
+package data
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RowError locates an error in claims input: the 1-based line of a file,
+// or the row of a query result
+type RowError struct {
+	Row   int
+	Query bool // Row counts query results rather than file lines
+	Err   error
+}
+
+func (e *RowError) Error() string {
+	if e.Query {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+	return fmt.Sprintf("line %d: %v", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error { return e.Err }
+
+// DuplicateAddressError reports an address listed more than once where
+// claims must be unique
+type DuplicateAddressError struct {
+	Index   int // Position of the repeat among the claims
+	Address common.Address
+}
+
+func (e *DuplicateAddressError) Error() string {
+	return fmt.Sprintf("duplicate address at index %d: %s", e.Index, e.Address.Hex())
+}
@@ -0,0 +1,258 @@
+package data
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// The JSON names of a merkle.MerkleProof's fields that a ProofMarshaller
+// can rename or omit
+const (
+	ProofFieldProof  = "proof"
+	ProofFieldIndex  = "index"
+	ProofFieldAmount = "amount"
+)
+
+// ProofMarshaller encodes proofs as JSON objects with the field names a
+// frontend expects, e.g. {"merkleProof": [...], "value": "..."}. The zero
+// value encodes proofs exactly as merkle.MerkleProof does; fields it leaves
+// out decode as their zero value.
+type ProofMarshaller struct {
+	Proof  string `json:"proof,omitempty"`  // Name of the proof field, "proof" when empty
+	Index  string `json:"index,omitempty"`  // Name of the index field, "index" when empty
+	Amount string `json:"amount,omitempty"` // Name of the amount field, "amount" when empty
+
+	OmitIndex  bool `json:"omitIndex,omitempty"`
+	OmitAmount bool `json:"omitAmount,omitempty"`
+}
+
+// ParseProofMarshaller parses field settings such as
+// "proof=merkleProof,amount=value,-index": name=alias renames a field and
+// -name leaves it out. An empty spec is the default encoding.
+func ParseProofMarshaller(spec string) (ProofMarshaller, error) {
+	var m ProofMarshaller
+	if strings.TrimSpace(spec) == "" {
+		return m, nil
+	}
+	for _, setting := range strings.Split(spec, ",") {
+		setting = strings.TrimSpace(setting)
+		if field, ok := strings.CutPrefix(setting, "-"); ok {
+			switch field {
+			case ProofFieldIndex:
+				m.OmitIndex = true
+			case ProofFieldAmount:
+				m.OmitAmount = true
+			default:
+				return m, fmt.Errorf("cannot omit proof field %q (expected index or amount)", field)
+			}
+			continue
+		}
+
+		field, name, ok := strings.Cut(setting, "=")
+		if !ok || name == "" {
+			return m, fmt.Errorf("invalid proof field setting %q (expected field=name or -field)", setting)
+		}
+		switch field {
+		case ProofFieldProof:
+			m.Proof = name
+		case ProofFieldIndex:
+			m.Index = name
+		case ProofFieldAmount:
+			m.Amount = name
+		default:
+			return m, fmt.Errorf("unknown proof field %q (expected proof, index or amount)", field)
+		}
+	}
+	return m, m.Validate()
+}
+
+// String returns the spec ParseProofMarshaller parses into m
+func (m ProofMarshaller) String() string {
+	var settings []string
+	for _, field := range m.fields() {
+		switch {
+		case field.omit:
+			settings = append(settings, "-"+field.name)
+		case field.alias != field.name:
+			settings = append(settings, field.name+"="+field.alias)
+		}
+	}
+	return strings.Join(settings, ",")
+}
+
+// IsDefault reports whether m encodes proofs as merkle.MerkleProof does
+func (m ProofMarshaller) IsDefault() bool {
+	for _, field := range m.fields() {
+		if field.omit || field.alias != field.name {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that no two fields share a name, and that none takes one
+// of reserved, the other keys of the objects m is used on
+func (m ProofMarshaller) Validate(reserved ...string) error {
+	taken := map[string]string{"positions": "positions", "paddingCount": "paddingCount", "root": "root"}
+	for _, name := range reserved {
+		taken[name] = name
+	}
+	for _, field := range m.fields() {
+		if field.omit {
+			continue
+		}
+		if other, clash := taken[field.alias]; clash {
+			return fmt.Errorf("proof field %s cannot be named %q, which %s uses", field.name, field.alias, other)
+		}
+		taken[field.alias] = field.name
+	}
+	return nil
+}
+
+// proofField is one renameable field of a ProofMarshaller
+type proofField struct {
+	name  string // Default name
+	alias string // Name m encodes it as
+	omit  bool
+}
+
+func (m ProofMarshaller) fields() []proofField {
+	alias := func(name, override string) string {
+		if override == "" {
+			return name
+		}
+		return override
+	}
+	return []proofField{
+		{ProofFieldProof, alias(ProofFieldProof, m.Proof), false},
+		{ProofFieldIndex, alias(ProofFieldIndex, m.Index), m.OmitIndex},
+		{ProofFieldAmount, alias(ProofFieldAmount, m.Amount), m.OmitAmount},
+	}
+}
+
+// Marshal encodes proof with m's field names
+func (m ProofMarshaller) Marshal(proof *merkle.MerkleProof) ([]byte, error) {
+	return m.Rename(proof)
+}
+
+// Unmarshal decodes a proof encoded with m's field names
+func (m ProofMarshaller) Unmarshal(data []byte) (*merkle.MerkleProof, error) {
+	var proof merkle.MerkleProof
+	if m.IsDefault() {
+		if err := json.Unmarshal(data, &proof); err != nil {
+			return nil, err
+		}
+		return &proof, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+
+	// Build the object anew, as one field may take another's default name
+	renamedKeys := make(map[string]bool)
+	for _, field := range m.fields() {
+		renamedKeys[field.name] = true
+		renamedKeys[field.alias] = true
+	}
+	renamedObject := make(map[string]json.RawMessage, len(object))
+	for key, value := range object {
+		if !renamedKeys[key] {
+			renamedObject[key] = value
+		}
+	}
+	for _, field := range m.fields() {
+		if value, ok := object[field.alias]; ok && !field.omit {
+			renamedObject[field.name] = value
+		}
+	}
+	renamed, err := json.Marshal(renamedObject)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(renamed, &proof); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// Rename encodes v, which must encode as a JSON object with the default
+// proof field names, then renames and drops its top-level keys as m says.
+// The order of the keys is kept.
+func (m ProofMarshaller) Rename(v interface{}) (json.RawMessage, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil || m.IsDefault() {
+		return encoded, err
+	}
+
+	fields := make(map[string]proofField)
+	for _, field := range m.fields() {
+		fields[field.name] = field
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		if field, ok := fields[key]; ok {
+			if field.omit {
+				continue
+			}
+			key = field.alias
+		}
+		if out.Len() > 1 {
+			out.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+// Proofs wraps proofs so they encode with m's field names
+func (m ProofMarshaller) Proofs(proofs map[string]*merkle.MerkleProof) map[string]json.Marshaler {
+	wrapped := make(map[string]json.Marshaler, len(proofs))
+	for address, proof := range proofs {
+		wrapped[address] = marshalledProof{proof, m}
+	}
+	return wrapped
+}
+
+// fileFields is the proofFields recorded in files m writes, nil for the
+// default so those files are unchanged
+func (m ProofMarshaller) fileFields() *ProofMarshaller {
+	if m.IsDefault() {
+		return nil
+	}
+	return &m
+}
+
+// marshalledProof is a proof that encodes with a ProofMarshaller
+type marshalledProof struct {
+	proof *merkle.MerkleProof
+	m     ProofMarshaller
+}
+
+func (p marshalledProof) MarshalJSON() ([]byte, error) {
+	return p.m.Marshal(p.proof)
+}
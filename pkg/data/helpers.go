@@ -4,45 +4,65 @@ package data
 import (
 	"encoding/csv"
 	"fmt"
-	"os"
+	"io"
+	"math/big"
 
+	"merkle-airdrop/internal/fsutil"
 	"merkle-airdrop/pkg/merkle"
 )
 
-// SaveClaimsToCSV saves airdrop claims to a CSV file
-func SaveClaimsToCSV(claims []merkle.AirdropClaim, filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+// SaveClaimsToCSV saves airdrop claims to a CSV file with addresses written
+// in addressCase
+func SaveClaimsToCSV(claims []merkle.AirdropClaim, filename string, addressCase AddressCase) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		writer := csv.NewWriter(w)
 
-	// Write header
-	if err := writer.Write([]string{"address", "amount"}); err != nil {
-		return fmt.Errorf("failed to write header: %w", err)
-	}
-
-	// Write claims
-	for _, claim := range claims {
-		record := []string{
-			claim.Address.Hex(),
-			claim.Amount.String(),
+		// Write header
+		if err := writer.Write([]string{"address", "amount"}); err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
 		}
-		if err := writer.Write(record); err != nil {
-			return fmt.Errorf("failed to write record: %w", err)
+
+		// Write claims
+		for _, claim := range claims {
+			record := []string{
+				addressCase.Format(claim.Address),
+				claim.Amount.String(),
+			}
+			if err := writer.Write(record); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
 		}
-	}
 
-	return nil
+		writer.Flush()
+		return writer.Error()
+	})
+}
+
+// withRoot returns proof with its Root set to root, that of the export it
+// is written to: proof itself when it records root, a copy when it records
+// no root. A proof of another root is a merkle.RootMismatchError.
+func withRoot(proof *merkle.MerkleProof, root []byte) (*merkle.MerkleProof, error) {
+	if err := merkle.CheckProofRoot(root, proof.Root); err != nil {
+		return nil, err
+	}
+	if proof.Root != "" {
+		return proof, nil
+	}
+	stamped := *proof
+	stamped.Root = merkle.EncodeHash(root)
+	return &stamped, nil
 }
 
 // ValidateClaimsData validates airdrop claims data
 func ValidateClaimsData(claims []merkle.AirdropClaim) error {
+	return ValidateClaimsDataWithOptions(claims, merkle.DefaultTreeOptions())
+}
+
+// ValidateClaimsDataWithOptions validates claims data for a tree built with
+// opts, whose MaxAmountBits bounds the amounts
+func ValidateClaimsDataWithOptions(claims []merkle.AirdropClaim, opts merkle.TreeOptions) error {
 	if len(claims) == 0 {
-		return fmt.Errorf("no claims provided")
+		return merkle.ErrEmptyClaims
 	}
 
 	addressMap := make(map[string]bool)
@@ -50,13 +70,16 @@ func ValidateClaimsData(claims []merkle.AirdropClaim) error {
 		// Check for duplicate addresses
 		addrHex := claim.Address.Hex()
 		if addressMap[addrHex] {
-			return fmt.Errorf("duplicate address at index %d: %s", i, addrHex)
+			return &DuplicateAddressError{Index: i, Address: claim.Address}
 		}
 		addressMap[addrHex] = true
 
-		// Check for zero amounts
-		if claim.Amount.Sign() <= 0 {
-			return fmt.Errorf("invalid amount at index %d: %s", i, claim.Amount.String())
+		// Check for zero amounts; hashing accepts them but they waste a claim
+		if err := merkle.CheckAmount(i, claim.Amount); err != nil {
+			return fmt.Errorf("claim %d (%s): %w", i, addrHex, err)
+		}
+		if claim.Amount.Sign() == 0 {
+			return fmt.Errorf("claim %d (%s): %w", i, addrHex, &merkle.InvalidAmountError{Index: i, Value: "0", Reason: "must be positive"})
 		}
 
 		// Check for zero address
@@ -65,7 +88,7 @@ func ValidateClaimsData(claims []merkle.AirdropClaim) error {
 		}
 	}
 
-	return nil
+	return merkle.CheckAmountBits(claims, opts.MaxAmountBits)
 }
 
 // FilterClaims filters claims based on various criteria
@@ -133,6 +156,66 @@ func DeduplicateClaims(claims []merkle.AirdropClaim) []merkle.AirdropClaim {
 	return deduplicated
 }
 
+// AggregateClaims merges claims for the same address by summing their
+// amounts. Each address keeps the position and index of its first claim.
+func AggregateClaims(claims []merkle.AirdropClaim) []merkle.AirdropClaim {
+	positions := make(map[string]int)
+	var aggregated []merkle.AirdropClaim
+
+	for _, claim := range claims {
+		addrHex := claim.Address.Hex()
+		if i, seen := positions[addrHex]; seen {
+			aggregated[i].Amount.Add(aggregated[i].Amount, claim.Amount)
+			continue
+		}
+
+		positions[addrHex] = len(aggregated)
+		claim.Amount = new(big.Int).Set(claim.Amount) // Don't add into the caller's amount
+		aggregated = append(aggregated, claim)
+	}
+
+	return aggregated
+}
+
+// DuplicatePolicy selects how addresses listed more than once are handled
+type DuplicatePolicy string
+
+const (
+	// DuplicateError rejects the claims through ValidateClaimsData
+	DuplicateError DuplicatePolicy = "error"
+	// DuplicateKeepFirst keeps the first claim per address
+	DuplicateKeepFirst DuplicatePolicy = "keep-first"
+	// DuplicateSum sums the amounts per address
+	DuplicateSum DuplicatePolicy = "sum"
+)
+
+// ParseDuplicatePolicy parses a policy name
+func ParseDuplicatePolicy(name string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(name); policy {
+	case DuplicateError, DuplicateKeepFirst, DuplicateSum:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q (expected error, keep-first or sum)", name)
+	}
+}
+
+// ApplyDuplicatePolicy resolves repeated addresses according to policy
+func ApplyDuplicatePolicy(claims []merkle.AirdropClaim, policy DuplicatePolicy) ([]merkle.AirdropClaim, error) {
+	switch policy {
+	case DuplicateError:
+		if err := ValidateClaimsData(claims); err != nil {
+			return nil, err
+		}
+		return claims, nil
+	case DuplicateKeepFirst:
+		return DeduplicateClaims(claims), nil
+	case DuplicateSum:
+		return AggregateClaims(claims), nil
+	default:
+		return nil, fmt.Errorf("unknown duplicate policy %q", policy)
+	}
+}
+
 // SplitClaims splits claims into batches for processing
 func SplitClaims(claims []merkle.AirdropClaim, batchSize int) [][]merkle.AirdropClaim {
 	if batchSize <= 0 {
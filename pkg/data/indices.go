@@ -0,0 +1,203 @@
+// pkg/data/indices.go
+package data
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// LoadAirdropFromCSVWithIndices loads airdrop data from a CSV file with an
+// address,amount,index header, keeping the index column instead of numbering
+// claims in file order. Rows may be in any order, but the indices must run
+// from 0 to len-1 with no gaps or repeats.
+func LoadAirdropFromCSVWithIndices(filename string) ([]merkle.AirdropClaim, error) {
+	return LoadAirdropFromCSVWithIndicesLimit(filename, 0)
+}
+
+// LoadAirdropFromCSVWithIndicesLimit is LoadAirdropFromCSVWithIndices
+// stopping at the first row over maxClaims claims, which is unlimited when
+// zero
+func LoadAirdropFromCSVWithIndicesLimit(filename string, maxClaims int) ([]merkle.AirdropClaim, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"address", "amount", "index"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("header has no %s column", name)
+		}
+	}
+
+	var claims []merkle.AirdropClaim
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if err := checkClaimLimit(len(claims), maxClaims, line); err != nil {
+			return nil, err
+		}
+		address, amount, err := parseClaimFields(len(claims), record[columns["address"]], record[columns["amount"]])
+		if err != nil {
+			return nil, &RowError{Row: line, Err: err}
+		}
+		index, err := strconv.ParseUint(record[columns["index"]], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index for %s: %s", address.Hex(), record[columns["index"]])
+		}
+
+		claims = append(claims, merkle.AirdropClaim{
+			Address: address,
+			Amount:  amount,
+			Index:   uint32(index),
+		})
+	}
+
+	if err := ValidateIndices(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ValidateIndices checks that the claims' indices are exactly 0 to len-1 in
+// any order, so every slot of the contract's claimed bitmap has one claim
+func ValidateIndices(claims []merkle.AirdropClaim) error {
+	owners := make(map[uint32]merkle.AirdropClaim, len(claims))
+	for _, claim := range claims {
+		if other, exists := owners[claim.Index]; exists {
+			return fmt.Errorf("duplicate index %d for %s and %s", claim.Index, other.Address.Hex(), claim.Address.Hex())
+		}
+		owners[claim.Index] = claim
+	}
+
+	for i := 0; i < len(claims); i++ {
+		if _, exists := owners[uint32(i)]; !exists {
+			return fmt.Errorf("index gap: %d is missing (indices must run from 0 to %d)", i, len(claims)-1)
+		}
+	}
+	return nil
+}
+
+// IndexIssue describes a claim whose proof disagrees with the CSV
+type IndexIssue struct {
+	Address string
+	Problem string
+}
+
+func (i IndexIssue) String() string {
+	return i.Address + ": " + i.Problem
+}
+
+// CheckProofIndices cross-checks a proofs file against the claims it should
+// have been built from, reporting claims without a proof, proofs whose index
+// or amount differs from the claim's, and proofs for addresses not in the
+// claims. Issues are sorted by address.
+func CheckProofIndices(claims []merkle.AirdropClaim, proofs *merkle.ProofSet) []IndexIssue {
+	var issues []IndexIssue
+	known := make(map[string]bool, len(claims))
+
+	for _, claim := range claims {
+		address := claim.Address.Hex()
+		known[address] = true
+
+		proof, exists := proofs.Proofs[address]
+		switch {
+		case !exists:
+			issues = append(issues, IndexIssue{address, "no proof"})
+		case proof.Index != claim.Index:
+			issues = append(issues, IndexIssue{address, fmt.Sprintf("proof index %d, CSV index %d", proof.Index, claim.Index)})
+		case proof.Amount != claim.Amount.String():
+			issues = append(issues, IndexIssue{address, fmt.Sprintf("proof amount %s, CSV amount %s", proof.Amount, claim.Amount)})
+		}
+	}
+
+	for address, proof := range proofs.Proofs {
+		if !known[address] {
+			issues = append(issues, IndexIssue{address, fmt.Sprintf("proof index %d is not in the CSV", proof.Index)})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].Address < issues[j].Address
+	})
+	return issues
+}
+
+// indexFile is the JSON form of an index allocator, with its indices keyed
+// by checksummed address
+type indexFile struct {
+	Next    uint64            `json:"next"`
+	Indices map[string]uint32 `json:"indices"`
+}
+
+// LoadIndexFile loads the index allocator saved with SaveIndexFile, or an
+// empty one when path doesn't exist yet
+func LoadIndexFile(path string) (*merkle.IndexAllocator, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return merkle.NewIndexAllocator(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index file: %w", err)
+	}
+
+	var file indexFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse index file %s: %w", path, err)
+	}
+	indices := make(map[common.Address]uint32, len(file.Indices))
+	for address, index := range file.Indices {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("invalid address in index file %s: %s", path, address)
+		}
+		indices[common.HexToAddress(address)] = index
+	}
+	allocator, err := merkle.RestoreIndexAllocator(indices, file.Next)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index file %s: %w", path, err)
+	}
+	return allocator, nil
+}
+
+// SaveIndexFile writes allocator's indices to path atomically
+func SaveIndexFile(path string, allocator *merkle.IndexAllocator) error {
+	file := indexFile{Next: allocator.Next(), Indices: make(map[string]uint32, allocator.Len())}
+	for address, index := range allocator.Indices() {
+		file.Indices[address.Hex()] = index
+	}
+	return fsutil.AtomicWriteFile(path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(file)
+	})
+}
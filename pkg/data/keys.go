@@ -0,0 +1,179 @@
+// pkg/data/keys.go
+package data
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+// KeyFormat selects how the key column of a generic claims CSV is written
+type KeyFormat int
+
+const (
+	// KeyHex is hex, with or without a 0x prefix
+	KeyHex KeyFormat = iota
+	// KeyBase58 is base58 with the Bitcoin alphabet, as Solana writes
+	// public keys
+	KeyBase58
+	// KeyRaw takes the column's bytes as they are
+	KeyRaw
+)
+
+var keyFormatNames = map[KeyFormat]string{
+	KeyHex:    "hex",
+	KeyBase58: "base58",
+	KeyRaw:    "raw",
+}
+
+func (f KeyFormat) String() string {
+	if name, ok := keyFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("KeyFormat(%d)", int(f))
+}
+
+// ParseKeyFormat parses a key format name as returned by String
+func ParseKeyFormat(name string) (KeyFormat, error) {
+	for f, n := range keyFormatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key format: %q (expected hex, base58 or raw)", name)
+}
+
+// Decode parses a key written in format f
+func (f KeyFormat) Decode(field string) (merkle.LeafKey, error) {
+	var key merkle.LeafKey
+	switch f {
+	case KeyHex:
+		decoded, err := hex.DecodeString(strings.TrimPrefix(field, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex key %q: %w", field, err)
+		}
+		key = decoded
+	case KeyBase58:
+		decoded, err := decodeBase58(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base58 key %q: %w", field, err)
+		}
+		key = decoded
+	case KeyRaw:
+		key = merkle.LeafKey(field)
+	default:
+		return nil, fmt.Errorf("unknown key format: %s", f)
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encode writes key in format f, reversing Decode
+func (f KeyFormat) Encode(key merkle.LeafKey) string {
+	switch f {
+	case KeyBase58:
+		return encodeBase58(key)
+	case KeyRaw:
+		return string(key)
+	}
+	return key.String()
+}
+
+// LoadGenericClaimsFromCSV loads claims keyed by arbitrary bytes from a CSV
+// file with a key,amount header. Keys are parsed in format; like
+// LoadAirdropFromCSV, an optional index column is ignored and claims are
+// numbered in file order.
+func LoadGenericClaimsFromCSV(filename string, format KeyFormat) ([]merkle.GenericClaim, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // key, amount[, index]
+
+	// Skip header
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	var claims []merkle.GenericClaim
+	for index := uint32(0); ; index++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read record: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) != 2 && len(record) != 3 {
+			return nil, &RowError{Row: line, Err: fmt.Errorf("expected 2 or 3 fields, got %d", len(record))}
+		}
+
+		key, err := format.Decode(record[0])
+		if err != nil {
+			return nil, &RowError{Row: line, Err: err}
+		}
+		amount, err := parseAmount(int(index), record[1])
+		if err != nil {
+			return nil, &RowError{Row: line, Err: err}
+		}
+
+		claims = append(claims, merkle.GenericClaim{Key: key, Amount: amount, Index: index})
+	}
+
+	return claims, nil
+}
+
+// base58Alphabet is the Bitcoin alphabet, also used by Solana
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// decodeBase58 decodes s, keeping each leading '1' as a zero byte
+func decodeBase58(s string) ([]byte, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty string")
+	}
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(base58Alphabet, s[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid character %q at offset %d", s[i], i)
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	zeros := len(s) - len(strings.TrimLeft(s, "1"))
+	return append(make([]byte, zeros), value.Bytes()...), nil
+}
+
+// encodeBase58 reverses decodeBase58
+func encodeBase58(b []byte) string {
+	value := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	digit := new(big.Int)
+
+	var encoded []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, digit)
+		encoded = append(encoded, base58Alphabet[digit.Int64()])
+	}
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		encoded = append(encoded, '1')
+	}
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
@@ -2,35 +2,108 @@
 package data
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
+	"strconv"
+	"strings"
 
 	"merkle-airdrop/pkg/merkle"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
+// LoadOptions describes the layout of a claims CSV
+type LoadOptions struct {
+	// Delimiter separates fields. When zero it is detected from the first
+	// line: the most frequent of comma, semicolon, tab and pipe.
+	Delimiter rune
+
+	// AddressColumn and AmountColumn pick the columns by header name, or by
+	// zero-based position when numeric. Unset, they are the address and
+	// amount columns of the header, or the first two columns. Other
+	// columns are ignored.
+	AddressColumn string
+	AmountColumn  string
+
+	// HasHeader is set when the first line names the columns
+	HasHeader bool
+
+	// MaxClaims stops reading at the first row over this many claims,
+	// failing with a merkle.TooManyClaimsError. Zero is unlimited.
+	MaxClaims int
+}
+
+// DefaultLoadOptions returns the layout of CSVs written by the CLI: a
+// header, then address,amount rows, optionally with an index column
+func DefaultLoadOptions() LoadOptions {
+	return LoadOptions{HasHeader: true}
+}
+
+// ParseDelimiter parses a field delimiter: a single character, "tab", or
+// empty to detect it
+func ParseDelimiter(name string) (rune, error) {
+	switch name {
+	case "":
+		return 0, nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	if r := []rune(name); len(r) == 1 && r[0] != '"' && r[0] != '\n' && r[0] != '\r' {
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("invalid delimiter: %q (expected one character or tab)", name)
+}
+
+// utf8BOM is the byte order mark some spreadsheet exports begin with
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
 // LoadAirdropFromCSV loads airdrop data from CSV file
-// Expected format: address,amount
+// Expected format: address,amount with an optional index column, which is
+// ignored; claims are numbered in file order
 func LoadAirdropFromCSV(filename string) ([]merkle.AirdropClaim, error) {
+	return LoadAirdropFromCSVWithOptions(filename, DefaultLoadOptions())
+}
+
+// LoadAirdropFromCSVWithOptions loads claims from a CSV laid out as opts
+// describes, numbering them in file order. A leading BOM is skipped.
+func LoadAirdropFromCSVWithOptions(filename string, opts LoadOptions) ([]merkle.AirdropClaim, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 2 // address, amount
+	reader := newCSVReader(file, opts.Delimiter)
+	reader.FieldsPerRecord = -1 // Extra columns are ignored
 
-	var claims []merkle.AirdropClaim
-
-	// Skip header
-	if _, err := reader.Read(); err != nil {
-		return nil, fmt.Errorf("failed to read header: %w", err)
+	var header []string
+	if opts.HasHeader {
+		if header, err = reader.Read(); err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+	}
+	addressColumn, err := findColumn(header, opts.AddressColumn, "address", 0)
+	if err != nil {
+		return nil, err
+	}
+	amountColumn, err := findColumn(header, opts.AmountColumn, "amount", 1)
+	if err != nil {
+		return nil, err
+	}
+	if addressColumn == amountColumn {
+		return nil, fmt.Errorf("address and amount are both column %d", addressColumn)
 	}
+	fields := max(addressColumn, amountColumn) + 1
+
+	var claims []merkle.AirdropClaim
 
 	index := uint32(0)
 	for {
@@ -41,17 +114,17 @@ func LoadAirdropFromCSV(filename string) ([]merkle.AirdropClaim, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to read record: %w", err)
 		}
-
-		// Parse address
-		if !common.IsHexAddress(record[0]) {
-			return nil, fmt.Errorf("invalid address: %s", record[0])
+		line, _ := reader.FieldPos(0)
+		if err := checkClaimLimit(len(claims), opts.MaxClaims, line); err != nil {
+			return nil, err
+		}
+		if len(record) < fields {
+			return nil, &RowError{Row: line, Err: fmt.Errorf("expected at least %d fields, got %d", fields, len(record))}
 		}
-		address := common.HexToAddress(record[0])
 
-		// Parse amount
-		amount, ok := new(big.Int).SetString(record[1], 10)
-		if !ok {
-			return nil, fmt.Errorf("invalid amount: %s", record[1])
+		address, amount, err := parseClaimFields(len(claims), record[addressColumn], record[amountColumn])
+		if err != nil {
+			return nil, &RowError{Row: line, Err: err}
 		}
 
 		claims = append(claims, merkle.AirdropClaim{
@@ -66,20 +139,161 @@ func LoadAirdropFromCSV(filename string) ([]merkle.AirdropClaim, error) {
 	return claims, nil
 }
 
+// checkClaimLimit returns a merkle.TooManyClaimsError for the claim at row
+// when loaded claims have been read before it and limit allows no more
+func checkClaimLimit(loaded, limit, row int) error {
+	if limit > 0 && loaded >= limit {
+		return &merkle.TooManyClaimsError{Limit: limit, Seen: loaded + 1, Row: row}
+	}
+	return nil
+}
+
+// newCSVReader reads CSV from r past a leading BOM, with fields separated
+// by delimiter, or by the one detected from the first line when zero
+func newCSVReader(r io.Reader, delimiter rune) *csv.Reader {
+	buffered := bufio.NewReader(r)
+	if bom, err := buffered.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+		buffered.Discard(len(utf8BOM))
+	}
+	if delimiter == 0 {
+		// A first line longer than the buffer is detected from its start
+		head, _ := buffered.Peek(buffered.Size())
+		if end := bytes.IndexByte(head, '\n'); end >= 0 {
+			head = head[:end]
+		}
+		delimiter = detectDelimiter(head)
+	}
+	reader := csv.NewReader(buffered)
+	reader.Comma = delimiter
+	return reader
+}
+
+// detectDelimiter returns the most frequent of the candidate delimiters
+// outside quotes in line, a comma if there are none
+func detectDelimiter(line []byte) rune {
+	candidates := []byte{',', ';', '\t', '|'}
+	counts := make([]int, len(candidates))
+	quoted := false
+	for _, c := range line {
+		if c == '"' {
+			quoted = !quoted
+			continue
+		}
+		if i := bytes.IndexByte(candidates, c); i >= 0 && !quoted {
+			counts[i]++
+		}
+	}
+	best := 0
+	for i := range candidates {
+		if counts[i] > counts[best] {
+			best = i
+		}
+	}
+	return rune(candidates[best])
+}
+
+// findColumn returns the position of the column spec names: a zero-based
+// position, a header name, or when empty the column named fallbackName or
+// else at fallbackPosition
+func findColumn(header []string, spec, fallbackName string, fallbackPosition int) (int, error) {
+	if position, err := strconv.Atoi(spec); err == nil {
+		if position < 0 {
+			return 0, fmt.Errorf("invalid column position %d", position)
+		}
+		return position, nil
+	}
+	name := strings.ToLower(strings.TrimSpace(spec))
+	if name == "" {
+		name = fallbackName
+	}
+	for i, column := range header {
+		if strings.ToLower(strings.TrimSpace(column)) == name {
+			return i, nil
+		}
+	}
+	switch {
+	case spec == "":
+		return fallbackPosition, nil
+	case header == nil:
+		return 0, fmt.Errorf("column %q needs a header; give its position instead", spec)
+	}
+	return 0, fmt.Errorf("header has no %s column", spec)
+}
+
+// parseClaimFields converts the address and decimal amount of the claim at
+// position index
+func parseClaimFields(index int, addressField, amountField string) (common.Address, *big.Int, error) {
+	// Parse address
+	if !common.IsHexAddress(addressField) {
+		return common.Address{}, nil, fmt.Errorf("invalid address: %s", addressField)
+	}
+	address := common.HexToAddress(addressField)
+
+	// Parse amount
+	amount, err := parseAmount(index, amountField)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+
+	return address, amount, nil
+}
+
+// parseAmount parses the decimal uint256 amount of the claim at position
+// index, returning a merkle.InvalidAmountError if it isn't one
+func parseAmount(index int, value string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, &merkle.InvalidAmountError{Index: index, Value: value, Reason: "not a decimal integer"}
+	}
+	if err := merkle.CheckAmount(index, amount); err != nil {
+		return nil, err
+	}
+	return amount, nil
+}
+
 // GenerateTestData creates test airdrop data
 func GenerateTestData(count int) []merkle.AirdropClaim {
+	return generateClaims(count, func(i int) common.Address {
+		// Generate pseudo-random address
+		return common.HexToAddress(fmt.Sprintf("0x%040d", i+1))
+	})
+}
+
+// GenerateRandomTestData creates test airdrop data with addresses spread
+// over the whole address space, in no particular order. The same seed
+// always gives the same claims.
+func GenerateRandomTestData(count int, seed int64) []merkle.AirdropClaim {
+	var preimage [16]byte
+	binary.BigEndian.PutUint64(preimage[:8], uint64(seed))
+	return generateClaims(count, func(i int) common.Address {
+		binary.BigEndian.PutUint64(preimage[8:], uint64(i))
+		return common.BytesToAddress(crypto.Keccak256(preimage[:]))
+	})
+}
+
+// CloneClaims deep-copies claims, including amounts, so a tree can sort and
+// re-index the copy while the original is reused
+func CloneClaims(claims []merkle.AirdropClaim) []merkle.AirdropClaim {
+	cloned := make([]merkle.AirdropClaim, len(claims))
+	for i, claim := range claims {
+		cloned[i] = claim
+		cloned[i].Amount = new(big.Int).Set(claim.Amount)
+	}
+	return cloned
+}
+
+// generateClaims creates count claims at the given addresses with amounts
+// cycling through 1-1000 tokens
+func generateClaims(count int, address func(i int) common.Address) []merkle.AirdropClaim {
 	claims := make([]merkle.AirdropClaim, count)
 
 	for i := 0; i < count; i++ {
-		// Generate pseudo-random address
-		address := common.HexToAddress(fmt.Sprintf("0x%040d", i+1))
-
 		// Generate amount (1-1000 tokens with 18 decimals)
 		amount := big.NewInt(int64(i%1000 + 1))
 		amount.Mul(amount, big.NewInt(1e18)) // 18 decimals
 
 		claims[i] = merkle.AirdropClaim{
-			Address: address,
+			Address: address(i),
 			Amount:  amount,
 			Index:   uint32(i),
 		}
@@ -87,3 +301,43 @@ func GenerateTestData(count int) []merkle.AirdropClaim {
 
 	return claims
 }
+
+// LoadProofsJSON reads a proofs file in the JSON format written by the CLI,
+// decoding proofs with the field names in its proofFields when it has them
+func LoadProofsJSON(r io.Reader) (string, *merkle.ProofSet, error) {
+	var file struct {
+		MerkleRoot  string                     `json:"merkleRoot"`
+		Metadata    merkle.TreeMetadata        `json:"metadata"`
+		ProofFields ProofMarshaller            `json:"proofFields"`
+		Proofs      map[string]json.RawMessage `json:"proofs"`
+	}
+	// Files written before metadata was recorded use the default encoding
+	file.Metadata = merkle.DefaultMetadata()
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return "", nil, fmt.Errorf("failed to decode proofs file: %w", err)
+	}
+	if file.MerkleRoot == "" {
+		return "", nil, fmt.Errorf("proofs file has no merkleRoot")
+	}
+	if err := file.ProofFields.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid proofFields: %w", err)
+	}
+	if err := file.Metadata.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	// Normalize keys so lookups by checksummed address always work
+	proofs := make(map[string]*merkle.MerkleProof, len(file.Proofs))
+	for addr, encoded := range file.Proofs {
+		if !common.IsHexAddress(addr) {
+			return "", nil, fmt.Errorf("invalid address: %s", addr)
+		}
+		proof, err := file.ProofFields.Unmarshal(encoded)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode proof of %s: %w", addr, err)
+		}
+		proofs[common.HexToAddress(addr).Hex()] = proof
+	}
+
+	return file.MerkleRoot, &merkle.ProofSet{Proofs: proofs, Metadata: file.Metadata}, nil
+}
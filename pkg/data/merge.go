@@ -0,0 +1,151 @@
+// pkg/data/merge.go
+package data
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ClaimSource is one input of MergeClaimSources, such as the holders of an
+// NFT or a list of stakers. Its amounts are scaled by Multiplier, or
+// replaced by Flat for every address it lists.
+type ClaimSource struct {
+	Label string
+
+	// Claims are the source's claims, unless Load is set
+	Claims []merkle.AirdropClaim
+	// Load returns the source's claims, such as from a CSV
+	Load func() ([]merkle.AirdropClaim, error)
+
+	// Multiplier scales each address's amount, rounding down; 1 when nil
+	Multiplier *big.Rat
+	// Flat gives each address in the source this amount instead, ignoring
+	// theirs. It can't be combined with Multiplier.
+	Flat *big.Int
+}
+
+// SourceReport is what one source contributed to a merge
+type SourceReport struct {
+	Label     string   `json:"label"`
+	Addresses int      `json:"addresses"` // Distinct addresses in the source
+	Input     *big.Int `json:"input"`     // Sum of the source's own amounts
+	Output    *big.Int `json:"output"`    // Sum the source adds to the merged claims
+	// Remainder is what rounding each scaled amount down dropped, below
+	// one base unit per address
+	Remainder *big.Rat `json:"remainder"`
+	// Overlapping is the number of its addresses also in another source
+	Overlapping int `json:"overlapping"`
+}
+
+// MergeReport breaks down a merge by source
+type MergeReport struct {
+	Sources   []SourceReport `json:"sources"`
+	Addresses int            `json:"addresses"` // Claims in the merged result
+	Total     *big.Int       `json:"total"`
+	// Overlaps counts addresses by the number of sources listing them,
+	// from two up
+	Overlaps map[int]int `json:"overlaps"`
+	// Zero is the number of addresses left out because their merged
+	// amount is zero
+	Zero int `json:"zero"`
+}
+
+// MergeClaimSources combines sources into one set of claims. Each source's
+// amounts are summed per address and then scaled with exact integer math,
+// floor(amount * numerator / denominator), and addresses in several
+// sources get the sum of their scaled amounts. Claims are ordered by
+// address and indexed from zero.
+func MergeClaimSources(sources []ClaimSource) ([]merkle.AirdropClaim, MergeReport, error) {
+	report := MergeReport{Total: new(big.Int), Overlaps: map[int]int{}}
+	if len(sources) == 0 {
+		return nil, report, fmt.Errorf("no claim sources provided")
+	}
+
+	totals := make(map[common.Address]*big.Int)
+	inSources := make(map[common.Address][]int) // Sources listing each address
+	for s, source := range sources {
+		if source.Multiplier != nil && source.Flat != nil {
+			return nil, report, fmt.Errorf("source %q: multiplier and flat amount are exclusive", source.Label)
+		}
+		if source.Multiplier != nil && source.Multiplier.Sign() < 0 {
+			return nil, report, fmt.Errorf("source %q: negative multiplier %s", source.Label, source.Multiplier.RatString())
+		}
+		if source.Flat != nil && !merkle.ValidAmount(source.Flat) {
+			return nil, report, fmt.Errorf("source %q: invalid flat amount %s", source.Label, source.Flat)
+		}
+
+		claims := source.Claims
+		if source.Load != nil {
+			loaded, err := source.Load()
+			if err != nil {
+				return nil, report, fmt.Errorf("source %q: %w", source.Label, err)
+			}
+			claims = loaded
+		}
+		for i, claim := range claims {
+			if err := merkle.CheckAmount(i, claim.Amount); err != nil {
+				return nil, report, fmt.Errorf("source %q: %w", source.Label, err)
+			}
+		}
+
+		sourceReport := SourceReport{Label: source.Label, Input: new(big.Int), Output: new(big.Int), Remainder: new(big.Rat)}
+		for _, claim := range AggregateClaims(claims) {
+			sourceReport.Addresses++
+			sourceReport.Input.Add(sourceReport.Input, claim.Amount)
+
+			amount := claim.Amount
+			switch {
+			case source.Flat != nil:
+				amount = source.Flat
+			case source.Multiplier != nil:
+				var remainder big.Int
+				amount, _ = new(big.Int).QuoRem(new(big.Int).Mul(claim.Amount, source.Multiplier.Num()), source.Multiplier.Denom(), &remainder)
+				sourceReport.Remainder.Add(sourceReport.Remainder, new(big.Rat).SetFrac(&remainder, source.Multiplier.Denom()))
+			}
+			sourceReport.Output.Add(sourceReport.Output, amount)
+
+			if totals[claim.Address] == nil {
+				totals[claim.Address] = new(big.Int)
+			}
+			totals[claim.Address].Add(totals[claim.Address], amount)
+			inSources[claim.Address] = append(inSources[claim.Address], s)
+		}
+		report.Sources = append(report.Sources, sourceReport)
+	}
+
+	addresses := make([]common.Address, 0, len(totals))
+	for address, listed := range inSources {
+		addresses = append(addresses, address)
+		if len(listed) > 1 {
+			report.Overlaps[len(listed)]++
+			for _, s := range listed {
+				report.Sources[s].Overlapping++
+			}
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+
+	claims := make([]merkle.AirdropClaim, 0, len(addresses))
+	for _, address := range addresses {
+		amount := totals[address]
+		if amount.Sign() == 0 {
+			report.Zero++
+			continue
+		}
+		if err := merkle.CheckAmount(len(claims), amount); err != nil {
+			return nil, report, fmt.Errorf("merged amount for %s: %w", address.Hex(), err)
+		}
+		claims = append(claims, merkle.AirdropClaim{Address: address, Amount: amount, Index: uint32(len(claims))})
+		report.Total.Add(report.Total, amount)
+	}
+	report.Addresses = len(claims)
+	return claims, report, nil
+}
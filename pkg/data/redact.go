@@ -0,0 +1,155 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RedactedProof is an address's proof with its claim's leaf hash in place
+// of the amount. The proof can be checked against the root from the leaf
+// alone; only someone who knows the amount can tie the leaf to the claim.
+type RedactedProof struct {
+	Leaf      string   `json:"leaf"`
+	Index     uint32   `json:"index"`
+	Proof     []string `json:"proof"`
+	Positions uint64   `json:"positions,omitempty"` // Set for trees without sorted pairs
+
+	PaddingCount int `json:"paddingCount,omitempty"`
+}
+
+// RedactedProofFile is the layout of a redacted export, keyed by
+// checksummed address
+type RedactedProofFile struct {
+	MerkleRoot  string                   `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata      `json:"metadata"`
+	Proofs      map[string]RedactedProof `json:"proofs"`
+	TotalClaims int                      `json:"totalClaims"`
+}
+
+// ExportProofsRedacted writes the proofs of addresses, or of every claim
+// when addresses is nil, without their amounts. Every proof is checked to
+// lead from its leaf to root before anything is written. Addresses that
+// have no proof are left out, as MissingAddresses reports them.
+func ExportProofsRedacted(proofs *merkle.ProofSet, addresses []common.Address, root string, w io.Writer) error {
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+
+	byAddress := proofsByAddress(proofs)
+	if addresses == nil {
+		addresses = make([]common.Address, 0, len(byAddress))
+		for address := range byAddress {
+			addresses = append(addresses, address)
+		}
+	}
+	opts := proofs.Metadata.Options()
+	file := RedactedProofFile{
+		MerkleRoot: merkle.EncodeHash(rootBytes),
+		Metadata:   proofs.Metadata,
+		Proofs:     make(map[string]RedactedProof, len(addresses)),
+	}
+	for _, address := range addresses {
+		proof, ok := byAddress[address]
+		if !ok {
+			continue
+		}
+		if err := merkle.CheckProofRoot(rootBytes, proof.Root); err != nil {
+			return fmt.Errorf("%s: %w", address.Hex(), err)
+		}
+		amount, ok := new(big.Int).SetString(proof.Amount, 10)
+		if !ok {
+			return fmt.Errorf("%s: invalid amount %q", address.Hex(), proof.Amount)
+		}
+		leaf, err := merkle.HashLeafWithOptions(address, amount, proof.Index, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", address.Hex(), err)
+		}
+		valid, err := merkle.VerifyLeafProof(rootBytes, leaf, proof.Proof, proof.Positions, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", address.Hex(), err)
+		}
+		if !valid {
+			return fmt.Errorf("%s: proof does not verify against root %s", address.Hex(), file.MerkleRoot)
+		}
+		file.Proofs[address.Hex()] = RedactedProof{
+			Leaf:         merkle.EncodeHash(leaf),
+			Index:        proof.Index,
+			Proof:        proof.Proof,
+			Positions:    proof.Positions,
+			PaddingCount: proof.PaddingCount,
+		}
+	}
+	file.TotalClaims = len(file.Proofs)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode proofs: %w", err)
+	}
+	return nil
+}
+
+// LoadRedactedProofs reads an export written by ExportProofsRedacted
+func LoadRedactedProofs(r io.Reader) (*RedactedProofFile, error) {
+	var file RedactedProofFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode redacted proofs: %w", err)
+	}
+	if _, err := merkle.DecodeHash(file.MerkleRoot); err != nil {
+		return nil, fmt.Errorf("invalid merkleRoot: %w", err)
+	}
+	return &file, nil
+}
+
+// Verify checks that every proof leads from its leaf to the file's root
+func (f *RedactedProofFile) Verify() error {
+	root, err := merkle.DecodeHash(f.MerkleRoot)
+	if err != nil {
+		return fmt.Errorf("invalid merkleRoot: %w", err)
+	}
+	opts := f.Metadata.Options()
+	for address, proof := range f.Proofs {
+		leaf, err := merkle.DecodeHash(proof.Leaf)
+		if err != nil {
+			return fmt.Errorf("%s: invalid leaf: %w", address, err)
+		}
+		valid, err := merkle.VerifyLeafProof(root, leaf, proof.Proof, proof.Positions, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+		if !valid {
+			return fmt.Errorf("%s: proof does not verify against root %s", address, f.MerkleRoot)
+		}
+	}
+	return nil
+}
+
+// VerifyClaim reports whether amount is address's claim in the file: the
+// claim hashes to the recorded leaf, whose proof leads to the root.
+// Addresses without a proof report false.
+func (f *RedactedProofFile) VerifyClaim(address common.Address, amount *big.Int) (bool, error) {
+	proof, ok := f.Proofs[address.Hex()]
+	if !ok {
+		return false, nil
+	}
+	opts := f.Metadata.Options()
+	leaf, err := merkle.HashLeafWithOptions(address, amount, proof.Index, opts)
+	if err != nil {
+		return false, err
+	}
+	if merkle.EncodeHash(leaf) != proof.Leaf {
+		return false, nil
+	}
+	root, err := merkle.DecodeHash(f.MerkleRoot)
+	if err != nil {
+		return false, fmt.Errorf("invalid merkleRoot: %w", err)
+	}
+	return merkle.VerifyLeafProof(root, leaf, proof.Proof, proof.Positions, opts)
+}
@@ -0,0 +1,237 @@
+// pkg/data/sanity.go
+package data
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Severity ranks sanity findings
+type Severity int
+
+const (
+	// SeverityWarning marks claims that are unusual but may be intended
+	SeverityWarning Severity = iota
+	// SeverityError marks claims that are almost certainly wrong
+	SeverityError
+)
+
+var severityNames = map[Severity]string{
+	SeverityWarning: "warning",
+	SeverityError:   "error",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses a severity name as returned by String
+func ParseSeverity(name string) (Severity, error) {
+	for s, n := range severityNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity: %q (expected warning or error)", name)
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name
+func (s *Severity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// The rules SanityReport checks, as reported in Finding.Rule
+const (
+	RuleSharedAmount      = "shared-amount"      // Many claims with the same amount, as if copy-pasted
+	RuleAddressAmount     = "address-amount"     // Amount equal to the address read as a number
+	RuleContractRecipient = "contract-recipient" // Recipient is, or looks like, a contract
+	RuleDust              = "dust"               // Amount below the dust threshold
+	RulePrecision         = "precision"          // Amount with too many significant digits
+)
+
+// DefaultMaxSignificantDigits is the SanityOptions.MaxSignificantDigits
+// of DefaultSanityOptions
+const DefaultMaxSignificantDigits = 30
+
+// DefaultMaxSharedAmount is the SanityOptions.MaxSharedAmount of
+// DefaultSanityOptions
+const DefaultMaxSharedAmount = 100
+
+// CodeReader reads contract code, as an ethclient.Client does
+type CodeReader interface {
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+}
+
+// SanityOptions configures SanityReport. Zero values disable the rule they
+// set a limit for.
+type SanityOptions struct {
+	// MaxSharedAmount is the number of claims that may share an amount
+	// before they are reported
+	MaxSharedAmount int
+	// DustThreshold reports amounts below it
+	DustThreshold *big.Int
+	// MaxSignificantDigits reports amounts with more significant digits,
+	// not counting trailing zeros
+	MaxSignificantDigits int
+	// Code looks up recipients' code on chain. Without it, recipients are
+	// only checked against precompiles and well-known contracts.
+	Code CodeReader
+}
+
+// DefaultSanityOptions returns the thresholds the lint command uses
+func DefaultSanityOptions() SanityOptions {
+	return SanityOptions{
+		MaxSharedAmount:      DefaultMaxSharedAmount,
+		MaxSignificantDigits: DefaultMaxSignificantDigits,
+	}
+}
+
+// Finding is one suspicious claim, or group of claims, in a Report
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Index    int      `json:"index"` // Position of the (first) claim concerned
+	Address  string   `json:"address,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	if f.Address == "" {
+		return fmt.Sprintf("%s [%s] claim %d: %s", f.Severity, f.Rule, f.Index, f.Message)
+	}
+	return fmt.Sprintf("%s [%s] claim %d (%s): %s", f.Severity, f.Rule, f.Index, f.Address, f.Message)
+}
+
+// Report is the outcome of SanityReport, with findings ordered by claim
+type Report struct {
+	Claims   int       `json:"claims"`
+	Findings []Finding `json:"findings"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// knownContracts are widely used Ethereum mainnet contracts that end up in
+// recipient lists by mistake
+var knownContracts = map[common.Address]string{
+	common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"): "WETH",
+	common.HexToAddress("0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"): "USDC",
+	common.HexToAddress("0xdAC17F958D2ee523a2206206994597C13D831ec7"): "USDT",
+	common.HexToAddress("0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"): "Uniswap V2 router",
+	common.HexToAddress("0xE592427A0AEce92De3Edee1F18E0157C05861564"): "Uniswap V3 router",
+	common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3"): "Permit2",
+}
+
+// systemAddressLimit bounds the addresses reserved for precompiles and
+// system contracts
+var systemAddressLimit = big.NewInt(1 << 16)
+
+// SanityReport flags claims that are valid but look like data bugs.
+// Code lookups that fail are reported as warnings rather than stopping the
+// report.
+func SanityReport(claims []merkle.AirdropClaim, opts SanityOptions) Report {
+	report := Report{Claims: len(claims), Findings: []Finding{}}
+	add := func(rule string, severity Severity, index int, address, format string, args ...interface{}) {
+		report.Findings = append(report.Findings, Finding{
+			Rule:     rule,
+			Severity: severity,
+			Index:    index,
+			Address:  address,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	byAmount := make(map[string][]int)
+	for i, claim := range claims {
+		if claim.Amount == nil {
+			continue
+		}
+		amount := claim.Amount.String()
+		byAmount[amount] = append(byAmount[amount], i)
+
+		if claim.Amount.Cmp(new(big.Int).SetBytes(claim.Address.Bytes())) == 0 {
+			add(RuleAddressAmount, SeverityError, i, claim.Address.Hex(), "amount %s is the address read as a number", amount)
+		}
+		if opts.DustThreshold != nil && claim.Amount.Cmp(opts.DustThreshold) < 0 {
+			add(RuleDust, SeverityWarning, i, claim.Address.Hex(), "amount %s is below the dust threshold %s", amount, opts.DustThreshold)
+		}
+		if digits := significantDigits(amount); opts.MaxSignificantDigits > 0 && digits > opts.MaxSignificantDigits {
+			add(RulePrecision, SeverityError, i, claim.Address.Hex(), "amount %s has %d significant digits (max %d)", amount, digits, opts.MaxSignificantDigits)
+		}
+
+		if reason := contractReason(claim.Address, opts.Code); reason != "" {
+			add(RuleContractRecipient, SeverityWarning, i, claim.Address.Hex(), "recipient %s", reason)
+		}
+	}
+
+	if opts.MaxSharedAmount > 0 {
+		for amount, indices := range byAmount {
+			if len(indices) > opts.MaxSharedAmount {
+				add(RuleSharedAmount, SeverityWarning, indices[0], "", "%d claims share amount %s exactly (max %d)", len(indices), amount, opts.MaxSharedAmount)
+			}
+		}
+	}
+
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Index != b.Index {
+			return a.Index < b.Index
+		}
+		return a.Rule < b.Rule
+	})
+	for _, f := range report.Findings {
+		if f.Severity == SeverityError {
+			report.Errors++
+		} else {
+			report.Warnings++
+		}
+	}
+	return report
+}
+
+// significantDigits counts the digits of a decimal integer without its
+// trailing zeros
+func significantDigits(decimal string) int {
+	digits := strings.TrimRight(strings.TrimPrefix(decimal, "-"), "0")
+	return len(digits)
+}
+
+// contractReason explains why address looks like a contract, or returns ""
+func contractReason(address common.Address, code CodeReader) string {
+	if name, ok := knownContracts[address]; ok {
+		return "is the " + name + " contract"
+	}
+	if new(big.Int).SetBytes(address.Bytes()).Cmp(systemAddressLimit) < 0 {
+		return "is in the precompile and system contract range"
+	}
+	if code == nil {
+		return ""
+	}
+	deployed, err := code.CodeAt(context.Background(), address, nil)
+	if err != nil {
+		return "could not be checked for code: " + err.Error()
+	}
+	if len(deployed) > 0 {
+		return fmt.Sprintf("has contract code (%d bytes)", len(deployed))
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// ShardIndexFile is the name of the index written next to proof shards
+const ShardIndexFile = "index.json"
+
+// MaxShardBits limits sharded exports to 65536 files
+const MaxShardBits = 16
+
+// ShardIndex describes a sharded proofs export. Shards maps each lowercase
+// hex address prefix to the file holding its proofs.
+type ShardIndex struct {
+	MerkleRoot  string              `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata `json:"metadata"`
+	ShardBits   int                 `json:"shardBits"`
+	TotalClaims int                 `json:"totalClaims"`
+	Shards      map[string]string   `json:"shards"`
+}
+
+// shardFile is the content of one shard, readable by LoadProofsJSON
+type shardFile struct {
+	MerkleRoot  string                    `json:"merkleRoot"`
+	Metadata    merkle.TreeMetadata       `json:"metadata"`
+	ProofFields *ProofMarshaller          `json:"proofFields,omitempty"`
+	Proofs      map[string]json.Marshaler `json:"proofs"`
+}
+
+// ValidateShardBits checks that shardBits selects whole hex digits and at
+// most MaxShardBits
+func ValidateShardBits(shardBits int) error {
+	if shardBits < 4 || shardBits > MaxShardBits || shardBits%4 != 0 {
+		return fmt.Errorf("shard bits must be a multiple of 4 between 4 and %d, got %d", MaxShardBits, shardBits)
+	}
+	return nil
+}
+
+// ShardPrefix returns the lowercase hex prefix selecting the shard of address
+func ShardPrefix(address string, shardBits int) string {
+	return strings.ToLower(strings.TrimPrefix(address, "0x")[:shardBits/4])
+}
+
+// ExportProofsSharded writes proofs to dir as 2^shardBits JSON files named
+// by the first shardBits/4 hex digits of the address (e.g. 0a.json), plus an
+// index.json mapping prefixes to files. Every shard is written, even when
+// empty, so frontends can fetch the one for any wallet. Shards are keyed by
+// address in addressCase, with proof fields named by fields.
+func ExportProofsSharded(proofs *merkle.ProofSet, root string, dir string, shardBits int, addressCase AddressCase, fields ProofMarshaller) error {
+	if err := ValidateShardBits(shardBits); err != nil {
+		return err
+	}
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create shard directory: %w", err)
+	}
+
+	digits := shardBits / 4
+	shards := make(map[string]map[string]*merkle.MerkleProof, 1<<shardBits)
+	for i := 0; i < 1<<shardBits; i++ {
+		shards[fmt.Sprintf("%0*x", digits, i)] = make(map[string]*merkle.MerkleProof)
+	}
+	for address, proof := range addressCase.FormatProofs(proofs.Proofs) {
+		if shards[ShardPrefix(address, shardBits)][address], err = withRoot(proof, rootBytes); err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+	}
+
+	index := ShardIndex{
+		MerkleRoot:  root,
+		Metadata:    proofs.Metadata,
+		ShardBits:   shardBits,
+		TotalClaims: proofs.Len(),
+		Shards:      make(map[string]string, len(shards)),
+	}
+	for prefix, shard := range shards {
+		name := prefix + ".json"
+		file := shardFile{MerkleRoot: root, Metadata: proofs.Metadata, ProofFields: fields.fileFields(), Proofs: fields.Proofs(shard)}
+		if err := writeJSONFile(filepath.Join(dir, name), file); err != nil {
+			return fmt.Errorf("failed to write shard %s: %w", prefix, err)
+		}
+		index.Shards[prefix] = name
+	}
+
+	if err := writeJSONFile(filepath.Join(dir, ShardIndexFile), index); err != nil {
+		return fmt.Errorf("failed to write shard index: %w", err)
+	}
+	return nil
+}
+
+// LoadShardedProofs reassembles a proofs export written by
+// ExportProofsSharded, checking every shard against the index
+func LoadShardedProofs(dir string) (string, *merkle.ProofSet, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ShardIndexFile))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read shard index: %w", err)
+	}
+
+	index := ShardIndex{Metadata: merkle.DefaultMetadata()}
+	if err := json.Unmarshal(content, &index); err != nil {
+		return "", nil, fmt.Errorf("failed to decode shard index: %w", err)
+	}
+	if index.MerkleRoot == "" {
+		return "", nil, fmt.Errorf("shard index has no merkleRoot")
+	}
+	if err := ValidateShardBits(index.ShardBits); err != nil {
+		return "", nil, fmt.Errorf("invalid shard index: %w", err)
+	}
+
+	proofs := make(map[string]*merkle.MerkleProof, index.TotalClaims)
+	for prefix, name := range index.Shards {
+		if filepath.Base(name) != name {
+			return "", nil, fmt.Errorf("shard %s: file %q is outside the shard directory", prefix, name)
+		}
+
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open shard %s: %w", prefix, err)
+		}
+		root, shard, err := LoadProofsJSON(file)
+		file.Close()
+		if err != nil {
+			return "", nil, fmt.Errorf("shard %s: %w", prefix, err)
+		}
+
+		if root != index.MerkleRoot {
+			return "", nil, fmt.Errorf("shard %s: root %s does not match index root %s", prefix, root, index.MerkleRoot)
+		}
+		if shard.Metadata != index.Metadata {
+			return "", nil, fmt.Errorf("shard %s: metadata does not match the index", prefix)
+		}
+		for address, proof := range shard.Proofs {
+			if ShardPrefix(address, index.ShardBits) != prefix {
+				return "", nil, fmt.Errorf("shard %s: address %s belongs in another shard", prefix, address)
+			}
+			proofs[address] = proof
+		}
+	}
+
+	if len(proofs) != index.TotalClaims {
+		return "", nil, fmt.Errorf("shards hold %d proofs, index expects %d", len(proofs), index.TotalClaims)
+	}
+
+	return index.MerkleRoot, &merkle.ProofSet{Proofs: proofs, Metadata: index.Metadata}, nil
+}
+
+// writeJSONFile encodes v to filename
+func writeJSONFile(filename string, v interface{}) error {
+	return fsutil.AtomicWriteFile(filename, func(w io.Writer) error {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		return nil
+	})
+}
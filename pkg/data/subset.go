@@ -0,0 +1,141 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// subsetMetadata is the metadata of a subset export: the tree's encoding
+// and the requested addresses that are not in the tree
+type subsetMetadata struct {
+	merkle.TreeMetadata
+	MissingAddresses []string `json:"missingAddresses"`
+}
+
+// subsetFile is the layout of a subset export, the same as the CLI's full
+// JSON export without its timings
+type subsetFile struct {
+	MerkleRoot  string                    `json:"merkleRoot"`
+	Metadata    subsetMetadata            `json:"metadata"`
+	ProofFields *ProofMarshaller          `json:"proofFields,omitempty"`
+	Proofs      map[string]json.Marshaler `json:"proofs"`
+	TotalClaims int                       `json:"totalClaims"`
+	IndexBitmap string                    `json:"indexBitmap"`
+	GeneratedAt int64                     `json:"generatedAt"`
+}
+
+// ExportProofsSubset writes the proofs of addresses as a JSON proofs file
+// readable by LoadProofsJSON, leaving every other claim out. Addresses match
+// regardless of case; the ones not in proofs are listed in the metadata's
+// missingAddresses, and MissingAddresses returns them too. Proofs are
+// encoded with fields.
+func ExportProofsSubset(proofs *merkle.ProofSet, addresses []common.Address, root string, w io.Writer, fields ProofMarshaller) error {
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return fmt.Errorf("invalid root: %w", err)
+	}
+
+	byAddress := proofsByAddress(proofs)
+	file := subsetFile{
+		MerkleRoot:  root,
+		Metadata:    subsetMetadata{TreeMetadata: proofs.Metadata, MissingAddresses: []string{}},
+		ProofFields: fields.fileFields(),
+		GeneratedAt: time.Now().Unix(),
+	}
+	subset := make(map[string]*merkle.MerkleProof, len(addresses))
+	indices := make([]uint32, 0, len(addresses))
+	for _, address := range addresses {
+		key := address.Hex()
+		if _, done := subset[key]; done {
+			continue // Requested twice
+		}
+		proof, ok := byAddress[address]
+		if !ok {
+			file.Metadata.MissingAddresses = append(file.Metadata.MissingAddresses, key)
+			continue
+		}
+		if subset[key], err = withRoot(proof, rootBytes); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		indices = append(indices, proof.Index)
+	}
+	file.Proofs = fields.Proofs(subset)
+	file.TotalClaims = len(subset)
+	file.IndexBitmap = merkle.EncodeIndexBitmap(merkle.IndexBitmapOf(indices))
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(file); err != nil {
+		return fmt.Errorf("failed to encode proofs: %w", err)
+	}
+	return nil
+}
+
+// MissingAddresses returns the addresses that have no proof in proofs, in
+// the order given and without repeats
+func MissingAddresses(proofs *merkle.ProofSet, addresses []common.Address) []common.Address {
+	byAddress := proofsByAddress(proofs)
+	seen := make(map[common.Address]bool, len(addresses))
+	var missing []common.Address
+	for _, address := range addresses {
+		if _, ok := byAddress[address]; !ok && !seen[address] {
+			missing = append(missing, address)
+		}
+		seen[address] = true
+	}
+	return missing
+}
+
+// proofsByAddress indexes proofs by parsed address, so keys in any case
+// match
+func proofsByAddress(proofs *merkle.ProofSet) map[common.Address]*merkle.MerkleProof {
+	byAddress := make(map[common.Address]*merkle.MerkleProof, len(proofs.Proofs))
+	for key, proof := range proofs.Proofs {
+		if common.IsHexAddress(key) {
+			byAddress[common.HexToAddress(key)] = proof
+		}
+	}
+	return byAddress
+}
+
+// LoadAddressesFromFile reads one address per line, in any case, keeping
+// the first of repeated addresses. Blank lines and lines starting with #
+// are skipped.
+func LoadAddressesFromFile(filename string) ([]common.Address, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	var addresses []common.Address
+	seen := make(map[common.Address]bool)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if !common.IsHexAddress(text) {
+			return nil, &RowError{Row: line, Err: fmt.Errorf("invalid address %q", text)}
+		}
+		address := common.HexToAddress(text)
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return addresses, nil
+}
@@ -0,0 +1,65 @@
+// pkg/data/testkeys.go
+package data
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"sync"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testKeyDomain separates test key scalars from other hashes of the seed
+const testKeyDomain = "merkle-airdrop test key"
+
+// testKeyAddresses holds every address GenerateTestDataWithKeys has derived
+// a key for, so IsTestKeyAddress can tell them apart
+var testKeyAddresses sync.Map
+
+// GenerateTestDataWithKeys creates claims like GenerateTestData at the
+// addresses of private keys derived from seed, returning the keys by
+// address so tests can sign claims and messages for them. The same seed
+// always gives the same keys.
+//
+// The keys are public to anyone who knows the seed. They are for tests and
+// simulated chains only; contract clients refuse to send transactions from
+// them to a mainnet.
+func GenerateTestDataWithKeys(count int, seed int64) ([]merkle.AirdropClaim, map[common.Address]*ecdsa.PrivateKey) {
+	keys := make(map[common.Address]*ecdsa.PrivateKey, count)
+	claims := generateClaims(count, func(i int) common.Address {
+		key := deriveTestKey(seed, i)
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		keys[address] = key
+		testKeyAddresses.Store(address, struct{}{})
+		return address
+	})
+	return claims, keys
+}
+
+// deriveTestKey takes keccak256(domain || seed || i) as the private key
+// scalar, hashing again until it is a valid secp256k1 key
+func deriveTestKey(seed int64, i int) *ecdsa.PrivateKey {
+	preimage := make([]byte, len(testKeyDomain)+16)
+	copy(preimage, testKeyDomain)
+	binary.BigEndian.PutUint64(preimage[len(testKeyDomain):], uint64(seed))
+	binary.BigEndian.PutUint64(preimage[len(testKeyDomain)+8:], uint64(i))
+
+	scalar := crypto.Keccak256(preimage)
+	for {
+		// Zero and scalars at or above the curve order are rejected
+		if key, err := crypto.ToECDSA(scalar); err == nil {
+			return key
+		}
+		scalar = crypto.Keccak256(scalar)
+	}
+}
+
+// IsTestKeyAddress reports whether address belongs to a key returned by
+// GenerateTestDataWithKeys in this process
+func IsTestKeyAddress(address common.Address) bool {
+	_, ok := testKeyAddresses.Load(address)
+	return ok
+}
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// maxReportedFailures bounds the addresses named when imported proofs do not
+// verify
+const maxReportedFailures = 5
+
+// uniswapClaims is the claims.json written by Uniswap's merkle-distributor
+// generator. Amounts are hex strings.
+type uniswapClaims struct {
+	MerkleRoot string                  `json:"merkleRoot"`
+	TokenTotal string                  `json:"tokenTotal"`
+	Claims     map[string]uniswapClaim `json:"claims"`
+}
+
+type uniswapClaim struct {
+	Index  uint64   `json:"index"`
+	Amount string   `json:"amount"`
+	Proof  []string `json:"proof"`
+}
+
+// UniswapTreeOptions returns the tree options that verify proofs from
+// Uniswap's generator: index-first leaves, sorted pairs and odd nodes
+// promoted. The generator orders leaves by hash, so its roots cannot be
+// rebuilt with NewMerkleTreeWithOptions; serve the imported proofs instead.
+func UniswapTreeOptions() merkle.TreeOptions {
+	opts := merkle.DefaultTreeOptions()
+	opts.IndexFirst = true
+	opts.SortOrder = merkle.SortByIndex
+	opts.OddLeafPolicy = merkle.Promote
+	return opts
+}
+
+// ImportUniswapFormat reads a Uniswap-style claims.json and checks every
+// embedded proof against its root with the MerkleDistributor encoding. The
+// root is returned as lowercase hex, as the proofs record it, the claims
+// in index order and the proofs keyed by checksummed address with decimal
+// amounts.
+func ImportUniswapFormat(r io.Reader) (string, []merkle.AirdropClaim, map[string]*merkle.MerkleProof, error) {
+	var file uniswapClaims
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return "", nil, nil, fmt.Errorf("failed to decode claims file: %w", err)
+	}
+	root, err := merkle.DecodeHash(file.MerkleRoot)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("invalid merkleRoot: %q", file.MerkleRoot)
+	}
+	if len(file.Claims) == 0 {
+		return "", nil, nil, fmt.Errorf("claims file: %w", merkle.ErrEmptyClaims)
+	}
+
+	claims := make([]merkle.AirdropClaim, 0, len(file.Claims))
+	proofs := make(map[string]*merkle.MerkleProof, len(file.Claims))
+	rootHex := merkle.EncodeHash(root)
+
+	// Map order is random; sorted, the same file fails the same way
+	keys := make([]string, 0, len(file.Claims))
+	for addr := range file.Claims {
+		keys = append(keys, addr)
+	}
+	sort.Strings(keys)
+	for _, addr := range keys {
+		entry := file.Claims[addr]
+		if !common.IsHexAddress(addr) {
+			return "", nil, nil, fmt.Errorf("invalid address: %s", addr)
+		}
+		if entry.Index > math.MaxUint32 {
+			return "", nil, nil, fmt.Errorf("index %d of %s does not fit in 32 bits", entry.Index, addr)
+		}
+		amount, err := parseHexAmount(entry.Amount)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("invalid amount for %s: %w", addr, err)
+		}
+
+		address := common.HexToAddress(addr)
+		if _, exists := proofs[address.Hex()]; exists {
+			return "", nil, nil, &DuplicateAddressError{Index: len(claims), Address: address}
+		}
+		if entry.Proof == nil {
+			// A single claim's proof is empty; serve it as [], not null
+			entry.Proof = []string{}
+		}
+		claims = append(claims, merkle.AirdropClaim{Address: address, Amount: amount, Index: uint32(entry.Index)})
+		proofs[address.Hex()] = &merkle.MerkleProof{
+			Proof:  entry.Proof,
+			Index:  uint32(entry.Index),
+			Amount: amount.String(),
+			Root:   rootHex,
+		}
+	}
+	sort.Slice(claims, func(i, j int) bool { return claims[i].Index < claims[j].Index })
+	if err := ValidateIndices(claims); err != nil {
+		return "", nil, nil, err
+	}
+
+	if file.TokenTotal != "" {
+		total, err := parseHexAmount(file.TokenTotal)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("invalid tokenTotal: %w", err)
+		}
+		if sum := merkle.TotalAmount(claims); sum.Cmp(total) != 0 {
+			return "", nil, nil, fmt.Errorf("claims add up to %s, but tokenTotal is %s", sum, total)
+		}
+	}
+
+	var failed []string
+	opts := UniswapTreeOptions()
+	for _, claim := range claims {
+		valid, err := merkle.VerifyProof(root, claim, proofs[claim.Address.Hex()].Proof, opts)
+		if err != nil || !valid {
+			failed = append(failed, claim.Address.Hex())
+		}
+	}
+	if len(failed) > 0 {
+		named := failed
+		if len(named) > maxReportedFailures {
+			named = named[:maxReportedFailures]
+		}
+		return "", nil, nil, fmt.Errorf("%d of %d proofs do not verify against %s with the MerkleDistributor encoding (%s)",
+			len(failed), len(claims), rootHex, strings.Join(named, ", "))
+	}
+
+	return rootHex, claims, proofs, nil
+}
+
+// parseHexAmount parses a 0x-prefixed hex amount of at most 32 bytes;
+// leading zeros are allowed
+func parseHexAmount(s string) (*big.Int, error) {
+	amount, err := merkle.DecodeUint256(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex amount: %w", err)
+	}
+	return amount, nil
+}
@@ -0,0 +1,96 @@
+// pkg/data/units.go
+package data
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultTokenDecimals is the decimals of tokens that don't say otherwise,
+// as for ether
+const DefaultTokenDecimals = 18
+
+// MaxTokenDecimals is the most decimals a token can have and still hold one
+// whole token in a uint256
+const MaxTokenDecimals = 77
+
+// AmountUnit selects the unit amounts are displayed in. Claims, proofs and
+// contracts always use base units (wei); units only apply to display.
+type AmountUnit int
+
+const (
+	// UnitWei displays base units as they are
+	UnitWei AmountUnit = iota
+	// UnitGwei displays amounts in units of 10^9
+	UnitGwei
+	// UnitEther displays amounts in units of 10^18
+	UnitEther
+	// UnitToken displays amounts in whole tokens of the token's decimals
+	UnitToken
+)
+
+var amountUnitNames = map[AmountUnit]string{
+	UnitWei:   "wei",
+	UnitGwei:  "gwei",
+	UnitEther: "ether",
+	UnitToken: "token",
+}
+
+func (u AmountUnit) String() string {
+	if name, ok := amountUnitNames[u]; ok {
+		return name
+	}
+	return fmt.Sprintf("AmountUnit(%d)", int(u))
+}
+
+// ParseAmountUnit parses a unit name as returned by String
+func ParseAmountUnit(name string) (AmountUnit, error) {
+	for u, n := range amountUnitNames {
+		if n == name {
+			return u, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown unit: %q (expected wei, gwei, ether or token)", name)
+}
+
+// Decimals returns the decimals of unit u, tokenDecimals for UnitToken
+func (u AmountUnit) Decimals(tokenDecimals int) int {
+	switch u {
+	case UnitGwei:
+		return 9
+	case UnitEther:
+		return 18
+	case UnitToken:
+		return tokenDecimals
+	}
+	return 0
+}
+
+// Format writes amount, in base units, in unit u; see FormatUnits
+func (u AmountUnit) Format(amount *big.Int, tokenDecimals int) string {
+	return FormatUnits(amount, u.Decimals(tokenDecimals))
+}
+
+// FormatUnits writes amount base units as a decimal number with up to
+// decimals fractional digits, exactly and without trailing zeros: 1500000
+// with 6 decimals is "1.5" and 1 with 18 is "0.000000000000000001"
+func FormatUnits(amount *big.Int, decimals int) string {
+	digits := new(big.Int).Abs(amount).String()
+	sign := ""
+	if amount.Sign() < 0 {
+		sign = "-"
+	}
+	if decimals <= 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
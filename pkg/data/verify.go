@@ -0,0 +1,153 @@
+// pkg/data/verify.go
+package data
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ClaimStatus is the outcome of CheckClaim. Its values are the exit codes
+// of the CLI's verify subcommand.
+type ClaimStatus int
+
+const (
+	// ClaimValid means the proof verifies for the amount
+	ClaimValid ClaimStatus = iota
+	// ClaimInvalid means the proof doesn't verify for the amount
+	ClaimInvalid
+	// ClaimNotFound means the address has no proof
+	ClaimNotFound
+)
+
+var claimStatusNames = map[ClaimStatus]string{
+	ClaimValid:    "valid",
+	ClaimInvalid:  "invalid",
+	ClaimNotFound: "not-found",
+}
+
+func (s ClaimStatus) String() string {
+	if name, ok := claimStatusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("ClaimStatus(%d)", int(s))
+}
+
+// MarshalText encodes the status by name
+func (s ClaimStatus) MarshalText() ([]byte, error) {
+	if _, ok := claimStatusNames[s]; !ok {
+		return nil, fmt.Errorf("unknown claim status: %d", int(s))
+	}
+	return []byte(s.String()), nil
+}
+
+// ClaimCheck is the result of CheckClaim
+type ClaimCheck struct {
+	Status     ClaimStatus `json:"status"`
+	Address    string      `json:"address"`
+	MerkleRoot string      `json:"merkleRoot"`
+	Amount     string      `json:"amount,omitempty"`     // Checked amount
+	TreeAmount string      `json:"treeAmount,omitempty"` // Amount the proof is for
+	Index      *uint32     `json:"index,omitempty"`
+	Proof      []string    `json:"proof,omitempty"`
+
+	// AmountMismatch is set when the checked amount differs from the
+	// proof's, the usual reason a user's claim reverts
+	AmountMismatch bool   `json:"amountMismatch,omitempty"`
+	Reason         string `json:"reason,omitempty"` // Why the claim is invalid
+
+	Trace *merkle.VerificationTrace `json:"trace,omitempty"` // Set from TraceClaim
+}
+
+// CheckClaim checks address's claim of amount against the proof set and its
+// root, as a distributor's claim call would. A nil amount checks the amount
+// the proof is for.
+func CheckClaim(root string, proofs *merkle.ProofSet, address common.Address, amount *big.Int) (*ClaimCheck, error) {
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+
+	check := &ClaimCheck{Address: address.Hex(), MerkleRoot: root}
+	proof := findProof(proofs, address)
+	if proof == nil {
+		check.Status = ClaimNotFound
+		if amount != nil {
+			check.Amount = amount.String()
+		}
+		return check, nil
+	}
+	check.Index, check.Proof, check.TreeAmount = &proof.Index, proof.Proof, proof.Amount
+
+	treeAmount, ok := new(big.Int).SetString(proof.Amount, 10)
+	if !ok {
+		check.Status, check.Reason = ClaimInvalid, fmt.Sprintf("the proof's amount %q is not an integer", proof.Amount)
+		return check, nil
+	}
+	if amount == nil {
+		amount = treeAmount
+	}
+	check.Amount = amount.String()
+
+	opts := proofs.Metadata.Options()
+	verify := func(amount *big.Int) (bool, error) {
+		claim := merkle.AirdropClaim{Address: address, Amount: amount, Index: proof.Index}
+		return merkle.VerifyMerkleProof(rootBytes, claim, proof, opts)
+	}
+	valid, err := verify(amount)
+	switch {
+	case err != nil:
+		check.Status, check.Reason = ClaimInvalid, err.Error()
+	case valid:
+		check.Status = ClaimValid
+	case amount.Cmp(treeAmount) != 0:
+		check.Status, check.AmountMismatch = ClaimInvalid, true
+		if treeValid, _ := verify(treeAmount); treeValid {
+			check.Reason = fmt.Sprintf("the tree holds %s for this address, not %s", treeAmount, amount)
+		} else {
+			check.Reason = fmt.Sprintf("the tree holds %s for this address, not %s, and the proof does not verify for either", treeAmount, amount)
+		}
+	default:
+		check.Status, check.Reason = ClaimInvalid, "the proof does not verify against the root"
+	}
+	return check, nil
+}
+
+// TraceClaim traces address's claim of amount like CheckClaim, the proof
+// set standing in for the tree. It returns nil when the address has no
+// proof.
+func TraceClaim(root string, proofs *merkle.ProofSet, address common.Address, amount *big.Int) (*merkle.VerificationTrace, error) {
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	proof := findProof(proofs, address)
+	if proof == nil {
+		return nil, nil
+	}
+	if amount == nil {
+		var ok bool
+		if amount, ok = new(big.Int).SetString(proof.Amount, 10); !ok {
+			return nil, fmt.Errorf("the proof's amount %q is not an integer", proof.Amount)
+		}
+	}
+	claim := merkle.AirdropClaim{Address: address, Amount: amount, Index: proof.Index}
+	return merkle.TraceMerkleProof(rootBytes, claim, proof, proofs.Metadata.Options(), &merkle.TraceReference{Proof: proof})
+}
+
+// findProof returns the proof keyed by address in any case, or nil
+func findProof(proofs *merkle.ProofSet, address common.Address) *merkle.MerkleProof {
+	if proof, ok := proofs.Proofs[address.Hex()]; ok {
+		return proof
+	}
+	for key, proof := range proofs.Proofs {
+		if strings.EqualFold(key, address.Hex()) {
+			return proof
+		}
+	}
+	return nil
+}
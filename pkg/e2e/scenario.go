@@ -0,0 +1,263 @@
+// Package e2e runs the whole airdrop pipeline against a simulated chain:
+// generate claims, build the tree, deploy and fund a MerkleDistributor,
+// fetch a proof from the in-process API server and claim it on-chain from
+// the claimant's own account.
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// Stage identifies the part of the pipeline a scenario failure came from
+type Stage string
+
+const (
+	StageTree  Stage = "tree"
+	StageProof Stage = "proof"
+	StageChain Stage = "chain"
+)
+
+// StageError reports which stage of a scenario broke
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s stage failed: %v", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func stageErr(stage Stage, format string, args ...interface{}) error {
+	return &StageError{Stage: stage, Err: fmt.Errorf(format, args...)}
+}
+
+// Result summarizes a successful scenario run
+type Result struct {
+	MerkleRoot  string
+	TotalClaims int
+	Token       common.Address
+	Distributor common.Address
+	Claimant    common.Address
+	Amount      *big.Int
+	Balance     *big.Int
+	ClaimGas    uint64
+}
+
+// RunScenario runs the pipeline with numClaims claims and fails t with the
+// stage that broke
+func RunScenario(t *testing.T, numClaims int) *Result {
+	t.Helper()
+
+	result, err := Run(numClaims)
+	if err != nil {
+		t.Fatalf("e2e scenario with %d claims: %v", numClaims, err)
+	}
+	return result
+}
+
+// Run executes the pipeline with numClaims generated claims
+func Run(numClaims int) (*Result, error) {
+	ctx := context.Background()
+
+	// Tree: build it and every proof the API will serve. Claimants have
+	// seeded keys so they can send their own claims.
+	claims, claimantKeys := data.GenerateTestDataWithKeys(numClaims, 1)
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+	if err != nil {
+		return nil, stageErr(StageTree, "failed to build tree: %w", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		return nil, stageErr(StageTree, "failed to generate proofs: %w", err)
+	}
+
+	total := new(big.Int)
+	for _, claim := range tree.Claims {
+		total.Add(total, claim.Amount)
+	}
+
+	// Chain: deploy the token and distributor, then fund the distributor
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to generate deployer key: %w", err)
+	}
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+
+	// The claimant pays for its claim transaction
+	claim := tree.Claims[len(tree.Claims)/2]
+	claimantKey := claimantKeys[claim.Address]
+
+	ether := new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		deployer:      {Balance: ether},
+		claim.Address: {Balance: ether},
+	})
+	defer backend.Close()
+	eth := backend.Client()
+
+	chainID, err := eth.ChainID(ctx)
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to read chain ID: %w", err)
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to create transactor: %w", err)
+	}
+
+	tokenAddr, tx, token, err := DeployTestToken(auth, eth, total)
+	if err != nil {
+		return nil, stageErr(StageChain, "%w", err)
+	}
+	if err := commitAndCheck(ctx, backend, tx); err != nil {
+		return nil, stageErr(StageChain, "token deployment: %w", err)
+	}
+
+	var root [32]byte
+	copy(root[:], tree.Root.Hash)
+
+	client := contract.NewContractClientWithBackend(eth, key, chainID)
+	distributorAddr, err := client.DeployAirdrop(tokenAddr, root)
+	if err != nil {
+		return nil, stageErr(StageChain, "%w", err)
+	}
+	backend.Commit()
+
+	distributor, err := contract.NewMerkleDistributor(distributorAddr, eth)
+	if err != nil {
+		return nil, stageErr(StageChain, "%w", err)
+	}
+	onChainRoot, err := distributor.MerkleRoot(nil)
+	if err != nil {
+		return nil, stageErr(StageChain, "distributor deployment: %w", err)
+	}
+	if onChainRoot != root {
+		return nil, stageErr(StageChain, "distributor root 0x%x does not match tree root %s", onChainRoot, tree.GetRootHash())
+	}
+
+	tx, err = token.Transfer(auth, distributorAddr, total)
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to fund distributor: %w", err)
+	}
+	if err := commitAndCheck(ctx, backend, tx); err != nil {
+		return nil, stageErr(StageChain, "funding distributor: %w", err)
+	}
+
+	// Proof: fetch through the HTTP API exactly like a frontend would
+	apiServer, err := api.NewAPIServer(tree, proofs)
+	if err != nil {
+		return nil, stageErr(StageProof, "%w", err)
+	}
+	server := httptest.NewServer(apiServer.SetupRoutes())
+	defer server.Close()
+
+	proof, err := fetchProof(server.URL, claim.Address)
+	if err != nil {
+		return nil, stageErr(StageProof, "%w", err)
+	}
+	if proof.Amount != claim.Amount.String() || proof.Index != claim.Index {
+		return nil, stageErr(StageProof, "API returned amount %s index %d, expected %s index %d",
+			proof.Amount, proof.Index, claim.Amount, claim.Index)
+	}
+	proofArgs, err := contract.DecodeProof(proof.Proof)
+	if err != nil {
+		return nil, stageErr(StageProof, "%w", err)
+	}
+
+	// Chain: the claimant submits the claim and is paid
+	claimant := contract.NewContractClientWithBackend(eth, claimantKey, chainID)
+	tx, err = claimant.Claim(distributorAddr, proof.Index, claim.Address, claim.Amount, proofArgs)
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to submit claim: %w", err)
+	}
+	backend.Commit()
+
+	receipt, err := eth.TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to fetch claim receipt: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, stageErr(StageChain, "claim transaction reverted (leaf encoding or proof rejected by the contract)")
+	}
+
+	balance, err := token.BalanceOf(nil, claim.Address)
+	if err != nil {
+		return nil, stageErr(StageChain, "failed to read claimant balance: %w", err)
+	}
+	if balance.Cmp(claim.Amount) != 0 {
+		return nil, stageErr(StageChain, "claimant balance %s, expected %s", balance, claim.Amount)
+	}
+
+	return &Result{
+		MerkleRoot:  tree.GetRootHash(),
+		TotalClaims: len(tree.Claims),
+		Token:       tokenAddr,
+		Distributor: distributorAddr,
+		Claimant:    claim.Address,
+		Amount:      claim.Amount,
+		Balance:     balance,
+		ClaimGas:    receipt.GasUsed,
+	}, nil
+}
+
+// commitAndCheck mines a block and ensures tx succeeded
+func commitAndCheck(ctx context.Context, backend *simulated.Backend, tx *types.Transaction) error {
+	backend.Commit()
+
+	receipt, err := backend.Client().TransactionReceipt(ctx, tx.Hash())
+	if err != nil {
+		return fmt.Errorf("failed to fetch receipt: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("transaction %s reverted", tx.Hash().Hex())
+	}
+	return nil
+}
+
+// fetchProof requests the proof for address from the API server at baseURL
+func fetchProof(baseURL string, address common.Address) (*merkle.MerkleProof, error) {
+	resp, err := http.Get(baseURL + "/api/proof/" + address.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to request proof: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proof request returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Proof  []string `json:"proof"`
+		Amount string   `json:"amount"`
+		Index  uint32   `json:"index"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode proof response: %w", err)
+	}
+
+	return &merkle.MerkleProof{
+		Proof:  body.Proof,
+		Index:  body.Index,
+		Amount: body.Amount,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package e2e
+
+import (
+	_ "embed"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// ABI and bytecode produced by scripts/compile-bindings.js from
+// contracts/test/TestToken.sol
+var (
+	//go:embed TestToken.abi
+	testTokenABI string
+
+	//go:embed TestToken.bin
+	testTokenBin string
+)
+
+// TestToken is a binding to the ERC-20 used to fund test distributors
+type TestToken struct {
+	Address  common.Address
+	contract *bind.BoundContract
+}
+
+// DeployTestToken deploys a TestToken minting initialSupply to the deployer
+func DeployTestToken(auth *bind.TransactOpts, backend bind.ContractBackend, initialSupply *big.Int) (common.Address, *types.Transaction, *TestToken, error) {
+	parsed, err := abi.JSON(strings.NewReader(testTokenABI))
+	if err != nil {
+		return common.Address{}, nil, nil, fmt.Errorf("failed to parse token ABI: %w", err)
+	}
+
+	bytecode := common.FromHex(strings.TrimSpace(testTokenBin))
+	address, tx, contract, err := bind.DeployContract(auth, parsed, bytecode, backend, initialSupply)
+	if err != nil {
+		return common.Address{}, nil, nil, fmt.Errorf("failed to deploy token: %w", err)
+	}
+
+	return address, tx, &TestToken{Address: address, contract: contract}, nil
+}
+
+// BalanceOf returns the token balance of account
+func (t *TestToken) BalanceOf(opts *bind.CallOpts, account common.Address) (*big.Int, error) {
+	var out []interface{}
+	if err := t.contract.Call(opts, &out, "balanceOf", account); err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+// Transfer sends amount tokens from the transactor to to
+func (t *TestToken) Transfer(auth *bind.TransactOpts, to common.Address, amount *big.Int) (*types.Transaction, error) {
+	return t.contract.Transact(auth, "transfer", to, amount)
+}
@@ -0,0 +1,666 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: airdrop.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetRootRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRootRequest) Reset() {
+	*x = GetRootRequest{}
+	mi := &file_airdrop_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRootRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRootRequest) ProtoMessage() {}
+
+func (x *GetRootRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRootRequest.ProtoReflect.Descriptor instead.
+func (*GetRootRequest) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{0}
+}
+
+type GetRootResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MerkleRoot    []byte                 `protobuf:"bytes,1,opt,name=merkle_root,json=merkleRoot,proto3" json:"merkle_root,omitempty"`
+	IncludeIndex  bool                   `protobuf:"varint,2,opt,name=include_index,json=includeIndex,proto3" json:"include_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRootResponse) Reset() {
+	*x = GetRootResponse{}
+	mi := &file_airdrop_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRootResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRootResponse) ProtoMessage() {}
+
+func (x *GetRootResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRootResponse.ProtoReflect.Descriptor instead.
+func (*GetRootResponse) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetRootResponse) GetMerkleRoot() []byte {
+	if x != nil {
+		return x.MerkleRoot
+	}
+	return nil
+}
+
+func (x *GetRootResponse) GetIncludeIndex() bool {
+	if x != nil {
+		return x.IncludeIndex
+	}
+	return false
+}
+
+type GetProofRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       []byte                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProofRequest) Reset() {
+	*x = GetProofRequest{}
+	mi := &file_airdrop_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProofRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProofRequest) ProtoMessage() {}
+
+func (x *GetProofRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProofRequest.ProtoReflect.Descriptor instead.
+func (*GetProofRequest) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetProofRequest) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+type GetProofByIndexRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         uint32                 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetProofByIndexRequest) Reset() {
+	*x = GetProofByIndexRequest{}
+	mi := &file_airdrop_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetProofByIndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetProofByIndexRequest) ProtoMessage() {}
+
+func (x *GetProofByIndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetProofByIndexRequest.ProtoReflect.Descriptor instead.
+func (*GetProofByIndexRequest) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetProofByIndexRequest) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+type Proof struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       []byte                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Amount        string                 `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Index         uint32                 `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
+	Proof         [][]byte               `protobuf:"bytes,4,rep,name=proof,proto3" json:"proof,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Proof) Reset() {
+	*x = Proof{}
+	mi := &file_airdrop_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Proof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Proof) ProtoMessage() {}
+
+func (x *Proof) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Proof.ProtoReflect.Descriptor instead.
+func (*Proof) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Proof) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *Proof) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *Proof) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Proof) GetProof() [][]byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+type VerifyProofRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Address []byte                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Amount  string                 `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Proof   [][]byte               `protobuf:"bytes,3,rep,name=proof,proto3" json:"proof,omitempty"`
+	// Defaults to the index recorded for the address
+	Index         *uint32 `protobuf:"varint,4,opt,name=index,proto3,oneof" json:"index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyProofRequest) Reset() {
+	*x = VerifyProofRequest{}
+	mi := &file_airdrop_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyProofRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyProofRequest) ProtoMessage() {}
+
+func (x *VerifyProofRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyProofRequest.ProtoReflect.Descriptor instead.
+func (*VerifyProofRequest) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *VerifyProofRequest) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *VerifyProofRequest) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *VerifyProofRequest) GetProof() [][]byte {
+	if x != nil {
+		return x.Proof
+	}
+	return nil
+}
+
+func (x *VerifyProofRequest) GetIndex() uint32 {
+	if x != nil && x.Index != nil {
+		return *x.Index
+	}
+	return 0
+}
+
+type VerifyProofResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Valid         bool                   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyProofResponse) Reset() {
+	*x = VerifyProofResponse{}
+	mi := &file_airdrop_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyProofResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyProofResponse) ProtoMessage() {}
+
+func (x *VerifyProofResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyProofResponse.ProtoReflect.Descriptor instead.
+func (*VerifyProofResponse) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *VerifyProofResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_airdrop_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{7}
+}
+
+type GetStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TotalClaims   uint64                 `protobuf:"varint,1,opt,name=total_claims,json=totalClaims,proto3" json:"total_claims,omitempty"`
+	TotalProofs   uint64                 `protobuf:"varint,2,opt,name=total_proofs,json=totalProofs,proto3" json:"total_proofs,omitempty"`
+	MerkleRoot    []byte                 `protobuf:"bytes,3,opt,name=merkle_root,json=merkleRoot,proto3" json:"merkle_root,omitempty"`
+	ProofDepth    uint32                 `protobuf:"varint,4,opt,name=proof_depth,json=proofDepth,proto3" json:"proof_depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsResponse) Reset() {
+	*x = GetStatsResponse{}
+	mi := &file_airdrop_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsResponse) ProtoMessage() {}
+
+func (x *GetStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsResponse.ProtoReflect.Descriptor instead.
+func (*GetStatsResponse) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetStatsResponse) GetTotalClaims() uint64 {
+	if x != nil {
+		return x.TotalClaims
+	}
+	return 0
+}
+
+func (x *GetStatsResponse) GetTotalProofs() uint64 {
+	if x != nil {
+		return x.TotalProofs
+	}
+	return 0
+}
+
+func (x *GetStatsResponse) GetMerkleRoot() []byte {
+	if x != nil {
+		return x.MerkleRoot
+	}
+	return nil
+}
+
+func (x *GetStatsResponse) GetProofDepth() uint32 {
+	if x != nil {
+		return x.ProofDepth
+	}
+	return 0
+}
+
+type ListClaimsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// First claim index to return
+	StartIndex uint32 `protobuf:"varint,1,opt,name=start_index,json=startIndex,proto3" json:"start_index,omitempty"`
+	// Maximum number of claims to return; 0 means all
+	Limit uint32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Leave proof hashes empty when false
+	IncludeProofs bool `protobuf:"varint,3,opt,name=include_proofs,json=includeProofs,proto3" json:"include_proofs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListClaimsRequest) Reset() {
+	*x = ListClaimsRequest{}
+	mi := &file_airdrop_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListClaimsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListClaimsRequest) ProtoMessage() {}
+
+func (x *ListClaimsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_airdrop_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListClaimsRequest.ProtoReflect.Descriptor instead.
+func (*ListClaimsRequest) Descriptor() ([]byte, []int) {
+	return file_airdrop_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListClaimsRequest) GetStartIndex() uint32 {
+	if x != nil {
+		return x.StartIndex
+	}
+	return 0
+}
+
+func (x *ListClaimsRequest) GetLimit() uint32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListClaimsRequest) GetIncludeProofs() bool {
+	if x != nil {
+		return x.IncludeProofs
+	}
+	return false
+}
+
+var File_airdrop_proto protoreflect.FileDescriptor
+
+const file_airdrop_proto_rawDesc = "" +
+	"\n" +
+	"\rairdrop.proto\x12\n" +
+	"airdrop.v1\"\x10\n" +
+	"\x0eGetRootRequest\"W\n" +
+	"\x0fGetRootResponse\x12\x1f\n" +
+	"\vmerkle_root\x18\x01 \x01(\fR\n" +
+	"merkleRoot\x12#\n" +
+	"\rinclude_index\x18\x02 \x01(\bR\fincludeIndex\"+\n" +
+	"\x0fGetProofRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\fR\aaddress\".\n" +
+	"\x16GetProofByIndexRequest\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\rR\x05index\"e\n" +
+	"\x05Proof\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\fR\aaddress\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\tR\x06amount\x12\x14\n" +
+	"\x05index\x18\x03 \x01(\rR\x05index\x12\x14\n" +
+	"\x05proof\x18\x04 \x03(\fR\x05proof\"\x81\x01\n" +
+	"\x12VerifyProofRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\fR\aaddress\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\tR\x06amount\x12\x14\n" +
+	"\x05proof\x18\x03 \x03(\fR\x05proof\x12\x19\n" +
+	"\x05index\x18\x04 \x01(\rH\x00R\x05index\x88\x01\x01B\b\n" +
+	"\x06_index\"+\n" +
+	"\x13VerifyProofResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\"\x11\n" +
+	"\x0fGetStatsRequest\"\x9a\x01\n" +
+	"\x10GetStatsResponse\x12!\n" +
+	"\ftotal_claims\x18\x01 \x01(\x04R\vtotalClaims\x12!\n" +
+	"\ftotal_proofs\x18\x02 \x01(\x04R\vtotalProofs\x12\x1f\n" +
+	"\vmerkle_root\x18\x03 \x01(\fR\n" +
+	"merkleRoot\x12\x1f\n" +
+	"\vproof_depth\x18\x04 \x01(\rR\n" +
+	"proofDepth\"q\n" +
+	"\x11ListClaimsRequest\x12\x1f\n" +
+	"\vstart_index\x18\x01 \x01(\rR\n" +
+	"startIndex\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\rR\x05limit\x12%\n" +
+	"\x0einclude_proofs\x18\x03 \x01(\bR\rincludeProofs2\xac\x03\n" +
+	"\aAirdrop\x12B\n" +
+	"\aGetRoot\x12\x1a.airdrop.v1.GetRootRequest\x1a\x1b.airdrop.v1.GetRootResponse\x12:\n" +
+	"\bGetProof\x12\x1b.airdrop.v1.GetProofRequest\x1a\x11.airdrop.v1.Proof\x12H\n" +
+	"\x0fGetProofByIndex\x12\".airdrop.v1.GetProofByIndexRequest\x1a\x11.airdrop.v1.Proof\x12N\n" +
+	"\vVerifyProof\x12\x1e.airdrop.v1.VerifyProofRequest\x1a\x1f.airdrop.v1.VerifyProofResponse\x12E\n" +
+	"\bGetStats\x12\x1b.airdrop.v1.GetStatsRequest\x1a\x1c.airdrop.v1.GetStatsResponse\x12@\n" +
+	"\n" +
+	"ListClaims\x12\x1d.airdrop.v1.ListClaimsRequest\x1a\x11.airdrop.v1.Proof0\x01B\x1cZ\x1amerkle-airdrop/pkg/grpcapib\x06proto3"
+
+var (
+	file_airdrop_proto_rawDescOnce sync.Once
+	file_airdrop_proto_rawDescData []byte
+)
+
+func file_airdrop_proto_rawDescGZIP() []byte {
+	file_airdrop_proto_rawDescOnce.Do(func() {
+		file_airdrop_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_airdrop_proto_rawDesc), len(file_airdrop_proto_rawDesc)))
+	})
+	return file_airdrop_proto_rawDescData
+}
+
+var file_airdrop_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_airdrop_proto_goTypes = []any{
+	(*GetRootRequest)(nil),         // 0: airdrop.v1.GetRootRequest
+	(*GetRootResponse)(nil),        // 1: airdrop.v1.GetRootResponse
+	(*GetProofRequest)(nil),        // 2: airdrop.v1.GetProofRequest
+	(*GetProofByIndexRequest)(nil), // 3: airdrop.v1.GetProofByIndexRequest
+	(*Proof)(nil),                  // 4: airdrop.v1.Proof
+	(*VerifyProofRequest)(nil),     // 5: airdrop.v1.VerifyProofRequest
+	(*VerifyProofResponse)(nil),    // 6: airdrop.v1.VerifyProofResponse
+	(*GetStatsRequest)(nil),        // 7: airdrop.v1.GetStatsRequest
+	(*GetStatsResponse)(nil),       // 8: airdrop.v1.GetStatsResponse
+	(*ListClaimsRequest)(nil),      // 9: airdrop.v1.ListClaimsRequest
+}
+var file_airdrop_proto_depIdxs = []int32{
+	0, // 0: airdrop.v1.Airdrop.GetRoot:input_type -> airdrop.v1.GetRootRequest
+	2, // 1: airdrop.v1.Airdrop.GetProof:input_type -> airdrop.v1.GetProofRequest
+	3, // 2: airdrop.v1.Airdrop.GetProofByIndex:input_type -> airdrop.v1.GetProofByIndexRequest
+	5, // 3: airdrop.v1.Airdrop.VerifyProof:input_type -> airdrop.v1.VerifyProofRequest
+	7, // 4: airdrop.v1.Airdrop.GetStats:input_type -> airdrop.v1.GetStatsRequest
+	9, // 5: airdrop.v1.Airdrop.ListClaims:input_type -> airdrop.v1.ListClaimsRequest
+	1, // 6: airdrop.v1.Airdrop.GetRoot:output_type -> airdrop.v1.GetRootResponse
+	4, // 7: airdrop.v1.Airdrop.GetProof:output_type -> airdrop.v1.Proof
+	4, // 8: airdrop.v1.Airdrop.GetProofByIndex:output_type -> airdrop.v1.Proof
+	6, // 9: airdrop.v1.Airdrop.VerifyProof:output_type -> airdrop.v1.VerifyProofResponse
+	8, // 10: airdrop.v1.Airdrop.GetStats:output_type -> airdrop.v1.GetStatsResponse
+	4, // 11: airdrop.v1.Airdrop.ListClaims:output_type -> airdrop.v1.Proof
+	6, // [6:12] is the sub-list for method output_type
+	0, // [0:6] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_airdrop_proto_init() }
+func file_airdrop_proto_init() {
+	if File_airdrop_proto != nil {
+		return
+	}
+	file_airdrop_proto_msgTypes[5].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_airdrop_proto_rawDesc), len(file_airdrop_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_airdrop_proto_goTypes,
+		DependencyIndexes: file_airdrop_proto_depIdxs,
+		MessageInfos:      file_airdrop_proto_msgTypes,
+	}.Build()
+	File_airdrop_proto = out.File
+	file_airdrop_proto_goTypes = nil
+	file_airdrop_proto_depIdxs = nil
+}
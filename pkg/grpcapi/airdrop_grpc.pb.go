@@ -0,0 +1,333 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: airdrop.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Airdrop_GetRoot_FullMethodName         = "/airdrop.v1.Airdrop/GetRoot"
+	Airdrop_GetProof_FullMethodName        = "/airdrop.v1.Airdrop/GetProof"
+	Airdrop_GetProofByIndex_FullMethodName = "/airdrop.v1.Airdrop/GetProofByIndex"
+	Airdrop_VerifyProof_FullMethodName     = "/airdrop.v1.Airdrop/VerifyProof"
+	Airdrop_GetStats_FullMethodName        = "/airdrop.v1.Airdrop/GetStats"
+	Airdrop_ListClaims_FullMethodName      = "/airdrop.v1.Airdrop/ListClaims"
+)
+
+// AirdropClient is the client API for Airdrop service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Airdrop serves Merkle roots and proofs. Addresses and hashes are raw
+// bytes (20 and 32 bytes); amounts are decimal strings.
+type AirdropClient interface {
+	// GetRoot returns the Merkle root and leaf encoding
+	GetRoot(ctx context.Context, in *GetRootRequest, opts ...grpc.CallOption) (*GetRootResponse, error)
+	// GetProof returns the proof for an address
+	GetProof(ctx context.Context, in *GetProofRequest, opts ...grpc.CallOption) (*Proof, error)
+	// GetProofByIndex returns the proof for a claim index
+	GetProofByIndex(ctx context.Context, in *GetProofByIndexRequest, opts ...grpc.CallOption) (*Proof, error)
+	// VerifyProof checks a proof against the served root
+	VerifyProof(ctx context.Context, in *VerifyProofRequest, opts ...grpc.CallOption) (*VerifyProofResponse, error)
+	// GetStats returns airdrop statistics
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	// ListClaims streams claims in index order
+	ListClaims(ctx context.Context, in *ListClaimsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Proof], error)
+}
+
+type airdropClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAirdropClient(cc grpc.ClientConnInterface) AirdropClient {
+	return &airdropClient{cc}
+}
+
+func (c *airdropClient) GetRoot(ctx context.Context, in *GetRootRequest, opts ...grpc.CallOption) (*GetRootResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRootResponse)
+	err := c.cc.Invoke(ctx, Airdrop_GetRoot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *airdropClient) GetProof(ctx context.Context, in *GetProofRequest, opts ...grpc.CallOption) (*Proof, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Proof)
+	err := c.cc.Invoke(ctx, Airdrop_GetProof_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *airdropClient) GetProofByIndex(ctx context.Context, in *GetProofByIndexRequest, opts ...grpc.CallOption) (*Proof, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Proof)
+	err := c.cc.Invoke(ctx, Airdrop_GetProofByIndex_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *airdropClient) VerifyProof(ctx context.Context, in *VerifyProofRequest, opts ...grpc.CallOption) (*VerifyProofResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VerifyProofResponse)
+	err := c.cc.Invoke(ctx, Airdrop_VerifyProof_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *airdropClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, Airdrop_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *airdropClient) ListClaims(ctx context.Context, in *ListClaimsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Proof], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Airdrop_ServiceDesc.Streams[0], Airdrop_ListClaims_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListClaimsRequest, Proof]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Airdrop_ListClaimsClient = grpc.ServerStreamingClient[Proof]
+
+// AirdropServer is the server API for Airdrop service.
+// All implementations must embed UnimplementedAirdropServer
+// for forward compatibility.
+//
+// Airdrop serves Merkle roots and proofs. Addresses and hashes are raw
+// bytes (20 and 32 bytes); amounts are decimal strings.
+type AirdropServer interface {
+	// GetRoot returns the Merkle root and leaf encoding
+	GetRoot(context.Context, *GetRootRequest) (*GetRootResponse, error)
+	// GetProof returns the proof for an address
+	GetProof(context.Context, *GetProofRequest) (*Proof, error)
+	// GetProofByIndex returns the proof for a claim index
+	GetProofByIndex(context.Context, *GetProofByIndexRequest) (*Proof, error)
+	// VerifyProof checks a proof against the served root
+	VerifyProof(context.Context, *VerifyProofRequest) (*VerifyProofResponse, error)
+	// GetStats returns airdrop statistics
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// ListClaims streams claims in index order
+	ListClaims(*ListClaimsRequest, grpc.ServerStreamingServer[Proof]) error
+	mustEmbedUnimplementedAirdropServer()
+}
+
+// UnimplementedAirdropServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAirdropServer struct{}
+
+func (UnimplementedAirdropServer) GetRoot(context.Context, *GetRootRequest) (*GetRootResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRoot not implemented")
+}
+func (UnimplementedAirdropServer) GetProof(context.Context, *GetProofRequest) (*Proof, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetProof not implemented")
+}
+func (UnimplementedAirdropServer) GetProofByIndex(context.Context, *GetProofByIndexRequest) (*Proof, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetProofByIndex not implemented")
+}
+func (UnimplementedAirdropServer) VerifyProof(context.Context, *VerifyProofRequest) (*VerifyProofResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method VerifyProof not implemented")
+}
+func (UnimplementedAirdropServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedAirdropServer) ListClaims(*ListClaimsRequest, grpc.ServerStreamingServer[Proof]) error {
+	return status.Error(codes.Unimplemented, "method ListClaims not implemented")
+}
+func (UnimplementedAirdropServer) mustEmbedUnimplementedAirdropServer() {}
+func (UnimplementedAirdropServer) testEmbeddedByValue()                 {}
+
+// UnsafeAirdropServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AirdropServer will
+// result in compilation errors.
+type UnsafeAirdropServer interface {
+	mustEmbedUnimplementedAirdropServer()
+}
+
+func RegisterAirdropServer(s grpc.ServiceRegistrar, srv AirdropServer) {
+	// If the following call panics, it indicates UnimplementedAirdropServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Airdrop_ServiceDesc, srv)
+}
+
+func _Airdrop_GetRoot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRootRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AirdropServer).GetRoot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Airdrop_GetRoot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AirdropServer).GetRoot(ctx, req.(*GetRootRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Airdrop_GetProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AirdropServer).GetProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Airdrop_GetProof_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AirdropServer).GetProof(ctx, req.(*GetProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Airdrop_GetProofByIndex_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProofByIndexRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AirdropServer).GetProofByIndex(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Airdrop_GetProofByIndex_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AirdropServer).GetProofByIndex(ctx, req.(*GetProofByIndexRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Airdrop_VerifyProof_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyProofRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AirdropServer).VerifyProof(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Airdrop_VerifyProof_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AirdropServer).VerifyProof(ctx, req.(*VerifyProofRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Airdrop_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AirdropServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Airdrop_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AirdropServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Airdrop_ListClaims_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListClaimsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AirdropServer).ListClaims(m, &grpc.GenericServerStream[ListClaimsRequest, Proof]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Airdrop_ListClaimsServer = grpc.ServerStreamingServer[Proof]
+
+// Airdrop_ServiceDesc is the grpc.ServiceDesc for Airdrop service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Airdrop_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "airdrop.v1.Airdrop",
+	HandlerType: (*AirdropServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRoot",
+			Handler:    _Airdrop_GetRoot_Handler,
+		},
+		{
+			MethodName: "GetProof",
+			Handler:    _Airdrop_GetProof_Handler,
+		},
+		{
+			MethodName: "GetProofByIndex",
+			Handler:    _Airdrop_GetProofByIndex_Handler,
+		},
+		{
+			MethodName: "VerifyProof",
+			Handler:    _Airdrop_VerifyProof_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _Airdrop_GetStats_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListClaims",
+			Handler:       _Airdrop_ListClaims_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "airdrop.proto",
+}
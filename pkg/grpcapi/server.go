@@ -0,0 +1,261 @@
+// pkg/grpcapi/server.go
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative airdrop.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the Airdrop gRPC service over the same proofs the HTTP
+// API serves
+type Server struct {
+	UnimplementedAirdropServer
+
+	root        []byte
+	proofs      map[string]*merkle.MerkleProof
+	byIndex     []string // Addresses ordered by claim index
+	options     merkle.TreeOptions
+	totalClaims int
+	claimGate   func() error // Why claims aren't served now; nil to always serve
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithClaimGate makes GetProof, GetProofByIndex and ListClaims fail with
+// FailedPrecondition while gate returns an error, so the gRPC API keeps
+// the claim window the HTTP API enforces
+func WithClaimGate(gate func() error) Option {
+	return func(s *Server) {
+		s.claimGate = gate
+	}
+}
+
+// NewServer creates a server backed by a built tree and its proofs
+func NewServer(tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof, opts ...Option) *Server {
+	return newServer(tree.Root.Hash, proofs, tree.Options(), len(tree.Claims), opts)
+}
+
+// NewServerFromProofs creates a server for an exported proof set without
+// rebuilding the tree
+func NewServerFromProofs(root string, proofs *merkle.ProofSet, opts ...Option) (*Server, error) {
+	rootBytes, err := merkle.DecodeHash(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	if err := proofs.Metadata.Validate(); err != nil {
+		return nil, err
+	}
+	return newServer(rootBytes, proofs.Proofs, proofs.Metadata.Options(), proofs.Len(), opts), nil
+}
+
+func newServer(root []byte, proofs map[string]*merkle.MerkleProof, options merkle.TreeOptions, totalClaims int, opts []Option) *Server {
+	set := &merkle.ProofSet{Proofs: proofs}
+	s := &Server{
+		root:        root,
+		proofs:      proofs,
+		byIndex:     set.Addresses(),
+		options:     options,
+		totalClaims: totalClaims,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// checkClaimGate returns the claim gate's refusal as a gRPC error
+func (s *Server) checkClaimGate() error {
+	if s.claimGate == nil {
+		return nil
+	}
+	if err := s.claimGate(); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+// Options returns the options of the tree the proofs come from
+func (s *Server) Options() merkle.TreeOptions {
+	return s.options
+}
+
+// GetRoot returns the Merkle root and leaf encoding
+func (s *Server) GetRoot(ctx context.Context, req *GetRootRequest) (*GetRootResponse, error) {
+	return &GetRootResponse{
+		MerkleRoot:   s.root,
+		IncludeIndex: s.options.IncludeIndex,
+	}, nil
+}
+
+// GetProof returns the proof for an address
+func (s *Server) GetProof(ctx context.Context, req *GetProofRequest) (*Proof, error) {
+	address, err := parseAddress(req.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkClaimGate(); err != nil {
+		return nil, err
+	}
+
+	proof, exists := s.proofs[address.Hex()]
+	if !exists {
+		return nil, status.Error(codes.NotFound, "address not found in airdrop")
+	}
+	return toProto(address, proof, true)
+}
+
+// GetProofByIndex returns the proof for a claim index
+func (s *Server) GetProofByIndex(ctx context.Context, req *GetProofByIndexRequest) (*Proof, error) {
+	if err := s.checkClaimGate(); err != nil {
+		return nil, err
+	}
+	i := sort.Search(len(s.byIndex), func(i int) bool {
+		return s.proofs[s.byIndex[i]].Index >= req.GetIndex()
+	})
+	if i == len(s.byIndex) || s.proofs[s.byIndex[i]].Index != req.GetIndex() {
+		return nil, status.Errorf(codes.NotFound, "no claim with index %d", req.GetIndex())
+	}
+
+	addr := s.byIndex[i]
+	return toProto(common.HexToAddress(addr), s.proofs[addr], true)
+}
+
+// VerifyProof checks a proof against the served root
+func (s *Server) VerifyProof(ctx context.Context, req *VerifyProofRequest) (*VerifyProofResponse, error) {
+	address, err := parseAddress(req.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	amount, ok := new(big.Int).SetString(req.GetAmount(), 10)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "invalid amount")
+	}
+
+	claim := merkle.AirdropClaim{
+		Address: address,
+		Amount:  amount,
+	}
+
+	// The index and positions default to the ones recorded for the address
+	var positions uint64
+	proof, exists := s.proofs[address.Hex()]
+	if exists {
+		positions = proof.Positions
+	}
+	if req.Index != nil {
+		claim.Index = req.GetIndex()
+	} else if exists {
+		claim.Index = proof.Index
+	}
+
+	valid, err := merkle.VerifyProofBytesWithPositions(s.root, claim, req.GetProof(), positions, s.options)
+	var amountErr *merkle.InvalidAmountError
+	var proofErr *merkle.InvalidProofError
+	switch {
+	case errors.As(err, &amountErr):
+		return nil, status.Errorf(codes.InvalidArgument, "invalid amount: %s", amountErr.Reason)
+	case errors.As(err, &proofErr):
+		return nil, status.Errorf(codes.InvalidArgument, "invalid proof: %v", err)
+	case err != nil:
+		return nil, status.Errorf(codes.Internal, "failed to verify proof: %v", err)
+	}
+	return &VerifyProofResponse{Valid: valid}, nil
+}
+
+// GetStats returns airdrop statistics
+func (s *Server) GetStats(ctx context.Context, req *GetStatsRequest) (*GetStatsResponse, error) {
+	return &GetStatsResponse{
+		TotalClaims: uint64(s.totalClaims),
+		TotalProofs: uint64(len(s.proofs)),
+		MerkleRoot:  s.root,
+		ProofDepth:  uint32(proofDepth(s.totalClaims, s.options)),
+	}, nil
+}
+
+// ListClaims streams claims in index order
+func (s *Server) ListClaims(req *ListClaimsRequest, stream Airdrop_ListClaimsServer) error {
+	if err := s.checkClaimGate(); err != nil {
+		return err
+	}
+	start := sort.Search(len(s.byIndex), func(i int) bool {
+		return s.proofs[s.byIndex[i]].Index >= req.GetStartIndex()
+	})
+
+	sent := uint32(0)
+	for _, addr := range s.byIndex[start:] {
+		if req.GetLimit() > 0 && sent == req.GetLimit() {
+			break
+		}
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		claim, err := toProto(common.HexToAddress(addr), s.proofs[addr], req.GetIncludeProofs())
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(claim); err != nil {
+			return err
+		}
+		sent++
+	}
+
+	return nil
+}
+
+func parseAddress(b []byte) (common.Address, error) {
+	if len(b) != common.AddressLength {
+		return common.Address{}, status.Errorf(codes.InvalidArgument, "invalid address length: %d", len(b))
+	}
+	return common.BytesToAddress(b), nil
+}
+
+// toProto converts a stored proof, decoding its hex hashes when withProof
+// is set
+func toProto(address common.Address, proof *merkle.MerkleProof, withProof bool) (*Proof, error) {
+	msg := &Proof{
+		Address: address.Bytes(),
+		Amount:  proof.Amount,
+		Index:   proof.Index,
+	}
+	if !withProof {
+		return msg, nil
+	}
+
+	msg.Proof = make([][]byte, len(proof.Proof))
+	for i, h := range proof.Proof {
+		hash, err := merkle.DecodeHash(h)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "stored proof for %s is malformed", address.Hex())
+		}
+		msg.Proof[i] = hash
+	}
+	return msg, nil
+}
+
+// proofDepth returns the length of the longest proof of a tree of leaves
+// leaves built with opts
+func proofDepth(leaves int, opts merkle.TreeOptions) int {
+	if opts.FixedDepth != 0 {
+		return opts.FixedDepth
+	}
+	depth := 0
+	for leaves > 1 {
+		leaves = (leaves + 1) / 2
+		depth++
+	}
+	return depth
+}
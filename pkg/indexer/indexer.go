@@ -0,0 +1,272 @@
+// Package indexer keeps a ClaimStore in sync with a distributor's Claimed
+// events
+package indexer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"time"
+
+	"merkle-airdrop/pkg/contract"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Defaults for WatchClaims
+const (
+	DefaultPollInterval = 15 * time.Second
+	DefaultMinBackoff   = time.Second
+	DefaultMaxBackoff   = time.Minute
+)
+
+// maxBlockRange bounds the blocks of one FilterLogs call, which many
+// providers limit
+const maxBlockRange = 10000
+
+// Backend is the subset of an Ethereum client WatchClaims needs, such as
+// *ethclient.Client
+type Backend interface {
+	ethereum.LogFilterer
+	ethereum.BlockNumberReader
+}
+
+// watcher holds the settings of a WatchClaims call
+type watcher struct {
+	client   Backend
+	query    ethereum.FilterQuery
+	store    ClaimStore
+	progress *Progress
+	logger   *slog.Logger
+
+	startBlock   uint64
+	pollInterval time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+}
+
+// Option configures WatchClaims
+type Option func(*watcher)
+
+// WithStartBlock backfills from block, such as the distributor's deployment
+// block, instead of genesis
+func WithStartBlock(block uint64) Option {
+	return func(w *watcher) {
+		w.startBlock = block
+	}
+}
+
+// WithPollInterval sets how often the chain head is read, and how often
+// logs are polled from nodes without subscriptions; DefaultPollInterval
+// unless set
+func WithPollInterval(interval time.Duration) Option {
+	return func(w *watcher) {
+		w.pollInterval = interval
+	}
+}
+
+// WithBackoff sets the wait before reconnecting after a failure, doubling
+// from minBackoff up to maxBackoff while failures repeat
+func WithBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(w *watcher) {
+		w.minBackoff = minBackoff
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// WithProgress reports the indexed and head blocks to progress
+func WithProgress(progress *Progress) Option {
+	return func(w *watcher) {
+		w.progress = progress
+	}
+}
+
+// WithLogger sets the logger for reconnects and skipped logs, which
+// defaults to slog.Default()
+func WithLogger(logger *slog.Logger) Option {
+	return func(w *watcher) {
+		w.logger = logger
+	}
+}
+
+// storeError marks a failure of the ClaimStore, which reconnecting can't fix
+type storeError struct{ err error }
+
+func (e *storeError) Error() string { return "claim store: " + e.err.Error() }
+func (e *storeError) Unwrap() error { return e.err }
+
+// WatchClaims records the Claimed events of the distributor at
+// contractAddr in store until ctx is done. It backfills with FilterLogs
+// from the start block to the head, then follows new blocks with
+// SubscribeFilterLogs, polling instead on nodes without subscriptions.
+// Dropped connections are retried with backoff, resuming where indexing
+// stopped. It returns ctx's error, or the first error of store.
+func WatchClaims(ctx context.Context, client Backend, contractAddr common.Address, store ClaimStore, opts ...Option) error {
+	w := &watcher{
+		client: client,
+		query: ethereum.FilterQuery{
+			Addresses: []common.Address{contractAddr},
+			Topics:    [][]common.Hash{{contract.ClaimedTopic}},
+		},
+		store:        store,
+		progress:     new(Progress),
+		logger:       slog.Default(),
+		pollInterval: DefaultPollInterval,
+		minBackoff:   DefaultMinBackoff,
+		maxBackoff:   DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	next := w.startBlock
+	backoff := w.minBackoff
+	for {
+		from := next
+		err := w.follow(ctx, &next)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var storeErr *storeError
+		if errors.As(err, &storeErr) {
+			return err
+		}
+
+		// Only failures that repeat without progress back off further
+		if next > from {
+			backoff = w.minBackoff
+		}
+		w.logger.Warn("claims indexer disconnected", "error", err, "next_block", next, "retry_in", backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, w.maxBackoff)
+	}
+}
+
+// follow subscribes to new logs, backfills from *next to the head and then
+// records logs as they arrive, until a call fails. *next is advanced past
+// every block known to be recorded.
+func (w *watcher) follow(ctx context.Context, next *uint64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribing before the backfill leaves no gap between the two; logs
+	// seen by both are recorded twice, which stores allow
+	logs := make(chan types.Log, 128)
+	var subErr <-chan error
+	sub, err := w.client.SubscribeFilterLogs(ctx, w.query, logs)
+	switch {
+	case errors.Is(err, rpc.ErrNotificationsUnsupported):
+		w.logger.Info("node has no log subscriptions; polling for claims", "interval", w.pollInterval)
+	case err != nil:
+		return fmt.Errorf("failed to subscribe to claims: %w", err)
+	default:
+		defer sub.Unsubscribe()
+		subErr = sub.Err()
+	}
+
+	if err := w.backfill(ctx, next); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-subErr:
+			return fmt.Errorf("claims subscription dropped: %w", err)
+		case log := <-logs:
+			if err := w.record(log); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if sub == nil {
+				if err := w.backfill(ctx, next); err != nil {
+					return err
+				}
+				continue
+			}
+			head, err := w.client.BlockNumber(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to read head: %w", err)
+			}
+			// Logs up to the previous head have arrived by now, so a
+			// reconnect can resume after it
+			*next = max(*next, w.progress.HeadBlock()+1)
+			w.progress.head.Store(head)
+			w.progress.indexed.Store(head)
+		}
+	}
+}
+
+// backfill records the logs from *next up to the current head
+func (w *watcher) backfill(ctx context.Context, next *uint64) error {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read head: %w", err)
+	}
+	w.progress.head.Store(head)
+
+	for *next <= head {
+		to := min(*next+maxBlockRange-1, head)
+		query := w.query
+		query.FromBlock = new(big.Int).SetUint64(*next)
+		query.ToBlock = new(big.Int).SetUint64(to)
+		logs, err := w.client.FilterLogs(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to filter claims in blocks %d-%d: %w", *next, to, err)
+		}
+		for _, log := range logs {
+			if err := w.record(log); err != nil {
+				return err
+			}
+		}
+		*next = to + 1
+		w.progress.indexed.Store(to)
+	}
+	w.progress.synced.Store(true)
+	return nil
+}
+
+// record writes a Claimed log to the store, skipping logs it can't decode
+func (w *watcher) record(log types.Log) error {
+	event, err := contract.ParseClaimed(log)
+	if err != nil {
+		w.logger.Warn("skipping undecodable claim log", "tx", log.TxHash.Hex(), "error", err)
+		return nil
+	}
+	if !event.Index.IsUint64() || event.Index.Uint64() > math.MaxUint32 {
+		w.logger.Warn("skipping claim with out-of-range index", "tx", log.TxHash.Hex(), "index", event.Index)
+		return nil
+	}
+	index := uint32(event.Index.Uint64())
+
+	if log.Removed {
+		err = w.store.RemoveClaim(index)
+	} else {
+		err = w.store.MarkClaimed(Claim{
+			Index:   index,
+			Account: event.Account,
+			Amount:  event.Amount,
+			Block:   log.BlockNumber,
+			TxHash:  log.TxHash,
+		})
+	}
+	if err != nil {
+		return &storeError{err}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package indexer
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Claim is a claim made on chain, as its Claimed event recorded it
+type Claim struct {
+	Index   uint32
+	Account common.Address
+	Amount  *big.Int
+	Block   uint64
+	TxHash  common.Hash
+}
+
+// ClaimStore records the claims WatchClaims reads from the chain. Both
+// methods may be called again for a claim already recorded.
+type ClaimStore interface {
+	// MarkClaimed records that the claim at claim.Index was made
+	MarkClaimed(claim Claim) error
+
+	// RemoveClaim forgets the claim at index after a reorg dropped the
+	// block that made it
+	RemoveClaim(index uint32) error
+}
+
+// MemoryStore is a ClaimStore kept in memory. Its IsClaimed makes it usable
+// as the API's claim status.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	claims map[uint32]Claim
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{claims: make(map[uint32]Claim)}
+}
+
+func (m *MemoryStore) MarkClaimed(claim Claim) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.claims[claim.Index] = claim
+	return nil
+}
+
+func (m *MemoryStore) RemoveClaim(index uint32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.claims, index)
+	return nil
+}
+
+// IsClaimed reports whether a claim at index has been recorded
+func (m *MemoryStore) IsClaimed(index uint32) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.claims[index]
+	return ok, nil
+}
+
+// Claim returns the recorded claim at index
+func (m *MemoryStore) Claim(index uint32) (Claim, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	claim, ok := m.claims[index]
+	return claim, ok
+}
+
+// Len returns the number of recorded claims
+func (m *MemoryStore) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.claims)
+}
+
+// Progress reports how far WatchClaims has read the chain. It is safe for
+// concurrent use.
+type Progress struct {
+	indexed atomic.Uint64
+	head    atomic.Uint64
+	synced  atomic.Bool
+}
+
+// IndexedBlock returns the last block whose Claimed events are recorded
+func (p *Progress) IndexedBlock() uint64 {
+	return p.indexed.Load()
+}
+
+// HeadBlock returns the chain head the node last reported
+func (p *Progress) HeadBlock() uint64 {
+	return p.head.Load()
+}
+
+// Lag returns how many blocks the index is behind the head
+func (p *Progress) Lag() uint64 {
+	indexed, head := p.IndexedBlock(), p.HeadBlock()
+	if indexed >= head {
+		return 0
+	}
+	return head - indexed
+}
+
+// Synced reports whether the backfill has reached the head at least once,
+// so the store holds every claim made up to IndexedBlock
+func (p *Progress) Synced() bool {
+	return p.synced.Load()
+}
@@ -0,0 +1,113 @@
+package merkle
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// IndexAllocator keeps the claim index of every address it has seen, so an
+// address keeps its index, and its slot in the contract's claimed bitmap,
+// across rebuilds. New addresses take the next free index, and the indices
+// of removed addresses are never reused. It is safe for concurrent use.
+type IndexAllocator struct {
+	mu      sync.Mutex
+	indices map[common.Address]uint32
+	next    uint64 // Above every index ever assigned
+}
+
+// NewIndexAllocator returns an allocator that has assigned no indices
+func NewIndexAllocator() *IndexAllocator {
+	return &IndexAllocator{indices: make(map[common.Address]uint32)}
+}
+
+// RestoreIndexAllocator returns an allocator holding indices, as saved from
+// Indices and Next. Indices must be unique and below next.
+func RestoreIndexAllocator(indices map[common.Address]uint32, next uint64) (*IndexAllocator, error) {
+	if next > math.MaxUint32+1 {
+		return nil, fmt.Errorf("next index %d exceeds the uint32 range", next)
+	}
+	owners := make(map[uint32]common.Address, len(indices))
+	for address, index := range indices {
+		if uint64(index) >= next {
+			return nil, fmt.Errorf("index %d of %s is not below the next index %d", index, address.Hex(), next)
+		}
+		if other, exists := owners[index]; exists {
+			return nil, fmt.Errorf("duplicate index %d for %s and %s", index, other.Hex(), address.Hex())
+		}
+		owners[index] = address
+	}
+	return &IndexAllocator{indices: maps.Clone(indices), next: next}, nil
+}
+
+// Indices returns a copy of the assigned indices by address
+func (a *IndexAllocator) Indices() map[common.Address]uint32 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return maps.Clone(a.indices)
+}
+
+// Next returns the index the next new address will take
+func (a *IndexAllocator) Next() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.next
+}
+
+// Len returns the number of addresses assigned an index
+func (a *IndexAllocator) Len() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.indices)
+}
+
+// Assign sets the index of every claim to its address's, first assigning
+// the addresses without one the next free indices in address order, so
+// the result doesn't depend on the claims' order. It returns how many
+// addresses were new.
+func (a *IndexAllocator) Assign(claims []AirdropClaim) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var added []common.Address
+	for _, claim := range claims {
+		if _, ok := a.indices[claim.Address]; !ok {
+			added = append(added, claim.Address)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return added[i].Hex() < added[j].Hex()
+	})
+	// Repeated addresses are assigned once and fail the build
+	added = slices.Compact(added)
+	if a.next+uint64(len(added)) > math.MaxUint32+1 {
+		return 0, fmt.Errorf("no free indices for %d new addresses", len(added))
+	}
+	for _, address := range added {
+		a.indices[address] = uint32(a.next)
+		a.next++
+	}
+
+	for i := range claims {
+		claims[i].Index = a.indices[claims[i].Address]
+	}
+	return len(added), nil
+}
+
+// peek returns the index Assign would give address, without assigning it
+func (a *IndexAllocator) peek(address common.Address) (uint64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if index, ok := a.indices[address]; ok {
+		return uint64(index), nil
+	}
+	if a.next > math.MaxUint32 {
+		return 0, fmt.Errorf("no free indices for %s", address.Hex())
+	}
+	return a.next, nil
+}
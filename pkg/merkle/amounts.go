@@ -0,0 +1,96 @@
+// pkg/merkle/amounts.go
+package merkle
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// TotalAmount returns the sum of the claim amounts, the least a distributor
+// must be funded with
+func TotalAmount(claims []AirdropClaim) *big.Int {
+	total := new(big.Int)
+	for _, claim := range claims {
+		total.Add(total, claim.Amount)
+	}
+	return total
+}
+
+// CheckAmountLimits returns an error when a claim exceeds maxPerClaim or the
+// claims together exceed maxTotal. A nil limit is not checked; amounts equal
+// to a limit are accepted.
+func CheckAmountLimits(claims []AirdropClaim, maxTotal, maxPerClaim *big.Int) error {
+	if maxPerClaim != nil {
+		over := 0
+		var first AirdropClaim
+		for _, claim := range claims {
+			if claim.Amount.Cmp(maxPerClaim) > 0 {
+				if over == 0 {
+					first = claim
+				}
+				over++
+			}
+		}
+		if over > 0 {
+			return fmt.Errorf("%d claims exceed the per-claim cap of %s; the first, %s for %s, is over by %s",
+				over, maxPerClaim, first.Amount, first.Address.Hex(), new(big.Int).Sub(first.Amount, maxPerClaim))
+		}
+	}
+
+	if maxTotal != nil {
+		total := TotalAmount(claims)
+		if total.Cmp(maxTotal) > 0 {
+			return fmt.Errorf("claims total %s, exceeding the cap of %s by %s",
+				total, maxTotal, new(big.Int).Sub(total, maxTotal))
+		}
+	}
+	return nil
+}
+
+// DefaultMaxAmountBits is the amount width of a uint256 distributor
+const DefaultMaxAmountBits = 256
+
+// maxReportedAmounts bounds the claims an amount width error lists
+const maxReportedAmounts = 5
+
+// CheckAmountBits returns an error naming the claims whose amounts don't fit
+// in an unsigned integer of bits bits, such as a distributor's uint96.
+// Zero means DefaultMaxAmountBits; other widths must be a multiple of 8 so
+// they name a Solidity type.
+func CheckAmountBits(claims []AirdropClaim, bits int) error {
+	return checkAmountBits(len(claims), bits, func(i int) (string, *big.Int) {
+		return claims[i].Address.Hex(), claims[i].Amount
+	})
+}
+
+// checkAmountBits checks the amounts of n claims, where claim(i) returns
+// the name and amount of claim i
+func checkAmountBits(n, bits int, claim func(i int) (string, *big.Int)) error {
+	if bits == 0 {
+		bits = DefaultMaxAmountBits
+	}
+	if bits < 8 || bits > DefaultMaxAmountBits || bits%8 != 0 {
+		return fmt.Errorf("amount bits must be a multiple of 8 from 8 to 256, got %d", bits)
+	}
+
+	var over []string
+	count := 0
+	for i := 0; i < n; i++ {
+		name, amount := claim(i)
+		if amount != nil && amount.BitLen() > bits {
+			if count < maxReportedAmounts {
+				over = append(over, fmt.Sprintf("%s (%s)", name, amount))
+			}
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	if count > len(over) {
+		over = append(over, fmt.Sprintf("and %d more", count-len(over)))
+	}
+	return fmt.Errorf("%d claims exceed the uint%d maximum of %s: %s",
+		count, bits, new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bits)), big.NewInt(1)), strings.Join(over, ", "))
+}
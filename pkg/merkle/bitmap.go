@@ -0,0 +1,141 @@
+// pkg/merkle/bitmap.go
+package merkle
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// IndexWordBits is the number of claim flags a distributor packs into one
+// uint256 storage word, at word index/256
+const IndexWordBits = 256
+
+// rleBitmapPrefix marks a run-length encoded bitmap from EncodeIndexBitmap
+const rleBitmapPrefix = "rle:0x"
+
+// maxPlainBitmap is the largest bitmap EncodeIndexBitmap leaves unencoded
+const maxPlainBitmap = 1024
+
+// IndexStats summarises claim indices the way a distributor stores them
+type IndexStats struct {
+	MaxIndex uint32   `json:"maxIndex"`
+	Words    int      `json:"words"`    // Storage words holding at least one claim flag
+	GapCount int      `json:"gapCount"` // Unused indices below MaxIndex
+	Gaps     []uint32 `json:"gaps"`     // The unused indices, possibly only the first few
+}
+
+// IndexBitmap returns a bitmap of the tree's claim indices: bit i%8 of byte
+// i/8 is set when index i is claimed, matching a distributor's claimed-word
+// layout. Its length follows the largest index, not the number of claims.
+func (mt *MerkleTree) IndexBitmap() []byte {
+	return IndexBitmapOf(mt.claimIndices())
+}
+
+// IndexStats returns the largest claim index, the number of storage words
+// the claimed flags touch and the unused indices below the largest
+func (mt *MerkleTree) IndexStats() (maxIndex uint32, words int, gaps []uint32) {
+	stats := IndexStatsOf(mt.claimIndices(), -1)
+	return stats.MaxIndex, stats.Words, stats.Gaps
+}
+
+func (mt *MerkleTree) claimIndices() []uint32 {
+	indices := make([]uint32, len(mt.Claims))
+	for i, claim := range mt.Claims {
+		indices[i] = claim.Index
+	}
+	return indices
+}
+
+// IndexBitmapOf returns the bitmap of indices described by IndexBitmap
+func IndexBitmapOf(indices []uint32) []byte {
+	if len(indices) == 0 {
+		return []byte{}
+	}
+	bitmap := make([]byte, int(slices.Max(indices))/8+1)
+	for _, index := range indices {
+		bitmap[index/8] |= 1 << (index % 8)
+	}
+	return bitmap
+}
+
+// IndexStatsOf summarises indices, which may repeat. At most maxGaps unused
+// indices are listed; a negative maxGaps lists all of them.
+func IndexStatsOf(indices []uint32, maxGaps int) IndexStats {
+	stats := IndexStats{Gaps: []uint32{}}
+	if len(indices) == 0 {
+		return stats
+	}
+	sorted := slices.Clone(indices)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	stats.MaxIndex = sorted[len(sorted)-1]
+	stats.GapCount = int(stats.MaxIndex) + 1 - len(sorted)
+
+	next := uint32(0) // Lowest index not yet accounted for
+	for i, index := range sorted {
+		if i == 0 || index/IndexWordBits != sorted[i-1]/IndexWordBits {
+			stats.Words++
+		}
+		for ; next < index && (maxGaps < 0 || len(stats.Gaps) < maxGaps); next++ {
+			stats.Gaps = append(stats.Gaps, next)
+		}
+		next = index + 1
+	}
+	return stats
+}
+
+// EncodeIndexBitmap hex-encodes bitmap with a 0x prefix. Bitmaps over 1 KiB
+// are run-length encoded instead, as "rle:0x" followed by (count, byte)
+// pairs with counts of 1 to 255.
+func EncodeIndexBitmap(bitmap []byte) string {
+	if len(bitmap) <= maxPlainBitmap {
+		return "0x" + hex.EncodeToString(bitmap)
+	}
+
+	var runs []byte
+	for i := 0; i < len(bitmap); {
+		n := 1
+		for i+n < len(bitmap) && bitmap[i+n] == bitmap[i] && n < 255 {
+			n++
+		}
+		runs = append(runs, byte(n), bitmap[i])
+		i += n
+	}
+	return rleBitmapPrefix + hex.EncodeToString(runs)
+}
+
+// DecodeIndexBitmap reverses EncodeIndexBitmap
+func DecodeIndexBitmap(encoded string) ([]byte, error) {
+	if runs, ok := strings.CutPrefix(encoded, rleBitmapPrefix); ok {
+		pairs, err := hex.DecodeString(runs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bitmap: %w", err)
+		}
+		if len(pairs)%2 != 0 {
+			return nil, fmt.Errorf("invalid bitmap: odd run-length data")
+		}
+		var bitmap []byte
+		for i := 0; i < len(pairs); i += 2 {
+			if pairs[i] == 0 {
+				return nil, fmt.Errorf("invalid bitmap: empty run at byte %d", i)
+			}
+			for n := 0; n < int(pairs[i]); n++ {
+				bitmap = append(bitmap, pairs[i+1])
+			}
+		}
+		return bitmap, nil
+	}
+
+	plain, ok := strings.CutPrefix(encoded, "0x")
+	if !ok {
+		return nil, fmt.Errorf("invalid bitmap: expected a 0x or rle:0x prefix")
+	}
+	bitmap, err := hex.DecodeString(plain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bitmap: %w", err)
+	}
+	return bitmap, nil
+}
@@ -0,0 +1,162 @@
+// pkg/merkle/bloom.go
+package merkle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// bloomMagic starts every encoded BloomFilter
+const bloomMagic = "MKBF"
+
+// bloomVersion is the encoding version after the magic
+const bloomVersion = 1
+
+// bloomHeaderSize is the encoded header: magic, version, hash count, bit
+// count and element count
+const bloomHeaderSize = len(bloomMagic) + 1 + 1 + 4 + 4
+
+// maxBloomHashes is the most hash functions an encoded filter can name
+const maxBloomHashes = math.MaxUint8
+
+// BloomFilter is a compact allowlist of airdrop addresses for eligibility
+// pre-checks. It never rejects an address in the airdrop but may accept
+// one that isn't, so a hit still has to be confirmed with a proof.
+//
+// An address's bits are (h1 + j*h2) mod Bits for j in [0, Hashes), where h1
+// and h2 are the first and second big-endian uint32 of keccak256 of the
+// 20 address bytes. Bit b is bit b%8 of byte b/8.
+type BloomFilter struct {
+	bits     []byte
+	size     uint32 // Bits
+	hashes   int
+	elements int
+}
+
+// BloomParams describe a filter so clients can reimplement its lookups
+type BloomParams struct {
+	Bits              uint32  `json:"bits"`
+	Hashes            int     `json:"hashes"`
+	Elements          int     `json:"elements"`          // Distinct addresses added
+	FalsePositiveRate float64 `json:"falsePositiveRate"` // Expected for an address not added
+}
+
+// BuildBloomFilter builds a filter over the claims' addresses sized for
+// falsePositiveRate, which must be between 0 and 1
+func BuildBloomFilter(claims []AirdropClaim, falsePositiveRate float64) (*BloomFilter, error) {
+	if len(claims) == 0 {
+		return nil, ErrEmptyClaims
+	}
+	if !(falsePositiveRate > 0 && falsePositiveRate < 1) {
+		return nil, fmt.Errorf("false positive rate must be between 0 and 1, got %v", falsePositiveRate)
+	}
+
+	addresses := make(map[common.Address]struct{}, len(claims))
+	for _, claim := range claims {
+		addresses[claim.Address] = struct{}{}
+	}
+	n := float64(len(addresses))
+
+	// m = -n ln p / (ln 2)^2 and k = m/n ln 2 minimise the filter for p
+	bits := math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2))
+	if bits > math.MaxUint32 {
+		return nil, fmt.Errorf("a filter of %d addresses at rate %v needs %.0f bits, more than 2^32-1", len(addresses), falsePositiveRate, bits)
+	}
+	size := max(uint32(bits), 8)
+	hashes := min(max(int(math.Round(float64(size)/n*math.Ln2)), 1), maxBloomHashes)
+
+	filter := &BloomFilter{
+		bits:     make([]byte, (uint64(size)+7)/8),
+		size:     size,
+		hashes:   hashes,
+		elements: len(addresses),
+	}
+	for address := range addresses {
+		filter.add(address)
+	}
+	return filter, nil
+}
+
+// positions calls fn with each of address's bit positions
+func (f *BloomFilter) positions(address common.Address, fn func(bit uint64)) {
+	hash := crypto.Keccak256(address[:])
+	h1 := uint64(binary.BigEndian.Uint32(hash[0:4]))
+	h2 := uint64(binary.BigEndian.Uint32(hash[4:8]))
+	for j := uint64(0); j < uint64(f.hashes); j++ {
+		fn((h1 + j*h2) % uint64(f.size))
+	}
+}
+
+func (f *BloomFilter) add(address common.Address) {
+	f.positions(address, func(bit uint64) {
+		f.bits[bit/8] |= 1 << (bit % 8)
+	})
+}
+
+// Contains reports whether address may be in the airdrop. False means it
+// certainly isn't.
+func (f *BloomFilter) Contains(address common.Address) bool {
+	found := true
+	f.positions(address, func(bit uint64) {
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+// Params returns the filter's size, hash count and expected false positive
+// rate
+func (f *BloomFilter) Params() BloomParams {
+	k, m, n := float64(f.hashes), float64(f.size), float64(f.elements)
+	return BloomParams{
+		Bits:              f.size,
+		Hashes:            f.hashes,
+		Elements:          f.elements,
+		FalsePositiveRate: math.Pow(1-math.Exp(-k*n/m), k),
+	}
+}
+
+// MarshalBinary encodes the filter as the magic "MKBF", a version byte, the
+// hash count as one byte, the bit and element counts as big-endian uint32s
+// and then the bits
+func (f *BloomFilter) MarshalBinary() ([]byte, error) {
+	encoded := make([]byte, bloomHeaderSize, bloomHeaderSize+len(f.bits))
+	copy(encoded, bloomMagic)
+	encoded[4] = bloomVersion
+	encoded[5] = byte(f.hashes)
+	binary.BigEndian.PutUint32(encoded[6:10], f.size)
+	binary.BigEndian.PutUint32(encoded[10:14], uint32(f.elements))
+	return append(encoded, f.bits...), nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary
+func (f *BloomFilter) UnmarshalBinary(encoded []byte) error {
+	if len(encoded) < bloomHeaderSize || string(encoded[:4]) != bloomMagic {
+		return fmt.Errorf("not a bloom filter")
+	}
+	if encoded[4] != bloomVersion {
+		return fmt.Errorf("unsupported bloom filter version %d", encoded[4])
+	}
+	hashes := int(encoded[5])
+	size := binary.BigEndian.Uint32(encoded[6:10])
+	if hashes == 0 || size == 0 {
+		return fmt.Errorf("bloom filter has %d bits and %d hashes", size, hashes)
+	}
+	bits := encoded[bloomHeaderSize:]
+	if uint64(len(bits)) != (uint64(size)+7)/8 {
+		return fmt.Errorf("bloom filter of %d bits has %d bytes of bits", size, len(bits))
+	}
+
+	*f = BloomFilter{
+		bits:     append([]byte(nil), bits...),
+		size:     size,
+		hashes:   hashes,
+		elements: int(binary.BigEndian.Uint32(encoded[10:14])),
+	}
+	return nil
+}
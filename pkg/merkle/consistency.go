@@ -0,0 +1,164 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ConsistencyProof shows that an address has the same claim, its amount
+// and index, in two trees: a proof of inclusion under each root. The trees
+// may hash leaves differently; each proof verifies with its tree's metadata.
+type ConsistencyProof struct {
+	Address     string       `json:"address"`
+	Amount      string       `json:"amount"`
+	Index       uint32       `json:"index"`
+	OldRoot     string       `json:"oldRoot"`
+	NewRoot     string       `json:"newRoot"`
+	OldProof    *MerkleProof `json:"oldProof"`
+	NewProof    *MerkleProof `json:"newProof"`
+	OldMetadata TreeMetadata `json:"oldMetadata"`
+	NewMetadata TreeMetadata `json:"newMetadata"`
+}
+
+// DifferenceProof shows an address's claims in two trees that differ:
+// each tree's proof, whose Amount and Index are its claim, or nil for a
+// tree without a claim for the address. A nil side is not proven absent.
+type DifferenceProof struct {
+	Address     string       `json:"address"`
+	OldRoot     string       `json:"oldRoot"`
+	NewRoot     string       `json:"newRoot"`
+	Old         *MerkleProof `json:"old,omitempty"`
+	New         *MerkleProof `json:"new,omitempty"`
+	OldMetadata TreeMetadata `json:"oldMetadata"`
+	NewMetadata TreeMetadata `json:"newMetadata"`
+}
+
+// ProveConsistency proves that address has the same claim in oldTree and
+// newTree. When the claims differ, or only one tree has a claim for the
+// address, it returns an AllocationChangedError carrying a DifferenceProof
+// instead; when neither has one, ErrAddressNotFound.
+func ProveConsistency(oldTree, newTree *MerkleTree, address common.Address) (*ConsistencyProof, error) {
+	oldProof, err := proofIfPresent(oldTree, address)
+	if err != nil {
+		return nil, fmt.Errorf("old tree: %w", err)
+	}
+	newProof, err := proofIfPresent(newTree, address)
+	if err != nil {
+		return nil, fmt.Errorf("new tree: %w", err)
+	}
+	if oldProof == nil && newProof == nil {
+		return nil, fmt.Errorf("%s: %w", address.Hex(), ErrAddressNotFound)
+	}
+
+	if !sameLeaf(oldProof, newProof) {
+		return nil, &AllocationChangedError{Difference: &DifferenceProof{
+			Address:     address.Hex(),
+			OldRoot:     oldTree.GetRootHash(),
+			NewRoot:     newTree.GetRootHash(),
+			Old:         oldProof,
+			New:         newProof,
+			OldMetadata: oldTree.Metadata(),
+			NewMetadata: newTree.Metadata(),
+		}}
+	}
+	return &ConsistencyProof{
+		Address:     address.Hex(),
+		Amount:      oldProof.Amount,
+		Index:       oldProof.Index,
+		OldRoot:     oldTree.GetRootHash(),
+		NewRoot:     newTree.GetRootHash(),
+		OldProof:    oldProof,
+		NewProof:    newProof,
+		OldMetadata: oldTree.Metadata(),
+		NewMetadata: newTree.Metadata(),
+	}, nil
+}
+
+// VerifyConsistency checks that both proofs of cp are for its claim and
+// reach their roots. A proof for another amount or index is invalid, so
+// the proofs verifying means the claim is the same under both roots.
+func VerifyConsistency(cp *ConsistencyProof) (bool, error) {
+	if cp.OldProof == nil || cp.NewProof == nil {
+		return false, proofError("consistency proof needs both proofs")
+	}
+	if !sameLeaf(cp.OldProof, cp.NewProof) || cp.OldProof.Amount != cp.Amount || cp.OldProof.Index != cp.Index {
+		return false, nil
+	}
+	claim, err := consistencyClaim(cp.Address, cp.Amount, cp.Index)
+	if err != nil {
+		return false, err
+	}
+	if valid, err := verifyUnder(cp.OldRoot, claim, cp.OldProof, cp.OldMetadata); !valid || err != nil {
+		return false, err
+	}
+	return verifyUnder(cp.NewRoot, claim, cp.NewProof, cp.NewMetadata)
+}
+
+// VerifyDifference checks that the claims of dp differ and that each proof
+// it has reaches its root
+func VerifyDifference(dp *DifferenceProof) (bool, error) {
+	if dp.Old == nil && dp.New == nil {
+		return false, proofError("difference proof has no proofs")
+	}
+	if sameLeaf(dp.Old, dp.New) {
+		return false, nil
+	}
+	sides := []struct {
+		root     string
+		proof    *MerkleProof
+		metadata TreeMetadata
+	}{{dp.OldRoot, dp.Old, dp.OldMetadata}, {dp.NewRoot, dp.New, dp.NewMetadata}}
+	for _, side := range sides {
+		if side.proof == nil {
+			continue
+		}
+		claim, err := consistencyClaim(dp.Address, side.proof.Amount, side.proof.Index)
+		if err != nil {
+			return false, err
+		}
+		if valid, err := verifyUnder(side.root, claim, side.proof, side.metadata); !valid || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// proofIfPresent returns the tree's proof for address, nil when it has no
+// claim for it
+func proofIfPresent(tree *MerkleTree, address common.Address) (*MerkleProof, error) {
+	proof, err := tree.GenerateProof(address)
+	if errors.Is(err, ErrAddressNotFound) {
+		return nil, nil
+	}
+	return proof, err
+}
+
+// sameLeaf reports whether both proofs are present and for the same claim
+func sameLeaf(a, b *MerkleProof) bool {
+	return a != nil && b != nil && a.Amount == b.Amount && a.Index == b.Index
+}
+
+// consistencyClaim parses the claim a consistency or difference proof is for
+func consistencyClaim(address, amount string, index uint32) (AirdropClaim, error) {
+	if !common.IsHexAddress(address) {
+		return AirdropClaim{}, proofError("invalid address %q", address)
+	}
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return AirdropClaim{}, &InvalidAmountError{Index: int(index), Value: amount, Reason: "not a base-10 integer"}
+	}
+	return AirdropClaim{Address: common.HexToAddress(address), Amount: value, Index: index}, nil
+}
+
+// verifyUnder checks proof for claim against the 0x-prefixed root of a tree
+// described by metadata
+func verifyUnder(root string, claim AirdropClaim, proof *MerkleProof, metadata TreeMetadata) (bool, error) {
+	rootBytes, err := DecodeHash(root)
+	if err != nil {
+		return false, proofError("invalid root %q: expected a 32-byte hex hash", root)
+	}
+	return VerifyMerkleProof(rootBytes, claim, proof, metadata.Options())
+}
@@ -0,0 +1,81 @@
+// pkg/merkle/depth.go
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// MaxFixedDepth is the largest TreeOptions.FixedDepth: the most siblings a
+// proof's positions bitmap can describe
+const MaxFixedDepth = 64
+
+// checkFixedDepth rejects a fixed depth no proof can be padded to
+func checkFixedDepth(depth int) error {
+	if depth < 0 || depth > MaxFixedDepth {
+		return fmt.Errorf("fixed depth must be between 0 and %d, got %d", MaxFixedDepth, depth)
+	}
+	return nil
+}
+
+// checkTreeDepth rejects a tree of levels levels, leaves and root
+// included, that is deeper than opts.FixedDepth
+func checkTreeDepth(levels int, opts TreeOptions) error {
+	if depth := levels - 1; opts.FixedDepth != 0 && depth > opts.FixedDepth {
+		return fmt.Errorf("tree is %d levels deep, more than the fixed depth of %d", depth, opts.FixedDepth)
+	}
+	return nil
+}
+
+// levelsFor returns the number of levels, leaves and root included, of a
+// tree of n leaves. Every odd leaf policy halves a level rounding up.
+func levelsFor(n int) int {
+	levels := 1
+	for ; n > 1; n = (n + 1) / 2 {
+		levels++
+	}
+	return levels
+}
+
+// paddingHash returns the element fixed-depth proofs are padded with: the
+// root under DuplicateLast, which pairs the path's node with itself, and
+// the zero hash under the other policies
+func paddingHash(root []byte, opts TreeOptions) []byte {
+	if opts.OddLeafPolicy == DuplicateLast {
+		return root
+	}
+	return zeroHash[:]
+}
+
+// padProof appends padding to path until it has opts.FixedDepth elements,
+// returning the padded path and the number of elements added
+func padProof(path [][]byte, root []byte, opts TreeOptions) ([][]byte, int) {
+	padding := opts.FixedDepth - len(path)
+	if opts.FixedDepth == 0 || padding <= 0 {
+		return path, 0
+	}
+	pad := paddingHash(root, opts)
+	for i := 0; i < padding; i++ {
+		path = append(path, pad)
+	}
+	return path, padding
+}
+
+// trimPadding drops the padding of a fixed-depth proof so it can be folded
+// to root. Padding sits above the tree's top level, whose two nodes are
+// never paired with a padding element, so the trailing run of them is all
+// padding.
+func trimPadding(proof [][]byte, root []byte, opts TreeOptions) ([][]byte, error) {
+	if opts.FixedDepth == 0 {
+		return proof, nil
+	}
+	if len(proof) != opts.FixedDepth {
+		return nil, proofError("proof has %d elements, expected the fixed depth of %d", len(proof), opts.FixedDepth)
+	}
+	pad := paddingHash(root, opts)
+	n := len(proof)
+	for n > 0 && bytes.Equal(proof[n-1], pad) {
+		n--
+	}
+	return proof[:n], nil
+}
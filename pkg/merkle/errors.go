@@ -0,0 +1,162 @@
+// pkg/merkle/errors.go
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// The errors callers tell apart with errors.Is and errors.As. They arrive
+// wrapped with context for humans, such as the claim they concern.
+var (
+	// ErrAddressNotFound is returned for an address without a claim in the tree
+	ErrAddressNotFound = errors.New("address not found in tree")
+
+	// ErrEmptyClaims is returned when there are no claims to build from
+	ErrEmptyClaims = errors.New("no claims provided")
+
+	// ErrRootMismatch is matched by a RootMismatchError, returned for a
+	// proof that records a root other than the one it is checked against
+	ErrRootMismatch = errors.New("proof is for a different root")
+
+	// ErrAllocationChanged is matched by an AllocationChangedError, returned
+	// by ProveConsistency for an address whose claim differs between trees
+	ErrAllocationChanged = errors.New("allocation changed between trees")
+
+	// ErrTooManyClaims is matched by a TooManyClaimsError, returned for
+	// more claims than a MaxClaims limit allows
+	ErrTooManyClaims = errors.New("too many claims")
+)
+
+// InvalidAmountError reports an amount that isn't a valid uint256, or that
+// the caller's checks reject
+type InvalidAmountError struct {
+	// Index locates the claim: its position among the claims being built
+	// or loaded, or the claim index of a leaf being hashed
+	Index int
+	// Value is the amount as given, empty when it is missing
+	Value  string
+	Reason string
+}
+
+func (e *InvalidAmountError) Error() string {
+	if e.Value == "" {
+		return "invalid amount: " + e.Reason
+	}
+	return fmt.Sprintf("invalid amount %s: %s", e.Value, e.Reason)
+}
+
+// InvalidProofError reports a proof that is malformed for the tree it is
+// checked against, as opposed to one that is well-formed but doesn't
+// reach the root
+type InvalidProofError struct {
+	Reason string
+}
+
+func (e *InvalidProofError) Error() string {
+	return e.Reason
+}
+
+// RootMismatchError reports a proof generated against another root, such
+// as one from an old export or another campaign's tree. It matches
+// ErrRootMismatch.
+type RootMismatchError struct {
+	Root      string // The root the proof was checked against
+	ProofRoot string // The root the proof records
+}
+
+func (e *RootMismatchError) Error() string {
+	return fmt.Sprintf("proof is for root %s, not %s", e.ProofRoot, e.Root)
+}
+
+// Is reports whether target is ErrRootMismatch
+func (e *RootMismatchError) Is(target error) bool {
+	return target == ErrRootMismatch
+}
+
+// AllocationChangedError reports an address whose claim differs between
+// two trees, or that only one of them has a claim for. It matches
+// ErrAllocationChanged.
+type AllocationChangedError struct {
+	Difference *DifferenceProof
+}
+
+func (e *AllocationChangedError) Error() string {
+	d := e.Difference
+	switch {
+	case d.Old == nil:
+		return fmt.Sprintf("%s was added in root %s with %s at index %d", d.Address, d.NewRoot, d.New.Amount, d.New.Index)
+	case d.New == nil:
+		return fmt.Sprintf("%s was removed in root %s, having had %s at index %d", d.Address, d.NewRoot, d.Old.Amount, d.Old.Index)
+	}
+	return fmt.Sprintf("%s changed from %s at index %d to %s at index %d in root %s", d.Address, d.Old.Amount, d.Old.Index, d.New.Amount, d.New.Index, d.NewRoot)
+}
+
+// Is reports whether target is ErrAllocationChanged
+func (e *AllocationChangedError) Is(target error) bool {
+	return target == ErrAllocationChanged
+}
+
+// TooManyClaimsError reports claims over a MaxClaims limit. Loaders stop
+// reading at the first claim over the limit, so for them Seen is Limit+1
+// and Row is where they stopped. It matches ErrTooManyClaims.
+type TooManyClaimsError struct {
+	Limit int
+	Seen  int // Claims counted
+	Row   int // Row of the claim over the limit when a loader stopped; zero when all were counted
+}
+
+func (e *TooManyClaimsError) Error() string {
+	if e.Row > 0 {
+		return fmt.Sprintf("more than the limit of %d claims: stopped reading at row %d after %d claims", e.Limit, e.Row, e.Seen)
+	}
+	return fmt.Sprintf("%d claims are over the limit of %d", e.Seen, e.Limit)
+}
+
+// Is reports whether target is ErrTooManyClaims
+func (e *TooManyClaimsError) Is(target error) bool {
+	return target == ErrTooManyClaims
+}
+
+// CheckClaimCount returns a TooManyClaimsError when count is over limit,
+// which is unlimited when zero
+func CheckClaimCount(count, limit int) error {
+	if limit > 0 && count > limit {
+		return &TooManyClaimsError{Limit: limit, Seen: count}
+	}
+	return nil
+}
+
+// ExistingClaimError reports claims added to a tree for addresses it
+// already has a claim for
+type ExistingClaimError struct {
+	Claims []AirdropClaim // The tree's claims for the addresses
+}
+
+func (e *ExistingClaimError) Error() string {
+	first := e.Claims[0]
+	if len(e.Claims) == 1 {
+		return fmt.Sprintf("%s already has a claim of %s", first.Address.Hex(), first.Amount)
+	}
+	return fmt.Sprintf("%d addresses already have claims, the first %s of %s", len(e.Claims), first.Address.Hex(), first.Amount)
+}
+
+// proofError returns an InvalidProofError with a formatted reason
+func proofError(format string, args ...interface{}) error {
+	return &InvalidProofError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// CheckAmount returns an InvalidAmountError unless amount is a valid
+// uint256; index locates its claim in the error
+func CheckAmount(index int, amount *big.Int) error {
+	switch {
+	case amount == nil:
+		return &InvalidAmountError{Index: index, Reason: "missing"}
+	case amount.Sign() < 0:
+		return &InvalidAmountError{Index: index, Value: amount.String(), Reason: "must not be negative"}
+	case amount.BitLen() > 256:
+		return &InvalidAmountError{Index: index, Value: amount.String(), Reason: "exceeds 2^256-1"}
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+// pkg/merkle/generic.go
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// MaxLeafKeyLength is the longest key a leaf can carry: one 32-byte word
+const MaxLeafKeyLength = 32
+
+// LeafKey identifies a claimant by raw bytes, such as a 20-byte Ethereum
+// address or a 32-byte Solana public key
+type LeafKey []byte
+
+// String returns the key as 0x-prefixed lowercase hex
+func (k LeafKey) String() string {
+	return "0x" + hex.EncodeToString(k)
+}
+
+// Validate checks that k has between 1 and MaxLeafKeyLength bytes
+func (k LeafKey) Validate() error {
+	if len(k) == 0 || len(k) > MaxLeafKeyLength {
+		return fmt.Errorf("invalid key %s: expected 1 to %d bytes, got %d", k, MaxLeafKeyLength, len(k))
+	}
+	return nil
+}
+
+// GenericClaim is an airdrop entry keyed by raw bytes instead of an address
+type GenericClaim struct {
+	Key    LeafKey  `json:"key"`
+	Amount *big.Int `json:"amount"`
+	Index  uint32   `json:"index"`
+}
+
+// Generic returns claim keyed by its address bytes. It hashes to the same
+// leaf under every TreeOptions.
+func (claim AirdropClaim) Generic() GenericClaim {
+	return GenericClaim{Key: claim.Address.Bytes(), Amount: claim.Amount, Index: claim.Index}
+}
+
+// GenericMerkleTree is a Merkle tree over claims keyed by raw bytes. It
+// hashes leaves with HashGenericLeaf and pairs like MerkleTree, so a tree of
+// address keys in the same leaf order has the same root.
+type GenericMerkleTree struct {
+	Claims []GenericClaim
+
+	options TreeOptions
+	levels  [][][]byte     // Node hashes per level, leaves first
+	index   map[string]int // Leaf position by key bytes
+}
+
+// NewGenericMerkleTree builds a tree from claims, which are always copied.
+// SortByAddress orders leaves by key bytes; unlike MerkleTree, which
+// compares checksummed hex, so address keys only reproduce an EVM tree's
+// root when given in its leaf order with PreserveInput. Keys must be unique.
+func NewGenericMerkleTree(claims []GenericClaim, opts TreeOptions) (*GenericMerkleTree, error) {
+	if len(claims) == 0 {
+		return nil, ErrEmptyClaims
+	}
+
+	copied := make([]GenericClaim, len(claims))
+	for i, claim := range claims {
+		if err := claim.Key.Validate(); err != nil {
+			return nil, fmt.Errorf("claim %d: %w", i, err)
+		}
+		if err := CheckAmount(i, claim.Amount); err != nil {
+			return nil, fmt.Errorf("claim %d (%s): %w", i, claim.Key, err)
+		}
+		copied[i] = GenericClaim{
+			Key:    bytes.Clone(claim.Key),
+			Amount: new(big.Int).Set(claim.Amount),
+			Index:  claim.Index,
+		}
+	}
+	claims = copied
+	err := checkAmountBits(len(claims), opts.MaxAmountBits, func(i int) (string, *big.Int) {
+		return claims[i].Key.String(), claims[i].Amount
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.SortOrder {
+	case SortByAddress:
+		sort.Slice(claims, func(i, j int) bool {
+			return bytes.Compare(claims[i].Key, claims[j].Key) < 0
+		})
+		if !opts.KeepIndices {
+			for i := range claims {
+				claims[i].Index = uint32(i)
+			}
+			break
+		}
+		if err := checkUniqueGenericIndices(claims); err != nil {
+			return nil, err
+		}
+	case SortByIndex:
+		sort.SliceStable(claims, func(i, j int) bool {
+			return claims[i].Index < claims[j].Index
+		})
+		fallthrough
+	case PreserveInput:
+		if err := checkUniqueGenericIndices(claims); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort order: %d", int(opts.SortOrder))
+	}
+	if _, ok := oddLeafPolicyNames[opts.OddLeafPolicy]; !ok {
+		return nil, fmt.Errorf("unknown odd leaf policy: %d", int(opts.OddLeafPolicy))
+	}
+	if err := checkWorkers(opts.Workers); err != nil {
+		return nil, err
+	}
+	if err := checkFixedDepth(opts.FixedDepth); err != nil {
+		return nil, err
+	}
+
+	tree := &GenericMerkleTree{
+		Claims:  claims,
+		options: opts,
+		index:   make(map[string]int, len(claims)),
+	}
+	for i, claim := range claims {
+		if _, exists := tree.index[string(claim.Key)]; exists {
+			return nil, fmt.Errorf("duplicate key %s", claim.Key)
+		}
+		tree.index[string(claim.Key)] = i
+	}
+
+	leaves := make([][]byte, len(claims))
+	parallelRange(len(claims), opts.Workers, opts.OnWorkerStart, func(start, end int) {
+		for i := start; i < end; i++ {
+			claim := &claims[i]
+			// Keys and amounts were checked above, so hashing cannot fail
+			leaves[i], _ = HashGenericLeaf(claim.Key, claim.Amount, claim.Index, opts)
+		}
+	})
+
+	levels, err := hashLevels(leaves, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTreeDepth(len(levels), opts); err != nil {
+		return nil, err
+	}
+	tree.levels = levels
+
+	return tree, nil
+}
+
+// checkUniqueGenericIndices is checkUniqueIndices for generic claims
+func checkUniqueGenericIndices(claims []GenericClaim) error {
+	seen := make(map[uint32]bool, len(claims))
+	for _, claim := range claims {
+		if seen[claim.Index] {
+			return fmt.Errorf("duplicate claim index %d", claim.Index)
+		}
+		seen[claim.Index] = true
+	}
+	return nil
+}
+
+// hashLevels hashes leaves up to the root, pairing them and odd nodes like
+// MerkleTree.buildTree, and returns every level, leaves first
+func hashLevels(leaves [][]byte, opts TreeOptions) ([][][]byte, error) {
+	levels := [][][]byte{leaves}
+	for nodes := leaves; len(nodes) > 1; {
+		next := make([][]byte, (len(nodes)+1)/2)
+
+		var (
+			errOnce  sync.Once
+			levelErr error
+		)
+		parallelRange(len(next), opts.Workers, opts.OnWorkerStart, func(start, end int) {
+			for p := start; p < end; p++ {
+				left, right := nodes[2*p], nodes[2*p]
+				if 2*p+1 < len(nodes) {
+					right = nodes[2*p+1]
+				} else if opts.OddLeafPolicy == Promote {
+					next[p] = left
+					continue
+				} else if opts.OddLeafPolicy == ZeroPad {
+					right = zeroHash[:]
+				}
+				hash, err := HashPair(left, right, opts)
+				if err != nil {
+					errOnce.Do(func() { levelErr = fmt.Errorf("failed to hash level %d: %w", len(levels), err) })
+					return
+				}
+				next[p] = hash
+			}
+		})
+		if levelErr != nil {
+			return nil, levelErr
+		}
+
+		levels = append(levels, next)
+		nodes = next
+	}
+	return levels, nil
+}
+
+// Root returns the root hash
+func (gt *GenericMerkleTree) Root() []byte {
+	return gt.levels[len(gt.levels)-1][0]
+}
+
+// GetRootHash returns the root hash as hex string
+func (gt *GenericMerkleTree) GetRootHash() string {
+	return EncodeHash(gt.Root())
+}
+
+// Options returns the options the tree was built with
+func (gt *GenericMerkleTree) Options() TreeOptions {
+	return gt.options
+}
+
+// FindClaim returns the claim for key without generating its proof
+func (gt *GenericMerkleTree) FindClaim(key LeafKey) (GenericClaim, bool) {
+	i, ok := gt.index[string(key)]
+	if !ok {
+		return GenericClaim{}, false
+	}
+	return gt.Claims[i], true
+}
+
+// GenerateProof creates a Merkle proof for the claim keyed by key
+func (gt *GenericMerkleTree) GenerateProof(key LeafKey) (*MerkleProof, error) {
+	i, ok := gt.index[string(key)]
+	if !ok {
+		return nil, fmt.Errorf("key not found in tree")
+	}
+	return gt.proofForLeaf(i, gt.GetRootHash()), nil
+}
+
+// GenerateAllProofs generates proofs for all claims, keyed by
+// LeafKey.String
+func (gt *GenericMerkleTree) GenerateAllProofs() (map[string]*MerkleProof, error) {
+	proofs := make(map[string]*MerkleProof, len(gt.Claims))
+	root := gt.GetRootHash()
+	for i, claim := range gt.Claims {
+		proofs[claim.Key.String()] = gt.proofForLeaf(i, root)
+	}
+	return proofs, nil
+}
+
+// proofForLeaf assembles the proof for leaf i under root, the tree's
+// GetRootHash
+func (gt *GenericMerkleTree) proofForLeaf(i int, root string) *MerkleProof {
+	path, positions := proofPath(gt.levels, i, make([][]byte, 0, len(gt.levels)), gt.options.OddLeafPolicy)
+	path, padding := padProof(path, gt.Root(), gt.options)
+	claim := gt.Claims[i]
+	proof := &MerkleProof{
+		Proof:  encodeProof(path),
+		Index:  claim.Index,
+		Amount: claim.Amount.String(),
+
+		PaddingCount: padding,
+		Root:         root,
+	}
+	if !gt.options.SortedPairs {
+		proof.Positions = positions
+	}
+	return proof
+}
+
+// VerifyGenericProof checks that claim is included under root using a
+// hex-encoded proof. positions is the MerkleProof's sibling bitmap, ignored
+// when opts uses sorted pairs.
+func VerifyGenericProof(root []byte, claim GenericClaim, proof []string, positions uint64, opts TreeOptions) (bool, error) {
+	path, err := decodeProof(proof)
+	if err != nil {
+		return false, err
+	}
+	if err := checkProofHashes(path); err != nil {
+		return false, err
+	}
+	if path, err = trimPadding(path, root, opts); err != nil {
+		return false, err
+	}
+
+	leaf, err := HashGenericLeaf(claim.Key, claim.Amount, claim.Index, opts)
+	if err != nil {
+		return false, err
+	}
+	computed, err := foldLeaf(leaf, path, positions, opts)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(computed, root), nil
+}
@@ -3,50 +3,152 @@ package merkle
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math/big"
+	"slices"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// HashLeaf creates a hash for a leaf node (address + amount)
-func HashLeaf(address common.Address, amount *big.Int, index uint32) []byte {
-	// Create a buffer to hold our data
-	data := make([]byte, 0, 32+32+4) // address(20) + amount(32) + index(4)
+// HashLeaf creates a hash for a leaf node (address + amount + index)
+func HashLeaf(address common.Address, amount *big.Int, index uint32) ([]byte, error) {
+	return HashLeafWithOptions(address, amount, index, DefaultTreeOptions())
+}
+
+// HashLeafWithOptions creates a leaf hash using the encoding selected by opts.
+// Without IncludeIndex the preimage is abi.encodePacked(address, amount), as
+// index-free distributor contracts expect. A DomainSeparator goes before
+// the preimage. Amounts that aren't a valid
+// uint256 are an error; see ValidAmount.
+func HashLeafWithOptions(address common.Address, amount *big.Int, index uint32, opts TreeOptions) ([]byte, error) {
+	hash := make([]byte, 32)
+	if err := HashLeafInto(hash, AirdropClaim{Address: address, Amount: amount, Index: index}, opts); err != nil {
+		return nil, err
+	}
+	return hash, nil
+}
+
+// HashGenericLeaf hashes a leaf keyed by key with the encoding selected by
+// opts, key taking the place of the address: left-padded to 32 bytes by
+// default and written as-is in the packed encodings. A 20-byte key hashes
+// exactly like the address with those bytes.
+func HashGenericLeaf(key LeafKey, amount *big.Int, index uint32, opts TreeOptions) ([]byte, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	// FillBytes would panic on an invalid amount
+	if err := CheckAmount(int(index), amount); err != nil {
+		return nil, err
+	}
+
+	hash := make([]byte, 32)
+	hashLeafData(hash, key, amount, index, opts)
+	return hash, nil
+}
+
+// EncodeLeafPreimage appends the bytes claim's leaf hash is the Keccak-256
+// of to dst: opts' DomainSeparator, then the fields in opts' encoding. It
+// allocates only when dst is short of capacity.
+func EncodeLeafPreimage(dst []byte, claim AirdropClaim, opts TreeOptions) ([]byte, error) {
+	if err := CheckAmount(int(claim.Index), claim.Amount); err != nil {
+		return dst, err
+	}
+	dst = append(dst, opts.DomainSeparator...)
+	return appendLeafData(dst, claim.Address[:], claim.Amount, claim.Index, opts), nil
+}
+
+// HashLeafInto writes claim's leaf hash in opts' encoding to dst, which
+// must be 32 bytes long, without allocating
+func HashLeafInto(dst []byte, claim AirdropClaim, opts TreeOptions) error {
+	if len(dst) != 32 {
+		return fmt.Errorf("invalid hash buffer length: %d bytes, expected 32", len(dst))
+	}
+	if err := CheckAmount(int(claim.Index), claim.Amount); err != nil {
+		return err
+	}
+	hashLeafData(dst, claim.Address[:], claim.Amount, claim.Index, opts)
+	return nil
+}
 
-	// Add address (pad to 32 bytes)
-	addressBytes := make([]byte, 32)
-	copy(addressBytes[12:], address.Bytes()) // Ethereum addresses are 20 bytes
-	data = append(data, addressBytes...)
+// hashLeafData writes the leaf hash of a checked key and amount to dst,
+// with a pooled buffer and Keccak state
+func hashLeafData(dst, key []byte, amount *big.Int, index uint32, opts TreeOptions) {
+	bufPtr := HashPool.Get().(*[]byte)
+	data := appendLeafData((*bufPtr)[:0], key, amount, index, opts)
 
-	// Add amount (pad to 32 bytes)
-	amountBytes := make([]byte, 32)
-	amount.FillBytes(amountBytes)
-	data = append(data, amountBytes...)
+	hasher := keccakPool.Get().(crypto.KeccakState)
+	hasher.Reset()
+	hasher.Write(opts.DomainSeparator)
+	hasher.Write(data)
+	hasher.Read(dst)
+	keccakPool.Put(hasher)
 
-	// Add index
-	indexBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(indexBytes, index)
-	data = append(data, indexBytes...)
+	*bufPtr = data
+	HashPool.Put(bufPtr)
+}
+
+// appendLeafData appends a leaf's fields in opts' encoding to dst, the
+// domain separator aside. Every leaf hash is built from it.
+func appendLeafData(dst, key []byte, amount *big.Int, index uint32, opts TreeOptions) []byte {
+	start := len(dst)
+	switch {
+	case !opts.IncludeIndex:
+		// key | amount(32), with the raw key as abi.encodePacked writes it
+		dst = grow(dst, len(key)+32)
+		copy(dst[start:], key)
+		amount.FillBytes(dst[start+len(key):])
+	case opts.IndexFirst:
+		// index(32) | key | amount(32)
+		dst = grow(dst, 32+len(key)+32)
+		clear(dst[start : start+28])
+		binary.BigEndian.PutUint32(dst[start+28:start+32], index)
+		copy(dst[start+32:], key)
+		amount.FillBytes(dst[start+32+len(key):])
+	default:
+		// key (padded to 32) | amount(32) | index(4)
+		dst = grow(dst, 32+32+4)
+		clear(dst[start : start+32-len(key)])
+		copy(dst[start+32-len(key):start+32], key)
+		amount.FillBytes(dst[start+32 : start+64])
+		binary.BigEndian.PutUint32(dst[start+64:], index)
+	}
+	return dst
+}
 
-	// Return Keccak256 hash (Ethereum standard)
-	return crypto.Keccak256(data)
+// grow extends dst by n bytes, reallocating only without the capacity
+func grow(dst []byte, n int) []byte {
+	return slices.Grow(dst, n)[:len(dst)+n]
 }
 
-// HashInternal creates a hash for internal nodes
-func HashInternal(left, right []byte) []byte {
-	// Sort hashes to ensure deterministic tree
+// ValidAmount reports whether amount can be hashed into a leaf: set,
+// non-negative and no wider than a uint256
+func ValidAmount(amount *big.Int) bool {
+	return amount != nil && amount.Sign() >= 0 && amount.BitLen() <= 256
+}
+
+// HashInternal creates a hash for internal nodes. Proof elements may come
+// from users, so hashes that aren't 32 bytes are an error.
+func HashInternal(left, right []byte) ([]byte, error) {
 	if len(left) != 32 || len(right) != 32 {
-		panic("Invalid hash length")
+		return nil, fmt.Errorf("invalid hash length: %d and %d bytes, expected 32", len(left), len(right))
 	}
 
-	var data []byte
 	// Smaller hash goes first for deterministic ordering
 	if string(left) < string(right) {
-		data = append(left, right...)
-	} else {
-		data = append(right, left...)
+		return crypto.Keccak256(left, right), nil
 	}
+	return crypto.Keccak256(right, left), nil
+}
 
-	return crypto.Keccak256(data)
+// HashPair hashes two child nodes the way opts selects: sorted like
+// HashInternal, or left before right for position-aware contracts
+func HashPair(left, right []byte, opts TreeOptions) ([]byte, error) {
+	if opts.SortedPairs {
+		return HashInternal(left, right)
+	}
+	if len(left) != 32 || len(right) != 32 {
+		return nil, fmt.Errorf("invalid hash length: %d and %d bytes, expected 32", len(left), len(right))
+	}
+	return crypto.Keccak256(left, right), nil
 }
@@ -0,0 +1,105 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// HashLength is the length in bytes of every hash in a tree
+const HashLength = 32
+
+// Hashes and amounts are written as 0x-prefixed lowercase hex of a fixed
+// width, so exports from any path compare equal as strings and pass
+// strict-length validators: 66 characters for a hash, and 66 for an
+// amount written as its 32-byte uint256 word. Decoding requires the 0x
+// prefix and an even number of digits, in either case, and rejects input
+// longer than the value it decodes rather than truncating it.
+
+// ErrInvalidHex is matched by the errors of the decoders below
+var ErrInvalidHex = errors.New("invalid hex")
+
+// EncodeHash returns hash as 0x-prefixed lowercase hex
+func EncodeHash(hash []byte) string {
+	buf := make([]byte, 2+hex.EncodedLen(len(hash)))
+	copy(buf, "0x")
+	hex.Encode(buf[2:], hash)
+	return string(buf)
+}
+
+// EncodeUint256 returns amount as its 32-byte big-endian uint256 word in
+// 0x-prefixed lowercase hex, leading zeros included
+func EncodeUint256(amount *big.Int) (string, error) {
+	if !ValidAmount(amount) {
+		return "", fmt.Errorf("%w: %v is not a uint256", ErrInvalidHex, amount)
+	}
+	return fmt.Sprintf("0x%064x", amount), nil
+}
+
+// DecodeHex decodes s, 0x-prefixed hex of exactly length bytes
+func DecodeHex(s string, length int) ([]byte, error) {
+	digits, err := hexDigits(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(digits) != 2*length {
+		return nil, fmt.Errorf("%w: %q is %d bytes, expected %d", ErrInvalidHex, s, len(digits)/2, length)
+	}
+	return hex.DecodeString(digits)
+}
+
+// DecodeHash decodes a 0x-prefixed 32-byte hash
+func DecodeHash(s string) ([]byte, error) {
+	return DecodeHex(s, HashLength)
+}
+
+// decodeHexBytes decodes 0x-prefixed hex of any length
+func decodeHexBytes(s string) ([]byte, error) {
+	digits, err := hexDigits(s)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(digits)
+}
+
+// DecodeUint256 decodes a 0x-prefixed amount of at most 32 bytes. Shorter
+// amounts, as other tools write them, are accepted; leading zeros are
+// allowed.
+func DecodeUint256(s string) (*big.Int, error) {
+	digits, err := hexDigits(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(digits) == 0 || len(digits) > 2*HashLength {
+		return nil, fmt.Errorf("%w: %q is %d bytes, expected 1 to 32", ErrInvalidHex, s, len(digits)/2)
+	}
+	decoded, err := hex.DecodeString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", ErrInvalidHex, s, err)
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// hexDigits returns the digits of s after its 0x prefix, checking that
+// they are whole bytes of hex
+func hexDigits(s string) (string, error) {
+	digits, ok := strings.CutPrefix(s, "0x")
+	if !ok {
+		return "", fmt.Errorf("%w: %q has no 0x prefix", ErrInvalidHex, s)
+	}
+	if len(digits)%2 != 0 {
+		return "", fmt.Errorf("%w: %q has an odd number of digits", ErrInvalidHex, s)
+	}
+	for i := 0; i < len(digits); i++ {
+		if !isHexDigit(digits[i]) {
+			return "", fmt.Errorf("%w: %q has a non-hex character at %d", ErrInvalidHex, s, 2+i)
+		}
+	}
+	return digits, nil
+}
+
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
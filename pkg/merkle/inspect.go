@@ -0,0 +1,156 @@
+// pkg/merkle/inspect.go
+package merkle
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// PathStep is one level of the path from a leaf to the root
+type PathStep struct {
+	Level    int    // 0 for the leaves
+	Position int    // Node position within the level
+	Hash     []byte // The node on the path
+	Sibling  []byte // Hashed with Hash; nil when the node is promoted
+	Parent   []byte // The node one level up
+}
+
+// Levels returns the number of levels in the tree, leaves and root included
+func (mt *MerkleTree) Levels() int {
+	return len(mt.levels)
+}
+
+// Level returns the node hashes of level i, 0 being the leaves and
+// Levels()-1 the root, or nil when there is no such level. The hashes are
+// the tree's own and must not be modified.
+func (mt *MerkleTree) Level(i int) [][]byte {
+	if i < 0 || i >= len(mt.levels) {
+		return nil
+	}
+	return slices.Clone(mt.levels[i])
+}
+
+// PathForAddress returns the nodes from address's leaf up to, but not
+// including, the root, with the sibling each is hashed with
+func (mt *MerkleTree) PathForAddress(address common.Address) ([]PathStep, error) {
+	i, ok := mt.index[address]
+	if !ok {
+		return nil, ErrAddressNotFound
+	}
+
+	steps := make([]PathStep, 0, len(mt.levels)-1)
+	for level, hashes := range mt.levels[:len(mt.levels)-1] {
+		steps = append(steps, PathStep{
+			Level:    level,
+			Position: i,
+			Hash:     hashes[i],
+			Sibling:  mt.siblingAt(level, i),
+			Parent:   mt.levels[level+1][i/2],
+		})
+		i /= 2
+	}
+	return steps, nil
+}
+
+// siblingAt returns the hash node i of level is paired with, or nil when
+// it is promoted
+func (mt *MerkleTree) siblingAt(level, i int) []byte {
+	hashes := mt.levels[level]
+	if i^1 < len(hashes) {
+		return hashes[i^1]
+	}
+	switch mt.options.OddLeafPolicy {
+	case Promote:
+		return nil
+	case ZeroPad:
+		return zeroHash[:]
+	}
+	return hashes[i]
+}
+
+// ExportDOT writes the whole tree as a Graphviz digraph, root at the top
+// and leaves labelled with their addresses. Trees with more than maxLeaves
+// leaves are refused; see ExportAddressDOT for those.
+func (mt *MerkleTree) ExportDOT(w io.Writer, maxLeaves int) error {
+	if len(mt.Leaves) > maxLeaves {
+		return fmt.Errorf("tree has %d leaves, more than the %d allowed", len(mt.Leaves), maxLeaves)
+	}
+
+	return mt.writeDOT(w, func(level, i int) bool { return true })
+}
+
+// ExportAddressDOT writes the path from address's leaf to the root and the
+// siblings along it as a Graphviz digraph, for trees too large to draw
+func (mt *MerkleTree) ExportAddressDOT(w io.Writer, address common.Address) error {
+	leaf, ok := mt.index[address]
+	if !ok {
+		return ErrAddressNotFound
+	}
+
+	return mt.writeDOT(w, func(level, i int) bool {
+		return i>>1 == leaf>>(level+1)
+	})
+}
+
+// writeDOT writes the nodes include selects, with an edge from each to the
+// children it was hashed from
+func (mt *MerkleTree) writeDOT(w io.Writer, include func(level, i int) bool) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph merkle {")
+	fmt.Fprintln(bw, "  node [shape=box, fontname=monospace];")
+
+	top := len(mt.levels) - 1
+	for level := top; level >= 0; level-- {
+		for i, hash := range mt.levels[level] {
+			if level < top && !include(level, i) {
+				continue
+			}
+			label := shortHash(hash)
+			if level == 0 && mt.Leaves[i].Data != nil {
+				label += `\n` + mt.Leaves[i].Data.Address.Hex()
+			} else if level == top {
+				label = "root " + label
+			}
+			fmt.Fprintf(bw, "  %s [label=\"%s\"];\n", dotNode(level, i), label)
+
+			// The children of node i, when they are drawn too
+			left := 2 * i
+			if level == 0 || !include(level-1, left) {
+				continue
+			}
+			fmt.Fprintf(bw, "  %s -> %s;\n", dotNode(level, i), dotNode(level-1, left))
+			switch {
+			case left+1 < len(mt.levels[level-1]):
+				fmt.Fprintf(bw, "  %s -> %s;\n", dotNode(level, i), dotNode(level-1, left+1))
+			case mt.options.OddLeafPolicy == Promote:
+				// The single edge is the whole story
+			case mt.options.OddLeafPolicy == ZeroPad:
+				fmt.Fprintf(bw, "  %s_pad [label=\"zero\", style=dashed];\n", dotNode(level-1, left))
+				fmt.Fprintf(bw, "  %s -> %s_pad;\n", dotNode(level, i), dotNode(level-1, left))
+			default:
+				fmt.Fprintf(bw, "  %s -> %s [style=dashed];\n", dotNode(level, i), dotNode(level-1, left))
+			}
+		}
+	}
+
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotNode names node i of level in DOT output
+func dotNode(level, i int) string {
+	return fmt.Sprintf("n%d_%d", level, i)
+}
+
+// shortHash abbreviates a hash to its first and last four bytes
+func shortHash(hash []byte) string {
+	if len(hash) <= 8 {
+		return EncodeHash(hash)
+	}
+	return "0x" + hex.EncodeToString(hash[:4]) + "…" + hex.EncodeToString(hash[len(hash)-4:])
+}
@@ -0,0 +1,242 @@
+// pkg/merkle/leaves.go
+package merkle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// leafHashSize is the length of every entry of a leaf hash file
+const leafHashSize = 32
+
+// LeafFileFormat selects how a leaf hash file stores its hashes
+type LeafFileFormat int
+
+const (
+	// LeafFileHex stores one hex hash per line, with or without 0x
+	LeafFileHex LeafFileFormat = iota
+	// LeafFileBinary stores the raw 32-byte hashes back to back
+	LeafFileBinary
+)
+
+var leafFileFormatNames = map[LeafFileFormat]string{
+	LeafFileHex:    "hex",
+	LeafFileBinary: "binary",
+}
+
+func (f LeafFileFormat) String() string {
+	if name, ok := leafFileFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("LeafFileFormat(%d)", int(f))
+}
+
+// ParseLeafFileFormat parses a format name as printed by String
+func ParseLeafFileFormat(name string) (LeafFileFormat, error) {
+	for format, formatName := range leafFileFormatNames {
+		if formatName == name {
+			return format, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown leaf file format %q (expected hex or binary)", name)
+}
+
+// errNoClaims is returned by the methods that need claims when the tree
+// was built from leaf hashes
+var errNoClaims = fmt.Errorf("tree was built from leaf hashes and has no claims; use ProofAt")
+
+// BuildFromLeafHashFile builds a tree from a file of precomputed leaf
+// hashes with default options, keeping the leaves in file order
+func BuildFromLeafHashFile(path string, format LeafFileFormat) (*MerkleTree, error) {
+	return BuildFromLeafHashFileWithOptions(path, format, DefaultTreeOptions())
+}
+
+// BuildFromLeafHashFileWithOptions builds a tree from a file of leaf
+// hashes, pairing nodes per opts. The leaves keep the file's order whatever
+// opts.SortOrder says, and the options that describe the leaf encoding are
+// only recorded in the metadata. The tree has no claims, so its proofs are
+// read by leaf position with ProofAt.
+func BuildFromLeafHashFileWithOptions(path string, format LeafFileFormat, opts TreeOptions) (*MerkleTree, error) {
+	if _, ok := oddLeafPolicyNames[opts.OddLeafPolicy]; !ok {
+		return nil, fmt.Errorf("unknown odd leaf policy: %d", int(opts.OddLeafPolicy))
+	}
+	if err := checkWorkers(opts.Workers); err != nil {
+		return nil, err
+	}
+	if err := checkFixedDepth(opts.FixedDepth); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open leaf file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open leaf file: %w", err)
+	}
+
+	var hashes []byte
+	switch format {
+	case LeafFileHex:
+		hashes, err = readHexLeaves(file, info.Size())
+	case LeafFileBinary:
+		hashes, err = readBinaryLeaves(file, info.Size())
+	default:
+		return nil, fmt.Errorf("unknown leaf file format: %d", int(format))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("%s: no leaf hashes", path)
+	}
+	if len(hashes)/leafHashSize > math.MaxUint32 {
+		return nil, fmt.Errorf("%s: %d leaves, more than a uint32 index can address", path, len(hashes)/leafHashSize)
+	}
+
+	// The leaves are slices of the one buffer the file was read into
+	leaves := make([]*MerkleNode, len(hashes)/leafHashSize)
+	for i := range leaves {
+		leaves[i] = &MerkleNode{Hash: hashes[i*leafHashSize : (i+1)*leafHashSize : (i+1)*leafHashSize]}
+	}
+	tree := &MerkleTree{
+		Leaves:      leaves,
+		options:     opts,
+		fingerprint: fingerprintLeaves(leaves),
+		index:       map[common.Address]int{},
+	}
+	root, err := tree.buildTree(leaves)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTreeDepth(len(tree.levels), opts); err != nil {
+		return nil, err
+	}
+	tree.Root = root
+	return tree, nil
+}
+
+// readBinaryLeaves reads back-to-back hashes into a buffer of the file's size
+func readBinaryLeaves(r io.Reader, size int64) ([]byte, error) {
+	if size%leafHashSize != 0 {
+		return nil, fmt.Errorf("size %d is not a multiple of %d: the last entry has %d bytes", size, leafHashSize, size%leafHashSize)
+	}
+	hashes := make([]byte, size)
+	if _, err := io.ReadFull(r, hashes); err != nil {
+		return nil, fmt.Errorf("failed to read leaf hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// readHexLeaves decodes one hash per line into a buffer sized for lines of
+// 0x-prefixed hashes, which is what the file's size suggests
+func readHexLeaves(r io.Reader, size int64) ([]byte, error) {
+	hashes := make([]byte, 0, size/(2+2*leafHashSize+1)*leafHashSize)
+	scanner := bufio.NewScanner(r)
+	var hash [leafHashSize]byte
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "0x")
+		if len(entry) != 2*leafHashSize {
+			return nil, fmt.Errorf("line %d: expected a %d-byte hash, got %d hex digits", line, leafHashSize, len(entry))
+		}
+		if _, err := hex.Decode(hash[:], []byte(entry)); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		hashes = append(hashes, hash[:]...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read leaf hashes: %w", err)
+	}
+	return hashes, nil
+}
+
+// WriteLeafHashFile writes the tree's leaf hashes in leaf order, the file
+// BuildFromLeafHashFile reads back
+func WriteLeafHashFile(path string, tree *MerkleTree, format LeafFileFormat) error {
+	if _, ok := leafFileFormatNames[format]; !ok {
+		return fmt.Errorf("unknown leaf file format: %d", int(format))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create leaf file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	var line [2 + 2*leafHashSize + 1]byte
+	copy(line[:], "0x")
+	line[len(line)-1] = '\n'
+	for _, leaf := range tree.Leaves {
+		if format == LeafFileBinary {
+			w.Write(leaf.Hash)
+			continue
+		}
+		hex.Encode(line[2:], leaf.Hash)
+		w.Write(line[:])
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write leaf file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write leaf file: %w", err)
+	}
+	return nil
+}
+
+// ProofAt returns the proof of the leaf at position. It's the only way to
+// read proofs from a tree built from leaf hashes, whose proofs have no
+// amount and carry the position as their index.
+func (mt *MerkleTree) ProofAt(position int) (*MerkleProof, error) {
+	if position < 0 || position >= len(mt.Leaves) {
+		return nil, fmt.Errorf("leaf position %d out of range [0, %d)", position, len(mt.Leaves))
+	}
+	if mt.Claims != nil {
+		if err := mt.checkLeaf(mt.Leaves[position]); err != nil {
+			return nil, err
+		}
+		return mt.proofForLeaf(position, nil, mt.GetRootHash()), nil
+	}
+
+	path, positions := mt.generateProofPath(uint32(position), nil)
+	path, padding := padProof(path, mt.Root.Hash, mt.options)
+	proof := &MerkleProof{Proof: encodeProof(path), Index: uint32(position), PaddingCount: padding, Root: mt.GetRootHash()}
+	if !mt.options.SortedPairs {
+		proof.Positions = positions
+	}
+	return proof, nil
+}
+
+// VerifyLeafProof checks that an already hashed leaf is included under root,
+// for proofs from ProofAt of a tree built from leaf hashes. positions is
+// ignored when opts uses sorted pairs.
+func VerifyLeafProof(root, leaf []byte, proof []string, positions uint64, opts TreeOptions) (bool, error) {
+	if len(leaf) != leafHashSize {
+		return false, fmt.Errorf("invalid leaf: expected %d bytes, got %d", leafHashSize, len(leaf))
+	}
+	path, err := decodeProof(proof)
+	if err != nil {
+		return false, err
+	}
+	if err := checkProofHashes(path); err != nil {
+		return false, err
+	}
+	if path, err = trimPadding(path, root, opts); err != nil {
+		return false, err
+	}
+
+	computed, err := foldLeaf(leaf, path, positions, opts)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(computed, root), nil
+}
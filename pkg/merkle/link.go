@@ -0,0 +1,179 @@
+package merkle
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// ClaimLinkParam is the query parameter holding a claim link payload
+	ClaimLinkParam = "claim"
+
+	// MaxClaimLinkPayload is the longest encoded payload accepted, which
+	// keeps links printable as QR codes
+	MaxClaimLinkPayload = 2048
+
+	// maxClaimLinkJSON bounds the decompressed payload so a small forged
+	// link cannot expand into a large allocation
+	maxClaimLinkJSON = 8192
+)
+
+// claimLinkPayload is the gzip-compressed JSON carried by a claim link
+type claimLinkPayload struct {
+	Address string   `json:"address"`
+	Amount  string   `json:"amount"`
+	Index   uint32   `json:"index"`
+	Proof   []string `json:"proof"`
+	Root    string   `json:"root"`
+
+	Positions    uint64 `json:"positions,omitempty"`
+	PaddingCount int    `json:"paddingCount,omitempty"`
+}
+
+// EncodeClaimLink returns baseURL with claim and its proof pre-filled, for
+// a tree built with default options
+func EncodeClaimLink(baseURL string, claim AirdropClaim, proof *MerkleProof) (string, error) {
+	return EncodeClaimLinkWithOptions(baseURL, claim, proof, DefaultTreeOptions())
+}
+
+// EncodeClaimLinkWithOptions is EncodeClaimLink for a tree built with opts.
+// The root is derived from the claim and proof, so a link cannot name a
+// root its proof does not reach.
+func EncodeClaimLinkWithOptions(baseURL string, claim AirdropClaim, proof *MerkleProof, opts TreeOptions) (string, error) {
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	path, err := decodeProof(proof.Proof)
+	if err != nil {
+		return "", err
+	}
+	if err := checkClaimProof(claim, path); err != nil {
+		return "", err
+	}
+	if proof.PaddingCount < 0 || proof.PaddingCount > len(path) {
+		return "", proofError("padding count %d out of range for a proof of %d elements", proof.PaddingCount, len(path))
+	}
+	root, err := foldProof(claim, path[:len(path)-proof.PaddingCount], proof.Positions, opts)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claimLinkPayload{
+		Address: claim.Address.Hex(),
+		Amount:  claim.Amount.String(),
+		Index:   claim.Index,
+		Proof:   proof.Proof,
+		Root:    EncodeHash(root),
+
+		Positions:    proof.Positions,
+		PaddingCount: proof.PaddingCount,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	var compressed bytes.Buffer
+	gz, _ := gzip.NewWriterLevel(&compressed, gzip.BestCompression)
+	if _, err := gz.Write(payload); err != nil {
+		return "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(compressed.Bytes())
+	if len(encoded) > MaxClaimLinkPayload {
+		return "", fmt.Errorf("claim link payload too large: %d bytes (max %d)", len(encoded), MaxClaimLinkPayload)
+	}
+
+	query := link.Query()
+	query.Set(ClaimLinkParam, encoded)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// DecodeClaimLink extracts the claim and proof from a link produced by
+// EncodeClaimLink. Links for another root or whose proof does not reach
+// root under opts are rejected.
+func DecodeClaimLink(link string, root []byte, opts TreeOptions) (AirdropClaim, *MerkleProof, error) {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid claim link: %w", err)
+	}
+
+	encoded := parsed.Query().Get(ClaimLinkParam)
+	if encoded == "" {
+		return AirdropClaim{}, nil, fmt.Errorf("claim link has no %s parameter", ClaimLinkParam)
+	}
+	if len(encoded) > MaxClaimLinkPayload {
+		return AirdropClaim{}, nil, fmt.Errorf("claim link payload too large: %d bytes (max %d)", len(encoded), MaxClaimLinkPayload)
+	}
+
+	compressed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid claim link payload: %w", err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid claim link payload: %w", err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(gz, maxClaimLinkJSON+1))
+	if err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid claim link payload: %w", err)
+	}
+	if len(raw) > maxClaimLinkJSON {
+		return AirdropClaim{}, nil, fmt.Errorf("claim link payload too large when decompressed (max %d bytes)", maxClaimLinkJSON)
+	}
+
+	var payload claimLinkPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid claim link payload: %w", err)
+	}
+
+	linkRoot, err := DecodeHash(payload.Root)
+	if err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid root in claim link: %w", err)
+	}
+	if !bytes.Equal(linkRoot, root) {
+		return AirdropClaim{}, nil, fmt.Errorf("claim link is for root %s, not %s: %w", payload.Root, EncodeHash(root), ErrRootMismatch)
+	}
+	if !common.IsHexAddress(payload.Address) {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid address in claim link: %s", payload.Address)
+	}
+	amount, ok := new(big.Int).SetString(payload.Amount, 10)
+	if !ok {
+		return AirdropClaim{}, nil, fmt.Errorf("claim link: %w", &InvalidAmountError{Index: int(payload.Index), Value: payload.Amount, Reason: "not a decimal integer"})
+	}
+
+	claim := AirdropClaim{
+		Address: common.HexToAddress(payload.Address),
+		Amount:  amount,
+		Index:   payload.Index,
+	}
+	valid, err := VerifyProofWithPositions(root, claim, payload.Proof, payload.Positions, opts)
+	if err != nil {
+		return AirdropClaim{}, nil, fmt.Errorf("invalid proof in claim link: %w", err)
+	}
+	if !valid {
+		return AirdropClaim{}, nil, fmt.Errorf("claim link proof does not match root %s", EncodeHash(root))
+	}
+
+	return claim, &MerkleProof{
+		Proof:        payload.Proof,
+		Index:        payload.Index,
+		Amount:       payload.Amount,
+		Positions:    payload.Positions,
+		PaddingCount: payload.PaddingCount,
+		Root:         EncodeHash(root),
+	}, nil
+}
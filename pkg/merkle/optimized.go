@@ -2,9 +2,10 @@
 package merkle
 
 import (
-	"encoding/binary"
+	"fmt"
 	"math/big"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -18,44 +19,57 @@ var HashPool = sync.Pool{
 	},
 }
 
-func OptimizedHashLeaf(address common.Address, amount *big.Int, index uint32) []byte {
-	dataPtr := HashPool.Get().(*[]byte)
-	defer HashPool.Put(dataPtr)
-	data := *dataPtr
-
-	// Use the existing data slice
-	data = data[:0] // Reset length but keep capacity
-	data = data[:0] // Reset length but keep capacity
-
-	// Add address (pad to 32 bytes)
-	addressBytes := make([]byte, 32)
-	copy(addressBytes[12:], address.Bytes()) // Ethereum addresses are 20 bytes
-	data = append(data, addressBytes...)
-
-	// Add amount (pad to 32 bytes)
-	amountBytes := make([]byte, 32)
-	amount.FillBytes(amountBytes)
-	data = append(data, amountBytes...)
-
-	// Add index
-	indexBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(indexBytes, index)
-	data = append(data, indexBytes...)
-
-	// Return a copy since we're returning the slice to pool
-	result := make([]byte, 32)
-	hash := crypto.Keccak256(data)
-	copy(result, hash)
-	return result
+// keccakPool holds Keccak-256 states reused across leaf hashes
+var keccakPool = sync.Pool{
+	New: func() interface{} {
+		return crypto.NewKeccakState()
+	},
+}
+
+// OptimizedHashLeaf is HashLeaf, which builds the preimage in a pooled
+// buffer and hashes it with a pooled Keccak state, leaving the returned
+// hash as its only allocation
+func OptimizedHashLeaf(address common.Address, amount *big.Int, index uint32) ([]byte, error) {
+	return HashLeafWithOptions(address, amount, index, DefaultTreeOptions())
+}
+
+// OptimizedHashLeafWithOptions is HashLeafWithOptions
+func OptimizedHashLeafWithOptions(address common.Address, amount *big.Int, index uint32, opts TreeOptions) ([]byte, error) {
+	return HashLeafWithOptions(address, amount, index, opts)
 }
 
 // BatchProcessor handles batch processing of claims
 type BatchProcessor struct {
 	BatchSize int
 	Workers   int
+
+	// Adaptive resizes batches after BatchSize toward a target processing
+	// time. Batches keep BatchSize when nil.
+	Adaptive *AdaptiveBatching
+	// OnStats receives the run's statistics when ProcessClaims succeeds
+	OnStats func(BatchStats)
+	// OnWorkerStart, when set, is called by each worker as it starts, with
+	// the number of workers. Workers call it concurrently.
+	OnWorkerStart func(poolSize int)
+}
+
+// AdaptiveBatching sizes batches so each takes about Target to process
+type AdaptiveBatching struct {
+	Target  time.Duration
+	MinSize int // At least 1
+	MaxSize int // No limit when zero
+}
+
+// BatchStats describes a ProcessClaims run
+type BatchStats struct {
+	Batches         int
+	Claims          int
+	AverageDuration time.Duration // Mean processing time of a batch
+	FinalBatchSize  int           // Batch size reached by the end of the run
 }
 
-// NewBatchProcessor creates a new batch processor
+// NewBatchProcessor creates a new batch processor running workers
+// goroutines, one per CPU when zero
 func NewBatchProcessor(batchSize, workers int) *BatchProcessor {
 	return &BatchProcessor{
 		BatchSize: batchSize,
@@ -63,19 +77,103 @@ func NewBatchProcessor(batchSize, workers int) *BatchProcessor {
 	}
 }
 
+// WithAdaptiveBatching enables adaptive batch sizing and returns bp
+func (bp *BatchProcessor) WithAdaptiveBatching(target time.Duration, minSize, maxSize int) *BatchProcessor {
+	bp.Adaptive = &AdaptiveBatching{Target: target, MinSize: minSize, MaxSize: maxSize}
+	return bp
+}
+
+// batchSizer tracks batch timings and the size of the next batch
+type batchSizer struct {
+	mu       sync.Mutex
+	adaptive *AdaptiveBatching
+	size     int
+	perClaim float64 // Moving average of processing time per claim, in ns
+	batches  int
+	claims   int
+	total    time.Duration
+}
+
+// next returns the size of the next batch
+func (s *batchSizer) next() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// observe records that a batch of size claims took elapsed and resizes
+// later batches
+func (s *batchSizer) observe(size int, elapsed time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches++
+	s.claims += size
+	s.total += elapsed
+	if s.adaptive == nil || size == 0 {
+		return
+	}
+
+	perClaim := float64(elapsed) / float64(size)
+	if s.perClaim == 0 {
+		s.perClaim = perClaim
+	} else {
+		s.perClaim = (s.perClaim + perClaim) / 2
+	}
+	target := s.size * 2 // Grow at most twofold per batch
+	if s.perClaim > 0 {
+		target = min(target, int(float64(s.adaptive.Target)/s.perClaim))
+	}
+	target = max(target, s.size/2, s.adaptive.MinSize, 1)
+	if s.adaptive.MaxSize > 0 {
+		target = min(target, s.adaptive.MaxSize)
+	}
+	s.size = target
+}
+
+func (s *batchSizer) stats() BatchStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := BatchStats{Batches: s.batches, Claims: s.claims, FinalBatchSize: s.size}
+	if s.batches > 0 {
+		stats.AverageDuration = s.total / time.Duration(s.batches)
+	}
+	return stats
+}
+
 // ProcessClaims processes claims in batches with worker pools
 func (bp *BatchProcessor) ProcessClaims(claims []AirdropClaim, processFn func([]AirdropClaim) error) error {
-	jobs := make(chan []AirdropClaim, bp.Workers)
-	results := make(chan error, bp.Workers)
+	if err := checkWorkers(bp.Workers); err != nil {
+		return err
+	}
+	if bp.BatchSize <= 0 {
+		return fmt.Errorf("batch size must be positive: %d", bp.BatchSize)
+	}
+	if a := bp.Adaptive; a != nil && (a.Target <= 0 || a.MinSize < 0 || (a.MaxSize > 0 && a.MaxSize < a.MinSize)) {
+		return fmt.Errorf("invalid adaptive batching: target %v, sizes %d to %d", a.Target, a.MinSize, a.MaxSize)
+	}
+	workers := resolveWorkers(bp.Workers)
+	jobs := make(chan []AirdropClaim, workers)
+	results := make(chan error, workers)
+	stop := make(chan struct{}) // Closed when a batch fails
+	sizer := &batchSizer{adaptive: bp.Adaptive, size: bp.BatchSize}
 
 	// Start workers
 	var wg sync.WaitGroup
-	for i := 0; i < bp.Workers; i++ {
+	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			workerStarted(bp.OnWorkerStart, workers)
 			for batch := range jobs {
-				results <- processFn(batch)
+				select {
+				case <-stop:
+					continue // Queued before the failure
+				default:
+				}
+				start := time.Now()
+				err := processFn(batch)
+				sizer.observe(len(batch), time.Since(start))
+				results <- err
 			}
 		}()
 	}
@@ -83,12 +181,14 @@ func (bp *BatchProcessor) ProcessClaims(claims []AirdropClaim, processFn func([]
 	// Send batches
 	go func() {
 		defer close(jobs)
-		for i := 0; i < len(claims); i += bp.BatchSize {
-			end := i + bp.BatchSize
-			if end > len(claims) {
-				end = len(claims)
+		for i := 0; i < len(claims); {
+			end := min(i+sizer.next(), len(claims))
+			select {
+			case jobs <- claims[i:end]:
+			case <-stop:
+				return
 			}
-			jobs <- claims[i:end]
+			i = end
 		}
 	}()
 
@@ -98,12 +198,20 @@ func (bp *BatchProcessor) ProcessClaims(claims []AirdropClaim, processFn func([]
 		close(results)
 	}()
 
-	// Check for errors
+	// Keep the first error, draining the rest so every goroutine finishes
+	var firstErr error
 	for err := range results {
-		if err != nil {
-			return err
+		if err != nil && firstErr == nil {
+			firstErr = err
+			close(stop)
 		}
 	}
+	if firstErr != nil {
+		return firstErr
+	}
 
+	if bp.OnStats != nil {
+		bp.OnStats(sizer.stats())
+	}
 	return nil
 }
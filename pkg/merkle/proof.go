@@ -2,8 +2,9 @@
 package merkle
 
 import (
-	"fmt"
-	"runtime"
+	"encoding/hex"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -12,136 +13,348 @@ import (
 // GenerateProof creates a Merkle proof for a specific address
 func (mt *MerkleTree) GenerateProof(address common.Address) (*MerkleProof, error) {
 	// Find the leaf for this address
-	var targetLeaf *MerkleNode
-	var targetIndex uint32
-
-	for i, leaf := range mt.Leaves {
-		if leaf.Data.Address == address {
-			targetLeaf = leaf
-			targetIndex = uint32(i)
-			break
+	i, ok := mt.index[address]
+	if !ok || mt.Leaves[i].Data.Address != address {
+		// A changed address would otherwise look like a plain miss
+		if err := mt.checkIntegrity(); err != nil {
+			return nil, err
 		}
+		return nil, ErrAddressNotFound
 	}
 
-	if targetLeaf == nil {
-		return nil, fmt.Errorf("address not found in tree")
+	if err := mt.checkLeaf(mt.Leaves[i]); err != nil {
+		return nil, err
 	}
 
-	// Generate proof path
-	proof := mt.generateProofPath(targetLeaf, targetIndex)
-
-	return &MerkleProof{
-		Proof:  proof,
-		Index:  targetIndex,
-		Amount: targetLeaf.Data.Amount.String(),
-	}, nil
+	return mt.proofForLeaf(i, nil, mt.GetRootHash()), nil
 }
 
-// generateProofPath generates the proof path for a leaf
-func (mt *MerkleTree) generateProofPath(_ *MerkleNode, index uint32) []string {
-	var proof []string
+// proofForLeaf assembles the proof for leaf i under root, the tree's
+// GetRootHash, reusing path as scratch space
+func (mt *MerkleTree) proofForLeaf(i int, path [][]byte, root string) *MerkleProof {
+	if path == nil {
+		path = make([][]byte, 0, len(mt.levels))
+	}
 
-	// Start from leaves and work up
-	nodes := mt.Leaves
-	currentIndex := index
+	leaf := mt.Leaves[i]
+	path, positions := mt.generateProofPath(uint32(i), path[:0])
+	path, padding := padProof(path, mt.Root.Hash, mt.options)
+	proof := &MerkleProof{
+		Proof:  encodeProof(path),
+		Index:  leaf.Data.Index,
+		Amount: leaf.Data.Amount.String(),
 
-	for len(nodes) > 1 {
-		var nextLevel []*MerkleNode
+		PaddingCount: padding,
+		Root:         root,
+	}
+	if !mt.options.SortedPairs {
+		proof.Positions = positions
+	}
+	return proof
+}
 
-		for i := 0; i < len(nodes); i += 2 {
-			left := nodes[i]
-			var right *MerkleNode
+// generateProofPath appends the sibling hashes for a leaf to path, read
+// straight from the levels recorded when the tree was built, and returns
+// the bitmap of siblings that sit on the left
+func (mt *MerkleTree) generateProofPath(index uint32, path [][]byte) ([][]byte, uint64) {
+	return proofPath(mt.levels, int(index), path, mt.options.OddLeafPolicy)
+}
 
-			if i+1 < len(nodes) {
-				right = nodes[i+1]
-			} else {
-				right = left // Duplicate for odd number
-			}
+// proofPath appends the siblings of leaf index in levels to path, paired
+// per policy. Bit i of the bitmap it returns belongs to the i-th sibling
+// appended, which under Promote need not be the i-th level.
+func proofPath(levels [][][]byte, index int, path [][]byte, policy OddLeafPolicy) ([][]byte, uint64) {
+	currentIndex := index
+	var positions uint64
 
-			// If current index is at this level, add sibling to proof
-			if uint32(i) == currentIndex {
-				if currentIndex%2 == 0 {
-					// We're left child, add right sibling
-					proof = append(proof, fmt.Sprintf("0x%x", right.Hash))
-				} else {
-					// We're right child, add left sibling
-					proof = append(proof, fmt.Sprintf("0x%x", left.Hash))
-				}
-			} else if uint32(i+1) == currentIndex {
-				// We're right child, add left sibling
-				proof = append(proof, fmt.Sprintf("0x%x", left.Hash))
+	n := 0
+	for _, level := range levels[:len(levels)-1] {
+		var sibling []byte
+		if currentIndex^1 < len(level) {
+			sibling = level[currentIndex^1]
+		} else {
+			// The last node of an odd level
+			switch policy {
+			case Promote:
+				currentIndex /= 2
+				continue
+			case ZeroPad:
+				sibling = zeroHash[:]
+			default:
+				sibling = level[currentIndex]
 			}
-
-			// Create parent for next level
-			parentHash := HashInternal(left.Hash, right.Hash)
-			parent := &MerkleNode{Hash: parentHash}
-			nextLevel = append(nextLevel, parent)
 		}
+		if currentIndex&1 == 1 {
+			positions |= 1 << n
+		}
+		path = append(path, sibling)
+		n++
+		currentIndex /= 2
+	}
+
+	return path, positions
+}
+
+// encodeProof hex-encodes a proof path into a single buffer; each element
+// is a substring of it. The empty proof of a single-leaf tree is an empty
+// slice, not nil, so it is written as [] rather than null.
+func encodeProof(path [][]byte) []string {
+	if len(path) == 0 {
+		return []string{}
+	}
+
+	const elemLen = 2 + 2*HashLength
+	var buf strings.Builder
+	buf.Grow(len(path) * elemLen)
 
-		nodes = nextLevel
-		currentIndex = currentIndex / 2
+	var scratch [2 * HashLength]byte
+	for _, hash := range path {
+		n := hex.Encode(scratch[:], hash)
+		buf.WriteString("0x")
+		buf.Write(scratch[:n])
 	}
 
+	encoded := buf.String()
+	proof := make([]string, len(path))
+	offset := 0
+	for j, hash := range path {
+		n := 2 + 2*len(hash)
+		proof[j] = encoded[offset : offset+n]
+		offset += n
+	}
 	return proof
 }
 
-// GenerateAllProofs generates proofs for all addresses using goroutines
+// GenerateAllProofs generates proofs for all addresses using goroutines,
+// as many as the tree's Workers option asks for
 func (mt *MerkleTree) GenerateAllProofs() (map[string]*MerkleProof, error) {
-	numWorkers := runtime.NumCPU()
-	if numWorkers > len(mt.Claims) {
-		numWorkers = len(mt.Claims)
+	return mt.GenerateAllProofsWithWorkers(mt.options.Workers)
+}
+
+// GenerateAllProofsWithWorkers generates proofs for all addresses on
+// workers goroutines, one per CPU when zero. The proofs do not depend on
+// the count.
+func (mt *MerkleTree) GenerateAllProofsWithWorkers(workers int) (map[string]*MerkleProof, error) {
+	result := make(map[string]*MerkleProof, len(mt.index))
+	err := mt.streamProofs(workers, func(i int, proof *MerkleProof) error {
+		result[mt.Leaves[i].Data.Address.Hex()] = proof
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DefaultProofBuffer is the ProofBuffer of trees that don't set one
+const DefaultProofBuffer = 4096
+
+// GenerateAllProofsTo generates the proof of every address and passes it
+// to sink with the address checksummed, one at a time in leaf order. At
+// most the tree's ProofBuffer proofs are held while sink runs, so memory
+// stays bounded however slow it is. A repeated address gets the proof of
+// its first occurrence. An error from sink stops generation and is
+// returned.
+func (mt *MerkleTree) GenerateAllProofsTo(sink func(address string, proof *MerkleProof) error) error {
+	return mt.streamProofs(mt.options.Workers, func(i int, proof *MerkleProof) error {
+		return sink(mt.Leaves[i].Data.Address.Hex(), proof)
+	})
+}
+
+// streamProofs generates proofs on workers goroutines and passes those of
+// first occurrences to sink in leaf order, on the calling goroutine. A
+// leaf is dispatched only once it takes a token from a bucket of
+// ProofBuffer, which sink returns, so workers never run further ahead.
+func (mt *MerkleTree) streamProofs(workers int, sink func(i int, proof *MerkleProof) error) error {
+	if err := checkWorkers(workers); err != nil {
+		return err
+	}
+	if err := mt.checkIntegrity(); err != nil {
+		return err
 	}
 
-	// Result type for channel
-	type proofResult struct {
-		Address string
-		Proof   *MerkleProof
-		Error   error
+	bound := mt.options.ProofBuffer
+	if bound == 0 {
+		bound = DefaultProofBuffer
 	}
+	// Never zero, whatever the setting and the number of leaves
+	numWorkers := max(1, min(resolveWorkers(workers), len(mt.Leaves)))
 
-	// Create channels
-	jobs := make(chan AirdropClaim, len(mt.Claims))
-	results := make(chan proofResult, len(mt.Claims))
+	tokens := make(chan struct{}, bound)
+	jobs := make(chan int, numWorkers)
+	type result struct {
+		i     int
+		proof *MerkleProof
+	}
+	// Every proof in it holds a token, so workers never block sending
+	results := make(chan result, bound)
+	stop := make(chan struct{}) // Closed when sink fails
+
+	go func() {
+		defer close(jobs)
+		for i := range mt.Leaves {
+			select {
+			case tokens <- struct{}{}:
+			case <-stop:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
 
-	// Start workers
+	root := mt.GetRootHash() // Shared by every proof
 	var wg sync.WaitGroup
-	for i := 0; i < numWorkers; i++ {
+	for w := 0; w < numWorkers; w++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for claim := range jobs {
-				proof, err := mt.GenerateProof(claim.Address)
-				results <- proofResult{
-					Address: claim.Address.Hex(),
-					Proof:   proof,
-					Error:   err,
-				}
+			workerStarted(mt.options.OnWorkerStart, numWorkers)
+			path := make([][]byte, 0, len(mt.levels))
+			for i := range jobs {
+				results <- result{i, mt.proofForLeaf(i, path, root)}
 			}
 		}()
 	}
-
-	// Send jobs
-	go func() {
-		for _, claim := range mt.Claims {
-			jobs <- claim
-		}
-		close(jobs)
-	}()
-
-	// Wait for workers to finish
 	go func() {
 		wg.Wait()
 		close(results)
 	}()
 
-	// Collect results
-	proofs := make(map[string]*MerkleProof)
-	for result := range results {
-		if result.Error != nil {
-			return nil, fmt.Errorf("failed to generate proof for %s: %w", result.Address, result.Error)
+	// Leaves from next hold the tokens, so a ring of bound slots reorders them
+	pending := make([]*MerkleProof, bound)
+	next := 0
+	var err error
+	for r := range results {
+		if err != nil {
+			continue // Drained so the workers can finish
+		}
+		if hook := mt.options.OnProofHeld; hook != nil {
+			hook(len(tokens))
+		}
+		pending[r.i%bound] = r.proof
+		for err == nil && pending[next%bound] != nil {
+			proof := pending[next%bound]
+			pending[next%bound] = nil
+			if mt.index[mt.Leaves[next].Data.Address] == next {
+				err = sink(next, proof)
+			}
+			<-tokens
+			next++
+		}
+		if err != nil {
+			close(stop)
+		}
+	}
+	return err
+}
+
+// ForEachProof generates the proof of every address and passes it to fn as
+// soon as it is ready. fn is called from several goroutines at once, once
+// per address; a repeated address gets the proof of its first occurrence.
+func (mt *MerkleTree) ForEachProof(fn func(address common.Address, proof *MerkleProof)) error {
+	if err := mt.checkIntegrity(); err != nil {
+		return err
+	}
+
+	mt.generateProofs(mt.options.Workers, func(i int, proof *MerkleProof) {
+		address := mt.Leaves[i].Data.Address
+		if mt.index[address] == i {
+			fn(address, proof)
 		}
-		proofs[result.Address] = result.Proof
+	})
+	return nil
+}
+
+// generateProofs generates the proof of every leaf on a pool of workers,
+// calling fn with each leaf's position and proof
+func (mt *MerkleTree) generateProofs(workers int, fn func(i int, proof *MerkleProof)) {
+	mt.generateProofRange(workers, 0, len(mt.Leaves), fn)
+}
+
+// generateProofRange is generateProofs for the leaves from up to to
+func (mt *MerkleTree) generateProofRange(workers, from, to int, fn func(i int, proof *MerkleProof)) {
+	mt.generateProofJobs(workers, to-from, func(j int) int { return from + j }, fn)
+}
+
+// generateProofsAt is generateProofs for the leaves at positions
+func (mt *MerkleTree) generateProofsAt(workers int, positions []int, fn func(i int, proof *MerkleProof)) {
+	mt.generateProofJobs(workers, len(positions), func(j int) int { return positions[j] }, fn)
+}
+
+// generateProofJobs generates the proofs of the n leaves at(0) to at(n-1)
+func (mt *MerkleTree) generateProofJobs(workers, n int, at func(j int) int, fn func(i int, proof *MerkleProof)) {
+	numWorkers := max(1, min(resolveWorkers(workers), n))
+
+	jobs := make(chan int, numWorkers)
+	root := mt.GetRootHash() // Shared by every proof
+
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workerStarted(mt.options.OnWorkerStart, numWorkers)
+			path := make([][]byte, 0, len(mt.levels))
+			for i := range jobs {
+				fn(i, mt.proofForLeaf(i, path, root))
+			}
+		}()
+	}
+
+	for j := 0; j < n; j++ {
+		jobs <- at(j)
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// GenerateProofSet generates proofs for all addresses as a ProofSet
+func (mt *MerkleTree) GenerateProofSet() (*ProofSet, error) {
+	proofs, err := mt.GenerateAllProofs()
+	if err != nil {
+		return nil, err
+	}
+
+	set := NewProofSet(proofs)
+	set.Metadata = mt.Metadata()
+	return set, nil
+}
+
+// NewProofSet wraps a map of proofs keyed by checksummed address
+func NewProofSet(proofs map[string]*MerkleProof) *ProofSet {
+	if proofs == nil {
+		proofs = make(map[string]*MerkleProof)
 	}
+	return &ProofSet{Proofs: proofs, Metadata: DefaultMetadata()}
+}
+
+// Get returns the proof for address
+func (ps *ProofSet) Get(address common.Address) (*MerkleProof, bool) {
+	proof, ok := ps.Proofs[address.Hex()]
+	return proof, ok
+}
+
+// Len returns the number of proofs in the set
+func (ps *ProofSet) Len() int {
+	return len(ps.Proofs)
+}
 
-	return proofs, nil
+// Addresses returns the addresses in the set ordered by claim index
+func (ps *ProofSet) Addresses() []string {
+	addresses := make([]string, 0, len(ps.Proofs))
+	for addr := range ps.Proofs {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		pi, pj := ps.Proofs[addresses[i]], ps.Proofs[addresses[j]]
+		if pi.Index != pj.Index {
+			return pi.Index < pj.Index
+		}
+		return addresses[i] < addresses[j]
+	})
+	return addresses
 }
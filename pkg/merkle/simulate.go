@@ -0,0 +1,145 @@
+package merkle
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// SimulateAddClaim returns the root the tree would have with claim added,
+// as AddClaims would add it, and claim's proof under that root. Only the
+// nodes above the new leaf are rehashed, on copies of the tree's levels;
+// the tree itself is unchanged. An address the tree already has fails with
+// an ExistingClaimError holding its claim.
+func (mt *MerkleTree) SimulateAddClaim(claim AirdropClaim) ([]byte, *MerkleProof, error) {
+	if err := CheckClaimCount(len(mt.Claims)+1, mt.options.MaxClaims); err != nil {
+		return nil, nil, err
+	}
+	if have, ok := mt.FindClaim(claim.Address); ok {
+		return nil, nil, &ExistingClaimError{Claims: []AirdropClaim{have}}
+	}
+	if err := CheckAmount(len(mt.Claims), claim.Amount); err != nil {
+		return nil, nil, fmt.Errorf("claim %s: %w", claim.Address.Hex(), err)
+	}
+	if err := CheckAmountBits([]AirdropClaim{claim}, mt.options.MaxAmountBits); err != nil {
+		return nil, nil, err
+	}
+
+	index, err := mt.nextIndex(claim.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	claim.Index = index
+	leaf, err := HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, mt.options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pos := mt.insertPosition(claim)
+	levels, err := mt.levelsWithLeaf(pos, leaf)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkTreeDepth(len(levels), mt.options); err != nil {
+		return nil, nil, err
+	}
+
+	root := common.CopyBytes(levels[len(levels)-1][0])
+	path, positions := proofPath(levels, pos, make([][]byte, 0, len(levels)), mt.options.OddLeafPolicy)
+	path, padding := padProof(path, root, mt.options)
+	proof := &MerkleProof{
+		Proof:  encodeProof(path),
+		Index:  claim.Index,
+		Amount: claim.Amount.String(),
+
+		PaddingCount: padding,
+		Root:         EncodeHash(root),
+	}
+	if !mt.options.SortedPairs {
+		proof.Positions = positions
+	}
+	return root, proof, nil
+}
+
+// nextIndex returns the index AddClaims would give a new claim for address
+func (mt *MerkleTree) nextIndex(address common.Address) (uint32, error) {
+	if mt.options.Indices != nil {
+		index, err := mt.options.Indices.peek(address)
+		return uint32(index), err
+	}
+	next := uint64(0)
+	for _, claim := range mt.Claims {
+		next = max(next, uint64(claim.Index)+1)
+	}
+	if next > math.MaxUint32 {
+		return 0, fmt.Errorf("no free indices for %s", address.Hex())
+	}
+	return uint32(next), nil
+}
+
+// insertPosition returns where claim's leaf would go among the tree's,
+// following the tree's sort order
+func (mt *MerkleTree) insertPosition(claim AirdropClaim) int {
+	switch mt.options.SortOrder {
+	case SortByAddress:
+		hex := claim.Address.Hex()
+		return sort.Search(len(mt.Leaves), func(i int) bool {
+			return mt.Leaves[i].Data.Address.Hex() >= hex
+		})
+	case SortByIndex:
+		return sort.Search(len(mt.Leaves), func(i int) bool {
+			return mt.Leaves[i].Data.Index > claim.Index
+		})
+	default:
+		return len(mt.Leaves)
+	}
+}
+
+// levelsWithLeaf returns the tree's levels with leaf inserted at pos. A
+// node whose subtree lies wholly before pos keeps its hash, so each level
+// is shared with the tree's up to half the previous level's first change
+// and rehashed from there.
+func (mt *MerkleTree) levelsWithLeaf(pos int, leaf []byte) ([][][]byte, error) {
+	old := mt.levels[0]
+	level := make([][]byte, 0, len(old)+1)
+	level = append(level, old[:pos]...)
+	level = append(level, leaf)
+	level = append(level, old[pos:]...)
+	levels := [][][]byte{level}
+
+	changed := pos
+	for depth := 0; len(level) > 1; depth++ {
+		next := make([][]byte, (len(level)+1)/2)
+		changed /= 2
+		if changed > 0 {
+			copy(next, mt.levels[depth+1][:changed])
+		}
+		for p := changed; p < len(next); p++ {
+			left := level[2*p]
+			var right []byte
+			if 2*p+1 < len(level) {
+				right = level[2*p+1]
+			} else {
+				switch mt.options.OddLeafPolicy {
+				case Promote:
+					next[p] = left
+					continue
+				case ZeroPad:
+					right = zeroHash[:]
+				default:
+					right = left
+				}
+			}
+			hash, err := HashPair(left, right, mt.options)
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash level %d: %w", depth+1, err)
+			}
+			next[p] = hash
+		}
+		level = next
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
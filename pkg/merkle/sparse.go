@@ -0,0 +1,370 @@
+// pkg/merkle/sparse.go
+package merkle
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// DefaultSparseDepth keys leaves by the first 160 bits of
+	// keccak256(address)
+	DefaultSparseDepth = 160
+	// MaxSparseDepth uses the whole key
+	MaxSparseDepth = 256
+)
+
+// sparseDefaults holds the hash of an empty subtree by height: an empty leaf
+// is 32 zero bytes and each level up hashes two copies of the one below
+var sparseDefaults = func() [][]byte {
+	defaults := make([][]byte, MaxSparseDepth+1)
+	defaults[0] = make([]byte, 32)
+	for height := 1; height <= MaxSparseDepth; height++ {
+		defaults[height] = crypto.Keccak256(defaults[height-1], defaults[height-1])
+	}
+	return defaults
+}()
+
+// SparseMerkleTree places each claim at the leaf addressed by its key, so
+// an empty leaf proves an address is not in the airdrop. Children are hashed
+// in position order, unlike the sorted pairs of MerkleTree.
+type SparseMerkleTree struct {
+	mu     sync.Mutex
+	depth  int
+	leaves map[[32]byte]sparseLeaf // Leaves by key
+
+	// Rebuilt by RootHash after inserts
+	keys  [][32]byte              // Leaf keys in ascending order
+	nodes map[sparseNodeID][]byte // Hashes of subtrees holding two or more leaves
+	root  []byte
+}
+
+type sparseLeaf struct {
+	claim AirdropClaim
+	hash  []byte
+}
+
+// sparseNodeID names a subtree by its height and the position of its first
+// leaf in the sorted keys
+type sparseNodeID struct {
+	first  int
+	height int
+}
+
+// SparseProof shows that an address's leaf holds its claim, or is empty.
+// Siblings equal to the empty subtree hash are left out; bit i of Bitmap
+// (most significant first) is set when the sibling at height i is present.
+type SparseProof struct {
+	Address  string   `json:"address"`
+	Included bool     `json:"included"`
+	Amount   string   `json:"amount,omitempty"`
+	Index    uint32   `json:"index,omitempty"`
+	Depth    int      `json:"depth"`
+	Bitmap   string   `json:"bitmap"`
+	Siblings []string `json:"siblings"` // Leaf level first
+}
+
+// NewSparseMerkleTree creates an empty sparse tree of the given depth
+func NewSparseMerkleTree(depth int) (*SparseMerkleTree, error) {
+	if depth < 1 || depth > MaxSparseDepth {
+		return nil, fmt.Errorf("sparse tree depth must be between 1 and %d, got %d", MaxSparseDepth, depth)
+	}
+	return &SparseMerkleTree{
+		depth:  depth,
+		leaves: make(map[[32]byte]sparseLeaf),
+	}, nil
+}
+
+// NewSparseMerkleTreeFromClaims creates a sparse tree holding claims and
+// hashes its root
+func NewSparseMerkleTreeFromClaims(claims []AirdropClaim, depth int) (*SparseMerkleTree, error) {
+	tree, err := NewSparseMerkleTree(depth)
+	if err != nil {
+		return nil, err
+	}
+	for _, claim := range claims {
+		if err := tree.Insert(claim); err != nil {
+			return nil, err
+		}
+	}
+	tree.RootHash()
+	return tree, nil
+}
+
+// SparseKey returns the leaf key of address in a tree of depth: the first
+// depth bits of keccak256(address), with the rest cleared
+func SparseKey(address common.Address, depth int) [32]byte {
+	key := crypto.Keccak256Hash(address.Bytes())
+	for i := depth; i < MaxSparseDepth; i++ {
+		key[i/8] &^= 0x80 >> (i % 8)
+	}
+	return key
+}
+
+// keyBit returns bit i of key, counting from the most significant
+func keyBit(key [32]byte, i int) byte {
+	return key[i/8] >> (7 - i%8) & 1
+}
+
+// Insert adds claim to the tree. Addresses can be inserted only once.
+func (t *SparseMerkleTree) Insert(claim AirdropClaim) error {
+	hash, err := HashLeaf(claim.Address, claim.Amount, claim.Index)
+	if err != nil {
+		return fmt.Errorf("claim for %s: %w", claim.Address.Hex(), err)
+	}
+	claim.Amount = new(big.Int).Set(claim.Amount)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := SparseKey(claim.Address, t.depth)
+	if existing, ok := t.leaves[key]; ok {
+		if existing.claim.Address == claim.Address {
+			return fmt.Errorf("address %s is already in the sparse tree", claim.Address.Hex())
+		}
+		return fmt.Errorf("addresses %s and %s share a %d-bit key; use a larger depth", existing.claim.Address.Hex(), claim.Address.Hex(), t.depth)
+	}
+
+	t.leaves[key] = sparseLeaf{claim: claim, hash: hash}
+	t.root = nil
+	return nil
+}
+
+// Depth returns the number of levels below the root
+func (t *SparseMerkleTree) Depth() int {
+	return t.depth
+}
+
+// Len returns the number of claims in the tree
+func (t *SparseMerkleTree) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.leaves)
+}
+
+// RootHash returns the root, rebuilding it if claims were inserted since
+// the last call
+func (t *SparseMerkleTree) RootHash() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.build()
+}
+
+// GetRootHash returns the root as a 0x-prefixed hex string
+func (t *SparseMerkleTree) GetRootHash() string {
+	return EncodeHash(t.RootHash())
+}
+
+// ProveInclusion returns a proof that address holds its claim
+func (t *SparseMerkleTree) ProveInclusion(address common.Address) (*SparseProof, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := SparseKey(address, t.depth)
+	leaf, ok := t.leaves[key]
+	if !ok || leaf.claim.Address != address {
+		return nil, fmt.Errorf("%s in sparse tree: %w", address.Hex(), ErrAddressNotFound)
+	}
+
+	proof := t.prove(key)
+	proof.Address = address.Hex()
+	proof.Included = true
+	proof.Amount = leaf.claim.Amount.String()
+	proof.Index = leaf.claim.Index
+	return proof, nil
+}
+
+// ProveNonInclusion returns a proof that address has no claim
+func (t *SparseMerkleTree) ProveNonInclusion(address common.Address) (*SparseProof, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := SparseKey(address, t.depth)
+	if leaf, ok := t.leaves[key]; ok {
+		if leaf.claim.Address == address {
+			return nil, fmt.Errorf("address %s is in the sparse tree", address.Hex())
+		}
+		return nil, fmt.Errorf("address %s shares its %d-bit key with %s", address.Hex(), t.depth, leaf.claim.Address.Hex())
+	}
+
+	proof := t.prove(key)
+	proof.Address = address.Hex()
+	return proof, nil
+}
+
+// build sorts the keys and hashes the tree when it changed. Callers hold
+// t.mu.
+func (t *SparseMerkleTree) build() []byte {
+	if t.root != nil {
+		return t.root
+	}
+
+	t.keys = make([][32]byte, 0, len(t.leaves))
+	for key := range t.leaves {
+		t.keys = append(t.keys, key)
+	}
+	sort.Slice(t.keys, func(i, j int) bool {
+		return bytes.Compare(t.keys[i][:], t.keys[j][:]) < 0
+	})
+
+	t.nodes = make(map[sparseNodeID][]byte, len(t.keys))
+	t.root = t.subtreeHash(0, len(t.keys), t.depth)
+	return t.root
+}
+
+// prove collects the non-empty siblings on key's path. Callers hold t.mu.
+func (t *SparseMerkleTree) prove(key [32]byte) *SparseProof {
+	t.build()
+
+	bitmap := make([]byte, (t.depth+7)/8)
+	var siblings []string
+
+	// Walk down from the root, then emit the siblings leaf level first
+	path := make([][]byte, t.depth)
+	first, end := 0, len(t.keys)
+	for height := t.depth; height > 0; height-- {
+		mid := t.split(first, end, height)
+		if keyBit(key, t.depth-height) == 0 {
+			path[height-1] = t.subtreeHash(mid, end, height-1)
+			end = mid
+		} else {
+			path[height-1] = t.subtreeHash(first, mid, height-1)
+			first = mid
+		}
+	}
+	for height, sibling := range path {
+		if bytes.Equal(sibling, sparseDefaults[height]) {
+			continue
+		}
+		bitmap[height/8] |= 0x80 >> (height % 8)
+		siblings = append(siblings, EncodeHash(sibling))
+	}
+
+	return &SparseProof{
+		Depth:    t.depth,
+		Bitmap:   "0x" + hex.EncodeToString(bitmap),
+		Siblings: siblings,
+	}
+}
+
+// split returns the position of the first key in [first, end) that takes
+// the right branch below a node at height
+func (t *SparseMerkleTree) split(first, end, height int) int {
+	bit := t.depth - height
+	return first + sort.Search(end-first, func(i int) bool {
+		return keyBit(t.keys[first+i], bit) == 1
+	})
+}
+
+// subtreeHash hashes the subtree at height holding the keys in [first, end).
+// Empty subtrees come from the default cache and lone leaves are hashed up
+// directly, so only branching subtrees are stored.
+func (t *SparseMerkleTree) subtreeHash(first, end, height int) []byte {
+	switch end - first {
+	case 0:
+		return sparseDefaults[height]
+	case 1:
+		key := t.keys[first]
+		return foldSparsePath(key, t.leaves[key].hash, t.depth, height, func(int) []byte { return nil })
+	}
+
+	id := sparseNodeID{first: first, height: height}
+	if hash, ok := t.nodes[id]; ok {
+		return hash
+	}
+	mid := t.split(first, end, height)
+	hash := crypto.Keccak256(t.subtreeHash(first, mid, height-1), t.subtreeHash(mid, end, height-1))
+	t.nodes[id] = hash
+	return hash
+}
+
+// foldSparsePath hashes leaf up to height along key's path. sibling returns
+// the hash beside the path at each height, or nil for an empty subtree.
+func foldSparsePath(key [32]byte, leaf []byte, depth, height int, sibling func(height int) []byte) []byte {
+	hasher := crypto.NewKeccakState()
+	current := make([]byte, 32)
+	copy(current, leaf)
+
+	for level := 0; level < height; level++ {
+		other := sibling(level)
+		if other == nil {
+			other = sparseDefaults[level]
+		}
+
+		hasher.Reset()
+		if keyBit(key, depth-1-level) == 0 {
+			hasher.Write(current)
+			hasher.Write(other)
+		} else {
+			hasher.Write(other)
+			hasher.Write(current)
+		}
+		hasher.Read(current)
+	}
+	return current
+}
+
+// VerifySparseProof checks proof against root. Inclusion proofs are checked
+// against the claim they carry; non-inclusion proofs against an empty leaf.
+// An error is returned when the proof is malformed.
+func VerifySparseProof(root []byte, proof *SparseProof) (bool, error) {
+	if proof.Depth < 1 || proof.Depth > MaxSparseDepth {
+		return false, fmt.Errorf("invalid sparse tree depth %d", proof.Depth)
+	}
+	if !common.IsHexAddress(proof.Address) {
+		return false, fmt.Errorf("invalid address: %s", proof.Address)
+	}
+	address := common.HexToAddress(proof.Address)
+
+	bitmap, err := hex.DecodeString(strings.TrimPrefix(proof.Bitmap, "0x"))
+	if err != nil || len(bitmap) != (proof.Depth+7)/8 {
+		return false, fmt.Errorf("invalid bitmap for depth %d: %s", proof.Depth, proof.Bitmap)
+	}
+	present := 0
+	for _, b := range bitmap {
+		present += bits.OnesCount8(b)
+	}
+	siblings, err := decodeProof(proof.Siblings)
+	if err != nil {
+		return false, err
+	}
+	if len(siblings) != present {
+		return false, proofError("bitmap marks %d siblings, proof has %d", present, len(siblings))
+	}
+
+	leaf := sparseDefaults[0]
+	if proof.Included {
+		amount, ok := new(big.Int).SetString(proof.Amount, 10)
+		if !ok {
+			return false, &InvalidAmountError{Index: int(proof.Index), Value: proof.Amount, Reason: "not a decimal integer"}
+		}
+		claim := AirdropClaim{Address: address, Amount: amount, Index: proof.Index}
+		if err := checkClaimProof(claim, siblings); err != nil {
+			return false, err
+		}
+		if leaf, err = HashLeaf(address, amount, proof.Index); err != nil {
+			return false, err
+		}
+	} else if err := checkClaimProof(AirdropClaim{Amount: new(big.Int)}, siblings); err != nil {
+		return false, err
+	}
+
+	next := 0
+	computed := foldSparsePath(SparseKey(address, proof.Depth), leaf, proof.Depth, proof.Depth, func(height int) []byte {
+		if bitmap[height/8]&(0x80>>(height%8)) == 0 {
+			return nil
+		}
+		next++
+		return siblings[next-1]
+	})
+	return bytes.Equal(computed, root), nil
+}
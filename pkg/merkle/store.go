@@ -0,0 +1,464 @@
+// pkg/merkle/store.go
+package merkle
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProofStore holds proofs by address. Get and Len are safe for concurrent
+// use; Put is only safe alongside other calls for stores that say so.
+type ProofStore interface {
+	// Get returns the proof for address, and false if there is none.
+	// Errors are for stores that fail to read, not for missing proofs.
+	Get(address common.Address) (*MerkleProof, bool, error)
+	// Put stores proof for address, replacing any stored before
+	Put(address common.Address, proof *MerkleProof) error
+	// Len returns the number of addresses with a proof
+	Len() int
+	// Iterate calls fn for every address and its proof, in no particular
+	// order, until fn returns false
+	Iterate(fn func(address common.Address, proof *MerkleProof) bool) error
+}
+
+// MapProofStore is a ProofStore over a map keyed by checksummed address,
+// as GenerateAllProofs returns. Put is not safe for concurrent use.
+type MapProofStore map[string]*MerkleProof
+
+// Get returns the proof for address
+func (m MapProofStore) Get(address common.Address) (*MerkleProof, bool, error) {
+	proof, ok := m[address.Hex()]
+	return proof, ok, nil
+}
+
+// Put stores proof for address
+func (m MapProofStore) Put(address common.Address, proof *MerkleProof) error {
+	m[address.Hex()] = proof
+	return nil
+}
+
+// Len returns the number of proofs in the map
+func (m MapProofStore) Len() int {
+	return len(m)
+}
+
+// Iterate calls fn for every proof in the map until it returns false
+func (m MapProofStore) Iterate(fn func(address common.Address, proof *MerkleProof) bool) error {
+	for address, proof := range m {
+		if !fn(common.HexToAddress(address), proof) {
+			break
+		}
+	}
+	return nil
+}
+
+// GenerateAllProofsInto generates the proof of every address into store,
+// in leaf order and holding at most the tree's ProofBuffer proofs at once.
+// A repeated address gets the proof of its first occurrence.
+func (mt *MerkleTree) GenerateAllProofsInto(store ProofStore) error {
+	return mt.streamProofs(mt.options.Workers, func(i int, proof *MerkleProof) error {
+		address := mt.Leaves[i].Data.Address
+		if err := store.Put(address, proof); err != nil {
+			return fmt.Errorf("failed to store proof for %s: %w", address.Hex(), err)
+		}
+		return nil
+	})
+}
+
+// AppendProofBinary appends proof to dst in the entry encoding of binary
+// proofs files, after the address (integers big-endian):
+//
+//	amount length uvarint | amount bytes | index uint32 |
+//	proof count uvarint | proof [count][32]byte |
+//	positions uvarint | padding count uvarint
+//
+// The proof's Root is not encoded: binary proofs files record it once, and
+// disk stores after the encoding.
+func AppendProofBinary(dst []byte, proof *MerkleProof) ([]byte, error) {
+	amount, ok := new(big.Int).SetString(proof.Amount, 10)
+	if !ok {
+		return dst, &InvalidAmountError{Index: int(proof.Index), Value: proof.Amount, Reason: "not a decimal integer"}
+	}
+	if err := CheckAmount(int(proof.Index), amount); err != nil {
+		return dst, err
+	}
+	amountBytes := amount.Bytes()
+
+	dst = binary.AppendUvarint(dst, uint64(len(amountBytes)))
+	dst = append(dst, amountBytes...)
+	dst = binary.BigEndian.AppendUint32(dst, proof.Index)
+	dst = binary.AppendUvarint(dst, uint64(len(proof.Proof)))
+	for _, h := range proof.Proof {
+		hash, err := DecodeHash(h)
+		if err != nil {
+			return dst, fmt.Errorf("invalid proof hash %q", h)
+		}
+		dst = append(dst, hash...)
+	}
+	dst = binary.AppendUvarint(dst, proof.Positions)
+	dst = binary.AppendUvarint(dst, uint64(proof.PaddingCount))
+	return dst, nil
+}
+
+// DecodeProofBinary decodes a proof encoded by AppendProofBinary from the
+// start of b, returning it and the number of bytes read
+func DecodeProofBinary(b []byte) (*MerkleProof, int, error) {
+	offset := 0
+	uvarint := func(what string) (uint64, error) {
+		v, n := binary.Uvarint(b[offset:])
+		if n <= 0 {
+			return 0, fmt.Errorf("failed to read %s", what)
+		}
+		offset += n
+		return v, nil
+	}
+	take := func(what string, n int) ([]byte, error) {
+		if len(b)-offset < n {
+			return nil, fmt.Errorf("failed to read %s: %w", what, io.ErrUnexpectedEOF)
+		}
+		offset += n
+		return b[offset-n : offset], nil
+	}
+
+	amountLen, err := uvarint("amount length")
+	if err != nil {
+		return nil, 0, err
+	}
+	if amountLen > 32 {
+		return nil, 0, fmt.Errorf("amount too long (%d bytes)", amountLen)
+	}
+	amountBytes, err := take("amount", int(amountLen))
+	if err != nil {
+		return nil, 0, err
+	}
+	indexBytes, err := take("index", 4)
+	if err != nil {
+		return nil, 0, err
+	}
+	proofLen, err := uvarint("proof length")
+	if err != nil {
+		return nil, 0, err
+	}
+	if proofLen > 256 {
+		return nil, 0, fmt.Errorf("proof too long (%d hashes)", proofLen)
+	}
+	proof := make([]string, proofLen)
+	for i := range proof {
+		hash, err := take("proof hash", 32)
+		if err != nil {
+			return nil, 0, err
+		}
+		proof[i] = EncodeHash(hash)
+	}
+	positions, err := uvarint("positions")
+	if err != nil {
+		return nil, 0, err
+	}
+	padding, err := uvarint("padding count")
+	if err != nil {
+		return nil, 0, err
+	}
+	if padding > proofLen {
+		return nil, 0, fmt.Errorf("padding count %d exceeds proof length %d", padding, proofLen)
+	}
+
+	return &MerkleProof{
+		Proof:     proof,
+		Index:     binary.BigEndian.Uint32(indexBytes),
+		Amount:    new(big.Int).SetBytes(amountBytes).String(),
+		Positions: positions,
+
+		PaddingCount: int(padding),
+	}, offset, nil
+}
+
+// Disk proof store layout:
+//
+//	magic "MKPS" | version uint8
+//	per record: address [20]byte | value length uvarint | value
+//
+// where the value is a proof in the AppendProofBinary encoding, followed
+// by its 32-byte root when it records one. Records are only ever appended;
+// the last record for an address wins.
+const (
+	diskStoreMagic   = "MKPS"
+	diskStoreVersion = 1
+	diskStoreHeader  = len(diskStoreMagic) + 1
+
+	// maxDiskStoreValue bounds record values, well above the largest
+	// proof of 256 hashes
+	maxDiskStoreValue = 1 << 14
+)
+
+// diskLocation locates a record's value in a disk store
+type diskLocation struct {
+	offset int64
+	size   uint32
+}
+
+// DiskProofStore is a ProofStore in a single append-only file, with only
+// an index of record offsets in memory. All its methods are safe for
+// concurrent use. Close flushes buffered records.
+type DiskProofStore struct {
+	mu      sync.RWMutex
+	file    *os.File
+	w       *bufio.Writer
+	size    int64 // Length of the file once buffered records are written
+	flushed int64 // Length of the file as written
+	index   map[common.Address]diskLocation
+}
+
+// CreateDiskProofStore creates an empty disk store at path, replacing any
+// file there
+func CreateDiskProofStore(path string) (*DiskProofStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create proof store: %w", err)
+	}
+	header := append([]byte(diskStoreMagic), diskStoreVersion)
+	if _, err := file.Write(header); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write proof store header: %w", err)
+	}
+	return newDiskProofStore(file, int64(len(header))), nil
+}
+
+// OpenDiskProofStore opens the disk store at path, creating it if it does
+// not exist. A record cut short by a crash is dropped.
+func OpenDiskProofStore(path string) (*DiskProofStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return CreateDiskProofStore(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proof store: %w", err)
+	}
+
+	index, size, err := scanDiskProofStore(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to drop incomplete record: %w", err)
+	}
+	s := newDiskProofStore(file, size)
+	s.index = index
+	return s, nil
+}
+
+func newDiskProofStore(file *os.File, size int64) *DiskProofStore {
+	return &DiskProofStore{
+		file:    file,
+		w:       bufio.NewWriter(io.NewOffsetWriter(file, size)),
+		size:    size,
+		flushed: size,
+		index:   make(map[common.Address]diskLocation),
+	}
+}
+
+// scanDiskProofStore reads the records of file into an index, returning it
+// and the length of the file up to the last complete record
+func scanDiskProofStore(file *os.File) (map[common.Address]diskLocation, int64, error) {
+	br := bufio.NewReader(file)
+	header := make([]byte, diskStoreHeader)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:4]) != diskStoreMagic {
+		return nil, 0, fmt.Errorf("not a proof store file")
+	}
+	if header[4] != diskStoreVersion {
+		return nil, 0, fmt.Errorf("unsupported proof store version: %d", header[4])
+	}
+
+	index := make(map[common.Address]diskLocation)
+	offset := int64(diskStoreHeader)
+	var address common.Address
+	for {
+		if _, err := io.ReadFull(br, address[:]); err != nil {
+			return index, offset, nil
+		}
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			return index, offset, nil
+		}
+		if size > maxDiskStoreValue {
+			return nil, 0, fmt.Errorf("record at offset %d is too long (%d bytes)", offset, size)
+		}
+		if _, err := br.Discard(int(size)); err != nil {
+			return index, offset, nil
+		}
+
+		valueOffset := offset + common.AddressLength + int64(uvarintLen(size))
+		index[address] = diskLocation{offset: valueOffset, size: uint32(size)}
+		offset = valueOffset + int64(size)
+	}
+}
+
+func uvarintLen(v uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], v)
+}
+
+// Get reads the proof for address from the file
+func (s *DiskProofStore) Get(address common.Address) (*MerkleProof, bool, error) {
+	s.mu.RLock()
+	loc, ok := s.index[address]
+	buffered := loc.offset+int64(loc.size) > s.flushed
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	if buffered {
+		if err := s.Flush(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	value := make([]byte, loc.size)
+	if _, err := s.file.ReadAt(value, loc.offset); err != nil {
+		return nil, false, fmt.Errorf("failed to read proof for %s: %w", address.Hex(), err)
+	}
+	proof, err := decodeDiskValue(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("corrupt proof for %s: %w", address.Hex(), err)
+	}
+	return proof, true, nil
+}
+
+// Put appends a record for address. It may stay buffered until Flush,
+// Close or a Get that needs it.
+func (s *DiskProofStore) Put(address common.Address, proof *MerkleProof) error {
+	value, err := appendDiskValue(nil, proof)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := make([]byte, 0, common.AddressLength+binary.MaxVarintLen64)
+	record = append(record, address.Bytes()...)
+	record = binary.AppendUvarint(record, uint64(len(value)))
+	if _, err := s.w.Write(record); err != nil {
+		return fmt.Errorf("failed to write proof record: %w", err)
+	}
+	if _, err := s.w.Write(value); err != nil {
+		return fmt.Errorf("failed to write proof record: %w", err)
+	}
+
+	valueOffset := s.size + int64(len(record))
+	s.index[address] = diskLocation{offset: valueOffset, size: uint32(len(value))}
+	s.size = valueOffset + int64(len(value))
+	return nil
+}
+
+// Len returns the number of addresses with a proof
+func (s *DiskProofStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.index)
+}
+
+// Iterate reads the file front to back, calling fn for the current record
+// of every address. Records put while it runs may be left out.
+func (s *DiskProofStore) Iterate(fn func(address common.Address, proof *MerkleProof) bool) error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	end := s.flushed
+	s.mu.RUnlock()
+
+	br := bufio.NewReader(io.NewSectionReader(s.file, int64(diskStoreHeader), end-int64(diskStoreHeader)))
+	offset := int64(diskStoreHeader)
+	var address common.Address
+	value := make([]byte, 0, 1024)
+	for offset < end {
+		if _, err := io.ReadFull(br, address[:]); err != nil {
+			return fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+		}
+		size, err := binary.ReadUvarint(br)
+		if err != nil || size > maxDiskStoreValue {
+			return fmt.Errorf("failed to read record at offset %d", offset)
+		}
+		value = value[:size]
+		if _, err := io.ReadFull(br, value); err != nil {
+			return fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+		}
+		valueOffset := offset + common.AddressLength + int64(uvarintLen(size))
+		offset = valueOffset + int64(size)
+
+		// Skip records a later one replaced
+		s.mu.RLock()
+		current := s.index[address].offset == valueOffset
+		s.mu.RUnlock()
+		if !current {
+			continue
+		}
+
+		proof, err := decodeDiskValue(value)
+		if err != nil {
+			return fmt.Errorf("corrupt proof for %s: %w", address.Hex(), err)
+		}
+		if !fn(address, proof) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// appendDiskValue appends proof to dst as a disk store record value
+func appendDiskValue(dst []byte, proof *MerkleProof) ([]byte, error) {
+	dst, err := AppendProofBinary(dst, proof)
+	if err != nil || proof.Root == "" {
+		return dst, err
+	}
+	root, err := DecodeHash(proof.Root)
+	if err != nil {
+		return dst, proofError("invalid proof root %q: expected a 32-byte hex hash", proof.Root)
+	}
+	return append(dst, root...), nil
+}
+
+// decodeDiskValue decodes a disk store record value
+func decodeDiskValue(value []byte) (*MerkleProof, error) {
+	proof, n, err := DecodeProofBinary(value)
+	if err != nil {
+		return nil, err
+	}
+	switch len(value) - n {
+	case 0:
+	case 32:
+		proof.Root = EncodeHash(value[n:])
+	default:
+		return nil, fmt.Errorf("%d trailing bytes after the proof", len(value)-n)
+	}
+	return proof, nil
+}
+
+// Flush writes buffered records to the file
+func (s *DiskProofStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.w.Flush(); err != nil {
+		return fmt.Errorf("failed to flush proof store: %w", err)
+	}
+	s.flushed = s.size
+	return nil
+}
+
+// Close flushes buffered records and closes the file
+func (s *DiskProofStore) Close() error {
+	if err := s.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,259 @@
+// Package testvectors generates canonical leaf hashes, roots and proofs for
+// every encoding pkg/merkle supports, so implementations in other languages,
+// such as a Solidity distributor or a TypeScript claim page, can check they
+// hash exactly like the Go library
+package testvectors
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Version is bumped when the file's layout changes, not when hashes do
+const Version = 1
+
+// File is the generated vectors file
+type File struct {
+	Version   int        `json:"version"`
+	Encodings []Encoding `json:"encodings"`
+	Leaves    []Leaf     `json:"leaves"`
+	Trees     []Tree     `json:"trees"`
+}
+
+// Encoding names a leaf encoding and spells out its preimage. Integers are
+// big-endian throughout; only the default encoding's index is narrower
+// than the 32-byte ABI word the others use.
+type Encoding struct {
+	Name     string              `json:"name"`
+	Preimage string              `json:"preimage"`
+	Solidity string              `json:"solidity"`
+	Metadata merkle.TreeMetadata `json:"metadata"`
+}
+
+// Encodings are the leaf encodings the vectors cover
+var Encodings = []Encoding{
+	{
+		Name:     "default",
+		Preimage: "address left-padded to 32 bytes || amount as uint256 (32 bytes) || index as uint32 (4 bytes)",
+		Solidity: "keccak256(abi.encodePacked(bytes32(uint256(uint160(account))), amount, uint32(index)))",
+		Metadata: merkle.TreeMetadata{IncludeIndex: true, SortedPairs: true},
+	},
+	{
+		Name:     "packed",
+		Preimage: "address (20 bytes) || amount as uint256 (32 bytes)",
+		Solidity: "keccak256(abi.encodePacked(account, amount))",
+		Metadata: merkle.TreeMetadata{SortedPairs: true},
+	},
+	{
+		Name:     "indexFirst",
+		Preimage: "index as uint256 (32 bytes) || address (20 bytes) || amount as uint256 (32 bytes)",
+		Solidity: "keccak256(abi.encodePacked(index, account, amount)) with uint256 index, as Uniswap's MerkleDistributor",
+		Metadata: merkle.TreeMetadata{IncludeIndex: true, SortedPairs: true, IndexFirst: true},
+	},
+}
+
+// Leaf is one claim hashed with every encoding, keyed by encoding name
+type Leaf struct {
+	Address   string            `json:"address"`
+	Amount    string            `json:"amount"` // Decimal base units
+	Index     uint32            `json:"index"`
+	Preimages map[string]string `json:"preimages"`
+	Hashes    map[string]string `json:"hashes"`
+}
+
+// Tree is a small tree built with Metadata's options, its claims in leaf
+// order with their proofs
+type Tree struct {
+	Name     string              `json:"name"`
+	Metadata merkle.TreeMetadata `json:"metadata"`
+	Root     string              `json:"root"`
+	Claims   []Claim             `json:"claims"`
+}
+
+// Claim is a tree's claim with its leaf hash and proof
+type Claim struct {
+	Address   string   `json:"address"`
+	Amount    string   `json:"amount"`
+	Index     uint32   `json:"index"`
+	Leaf      string   `json:"leaf"`
+	Proof     []string `json:"proof"`
+	Positions uint64   `json:"positions,omitempty"`
+}
+
+// treeSizes cover a single leaf, an even level and odd levels at the
+// leaves and above them
+var treeSizes = []int{1, 2, 3, 5}
+
+// treeConfigs are the option sets the tree vectors are built with
+var treeConfigs = []struct {
+	name  string
+	base  string // Encoding name
+	apply func(*merkle.TreeMetadata)
+}{
+	{"default", "default", nil},
+	{"packed", "packed", nil},
+	{"indexFirst", "indexFirst", nil},
+	{"positional", "default", func(m *merkle.TreeMetadata) { m.SortedPairs = false }},
+	{"promote", "default", func(m *merkle.TreeMetadata) { m.OddLeafPolicy = merkle.Promote }},
+	{"zero", "default", func(m *merkle.TreeMetadata) { m.OddLeafPolicy = merkle.ZeroPad }},
+	{"positional-promote", "default", func(m *merkle.TreeMetadata) {
+		m.SortedPairs = false
+		m.OddLeafPolicy = merkle.Promote
+	}},
+	{"uniswap", "indexFirst", func(m *merkle.TreeMetadata) {
+		m.SortOrder = merkle.SortByIndex
+		m.OddLeafPolicy = merkle.Promote
+	}},
+}
+
+// Generate builds the vectors. The output depends only on the library's
+// hashing, so two runs give identical files.
+func Generate() (*File, error) {
+	file := &File{Version: Version, Encodings: Encodings}
+
+	for _, claim := range leafClaims() {
+		leaf := Leaf{
+			Address:   claim.Address.Hex(),
+			Amount:    claim.Amount.String(),
+			Index:     claim.Index,
+			Preimages: make(map[string]string, len(Encodings)),
+			Hashes:    make(map[string]string, len(Encodings)),
+		}
+		for _, encoding := range Encodings {
+			hash, err := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, encoding.Metadata.Options())
+			if err != nil {
+				return nil, fmt.Errorf("failed to hash %s leaf for %s: %w", encoding.Name, leaf.Address, err)
+			}
+			preimage := Preimage(encoding.Name, claim)
+			if !bytes.Equal(crypto.Keccak256(preimage), hash) {
+				return nil, fmt.Errorf("%s leaf for %s doesn't hash its documented preimage", encoding.Name, leaf.Address)
+			}
+			leaf.Preimages[encoding.Name] = "0x" + hex.EncodeToString(preimage)
+			leaf.Hashes[encoding.Name] = merkle.EncodeHash(hash)
+		}
+		file.Leaves = append(file.Leaves, leaf)
+	}
+
+	for _, config := range treeConfigs {
+		metadata := encodingMetadata(config.base)
+		if config.apply != nil {
+			config.apply(&metadata)
+		}
+		for _, size := range treeSizes {
+			tree, err := buildTree(fmt.Sprintf("%s-%d", config.name, size), metadata, treeClaims(size))
+			if err != nil {
+				return nil, err
+			}
+			file.Trees = append(file.Trees, *tree)
+		}
+	}
+	return file, nil
+}
+
+// Write generates the vectors and writes them to w as indented JSON
+func Write(w io.Writer) error {
+	file, err := Generate()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+// Preimage assembles the bytes the named encoding hashes for claim by hand,
+// independently of pkg/merkle, following the Encoding's Preimage. It
+// returns nil for unknown encodings.
+func Preimage(encoding string, claim merkle.AirdropClaim) []byte {
+	word := func(n *big.Int) []byte { return common.LeftPadBytes(n.Bytes(), 32) }
+	index := new(big.Int).SetUint64(uint64(claim.Index))
+
+	switch encoding {
+	case "default":
+		preimage := append(common.LeftPadBytes(claim.Address.Bytes(), 32), word(claim.Amount)...)
+		return append(preimage, common.LeftPadBytes(index.Bytes(), 4)...)
+	case "packed":
+		return append(claim.Address.Bytes(), word(claim.Amount)...)
+	case "indexFirst":
+		preimage := append(word(index), claim.Address.Bytes()...)
+		return append(preimage, word(claim.Amount)...)
+	}
+	return nil
+}
+
+// buildTree builds one tree vector
+func buildTree(name string, metadata merkle.TreeMetadata, claims []merkle.AirdropClaim) (*Tree, error) {
+	opts := metadata.Options()
+	opts.Workers = 1
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tree %s: %w", name, err)
+	}
+
+	vector := &Tree{Name: name, Metadata: tree.Metadata(), Root: tree.GetRootHash()}
+	for i, claim := range tree.Claims {
+		proof, err := tree.GenerateProof(claim.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate proof in tree %s: %w", name, err)
+		}
+		vector.Claims = append(vector.Claims, Claim{
+			Address:   claim.Address.Hex(),
+			Amount:    claim.Amount.String(),
+			Index:     claim.Index,
+			Leaf:      merkle.EncodeHash(tree.Leaves[i].Hash),
+			Proof:     append([]string{}, proof.Proof...), // [] rather than null for a lone leaf
+			Positions: proof.Positions,
+		})
+	}
+	return vector, nil
+}
+
+// encodingMetadata returns the named encoding's metadata
+func encodingMetadata(name string) merkle.TreeMetadata {
+	for _, encoding := range Encodings {
+		if encoding.Name == name {
+			return encoding.Metadata
+		}
+	}
+	panic("testvectors: unknown encoding " + name)
+}
+
+// leafClaims are the leaf vectors' inputs: the extremes of each field and
+// values whose bytes differ in every position
+func leafClaims() []merkle.AirdropClaim {
+	maxAmount := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	oneToken, _ := new(big.Int).SetString("1000000000000000000", 10)
+	mixed, _ := new(big.Int).SetString("0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20", 16)
+
+	return []merkle.AirdropClaim{
+		{Address: common.Address{}, Amount: big.NewInt(0), Index: 0},
+		{Address: common.HexToAddress("0x0000000000000000000000000000000000000001"), Amount: big.NewInt(1), Index: 1},
+		{Address: common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"), Amount: oneToken, Index: 42},
+		{Address: common.HexToAddress("0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB"), Amount: mixed, Index: 0x01020304},
+		{Address: common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff"), Amount: maxAmount, Index: ^uint32(0)},
+	}
+}
+
+// treeClaims returns n claims with addresses derived from their position,
+// indexed in reverse so index order and address order differ
+func treeClaims(n int) []merkle.AirdropClaim {
+	claims := make([]merkle.AirdropClaim, n)
+	for i := range claims {
+		seed := crypto.Keccak256([]byte(fmt.Sprintf("merkle-airdrop test vector %d", i)))
+		claims[i] = merkle.AirdropClaim{
+			Address: common.BytesToAddress(seed),
+			Amount:  new(big.Int).Mul(big.NewInt(int64(i+1)), big.NewInt(1_000_000_007)),
+			Index:   uint32(n - 1 - i),
+		}
+	}
+	return claims
+}
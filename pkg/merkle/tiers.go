@@ -0,0 +1,81 @@
+package merkle
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Tier is the claims of a TierBreakdown with amounts from Min up to, but
+// not including, Max
+type Tier struct {
+	Min       *big.Int // Zero for the first tier
+	Max       *big.Int // Nil for the last tier
+	Addresses int
+	Total     *big.Int
+}
+
+// TierBreakdown counts the claims and sums their amounts in each of the
+// tiers that boundaries split amounts into: below boundaries[0], from each
+// boundary to the next, and from the last one up. An amount equal to a
+// boundary goes to the tier above it. Boundaries must be positive and
+// strictly increasing; with none, every claim is in one tier.
+func TierBreakdown(claims []AirdropClaim, boundaries []*big.Int) ([]Tier, error) {
+	if err := ValidateTierBoundaries(boundaries); err != nil {
+		return nil, err
+	}
+
+	tiers := make([]Tier, len(boundaries)+1)
+	for i := range tiers {
+		tiers[i].Min = new(big.Int)
+		if i > 0 {
+			tiers[i].Min.Set(boundaries[i-1])
+		}
+		if i < len(boundaries) {
+			tiers[i].Max = new(big.Int).Set(boundaries[i])
+		}
+		tiers[i].Total = new(big.Int)
+	}
+	for _, claim := range claims {
+		// The number of boundaries at or below the amount is its tier
+		i := sort.Search(len(boundaries), func(i int) bool {
+			return boundaries[i].Cmp(claim.Amount) > 0
+		})
+		tiers[i].Addresses++
+		tiers[i].Total.Add(tiers[i].Total, claim.Amount)
+	}
+	return tiers, nil
+}
+
+// ValidateTierBoundaries checks that boundaries are positive and strictly
+// increasing
+func ValidateTierBoundaries(boundaries []*big.Int) error {
+	for i, boundary := range boundaries {
+		if boundary == nil || boundary.Sign() <= 0 {
+			return fmt.Errorf("tier boundary %d must be positive, got %s", i, boundary)
+		}
+		if i > 0 && boundary.Cmp(boundaries[i-1]) <= 0 {
+			return fmt.Errorf("tier boundaries must be strictly increasing, got %s after %s", boundary, boundaries[i-1])
+		}
+	}
+	return nil
+}
+
+// ParseTierBoundaries parses comma-separated whole amounts in base units,
+// accepting exponents such as 100e18, and validates them as boundaries
+func ParseTierBoundaries(s string) ([]*big.Int, error) {
+	var boundaries []*big.Int
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		amount, ok := new(big.Rat).SetString(field)
+		if !ok || !amount.IsInt() {
+			return nil, fmt.Errorf("tier boundary %q is not a whole amount", field)
+		}
+		boundaries = append(boundaries, new(big.Int).Set(amount.Num()))
+	}
+	if err := ValidateTierBoundaries(boundaries); err != nil {
+		return nil, err
+	}
+	return boundaries, nil
+}
@@ -0,0 +1,170 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// VerifyFailure classifies why a traced proof didn't verify
+type VerifyFailure string
+
+const (
+	// LeafMismatch is a claim the tree doesn't hold: the address, amount or
+	// index differs from the tree's, while the path is the address's own
+	LeafMismatch VerifyFailure = "LEAF_MISMATCH"
+	// PathCorrupt is a proof element that is malformed or, when the tree's
+	// proof is known, isn't the sibling the tree has at that step
+	PathCorrupt VerifyFailure = "PATH_CORRUPT"
+	// RootMismatch is any other failure, such as a proof for a stale root
+	RootMismatch VerifyFailure = "ROOT_MISMATCH"
+)
+
+// TraceStep is one proof element as a trace consumed it
+type TraceStep struct {
+	Sibling string `json:"sibling"`        // As given in the proof
+	Left    bool   `json:"left,omitempty"` // Hashed on the left of the running hash
+	Hash    string `json:"hash,omitempty"` // The running hash after this step; empty when Sibling is malformed
+}
+
+// VerificationTrace shows how a proof was checked: the claim's leaf, each
+// intermediate hash and the root they lead to, and what failed when the
+// proof doesn't verify
+type VerificationTrace struct {
+	Leaf         string      `json:"leaf"`
+	Steps        []TraceStep `json:"steps"`
+	ComputedRoot string      `json:"computedRoot,omitempty"` // Empty when a step is malformed
+	ExpectedRoot string      `json:"expectedRoot"`
+	Valid        bool        `json:"valid"`
+
+	Failure    VerifyFailure `json:"failure,omitempty"`
+	FailedStep *int          `json:"failedStep,omitempty"` // The step at fault, for PATH_CORRUPT
+	Reason     string        `json:"reason,omitempty"`
+}
+
+// TraceReference is what the tree holds for a traced claim's address,
+// letting a trace tell a wrong claim or path from a wrong root
+type TraceReference struct {
+	Proof *MerkleProof // The address's proof; nil when the tree has no claim for it
+}
+
+// TraceMerkleProof checks proof for claim against root like
+// VerifyMerkleProof, recording each step. A malformed proof is reported in
+// the trace rather than as an error; only a claim that can't be hashed
+// fails. A nil ref, when the tree isn't at hand, classifies every failure
+// of a well-formed proof as ROOT_MISMATCH.
+func TraceMerkleProof(root []byte, claim AirdropClaim, proof *MerkleProof, opts TreeOptions, ref *TraceReference) (*VerificationTrace, error) {
+	if err := CheckAmount(int(claim.Index), claim.Amount); err != nil {
+		return nil, err
+	}
+	leaf, err := HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+	if err != nil {
+		return nil, err
+	}
+	trace := &VerificationTrace{
+		Leaf:         EncodeHash(leaf),
+		Steps:        make([]TraceStep, 0, len(proof.Proof)),
+		ExpectedRoot: EncodeHash(root),
+	}
+	fail := func(failure VerifyFailure, step int, format string, args ...interface{}) (*VerificationTrace, error) {
+		trace.Failure, trace.Reason = failure, fmt.Sprintf(format, args...)
+		if step >= 0 {
+			trace.FailedStep = &step
+		}
+		return trace, nil
+	}
+
+	path := make([][]byte, len(proof.Proof))
+	for i, element := range proof.Proof {
+		sibling, err := DecodeHash(element)
+		if err != nil {
+			trace.Steps = append(trace.Steps, TraceStep{Sibling: element})
+			return fail(PathCorrupt, i, "proof element %d is malformed: %v", i, err)
+		}
+		path[i] = sibling
+	}
+	if !opts.SortedPairs && len(path) < 64 && proof.Positions>>len(path) != 0 {
+		return fail(PathCorrupt, -1, "positions 0x%x have bits beyond the %d proof elements", proof.Positions, len(path))
+	}
+	trimmed, err := trimPadding(path, root, opts)
+	if err != nil {
+		return fail(PathCorrupt, -1, "%v", err)
+	}
+
+	current := leaf
+	for i, sibling := range trimmed {
+		// Sorted pairs put the smaller hash on the left
+		left := i < 64 && proof.Positions&(1<<i) != 0
+		if opts.SortedPairs {
+			left = bytes.Compare(sibling, current) < 0
+		}
+		l, r := current, sibling
+		if left {
+			l, r = sibling, current
+		}
+		next, err := HashPair(l, r, opts)
+		if err != nil {
+			trace.Steps = append(trace.Steps, TraceStep{Sibling: proof.Proof[i]})
+			return fail(PathCorrupt, i, "proof element %d can't be hashed: %v", i, err)
+		}
+		current = next
+		trace.Steps = append(trace.Steps, TraceStep{Sibling: proof.Proof[i], Left: left, Hash: EncodeHash(current)})
+	}
+	trace.ComputedRoot = EncodeHash(current)
+
+	// A stale proof says so itself when it records its root
+	var rootErr *RootMismatchError
+	switch err := CheckProofRoot(root, proof.Root); {
+	case errors.As(err, &rootErr):
+		return fail(RootMismatch, -1, "%v", err)
+	case err != nil:
+		return fail(PathCorrupt, -1, "%v", err)
+	}
+	if bytes.Equal(current, root) {
+		trace.Valid = true
+		return trace, nil
+	}
+	if ref == nil {
+		return fail(RootMismatch, -1, "the proof leads to %s, not the expected root", trace.ComputedRoot)
+	}
+	if ref.Proof == nil {
+		return fail(LeafMismatch, -1, "the tree has no claim for %s", claim.Address.Hex())
+	}
+	return classifyTrace(trace, claim, leaf, proof, ref.Proof, opts, fail)
+}
+
+// classifyTrace tells why a well-formed proof that leads to the wrong root
+// failed, comparing it with want, the proof the tree holds for the address
+func classifyTrace(trace *VerificationTrace, claim AirdropClaim, leaf []byte, proof, want *MerkleProof, opts TreeOptions,
+	fail func(VerifyFailure, int, string, ...interface{}) (*VerificationTrace, error)) (*VerificationTrace, error) {
+	amount, ok := new(big.Int).SetString(want.Amount, 10)
+	if !ok {
+		return nil, proofError("the tree's proof has an invalid amount %q", want.Amount)
+	}
+	wantLeaf, err := HashLeafWithOptions(claim.Address, amount, want.Index, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(leaf, wantLeaf) {
+		return fail(LeafMismatch, -1, "the tree holds amount %s at index %d for %s, not amount %s at index %d",
+			want.Amount, want.Index, claim.Address.Hex(), claim.Amount, claim.Index)
+	}
+
+	for i, element := range proof.Proof {
+		if i >= len(want.Proof) {
+			return fail(PathCorrupt, i, "the tree's proof has %d elements, not %d", len(want.Proof), len(proof.Proof))
+		}
+		if !strings.EqualFold(strings.TrimPrefix(element, "0x"), strings.TrimPrefix(want.Proof[i], "0x")) {
+			return fail(PathCorrupt, i, "proof element %d is not the tree's sibling %s", i, want.Proof[i])
+		}
+	}
+	if len(proof.Proof) < len(want.Proof) {
+		return fail(PathCorrupt, len(proof.Proof), "the tree's proof has %d elements, not %d", len(want.Proof), len(proof.Proof))
+	}
+	if !opts.SortedPairs && proof.Positions != want.Positions {
+		return fail(PathCorrupt, -1, "positions 0x%x are not the tree's 0x%x", proof.Positions, want.Positions)
+	}
+	return fail(RootMismatch, -1, "the tree's own proof leads to %s, not the expected root", trace.ComputedRoot)
+}
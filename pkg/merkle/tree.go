@@ -2,80 +2,526 @@
 package merkle
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"math/big"
 	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// NewMerkleTree creates a new Merkle tree from airdrop claims
+// DefaultTreeOptions returns the recommended options for building a tree
+func DefaultTreeOptions() TreeOptions {
+	return TreeOptions{
+		CopyClaims:    true,
+		IncludeIndex:  true,
+		SortedPairs:   true,
+		MaxAmountBits: DefaultMaxAmountBits,
+	}
+}
+
+// DefaultMetadata returns the metadata of a tree built with default options
+func DefaultMetadata() TreeMetadata {
+	return DefaultTreeOptions().Metadata()
+}
+
+// Metadata returns the subset of the options that affects hashing and order
+func (o TreeOptions) Metadata() TreeMetadata {
+	return TreeMetadata{
+		IncludeIndex: o.IncludeIndex,
+		SortOrder:    o.SortOrder,
+		KeepIndices:  o.KeepIndices,
+		SortedPairs:  o.SortedPairs,
+		IndexFirst:   o.IndexFirst,
+
+		OddLeafPolicy: o.OddLeafPolicy,
+		MaxAmountBits: o.amountBits(),
+		FixedDepth:    o.FixedDepth,
+
+		DomainSeparator: o.domainSeparatorHex(),
+	}
+}
+
+// domainSeparatorHex returns the metadata's domain separator, empty
+// without one
+func (o TreeOptions) domainSeparatorHex() string {
+	if len(o.DomainSeparator) == 0 {
+		return ""
+	}
+	return EncodeHash(o.DomainSeparator)
+}
+
+// DomainSeparatorFor returns the domain separator of a campaign name,
+// keccak256(name)
+func DomainSeparatorFor(name string) []byte {
+	return crypto.Keccak256([]byte(name))
+}
+
+// amountBits returns the metadata's amount width: zero for the default, so
+// trees built before the option existed have the same metadata
+func (o TreeOptions) amountBits() int {
+	if o.MaxAmountBits == DefaultMaxAmountBits {
+		return 0
+	}
+	return o.MaxAmountBits
+}
+
+// Validate checks the fields of decoded metadata that decoding leaves
+// unchecked: the domain separator must be 0x-prefixed hex
+func (m TreeMetadata) Validate() error {
+	if m.DomainSeparator == "" {
+		return nil
+	}
+	if _, err := decodeHexBytes(m.DomainSeparator); err != nil {
+		return fmt.Errorf("invalid domain separator: %w", err)
+	}
+	return nil
+}
+
+// Options returns tree options that hash leaves the way the metadata
+// describes. A domain separator Validate rejects is left out.
+func (m TreeMetadata) Options() TreeOptions {
+	opts := DefaultTreeOptions()
+	opts.IncludeIndex = m.IncludeIndex
+	opts.SortOrder = m.SortOrder
+	opts.KeepIndices = m.KeepIndices
+	opts.SortedPairs = m.SortedPairs
+	opts.IndexFirst = m.IndexFirst
+	opts.OddLeafPolicy = m.OddLeafPolicy
+	opts.FixedDepth = m.FixedDepth
+	if m.DomainSeparator != "" {
+		opts.DomainSeparator, _ = decodeHexBytes(m.DomainSeparator)
+	}
+	if m.MaxAmountBits != 0 {
+		opts.MaxAmountBits = m.MaxAmountBits
+	}
+	return opts
+}
+
+var sortOrderNames = map[SortOrder]string{
+	SortByAddress: "address",
+	SortByIndex:   "index",
+	PreserveInput: "input",
+}
+
+// String returns the name used for the order in metadata and flags
+func (o SortOrder) String() string {
+	if name, ok := sortOrderNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("SortOrder(%d)", int(o))
+}
+
+// ParseSortOrder parses a sort order name as returned by String
+func ParseSortOrder(name string) (SortOrder, error) {
+	for order, n := range sortOrderNames {
+		if n == name {
+			return order, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sort order: %q", name)
+}
+
+// MarshalText encodes the order by name
+func (o SortOrder) MarshalText() ([]byte, error) {
+	if _, ok := sortOrderNames[o]; !ok {
+		return nil, fmt.Errorf("unknown sort order: %d", int(o))
+	}
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes an order name; empty means SortByAddress
+func (o *SortOrder) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*o = SortByAddress
+		return nil
+	}
+	order, err := ParseSortOrder(string(text))
+	if err != nil {
+		return err
+	}
+	*o = order
+	return nil
+}
+
+var oddLeafPolicyNames = map[OddLeafPolicy]string{
+	DuplicateLast: "duplicate",
+	Promote:       "promote",
+	ZeroPad:       "zero",
+}
+
+// String returns the name used for the policy in metadata and flags
+func (p OddLeafPolicy) String() string {
+	if name, ok := oddLeafPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OddLeafPolicy(%d)", int(p))
+}
+
+// ParseOddLeafPolicy parses a policy name as returned by String
+func ParseOddLeafPolicy(name string) (OddLeafPolicy, error) {
+	for policy, n := range oddLeafPolicyNames {
+		if n == name {
+			return policy, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown odd leaf policy: %q", name)
+}
+
+// MarshalText encodes the policy by name
+func (p OddLeafPolicy) MarshalText() ([]byte, error) {
+	if _, ok := oddLeafPolicyNames[p]; !ok {
+		return nil, fmt.Errorf("unknown odd leaf policy: %d", int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a policy name; empty means DuplicateLast
+func (p *OddLeafPolicy) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = DuplicateLast
+		return nil
+	}
+	policy, err := ParseOddLeafPolicy(string(text))
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+// zeroHash is the sibling of an odd node under ZeroPad
+var zeroHash [32]byte
+
+// NewMerkleTree creates a new Merkle tree from airdrop claims.
+// The claims slice is sorted and re-indexed in place and the tree keeps
+// referencing it; use NewMerkleTreeWithOptions to build from a copy.
 func NewMerkleTree(claims []AirdropClaim) (*MerkleTree, error) {
+	opts := DefaultTreeOptions()
+	opts.CopyClaims = false
+	return NewMerkleTreeWithOptions(claims, opts)
+}
+
+// NewMerkleTreeWithOptions creates a new Merkle tree from airdrop claims
+func NewMerkleTreeWithOptions(claims []AirdropClaim, opts TreeOptions) (*MerkleTree, error) {
 	if len(claims) == 0 {
-		return nil, fmt.Errorf("no claims provided")
+		return nil, ErrEmptyClaims
+	}
+	if opts.MaxClaims < 0 {
+		return nil, fmt.Errorf("max claims must not be negative: %d", opts.MaxClaims)
+	}
+	if err := CheckClaimCount(len(claims), opts.MaxClaims); err != nil {
+		return nil, err
 	}
 
-	// Sort claims by address for deterministic tree
-	sort.Slice(claims, func(i, j int) bool {
-		return claims[i].Address.Hex() < claims[j].Address.Hex()
-	})
+	for i, claim := range claims {
+		// Negative amounts would hash like their absolute value
+		if err := CheckAmount(i, claim.Amount); err != nil {
+			return nil, fmt.Errorf("claim %d (%s): %w", i, claim.Address.Hex(), err)
+		}
+	}
+	if err := CheckAmountBits(claims, opts.MaxAmountBits); err != nil {
+		return nil, err
+	}
 
-	// Update indices after sorting
-	for i := range claims {
-		claims[i].Index = uint32(i)
+	if _, ok := sortOrderNames[opts.SortOrder]; !ok {
+		return nil, fmt.Errorf("unknown sort order: %d", int(opts.SortOrder))
+	}
+	if _, ok := oddLeafPolicyNames[opts.OddLeafPolicy]; !ok {
+		return nil, fmt.Errorf("unknown odd leaf policy: %d", int(opts.OddLeafPolicy))
+	}
+	if err := checkWorkers(opts.Workers); err != nil {
+		return nil, err
+	}
+	if opts.ProofBuffer < 0 {
+		return nil, fmt.Errorf("proof buffer must not be negative: %d", opts.ProofBuffer)
+	}
+	if err := checkFixedDepth(opts.FixedDepth); err != nil {
+		return nil, err
+	}
+	if err := checkTreeDepth(levelsFor(len(claims)), opts); err != nil {
+		return nil, err
+	}
+
+	opts.DomainSeparator = common.CopyBytes(opts.DomainSeparator)
+	if opts.CopyClaims {
+		claims = copyClaims(claims)
+	}
+	if opts.Indices != nil {
+		// The allocator keeps what it assigns, so nothing may fail the
+		// build after it: the checks above and this one come first
+		if err := checkUniqueAddresses(claims); err != nil {
+			return nil, err
+		}
+		if _, err := opts.Indices.Assign(claims); err != nil {
+			return nil, err
+		}
+		opts.KeepIndices = true
+	}
+
+	switch opts.SortOrder {
+	case SortByAddress:
+		// Sort claims by address for deterministic tree
+		sort.Slice(claims, func(i, j int) bool {
+			return claims[i].Address.Hex() < claims[j].Address.Hex()
+		})
+
+		if opts.KeepIndices {
+			if err := checkUniqueIndices(claims); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		// Update indices after sorting
+		for i := range claims {
+			claims[i].Index = uint32(i)
+		}
+	case SortByIndex:
+		sort.SliceStable(claims, func(i, j int) bool {
+			return claims[i].Index < claims[j].Index
+		})
+		fallthrough
+	case PreserveInput:
+		if err := checkUniqueIndices(claims); err != nil {
+			return nil, err
+		}
 	}
 
 	tree := &MerkleTree{
-		Claims: claims,
+		Claims:  claims,
+		options: opts,
 	}
 
 	// Create leaf nodes
 	leaves := make([]*MerkleNode, len(claims))
+	parallelRange(len(claims), opts.Workers, opts.OnWorkerStart, func(start, end int) {
+		for i := start; i < end; i++ {
+			claim := &claims[i]
+			// Amounts were checked above, so hashing cannot fail
+			hash, _ := HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+			leaves[i] = &MerkleNode{
+				Hash: hash,
+				Data: claim,
+			}
+		}
+	})
+
+	tree.index = make(map[common.Address]int, len(claims))
 	for i, claim := range claims {
-		hash := HashLeaf(claim.Address, claim.Amount, claim.Index)
-		leaves[i] = &MerkleNode{
-			Hash: hash,
-			Data: &claims[i],
+		// Keep the first occurrence, matching a front-to-back search
+		if _, exists := tree.index[claim.Address]; !exists {
+			tree.index[claim.Address] = i
 		}
 	}
 
 	tree.Leaves = leaves
+	tree.fingerprint = fingerprintLeaves(leaves)
 
 	// Build the tree bottom-up
-	tree.Root = tree.buildTree(leaves)
+	root, err := tree.buildTree(leaves)
+	if err != nil {
+		return nil, err
+	}
+	tree.Root = root
 
 	return tree, nil
 }
 
-// buildTree recursively builds the Merkle tree
-func (mt *MerkleTree) buildTree(nodes []*MerkleNode) *MerkleNode {
+// checkUniqueIndices rejects claims that keep their own indices when two
+// share one, since the contract's claimed bitmap relies on them being unique
+func checkUniqueIndices(claims []AirdropClaim) error {
+	seen := make(map[uint32]bool, len(claims))
+	for _, claim := range claims {
+		if seen[claim.Index] {
+			return fmt.Errorf("duplicate claim index %d", claim.Index)
+		}
+		seen[claim.Index] = true
+	}
+	return nil
+}
+
+// checkUniqueAddresses rejects claims that repeat an address, which an
+// allocator would give a single index
+func checkUniqueAddresses(claims []AirdropClaim) error {
+	seen := make(map[common.Address]bool, len(claims))
+	for _, claim := range claims {
+		if seen[claim.Address] {
+			return fmt.Errorf("duplicate claim address %s", claim.Address.Hex())
+		}
+		seen[claim.Address] = true
+	}
+	return nil
+}
+
+// buildTree recursively builds the Merkle tree, recording each level's
+// hashes so proofs can be read off without rebuilding
+func (mt *MerkleTree) buildTree(nodes []*MerkleNode) (*MerkleNode, error) {
+	hashes := make([][]byte, len(nodes))
+	for i, node := range nodes {
+		hashes[i] = node.Hash
+	}
+	mt.levels = append(mt.levels, hashes)
+
 	if len(nodes) == 1 {
-		return nodes[0]
+		return nodes[0], nil
 	}
 
-	var nextLevel []*MerkleNode
+	nextLevel := make([]*MerkleNode, (len(nodes)+1)/2)
+
+	// Process pairs of nodes, keeping the first error any worker hits
+	var (
+		errOnce  sync.Once
+		levelErr error
+	)
+	parallelRange(len(nextLevel), mt.options.Workers, mt.options.OnWorkerStart, func(start, end int) {
+		for p := start; p < end; p++ {
+			left := nodes[2*p]
+			var right *MerkleNode
 
-	// Process pairs of nodes
-	for i := 0; i < len(nodes); i += 2 {
-		left := nodes[i]
-		var right *MerkleNode
+			if 2*p+1 < len(nodes) {
+				right = nodes[2*p+1]
+			} else {
+				// Odd number of nodes: pair the last one per the policy
+				switch mt.options.OddLeafPolicy {
+				case Promote:
+					nextLevel[p] = left
+					continue
+				case ZeroPad:
+					right = &MerkleNode{Hash: zeroHash[:]}
+				default:
+					right = left
+				}
+			}
 
-		if i+1 < len(nodes) {
-			right = nodes[i+1]
-		} else {
-			// Odd number of nodes, duplicate the last one
-			right = left
+			// Create parent node
+			hash, err := HashPair(left.Hash, right.Hash, mt.options)
+			if err != nil {
+				errOnce.Do(func() { levelErr = fmt.Errorf("failed to hash level %d: %w", len(mt.levels), err) })
+				return
+			}
+			nextLevel[p] = &MerkleNode{
+				Hash:  hash,
+				Left:  left,
+				Right: right,
+			}
 		}
+	})
+	if levelErr != nil {
+		return nil, levelErr
+	}
+
+	return mt.buildTree(nextLevel)
+}
+
+// minParallelRange is the smallest amount of work split between workers;
+// below it goroutine overhead outweighs the hashing
+const minParallelRange = 1024
+
+// parallelRange calls fn over [0, n) split into contiguous chunks, one per
+// worker; zero workers means one per CPU. Each worker started is reported
+// to onStart.
+func parallelRange(n, workers int, onStart func(poolSize int), fn func(start, end int)) {
+	workers = resolveWorkers(workers)
+	if workers > n/minParallelRange {
+		workers = n / minParallelRange
+	}
+	if workers <= 1 {
+		fn(0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	pool := (n + chunk - 1) / chunk
+	for start := 0; start < n; start += chunk {
+		end := min(start+chunk, n)
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			workerStarted(onStart, pool)
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
+
+// FindClaim returns the claim for address without generating its proof
+func (mt *MerkleTree) FindClaim(address common.Address) (AirdropClaim, bool) {
+	i, ok := mt.index[address]
+	if !ok {
+		return AirdropClaim{}, false
+	}
+	return *mt.Leaves[i].Data, true
+}
 
-		// Create parent node
-		parentHash := HashInternal(left.Hash, right.Hash)
-		parent := &MerkleNode{
-			Hash:  parentHash,
-			Left:  left,
-			Right: right,
+// AddressCount returns the number of distinct addresses with a claim, the
+// number of proofs GenerateAllProofs returns
+func (mt *MerkleTree) AddressCount() int {
+	return len(mt.index)
+}
+
+// AddClaims returns a new tree of the tree's claims and added, which take
+// the next free indices in order. The tree's claims keep their indices and
+// so their leaves; the tree itself is unchanged. Addresses the tree already
+// has fail with an ExistingClaimError listing its claims for them.
+func (mt *MerkleTree) AddClaims(added []AirdropClaim) (*MerkleTree, error) {
+	if len(added) == 0 {
+		return nil, ErrEmptyClaims
+	}
+	if err := CheckClaimCount(len(mt.Claims)+len(added), mt.options.MaxClaims); err != nil {
+		return nil, err
+	}
+
+	var existing []AirdropClaim
+	seen := make(map[common.Address]bool, len(added))
+	for _, claim := range added {
+		if seen[claim.Address] {
+			return nil, fmt.Errorf("%s is added twice", claim.Address.Hex())
+		}
+		seen[claim.Address] = true
+		if have, ok := mt.FindClaim(claim.Address); ok {
+			existing = append(existing, have)
 		}
+	}
+	if len(existing) > 0 {
+		return nil, &ExistingClaimError{Claims: existing}
+	}
 
-		nextLevel = append(nextLevel, parent)
+	next := uint64(0)
+	for _, claim := range mt.Claims {
+		next = max(next, uint64(claim.Index)+1)
+	}
+	if next+uint64(len(added)) > math.MaxUint32+1 {
+		return nil, fmt.Errorf("no free indices for %d more claims", len(added))
 	}
 
-	return mt.buildTree(nextLevel)
+	claims := make([]AirdropClaim, 0, len(mt.Claims)+len(added))
+	claims = append(claims, mt.Claims...)
+	for i, claim := range added {
+		claim.Index = uint32(next) + uint32(i)
+		claims = append(claims, claim)
+	}
+
+	opts := mt.options
+	if opts.SortOrder == SortByAddress {
+		// Sorting would otherwise renumber the claims after the new ones
+		opts.KeepIndices = true
+	}
+	return NewMerkleTreeWithOptions(claims, opts)
+}
+
+// Options returns the options the tree was built with
+func (mt *MerkleTree) Options() TreeOptions {
+	return mt.options
+}
+
+// Metadata describes how the tree's leaves and nodes were hashed
+func (mt *MerkleTree) Metadata() TreeMetadata {
+	return mt.options.Metadata()
 }
 
 // GetRootHash returns the root hash as hex string
@@ -83,5 +529,76 @@ func (mt *MerkleTree) GetRootHash() string {
 	if mt.Root == nil {
 		return ""
 	}
-	return fmt.Sprintf("0x%x", mt.Root.Hash)
+	return EncodeHash(mt.Root.Hash)
+}
+
+// copyClaims returns a deep copy of claims, including the amounts
+func copyClaims(claims []AirdropClaim) []AirdropClaim {
+	copied := make([]AirdropClaim, len(claims))
+	for i, claim := range claims {
+		copied[i] = claim
+		if claim.Amount != nil {
+			copied[i].Amount = new(big.Int).Set(claim.Amount)
+		}
+	}
+	return copied
+}
+
+// fingerprintLeaves hashes the concatenated leaf hashes
+func fingerprintLeaves(leaves []*MerkleNode) []byte {
+	data := make([]byte, 0, len(leaves)*32)
+	for _, leaf := range leaves {
+		data = append(data, leaf.Hash...)
+	}
+	return crypto.Keccak256(data)
+}
+
+// Fingerprint returns a digest of the claims taken when the tree was built
+func (mt *MerkleTree) Fingerprint() []byte {
+	return append([]byte(nil), mt.fingerprint...)
+}
+
+// checkLeaf ensures a leaf's claim still matches the data it was hashed from
+func (mt *MerkleTree) checkLeaf(leaf *MerkleNode) error {
+	if mt.options.CopyClaims {
+		return nil
+	}
+
+	claim := leaf.Data
+	hash, err := HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, mt.options)
+	if err != nil || !bytes.Equal(hash, leaf.Hash) {
+		return fmt.Errorf("claims modified after tree was built (leaf %d, address %s)", claim.Index, claim.Address.Hex())
+	}
+	return nil
+}
+
+// checkIntegrity recomputes the fingerprint from the current claims and
+// compares it with the one taken at build time. Trees built from leaf
+// hashes have no claims to check and fail with errNoClaims; trees without
+// leaves, or whose claims were emptied, fail with ErrEmptyClaims.
+func (mt *MerkleTree) checkIntegrity() error {
+	if len(mt.Leaves) == 0 || (mt.Claims != nil && len(mt.Claims) == 0) {
+		return fmt.Errorf("tree has no claims to prove: %w", ErrEmptyClaims)
+	}
+	if mt.Claims == nil {
+		return errNoClaims
+	}
+	if mt.options.CopyClaims {
+		return nil
+	}
+
+	data := make([]byte, 0, len(mt.Leaves)*32)
+	for _, leaf := range mt.Leaves {
+		claim := leaf.Data
+		hash, err := HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, mt.options)
+		if err != nil {
+			return fmt.Errorf("claims modified after tree was built (address %s): %w", claim.Address.Hex(), err)
+		}
+		data = append(data, hash...)
+	}
+
+	if !bytes.Equal(crypto.Keccak256(data), mt.fingerprint) {
+		return fmt.Errorf("claims modified after tree was built (fingerprint mismatch)")
+	}
+	return nil
 }
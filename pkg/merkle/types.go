@@ -27,6 +27,140 @@ type MerkleTree struct {
 	Root   *MerkleNode
 	Leaves []*MerkleNode
 	Claims []AirdropClaim
+
+	options     TreeOptions
+	fingerprint []byte                 // Digest of the claims at build time
+	levels      [][][]byte             // Node hashes per level, leaves first
+	index       map[common.Address]int // Leaf position by address
+}
+
+// TreeOptions controls how a Merkle tree is built
+type TreeOptions struct {
+	// CopyClaims deep-copies the input claims (including amounts) so later
+	// changes to the caller's slice cannot affect the tree
+	CopyClaims bool
+
+	// IncludeIndex appends the claim index to the leaf preimage. When false,
+	// leaves are keccak256(abi.encodePacked(address, amount)).
+	IncludeIndex bool
+
+	// IndexFirst, with IncludeIndex, hashes leaves as
+	// keccak256(abi.encodePacked(uint256(index), address, amount)), the
+	// encoding of Uniswap's MerkleDistributor
+	IndexFirst bool
+
+	// SortOrder selects the leaf order. Indices are rewritten to leaf
+	// positions only when sorting by address; the other orders keep the
+	// indices the claims already carry.
+	SortOrder SortOrder
+
+	// KeepIndices keeps the indices the claims carry when sorting by
+	// address, instead of rewriting them to leaf positions. Indices must be
+	// unique.
+	KeepIndices bool
+
+	// Indices, when set, gives each claim the index the allocator holds for
+	// its address, assigning new addresses the next free ones, and then
+	// keeps them as KeepIndices does. Indices survive rebuilds that add or
+	// remove claims; removals leave gaps, which trees tolerate. A build
+	// that fails leaves the allocator unchanged.
+	Indices *IndexAllocator
+
+	// SortedPairs orders each pair of child hashes before hashing them, as
+	// OpenZeppelin's MerkleProof does. When false, the left child always
+	// comes first and proofs carry the position of every sibling.
+	SortedPairs bool
+
+	// OddLeafPolicy selects what pairs with the last node of a level with
+	// an odd number of nodes
+	OddLeafPolicy OddLeafPolicy
+
+	// MaxAmountBits rejects claims whose amounts don't fit the
+	// distributor's amount type, such as 96 for a uint96. Zero means
+	// DefaultMaxAmountBits.
+	MaxAmountBits int
+
+	// FixedDepth pads every proof to this many elements for verifiers of
+	// a fixed depth, such as zk circuits, and rejects trees deeper than it.
+	// Padding follows the real siblings: the root under DuplicateLast and
+	// the zero hash otherwise. Zero disables padding.
+	FixedDepth int
+
+	// DomainSeparator is prepended to every leaf preimage when set, so the
+	// same claim hashes differently in trees of different campaigns; see
+	// DomainSeparatorFor. Without it leaves hash as they always have.
+	DomainSeparator []byte
+
+	// Workers is the number of goroutines hashing leaves and tree levels,
+	// and generating proofs with GenerateAllProofs. Zero uses one per CPU,
+	// one builds serially and negative counts are rejected; the root does
+	// not depend on it.
+	Workers int
+
+	// ProofBuffer bounds the proofs GenerateAllProofsTo and the functions
+	// built on it hold at once: generated but not yet taken by their sink,
+	// or waiting for a worker. Workers wait for a slow sink rather than
+	// run ahead of it. Zero uses DefaultProofBuffer; proofs do not depend
+	// on it.
+	ProofBuffer int
+
+	// OnWorkerStart, when set, is called by each worker goroutine that
+	// building the tree and generating its proofs start, with the number
+	// of workers in its pool. Workers call it concurrently.
+	OnWorkerStart func(poolSize int)
+
+	// OnProofHeld, when set, is called by GenerateAllProofsTo and the
+	// functions built on it for each proof generated, with the number of
+	// proofs then held: generated or dispatched to a worker, and not yet
+	// taken by the sink. It is called on the generating goroutine.
+	OnProofHeld func(held int)
+
+	// MaxClaims fails a tree of more claims, or additions with AddClaims
+	// that would take it over, with a TooManyClaimsError. Zero is
+	// unlimited and negative limits are rejected.
+	MaxClaims int
+}
+
+// SortOrder selects how claims are ordered into leaves
+type SortOrder int
+
+const (
+	// SortByAddress orders leaves by checksummed address
+	SortByAddress SortOrder = iota
+	// SortByIndex orders leaves by the claims' existing indices
+	SortByIndex
+	// PreserveInput keeps leaves in the order the claims were given
+	PreserveInput
+)
+
+// OddLeafPolicy selects how a level with an odd number of nodes is paired
+type OddLeafPolicy int
+
+const (
+	// DuplicateLast hashes the last node with itself
+	DuplicateLast OddLeafPolicy = iota
+	// Promote carries the last node up to the next level unhashed, as
+	// merkletreejs and Uniswap's generator do. Proofs skip that level, so
+	// they can be shorter than the tree is deep.
+	Promote
+	// ZeroPad hashes the last node with 32 zero bytes
+	ZeroPad
+)
+
+// TreeMetadata records the options that affect hashing and leaf order, so
+// proofs can be verified with the same encoding they were generated with
+type TreeMetadata struct {
+	IncludeIndex bool      `json:"includeIndex"`
+	SortOrder    SortOrder `json:"sortOrder"`
+	KeepIndices  bool      `json:"keepIndices,omitempty"`
+	SortedPairs  bool      `json:"sortedPairs"`
+	IndexFirst   bool      `json:"indexFirst,omitempty"`
+
+	OddLeafPolicy OddLeafPolicy `json:"oddLeafPolicy,omitempty"`
+	MaxAmountBits int           `json:"maxAmountBits,omitempty"` // Zero for DefaultMaxAmountBits
+	FixedDepth    int           `json:"fixedDepth,omitempty"`
+
+	DomainSeparator string `json:"domainSeparator,omitempty"` // 0x-prefixed hex
 }
 
 // MerkleProof represents the proof needed to verify a claim
@@ -34,4 +168,23 @@ type MerkleProof struct {
 	Proof  []string `json:"proof"`
 	Index  uint32   `json:"index"`
 	Amount string   `json:"amount"`
+
+	// Positions is set for trees without sorted pairs: bit i is set when
+	// Proof[i] is the left sibling
+	Positions uint64 `json:"positions,omitempty"`
+
+	// PaddingCount is the number of trailing elements of Proof that pad it
+	// to the tree's FixedDepth
+	PaddingCount int `json:"paddingCount,omitempty"`
+
+	// Root is the 0x-prefixed root the proof was generated against, so a
+	// proof checked against another root fails with ErrRootMismatch
+	// rather than as invalid. Empty in files written before it was added.
+	Root string `json:"root,omitempty"`
+}
+
+// ProofSet holds generated proofs keyed by checksummed address
+type ProofSet struct {
+	Proofs   map[string]*MerkleProof
+	Metadata TreeMetadata
 }
@@ -0,0 +1,238 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// UpdateAmounts sets the amounts of claims the tree already has, in place.
+// Leaf order doesn't depend on amounts, so only the changed leaves and the
+// nodes above them are rehashed, giving the root a full rebuild would. It
+// returns the new proofs of the addresses whose proofs changed, keyed by
+// checksummed address; the others' proofs differ only in their Root. An
+// amount changed anywhere is a sibling on every other leaf's path, so in
+// practice that is every address: the saving is in hashing, not proofs.
+//
+// Addresses the tree doesn't have, and zero or invalid amounts, fail
+// before anything is changed. The tree must not be read while it runs,
+// and hashes returned by Level and PathForAddress change with it: to
+// update a tree being served, update its Clone and swap that in, the way
+// a rebuilt tree is.
+func (mt *MerkleTree) UpdateAmounts(changes map[common.Address]*big.Int) (map[string]*MerkleProof, error) {
+	if err := mt.checkIntegrity(); err != nil {
+		return nil, err
+	}
+
+	addresses := make([]common.Address, 0, len(changes))
+	for address := range changes {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i][:], addresses[j][:]) < 0
+	})
+
+	type update struct {
+		pos    int
+		amount *big.Int
+		hash   []byte
+	}
+	updates := make([]update, 0, len(addresses))
+	for _, address := range addresses {
+		pos, ok := mt.index[address]
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", address.Hex(), ErrAddressNotFound)
+		}
+		claim := mt.Leaves[pos].Data
+		amount := changes[address]
+		if err := CheckAmount(int(claim.Index), amount); err != nil {
+			return nil, fmt.Errorf("claim %s: %w", address.Hex(), err)
+		}
+		if amount.Sign() == 0 {
+			return nil, fmt.Errorf("claim %s: %w", address.Hex(), &InvalidAmountError{Index: int(claim.Index), Value: "0", Reason: "must be positive"})
+		}
+		hash, err := HashLeafWithOptions(claim.Address, amount, claim.Index, mt.options)
+		if err != nil {
+			return nil, fmt.Errorf("claim %s: %w", address.Hex(), err)
+		}
+		if !bytes.Equal(hash, mt.Leaves[pos].Hash) {
+			updates = append(updates, update{pos: pos, amount: amount, hash: hash})
+		}
+	}
+	err := checkAmountBits(len(updates), mt.options.MaxAmountBits, func(i int) (string, *big.Int) {
+		return mt.Leaves[updates[i].pos].Data.Address.Hex(), updates[i].amount
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(updates) == 0 {
+		return map[string]*MerkleProof{}, nil
+	}
+
+	// Hashes are written into the nodes' own buffers, which the levels
+	// share, so the nodes and levels stay in step
+	dirty := make([][]bool, len(mt.levels))
+	for level, hashes := range mt.levels {
+		dirty[level] = make([]bool, len(hashes))
+	}
+	positions := make([]int, len(updates))
+	for i, u := range updates {
+		mt.Leaves[u.pos].Data.Amount = new(big.Int).Set(u.amount)
+		copy(mt.levels[0][u.pos], u.hash)
+		dirty[0][u.pos] = true
+		positions[i] = u.pos
+	}
+	sort.Ints(positions)
+	for level := 0; level < len(mt.levels)-1; level++ {
+		if positions, err = mt.rehashParents(level, positions, dirty[level+1]); err != nil {
+			return nil, err
+		}
+	}
+	mt.fingerprint = fingerprintLeaves(mt.Leaves)
+
+	changed := mt.changedProofs(dirty)
+	generated := make([]*MerkleProof, len(mt.Leaves)) // Workers write apart
+	mt.generateProofsAt(mt.options.Workers, changed, func(i int, proof *MerkleProof) {
+		generated[i] = proof
+	})
+	proofs := make(map[string]*MerkleProof, len(changed))
+	for _, i := range changed {
+		proofs[mt.Leaves[i].Data.Address.Hex()] = generated[i]
+	}
+	return proofs, nil
+}
+
+// Clone returns a copy of the tree sharing no claims or hashes with it, so
+// the copy can be updated while the tree is read. Nothing is rehashed.
+func (mt *MerkleTree) Clone() *MerkleTree {
+	clone := &MerkleTree{
+		options:     mt.options,
+		fingerprint: bytes.Clone(mt.fingerprint),
+		index:       maps.Clone(mt.index),
+	}
+	if mt.Claims != nil {
+		clone.Claims = copyClaims(mt.Claims)
+	}
+	if len(mt.Leaves) == 0 {
+		return clone
+	}
+
+	// The nodes are linked as buildTree links them, and the levels share
+	// their buffers, as UpdateAmounts needs
+	nodes := make([]*MerkleNode, len(mt.Leaves))
+	for i, leaf := range mt.Leaves {
+		nodes[i] = &MerkleNode{Hash: bytes.Clone(leaf.Hash)}
+		if leaf.Data != nil {
+			nodes[i].Data = &clone.Claims[i]
+		}
+	}
+	clone.Leaves = nodes
+	for level := 1; ; level++ {
+		hashes := make([][]byte, len(nodes))
+		for i, node := range nodes {
+			hashes[i] = node.Hash
+		}
+		clone.levels = append(clone.levels, hashes)
+		if len(nodes) == 1 {
+			break
+		}
+
+		parents := make([]*MerkleNode, (len(nodes)+1)/2)
+		for p := range parents {
+			left, right := nodes[2*p], (*MerkleNode)(nil)
+			if 2*p+1 < len(nodes) {
+				right = nodes[2*p+1]
+			} else {
+				switch mt.options.OddLeafPolicy {
+				case Promote:
+					parents[p] = left
+					continue
+				case ZeroPad:
+					right = &MerkleNode{Hash: zeroHash[:]}
+				default:
+					right = left
+				}
+			}
+			parents[p] = &MerkleNode{Hash: bytes.Clone(mt.levels[level][p]), Left: left, Right: right}
+		}
+		nodes = parents
+	}
+	clone.Root = nodes[0]
+	return clone
+}
+
+// rehashParents recomputes the parents in level+1 of the sorted positions
+// of level, marking them in dirty, and returns the parents' positions
+func (mt *MerkleTree) rehashParents(level int, positions []int, dirty []bool) ([]int, error) {
+	nodes, parents := mt.levels[level], mt.levels[level+1]
+	next := positions[:0]
+	for _, pos := range positions {
+		p := pos / 2
+		if dirty[p] {
+			continue
+		}
+		dirty[p] = true
+		next = append(next, p)
+
+		left := nodes[2*p]
+		var right []byte
+		if 2*p+1 < len(nodes) {
+			right = nodes[2*p+1]
+		} else {
+			switch mt.options.OddLeafPolicy {
+			case Promote:
+				copy(parents[p], left) // The same buffer
+				continue
+			case ZeroPad:
+				right = zeroHash[:]
+			default:
+				right = left
+			}
+		}
+		hash, err := HashPair(left, right, mt.options)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash level %d: %w", level+1, err)
+		}
+		copy(parents[p], hash)
+	}
+	return next, nil
+}
+
+// changedProofs returns the positions, in leaf order, of the first
+// occurrences whose proofs differ after the nodes in dirty were rehashed:
+// their own leaf or a sibling on their path changed, or, under
+// DuplicateLast, the root their proof is padded with
+func (mt *MerkleTree) changedProofs(dirty [][]bool) []int {
+	top := len(mt.levels) - 1
+	padded := mt.options.OddLeafPolicy == DuplicateLast && mt.options.FixedDepth > top
+
+	// stale marks the nodes a changed sibling sits above or beside
+	stale := make([]bool, 1)
+	stale[0] = padded && dirty[top][0]
+	for level := top - 1; level >= 0; level-- {
+		below := make([]bool, len(mt.levels[level]))
+		for i := range below {
+			sibling := false
+			switch {
+			case i^1 < len(below):
+				sibling = dirty[level][i^1]
+			case mt.options.OddLeafPolicy == DuplicateLast:
+				sibling = dirty[level][i]
+			}
+			below[i] = stale[i/2] || sibling
+		}
+		stale = below
+	}
+
+	var changed []int
+	for i, leaf := range mt.Leaves {
+		if (stale[i] || dirty[0][i]) && mt.index[leaf.Data.Address] == i {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
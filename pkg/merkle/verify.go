@@ -0,0 +1,150 @@
+package merkle
+
+import (
+	"bytes"
+)
+
+// VerifyProof checks that claim is included under root using a hex-encoded
+// proof, hashing the leaf with the encoding selected by opts. An error is
+// returned when the claim or a proof element is malformed. Trees without
+// sorted pairs need the sibling positions; use VerifyProofWithPositions,
+// or VerifyMerkleProof to check the root a MerkleProof records too.
+func VerifyProof(root []byte, claim AirdropClaim, proof []string, opts TreeOptions) (bool, error) {
+	if !opts.SortedPairs {
+		return false, errPositionsRequired
+	}
+	return VerifyProofWithPositions(root, claim, proof, 0, opts)
+}
+
+// VerifyProofWithPositions is VerifyProof with the positions bitmap of a
+// MerkleProof, which is ignored when opts uses sorted pairs
+func VerifyProofWithPositions(root []byte, claim AirdropClaim, proof []string, positions uint64, opts TreeOptions) (bool, error) {
+	path, err := decodeProof(proof)
+	if err != nil {
+		return false, err
+	}
+
+	return VerifyProofBytesWithPositions(root, claim, path, positions, opts)
+}
+
+// VerifyMerkleProof checks proof for claim against root like
+// VerifyProofWithPositions, after checking the root the proof records with
+// CheckProofRoot
+func VerifyMerkleProof(root []byte, claim AirdropClaim, proof *MerkleProof, opts TreeOptions) (bool, error) {
+	if err := CheckProofRoot(root, proof.Root); err != nil {
+		return false, err
+	}
+	return VerifyProofWithPositions(root, claim, proof.Proof, proof.Positions, opts)
+}
+
+// CheckProofRoot returns a RootMismatchError when proofRoot, the root a
+// proof records, is set and isn't root. Proofs that record no root, such
+// as those of older files, pass.
+func CheckProofRoot(root []byte, proofRoot string) error {
+	if proofRoot == "" {
+		return nil
+	}
+	recorded, err := DecodeHash(proofRoot)
+	if err != nil {
+		return proofError("invalid proof root %q: expected a 32-byte hex hash", proofRoot)
+	}
+	if !bytes.Equal(recorded, root) {
+		return &RootMismatchError{Root: EncodeHash(root), ProofRoot: EncodeHash(recorded)}
+	}
+	return nil
+}
+
+// VerifyProofBytes is VerifyProof for a proof of raw 32-byte hashes
+func VerifyProofBytes(root []byte, claim AirdropClaim, proof [][]byte, opts TreeOptions) (bool, error) {
+	if !opts.SortedPairs {
+		return false, errPositionsRequired
+	}
+	return VerifyProofBytesWithPositions(root, claim, proof, 0, opts)
+}
+
+// VerifyProofBytesWithPositions is VerifyProofWithPositions for a proof of
+// raw 32-byte hashes
+func VerifyProofBytesWithPositions(root []byte, claim AirdropClaim, proof [][]byte, positions uint64, opts TreeOptions) (bool, error) {
+	if err := checkClaimProof(claim, proof); err != nil {
+		return false, err
+	}
+	proof, err := trimPadding(proof, root, opts)
+	if err != nil {
+		return false, err
+	}
+
+	computed, err := foldProof(claim, proof, positions, opts)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(computed, root), nil
+}
+
+// errPositionsRequired is returned when a proof without positions is
+// checked against a tree that needs them
+var errPositionsRequired error = &InvalidProofError{Reason: "proof positions are required for trees without sorted pairs"}
+
+// foldProof hashes claim's leaf up through proof, returning the root it
+// implies. Without sorted pairs, bit i of positions puts proof[i] on the
+// left.
+func foldProof(claim AirdropClaim, proof [][]byte, positions uint64, opts TreeOptions) ([]byte, error) {
+	leaf, err := HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+	if err != nil {
+		return nil, err
+	}
+	return foldLeaf(leaf, proof, positions, opts)
+}
+
+// foldLeaf hashes leaf up through proof like foldProof, for leaves already
+// hashed
+func foldLeaf(leaf []byte, proof [][]byte, positions uint64, opts TreeOptions) ([]byte, error) {
+	if !opts.SortedPairs && len(proof) < 64 && positions>>len(proof) != 0 {
+		return nil, proofError("positions 0x%x have bits beyond the %d proof elements", positions, len(proof))
+	}
+
+	currentHash := leaf
+	for i, sibling := range proof {
+		left, right := currentHash, sibling
+		if i < 64 && positions&(1<<i) != 0 {
+			left, right = sibling, currentHash
+		}
+		next, err := HashPair(left, right, opts)
+		if err != nil {
+			return nil, proofError("invalid proof element %d: %v", i, err)
+		}
+		currentHash = next
+	}
+	return currentHash, nil
+}
+
+// decodeProof decodes hex-encoded proof elements
+func decodeProof(proof []string) ([][]byte, error) {
+	path := make([][]byte, len(proof))
+	for i, element := range proof {
+		sibling, err := DecodeHash(element)
+		if err != nil {
+			return nil, proofError("invalid proof element %d: %v", i, err)
+		}
+		path[i] = sibling
+	}
+	return path, nil
+}
+
+// checkClaimProof checks that claim can be hashed and proof holds 32-byte
+// hashes
+func checkClaimProof(claim AirdropClaim, proof [][]byte) error {
+	if err := CheckAmount(int(claim.Index), claim.Amount); err != nil {
+		return err
+	}
+	return checkProofHashes(proof)
+}
+
+// checkProofHashes checks that proof holds 32-byte hashes
+func checkProofHashes(proof [][]byte) error {
+	for i, sibling := range proof {
+		if len(sibling) != 32 {
+			return proofError("invalid proof element %d: expected 32 bytes, got %d", i, len(sibling))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package merkle
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// checkWorkers rejects a negative worker count
+func checkWorkers(workers int) error {
+	if workers < 0 {
+		return fmt.Errorf("workers must not be negative: %d", workers)
+	}
+	return nil
+}
+
+// resolveWorkers returns the worker count a setting asks for, where zero
+// means one per CPU
+func resolveWorkers(workers int) int {
+	if workers == 0 {
+		return runtime.NumCPU()
+	}
+	return workers
+}
+
+// workerStarted reports a new worker of a pool of poolSize to hook, when
+// set
+func workerStarted(hook func(poolSize int), poolSize int) {
+	if hook != nil {
+		hook(poolSize)
+	}
+}
@@ -0,0 +1,316 @@
+// Package release records what a campaign launch is made of in one
+// manifest, release.json: the claims it was built from, the tree's root and
+// leaf encoding, the proofs file, the deployed contracts and the tool that
+// generated them, each file with its SHA-256. VerifyManifest checks a
+// release against its manifest, so CI can refuse artifacts that changed
+// after it was cut.
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// ManifestVersion is the version of the manifest schema
+const ManifestVersion = 1
+
+// DefaultManifestFile is where the CLI writes a release's manifest
+const DefaultManifestFile = "release.json"
+
+// Version is the generator's version, set at build time with
+// -ldflags "-X merkle-airdrop/pkg/release.Version=v1.4.0"
+var Version = "dev"
+
+// Manifest is the content of release.json
+type Manifest struct {
+	ManifestVersion int    `json:"manifestVersion"`
+	Campaign        string `json:"campaign,omitempty"`
+	Version         string `json:"version"` // Semantic version of the campaign, e.g. 1.2.0
+
+	Input        Artifact            `json:"input"` // Claims CSV the tree was built from
+	MerkleRoot   string              `json:"merkleRoot"`
+	LeafEncoding merkle.TreeMetadata `json:"leafEncoding"`
+	Proofs       Artifact            `json:"proofs"`
+	Config       *Artifact           `json:"config,omitempty"`
+	TotalAmount  string              `json:"totalAmount"`
+	TotalClaims  int                 `json:"totalClaims"`
+
+	// Deployments of the root, keyed by chain name; empty before the
+	// contracts are deployed
+	Deployments map[string]Deployment `json:"deployments,omitempty"`
+
+	Generator  Generator `json:"generator"`
+	CreatedAt  string    `json:"createdAt"`            // RFC3339, UTC
+	BuiltAt    string    `json:"builtAt,omitempty"`    // When the proofs were generated, as the proofs file records it
+	DeployedAt string    `json:"deployedAt,omitempty"` // Last change to the deployments file
+}
+
+// Artifact is a file of the release. Path is relative to the manifest's
+// directory, with forward slashes.
+type Artifact struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"` // Hex, no prefix
+}
+
+// Deployment is a distributor serving the release's root
+type Deployment struct {
+	ChainID     uint64 `json:"chainId"`
+	Contract    string `json:"contract"`
+	Token       string `json:"token"`
+	TxHash      string `json:"txHash"`
+	BlockNumber uint64 `json:"blockNumber"`
+}
+
+// Generator identifies the build of the tool that cut the release
+type Generator struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"goVersion,omitempty"`
+	Revision  string `json:"revision,omitempty"` // VCS commit the tool was built from
+	Modified  bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+}
+
+// Options are the artifacts and details of a release to create
+type Options struct {
+	Campaign string
+	Version  string // Semantic version, required
+
+	Input       string           // Claims CSV, read with Layout
+	Layout      data.LoadOptions // HasHeader is usually wanted; see data.DefaultLoadOptions
+	Proofs      string           // Proofs file (.json or .bin) built from Input
+	Config      string           // Config file to record; none when empty
+	Deployments string           // deployments.json from the deploy command; none when empty
+
+	CreatedAt time.Time // The current time when zero
+}
+
+// semver matches semantic versions (https://semver.org) without a v prefix
+var semver = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?(\+[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
+
+// ValidateVersion checks that version is a semantic version such as 1.2.0
+// or 2.0.0-rc.1
+func ValidateVersion(version string) error {
+	if !semver.MatchString(version) {
+		return fmt.Errorf("invalid version %q: expected a semantic version such as 1.2.0", version)
+	}
+	return nil
+}
+
+// Create describes the release of opts and writes its manifest to path,
+// atomically. The tree is rebuilt from the claims to check that it has the
+// proofs file's root, and every deployment must be of that root; failed
+// deployments are left out.
+func Create(path string, opts Options) (*Manifest, error) {
+	if err := ValidateVersion(opts.Version); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	createdAt := opts.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	input, err := newArtifact(dir, opts.Input)
+	if err != nil {
+		return nil, fmt.Errorf("input: %w", err)
+	}
+	proofsArtifact, err := newArtifact(dir, opts.Proofs)
+	if err != nil {
+		return nil, fmt.Errorf("proofs: %w", err)
+	}
+
+	claims, err := data.LoadAirdropFromCSVWithOptions(opts.Input, opts.Layout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load claims: %w", err)
+	}
+	root, proofs, err := data.LoadProofsFile(opts.Proofs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load proofs: %w", err)
+	}
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, proofs.Metadata.Options())
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tree: %w", err)
+	}
+	if tree.GetRootHash() != root {
+		return nil, fmt.Errorf("%s builds root %s, but %s has root %s", opts.Input, tree.GetRootHash(), opts.Proofs, root)
+	}
+	if len(proofs.Proofs) != len(tree.Claims) {
+		return nil, fmt.Errorf("%s has %d proofs for %d claims", opts.Proofs, len(proofs.Proofs), len(tree.Claims))
+	}
+
+	manifest := &Manifest{
+		ManifestVersion: ManifestVersion,
+		Campaign:        opts.Campaign,
+		Version:         opts.Version,
+		Input:           input,
+		MerkleRoot:      root,
+		LeafEncoding:    proofs.Metadata,
+		Proofs:          proofsArtifact,
+		TotalAmount:     merkle.TotalAmount(tree.Claims).String(),
+		TotalClaims:     len(tree.Claims),
+		Generator:       generator(),
+		CreatedAt:       createdAt.UTC().Truncate(time.Second).Format(time.RFC3339),
+		BuiltAt:         builtAt(opts.Proofs),
+	}
+	if opts.Config != "" {
+		config, err := newArtifact(dir, opts.Config)
+		if err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+		manifest.Config = &config
+	}
+	if opts.Deployments != "" {
+		if err := manifest.addDeployments(opts.Deployments); err != nil {
+			return nil, err
+		}
+	}
+
+	err = fsutil.AtomicWriteFile(path, func(w io.Writer) error {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(manifest); err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// LoadManifest reads a manifest written by Create
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest %s: %w", path, err)
+	}
+	if manifest.ManifestVersion != ManifestVersion {
+		return nil, fmt.Errorf("manifest %s has version %d, expected %d", path, manifest.ManifestVersion, ManifestVersion)
+	}
+	return &manifest, nil
+}
+
+// addDeployments records the successful deployments in filename
+func (m *Manifest) addDeployments(filename string) error {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to read deployments: %w", err)
+	}
+	var results map[string]contract.DeployResult
+	if err := json.Unmarshal(content, &results); err != nil {
+		return fmt.Errorf("failed to decode deployments %s: %w", filename, err)
+	}
+	m.Deployments = make(map[string]Deployment, len(results))
+	for chain, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		if result.MerkleRoot != m.MerkleRoot {
+			return fmt.Errorf("deployment on %s is of root %s, not the release's %s", chain, result.MerkleRoot, m.MerkleRoot)
+		}
+		m.Deployments[chain] = Deployment{
+			ChainID:     result.ChainID,
+			Contract:    result.Contract.Hex(),
+			Token:       result.Token.Hex(),
+			TxHash:      result.TxHash.Hex(),
+			BlockNumber: result.BlockNumber,
+		}
+	}
+	if info, err := os.Stat(filename); err == nil && len(m.Deployments) > 0 {
+		m.DeployedAt = info.ModTime().UTC().Truncate(time.Second).Format(time.RFC3339)
+	}
+	return nil
+}
+
+// newArtifact hashes the file at path and records it relative to dir
+func newArtifact(dir, path string) (Artifact, error) {
+	if path == "" {
+		return Artifact{}, fmt.Errorf("no file given")
+	}
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	rel := path
+	if abs, err := filepath.Abs(path); err == nil {
+		if absDir, err := filepath.Abs(dir); err == nil {
+			if r, err := filepath.Rel(absDir, abs); err == nil {
+				rel = r
+			}
+		}
+	}
+	return Artifact{Path: filepath.ToSlash(rel), SHA256: sum}, nil
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		return "", fmt.Errorf("%s is a directory; releases record single files", path)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// builtAt returns the generatedAt of a JSON proofs file, empty when it
+// records none
+func builtAt(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var file struct {
+		GeneratedAt int64 `json:"generatedAt"`
+	}
+	if json.NewDecoder(f).Decode(&file) != nil || file.GeneratedAt == 0 {
+		return ""
+	}
+	return time.Unix(file.GeneratedAt, 0).UTC().Format(time.RFC3339)
+}
+
+// generator describes the running binary
+func generator() Generator {
+	gen := Generator{Version: Version}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return gen
+	}
+	gen.GoVersion = info.GoVersion
+	if gen.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		gen.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			gen.Revision = setting.Value
+		case "vcs.modified":
+			gen.Modified = setting.Value == "true"
+		}
+	}
+	return gen
+}
@@ -0,0 +1,155 @@
+package release
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"sort"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultSampleSize is the number of proofs VerifyManifest re-verifies
+const DefaultSampleSize = 32
+
+// ErrChecksumMismatch is matched by the ArtifactError of a file that
+// changed since its manifest was written
+var ErrChecksumMismatch = errors.New("SHA-256 does not match the manifest")
+
+// ArtifactError is a file of a release failing verification
+type ArtifactError struct {
+	Artifact string // input, proofs or config
+	Path     string // As the manifest records it
+	Err      error
+}
+
+func (e *ArtifactError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Artifact, e.Path, e.Err)
+}
+
+func (e *ArtifactError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyManifest checks the release described by the manifest at path:
+// every file's SHA-256, the proofs file's root, encoding, claim count and
+// total, and DefaultSampleSize of its proofs against the manifest's root.
+// Each failing file is reported as an *ArtifactError, joined with errors.Join.
+func VerifyManifest(path string) error {
+	return VerifyManifestSample(path, DefaultSampleSize)
+}
+
+// VerifyManifestSample is VerifyManifest re-verifying sample proofs, spread
+// evenly over the addresses, or every proof when sample is zero
+func VerifyManifestSample(path string, sample int) error {
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		return err
+	}
+	if err := ValidateVersion(manifest.Version); err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	resolve := func(artifact Artifact) string {
+		p := filepath.FromSlash(artifact.Path)
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	var errs []error
+	check := func(name string, artifact Artifact) bool {
+		sum, err := fileSHA256(resolve(artifact))
+		switch {
+		case err != nil:
+			errs = append(errs, &ArtifactError{Artifact: name, Path: artifact.Path, Err: err})
+		case sum != artifact.SHA256:
+			errs = append(errs, &ArtifactError{Artifact: name, Path: artifact.Path, Err: fmt.Errorf("%w: manifest has %s, file has %s", ErrChecksumMismatch, artifact.SHA256, sum)})
+		default:
+			return true
+		}
+		return false
+	}
+	check("input", manifest.Input)
+	if manifest.Config != nil {
+		check("config", *manifest.Config)
+	}
+	if check("proofs", manifest.Proofs) {
+		if err := manifest.verifyProofs(resolve(manifest.Proofs), sample); err != nil {
+			errs = append(errs, &ArtifactError{Artifact: "proofs", Path: manifest.Proofs.Path, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyProofs checks the proofs file at path against the manifest's
+// description of it
+func (m *Manifest) verifyProofs(path string, sample int) error {
+	root, proofs, err := data.LoadProofsFile(path)
+	if err != nil {
+		return err
+	}
+	if root != m.MerkleRoot {
+		return fmt.Errorf("root is %s, manifest has %s", root, m.MerkleRoot)
+	}
+	if proofs.Metadata != m.LeafEncoding {
+		return fmt.Errorf("leaf encoding is %+v, manifest has %+v", proofs.Metadata, m.LeafEncoding)
+	}
+	if len(proofs.Proofs) != m.TotalClaims {
+		return fmt.Errorf("has %d proofs, manifest has %d claims", len(proofs.Proofs), m.TotalClaims)
+	}
+	total := new(big.Int)
+	for address, proof := range proofs.Proofs {
+		amount, ok := new(big.Int).SetString(proof.Amount, 10)
+		if !ok {
+			return fmt.Errorf("%s: invalid amount %q", address, proof.Amount)
+		}
+		total.Add(total, amount)
+	}
+	if total.String() != m.TotalAmount {
+		return fmt.Errorf("amounts add up to %s, manifest has %s", total, m.TotalAmount)
+	}
+
+	rootBytes, err := merkle.DecodeHash(m.MerkleRoot)
+	if err != nil {
+		return fmt.Errorf("invalid manifest root: %w", err)
+	}
+	opts := m.LeafEncoding.Options()
+	for _, address := range sampleAddresses(proofs.Proofs, sample) {
+		proof := proofs.Proofs[address]
+		amount, _ := new(big.Int).SetString(proof.Amount, 10)
+		claim := merkle.AirdropClaim{Address: common.HexToAddress(address), Amount: amount, Index: proof.Index}
+		valid, err := merkle.VerifyMerkleProof(rootBytes, claim, proof, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+		if !valid {
+			return fmt.Errorf("proof of %s does not verify against root %s", address, m.MerkleRoot)
+		}
+	}
+	return nil
+}
+
+// sampleAddresses picks n of proofs' addresses spread evenly in sorted
+// order, so a sample is the same on every run; all of them when n is zero
+// or at least their number
+func sampleAddresses(proofs map[string]*merkle.MerkleProof, n int) []string {
+	addresses := make([]string, 0, len(proofs))
+	for address := range proofs {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	if n <= 0 || n >= len(addresses) {
+		return addresses
+	}
+	sample := make([]string, n)
+	for i := range sample {
+		sample[i] = addresses[i*len(addresses)/n]
+	}
+	return sample
+}
@@ -0,0 +1,226 @@
+// Package snapshot builds airdrop claims from on-chain token balances.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// transferTopic is the ERC-20 Transfer(address,address,uint256) event ID
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Client is the chain access a snapshot needs. *ethclient.Client and
+// simulated backends satisfy it.
+type Client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Options controls how a snapshot scans the chain and sizes claims
+type Options struct {
+	FromBlock  uint64        // First block scanned, e.g. the token's deployment block
+	BlockRange uint64        // Blocks per log query
+	MaxRetries int           // Retries for a failed log query
+	RetryDelay time.Duration // Wait before the first retry, growing linearly
+
+	// TotalAllocation is split between holders in proportion to their
+	// balances. Claims equal the balances when nil.
+	TotalAllocation *big.Int
+}
+
+// DefaultOptions returns options that scan from genesis in ranges most RPC
+// providers accept
+func DefaultOptions() Options {
+	return Options{
+		BlockRange: 2000,
+		MaxRetries: 3,
+		RetryDelay: time.Second,
+	}
+}
+
+// TakeERC20Snapshot returns a claim per holder of token at blockNumber with a
+// balance of at least minBalance, scanning with DefaultOptions
+func TakeERC20Snapshot(ctx context.Context, client Client, token common.Address, blockNumber uint64, minBalance *big.Int) ([]merkle.AirdropClaim, error) {
+	return TakeERC20SnapshotWithOptions(ctx, client, token, blockNumber, minBalance, DefaultOptions())
+}
+
+// TakeERC20SnapshotWithOptions is TakeERC20Snapshot with custom options.
+// Claims are ordered by address and indexed from zero.
+func TakeERC20SnapshotWithOptions(ctx context.Context, client Client, token common.Address, blockNumber uint64, minBalance *big.Int, opts Options) ([]merkle.AirdropClaim, error) {
+	if opts.BlockRange == 0 {
+		return nil, fmt.Errorf("block range must be positive")
+	}
+	if opts.FromBlock > blockNumber {
+		return nil, fmt.Errorf("from block %d is after snapshot block %d", opts.FromBlock, blockNumber)
+	}
+	if opts.TotalAllocation != nil && opts.TotalAllocation.Sign() <= 0 {
+		return nil, fmt.Errorf("total allocation must be positive")
+	}
+
+	head, err := client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block number: %w", err)
+	}
+	if blockNumber > head {
+		return nil, fmt.Errorf("snapshot block %d is beyond the chain head %d", blockNumber, head)
+	}
+
+	balances, err := scanBalances(ctx, client, token, blockNumber, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := filterHolders(balances, minBalance)
+	if len(holders) == 0 {
+		return nil, fmt.Errorf("no holders with a balance of at least %v at block %d", minBalance, blockNumber)
+	}
+
+	amounts := make([]*big.Int, len(holders))
+	for i, holder := range holders {
+		amounts[i] = balances[holder]
+	}
+	if opts.TotalAllocation != nil {
+		amounts = allocate(amounts, opts.TotalAllocation)
+	}
+
+	claims := make([]merkle.AirdropClaim, 0, len(holders))
+	for i, holder := range holders {
+		if amounts[i].Sign() == 0 {
+			continue // Share rounded down to nothing
+		}
+		claims = append(claims, merkle.AirdropClaim{
+			Address: holder,
+			Amount:  amounts[i],
+			Index:   uint32(len(claims)),
+		})
+	}
+	return claims, nil
+}
+
+// scanBalances replays token's Transfer logs up to blockNumber
+func scanBalances(ctx context.Context, client Client, token common.Address, blockNumber uint64, opts Options) (map[common.Address]*big.Int, error) {
+	balances := make(map[common.Address]*big.Int)
+	credit := func(account common.Address, delta *big.Int) {
+		if account == (common.Address{}) {
+			return // Mints and burns
+		}
+		if balances[account] == nil {
+			balances[account] = new(big.Int)
+		}
+		balances[account].Add(balances[account], delta)
+	}
+
+	for from := opts.FromBlock; from <= blockNumber; from += opts.BlockRange {
+		to := from + opts.BlockRange - 1
+		if to > blockNumber || to < from {
+			to = blockNumber
+		}
+
+		logs, err := filterLogsWithRetry(ctx, client, ethereum.FilterQuery{
+			FromBlock: new(big.Int).SetUint64(from),
+			ToBlock:   new(big.Int).SetUint64(to),
+			Addresses: []common.Address{token},
+			Topics:    [][]common.Hash{{transferTopic}},
+		}, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch logs for blocks %d-%d: %w", from, to, err)
+		}
+
+		for _, entry := range logs {
+			if entry.Removed {
+				continue
+			}
+			// ERC-721 Transfer shares the signature but indexes the token ID
+			if len(entry.Topics) != 3 || len(entry.Data) != 32 {
+				return nil, fmt.Errorf("log %d in block %d is not an ERC-20 Transfer", entry.Index, entry.BlockNumber)
+			}
+
+			value := new(big.Int).SetBytes(entry.Data)
+			credit(common.BytesToAddress(entry.Topics[1].Bytes()), new(big.Int).Neg(value))
+			credit(common.BytesToAddress(entry.Topics[2].Bytes()), value)
+		}
+
+		if to == blockNumber {
+			break
+		}
+	}
+
+	for account, balance := range balances {
+		if balance.Sign() < 0 {
+			return nil, fmt.Errorf("negative balance for %s; scan from the token's deployment block", account.Hex())
+		}
+	}
+	return balances, nil
+}
+
+// filterLogsWithRetry runs q, retrying failures up to opts.MaxRetries times
+func filterLogsWithRetry(ctx context.Context, client Client, q ethereum.FilterQuery, opts Options) ([]types.Log, error) {
+	var lastErr error
+	for attempt := 0; attempt <= opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(attempt) * opts.RetryDelay):
+			}
+		}
+
+		logs, err := client.FilterLogs(ctx, q)
+		if err == nil {
+			return logs, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("after %d attempts: %w", opts.MaxRetries+1, lastErr)
+}
+
+// filterHolders returns the accounts holding at least minBalance (and more
+// than zero), ordered by address
+func filterHolders(balances map[common.Address]*big.Int, minBalance *big.Int) []common.Address {
+	var holders []common.Address
+	for account, balance := range balances {
+		if balance.Sign() <= 0 || (minBalance != nil && balance.Cmp(minBalance) < 0) {
+			continue
+		}
+		holders = append(holders, account)
+	}
+	sort.Slice(holders, func(i, j int) bool {
+		return bytes.Compare(holders[i].Bytes(), holders[j].Bytes()) < 0
+	})
+	return holders
+}
+
+// allocate splits total in proportion to balances, rounding down. The
+// rounding remainder goes to the largest balance, the first one on ties, so
+// the shares always add up to total.
+func allocate(balances []*big.Int, total *big.Int) []*big.Int {
+	sum := new(big.Int)
+	largest := 0
+	for i, balance := range balances {
+		sum.Add(sum, balance)
+		if balance.Cmp(balances[largest]) > 0 {
+			largest = i
+		}
+	}
+
+	shares := make([]*big.Int, len(balances))
+	allocated := new(big.Int)
+	for i, balance := range balances {
+		shares[i] = new(big.Int).Mul(total, balance)
+		shares[i].Quo(shares[i], sum)
+		allocated.Add(allocated, shares[i])
+	}
+	shares[largest].Add(shares[largest], new(big.Int).Sub(total, allocated))
+	return shares
+}
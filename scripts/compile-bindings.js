@@ -0,0 +1,52 @@
+// Compiles the contracts used by the Go packages and writes their ABI and
+// bytecode next to the Go code that embeds them.
+//
+//   node scripts/compile-bindings.js
+const fs = require("fs");
+const path = require("path");
+const solc = require("solc");
+
+const root = path.join(__dirname, "..");
+
+const targets = [
+    { source: "contracts/MerkleDistributor.sol", name: "MerkleDistributor", out: "pkg/contract" },
+    { source: "contracts/test/TestToken.sol", name: "TestToken", out: "pkg/e2e" },
+];
+
+function findImports(importPath) {
+    for (const base of [root, path.join(root, "node_modules")]) {
+        const file = path.join(base, importPath);
+        if (fs.existsSync(file)) {
+            return { contents: fs.readFileSync(file, "utf8") };
+        }
+    }
+    return { error: `File not found: ${importPath}` };
+}
+
+const input = {
+    language: "Solidity",
+    sources: {},
+    settings: {
+        optimizer: { enabled: true, runs: 200 },
+        evmVersion: "paris",
+        outputSelection: { "*": { "*": ["abi", "evm.bytecode.object"] } },
+    },
+};
+for (const t of targets) {
+    input.sources[t.source] = { content: fs.readFileSync(path.join(root, t.source), "utf8") };
+}
+
+const output = JSON.parse(solc.compile(JSON.stringify(input), { import: findImports }));
+const errors = (output.errors || []).filter((e) => e.severity === "error");
+if (errors.length > 0) {
+    errors.forEach((e) => console.error(e.formattedMessage));
+    process.exit(1);
+}
+
+for (const t of targets) {
+    const contract = output.contracts[t.source][t.name];
+    const outDir = path.join(root, t.out);
+    fs.writeFileSync(path.join(outDir, `${t.name}.abi`), JSON.stringify(contract.abi) + "\n");
+    fs.writeFileSync(path.join(outDir, `${t.name}.bin`), contract.evm.bytecode.object + "\n");
+    console.log(`Wrote ${t.out}/${t.name}.abi and ${t.name}.bin`);
+}
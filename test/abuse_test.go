@@ -0,0 +1,229 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+)
+
+func TestAbuseDetection(t *testing.T) {
+	tree, proofs := buildProofSet(t, 20)
+	claimant := "0x0000000000000000000000000000000000000007"
+	const scanner, normal = "203.0.113.5", "198.51.100.7"
+
+	newHandler := func(cfg api.AbuseConfig) (http.Handler, *bytes.Buffer) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		return api.MustNewAPIServer(tree, proofs.Proofs, api.WithAbuseDetection(cfg), api.WithLogger(logger), api.WithAdminTokens([]string{"admin-secret"})).SetupRoutes(), &logs
+	}
+	get := func(handler http.Handler, ip, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = ip + ":40000"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	// enumerate looks up count consecutive addresses outside the airdrop
+	enumerate := func(handler http.Handler, count int) []int {
+		statuses := make([]int, count)
+		for i := range statuses {
+			statuses[i] = get(handler, scanner, fmt.Sprintf("/api/proof/0x00000000000000000000000000000000dead%04x", i)).Code
+		}
+		return statuses
+	}
+
+	t.Run("Reject", func(t *testing.T) {
+		handler, logs := newHandler(api.AbuseConfig{Window: time.Minute, MaxNotFound: 5, Action: api.AbuseReject})
+		statuses := enumerate(handler, 8)
+		for i, status := range statuses {
+			expected := http.StatusNotFound
+			if i > 5 {
+				expected = http.StatusTooManyRequests
+			}
+			if status != expected {
+				t.Errorf("Lookup %d: expected %d, got %d", i, expected, status)
+			}
+		}
+		if w := get(handler, scanner, "/api/proof/"+claimant); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+			t.Errorf("Expected the scanner to stay throttled, got %d", w.Code)
+		}
+		if w := get(handler, normal, "/api/proof/"+claimant); w.Code != http.StatusOK {
+			t.Errorf("Expected another client to be unaffected, got %d", w.Code)
+		}
+
+		flagged := false
+		for _, line := range logLines(t, logs) {
+			if line["msg"] == "sequential address probe" && line["ip"] == scanner && line["request_id"] != "" {
+				flagged = true
+			}
+		}
+		if !flagged {
+			t.Error("Expected the enumeration to be logged with its request ID")
+		}
+	})
+
+	t.Run("Delay", func(t *testing.T) {
+		handler, _ := newHandler(api.AbuseConfig{Window: time.Minute, MaxNotFound: 3, Delay: 50 * time.Millisecond, Jitter: 10 * time.Millisecond})
+		enumerate(handler, 4)
+
+		start := time.Now()
+		if w := get(handler, scanner, "/api/proof/"+claimant); w.Code != http.StatusOK {
+			t.Errorf("Expected a delayed answer, got %d", w.Code)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("Expected at least 50ms of delay, answered in %v", elapsed)
+		}
+	})
+
+	t.Run("ZeroAddress", func(t *testing.T) {
+		handler, logs := newHandler(api.AbuseConfig{Window: time.Minute, MaxNotFound: 5})
+		if w := get(handler, normal, "/api/proof/0x0000000000000000000000000000000000000000"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for the zero address, got %d", w.Code)
+		}
+		lines := logLines(t, logs)
+		if len(lines) == 0 || lines[0]["msg"] != "zero address lookup" || lines[0]["request_id"] == "" {
+			t.Errorf("Expected the zero address lookup to be logged, got %v", lines)
+		}
+	})
+
+	t.Run("OtherLookups", func(t *testing.T) {
+		// Eligibility and claim links name members as well as proofs do
+		for _, tc := range []struct {
+			path string
+			miss int
+		}{
+			{"/api/eligible/", http.StatusOK},
+			{"/api/link/", http.StatusNotFound},
+		} {
+			handler := api.MustNewAPIServer(tree, proofs.Proofs,
+				api.WithAbuseDetection(api.AbuseConfig{Window: time.Minute, MaxNotFound: 3, Action: api.AbuseReject}),
+				api.WithClaimLinkURL("https://claim.example"),
+			).SetupRoutes()
+			if w := get(handler, normal, tc.path+"0x0000000000000000000000000000000000000000"); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400 for the zero address, got %d", tc.path, w.Code)
+			}
+			for i := 0; i < 6; i++ {
+				expected := tc.miss
+				if i > 3 {
+					expected = http.StatusTooManyRequests
+				}
+				if w := get(handler, scanner, fmt.Sprintf("%s0x00000000000000000000000000000000dead%04x", tc.path, i)); w.Code != expected {
+					t.Errorf("%s lookup %d: expected %d, got %d", tc.path, i, expected, w.Code)
+				}
+			}
+			if w := get(handler, scanner, "/api/proof/"+claimant); w.Code != http.StatusTooManyRequests {
+				t.Errorf("%s: expected the scanner to be throttled on proofs too, got %d", tc.path, w.Code)
+			}
+		}
+	})
+
+	t.Run("TrustedProxy", func(t *testing.T) {
+		const ingress, other = "10.0.0.2", "10.1.0.2"
+		handler := api.MustNewAPIServer(tree, proofs.Proofs,
+			api.WithAbuseDetection(api.AbuseConfig{Window: time.Minute, MaxNotFound: 3, Action: api.AbuseReject}),
+			api.WithTrustedProxies([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")}),
+		).SetupRoutes()
+		forwarded := func(peer, forwardedFor, path string) int {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.RemoteAddr = peer + ":40000"
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w.Code
+		}
+		miss := func(i int) string { return fmt.Sprintf("/api/proof/0x00000000000000000000000000000000beef%04x", i) }
+
+		// Clients behind the ingress are throttled apart
+		for i := 0; i < 5; i++ {
+			forwarded(ingress, scanner, miss(i))
+		}
+		if code := forwarded(ingress, scanner, "/api/proof/"+claimant); code != http.StatusTooManyRequests {
+			t.Errorf("Expected the scanner behind the ingress to be throttled, got %d", code)
+		}
+		if code := forwarded(ingress, normal, "/api/proof/"+claimant); code != http.StatusOK {
+			t.Errorf("Expected another client behind the ingress to be served, got %d", code)
+		}
+		// Entries the client prepended are skipped, as are trusted hops
+		if code := forwarded(ingress, normal+", "+scanner+", 10.0.5.5", "/api/proof/"+claimant); code != http.StatusTooManyRequests {
+			t.Errorf("Expected the right-most untrusted entry to be used, got %d", code)
+		}
+
+		// An untrusted peer can't get around its throttle by forging the header
+		for i := 0; i < 5; i++ {
+			forwarded(other, scanner, miss(i))
+		}
+		if code := forwarded(other, normal, "/api/proof/"+claimant); code != http.StatusTooManyRequests {
+			t.Errorf("Expected a forged X-Forwarded-For to be ignored, got %d", code)
+		}
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		handler, _ := newHandler(api.AbuseConfig{Window: time.Minute, MaxNotFound: 3, Action: api.AbuseReject})
+		enumerate(handler, 5)
+		get(handler, normal, "/api/proof/"+claimant)
+
+		admin := func(method, path string) map[string]interface{} {
+			req := httptest.NewRequest(method, path, nil)
+			req.Header.Set("Authorization", "Bearer admin-secret")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s %s: expected 200, got %d", method, path, w.Code)
+			}
+			var body map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&body)
+			return body
+		}
+
+		var state api.AbuseResponse
+		encoded, _ := json.Marshal(admin(http.MethodGet, "/api/admin/abuse"))
+		json.Unmarshal(encoded, &state)
+		if len(state.Clients) != 2 || state.Clients[0].IP != scanner || !state.Clients[0].Throttled || state.Clients[0].NotFound != 4 || state.Clients[0].Limited != 1 || state.Clients[0].Probes != 1 {
+			t.Fatalf("Expected the scanner to be throttled, got %+v", state.Clients)
+		}
+		if state.Clients[1].IP != normal || state.Clients[1].Throttled {
+			t.Errorf("Expected the normal client to be unthrottled, got %+v", state.Clients[1])
+		}
+
+		if body := admin(http.MethodDelete, "/api/admin/abuse?ip="+scanner); body["reset"] != float64(1) {
+			t.Errorf("Expected one client reset, got %v", body)
+		}
+		if w := get(handler, scanner, "/api/proof/"+claimant); w.Code != http.StatusOK {
+			t.Errorf("Expected the reset client to be served, got %d", w.Code)
+		}
+		if body := admin(http.MethodDelete, "/api/admin/abuse"); body["reset"] != float64(2) {
+			t.Errorf("Expected both clients reset, got %v", body)
+		}
+	})
+}
+
+func TestAbuseConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.AbuseMaxNotFound = 10
+	if err := cfg.ValidateAll(); err != nil {
+		t.Fatalf("Expected the default abuse settings to be valid, got %v", err)
+	}
+	for name, mutate := range map[string]func(*config.ServerConfig){
+		"Window": func(s *config.ServerConfig) { s.AbuseWindow = 0 },
+		"Action": func(s *config.ServerConfig) { s.AbuseAction = "block" },
+		"Delay":  func(s *config.ServerConfig) { s.AbuseDelayMS = -1 },
+	} {
+		invalid := *cfg
+		mutate(&invalid.Server)
+		if err := invalid.ValidateAll(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := api.ParseAbuseAction(cfg.Server.AbuseAction); err != nil {
+		t.Errorf("Expected the default action to parse, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestAddressCase(t *testing.T) {
+	tree, proofs := buildSpreadProofSet(t, 64)
+	root := tree.GetRootHash()
+
+	t.Run("Parse", func(t *testing.T) {
+		for _, c := range []data.AddressCase{data.AddressChecksum, data.AddressLower} {
+			if parsed, err := data.ParseAddressCase(c.String()); err != nil || parsed != c {
+				t.Errorf("Expected %s to round-trip, got %v (%v)", c, parsed, err)
+			}
+		}
+		if _, err := data.ParseAddressCase("upper"); err == nil {
+			t.Error("Expected an unknown case to be rejected")
+		}
+	})
+
+	t.Run("CSVRoundTrip", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "claims.csv")
+		if err := data.SaveClaimsToCSV(tree.Claims, filename, data.AddressLower); err != nil {
+			t.Fatalf("Failed to save claims: %v", err)
+		}
+		content, _ := os.ReadFile(filename)
+		if string(content) != strings.ToLower(string(content)) {
+			t.Error("Expected every address in the CSV to be lowercase")
+		}
+
+		claims, err := data.LoadAirdropFromCSV(filename)
+		if err != nil {
+			t.Fatalf("Failed to load claims: %v", err)
+		}
+		reloaded, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		if reloaded.GetRootHash() != root {
+			t.Errorf("Expected root %s from the lowercase CSV, got %s", root, reloaded.GetRootHash())
+		}
+	})
+
+	t.Run("JSONRoundTrip", func(t *testing.T) {
+		lower := data.AddressLower.FormatProofs(proofs.Proofs)
+		for address := range lower {
+			if address != strings.ToLower(address) {
+				t.Fatalf("Expected lowercase keys, got %s", address)
+			}
+		}
+
+		body, _ := json.Marshal(map[string]interface{}{"merkleRoot": root, "proofs": lower})
+		_, loaded, err := data.LoadProofsJSON(strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("Failed to load proofs: %v", err)
+		}
+
+		// Keyed either way, the same claim's proof verifies identically
+		for _, claim := range tree.Claims {
+			proof, ok := loaded.Get(claim.Address)
+			if !ok {
+				t.Fatalf("Expected a proof for %s after loading", claim.Address.Hex())
+			}
+			fromLower, err := merkle.VerifyProof(tree.Root.Hash, claim, lower[data.AddressLower.Format(claim.Address)].Proof, tree.Options())
+			if err != nil {
+				t.Fatalf("Failed to verify: %v", err)
+			}
+			fromChecksum, _ := merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, tree.Options())
+			if !fromLower || !fromChecksum {
+				t.Errorf("Expected %s to verify from both keys, got %v and %v", claim.Address.Hex(), fromLower, fromChecksum)
+			}
+		}
+	})
+
+	t.Run("ShardedRoundTrip", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := data.ExportProofsSharded(proofs, root, dir, 4, data.AddressLower, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export shards: %v", err)
+		}
+		_, loaded, err := data.LoadShardedProofs(dir)
+		if err != nil {
+			t.Fatalf("Failed to load shards: %v", err)
+		}
+		for address, proof := range proofs.Proofs {
+			if got, ok := loaded.Proofs[address]; !ok || got.Index != proof.Index {
+				t.Errorf("Expected %s to load under its checksummed key", address)
+			}
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+		claim := tree.Claims[7]
+
+		get := func(path string) (int, map[string]interface{}) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			var body map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&body)
+			return w.Code, body
+		}
+
+		_, checksum := get("/api/proof/" + strings.ToLower(claim.Address.Hex()))
+		if checksum["address"] != claim.Address.Hex() {
+			t.Errorf("Expected a checksummed address by default, got %v", checksum["address"])
+		}
+		_, lower := get("/api/proof/" + claim.Address.Hex() + "?case=lower")
+		if lower["address"] != strings.ToLower(claim.Address.Hex()) {
+			t.Errorf("Expected a lowercase address, got %v", lower["address"])
+		}
+		if len(lower["proof"].([]interface{})) != len(checksum["proof"].([]interface{})) {
+			t.Error("Expected the same proof in either case")
+		}
+
+		if code, _ := get("/api/proof/" + claim.Address.Hex() + "?case=upper"); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for an unknown case, got %d", code)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAllocateProRata(t *testing.T) {
+	address := func(i int64) common.Address { return common.BigToAddress(big.NewInt(i)) }
+
+	t.Run("Remainders", func(t *testing.T) {
+		cases := []struct {
+			name    string
+			weights []int64 // For addresses 1, 2, ...
+			total   int64
+			want    []int64 // Claims in address order
+		}{
+			// Equal remainders: the lowest address gets the unit
+			{"Ties", []int64{1, 1, 1}, 100, []int64{34, 33, 33}},
+			// 1000 over 995: 497.48, 301.51 and 201.01, so the
+			// largest remainder is address 2's, not the largest weight's
+			{"LargestRemainder", []int64{495, 300, 200}, 1000, []int64{497, 302, 201}},
+			// Two units of dust for remainders 2/3, 2/3 and 2/3
+			{"SeveralUnits", []int64{1, 1, 1}, 2, []int64{1, 1}},
+			{"Exact", []int64{1, 3}, 8, []int64{2, 6}},
+		}
+		for _, tc := range cases {
+			weights := make(map[common.Address]*big.Int)
+			for i, w := range tc.weights {
+				weights[address(int64(i+1))] = big.NewInt(w)
+			}
+			claims, err := data.AllocateProRata(weights, big.NewInt(tc.total))
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			got := make([]int64, len(claims))
+			for i, claim := range claims {
+				got[i] = claim.Amount.Int64()
+				if claim.Index != uint32(i) {
+					t.Errorf("%s: expected claim %d to have index %d, got %d", tc.name, i, i, claim.Index)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+			}
+		}
+	})
+
+	t.Run("Properties", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(7))
+		for run := 0; run < 200; run++ {
+			n := 1 + rng.Intn(300)
+			weights := make(map[common.Address]*big.Int, n)
+			sum := new(big.Int)
+			for len(weights) < n {
+				weight := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(1+rng.Intn(200))))
+				if rng.Intn(10) == 0 {
+					weight.SetInt64(0)
+				}
+				holder := common.BigToAddress(big.NewInt(rng.Int63()))
+				if _, exists := weights[holder]; !exists {
+					weights[holder] = weight
+					sum.Add(sum, weight)
+				}
+			}
+			if sum.Sign() == 0 {
+				continue
+			}
+			// Totals both far above and below the number of addresses
+			total := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(1+rng.Intn(255))))
+			total.Add(total, big.NewInt(1))
+
+			claims, err := data.AllocateProRata(weights, total)
+			if err != nil {
+				t.Fatalf("Run %d: %v", run, err)
+			}
+			if got := merkle.TotalAmount(claims); got.Cmp(total) != 0 {
+				t.Fatalf("Run %d: expected claims to total %s, got %s", run, total, got)
+			}
+
+			// Each share is its exact share rounded down, or one more for
+			// remainders no smaller than any that went without
+			amounts := make(map[common.Address]*big.Int, len(claims))
+			for _, claim := range claims {
+				amounts[claim.Address] = claim.Amount
+			}
+			smallestRaised, largestKept := (*big.Int)(nil), (*big.Int)(nil)
+			for holder, weight := range weights {
+				floor, remainder := new(big.Int).QuoRem(new(big.Int).Mul(total, weight), sum, new(big.Int))
+				amount := amounts[holder]
+				if amount == nil {
+					amount = new(big.Int)
+				}
+				switch new(big.Int).Sub(amount, floor).Int64() {
+				case 0:
+					if largestKept == nil || remainder.Cmp(largestKept) > 0 {
+						largestKept = remainder
+					}
+				case 1:
+					if smallestRaised == nil || remainder.Cmp(smallestRaised) < 0 {
+						smallestRaised = remainder
+					}
+				default:
+					t.Fatalf("Run %d: %s got %s, expected %s or one more", run, holder.Hex(), amount, floor)
+				}
+			}
+			if smallestRaised != nil && largestKept != nil && smallestRaised.Cmp(largestKept) < 0 {
+				t.Fatalf("Run %d: a remainder of %s was raised over one of %s", run, smallestRaised, largestKept)
+			}
+
+			// Map iteration order differs between calls; the result doesn't
+			again, _ := data.AllocateProRata(weights, total)
+			if !reflect.DeepEqual(again, claims) {
+				t.Fatalf("Run %d: expected the same claims on every call", run)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		one := map[common.Address]*big.Int{address(1): big.NewInt(1)}
+		tooLarge := new(big.Int).Lsh(big.NewInt(1), 256)
+		for name, tc := range map[string]struct {
+			weights map[common.Address]*big.Int
+			total   *big.Int
+		}{
+			"NoWeights":      {nil, big.NewInt(1)},
+			"ZeroWeights":    {map[common.Address]*big.Int{address(1): big.NewInt(0)}, big.NewInt(1)},
+			"NegativeWeight": {map[common.Address]*big.Int{address(1): big.NewInt(-1)}, big.NewInt(1)},
+			"NilWeight":      {map[common.Address]*big.Int{address(1): nil}, big.NewInt(1)},
+			"ZeroTotal":      {one, big.NewInt(0)},
+			"NoTotal":        {one, nil},
+			"TotalTooLarge":  {one, tooLarge},
+		} {
+			if _, err := data.AllocateProRata(tc.weights, tc.total); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+
+	t.Run("WeightsCSV", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "weights.csv")
+		os.WriteFile(path, []byte("address,weight\n0x0000000000000000000000000000000000000001,3\n0x0000000000000000000000000000000000000002,0\n"), 0o644)
+		weights, err := data.LoadWeightsFromCSV(path)
+		if err != nil || len(weights) != 2 || weights[address(1)].Int64() != 3 {
+			t.Errorf("Expected two weights, got %v (%v)", weights, err)
+		}
+
+		os.WriteFile(path, []byte("address,weight\n0x0000000000000000000000000000000000000001,3\n0x0000000000000000000000000000000000000001,4\n"), 0o644)
+		if _, err := data.LoadWeightsFromCSV(path); err == nil {
+			t.Error("Expected a repeated address to be rejected")
+		}
+	})
+}
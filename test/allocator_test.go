@@ -0,0 +1,171 @@
+package test
+
+import (
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestIndexAllocator(t *testing.T) {
+	// build builds claims taking indices from the allocator saved at path,
+	// saving it again as the CLI does
+	build := func(t *testing.T, claims []merkle.AirdropClaim, path string) *merkle.MerkleTree {
+		t.Helper()
+		allocator, err := data.LoadIndexFile(path)
+		if err != nil {
+			t.Fatalf("Failed to load indices: %v", err)
+		}
+		opts := merkle.DefaultTreeOptions()
+		opts.Indices = allocator
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		if err := data.SaveIndexFile(path, allocator); err != nil {
+			t.Fatalf("Failed to save indices: %v", err)
+		}
+		return tree
+	}
+	indicesOf := func(tree *merkle.MerkleTree) map[common.Address]uint32 {
+		indices := make(map[common.Address]uint32)
+		for _, claim := range tree.Claims {
+			indices[claim.Address] = claim.Index
+		}
+		return indices
+	}
+
+	t.Run("SurvivesRebuilds", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "indices.json")
+		claims := data.GenerateRandomTestData(20, 1)
+		first := build(t, claims, path)
+		before := indicesOf(first)
+
+		// Drop three claims, add five and shuffle the rest
+		removed := claims[:3]
+		next := append(append([]merkle.AirdropClaim{}, claims[3:]...), data.GenerateRandomTestData(25, 2)[20:]...)
+		rand.New(rand.NewSource(3)).Shuffle(len(next), func(i, j int) { next[i], next[j] = next[j], next[i] })
+		second := build(t, next, path)
+
+		after := indicesOf(second)
+		for _, claim := range claims[3:] {
+			if after[claim.Address] != before[claim.Address] {
+				t.Errorf("Expected %s to keep index %d, got %d", claim.Address.Hex(), before[claim.Address], after[claim.Address])
+			}
+		}
+		freed := make(map[uint32]bool)
+		for _, claim := range removed {
+			freed[before[claim.Address]] = true
+		}
+		for address, index := range after {
+			if _, known := before[address]; !known && (index < 20 || freed[index]) {
+				t.Errorf("Expected new address %s to take a fresh index, got %d", address.Hex(), index)
+			}
+		}
+
+		// Proofs of the rebuilt tree verify with the stable indices
+		root := hexutil.MustDecode(second.GetRootHash())
+		for _, claim := range second.Claims {
+			proof, err := second.GenerateProof(claim.Address)
+			if err != nil {
+				t.Fatalf("Failed to generate proof: %v", err)
+			}
+			if proof.Index != after[claim.Address] {
+				t.Errorf("Expected the proof of %s at index %d, got %d", claim.Address.Hex(), after[claim.Address], proof.Index)
+			}
+			if valid, err := merkle.VerifyProof(root, claim, proof.Proof, second.Options()); !valid || err != nil {
+				t.Errorf("Expected the proof of %s to verify (%v)", claim.Address.Hex(), err)
+			}
+		}
+		if !second.Metadata().KeepIndices {
+			t.Error("Expected the metadata to record kept indices")
+		}
+
+		// A removed address coming back gets its old index
+		third := build(t, append(next, removed[0]), path)
+		if got := indicesOf(third)[removed[0].Address]; got != before[removed[0].Address] {
+			t.Errorf("Expected a returning address to get index %d back, got %d", before[removed[0].Address], got)
+		}
+	})
+
+	t.Run("OrderIndependent", func(t *testing.T) {
+		claims := data.GenerateRandomTestData(10, 4)
+		reversed := make([]merkle.AirdropClaim, len(claims))
+		for i, claim := range claims {
+			reversed[len(claims)-1-i] = claim
+		}
+		a, b := merkle.NewIndexAllocator(), merkle.NewIndexAllocator()
+		if n, err := a.Assign(claims); err != nil || n != 10 {
+			t.Fatalf("Expected 10 new addresses, got %d (%v)", n, err)
+		}
+		b.Assign(reversed)
+		for address, index := range a.Indices() {
+			if b.Indices()[address] != index {
+				t.Errorf("Expected %s to get index %d in any order, got %d", address.Hex(), index, b.Indices()[address])
+			}
+		}
+	})
+
+	t.Run("DuplicateAddress", func(t *testing.T) {
+		claims := data.GenerateTestData(3)
+		claims = append(claims, merkle.AirdropClaim{Address: claims[0].Address, Amount: big.NewInt(1)})
+		opts := merkle.DefaultTreeOptions()
+		opts.Indices = merkle.NewIndexAllocator()
+		if _, err := merkle.NewMerkleTreeWithOptions(claims, opts); err == nil {
+			t.Error("Expected a repeated address to fail the build")
+		}
+	})
+
+	t.Run("FailedBuild", func(t *testing.T) {
+		// A build that fails leaves the allocator as it was
+		allocator := merkle.NewIndexAllocator()
+		claims := data.GenerateTestData(10)
+		for name, modify := range map[string]func(*merkle.TreeOptions, *[]merkle.AirdropClaim){
+			"DuplicateAddress": func(_ *merkle.TreeOptions, c *[]merkle.AirdropClaim) {
+				*c = append(*c, merkle.AirdropClaim{Address: claims[0].Address, Amount: big.NewInt(1)})
+			},
+			"TooDeep":       func(o *merkle.TreeOptions, _ *[]merkle.AirdropClaim) { o.FixedDepth = 2 },
+			"OddLeafPolicy": func(o *merkle.TreeOptions, _ *[]merkle.AirdropClaim) { o.OddLeafPolicy = 99 },
+			"SortOrder":     func(o *merkle.TreeOptions, _ *[]merkle.AirdropClaim) { o.SortOrder = 99 },
+			"Workers":       func(o *merkle.TreeOptions, _ *[]merkle.AirdropClaim) { o.Workers = -1 },
+		} {
+			opts := merkle.DefaultTreeOptions()
+			opts.Indices = allocator
+			input := data.CloneClaims(claims)
+			modify(&opts, &input)
+			if _, err := merkle.NewMerkleTreeWithOptions(input, opts); err == nil {
+				t.Fatalf("%s: expected the build to fail", name)
+			}
+			if allocator.Len() != 0 || allocator.Next() != 0 {
+				t.Errorf("%s: expected no indices assigned, got %d up to %d", name, allocator.Len(), allocator.Next())
+			}
+		}
+	})
+
+	t.Run("File", func(t *testing.T) {
+		dir := t.TempDir()
+		if allocator, err := data.LoadIndexFile(filepath.Join(dir, "missing.json")); err != nil || allocator.Len() != 0 || allocator.Next() != 0 {
+			t.Errorf("Expected an empty allocator for a missing file, got %v", err)
+		}
+
+		for name, content := range map[string]string{
+			"Duplicate":  `{"next": 2, "indices": {"0x0000000000000000000000000000000000000001": 0, "0x0000000000000000000000000000000000000002": 0}}`,
+			"BeyondNext": `{"next": 1, "indices": {"0x0000000000000000000000000000000000000001": 1}}`,
+			"BadAddress": `{"next": 1, "indices": {"0x12": 0}}`,
+			"Malformed":  `{"next": `,
+		} {
+			path := filepath.Join(dir, name+".json")
+			os.WriteFile(path, []byte(content), 0o644)
+			if _, err := data.LoadIndexFile(path); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
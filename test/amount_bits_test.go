@@ -0,0 +1,133 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestMaxAmountBits(t *testing.T) {
+	limit := new(big.Int).Lsh(big.NewInt(1), 96) // 2^96
+	withAmounts := func(amounts ...*big.Int) []merkle.AirdropClaim {
+		claims := data.GenerateTestData(len(amounts) + 2)
+		for i, amount := range amounts {
+			claims[i].Amount = amount
+		}
+		return claims
+	}
+	opts := merkle.DefaultTreeOptions()
+	opts.MaxAmountBits = 96
+
+	t.Run("Boundary", func(t *testing.T) {
+		fits := withAmounts(new(big.Int).Sub(limit, big.NewInt(1)))
+		if _, err := merkle.NewMerkleTreeWithOptions(fits, opts); err != nil {
+			t.Errorf("Expected 2^96-1 to fit in 96 bits, got %v", err)
+		}
+		if err := data.ValidateClaimsDataWithOptions(fits, opts); err != nil {
+			t.Errorf("Expected 2^96-1 to validate, got %v", err)
+		}
+
+		over := withAmounts(limit, new(big.Int).Add(limit, limit))
+		_, err := merkle.NewMerkleTreeWithOptions(over, opts)
+		if err == nil {
+			t.Fatal("Expected 2^96 to be rejected")
+		}
+		for _, want := range []string{"2 claims", over[0].Address.Hex(), limit.String(), over[1].Address.Hex(), "uint96"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected the error to mention %s, got %v", want, err)
+			}
+		}
+		if err := data.ValidateClaimsDataWithOptions(over, opts); err == nil {
+			t.Error("Expected validation to reject 2^96")
+		}
+
+		generic := make([]merkle.GenericClaim, len(over))
+		for i, claim := range over {
+			generic[i] = claim.Generic()
+		}
+		if _, err := merkle.NewGenericMerkleTree(generic, opts); err == nil {
+			t.Error("Expected the generic tree to reject 2^96")
+		}
+	})
+
+	t.Run("ManyOffenders", func(t *testing.T) {
+		amounts := make([]*big.Int, 8)
+		for i := range amounts {
+			amounts[i] = limit
+		}
+		err := merkle.CheckAmountBits(withAmounts(amounts...), 96)
+		if err == nil || !strings.Contains(err.Error(), "8 claims") || !strings.Contains(err.Error(), "and 3 more") {
+			t.Errorf("Expected the first offenders and a count of the rest, got %v", err)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		huge := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+		claims := withAmounts(huge)
+		for _, opts := range []merkle.TreeOptions{merkle.DefaultTreeOptions(), {IncludeIndex: true, SortedPairs: true}} {
+			tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+			if err != nil {
+				t.Fatalf("Expected 2^256-1 to be accepted by default, got %v", err)
+			}
+			if tree.Metadata().MaxAmountBits != 0 {
+				t.Errorf("Expected default metadata to leave maxAmountBits out, got %+v", tree.Metadata())
+			}
+		}
+		if err := data.ValidateClaimsData(claims); err != nil {
+			t.Errorf("Expected default validation to accept 2^256-1, got %v", err)
+		}
+		encoded, _ := json.Marshal(merkle.DefaultMetadata())
+		if strings.Contains(string(encoded), "maxAmountBits") {
+			t.Errorf("Expected default metadata to be unchanged, got %s", encoded)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, bits := range []int{-8, 4, 95, 264} {
+			opts := merkle.DefaultTreeOptions()
+			opts.MaxAmountBits = bits
+			if _, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(3), opts); err == nil {
+				t.Errorf("%d bits: expected an error", bits)
+			}
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		metadata := tree.Metadata()
+		if metadata.MaxAmountBits != 96 || metadata.Options().MaxAmountBits != 96 {
+			t.Errorf("Expected metadata to record 96 bits, got %+v", metadata)
+		}
+		proofs, _ := tree.GenerateAllProofs()
+
+		stats := func(server *api.APIServer) float64 {
+			w := httptest.NewRecorder()
+			server.SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+			var body map[string]interface{}
+			json.NewDecoder(w.Body).Decode(&body)
+			bits, _ := body["amountBits"].(float64)
+			return bits
+		}
+		if bits := stats(api.MustNewAPIServer(tree, proofs)); bits != 96 {
+			t.Errorf("Expected /api/stats to report 96 bits, got %v", bits)
+		}
+		set := &merkle.ProofSet{Proofs: proofs, Metadata: metadata}
+		if bits := stats(mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), set))); bits != 96 {
+			t.Errorf("Expected /api/stats to report 96 bits from a proof set, got %v", bits)
+		}
+		defaultTree, _ := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), merkle.DefaultTreeOptions())
+		if bits := stats(mustServer(t)(api.NewLazyAPIServer(defaultTree, 1))); bits != 256 {
+			t.Errorf("Expected /api/stats to report 256 bits by default, got %v", bits)
+		}
+	})
+}
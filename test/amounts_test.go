@@ -0,0 +1,157 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAmountLimits(t *testing.T) {
+	tokens := func(n int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+	}
+	claims := []merkle.AirdropClaim{
+		{Address: common.HexToAddress("0x01"), Amount: tokens(400)},
+		{Address: common.HexToAddress("0x02"), Amount: tokens(350)},
+		{Address: common.HexToAddress("0x03"), Amount: tokens(250)},
+	}
+
+	if total := merkle.TotalAmount(claims); total.Cmp(tokens(1000)) != 0 {
+		t.Fatalf("Expected a total of %s, got %s", tokens(1000), total)
+	}
+	if total := merkle.TotalAmount(nil); total.Sign() != 0 {
+		t.Errorf("Expected an empty total of 0, got %s", total)
+	}
+
+	t.Run("ExactBoundary", func(t *testing.T) {
+		if err := merkle.CheckAmountLimits(claims, tokens(1000), tokens(400)); err != nil {
+			t.Errorf("Expected totals equal to the caps to pass, got %v", err)
+		}
+		if err := merkle.CheckAmountLimits(claims, nil, nil); err != nil {
+			t.Errorf("Expected no limits to pass, got %v", err)
+		}
+	})
+
+	t.Run("TotalOverflow", func(t *testing.T) {
+		// Every claim is under the per-claim cap; only the sum is too large
+		maxTotal := new(big.Int).Sub(tokens(1000), big.NewInt(1))
+		err := merkle.CheckAmountLimits(claims, maxTotal, tokens(500))
+		if err == nil {
+			t.Fatal("Expected the total to exceed the cap")
+		}
+		for _, want := range []string{tokens(1000).String(), maxTotal.String(), "by 1"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected the error to mention %q, got %v", want, err)
+			}
+		}
+	})
+
+	t.Run("PerClaim", func(t *testing.T) {
+		maxPerClaim := new(big.Int).Sub(tokens(350), big.NewInt(1))
+		err := merkle.CheckAmountLimits(claims, nil, maxPerClaim)
+		if err == nil {
+			t.Fatal("Expected claims to exceed the per-claim cap")
+		}
+		if !strings.Contains(err.Error(), "2 claims") || !strings.Contains(err.Error(), common.HexToAddress("0x01").Hex()) {
+			t.Errorf("Expected the error to count and name offending claims, got %v", err)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		tree, proofs := buildProofSet(t, 20)
+		want := merkle.TotalAmount(tree.Claims).String()
+
+		servers := map[string]http.Handler{
+			"Tree":   api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes(),
+			"Lazy":   mustServer(t)(api.NewLazyAPIServer(tree, 4)).SetupRoutes(),
+			"Proofs": mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), proofs)).SetupRoutes(),
+		}
+		for name, handler := range servers {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+
+			var stats struct {
+				TotalAmount string `json:"totalAmount"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+				t.Fatalf("%s: failed to decode stats: %v", name, err)
+			}
+			if stats.TotalAmount != want {
+				t.Errorf("%s: expected total amount %s, got %q", name, want, stats.TotalAmount)
+			}
+		}
+	})
+}
+
+func TestAmountRange(t *testing.T) {
+	maxUint256 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	cases := []struct {
+		name   string
+		amount *big.Int
+		want   string // Expected error; empty when the amount hashes
+	}{
+		{"Zero", new(big.Int), ""},
+		{"MaxUint256", maxUint256, ""},
+		{"MaxPlusOne", new(big.Int).Add(maxUint256, big.NewInt(1)), "exceeds 2^256-1"},
+		{"Negative", big.NewInt(-1), "must not be negative"},
+		{"Nil", nil, "missing"},
+	}
+
+	address := common.HexToAddress("0x742d35Cc6634C0532925a3b8D8c6632F2d50a9a6")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			hashers := map[string]func() ([]byte, error){
+				"HashLeaf":          func() ([]byte, error) { return merkle.HashLeaf(address, tc.amount, 0) },
+				"OptimizedHashLeaf": func() ([]byte, error) { return merkle.OptimizedHashLeaf(address, tc.amount, 0) },
+			}
+			for name, hash := range hashers {
+				leaf, err := hash()
+				if tc.want == "" && (err != nil || len(leaf) != 32) {
+					t.Errorf("%s: expected a leaf, got %v", name, err)
+				}
+				if tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+					t.Errorf("%s: expected an error mentioning %q, got %v", name, tc.want, err)
+				}
+			}
+
+			// Tree construction names the claim; verification rejects the
+			// amount instead of panicking
+			claims := []merkle.AirdropClaim{
+				{Address: common.HexToAddress("0x01"), Amount: big.NewInt(1)},
+				{Address: address, Amount: tc.amount},
+			}
+			_, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+			if tc.want == "" && err != nil {
+				t.Errorf("Expected the tree to build, got %v", err)
+			}
+			if tc.want != "" && (err == nil || !strings.Contains(err.Error(), "claim 1 ("+address.Hex()+")")) {
+				t.Errorf("Expected the error to name claim 1 and its address, got %v", err)
+			}
+
+			claim := merkle.AirdropClaim{Address: address, Amount: tc.amount}
+			valid, err := merkle.VerifyProof(make([]byte, 32), claim, nil, merkle.DefaultTreeOptions())
+			if valid || (tc.want != "") != (err != nil) {
+				t.Errorf("Expected verification to fail with error=%v, got %v %v", tc.want != "", valid, err)
+			}
+		})
+	}
+
+	// Zero hashes, but is still not a valid airdrop claim
+	claims := []merkle.AirdropClaim{{Address: address, Amount: new(big.Int)}}
+	if err := data.ValidateClaimsData(claims); err == nil {
+		t.Error("Expected ValidateClaimsData to reject a zero amount")
+	}
+	claims[0].Amount = nil
+	if err := data.ValidateClaimsData(claims); err == nil {
+		t.Error("Expected ValidateClaimsData to reject a missing amount")
+	}
+}
@@ -0,0 +1,400 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestAdminAuth(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	newRouter := func(tokens []string) *api.Router {
+		router := api.NewRouter(tokens)
+		router.Handle(api.Endpoint{Path: "/api/admin/ping", Methods: []string{http.MethodPost}, Admin: true, Handler: okHandler})
+		return router
+	}
+
+	request := func(router *api.Router, authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/ping", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	expectUnauthorized := func(t *testing.T, w *httptest.ResponseRecorder) {
+		t.Helper()
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status 401, got %d", w.Code)
+		}
+
+		var response api.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response.Success || response.Error.Code != api.CodeUnauthorized {
+			t.Errorf("Expected an %s error, got %+v", api.CodeUnauthorized, response)
+		}
+	}
+
+	router := newRouter([]string{"first-token", "second-token"})
+
+	t.Run("ValidToken", func(t *testing.T) {
+		for _, token := range []string{"first-token", "second-token"} {
+			if w := request(router, "Bearer "+token); w.Code != http.StatusOK {
+				t.Errorf("Expected status 200 for %s, got %d", token, w.Code)
+			}
+		}
+	})
+
+	t.Run("WrongToken", func(t *testing.T) {
+		expectUnauthorized(t, request(router, "Bearer wrong-token"))
+		expectUnauthorized(t, request(router, "first-token"))
+	})
+
+	t.Run("MissingHeader", func(t *testing.T) {
+		expectUnauthorized(t, request(router, ""))
+	})
+
+	t.Run("DisabledWithoutTokens", func(t *testing.T) {
+		for _, tokens := range [][]string{nil, {""}} {
+			disabled := newRouter(tokens)
+			if w := request(disabled, "Bearer anything"); w.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", w.Code)
+			}
+			if w := request(disabled, ""); w.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", w.Code)
+			}
+		}
+	})
+}
+
+func TestVerifyHonorsTreeEncoding(t *testing.T) {
+	opts := merkle.DefaultTreeOptions()
+	opts.IncludeIndex = false
+
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(20), opts)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateProofSet()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	verify := func(handler http.Handler, payload map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response
+	}
+
+	claim := tree.Claims[3]
+	proof := proofs.Proofs[claim.Address.Hex()]
+
+	// Both a tree-backed server and one booted from an export must pick up
+	// the index-free encoding
+	var exported bytes.Buffer
+	if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &exported); err != nil {
+		t.Fatalf("Failed to export proofs: %v", err)
+	}
+	loaded, root, err := data.LoadProofsBinary(&exported)
+	if err != nil {
+		t.Fatalf("Failed to load proofs: %v", err)
+	}
+	if loaded.Metadata.IncludeIndex {
+		t.Fatal("Expected exported metadata to record index-free encoding")
+	}
+
+	handlers := map[string]http.Handler{
+		"Tree":     api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes(),
+		"Exported": mustServer(t)(api.NewAPIServerFromProofs(fmt.Sprintf("0x%x", root), loaded)).SetupRoutes(),
+	}
+
+	for name, handler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			response := verify(handler, map[string]interface{}{
+				"address": claim.Address.Hex(),
+				"amount":  proof.Amount,
+				"proof":   proof.Proof,
+			})
+			if response["valid"] != true {
+				t.Error("Expected genuine proof to be valid")
+			}
+
+			response = verify(handler, map[string]interface{}{
+				"address": claim.Address.Hex(),
+				"amount":  "1",
+				"proof":   proof.Proof,
+			})
+			if response["valid"] != false {
+				t.Error("Expected proof with wrong amount to be invalid")
+			}
+		})
+	}
+}
+
+func TestEligibility(t *testing.T) {
+	tree, proofs := buildProofSet(t, 20)
+	member := tree.Claims[4].Address.Hex()
+	stranger := "0x000000000000000000000000000000000000dEaD"
+
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	checkEligible := func(t *testing.T, handler http.Handler, address string, want bool) {
+		t.Helper()
+		w := get(handler, "/api/eligible/"+address)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response["eligible"] != want {
+			t.Errorf("Expected eligible %v for %s, got %v", want, address, response["eligible"])
+		}
+		if len(response) != 1 {
+			t.Errorf("Expected only the eligible field, got %v", response)
+		}
+	}
+
+	handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+
+	t.Run("Eligible", func(t *testing.T) {
+		checkEligible(t, handler, member, true)
+		checkEligible(t, handler, strings.ToLower(member), true)
+	})
+
+	t.Run("Ineligible", func(t *testing.T) {
+		checkEligible(t, handler, stranger, false)
+
+		if w := get(handler, "/api/eligible/not-an-address"); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for invalid address, got %d", w.Code)
+		}
+	})
+
+	t.Run("ProofsDisabled", func(t *testing.T) {
+		restricted := api.MustNewAPIServer(tree, proofs.Proofs, api.WithProofsDisabled()).SetupRoutes()
+
+		for _, address := range []string{member, stranger} {
+			w := get(restricted, "/api/proof/"+address)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected status 403 for %s, got %d", address, w.Code)
+			}
+			if strings.Contains(w.Body.String(), proofs.Proofs[member].Amount) {
+				t.Error("Expected restricted response to omit the amount")
+			}
+		}
+
+		checkEligible(t, restricted, member, true)
+		checkEligible(t, restricted, stranger, false)
+	})
+}
+
+func TestLazyAPIServer(t *testing.T) {
+	tree, proofs := buildProofSet(t, 64)
+
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(api.RequestIDHeader, "lazy-test") // Keep error bodies comparable
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("MatchesPrecomputed", func(t *testing.T) {
+		precomputed := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+		lazy := mustServer(t)(api.NewLazyAPIServer(tree, 8)).SetupRoutes()
+
+		paths := []string{"/api/root", "/api/proof/0x000000000000000000000000000000000000dEaD"}
+		for _, i := range []int{0, 1, 31, 63, 1} {
+			address := tree.Claims[i].Address.Hex()
+			paths = append(paths, "/api/proof/"+address, "/api/proof/"+strings.ToLower(address), "/api/eligible/"+address)
+		}
+
+		for _, path := range paths {
+			want, got := get(precomputed, path), get(lazy, path)
+			if want.Code != got.Code {
+				t.Errorf("%s: expected status %d, got %d", path, want.Code, got.Code)
+			}
+			if !bytes.Equal(want.Body.Bytes(), got.Body.Bytes()) {
+				t.Errorf("%s: responses differ\nprecomputed: %s\nlazy: %s", path, want.Body, got.Body)
+			}
+		}
+	})
+
+	t.Run("CacheEviction", func(t *testing.T) {
+		handler := mustServer(t)(api.NewLazyAPIServer(tree, 2)).SetupRoutes()
+		a, b, c := tree.Claims[0].Address.Hex(), tree.Claims[1].Address.Hex(), tree.Claims[2].Address.Hex()
+
+		// a and b miss, a hits, c evicts b, so b misses again
+		for _, address := range []string{a, b, a, c, b} {
+			if w := get(handler, "/api/proof/"+address); w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200 for %s, got %d", address, w.Code)
+			}
+		}
+
+		var stats struct {
+			TotalProofs int            `json:"totalProofs"`
+			ProofCache  api.CacheStats `json:"proofCache"`
+		}
+		if err := json.NewDecoder(get(handler, "/api/stats").Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode stats: %v", err)
+		}
+
+		want := api.CacheStats{Size: 2, Capacity: 2, Hits: 1, Misses: 4}
+		if stats.ProofCache != want {
+			t.Errorf("Expected cache stats %+v, got %+v", want, stats.ProofCache)
+		}
+		if stats.TotalProofs != 64 {
+			t.Errorf("Expected 64 servable proofs, got %d", stats.TotalProofs)
+		}
+	})
+}
+
+type fakeClaimStatus map[uint32]bool
+
+func (f fakeClaimStatus) IsClaimed(index uint32) (bool, error) {
+	return f[index], nil
+}
+
+func TestProofSuggestions(t *testing.T) {
+	tree, proofs := buildSpreadProofSet(t, 200)
+	member := tree.Claims[7]
+	memberHex := strings.ToLower(member.Address.Hex())
+
+	// typo replaces one hex character of the member address in the middle
+	typo := func(pos int) string {
+		c := byte('0')
+		if memberHex[pos] == '0' {
+			c = '1'
+		}
+		return memberHex[:pos] + string(c) + memberHex[pos+1:]
+	}
+
+	type suggestionResponse struct {
+		Success     bool             `json:"success"`
+		Suggestions []api.Suggestion `json:"suggestions"`
+	}
+	get := func(t *testing.T, handler http.Handler, path string) (suggestionResponse, string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status 404, got %d", path, w.Code)
+		}
+
+		body := w.Body.String()
+		var response suggestionResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return response, body
+	}
+
+	servers := map[string]http.Handler{
+		"Tree":   api.MustNewAPIServer(tree, proofs.Proofs, api.WithSuggestions()).SetupRoutes(),
+		"Lazy":   mustServer(t)(api.NewLazyAPIServer(tree, 8, api.WithSuggestions())).SetupRoutes(),
+		"Proofs": mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), proofs, api.WithSuggestions())).SetupRoutes(),
+	}
+
+	for name, handler := range servers {
+		t.Run(name, func(t *testing.T) {
+			t.Run("NearMiss", func(t *testing.T) {
+				// One edit in the middle, two edits in different segments, and a
+				// deletion plus insertion that shifts the middle segment
+				shifted := memberHex[:7] + memberHex[8:37] + "0" + memberHex[37:]
+				for _, address := range []string{typo(20), typo(15)[:33] + typo(33)[33:], shifted} {
+					response, body := get(t, handler, "/api/proof/"+address+"?suggest=true")
+					if len(response.Suggestions) == 0 || response.Suggestions[0].Address != member.Address.Hex() {
+						t.Fatalf("Expected %s first for %s, got %+v", member.Address.Hex(), address, response.Suggestions)
+					}
+					if len(response.Suggestions) > 3 {
+						t.Errorf("Expected at most 3 suggestions, got %d", len(response.Suggestions))
+					}
+					if response.Suggestions[0].Claimed != nil {
+						t.Error("Expected no claimed state without a claim status")
+					}
+					if strings.Contains(body, proofs.Proofs[member.Address.Hex()].Proof[0]) || strings.Contains(body, member.Amount.String()) {
+						t.Error("Expected suggestions to omit proofs and amounts")
+					}
+				}
+			})
+
+			t.Run("SharedPrefix", func(t *testing.T) {
+				address := memberHex[:10] + strings.Repeat("0", 32)
+				response, _ := get(t, handler, "/api/proof/"+address+"?suggest=true")
+				if len(response.Suggestions) == 0 || response.Suggestions[0].Address != member.Address.Hex() {
+					t.Errorf("Expected %s for a shared prefix, got %+v", member.Address.Hex(), response.Suggestions)
+				}
+			})
+
+			t.Run("NoSuggestions", func(t *testing.T) {
+				response, body := get(t, handler, "/api/proof/0x000000000000000000000000000000000000dEaD?suggest=true")
+				if response.Success || response.Suggestions == nil || len(response.Suggestions) != 0 {
+					t.Errorf("Expected an empty suggestions list, got %s", body)
+				}
+			})
+
+			t.Run("NotRequested", func(t *testing.T) {
+				_, body := get(t, handler, "/api/proof/"+typo(20))
+				if strings.Contains(body, "suggestions") {
+					t.Errorf("Expected no suggestions without ?suggest=true, got %s", body)
+				}
+			})
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+		_, body := get(t, handler, "/api/proof/"+typo(20)+"?suggest=true")
+		if strings.Contains(body, "suggestions") {
+			t.Errorf("Expected no suggestions unless enabled, got %s", body)
+		}
+	})
+
+	t.Run("ClaimStatus", func(t *testing.T) {
+		status := fakeClaimStatus{member.Index: true}
+		handler := api.MustNewAPIServer(tree, proofs.Proofs, api.WithSuggestions(), api.WithClaimStatus(status)).SetupRoutes()
+
+		response, _ := get(t, handler, "/api/proof/"+typo(20)+"?suggest=true")
+		if len(response.Suggestions) == 0 {
+			t.Fatal("Expected a suggestion")
+		}
+		if claimed := response.Suggestions[0].Claimed; claimed == nil || !*claimed {
+			t.Errorf("Expected %s to be reported as claimed", response.Suggestions[0].Address)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestAppendClaims(t *testing.T) {
+	const token = "admin-secret"
+	newTree := func(t *testing.T) *merkle.MerkleTree {
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		return tree
+	}
+	// start serves tree, letting admins append to it
+	start := func(t *testing.T, tree *merkle.MerkleTree) *rebuild.Appender {
+		var appender *rebuild.Appender
+		serve := func(tree *merkle.MerkleTree) (http.Handler, error) {
+			proofs, err := tree.GenerateAllProofs()
+			if err != nil {
+				return nil, err
+			}
+			return api.MustNewAPIServer(tree, proofs, api.WithAdminTokens([]string{token}), api.WithClaimAppender(appender)).SetupRoutes(), nil
+		}
+		appender = rebuild.NewAppender(serve, nil)
+		if err := appender.Start(tree); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		return appender
+	}
+	do := func(handler http.Handler, method, path, body, token string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&response)
+		return w.Code, response
+	}
+	code := func(body map[string]interface{}) interface{} {
+		apiErr, _ := body["error"].(map[string]interface{})
+		return apiErr["code"]
+	}
+	added := []string{"0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2"}
+
+	t.Run("AddsClaims", func(t *testing.T) {
+		tree := newTree(t)
+		oldRoot := tree.GetRootHash()
+		appender := start(t, tree)
+
+		status, body := do(appender, http.MethodPost, "/api/admin/claims",
+			`{"claims":[{"address":"`+added[0]+`","amount":"500"},{"address":"`+added[1]+`","amount":"600"}]}`, token)
+		if status != http.StatusOK || body["previousRoot"] != oldRoot || body["version"] != float64(2) {
+			t.Fatalf("Expected version 2 replacing %s, got %d %v", oldRoot, status, body)
+		}
+		newRoot, _ := body["merkleRoot"].(string)
+		if newRoot == oldRoot {
+			t.Fatal("Expected a new root")
+		}
+		claims, _ := body["claims"].([]interface{})
+		if len(claims) != 2 {
+			t.Fatalf("Expected the proofs of both claims, got %v", body["claims"])
+		}
+
+		// The added claims take the next indices and are served from the new tree
+		for i, address := range added {
+			returned, _ := claims[i].(map[string]interface{})
+			status, served := do(appender, http.MethodGet, "/api/proof/"+address, "", "")
+			if status != http.StatusOK || served["merkleRoot"] != newRoot || served["index"] != float64(10+i) {
+				t.Fatalf("Expected %s at index %d in the new tree, got %d %v", address, 10+i, status, served)
+			}
+			if strings.Join(toStrings(served["proof"]), ",") != strings.Join(toStrings(returned["proof"]), ",") {
+				t.Errorf("Expected the returned proof of %s to be the served one", address)
+			}
+			amount, _ := new(big.Int).SetString(served["amount"].(string), 10)
+			claim := merkle.AirdropClaim{Address: common.HexToAddress(address), Amount: amount, Index: uint32(10 + i)}
+			if valid, err := merkle.VerifyProof(hexutil.MustDecode(newRoot), claim, toStrings(served["proof"]), merkle.DefaultTreeOptions()); !valid || err != nil {
+				t.Errorf("Expected the proof of %s to verify against the new root (%v)", address, err)
+			}
+		}
+
+		// Existing claims keep their indices
+		for _, claim := range tree.Claims {
+			_, served := do(appender, http.MethodGet, "/api/proof/"+claim.Address.Hex(), "", "")
+			if served["index"] != float64(claim.Index) {
+				t.Errorf("Expected %s to keep index %d, got %v", claim.Address.Hex(), claim.Index, served["index"])
+			}
+		}
+
+		// The old root stays in the version history
+		status, body = do(appender, http.MethodGet, "/api/admin/versions", "", token)
+		versions, _ := body["versions"].([]interface{})
+		if status != http.StatusOK || len(versions) != 2 {
+			t.Fatalf("Expected two versions, got %d %v", status, body)
+		}
+		first, _ := versions[0].(map[string]interface{})
+		second, _ := versions[1].(map[string]interface{})
+		if first["merkleRoot"] != oldRoot || first["totalClaims"] != float64(10) || second["merkleRoot"] != newRoot || second["added"] != float64(2) {
+			t.Errorf("Expected the old then the new root, got %v", versions)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		tree := newTree(t)
+		appender := start(t, tree)
+		existing := tree.Claims[3]
+
+		status, body := do(appender, http.MethodPost, "/api/admin/claims",
+			`{"claims":[{"address":"`+added[0]+`","amount":"500"},{"address":"`+existing.Address.Hex()+`","amount":"1"}]}`, token)
+		listed, _ := body["existing"].([]interface{})
+		if status != http.StatusConflict || code(body) != api.CodeClaimExists || len(listed) != 1 {
+			t.Fatalf("Expected 409 listing the existing claim, got %d %v", status, body)
+		}
+		if claim, _ := listed[0].(map[string]interface{}); claim["address"] != existing.Address.Hex() || claim["amount"] != existing.Amount.String() {
+			t.Errorf("Expected the existing amount %s, got %v", existing.Amount, claim)
+		}
+
+		// Nothing was added, not even the new address
+		if status, _ := do(appender, http.MethodGet, "/api/proof/"+added[0], "", ""); status != http.StatusNotFound {
+			t.Errorf("Expected no claims to be added on conflict, got %d", status)
+		}
+		if _, body := do(appender, http.MethodGet, "/api/root", "", ""); body["merkleRoot"] != tree.GetRootHash() {
+			t.Errorf("Expected the old root to be served, got %v", body["merkleRoot"])
+		}
+		if versions := appender.TreeVersions(); len(versions) != 1 {
+			t.Errorf("Expected no new version, got %v", versions)
+		}
+	})
+
+	t.Run("Rejects", func(t *testing.T) {
+		appender := start(t, newTree(t))
+		for _, tc := range []struct {
+			name, claims, code string
+		}{
+			{"Empty", ``, api.CodeInvalidRequest},
+			{"BadAddress", `{"address":"0x12","amount":"1"}`, api.CodeInvalidAddress},
+			{"ZeroAddress", `{"address":"0x0000000000000000000000000000000000000000","amount":"1"}`, api.CodeInvalidAddress},
+			{"Duplicate", `{"address":"` + added[0] + `","amount":"1"},{"address":"` + strings.ToUpper(added[0][2:]) + `","amount":"2"}`, api.CodeDuplicateAddress},
+			{"ZeroAmount", `{"address":"` + added[0] + `","amount":"0"}`, api.CodeInvalidAmount},
+			{"NegativeAmount", `{"address":"` + added[0] + `","amount":"-5"}`, api.CodeInvalidAmount},
+			{"WideAmount", `{"address":"` + added[0] + `","amount":"1` + strings.Repeat("0", 80) + `"}`, api.CodeInvalidAmount},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				status, body := do(appender, http.MethodPost, "/api/admin/claims", `{"claims":[`+tc.claims+`]}`, token)
+				if status != http.StatusBadRequest || code(body) != tc.code {
+					t.Errorf("Expected 400 %s, got %d %v", tc.code, status, body)
+				}
+			})
+		}
+		if versions := appender.TreeVersions(); len(versions) != 1 {
+			t.Errorf("Expected rejected requests to add nothing, got %v", versions)
+		}
+
+		if status, _ := do(appender, http.MethodPost, "/api/admin/claims", `{"claims":[{"address":"`+added[0]+`","amount":"1"}]}`, ""); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without the admin token, got %d", status)
+		}
+	})
+
+	t.Run("TreeAddClaims", func(t *testing.T) {
+		tree := newTree(t)
+		grown, err := tree.AddClaims([]merkle.AirdropClaim{{Address: common.HexToAddress(added[0]), Amount: big.NewInt(5)}})
+		if err != nil {
+			t.Fatalf("Failed to add: %v", err)
+		}
+		if len(tree.Claims) != 10 || len(grown.Claims) != 11 {
+			t.Errorf("Expected the old tree to be unchanged, got %d and %d claims", len(tree.Claims), len(grown.Claims))
+		}
+		if claim, _ := grown.FindClaim(common.HexToAddress(added[0])); claim.Index != 10 {
+			t.Errorf("Expected the next index, got %d", claim.Index)
+		}
+
+		_, err = grown.AddClaims([]merkle.AirdropClaim{{Address: tree.Claims[0].Address, Amount: big.NewInt(5)}})
+		var existing *merkle.ExistingClaimError
+		if !errors.As(err, &existing) || existing.Claims[0].Amount.Cmp(tree.Claims[0].Amount) != 0 {
+			t.Errorf("Expected an ExistingClaimError with the tree's amount, got %v", err)
+		}
+		if _, err := tree.AddClaims(nil); !errors.Is(err, merkle.ErrEmptyClaims) {
+			t.Errorf("Expected ErrEmptyClaims, got %v", err)
+		}
+	})
+}
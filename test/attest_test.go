@@ -0,0 +1,123 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+func TestRootAttestation(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	meta := contract.CampaignMeta{
+		Name:        "Season 1",
+		InputSHA256: "0x" + strings.Repeat("AB", 32),
+		Timestamp:   time.Date(2026, 1, 2, 3, 4, 5, 999, time.FixedZone("UTC+1", 3600)),
+	}
+
+	t.Run("StableDigest", func(t *testing.T) {
+		att, err := contract.BuildRootAttestation(tree, meta)
+		if err != nil {
+			t.Fatalf("Failed to build attestation: %v", err)
+		}
+		again, _ := contract.BuildRootAttestation(tree, meta)
+		if att.Digest != again.Digest || !bytes.Equal(att.Encoded, again.Encoded) {
+			t.Fatal("Expected the same attestation for the same campaign")
+		}
+		if att.Digest != crypto.Keccak256Hash(att.Encoded) {
+			t.Error("Expected the digest to be keccak256 of the payload")
+		}
+
+		var payload map[string]interface{}
+		json.Unmarshal(att.Encoded, &payload)
+		if payload["merkleRoot"] != tree.GetRootHash() || payload["totalClaims"] != float64(10) ||
+			payload["totalAmount"] != merkle.TotalAmount(tree.Claims).String() ||
+			payload["inputSha256"] != strings.Repeat("ab", 32) || payload["timestamp"] != "2026-01-02T02:04:05Z" {
+			t.Errorf("Unexpected payload %s", att.Encoded)
+		}
+
+		// Another root gives another digest
+		other, _ := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(11), merkle.DefaultTreeOptions())
+		if changed, _ := contract.BuildRootAttestation(other, meta); changed.Digest == att.Digest {
+			t.Error("Expected a different digest for a different tree")
+		}
+
+		if _, err := contract.BuildRootAttestation(tree, contract.CampaignMeta{InputSHA256: "abcd"}); err == nil {
+			t.Error("Expected an error for a malformed input hash")
+		}
+	})
+
+	t.Run("Signature", func(t *testing.T) {
+		key, _ := crypto.GenerateKey()
+		signer := contract.NewKeySigner(key)
+		att, _ := contract.BuildRootAttestation(tree, meta)
+		if err := att.Sign(signer); err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		if v := att.Signature[crypto.RecoveryIDOffset]; v != 27 && v != 28 {
+			t.Errorf("Expected v as 27 or 28, got %d", v)
+		}
+		recovered, err := contract.RecoverAttestationSigner(att.Encoded, att.Signature)
+		if err != nil || recovered != signer.Address() || att.Signer != signer.Address() {
+			t.Errorf("Expected the signature to recover to %s, got %s (%v)", signer.Address().Hex(), recovered.Hex(), err)
+		}
+
+		if err := att.Sign(contract.NewRemoteSigner("http://localhost:0", signer.Address())); err == nil {
+			t.Error("Expected a transaction-only signer to be refused")
+		}
+	})
+
+	t.Run("Publish", func(t *testing.T) {
+		key, _ := crypto.GenerateKey()
+		from := crypto.PubkeyToAddress(key.PublicKey)
+		backend := simulated.NewBackend(types.GenesisAlloc{
+			from: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))},
+		})
+		defer backend.Close()
+		client := contract.NewContractClientWithBackend(backend.Client(), key, big.NewInt(1337))
+		client.SetGas(100000, nil)
+		att, _ := contract.BuildRootAttestation(tree, meta)
+
+		publish := func() *types.Transaction {
+			tx, err := client.PublishAttestation(context.Background(), att.Digest)
+			if err != nil {
+				t.Fatalf("Failed to publish: %v", err)
+			}
+			backend.Commit()
+			receipt, err := backend.Client().TransactionReceipt(context.Background(), tx.Hash())
+			if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+				t.Fatalf("Expected a successful transaction, got %v", err)
+			}
+			return tx
+		}
+
+		// Without a registry the digest is the calldata of a transaction to self
+		tx := publish()
+		if *tx.To() != from || !bytes.Equal(tx.Data(), att.Digest[:]) {
+			t.Errorf("Expected a self-transaction carrying the digest, got to %s data %x", tx.To().Hex(), tx.Data())
+		}
+
+		registry := common.HexToAddress("0x00000000000000000000000000000000000A77e5")
+		client.SetAttestationRegistry(registry)
+		tx = publish()
+		selector := crypto.Keccak256([]byte("attest(bytes32)"))[:4]
+		if *tx.To() != registry || !bytes.Equal(tx.Data(), append(selector, att.Digest[:]...)) {
+			t.Errorf("Expected attest(digest) on the registry, got to %s data %x", tx.To().Hex(), tx.Data())
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// refusingAppender registers the append endpoints without adding anything
+type refusingAppender struct{}
+
+func (refusingAppender) AppendClaims([]merkle.AirdropClaim) (api.AppendResult, error) {
+	return api.AppendResult{}, errors.New("appending is disabled")
+}
+
+func (refusingAppender) TreeVersions() []api.TreeVersion { return nil }
+
+func TestBasePath(t *testing.T) {
+	const token = "admin-secret"
+	tree, proofs := buildProofSet(t, 10)
+	address := tree.Claims[0].Address.Hex()
+	serve := func(base string, extra ...api.Option) http.Handler {
+		opts := append([]api.Option{
+			api.WithBasePath(base),
+			api.WithAdminTokens([]string{token}),
+			api.WithReservation(api.NewMemoryClaimStore()),
+			api.WithAbuseDetection(api.AbuseConfig{Window: time.Minute, MaxNotFound: 100, Action: api.AbuseReject}),
+			api.WithCampaign(api.CampaignMeta{Name: "Season 1"}, ""),
+			api.WithClaimAppender(refusingAppender{}),
+			api.WithBloomFilter(0.01),
+		}, extra...)
+		return api.MustNewAPIServer(tree, proofs.Proofs, opts...).SetupRoutes()
+	}
+	do := func(handler http.Handler, method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	// routeMissing reports whether w is the 404 for a path no route matches
+	routeMissing := func(w *httptest.ResponseRecorder) bool {
+		var body api.ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code == http.StatusNotFound && body.Error.Code == api.CodeNotFound
+	}
+
+	for name, base := range map[string]string{"Prefixed": "/airdrop", "Root": ""} {
+		t.Run(name, func(t *testing.T) {
+			handler := serve(base)
+
+			w := do(handler, http.MethodGet, base+"/api/openapi.json")
+			var doc struct {
+				Servers []struct {
+					URL string `json:"url"`
+				} `json:"servers"`
+				Paths map[string]map[string]json.RawMessage `json:"paths"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("Failed to decode the document: %d %v", w.Code, err)
+			}
+			wantServer := base
+			if wantServer == "" {
+				wantServer = "/"
+			}
+			if len(doc.Servers) != 1 || doc.Servers[0].URL != wantServer {
+				t.Errorf("Expected the server URL %q, got %+v", wantServer, doc.Servers)
+			}
+			if len(doc.Paths) < 20 {
+				t.Fatalf("Expected every endpoint in the document, got %d paths", len(doc.Paths))
+			}
+
+			// Every documented operation resolves under the base path, and
+			// path parameters are read relative to it
+			for path, item := range doc.Paths {
+				for method := range item {
+					w := do(handler, strings.ToUpper(method), base+strings.ReplaceAll(path, "{address}", address))
+					if routeMissing(w) {
+						t.Errorf("%s %s%s: no route", method, base, path)
+					}
+					if base != "" && !routeMissing(do(handler, strings.ToUpper(method), path)) {
+						t.Errorf("%s %s: expected no route outside the base path", method, path)
+					}
+				}
+			}
+			w = do(handler, http.MethodGet, base+"/api/eligible/"+address)
+			var eligible api.EligibilityResponse
+			json.Unmarshal(w.Body.Bytes(), &eligible)
+			if w.Code != http.StatusOK || !eligible.Eligible {
+				t.Errorf("Expected %s to be eligible, got %d %s", address, w.Code, w.Body.String())
+			}
+
+			w = do(handler, http.MethodGet, base+"/api/campaign")
+			var campaign api.CampaignResponse
+			json.Unmarshal(w.Body.Bytes(), &campaign)
+			if campaign.BasePath != base {
+				t.Errorf("Expected the campaign's base path %q, got %q", base, campaign.BasePath)
+			}
+
+			if w := do(handler, http.MethodGet, base+"/"); w.Code != http.StatusFound || w.Header().Get("Location") != base+"/api/docs" {
+				t.Errorf("Expected the root to redirect to the docs, got %d %q", w.Code, w.Header().Get("Location"))
+			}
+			if w := do(handler, http.MethodGet, base+"/api/docs"); !strings.Contains(w.Body.String(), base+"/api/proof/{address}") {
+				t.Errorf("Expected the docs page to list paths under %q", base)
+			}
+		})
+	}
+
+	t.Run("Normalized", func(t *testing.T) {
+		for _, base := range []string{"airdrop", "/airdrop/", "//airdrop//"} {
+			if got := api.CleanBasePath(base); got != "/airdrop" {
+				t.Errorf("%q: expected /airdrop, got %q", base, got)
+			}
+		}
+		if got := api.CleanBasePath("/"); got != "" {
+			t.Errorf("Expected / to be no prefix, got %q", got)
+		}
+
+		handler := serve("//airdrop/v1/")
+		if w := do(handler, http.MethodGet, "/airdrop/v1/api/eligible/"+address); w.Code != http.StatusOK {
+			t.Errorf("Expected the normalized prefix to serve eligibility, got %d", w.Code)
+		}
+		// The bare prefix gains its slash; repeated slashes redirect to
+		// the clean path under the prefix
+		if w := do(handler, http.MethodGet, "/airdrop/v1"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/airdrop/v1/" {
+			t.Errorf("Expected a redirect to /airdrop/v1/, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+		if w := do(handler, http.MethodGet, "/airdrop/v1//api/eligible/"+address); w.Code/100 != 3 || w.Header().Get("Location") != "/airdrop/v1/api/eligible/"+address {
+			t.Errorf("Expected a redirect to the clean path, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+		if w := do(handler, http.MethodGet, "/airdrop/v1x/api/root"); !routeMissing(w) {
+			t.Errorf("Expected a path merely starting with the prefix not to match, got %d", w.Code)
+		}
+	})
+
+	t.Run("StaticSite", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<html data-root="{{MERKLE_ROOT}}"></html>`), 0644)
+		handler := serve("/airdrop", api.WithStaticDir(dir, ""))
+
+		for _, path := range []string{"/airdrop/", "/airdrop/claim/step-2"} {
+			if w := do(handler, http.MethodGet, path); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tree.GetRootHash()) {
+				t.Errorf("%s: expected the claim site, got %d", path, w.Code)
+			}
+		}
+		if w := do(handler, http.MethodGet, "/airdrop/api/unknown"); !routeMissing(w) {
+			t.Errorf("Expected unknown API paths to keep the JSON 404, got %d", w.Code)
+		}
+		if w := do(handler, http.MethodGet, "/"); !routeMissing(w) {
+			t.Errorf("Expected no site outside the base path, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"errors"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestAdaptiveBatching(t *testing.T) {
+	claims := data.GenerateTestData(4000)
+
+	// process simulates claims costing about perClaim each, give or take 20%
+	process := func(perClaim time.Duration) (func([]merkle.AirdropClaim) error, func() []int) {
+		var mu sync.Mutex
+		var sizes []int
+		rng := rand.New(rand.NewSource(1))
+		return func(batch []merkle.AirdropClaim) error {
+				mu.Lock()
+				sizes = append(sizes, len(batch))
+				jitter := 0.8 + 0.4*rng.Float64()
+				mu.Unlock()
+				time.Sleep(time.Duration(float64(perClaim) * float64(len(batch)) * jitter))
+				return nil
+			}, func() []int {
+				mu.Lock()
+				defer mu.Unlock()
+				return sizes
+			}
+	}
+
+	t.Run("Converges", func(t *testing.T) {
+		var stats merkle.BatchStats
+		bp := merkle.NewBatchProcessor(10, 2).WithAdaptiveBatching(10*time.Millisecond, 1, 0)
+		bp.OnStats = func(s merkle.BatchStats) { stats = s }
+		processFn, sizes := process(200 * time.Microsecond)
+		if err := bp.ProcessClaims(claims, processFn); err != nil {
+			t.Fatalf("ProcessClaims failed: %v", err)
+		}
+		// 10ms of 200µs claims is 50 a batch; average the last batches to
+		// smooth out scheduling noise
+		recent := sizes()
+		recent = recent[len(recent)-10 : len(recent)-1]
+		sum := 0
+		for _, size := range recent {
+			sum += size
+		}
+		if mean := sum / len(recent); mean < 35 || mean > 65 {
+			t.Errorf("Expected batch sizes to approach 50, got %v", recent)
+		}
+		if stats.Claims != len(claims) || stats.Batches == 0 || stats.AverageDuration <= 0 {
+			t.Errorf("Unexpected stats %+v", stats)
+		}
+	})
+
+	t.Run("Bounds", func(t *testing.T) {
+		var stats merkle.BatchStats
+		bp := merkle.NewBatchProcessor(10, 2).WithAdaptiveBatching(5*time.Millisecond, 20, 25)
+		bp.OnStats = func(s merkle.BatchStats) { stats = s }
+		processFn, sizes := process(10 * time.Microsecond)
+		if err := bp.ProcessClaims(claims[:1000], processFn); err != nil {
+			t.Fatalf("ProcessClaims failed: %v", err)
+		}
+		total := 0
+		for i, size := range sizes() {
+			total += size
+			if size > 25 {
+				t.Errorf("Batch %d: expected at most 25 claims, got %d", i, size)
+			}
+		}
+		if total != 1000 || stats.FinalBatchSize != 25 {
+			t.Errorf("Expected every claim once and a batch size held at the maximum, got %d claims and size %d", total, stats.FinalBatchSize)
+		}
+	})
+
+	t.Run("Fixed", func(t *testing.T) {
+		var stats merkle.BatchStats
+		bp := merkle.NewBatchProcessor(100, 2)
+		bp.OnStats = func(s merkle.BatchStats) { stats = s }
+		processFn, sizes := process(time.Microsecond)
+		if err := bp.ProcessClaims(claims[:950], processFn); err != nil {
+			t.Fatalf("ProcessClaims failed: %v", err)
+		}
+		if len(sizes()) != 10 || stats.Batches != 10 || stats.FinalBatchSize != 100 {
+			t.Errorf("Expected ten fixed batches, got %v and %+v", sizes(), stats)
+		}
+	})
+
+	t.Run("NoLeakOnError", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		failure := errors.New("batch failed")
+		var calls atomic.Int32
+		withinDeadline(t, "failing batch", func() {
+			err := merkle.NewBatchProcessor(10, 4).ProcessClaims(claims, func([]merkle.AirdropClaim) error {
+				if calls.Add(1) == 3 {
+					return failure
+				}
+				return nil
+			})
+			if !errors.Is(err, failure) {
+				t.Errorf("Expected the batch's error, got %v", err)
+			}
+		})
+		if n := settleGoroutines(before); n > before {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines left running, expected %d:\n%s", n, before, buf[:runtime.Stack(buf, true)])
+		}
+		if n := calls.Load(); n >= int32(len(claims)/10) {
+			t.Errorf("Expected batches to stop after the failure, %d of %d ran", n, len(claims)/10)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		noop := func([]merkle.AirdropClaim) error { return nil }
+		if err := merkle.NewBatchProcessor(0, 1).ProcessClaims(claims[:10], noop); err == nil {
+			t.Error("Expected a zero batch size to be rejected")
+		}
+		if err := merkle.NewBatchProcessor(10, 1).WithAdaptiveBatching(0, 1, 0).ProcessClaims(claims[:10], noop); err == nil {
+			t.Error("Expected a zero target to be rejected")
+		}
+		if err := merkle.NewBatchProcessor(10, 1).WithAdaptiveBatching(time.Millisecond, 50, 10).ProcessClaims(claims[:10], noop); err == nil {
+			t.Error("Expected a maximum below the minimum to be rejected")
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/benchcmp"
+)
+
+const sampleBenchOutput = `goos: linux
+goarch: amd64
+pkg: merkle-airdrop/test
+BenchmarkProofGeneration/claims=1k-8     	     200	   5000000 ns/op	         0.6600 proof-MB	    200000 proofs/sec	 1343325 B/op	   10995 allocs/op
+BenchmarkProofGeneration/claims=1k-8     	     200	   5000000 ns/op	         0.6600 proof-MB	    100000 proofs/sec	 1343325 B/op	   10995 allocs/op
+BenchmarkProofGeneration/claims=10k-8    	      12	  90000000 ns/op	         9.240 proof-MB	    110000 proofs/sec	17158652 B/op	  109863 allocs/op
+BenchmarkSingleProof/claims=1k-8         	  500000	      2500 ns/op	    1408 B/op	       9 allocs/op
+PASS
+ok  	merkle-airdrop/test	12.345s
+`
+
+func TestBenchmarkCompare(t *testing.T) {
+	results, err := benchcmp.Parse(strings.NewReader(sampleBenchOutput))
+	if err != nil {
+		t.Fatalf("Failed to parse output: %v", err)
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		if got := results["BenchmarkProofGeneration/claims=1k"]["proofs/sec"]; got != 150000 {
+			t.Errorf("Expected repeated runs to average to 150000 proofs/sec, got %v", got)
+		}
+		if got := results["BenchmarkSingleProof/claims=1k"]["allocs/op"]; got != 9 {
+			t.Errorf("Expected 9 allocs/op, got %v", got)
+		}
+		if len(results) != 3 {
+			t.Errorf("Expected 3 benchmarks, got %d", len(results))
+		}
+	})
+
+	t.Run("WithinTolerance", func(t *testing.T) {
+		baseline := &benchcmp.Baseline{Metric: "proofs/sec", Tolerance: 0.2, Benchmarks: map[string]float64{
+			"BenchmarkProofGeneration/claims=1k":  180000, // 16.7% drop
+			"BenchmarkProofGeneration/claims=10k": 100000,
+			"BenchmarkProofGeneration/claims=1M":  50000, // Not run
+		}}
+		regressions, err := baseline.Compare(results)
+		if err != nil || len(regressions) != 0 {
+			t.Errorf("Expected no regressions, got %v (%v)", regressions, err)
+		}
+	})
+
+	t.Run("Regression", func(t *testing.T) {
+		baseline := &benchcmp.Baseline{Metric: "proofs/sec", Tolerance: 0.2, Benchmarks: map[string]float64{
+			"BenchmarkProofGeneration/claims=1k":  200000, // 25% drop
+			"BenchmarkProofGeneration/claims=10k": 110000,
+		}}
+		regressions, err := baseline.Compare(results)
+		if err != nil {
+			t.Fatalf("Compare failed: %v", err)
+		}
+		if len(regressions) != 1 || regressions[0].Name != "BenchmarkProofGeneration/claims=1k" {
+			t.Fatalf("Expected the 1k benchmark to regress, got %v", regressions)
+		}
+		if change := regressions[0].Change(); change != -0.25 {
+			t.Errorf("Expected a -25%% change, got %v", change)
+		}
+	})
+
+	t.Run("NoMatches", func(t *testing.T) {
+		baseline := &benchcmp.Baseline{Metric: "proofs/sec", Tolerance: 0.2, Benchmarks: map[string]float64{
+			"BenchmarkRenamed": 1000,
+		}}
+		if _, err := baseline.Compare(results); err == nil {
+			t.Error("Expected an error when no benchmark matches the baseline")
+		}
+	})
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		baseline, err := benchcmp.NewBaseline(results, benchcmp.DefaultMetric, benchcmp.DefaultTolerance)
+		if err != nil {
+			t.Fatalf("Failed to create baseline: %v", err)
+		}
+		if len(baseline.Benchmarks) != 2 {
+			t.Errorf("Expected only benchmarks reporting proofs/sec, got %v", baseline.Benchmarks)
+		}
+
+		filename := filepath.Join(t.TempDir(), "baseline.json")
+		if err := baseline.Save(filename); err != nil {
+			t.Fatalf("Failed to save baseline: %v", err)
+		}
+		loaded, err := benchcmp.LoadBaseline(filename)
+		if err != nil {
+			t.Fatalf("Failed to load baseline: %v", err)
+		}
+		if regressions, err := loaded.Compare(results); err != nil || len(regressions) != 0 {
+			t.Errorf("Expected results to match their own baseline, got %v (%v)", regressions, err)
+		}
+	})
+
+	t.Run("CheckedInBaseline", func(t *testing.T) {
+		if _, err := benchcmp.LoadBaseline("bench_baseline.json"); err != nil {
+			t.Errorf("Failed to load the checked-in baseline: %v", err)
+		}
+	})
+}
@@ -2,33 +2,305 @@
 package test
 
 import (
+	"fmt"
+	"io"
+	"math/big"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
 	"merkle-airdrop/pkg/data"
 	"merkle-airdrop/pkg/merkle"
-	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
+// benchSizes are the claim counts each sized benchmark runs at
+var benchSizes = []struct {
+	name  string
+	count int
+}{
+	{"1k", 1000},
+	{"10k", 10000},
+	{"100k", 100000},
+	{"1M", 1000000},
+}
+
+var (
+	benchClaimsMu sync.Mutex
+	benchClaims   = make(map[int][]merkle.AirdropClaim)
+)
+
+// benchmarkClaims returns a fresh copy of count random claims. The claims
+// are generated once per size; callers may sort and re-index the copy.
+func benchmarkClaims(count int) []merkle.AirdropClaim {
+	benchClaimsMu.Lock()
+	defer benchClaimsMu.Unlock()
+
+	claims, ok := benchClaims[count]
+	if !ok {
+		claims = data.GenerateRandomTestData(count, 1)
+		benchClaims[count] = claims
+	}
+	return data.CloneClaims(claims)
+}
+
+// benchmarkTree builds a tree over count random claims
+func benchmarkTree(b *testing.B, count int) *merkle.MerkleTree {
+	b.Helper()
+
+	opts := merkle.DefaultTreeOptions()
+	opts.CopyClaims = false
+	opts.Workers = runtime.NumCPU()
+	tree, err := merkle.NewMerkleTreeWithOptions(benchmarkClaims(count), opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return tree
+}
+
+// skipLarge skips sizes too slow for -short runs
+func skipLarge(b *testing.B, count int) {
+	if testing.Short() && count > 100000 {
+		b.Skip("skipping large size in short mode")
+	}
+}
+
+func BenchmarkLeafHashing(b *testing.B) {
+	claim := data.GenerateTestData(1)[0]
+
+	b.Run("HashLeaf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			merkle.HashLeaf(claim.Address, claim.Amount, uint32(i))
+		}
+	})
+
+	b.Run("OptimizedHashLeaf", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			merkle.OptimizedHashLeaf(claim.Address, claim.Amount, uint32(i))
+		}
+	})
+}
+
 func BenchmarkTreeConstruction(b *testing.B) {
-	claims := data.GenerateTestData(10000)
+	modes := []struct {
+		name    string
+		workers int
+	}{
+		{"serial", 1},
+		{"parallel", runtime.NumCPU()},
+	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := merkle.NewMerkleTree(claims)
-		if err != nil {
-			b.Fatal(err)
+	for _, size := range benchSizes {
+		for _, mode := range modes {
+			b.Run(fmt.Sprintf("claims=%s/%s", size.name, mode.name), func(b *testing.B) {
+				skipLarge(b, size.count)
+
+				opts := merkle.DefaultTreeOptions()
+				opts.CopyClaims = false
+				opts.Workers = mode.workers
+
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					// Building sorts in place, so each run gets unsorted claims
+					b.StopTimer()
+					claims := benchmarkClaims(size.count)
+					b.StartTimer()
+
+					if _, err := merkle.NewMerkleTreeWithOptions(claims, opts); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size.count)*float64(b.N)/b.Elapsed().Seconds(), "claims/sec")
+			})
 		}
 	}
 }
 
 func BenchmarkProofGeneration(b *testing.B) {
-	claims := data.GenerateTestData(10000)
-	tree, _ := merkle.NewMerkleTree(claims)
+	for _, size := range benchSizes {
+		b.Run("claims="+size.name, func(b *testing.B) {
+			skipLarge(b, size.count)
+			tree := benchmarkTree(b, size.count)
+
+			var proofs map[string]*merkle.MerkleProof
+			var err error
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := tree.GenerateAllProofs()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				proofs, err = tree.GenerateAllProofs()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			outputBytes := 0
+			for _, proof := range proofs {
+				for _, element := range proof.Proof {
+					outputBytes += len(element)
+				}
+			}
+			b.ReportMetric(float64(len(proofs))*float64(b.N)/b.Elapsed().Seconds(), "proofs/sec")
+			b.ReportMetric(float64(outputBytes)/1e6, "proof-MB")
+		})
+	}
+}
+
+// BenchmarkProofStream streams every proof to a sink that drops it and
+// spills every proof to a binary file, neither holding the proof set
+func BenchmarkProofStream(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run("claims="+size.name, func(b *testing.B) {
+			skipLarge(b, size.count)
+			tree := benchmarkTree(b, size.count)
+
+			b.Run("Sink", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := tree.GenerateAllProofsTo(func(string, *merkle.MerkleProof) error { return nil }); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size.count)*float64(b.N)/b.Elapsed().Seconds(), "proofs/sec")
+			})
+			b.Run("Spill", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := data.ExportTreeProofsBinary(tree, io.Discard); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size.count)*float64(b.N)/b.Elapsed().Seconds(), "proofs/sec")
+			})
+		})
+	}
+}
+
+func BenchmarkSingleProof(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run("claims="+size.name, func(b *testing.B) {
+			skipLarge(b, size.count)
+			tree := benchmarkTree(b, size.count)
+			address := tree.Claims[len(tree.Claims)/2].Address
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := tree.GenerateProof(address); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAmountUpdate changes the amounts of 1% of the claims, by a full
+// rebuild and with UpdateAmounts, each ending with every changed proof
+func BenchmarkAmountUpdate(b *testing.B) {
+	for _, size := range benchSizes {
+		b.Run("claims="+size.name, func(b *testing.B) {
+			skipLarge(b, size.count)
+			tree := benchmarkTree(b, size.count)
+			// changes adds one to the amounts of every 100th claim
+			changes := func() map[common.Address]*big.Int {
+				changed := make(map[common.Address]*big.Int, size.count/100+1)
+				for i := 0; i < size.count; i += 100 {
+					claim := tree.Claims[i]
+					changed[claim.Address] = new(big.Int).Add(claim.Amount, big.NewInt(1))
+				}
+				return changed
+			}
+
+			b.Run("Rebuild", func(b *testing.B) {
+				changed := changes()
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					claims := data.CloneClaims(tree.Claims)
+					for j := range claims {
+						if amount, ok := changed[claims[j].Address]; ok {
+							claims[j].Amount = amount
+						}
+					}
+					b.StartTimer()
+
+					rebuilt, err := merkle.NewMerkleTreeWithOptions(claims, tree.Options())
+					if err != nil {
+						b.Fatal(err)
+					}
+					if _, err := rebuilt.GenerateAllProofs(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size.count)*float64(b.N)/b.Elapsed().Seconds(), "claims/sec")
+			})
+			b.Run("Update", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					changed := changes()
+					b.StartTimer()
+
+					if _, err := tree.UpdateAmounts(changed); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size.count)*float64(b.N)/b.Elapsed().Seconds(), "claims/sec")
+			})
+		})
+	}
+}
+
+// benchmarkStores are the proof stores BenchmarkProofStore compares
+var benchmarkStores = []struct {
+	name string
+	open func(b *testing.B) merkle.ProofStore
+}{
+	{"memory", func(b *testing.B) merkle.ProofStore { return merkle.MapProofStore{} }},
+	{"disk", func(b *testing.B) merkle.ProofStore {
+		store, err := merkle.CreateDiskProofStore(filepath.Join(b.TempDir(), "proofs.store"))
 		if err != nil {
 			b.Fatal(err)
 		}
+		b.Cleanup(func() { store.Close() })
+		return store
+	}},
+}
+
+func BenchmarkProofStore(b *testing.B) {
+	for _, size := range benchSizes[:3] {
+		tree := benchmarkTree(b, size.count)
+		for _, kind := range benchmarkStores {
+			b.Run(fmt.Sprintf("claims=%s/%s/Generate", size.name, kind.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if err := tree.GenerateAllProofsInto(kind.open(b)); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(size.count)*float64(b.N)/b.Elapsed().Seconds(), "proofs/sec")
+			})
+
+			b.Run(fmt.Sprintf("claims=%s/%s/Get", size.name, kind.name), func(b *testing.B) {
+				store := kind.open(b)
+				if err := tree.GenerateAllProofsInto(store); err != nil {
+					b.Fatal(err)
+				}
+
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, exists, err := store.Get(tree.Claims[i%size.count].Address); err != nil || !exists {
+						b.Fatal("missing proof", err)
+					}
+				}
+			})
+		}
 	}
 }
 
@@ -51,3 +323,30 @@ func TestMerkleTreeCorrectness(t *testing.T) {
 		}
 	}
 }
+
+func TestParallelConstruction(t *testing.T) {
+	claims := data.GenerateRandomTestData(5000, 7)
+
+	opts := merkle.DefaultTreeOptions()
+	opts.Workers = 1 // Zero would mean one per CPU
+	serial, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Workers = 8
+	parallel, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if serial.GetRootHash() != parallel.GetRootHash() {
+		t.Errorf("Expected the same root, got %s serially and %s in parallel", serial.GetRootHash(), parallel.GetRootHash())
+	}
+	address := claims[1234].Address
+	a, _ := serial.GenerateProof(address)
+	b, _ := parallel.GenerateProof(address)
+	if fmt.Sprint(a.Proof) != fmt.Sprint(b.Proof) {
+		t.Error("Expected the same proof from both trees")
+	}
+}
@@ -0,0 +1,162 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// indexedTree builds a tree whose claims keep the given indices
+func indexedTree(t *testing.T, indices []uint32) *merkle.MerkleTree {
+	t.Helper()
+	claims := make([]merkle.AirdropClaim, len(indices))
+	for i, index := range indices {
+		claims[i] = merkle.AirdropClaim{
+			Address: common.BigToAddress(big.NewInt(int64(i + 1))),
+			Amount:  big.NewInt(100),
+			Index:   index,
+		}
+	}
+	opts := merkle.DefaultTreeOptions()
+	opts.SortOrder = merkle.SortByIndex
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	return tree
+}
+
+func span(from, to uint32) []uint32 {
+	var indices []uint32
+	for i := from; i <= to; i++ {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+func TestIndexBitmap(t *testing.T) {
+	cases := []struct {
+		name     string
+		indices  []uint32
+		maxIndex uint32
+		words    int
+		gaps     []uint32
+	}{
+		{"Contiguous", span(0, 99), 99, 1, []uint32{}},
+		{"Filtered", []uint32{0, 1, 4, 5, 9}, 9, 1, []uint32{2, 3, 6, 7, 8}},
+		{"OneFullWord", span(0, 255), 255, 1, []uint32{}},
+		{"WordBoundary", span(0, 256), 256, 2, []uint32{}},
+		{"SkippedWord", []uint32{0, 255, 512}, 512, 2, nil}, // Word 1 is never touched
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tree := indexedTree(t, tc.indices)
+			maxIndex, words, gaps := tree.IndexStats()
+			if maxIndex != tc.maxIndex || words != tc.words {
+				t.Errorf("Expected max index %d in %d words, got %d in %d", tc.maxIndex, tc.words, maxIndex, words)
+			}
+			if tc.gaps != nil && !reflect.DeepEqual(gaps, tc.gaps) {
+				t.Errorf("Expected gaps %v, got %v", tc.gaps, gaps)
+			}
+			if want := int(maxIndex) + 1 - len(tc.indices); len(gaps) != want {
+				t.Errorf("Expected %d gaps, got %d", want, len(gaps))
+			}
+
+			bitmap := tree.IndexBitmap()
+			if len(bitmap) != int(tc.maxIndex)/8+1 {
+				t.Fatalf("Expected a %d-byte bitmap, got %d", tc.maxIndex/8+1, len(bitmap))
+			}
+			claimed := map[uint32]bool{}
+			for _, index := range tc.indices {
+				claimed[index] = true
+			}
+			for i := uint32(0); i <= tc.maxIndex; i++ {
+				if set := bitmap[i/8]&(1<<(i%8)) != 0; set != claimed[i] {
+					t.Fatalf("Bit %d: expected %v", i, claimed[i])
+				}
+			}
+		})
+	}
+
+	t.Run("GapLimit", func(t *testing.T) {
+		stats := merkle.IndexStatsOf([]uint32{1000, 3, 3}, 10)
+		if stats.GapCount != 999 || len(stats.Gaps) != 10 || stats.Gaps[9] != 10 {
+			t.Errorf("Expected 999 gaps with the first 10 listed, got %d %v", stats.GapCount, stats.Gaps)
+		}
+	})
+
+	t.Run("Encoding", func(t *testing.T) {
+		small := merkle.IndexBitmapOf([]uint32{0, 9})
+		if encoded := merkle.EncodeIndexBitmap(small); encoded != "0x0102" {
+			t.Errorf("Expected a plain hex bitmap, got %s", encoded)
+		}
+
+		large := merkle.IndexBitmapOf(append(span(0, 9999), 20000))
+		encoded := merkle.EncodeIndexBitmap(large)
+		if !strings.HasPrefix(encoded, "rle:0x") || len(encoded) > 100 {
+			t.Errorf("Expected a short run-length encoding, got %d characters", len(encoded))
+		}
+		for _, bitmap := range [][]byte{small, large} {
+			decoded, err := merkle.DecodeIndexBitmap(merkle.EncodeIndexBitmap(bitmap))
+			if err != nil || !bytes.Equal(decoded, bitmap) {
+				t.Errorf("Expected the bitmap to round-trip, got %v", err)
+			}
+		}
+		for _, invalid := range []string{"ff", "rle:0x00ff", "rle:0x01"} {
+			if _, err := merkle.DecodeIndexBitmap(invalid); err == nil {
+				t.Errorf("Expected %q to be rejected", invalid)
+			}
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		tree := indexedTree(t, []uint32{0, 1, 4, 300})
+		proofs, err := tree.GenerateAllProofs()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		servers := map[string]*api.APIServer{
+			"Tree":   api.MustNewAPIServer(tree, proofs),
+			"Proofs": mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()})),
+		}
+		for name, server := range servers {
+			req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+			w := httptest.NewRecorder()
+			server.SetupRoutes().ServeHTTP(w, req)
+
+			var response struct {
+				Indices merkle.IndexStats `json:"indices"`
+			}
+			json.NewDecoder(w.Body).Decode(&response)
+			if response.Indices.MaxIndex != 300 || response.Indices.Words != 2 || response.Indices.GapCount != 297 {
+				t.Errorf("%s: unexpected index stats %+v", name, response.Indices)
+			}
+			if len(response.Indices.Gaps) != 100 || response.Indices.Gaps[0] != 2 {
+				t.Errorf("%s: expected the first 100 gaps, got %d", name, len(response.Indices.Gaps))
+			}
+		}
+	})
+
+	t.Run("GeneratedData", func(t *testing.T) {
+		// Trees numbered by leaf position never have gaps
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(512), merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		if maxIndex, words, gaps := tree.IndexStats(); maxIndex != 511 || words != 2 || len(gaps) != 0 {
+			t.Errorf("Expected indices 0-511 in 2 words, got %d %d %v", maxIndex, words, gaps)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestBloomFilter(t *testing.T) {
+	claims := data.GenerateRandomTestData(20000, 1)
+
+	t.Run("FalsePositiveRate", func(t *testing.T) {
+		for _, rate := range []float64{0.1, 0.01, 0.001} {
+			filter, err := merkle.BuildBloomFilter(claims, rate)
+			if err != nil {
+				t.Fatalf("Rate %v: %v", rate, err)
+			}
+			for _, claim := range claims {
+				if !filter.Contains(claim.Address) {
+					t.Fatalf("Rate %v: expected %s to be contained", rate, claim.Address.Hex())
+				}
+			}
+
+			nonMembers := data.GenerateRandomTestData(100000, 2)
+			positives := 0
+			for _, claim := range nonMembers {
+				if filter.Contains(claim.Address) {
+					positives++
+				}
+			}
+			measured := float64(positives) / float64(len(nonMembers))
+			params := filter.Params()
+			t.Logf("Rate %v: %d bits, %d hashes, expected %.5f, measured %.5f", rate, params.Bits, params.Hashes, params.FalsePositiveRate, measured)
+			if params.FalsePositiveRate > rate*1.05 {
+				t.Errorf("Rate %v: filter sized for %v", rate, params.FalsePositiveRate)
+			}
+			if measured > rate*1.5 {
+				t.Errorf("Rate %v: measured false positive rate %v", rate, measured)
+			}
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		filter, _ := merkle.BuildBloomFilter(claims, 0.01)
+		encoded, err := filter.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded merkle.BloomFilter
+		if err := decoded.UnmarshalBinary(encoded); err != nil {
+			t.Fatalf("Failed to decode filter: %v", err)
+		}
+		if decoded.Params() != filter.Params() {
+			t.Errorf("Expected params %+v, got %+v", filter.Params(), decoded.Params())
+		}
+		for _, claim := range claims[:1000] {
+			if !decoded.Contains(claim.Address) {
+				t.Fatalf("Expected decoded filter to contain %s", claim.Address.Hex())
+			}
+		}
+
+		for name, corrupt := range map[string][]byte{
+			"Empty":     nil,
+			"Magic":     append([]byte("XXXX"), encoded[4:]...),
+			"Version":   append(append(append([]byte{}, encoded[:4]...), 9), encoded[5:]...),
+			"Truncated": encoded[:len(encoded)-1],
+		} {
+			if err := new(merkle.BloomFilter).UnmarshalBinary(corrupt); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+
+	// A client holding only the encoded bytes finds the same bits
+	t.Run("DocumentedLayout", func(t *testing.T) {
+		members := claims[:50]
+		filter, _ := merkle.BuildBloomFilter(members, 0.01)
+		encoded, _ := filter.MarshalBinary()
+		hashes := uint64(encoded[5])
+		size := uint64(binary.BigEndian.Uint32(encoded[6:10]))
+		bits := encoded[14:]
+
+		contains := func(address common.Address) bool {
+			hash := crypto.Keccak256(address.Bytes())
+			h1 := uint64(binary.BigEndian.Uint32(hash[0:4]))
+			h2 := uint64(binary.BigEndian.Uint32(hash[4:8]))
+			for j := uint64(0); j < hashes; j++ {
+				bit := (h1 + j*h2) % size
+				if bits[bit/8]&(1<<(bit%8)) == 0 {
+					return false
+				}
+			}
+			return true
+		}
+		for _, claim := range claims[:2000] {
+			if contains(claim.Address) != filter.Contains(claim.Address) {
+				t.Fatalf("Layout lookup disagrees with Contains for %s", claim.Address.Hex())
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, rate := range []float64{0, 1, -0.5, 2} {
+			if _, err := merkle.BuildBloomFilter(claims, rate); err == nil {
+				t.Errorf("Rate %v: expected an error", rate)
+			}
+		}
+		if _, err := merkle.BuildBloomFilter(nil, 0.01); err == nil {
+			t.Error("Expected an error without claims")
+		}
+	})
+
+	t.Run("Serve", func(t *testing.T) {
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(30), merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		proofs, _ := tree.GenerateAllProofs()
+		want, _ := merkle.BuildBloomFilter(tree.Claims, 0.001)
+		wantEncoded, _ := want.MarshalBinary()
+
+		servers := map[string]http.Handler{
+			"Tree":   api.MustNewAPIServer(tree, proofs, api.WithBloomFilter(0.001)).SetupRoutes(),
+			"Proofs": mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}, api.WithBloomFilter(0.001))).SetupRoutes(),
+		}
+		for name, handler := range servers {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/bloom", nil))
+			if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), wantEncoded) {
+				t.Fatalf("%s: expected the encoded filter, got %d (%d bytes)", name, w.Code, w.Body.Len())
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" || w.Header().Get("Cache-Control") == "" || w.Header().Get("Content-Type") != "application/octet-stream" {
+				t.Errorf("%s: expected caching headers, got %v", name, w.Header())
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/bloom", nil)
+			req.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusNotModified {
+				t.Errorf("%s: expected 304 for a matching ETag, got %d", name, w.Code)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		api.MustNewAPIServer(tree, proofs).SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/bloom", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected /api/bloom to be disabled by default, got %d", w.Code)
+		}
+	})
+}
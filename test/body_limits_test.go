@@ -0,0 +1,113 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestRequestBodyLimits(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(8), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, _ := tree.GenerateAllProofs()
+	claim := tree.Claims[3]
+	proof := proofs[claim.Address.Hex()]
+	valid, _ := json.Marshal(map[string]interface{}{
+		"address": claim.Address.Hex(),
+		"amount":  claim.Amount.String(),
+		"proof":   proof.Proof,
+	})
+
+	// post sends body to /api/verify and decodes the response
+	post := func(t *testing.T, handler http.Handler, contentType, body string) (int, api.ErrorResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/verify", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var response api.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	t.Run("Oversized", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs, api.WithMaxBodyBytes(int64(len(valid)))).SetupRoutes()
+		if code, _ := post(t, handler, "application/json", string(valid)); code != http.StatusOK {
+			t.Errorf("Expected a body at the limit to be accepted, got %d", code)
+		}
+
+		padded := strings.Replace(string(valid), "{", "{"+strings.Repeat(" ", 64), 1)
+		code, response := post(t, handler, "application/json", padded)
+		if code != http.StatusRequestEntityTooLarge || response.Error.Code != api.CodePayloadTooLarge || response.RequestID == "" {
+			t.Errorf("Expected 413 %s with a request ID, got %d %+v", api.CodePayloadTooLarge, code, response)
+		}
+	})
+
+	t.Run("DefaultLimit", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		huge := `{"address": "` + claim.Address.Hex() + `", "amount": "` + strings.Repeat("1", int(api.DefaultMaxBodyBytes)) + `"}`
+		if code, response := post(t, handler, "application/json", huge); code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected 413 beyond the default limit, got %d %+v", code, response)
+		}
+	})
+
+	t.Run("ContentType", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded", "application/jsonx"} {
+			code, response := post(t, handler, contentType, string(valid))
+			if code != http.StatusUnsupportedMediaType || response.Error.Code != api.CodeUnsupportedMediaType {
+				t.Errorf("%q: expected 415 %s, got %d %+v", contentType, api.CodeUnsupportedMediaType, code, response)
+			}
+		}
+		if code, _ := post(t, handler, "application/json; charset=utf-8", string(valid)); code != http.StatusOK {
+			t.Errorf("Expected a charset parameter to be accepted, got %d", code)
+		}
+	})
+
+	t.Run("DocumentedFields", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		body := strings.Replace(string(valid), "{", `{"merkleRoot": "`+tree.GetRootHash()+`", "index": `+fmt.Sprint(claim.Index)+`, "positions": 0, `, 1)
+		if code, response := post(t, handler, "application/json", body); code != http.StatusOK {
+			t.Errorf("Expected every documented field to be accepted, got %d %+v", code, response)
+		}
+	})
+
+	t.Run("TrailingData", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		if code, _ := post(t, handler, "application/json", string(valid)+"\n\t "); code != http.StatusOK {
+			t.Errorf("Expected trailing whitespace to be accepted, got %d", code)
+		}
+		for name, trailer := range map[string]string{
+			"SecondValue": `{"address": "0x0000000000000000000000000000000000000001"}`,
+			"Garbage":     "xyz",
+			"Bracket":     "}",
+		} {
+			code, response := post(t, handler, "application/json", string(valid)+trailer)
+			if code != http.StatusBadRequest || response.Error.Code != api.CodeInvalidRequest {
+				t.Errorf("%s: expected 400 %s, got %d %+v", name, api.CodeInvalidRequest, code, response)
+			}
+		}
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		typo := strings.Replace(string(valid), `"proof"`, `"proofs"`, 1)
+		code, response := post(t, handler, "application/json", typo)
+		if code != http.StatusBadRequest || response.Error.Code != api.CodeInvalidRequest || !strings.Contains(response.Error.Message, "proofs") {
+			t.Errorf("Expected 400 %s naming the field, got %d %+v", api.CodeInvalidRequest, code, response)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestCampaign(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(12), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, _ := tree.GenerateAllProofs()
+	meta := api.CampaignMeta{
+		Name:            "Season 1",
+		TokenSymbol:     "DROP",
+		ContractAddress: "0x000000000000000000000000000000000000dEaD",
+		ChainID:         1,
+		Deadline:        "2026-12-31T23:59:59Z",
+	}
+
+	// serve returns a handler for a campaign saved to path
+	serve := func(path string) http.Handler {
+		return api.MustNewAPIServer(tree, proofs, api.WithCampaign(meta, path), api.WithAdminTokens([]string{"admin-secret"})).SetupRoutes()
+	}
+	do := func(handler http.Handler, method, body, token string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, "/api/campaign", nil)
+		if method == http.MethodPut {
+			req = httptest.NewRequest(method, "/api/admin/campaign", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&response)
+		return w.Code, response
+	}
+
+	t.Run("Serve", func(t *testing.T) {
+		status, body := do(serve(""), http.MethodGet, "", "")
+		campaign, _ := body["campaign"].(map[string]interface{})
+		if status != http.StatusOK || campaign["name"] != "Season 1" || campaign["chainId"] != float64(1) || campaign["deadline"] != meta.Deadline {
+			t.Fatalf("Expected the campaign, got %d %v", status, body)
+		}
+		if body["merkleRoot"] != tree.GetRootHash() || body["totalClaims"] != float64(12) {
+			t.Errorf("Expected the root and claim count alongside the campaign, got %v", body)
+		}
+
+		// Without a campaign there is no endpoint
+		w := httptest.NewRecorder()
+		api.MustNewAPIServer(tree, proofs).SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/campaign", nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 without a campaign, got %d", w.Code)
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		for name, mutate := range map[string]func(*api.CampaignMeta){
+			"NoName":          func(m *api.CampaignMeta) { m.Name = "" },
+			"ContractAddress": func(m *api.CampaignMeta) { m.ContractAddress = "0x1234" },
+			"NoChainID":       func(m *api.CampaignMeta) { m.ChainID = 0 },
+			"Deadline":        func(m *api.CampaignMeta) { m.Deadline = "2026-12-31" },
+		} {
+			invalid := meta
+			mutate(&invalid)
+			if err := invalid.Validate(); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+		if err := (api.CampaignMeta{Name: "No contract yet"}).Validate(); err != nil {
+			t.Errorf("Expected a campaign with only a name to be valid, got %v", err)
+		}
+
+		// The same checks on the config section
+		for name, mutate := range map[string]func(*config.CampaignConfig){
+			"NoName":          func(c *config.CampaignConfig) { c.Name = "" },
+			"ContractAddress": func(c *config.CampaignConfig) { c.ContractAddress = "dead" },
+			"NoChainID":       func(c *config.CampaignConfig) { c.ChainID = 0 },
+			"Deadline":        func(c *config.CampaignConfig) { c.Deadline = "tomorrow" },
+		} {
+			cfg := config.DefaultConfig()
+			cfg.Campaign = config.CampaignConfig{Name: "Season 1", TokenSymbol: "DROP", ContractAddress: meta.ContractAddress, ChainID: 1, Deadline: meta.Deadline}
+			mutate(&cfg.Campaign)
+			if err := cfg.ValidateAll(); err == nil || !strings.Contains(err.Error(), "campaign") {
+				t.Errorf("Config %s: expected a campaign error, got %v", name, err)
+			}
+		}
+
+		path := filepath.Join(t.TempDir(), "campaign.json")
+		os.WriteFile(path, []byte(`{"name": "Season 1", "deadline": "next week"}`), 0o644)
+		if _, err := api.LoadCampaignFile(path); err == nil {
+			t.Error("Expected an invalid campaign file to be rejected")
+		}
+	})
+
+	t.Run("AdminUpdate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "campaign.json")
+		handler := serve(path)
+		update := `{"name": "Season 1 (extended)", "tokenSymbol": "DROP", "contractAddress": "0x000000000000000000000000000000000000dEaD", "chainId": 10, "deadline": "2027-01-31T00:00:00+01:00"}`
+
+		if status, _ := do(handler, http.MethodPut, update, ""); status != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without a token, got %d", status)
+		}
+		status, body := do(handler, http.MethodPut, `{"name": "Season 1", "deadline": "soon"}`, "admin-secret")
+		envelope, _ := body["error"].(map[string]interface{})
+		if status != http.StatusBadRequest || envelope["code"] != api.CodeInvalidCampaign {
+			t.Errorf("Expected 400 %s, got %d %v", api.CodeInvalidCampaign, status, body)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Error("Expected a rejected update not to be saved")
+		}
+
+		if status, body := do(handler, http.MethodPut, update, "admin-secret"); status != http.StatusOK {
+			t.Fatalf("Expected the update to succeed, got %d %v", status, body)
+		}
+		_, body = do(handler, http.MethodGet, "", "")
+		if campaign, _ := body["campaign"].(map[string]interface{}); campaign["name"] != "Season 1 (extended)" || campaign["chainId"] != float64(10) {
+			t.Errorf("Expected the updated campaign to be served, got %v", body)
+		}
+
+		saved, err := api.LoadCampaignFile(path)
+		if err != nil || saved.Name != "Season 1 (extended)" || saved.Deadline != "2027-01-31T00:00:00+01:00" {
+			t.Errorf("Expected the update to be saved to %s, got %+v (%v)", path, saved, err)
+		}
+		if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp*")); len(matches) != 0 {
+			t.Errorf("Expected no temporary files left behind, got %v", matches)
+		}
+	})
+}
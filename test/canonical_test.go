@@ -0,0 +1,96 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestExportProofsCanonical(t *testing.T) {
+	// export builds a tree from claims and returns its canonical export
+	export := func(t *testing.T, claims []merkle.AirdropClaim, addressCase data.AddressCase) []byte {
+		t.Helper()
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		proofs, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := data.ExportProofsCanonical(proofs, tree.GetRootHash(), &buf, addressCase, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	claims := data.GenerateTestData(300)
+	first := export(t, claims, data.AddressChecksum)
+
+	t.Run("Repeatable", func(t *testing.T) {
+		second := export(t, claims, data.AddressChecksum)
+		if sha256.Sum256(first) != sha256.Sum256(second) {
+			t.Error("Expected two exports of the same claims to be byte-identical")
+		}
+	})
+
+	t.Run("ShuffledInput", func(t *testing.T) {
+		shuffled := append([]merkle.AirdropClaim(nil), claims...)
+		rand.New(rand.NewSource(7)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		if sha256.Sum256(first) != sha256.Sum256(export(t, shuffled, data.AddressChecksum)) {
+			t.Error("Expected the export not to depend on the claims' input order")
+		}
+	})
+
+	t.Run("Readable", func(t *testing.T) {
+		root, proofs, err := data.LoadProofsJSON(bytes.NewReader(first))
+		if err != nil {
+			t.Fatalf("Failed to load canonical export: %v", err)
+		}
+		if proofs.Len() != 300 || root != strings.ToLower(root) {
+			t.Errorf("Expected 300 proofs under a lowercase root, got %d under %s", proofs.Len(), root)
+		}
+		for _, volatile := range []string{"generatedAt", "buildTime", "proofTime"} {
+			if bytes.Contains(first, []byte(volatile)) {
+				t.Errorf("Expected %s to be left out of the export", volatile)
+			}
+		}
+	})
+
+	t.Run("Normalized", func(t *testing.T) {
+		tree, _ := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(4), merkle.DefaultTreeOptions())
+		proofs, _ := tree.GenerateProofSet()
+
+		var want bytes.Buffer
+		data.ExportProofsCanonical(proofs, tree.GetRootHash(), &want, data.AddressChecksum, data.ProofMarshaller{})
+
+		// Uppercase hex and padded amounts are written the same way
+		for _, proof := range proofs.Proofs {
+			for i, element := range proof.Proof {
+				proof.Proof[i] = "0x" + strings.ToUpper(element[2:])
+			}
+			proof.Amount = "000" + proof.Amount
+		}
+		var got bytes.Buffer
+		data.ExportProofsCanonical(proofs, "0x"+strings.ToUpper(tree.GetRootHash()[2:]), &got, data.AddressChecksum, data.ProofMarshaller{})
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Error("Expected equivalent proofs to export identically")
+		}
+
+		for _, proof := range proofs.Proofs {
+			proof.Proof[0] = "0x1234"
+			break
+		}
+		if err := data.ExportProofsCanonical(proofs, tree.GetRootHash(), &got, data.AddressChecksum, data.ProofMarshaller{}); err == nil {
+			t.Error("Expected a short proof element to be rejected")
+		}
+	})
+}
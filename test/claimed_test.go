@@ -0,0 +1,174 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/e2e"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// countingClaimStatus counts lookups that reach the underlying status
+type countingClaimStatus struct {
+	status api.ClaimStatus
+	calls  atomic.Int32
+	err    error
+}
+
+func (c *countingClaimStatus) IsClaimed(index uint32) (bool, error) {
+	c.calls.Add(1)
+	if c.err != nil {
+		return false, c.err
+	}
+	return c.status.IsClaimed(index)
+}
+
+func TestVerifyClaimedState(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(8), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	// Deploy and fund a distributor, then claim index 3 on chain
+	key, _ := crypto.GenerateKey()
+	deployer := crypto.PubkeyToAddress(key.PublicKey)
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		deployer: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))},
+	})
+	defer backend.Close()
+	eth := backend.Client()
+	chainID := big.NewInt(1337)
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := merkle.TotalAmount(tree.Claims)
+	tokenAddr, _, token, err := e2e.DeployTestToken(auth, eth, total)
+	if err != nil {
+		t.Fatalf("Failed to deploy token: %v", err)
+	}
+	backend.Commit()
+
+	var root [32]byte
+	copy(root[:], tree.Root.Hash)
+	client := contract.NewContractClientWithBackend(eth, key, chainID)
+	distributorAddr, err := client.DeployAirdrop(tokenAddr, root)
+	if err != nil {
+		t.Fatalf("Failed to deploy distributor: %v", err)
+	}
+	backend.Commit()
+	if _, err := token.Transfer(auth, distributorAddr, total); err != nil {
+		t.Fatalf("Failed to fund distributor: %v", err)
+	}
+	backend.Commit()
+
+	claimed := tree.Claims[3]
+	proofArgs, _ := contract.DecodeProof(proofs[claimed.Address.Hex()].Proof)
+	if _, err := client.Claim(distributorAddr, claimed.Index, claimed.Address, claimed.Amount, proofArgs); err != nil {
+		t.Fatalf("Failed to claim: %v", err)
+	}
+	backend.Commit()
+
+	status, err := contract.NewClaimStatus(distributorAddr, eth)
+	if err != nil {
+		t.Fatalf("Failed to bind distributor: %v", err)
+	}
+
+	verify := func(handler http.Handler, claim merkle.AirdropClaim) map[string]interface{} {
+		t.Helper()
+		body, _ := json.Marshal(map[string]interface{}{
+			"address": claim.Address.Hex(),
+			"amount":  claim.Amount.String(),
+			"proof":   proofs[claim.Address.Hex()].Proof,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+		}
+		var response map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&response)
+		return response
+	}
+
+	t.Run("OnChain", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs, api.WithClaimedCheck(status, distributorAddr)).SetupRoutes()
+		for i, want := range map[int]bool{3: true, 4: false} {
+			response := verify(handler, tree.Claims[i])
+			if response["valid"] != true || response["alreadyClaimed"] != want {
+				t.Errorf("Claim %d: expected valid and alreadyClaimed=%v, got %v", i, want, response)
+			}
+			if response["contractAddress"] != distributorAddr.Hex() {
+				t.Errorf("Claim %d: expected contract %s, got %v", i, distributorAddr.Hex(), response["contractAddress"])
+			}
+		}
+
+		// Invalid proofs are not looked up
+		forged := tree.Claims[4]
+		forged.Amount = big.NewInt(1)
+		if response := verify(handler, forged); response["valid"] != false || response["alreadyClaimed"] != nil {
+			t.Errorf("Expected no claimed state for an invalid proof, got %v", response)
+		}
+	})
+
+	t.Run("Cached", func(t *testing.T) {
+		counting := &countingClaimStatus{status: status}
+		cached := api.NewCachedClaimStatus(counting, 16, time.Minute)
+		handler := api.MustNewAPIServer(tree, proofs, api.WithClaimedCheck(cached, distributorAddr)).SetupRoutes()
+		for i := 0; i < 3; i++ {
+			verify(handler, tree.Claims[3])
+		}
+		if calls := counting.calls.Load(); calls != 1 {
+			t.Errorf("Expected one RPC lookup, got %d", calls)
+		}
+
+		expiring := &countingClaimStatus{status: status}
+		handler = api.MustNewAPIServer(tree, proofs,
+			api.WithClaimedCheck(api.NewCachedClaimStatus(expiring, 16, 10*time.Millisecond), distributorAddr)).SetupRoutes()
+		verify(handler, tree.Claims[3])
+		time.Sleep(20 * time.Millisecond)
+		verify(handler, tree.Claims[3])
+		if calls := expiring.calls.Load(); calls != 2 {
+			t.Errorf("Expected the lookup to expire, got %d calls", calls)
+		}
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		failing := &countingClaimStatus{err: errors.New("connection refused")}
+		handler := api.MustNewAPIServer(tree, proofs, api.WithClaimedCheck(failing, distributorAddr)).SetupRoutes()
+		response := verify(handler, tree.Claims[3])
+		if response["valid"] != true || response["alreadyClaimed"] != nil {
+			t.Errorf("Expected a valid result without claimed state, got %v", response)
+		}
+	})
+
+	t.Run("Offline", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		response := verify(handler, tree.Claims[3])
+		if _, ok := response["alreadyClaimed"]; ok || response["valid"] != true {
+			t.Errorf("Expected alreadyClaimed to be omitted offline, got %v", response)
+		}
+	})
+}
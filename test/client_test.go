@@ -0,0 +1,208 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/client"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestClient(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(20), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+	handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+
+	// serve starts a server running wrap around the real API
+	serve := func(t *testing.T, wrap func(http.Handler) http.Handler, opts ...client.Option) *client.Client {
+		t.Helper()
+		server := httptest.NewServer(wrap(handler))
+		t.Cleanup(server.Close)
+		c, err := client.NewClient(server.URL, append([]client.Option{client.WithRetries(3, time.Millisecond)}, opts...)...)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		return c
+	}
+	direct := func(h http.Handler) http.Handler { return h }
+	ctx := context.Background()
+	claim := tree.Claims[5]
+	stranger := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+
+	t.Run("Endpoints", func(t *testing.T) {
+		c := serve(t, direct)
+
+		root, err := c.GetRoot(ctx)
+		if err != nil || root.MerkleRoot != tree.GetRootHash() || root.Metadata != tree.Metadata() {
+			t.Fatalf("Expected root %s with the tree's metadata, got %+v (%v)", tree.GetRootHash(), root, err)
+		}
+
+		proof, err := c.GetProof(ctx, claim.Address)
+		if err != nil {
+			t.Fatalf("GetProof failed: %v", err)
+		}
+		if proof.Index != claim.Index || proof.Amount != claim.Amount.String() {
+			t.Errorf("Expected index %d and amount %s, got %+v", claim.Index, claim.Amount, proof)
+		}
+		if valid, err := merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, root.Metadata.Options()); err != nil || !valid {
+			t.Errorf("Expected the fetched proof to verify locally, got %v", err)
+		}
+
+		verification, err := c.VerifyProof(ctx, claim, proof)
+		if err != nil || !verification.Valid || verification.AlreadyClaimed != nil {
+			t.Errorf("Expected a valid verification, got %+v (%v)", verification, err)
+		}
+		forged := claim
+		forged.Index++
+		if verification, err := c.VerifyProof(ctx, forged, proof); err != nil || verification.Valid {
+			t.Errorf("Expected a forged claim to be invalid without an error, got %+v (%v)", verification, err)
+		}
+
+		stats, err := c.GetStats(ctx)
+		if err != nil || stats.TotalClaims != 20 || stats.MerkleRoot != tree.GetRootHash() || stats.Indices.MaxIndex != 19 {
+			t.Errorf("Unexpected stats %+v (%v)", stats, err)
+		}
+
+		for address, want := range map[common.Address]bool{claim.Address: true, stranger: false} {
+			if eligible, err := c.Eligible(ctx, address); err != nil || eligible != want {
+				t.Errorf("%s: expected eligible=%v, got %v (%v)", address.Hex(), want, eligible, err)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		c := serve(t, direct)
+		_, err := c.GetProof(ctx, stranger)
+		var apiErr *client.APIError
+		if !errors.Is(err, client.ErrNotFound) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.RequestID == "" {
+			t.Errorf("Expected ErrNotFound with a request ID, got %v", err)
+		}
+
+		// The real server's answer to a malformed address
+		mangle := func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.URL.Path = "/api/eligible/0xnope"
+				h.ServeHTTP(w, r)
+			})
+		}
+		if _, err := serve(t, mangle).Eligible(ctx, claim.Address); !errors.Is(err, client.ErrInvalidAddress) {
+			t.Errorf("Expected ErrInvalidAddress, got %v", err)
+		}
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		// failing answers the first n requests with status, then serves
+		failing := func(n int32, status int, calls *atomic.Int32) func(http.Handler) http.Handler {
+			return func(h http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if calls.Add(1) <= n {
+						if status == http.StatusTooManyRequests {
+							w.Header().Set("Retry-After", "7")
+						}
+						http.Error(w, "unavailable", status)
+						return
+					}
+					h.ServeHTTP(w, r)
+				})
+			}
+		}
+
+		var calls atomic.Int32
+		c := serve(t, failing(2, http.StatusServiceUnavailable, &calls))
+		if _, err := c.GetProof(ctx, claim.Address); err != nil || calls.Load() != 3 {
+			t.Errorf("Expected success on the third attempt, got %v after %d", err, calls.Load())
+		}
+
+		calls.Store(0)
+		c = serve(t, failing(100, http.StatusInternalServerError, &calls))
+		if _, err := c.GetStats(ctx); err == nil || calls.Load() != 4 {
+			t.Errorf("Expected failure after 4 attempts, got %v after %d", err, calls.Load())
+		}
+
+		calls.Store(0)
+		c = serve(t, failing(1, http.StatusTooManyRequests, &calls), client.WithRetries(0, 0))
+		_, err := c.GetRoot(ctx)
+		var apiErr *client.APIError
+		if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.RetryAfter != 7*time.Second {
+			t.Errorf("Expected ErrRateLimited with Retry-After 7s, got %v", err)
+		}
+
+		// Waiting between retries gives way to the context
+		calls.Store(0)
+		c = serve(t, failing(100, http.StatusBadGateway, &calls), client.WithRetries(5, time.Hour))
+		timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := c.GetRoot(timeout); !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+			t.Errorf("Expected the deadline to cut the backoff short, got %v", err)
+		}
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		server := httptest.NewServer(mustServer(t)(api.NewPrecomputingAPIServer(tree)).SetupRoutes())
+		defer server.Close()
+		c, _ := client.NewClient(server.URL, client.WithRetries(0, 0))
+		_, err := c.GetProof(ctx, claim.Address)
+		var apiErr *client.APIError
+		if !errors.Is(err, client.ErrNotReady) || !errors.As(err, &apiErr) || apiErr.RetryAfter != time.Second {
+			t.Errorf("Expected ErrNotReady with Retry-After 1s, got %v", err)
+		}
+	})
+
+	t.Run("AuthToken", func(t *testing.T) {
+		var got atomic.Value
+		record := func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got.Store(r.Header.Get("Authorization"))
+				h.ServeHTTP(w, r)
+			})
+		}
+		c := serve(t, record, client.WithAuthToken("s3cret"))
+		c.GetRoot(ctx)
+		if got.Load() != "Bearer s3cret" {
+			t.Errorf("Expected a bearer token, got %v", got.Load())
+		}
+	})
+
+	t.Run("Positional", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.SortedPairs = false
+		positional, _ := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(9), opts)
+		positionalProofs, _ := positional.GenerateAllProofs()
+		server := httptest.NewServer(api.MustNewAPIServer(positional, positionalProofs).SetupRoutes())
+		defer server.Close()
+		c, _ := client.NewClient(server.URL)
+
+		claim := positional.Claims[6]
+		proof, err := c.GetProof(ctx, claim.Address)
+		if err != nil || proof.Positions != positionalProofs[claim.Address.Hex()].Positions {
+			t.Fatalf("Expected the proof's positions, got %+v (%v)", proof, err)
+		}
+		if verification, err := c.VerifyProof(ctx, claim, proof); err != nil || !verification.Valid {
+			t.Errorf("Expected a valid positional proof, got %+v (%v)", verification, err)
+		}
+	})
+
+	t.Run("BaseURL", func(t *testing.T) {
+		for _, invalid := range []string{"", "ftp://example.com", "://"} {
+			if _, err := client.NewClient(invalid); err == nil {
+				t.Errorf("Expected %q to be rejected", invalid)
+			}
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompression(t *testing.T) {
+	tree, proofs := buildProofSet(t, 2000)
+	serve := func(mode api.Compression) http.Handler {
+		return api.MustNewAPIServer(tree, proofs.Proofs, api.WithCompression(mode), api.WithBloomFilter(0.01)).SetupRoutes()
+	}
+	get := func(handler http.Handler, path, acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	// decode returns the body of w as sent before compression
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) []byte {
+		t.Helper()
+		var reader io.Reader = w.Body
+		switch coding := w.Header().Get("Content-Encoding"); coding {
+		case "":
+		case "gzip":
+			gz, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("Failed to open gzip body: %v", err)
+			}
+			reader = gz
+		case "zstd":
+			zr, err := zstd.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("Failed to open zstd body: %v", err)
+			}
+			defer zr.Close()
+			reader = zr
+		default:
+			t.Fatalf("Unexpected Content-Encoding %q", coding)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("Failed to decompress body: %v", err)
+		}
+		return body
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		handler := serve(api.CompressionAuto)
+		plain := get(handler, "/api/openapi.json", "", "")
+		if plain.Code != http.StatusOK || plain.Header().Get("Content-Encoding") != "" || plain.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("Expected an uncompressed body varying on Accept-Encoding, got %d %v", plain.Code, plain.Header())
+		}
+		if plain.Body.Len() < api.MinCompressBytes {
+			t.Fatalf("Expected a document over the threshold, got %d bytes", plain.Body.Len())
+		}
+		identity := plain.Body.Bytes()
+
+		for _, tc := range []struct{ accept, want string }{
+			{"gzip", "gzip"},
+			{"gzip, deflate, br, zstd", "zstd"},
+			{"zstd;q=0.5, gzip", "gzip"},
+			{"*", "zstd"},
+			{"gzip;q=0, zstd;q=0", ""},
+			{"br", ""},
+		} {
+			w := get(handler, "/api/openapi.json", tc.accept, "")
+			if got := w.Header().Get("Content-Encoding"); got != tc.want {
+				t.Errorf("%q: expected Content-Encoding %q, got %q", tc.accept, tc.want, got)
+				continue
+			}
+			if tc.want != "" && (w.Header().Get("Content-Length") != "" || w.Body.Len() >= len(identity)) {
+				t.Errorf("%q: expected a smaller body without Content-Length, got %d bytes of %d", tc.accept, w.Body.Len(), len(identity))
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("%q: expected Vary: Accept-Encoding, got %q", tc.accept, w.Header().Get("Vary"))
+			}
+			body := decode(t, w)
+			if !bytes.Equal(body, identity) {
+				t.Errorf("%q: expected the decompressed body to match the uncompressed one", tc.accept)
+			}
+			if !json.Valid(body) {
+				t.Errorf("%q: expected JSON", tc.accept)
+			}
+		}
+
+		// Gzip mode never sends zstd
+		if w := get(serve(api.CompressionGzip), "/api/openapi.json", "zstd", ""); w.Header().Get("Content-Encoding") != "" {
+			t.Errorf("Expected no compression for zstd in gzip mode, got %q", w.Header().Get("Content-Encoding"))
+		}
+	})
+
+	t.Run("Small", func(t *testing.T) {
+		w := get(serve(api.CompressionAuto), "/healthz", "gzip", "")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Expected a small body to be sent as it is, got %d %v", w.Code, w.Header())
+		}
+		if !json.Valid(w.Body.Bytes()) {
+			t.Errorf("Expected JSON, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("ETag", func(t *testing.T) {
+		handler := serve(api.CompressionGzip)
+		plain := get(handler, "/api/bloom", "", "")
+		etag := plain.Header().Get("ETag")
+		compressed := get(handler, "/api/bloom", "gzip", "")
+		gzipETag := compressed.Header().Get("ETag")
+		if compressed.Header().Get("Content-Encoding") != "gzip" || gzipETag != strings.TrimSuffix(etag, `"`)+`-gzip"` {
+			t.Fatalf("Expected the gzip ETag of %s, got %q %v", etag, gzipETag, compressed.Header())
+		}
+		if !bytes.Equal(decode(t, compressed), plain.Body.Bytes()) {
+			t.Error("Expected the decompressed filter to match")
+		}
+
+		for _, tc := range []struct {
+			name, accept, ifNoneMatch, etag string
+			status                          int
+		}{
+			{"Compressed", "gzip", gzipETag, gzipETag, http.StatusNotModified},
+			{"Identity", "", etag, etag, http.StatusNotModified},
+			// A cached identity response stays valid for a client that now
+			// accepts gzip
+			{"IdentityCached", "gzip", etag, etag, http.StatusNotModified},
+			{"CompressedCached", "", gzipETag, etag, http.StatusOK},
+		} {
+			w := get(handler, "/api/bloom", tc.accept, tc.ifNoneMatch)
+			if w.Code != tc.status || w.Header().Get("ETag") != tc.etag {
+				t.Errorf("%s: expected %d with ETag %s, got %d %q", tc.name, tc.status, tc.etag, w.Code, w.Header().Get("ETag"))
+			}
+		}
+	})
+
+	t.Run("Off", func(t *testing.T) {
+		w := get(serve(api.CompressionOff), "/api/openapi.json", "gzip, zstd", "")
+		if w.Header().Get("Content-Encoding") != "" || w.Header().Get("Vary") != "" {
+			t.Errorf("Expected no compression headers when off, got %v", w.Header())
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		// Streamed results are flushed per line, before any compression
+		// could pay off, and still arrive intact
+		var entries bytes.Buffer
+		for _, claim := range tree.Claims[:100] {
+			encoded, _ := json.Marshal(map[string]interface{}{
+				"address": claim.Address.Hex(),
+				"amount":  claim.Amount.String(),
+				"proof":   proofs.Proofs[claim.Address.Hex()].Proof,
+			})
+			entries.Write(append(encoded, '\n'))
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/verify/batch", &entries)
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		req.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		serve(api.CompressionGzip).ServeHTTP(w, req)
+
+		scanner := bufio.NewScanner(bytes.NewReader(decode(t, w)))
+		valid := 0
+		for scanner.Scan() {
+			var result data.BatchResult
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to decode result %q: %v", scanner.Text(), err)
+			}
+			if result.Valid {
+				valid++
+			}
+		}
+		if w.Code != http.StatusOK || valid != 100 {
+			t.Errorf("Expected 100 valid results, got %d %d", w.Code, valid)
+		}
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		for _, mode := range []api.Compression{api.CompressionOff, api.CompressionGzip, api.CompressionAuto} {
+			if parsed, err := api.ParseCompression(mode.String()); err != nil || parsed != mode {
+				t.Errorf("Expected %s to round trip, got %v (%v)", mode, parsed, err)
+			}
+		}
+		if _, err := api.ParseCompression("br"); err == nil {
+			t.Error("Expected an unknown mode to fail")
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/config"
+)
+
+func TestConfigValidateAll(t *testing.T) {
+	t.Run("DefaultIsValid", func(t *testing.T) {
+		if err := config.DefaultConfig().ValidateAll(); err != nil {
+			t.Errorf("Expected the default config to be valid, got %v", err)
+		}
+	})
+
+	rules := []struct {
+		name   string
+		mutate func(*config.Config)
+		want   string
+	}{
+		{"ServerPort", func(c *config.Config) { c.Server.Port = 70000 }, "invalid server port"},
+		{"GRPCPort", func(c *config.Config) { c.Server.GRPCPort = -1 }, "invalid grpc port"},
+		{"GRPCSamePort", func(c *config.Config) { c.Server.GRPCPort = c.Server.Port }, "grpc port must differ"},
+		{"ReadTimeout", func(c *config.Config) { c.Server.ReadTimeout = -1 }, "read_timeout"},
+		{"WriteTimeout", func(c *config.Config) { c.Server.WriteTimeout = -5 }, "write_timeout"},
+		{"MaxBodyBytes", func(c *config.Config) { c.Server.MaxBodyBytes = 0 }, "max_body_bytes"},
+		{"ProofCacheSize", func(c *config.Config) { c.Server.ProofCacheSize = -1 }, "proof_cache_size"},
+		{"BloomFPR", func(c *config.Config) { c.Server.BloomFPR = 1 }, "bloom_fpr"},
+		{"Compression", func(c *config.Config) { c.Server.Compression = "brotli" }, "invalid compression"},
+		{"BasePath", func(c *config.Config) { c.Server.BasePath = "airdrop/{id}" }, "invalid base_path"},
+		{"CORSOrigins", func(c *config.Config) { c.Server.CORSOrigins = []string{"https://claim.example.org/app"} }, "invalid cors_origins entry"},
+		{"TrustedProxies", func(c *config.Config) { c.Server.TrustedProxies = []string{"10.0.0.1"} }, "invalid trusted_proxies entry"},
+		{"ReservationGRPC", func(c *config.Config) {
+			c.Server.Reservation = true
+			c.Server.GRPCPort = 9091
+		}, "reservation is not supported"},
+		{"EligibilityOnlyGRPC", func(c *config.Config) {
+			c.Server.EligibilityOnly = true
+			c.Server.GRPCPort = 9091
+		}, "eligibility_only is not supported"},
+		{"AsyncProofsGRPC", func(c *config.Config) {
+			c.Server.AsyncProofs = true
+			c.Server.GRPCPort = 9091
+		}, "async_proofs is not supported"},
+		{"AsyncLazyProofs", func(c *config.Config) {
+			c.Server.AsyncProofs = true
+			c.Server.LazyProofs = true
+		}, "mutually exclusive"},
+		{"StaticDir", func(c *config.Config) {
+			c.Server.StaticDir = filepath.Join(t.TempDir(), "missing")
+		}, "static_dir is not readable"},
+		{"ReservationStore", func(c *config.Config) { c.Server.ReservationStore = "issued.json" }, "requires reservation"},
+		{"RPCURL", func(c *config.Config) { c.Ethereum.RPCURL = "localhost:8545" }, "invalid rpc_url"},
+		{"GasPrice", func(c *config.Config) { c.Ethereum.GasPrice = 0 }, "gas_price must be positive"},
+		{"ContractAddress", func(c *config.Config) { c.Ethereum.ContractAddress = "0x1234" }, "invalid contract_address"},
+		{"ClaimedCacheTTL", func(c *config.Config) { c.Ethereum.ClaimedCacheTTL = -1 }, "claimed_cache_ttl"},
+		{"TokenDecimals", func(c *config.Config) { c.Ethereum.TokenDecimals = 78 }, "token_decimals"},
+		{"SignerAddress", func(c *config.Config) { c.Ethereum.SignerURL = "http://signer" }, "signer_address is required"},
+		{"TokenNeedsSigner", func(c *config.Config) {
+			c.Ethereum.TokenAddress = "0x000000000000000000000000000000000000dEaD"
+		}, "required with token_address"},
+		{"MaxClaims", func(c *config.Config) { c.Merkle.MaxClaims = -1 }, "max_claims must not be negative"},
+		{"WorkerCount", func(c *config.Config) { c.Merkle.WorkerCount = -2 }, "worker_count"},
+		{"ProofBuffer", func(c *config.Config) { c.Merkle.ProofBuffer = -1 }, "proof_buffer"},
+		{"BatchSize", func(c *config.Config) { c.Merkle.BatchSize = 0 }, "batch_size"},
+		{"CacheSize", func(c *config.Config) { c.Merkle.CacheSize = 0 }, "cache_size must be positive"},
+		{"CacheTTL", func(c *config.Config) { c.Merkle.CacheTTL = -1 }, "cache_ttl must be positive"},
+		{"RebuildInterval", func(c *config.Config) { c.Merkle.RebuildInterval = -1 }, "rebuild_interval must not be negative"},
+		{"RebuildQuery", func(c *config.Config) { c.Merkle.RebuildQuery = "SELECT address, amount FROM claims" }, "requires rebuild_interval"},
+		{"RebuildLazy", func(c *config.Config) {
+			c.Merkle.RebuildInterval = 60
+			c.Server.LazyProofs = true
+		}, "not supported with lazy_proofs"},
+		{"OutputFormat", func(c *config.Config) { c.Merkle.OutputFormat = "xml" }, "invalid output format"},
+		{"ProofStore", func(c *config.Config) { c.Merkle.ProofStore = "bolt" }, "unknown proof store"},
+		{"ProofStorePath", func(c *config.Config) { c.Merkle.ProofStore = config.ProofStoreDisk }, "proof_store_path is required"},
+		{"ProofStoreLazy", func(c *config.Config) {
+			c.Merkle.ProofStore = config.ProofStoreDisk
+			c.Merkle.ProofStorePath = "proofs.store"
+			c.Server.LazyProofs = true
+		}, "not supported with lazy_proofs"},
+		{"AppendClaimsAdmin", func(c *config.Config) { c.Server.AppendClaims = true }, "append_claims requires admin_tokens"},
+		{"FinalizationAdmin", func(c *config.Config) { c.Server.FinalizationFile = "finalization.json" }, "finalization_file requires admin_tokens"},
+		{"UnlockTokens", func(c *config.Config) { c.Server.UnlockTokens = []string{"unlock"} }, "unlock_tokens requires finalization_file"},
+		{"TraceLimit", func(c *config.Config) { c.Server.TraceLimit = -1 }, "trace_limit must not be negative"},
+		{"UnlockTokensAdmin", func(c *config.Config) {
+			c.Server.AdminTokens = []string{"secret"}
+			c.Server.FinalizationFile = "finalization.json"
+			c.Server.UnlockTokens = []string{"secret"}
+		}, "unlock_tokens must differ"},
+		{"AppendClaimsRebuild", func(c *config.Config) {
+			c.Server.AppendClaims = true
+			c.Server.AdminTokens = []string{"secret"}
+			c.Merkle.RebuildInterval = 60
+		}, "append_claims is not supported with rebuild_interval"},
+		{"VersionHistory", func(c *config.Config) { c.Server.VersionHistory = true }, "version_history requires append_claims"},
+		{"DatabaseType", func(c *config.Config) { c.Database.Type = "mongo" }, "unknown database type"},
+		{"LogLevel", func(c *config.Config) { c.Logging.Level = "loud" }, "invalid log level"},
+		{"LogFormat", func(c *config.Config) { c.Logging.Format = "xml" }, "invalid log format"},
+		{"LogDirectory", func(c *config.Config) {
+			c.Logging.File = filepath.Join(t.TempDir(), "missing", "airdrop.log")
+		}, "log file directory"},
+	}
+
+	for _, rule := range rules {
+		t.Run(rule.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			rule.mutate(cfg)
+
+			err := cfg.ValidateAll()
+			if err == nil || !strings.Contains(err.Error(), rule.want) {
+				t.Errorf("Expected an error containing %q, got %v", rule.want, err)
+			}
+		})
+	}
+
+	t.Run("Accepted", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Ethereum.RPCURL = "wss://mainnet.example.org/ws"
+		cfg.Ethereum.ContractAddress = "0x000000000000000000000000000000000000dEaD"
+		cfg.Ethereum.TokenAddress = "0x000000000000000000000000000000000000bEEF"
+		cfg.Ethereum.KeystoreFile = "key.json"
+		cfg.Logging.File = filepath.Join(t.TempDir(), "airdrop.log")
+
+		if err := cfg.ValidateAll(); err != nil {
+			t.Errorf("Expected config to be valid, got %v", err)
+		}
+	})
+
+	t.Run("AllErrorsReported", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Server.Port = 0
+		cfg.Server.ReadTimeout = -1
+		cfg.Ethereum.RPCURL = "not a url"
+		cfg.Ethereum.GasPrice = -1
+		cfg.Database.Type = "oracle"
+
+		err := cfg.ValidateAll()
+		if err == nil {
+			t.Fatal("Expected validation errors")
+		}
+		joined, ok := err.(interface{ Unwrap() []error })
+		if !ok {
+			t.Fatalf("Expected joined errors, got %T", err)
+		}
+		if got := len(joined.Unwrap()); got != 5 {
+			t.Errorf("Expected 5 errors, got %d:\n%v", got, err)
+		}
+	})
+
+	t.Run("CacheWarning", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		if warnings := cfg.Warnings(); len(warnings) != 0 {
+			t.Errorf("Expected no warnings by default, got %v", warnings)
+		}
+
+		cfg.Merkle.MaxClaims = config.LargeCacheClaims + 1
+		if warnings := cfg.Warnings(); len(warnings) != 1 {
+			t.Errorf("Expected a cache warning, got %v", warnings)
+		}
+		if err := cfg.ValidateAll(); err != nil {
+			t.Errorf("Expected a warning only, got %v", err)
+		}
+	})
+}
+
+func TestLoadConfigKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"port": 9090}}`), 0o644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Server.Port != 9090 {
+		t.Errorf("Expected port 9090, got %d", cfg.Server.Port)
+	}
+	if cfg.Merkle.MaxClaims != config.DefaultConfig().Merkle.MaxClaims {
+		t.Errorf("Expected default max_claims, got %d", cfg.Merkle.MaxClaims)
+	}
+	if err := cfg.ValidateAll(); err != nil {
+		t.Errorf("Expected a partial config to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,223 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestConsistency(t *testing.T) {
+	opts := merkle.DefaultTreeOptions()
+	opts.KeepIndices = true
+	claims := data.GenerateTestData(10)
+	oldTree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	// The next version: claims[1] changes, claims[2] is removed and one is added
+	var next []merkle.AirdropClaim
+	for i, claim := range oldTree.Claims {
+		switch i {
+		case 1:
+			claim.Amount = new(big.Int).Add(claim.Amount, big.NewInt(1))
+		case 2:
+			continue
+		}
+		next = append(next, claim)
+	}
+	added := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	next = append(next, merkle.AirdropClaim{Address: added, Amount: big.NewInt(500), Index: 10})
+	newTree, err := merkle.NewMerkleTreeWithOptions(next, opts)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	unchanged, changed, removed := oldTree.Claims[0], oldTree.Claims[1], oldTree.Claims[2]
+
+	// difference proves address expecting its claim to differ
+	difference := func(t *testing.T, address common.Address) *merkle.DifferenceProof {
+		t.Helper()
+		_, err := merkle.ProveConsistency(oldTree, newTree, address)
+		var changedErr *merkle.AllocationChangedError
+		if !errors.Is(err, merkle.ErrAllocationChanged) || !errors.As(err, &changedErr) {
+			t.Fatalf("Expected an AllocationChangedError, got %v", err)
+		}
+		dp := changedErr.Difference
+		if valid, err := merkle.VerifyDifference(dp); !valid || err != nil {
+			t.Errorf("Expected the difference proof to verify, got %v (%v)", valid, err)
+		}
+		return dp
+	}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		cp, err := merkle.ProveConsistency(oldTree, newTree, unchanged.Address)
+		if err != nil {
+			t.Fatalf("Failed to prove: %v", err)
+		}
+		if cp.Amount != unchanged.Amount.String() || cp.Index != unchanged.Index || cp.OldRoot != oldTree.GetRootHash() || cp.NewRoot != newTree.GetRootHash() {
+			t.Errorf("Expected the claim under both roots, got %+v", cp)
+		}
+		if valid, err := merkle.VerifyConsistency(cp); !valid || err != nil {
+			t.Fatalf("Expected the proof to verify, got %v (%v)", valid, err)
+		}
+
+		// A proof survives JSON, as served and written by the CLI
+		encoded, _ := json.Marshal(cp)
+		var decoded merkle.ConsistencyProof
+		json.Unmarshal(encoded, &decoded)
+		if valid, err := merkle.VerifyConsistency(&decoded); !valid || err != nil {
+			t.Errorf("Expected the decoded proof to verify, got %v (%v)", valid, err)
+		}
+
+		tampered := decoded
+		tampered.Amount = "1"
+		if valid, _ := merkle.VerifyConsistency(&tampered); valid {
+			t.Error("Expected a proof claiming another amount to fail")
+		}
+		tampered = decoded
+		newProof := *decoded.NewProof
+		newProof.Proof = decoded.OldProof.Proof
+		tampered.NewProof = &newProof
+		if valid, _ := merkle.VerifyConsistency(&tampered); valid {
+			t.Error("Expected the old path under the new root to fail")
+		}
+		tampered = decoded
+		tampered.OldRoot, tampered.NewRoot = decoded.NewRoot, decoded.OldRoot
+		if _, err := merkle.VerifyConsistency(&tampered); !errors.Is(err, merkle.ErrRootMismatch) {
+			t.Errorf("Expected swapped roots to be ErrRootMismatch, got %v", err)
+		}
+	})
+
+	t.Run("Changed", func(t *testing.T) {
+		dp := difference(t, changed.Address)
+		if dp.Old == nil || dp.New == nil || dp.Old.Amount != changed.Amount.String() || dp.New.Amount == dp.Old.Amount {
+			t.Errorf("Expected both claims, got %+v and %+v", dp.Old, dp.New)
+		}
+		same := *dp
+		same.New = dp.Old
+		if valid, _ := merkle.VerifyDifference(&same); valid {
+			t.Error("Expected a difference proof of equal claims to fail")
+		}
+	})
+
+	t.Run("Added", func(t *testing.T) {
+		dp := difference(t, added)
+		if dp.Old != nil || dp.New == nil || dp.New.Index != 10 {
+			t.Errorf("Expected only the new claim, got %+v and %+v", dp.Old, dp.New)
+		}
+		_, err := merkle.ProveConsistency(oldTree, newTree, added)
+		if !strings.Contains(err.Error(), "was added") {
+			t.Errorf("Expected the error to say the claim was added, got %v", err)
+		}
+	})
+
+	t.Run("Removed", func(t *testing.T) {
+		dp := difference(t, removed.Address)
+		if dp.Old == nil || dp.New != nil || dp.Old.Index != removed.Index {
+			t.Errorf("Expected only the old claim, got %+v and %+v", dp.Old, dp.New)
+		}
+	})
+
+	t.Run("Neither", func(t *testing.T) {
+		missing := common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		if _, err := merkle.ProveConsistency(oldTree, newTree, missing); !errors.Is(err, merkle.ErrAddressNotFound) {
+			t.Errorf("Expected ErrAddressNotFound, got %v", err)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		const token = "admin-secret"
+		start := func(t *testing.T, history bool) *rebuild.Appender {
+			var appender *rebuild.Appender
+			serve := func(tree *merkle.MerkleTree) (http.Handler, error) {
+				proofs, err := tree.GenerateAllProofs()
+				if err != nil {
+					return nil, err
+				}
+				serverOpts := []api.Option{api.WithAdminTokens([]string{token}), api.WithClaimAppender(appender)}
+				if history {
+					serverOpts = append(serverOpts, api.WithTreeHistory(appender))
+				}
+				return api.MustNewAPIServer(tree, proofs, serverOpts...).SetupRoutes(), nil
+			}
+			var appenderOpts []rebuild.AppenderOption
+			if history {
+				appenderOpts = append(appenderOpts, rebuild.KeepTrees())
+			}
+			appender = rebuild.NewAppender(serve, nil, appenderOpts...)
+			if err := appender.Start(oldTree); err != nil {
+				t.Fatalf("Failed to start: %v", err)
+			}
+			if _, err := appender.AppendClaims([]merkle.AirdropClaim{{Address: added, Amount: big.NewInt(500)}}); err != nil {
+				t.Fatalf("Failed to append: %v", err)
+			}
+			return appender
+		}
+		get := func(handler http.Handler, path string) (int, []byte) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			return w.Code, w.Body.Bytes()
+		}
+		errorCode := func(body []byte) string {
+			var apiErr api.ErrorResponse
+			json.Unmarshal(body, &apiErr)
+			return apiErr.Error.Code
+		}
+		appender := start(t, true)
+
+		status, body := get(appender, "/api/consistency/"+unchanged.Address.Hex()+"?from=1&to=2")
+		var response api.ConsistencyResponse
+		json.Unmarshal(body, &response)
+		if status != http.StatusOK || response.Changed || response.Consistency == nil || response.From != 1 || response.To != 2 {
+			t.Fatalf("Expected a consistency proof, got %d %s", status, body)
+		}
+		if valid, err := merkle.VerifyConsistency(response.Consistency); !valid || err != nil {
+			t.Errorf("Expected the served proof to verify, got %v (%v)", valid, err)
+		}
+
+		status, body = get(appender, "/api/consistency/"+added.Hex()+"?from=1&to=2")
+		response = api.ConsistencyResponse{}
+		json.Unmarshal(body, &response)
+		if status != http.StatusOK || !response.Changed || response.Difference == nil || response.Difference.Old != nil {
+			t.Fatalf("Expected a difference proof for the appended claim, got %d %s", status, body)
+		}
+
+		for _, tc := range []struct {
+			path   string
+			status int
+			code   string
+		}{
+			{"/api/consistency/0x1234?from=1&to=2", http.StatusBadRequest, api.CodeInvalidAddress},
+			{"/api/consistency/" + unchanged.Address.Hex() + "?from=one&to=2", http.StatusBadRequest, api.CodeInvalidParameter},
+			{"/api/consistency/" + unchanged.Address.Hex() + "?to=2", http.StatusBadRequest, api.CodeInvalidParameter},
+			{"/api/consistency/" + unchanged.Address.Hex() + "?from=1&to=3", http.StatusNotFound, api.CodeVersionNotFound},
+			{"/api/consistency/0x00000000000000000000000000000000deadbeef?from=1&to=2", http.StatusNotFound, api.CodeAddressNotFound},
+		} {
+			if status, body := get(appender, tc.path); status != tc.status || errorCode(body) != tc.code {
+				t.Errorf("%s: expected %d %s, got %d %s", tc.path, tc.status, tc.code, status, body)
+			}
+		}
+
+		// Without the history only the served version is held
+		if tree, ok := start(t, false).TreeAt(1); ok || tree != nil {
+			t.Error("Expected past trees to be dropped without KeepTrees")
+		}
+		if tree, ok := appender.TreeAt(1); !ok || tree.GetRootHash() != oldTree.GetRootHash() {
+			t.Error("Expected version 1 to be kept")
+		}
+		if status, _ := get(start(t, false), "/api/consistency/"+unchanged.Address.Hex()+"?from=1&to=2"); status != http.StatusNotFound {
+			t.Errorf("Expected no consistency route without the history, got %d", status)
+		}
+	})
+}
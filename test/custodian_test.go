@@ -0,0 +1,192 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestAggregateByCustodian(t *testing.T) {
+	claims := data.GenerateTestData(8)
+	exchangeA := common.HexToAddress("0x00000000000000000000000000000000000a0001")
+	exchangeB := common.HexToAddress("0x00000000000000000000000000000000000b0001")
+	custodians := map[common.Address]common.Address{
+		claims[1].Address: exchangeA,
+		claims[4].Address: exchangeA,
+		claims[6].Address: exchangeA,
+		claims[2].Address: exchangeB,
+		claims[7].Address: exchangeB,
+		// Users without a claim are ignored
+		common.HexToAddress("0x00000000000000000000000000000000000c0001"): exchangeB,
+	}
+	amounts := make([]string, len(claims))
+	for i, claim := range claims {
+		amounts[i] = claim.Amount.String()
+	}
+
+	aggregated, users, err := data.AggregateByCustodian(claims, custodians)
+	if err != nil {
+		t.Fatalf("Failed to aggregate: %v", err)
+	}
+	for i, claim := range claims {
+		if claim.Amount.String() != amounts[i] {
+			t.Fatalf("Expected claim %d's amount to be left alone, got %s", i, claim.Amount)
+		}
+	}
+
+	// Pass-through users keep their place between the custodians, which take
+	// the place of their first user
+	want := []common.Address{claims[0].Address, exchangeA, exchangeB, claims[3].Address, claims[5].Address}
+	if len(aggregated) != len(want) {
+		t.Fatalf("Expected %d claims, got %d", len(want), len(aggregated))
+	}
+	for i, claim := range aggregated {
+		if claim.Address != want[i] || claim.Index != uint32(i) {
+			t.Errorf("Claim %d: expected %s at index %d, got %s at %d", i, want[i].Hex(), i, claim.Address.Hex(), claim.Index)
+		}
+	}
+	if merkle.TotalAmount(aggregated).Cmp(merkle.TotalAmount(claims)) != 0 {
+		t.Errorf("Expected the aggregated total %s, got %s", merkle.TotalAmount(claims), merkle.TotalAmount(aggregated))
+	}
+	if aggregated[3].Amount.Cmp(claims[3].Amount) != 0 {
+		t.Errorf("Expected a pass-through claim's amount as it was, got %s", aggregated[3].Amount)
+	}
+	if len(users) != 2 || len(users[exchangeA]) != 3 || len(users[exchangeB]) != 2 {
+		t.Fatalf("Expected three users at A and two at B, got %d and %d", len(users[exchangeA]), len(users[exchangeB]))
+	}
+	if users[exchangeA][1].Address != claims[4].Address {
+		t.Errorf("Expected users in claim order, got %s", users[exchangeA][1].Address.Hex())
+	}
+	if sum := new(big.Int).Add(claims[2].Amount, claims[7].Amount); aggregated[2].Amount.Cmp(sum) != 0 {
+		t.Errorf("Expected B's claim for %s, got %s", sum, aggregated[2].Amount)
+	}
+	if err := data.CheckCustodianAllocations(aggregated, users); err != nil {
+		t.Errorf("Expected the allocations to check out: %v", err)
+	}
+
+	t.Run("Export", func(t *testing.T) {
+		tree, err := merkle.NewMerkleTreeWithOptions(aggregated, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		proofs, _ := tree.GenerateAllProofs()
+		proofSet := &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}
+
+		var buf bytes.Buffer
+		if err := data.ExportCustodianAllocation(proofSet, tree.GetRootHash(), exchangeA, users[exchangeA], &buf); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		var file struct {
+			MerkleRoot string               `json:"merkleRoot"`
+			Custodian  string               `json:"custodian"`
+			Claim      *merkle.MerkleProof  `json:"claim"`
+			Users      []data.CustodianUser `json:"users"`
+			TotalUsers int                  `json:"totalUsers"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &file); err != nil {
+			t.Fatalf("Failed to decode export: %v", err)
+		}
+		if file.MerkleRoot != tree.GetRootHash() || file.Custodian != exchangeA.Hex() || file.TotalUsers != 3 || len(file.Users) != 3 {
+			t.Fatalf("Unexpected export: %s", buf.String())
+		}
+		sum := new(big.Int)
+		for i, user := range file.Users {
+			amount, _ := new(big.Int).SetString(user.Amount, 10)
+			sum.Add(sum, amount)
+			if user.Address != users[exchangeA][i].Address.Hex() {
+				t.Errorf("User %d: expected %s, got %s", i, users[exchangeA][i].Address.Hex(), user.Address)
+			}
+		}
+		if sum.String() != file.Claim.Amount {
+			t.Errorf("Expected the users to add up to the claim's %s, got %s", file.Claim.Amount, sum)
+		}
+
+		claim := merkle.AirdropClaim{Address: exchangeA, Amount: sum, Index: file.Claim.Index}
+		root := hexutil.MustDecode(tree.GetRootHash())
+		if valid, err := merkle.VerifyProofWithPositions(root, claim, file.Claim.Proof, file.Claim.Positions, tree.Options()); !valid || err != nil {
+			t.Errorf("Expected the custodian's proof to verify (%v)", err)
+		}
+
+		// A breakdown that doesn't match the leaf is refused
+		short := data.CloneClaims(users[exchangeA][:2])
+		if err := data.ExportCustodianAllocation(proofSet, tree.GetRootHash(), exchangeA, short, &buf); err == nil {
+			t.Error("Expected a breakdown short of the claim to fail")
+		}
+		if err := data.ExportCustodianAllocation(proofSet, tree.GetRootHash(), claims[1].Address, users[exchangeA], &buf); err == nil {
+			t.Error("Expected a custodian without a leaf to fail")
+		}
+	})
+
+	t.Run("CheckMismatch", func(t *testing.T) {
+		tampered := data.CloneClaims(aggregated)
+		tampered[1].Amount.Add(tampered[1].Amount, big.NewInt(1))
+		if err := data.CheckCustodianAllocations(tampered, users); err == nil || !strings.Contains(err.Error(), exchangeA.Hex()) {
+			t.Errorf("Expected a sum one unit off to fail for A, got %v", err)
+		}
+		if err := data.CheckCustodianAllocations(aggregated[:2], users); err == nil {
+			t.Error("Expected a custodian without a claim to fail")
+		}
+	})
+
+	t.Run("CustodianOwnClaim", func(t *testing.T) {
+		own := data.CloneClaims(claims[:3])
+		own = append(own, merkle.AirdropClaim{Address: exchangeA, Amount: big.NewInt(5)})
+		aggregated, users, err := data.AggregateByCustodian(own, map[common.Address]common.Address{own[1].Address: exchangeA})
+		if err != nil {
+			t.Fatalf("Failed to aggregate: %v", err)
+		}
+		if len(aggregated) != 3 || len(users[exchangeA]) != 2 {
+			t.Fatalf("Expected the custodian's own claim folded into its sum, got %d claims and %d users", len(aggregated), len(users[exchangeA]))
+		}
+		if sum := new(big.Int).Add(claims[1].Amount, big.NewInt(5)); aggregated[1].Amount.Cmp(sum) != 0 {
+			t.Errorf("Expected %s, got %s", sum, aggregated[1].Amount)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, mapping := range map[string]map[common.Address]common.Address{
+			"Self":  {claims[1].Address: claims[1].Address},
+			"Chain": {claims[1].Address: exchangeA, exchangeA: exchangeB},
+		} {
+			if _, _, err := data.AggregateByCustodian(claims, mapping); err == nil {
+				t.Errorf("%s: expected the mapping to fail", name)
+			}
+		}
+		repeated := append(data.CloneClaims(claims), claims[3])
+		var duplicate *data.DuplicateAddressError
+		if _, _, err := data.AggregateByCustodian(repeated, custodians); !errors.As(err, &duplicate) || duplicate.Address != claims[3].Address {
+			t.Errorf("Expected a DuplicateAddressError, got %v", err)
+		}
+	})
+
+	t.Run("LoadCSV", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "custodians.csv")
+		os.WriteFile(path, []byte("user,custodian\n"+strings.ToLower(claims[1].Address.Hex())+","+exchangeA.Hex()+"\n"+claims[2].Address.Hex()+","+exchangeB.Hex()+"\n"), 0644)
+		loaded, err := data.LoadCustodiansFromCSV(path)
+		if err != nil || len(loaded) != 2 || loaded[claims[1].Address] != exchangeA {
+			t.Fatalf("Expected two mappings, got %v (%v)", loaded, err)
+		}
+
+		os.WriteFile(path, []byte("user,custodian\n"+claims[1].Address.Hex()+","+exchangeA.Hex()+"\n"+claims[1].Address.Hex()+","+exchangeB.Hex()+"\n"), 0644)
+		var rowErr *data.RowError
+		if _, err := data.LoadCustodiansFromCSV(path); !errors.As(err, &rowErr) || rowErr.Row != 3 {
+			t.Errorf("Expected the repeated user on row 3 to fail, got %v", err)
+		}
+		os.WriteFile(path, []byte("user,custodian\n"+claims[1].Address.Hex()+",exchange\n"), 0644)
+		if _, err := data.LoadCustodiansFromCSV(path); !errors.As(err, &rowErr) {
+			t.Errorf("Expected an invalid custodian to fail, got %v", err)
+		}
+	})
+}
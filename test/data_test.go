@@ -0,0 +1,90 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// writeCSV writes a claims CSV with the given rows under a fresh temp dir
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "claims.csv")
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	return filename
+}
+
+func TestDuplicateClaims(t *testing.T) {
+	// One row per quest completed; 0xaaaa... appears three times
+	filename := writeCSV(t, `address,amount
+0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa,1000000000000000001
+0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB,5
+0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa,2000000000000000002
+0xcCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC,7
+0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa,3000000000000000003
+`)
+	repeated := common.HexToAddress("0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa")
+
+	claims, err := data.LoadAirdropFromCSV(filename)
+	if err != nil {
+		t.Fatalf("Failed to load CSV: %v", err)
+	}
+
+	t.Run("Sum", func(t *testing.T) {
+		aggregated, err := data.ApplyDuplicatePolicy(claims, data.DuplicateSum)
+		if err != nil {
+			t.Fatalf("Failed to aggregate: %v", err)
+		}
+		if len(aggregated) != 3 {
+			t.Fatalf("Expected 3 claims, got %d", len(aggregated))
+		}
+
+		// First-seen order is preserved
+		if aggregated[0].Address != repeated || aggregated[0].Index != 0 {
+			t.Errorf("Expected repeated address first with index 0, got %s index %d", aggregated[0].Address.Hex(), aggregated[0].Index)
+		}
+		if got := aggregated[0].Amount.String(); got != "6000000000000000006" {
+			t.Errorf("Expected summed amount 6000000000000000006, got %s", got)
+		}
+		if aggregated[1].Amount.String() != "5" || aggregated[2].Amount.String() != "7" {
+			t.Errorf("Expected other amounts unchanged, got %s and %s", aggregated[1].Amount, aggregated[2].Amount)
+		}
+
+		if claims[0].Amount.String() != "1000000000000000001" {
+			t.Errorf("Expected input amount untouched, got %s", claims[0].Amount)
+		}
+	})
+
+	t.Run("KeepFirst", func(t *testing.T) {
+		deduplicated, err := data.ApplyDuplicatePolicy(claims, data.DuplicateKeepFirst)
+		if err != nil {
+			t.Fatalf("Failed to deduplicate: %v", err)
+		}
+		if len(deduplicated) != 3 || deduplicated[0].Amount.String() != "1000000000000000001" {
+			t.Errorf("Expected first amount kept, got %v", deduplicated)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		if _, err := data.ApplyDuplicatePolicy(claims, data.DuplicateError); err == nil {
+			t.Error("Expected error for duplicate addresses")
+		}
+	})
+
+	t.Run("ParsePolicy", func(t *testing.T) {
+		for _, name := range []string{"error", "keep-first", "sum"} {
+			if _, err := data.ParseDuplicatePolicy(name); err != nil {
+				t.Errorf("Expected %s to parse: %v", name, err)
+			}
+		}
+		if _, err := data.ParseDuplicatePolicy("merge"); err == nil {
+			t.Error("Expected error for unknown policy")
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+)
+
+// fakeResult is the canned result for the one query a fakeConnector expects
+type fakeResult struct {
+	query   string
+	columns []string
+	rows    [][]driver.Value
+}
+
+// fakeConnector is a minimal sqlmock-style driver: it answers the expected
+// query with canned rows and fails any other
+type fakeConnector struct {
+	result fakeResult
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return fakeDriver{c} }
+
+type fakeDriver struct{ c *fakeConnector }
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d.c}, nil }
+
+type fakeConn struct{ c *fakeConnector }
+
+func (fc *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("prepare not supported")
+}
+func (fc *fakeConn) Close() error              { return nil }
+func (fc *fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("transactions not supported") }
+
+func (fc *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query != fc.c.result.query {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	return &fakeRows{columns: fc.c.result.columns, rows: fc.c.result.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestLoadAirdropFromDB(t *testing.T) {
+	const query = "SELECT address, amount FROM allowlist ORDER BY id"
+	columns := []string{"address", "amount"}
+
+	open := func(result fakeResult) *sql.DB {
+		db := sql.OpenDB(&fakeConnector{result: result})
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	t.Run("ResultOrder", func(t *testing.T) {
+		db := open(fakeResult{query: query, columns: columns, rows: [][]driver.Value{
+			{"0x3333333333333333333333333333333333333333", "300"},
+			{"0x1111111111111111111111111111111111111111", []byte("100")},
+			{"0x2222222222222222222222222222222222222222", "200000000000000000000000"},
+		}})
+
+		claims, err := data.LoadAirdropFromDB(context.Background(), db, query)
+		if err != nil {
+			t.Fatalf("Failed to load claims: %v", err)
+		}
+		if len(claims) != 3 {
+			t.Fatalf("Expected 3 claims, got %d", len(claims))
+		}
+
+		want := []string{"0x3333333333333333333333333333333333333333", "0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"}
+		for i, claim := range claims {
+			if claim.Index != uint32(i) || !strings.EqualFold(claim.Address.Hex(), want[i]) {
+				t.Errorf("Claim %d: expected %s at index %d, got %s at %d", i, want[i], i, claim.Address.Hex(), claim.Index)
+			}
+		}
+		if claims[2].Amount.String() != "200000000000000000000000" {
+			t.Errorf("Expected amount 200000000000000000000000, got %s", claims[2].Amount)
+		}
+	})
+
+	t.Run("BadRowsReportRowNumber", func(t *testing.T) {
+		tests := []struct {
+			name string
+			row  []driver.Value
+			want string
+		}{
+			{"InvalidAddress", []driver.Value{"0x1234", "1"}, "row 2: invalid address"},
+			{"InvalidAmount", []driver.Value{"0x2222222222222222222222222222222222222222", "1.5"}, "row 2: invalid amount"},
+			{"NullAmount", []driver.Value{"0x2222222222222222222222222222222222222222", nil}, "row 2: address and amount must not be NULL"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				db := open(fakeResult{query: query, columns: columns, rows: [][]driver.Value{
+					{"0x1111111111111111111111111111111111111111", "1"},
+					tt.row,
+				}})
+
+				_, err := data.LoadAirdropFromDB(context.Background(), db, query)
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("Expected error containing %q, got %v", tt.want, err)
+				}
+			})
+		}
+	})
+
+	t.Run("WrongColumnCount", func(t *testing.T) {
+		db := open(fakeResult{query: query, columns: []string{"address"}, rows: [][]driver.Value{{"0x1111111111111111111111111111111111111111"}}})
+		if _, err := data.LoadAirdropFromDB(context.Background(), db, query); err == nil {
+			t.Error("Expected error for a single-column result")
+		}
+	})
+
+	t.Run("QueryError", func(t *testing.T) {
+		db := open(fakeResult{query: query, columns: columns})
+		if _, err := data.LoadAirdropFromDB(context.Background(), db, "SELECT 1"); err == nil {
+			t.Error("Expected error for a failing query")
+		}
+	})
+}
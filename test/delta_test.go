@@ -0,0 +1,106 @@
+package test
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestProofsDelta(t *testing.T) {
+	// build returns the proof set and root of claims
+	build := func(claims []merkle.AirdropClaim, opts merkle.TreeOptions) (*merkle.ProofSet, string) {
+		t.Helper()
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		set, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		return set, tree.GetRootHash()
+	}
+	// roundTrip exports the delta from old to new and applies it to old
+	roundTrip := func(old, new *merkle.ProofSet, oldRoot, newRoot string) (*merkle.ProofSet, string, error) {
+		t.Helper()
+		var delta bytes.Buffer
+		if err := data.ExportProofsDelta(old, new, oldRoot, newRoot, &delta); err != nil {
+			t.Fatalf("Failed to export delta: %v", err)
+		}
+		return data.ApplyProofsDelta(old, &delta)
+	}
+
+	claims := data.GenerateTestData(300)
+	next := append([]merkle.AirdropClaim(nil), claims[3:]...) // Three removed
+	next[10].Amount = new(big.Int).Add(next[10].Amount, big.NewInt(1))
+	next = append(next, data.GenerateTestData(305)[300:]...) // Five added
+
+	for name, opts := range map[string]merkle.TreeOptions{
+		"Sorted":     merkle.DefaultTreeOptions(),
+		"Positional": {CopyClaims: true, IncludeIndex: true, OddLeafPolicy: merkle.Promote},
+	} {
+		t.Run(name, func(t *testing.T) {
+			oldSet, oldRoot := build(claims, opts)
+			newSet, newRoot := build(next, opts)
+
+			applied, root, err := roundTrip(oldSet, newSet, oldRoot, newRoot)
+			if err != nil {
+				t.Fatalf("Failed to apply delta: %v", err)
+			}
+			if root != newRoot || !reflect.DeepEqual(applied, newSet) {
+				t.Errorf("Expected the applied delta to give the new proof set under %s, got %s", newRoot, root)
+			}
+		})
+	}
+
+	oldSet, oldRoot := build(claims, merkle.DefaultTreeOptions())
+
+	t.Run("Unchanged", func(t *testing.T) {
+		var delta bytes.Buffer
+		data.ExportProofsDelta(oldSet, oldSet, oldRoot, oldRoot, &delta)
+		if !strings.Contains(delta.String(), `"proofs": {}`) || !strings.Contains(delta.String(), `"removed": []`) {
+			t.Errorf("Expected an empty delta, got %s", delta.String())
+		}
+		applied, root, err := data.ApplyProofsDelta(oldSet, &delta)
+		if err != nil || root != oldRoot || !reflect.DeepEqual(applied, oldSet) {
+			t.Errorf("Expected the base back, got %v", err)
+		}
+	})
+
+	t.Run("WrongBase", func(t *testing.T) {
+		newSet, newRoot := build(next, merkle.DefaultTreeOptions())
+		var delta bytes.Buffer
+		data.ExportProofsDelta(oldSet, newSet, oldRoot, newRoot, &delta)
+
+		// An unrelated base doesn't have the removed addresses
+		other, _ := build(data.GenerateTestData(300)[100:], merkle.DefaultTreeOptions())
+		if _, _, err := data.ApplyProofsDelta(other, bytes.NewReader(delta.Bytes())); err == nil {
+			t.Error("Expected a delta applied to another base to fail")
+		}
+
+		// A base whose carried-over proofs differ fails verification
+		same, _ := build(claims, merkle.DefaultTreeOptions())
+		var empty bytes.Buffer
+		data.ExportProofsDelta(same, same, oldRoot, oldRoot, &empty)
+		for _, proof := range same.Proofs {
+			proof.Amount = "1"
+		}
+		if _, _, err := data.ApplyProofsDelta(same, &empty); err == nil || !strings.Contains(err.Error(), "does not verify") {
+			t.Errorf("Expected a tampered base to fail verification, got %v", err)
+		}
+	})
+
+	t.Run("InvalidRoot", func(t *testing.T) {
+		if err := data.ExportProofsDelta(oldSet, oldSet, "0x1234", oldRoot, &bytes.Buffer{}); err == nil {
+			t.Error("Expected an invalid base root to be rejected")
+		}
+		if _, _, err := data.ApplyProofsDelta(oldSet, strings.NewReader(`{"merkleRoot": "nope"}`)); err == nil {
+			t.Error("Expected a delta without a valid root to be rejected")
+		}
+	})
+}
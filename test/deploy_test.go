@@ -0,0 +1,125 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/contract"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+func TestDeployToChains(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	signer := contract.NewKeySigner(key)
+
+	// Two chains that mine a block every few milliseconds
+	backends := make(map[string]*simulated.Backend)
+	for _, name := range []string{"ethereum", "base"} {
+		backend := simulated.NewBackend(types.GenesisAlloc{
+			signer.Address(): {Balance: new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))},
+		})
+		defer backend.Close()
+		backends[name] = backend
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				for _, backend := range backends {
+					backend.Commit()
+				}
+			}
+		}
+	}()
+
+	chainID, err := backends["ethereum"].Client().ChainID(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read chain ID: %v", err)
+	}
+	chains := []contract.ChainConfig{
+		{Name: "ethereum", ChainID: chainID.Uint64(), GasPrice: 2000000000, Signer: signer, Backend: backends["ethereum"].Client()},
+		{Name: "base", ChainID: chainID.Uint64(), GasLimit: 2500000, Signer: signer, Backend: backends["base"].Client()},
+		// Nothing listens here, so this chain fails without affecting the others
+		{Name: "arbitrum", RPCURL: "http://127.0.0.1:1", ChainID: 42161, Attempts: 1, Signer: signer},
+	}
+	tokens := map[string]common.Address{
+		"ethereum": common.HexToAddress("0x01"),
+		"base":     common.HexToAddress("0x02"),
+		"arbitrum": common.HexToAddress("0x03"),
+	}
+	root := [32]byte{0xab, 0xcd}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	results, err := contract.DeployToChains(ctx, chains, tokens, root)
+	if err != nil {
+		t.Fatalf("DeployToChains failed: %v", err)
+	}
+
+	for name, backend := range backends {
+		result := results[name]
+		if result.Error != "" {
+			t.Fatalf("Expected %s to deploy, got %s", name, result.Error)
+		}
+		if result.BlockNumber == 0 || result.TxHash == (common.Hash{}) || result.Attempts != 1 {
+			t.Errorf("Expected %s to record its transaction and block, got %+v", name, result)
+		}
+
+		distributor, err := contract.NewMerkleDistributor(result.Contract, backend.Client())
+		if err != nil {
+			t.Fatalf("Failed to bind %s distributor: %v", name, err)
+		}
+		onChainRoot, err := distributor.MerkleRoot(nil)
+		if err != nil || onChainRoot != root {
+			t.Errorf("Expected root 0x%x on %s, got 0x%x (%v)", root, name, onChainRoot, err)
+		}
+		token, err := distributor.Token(nil)
+		if err != nil || token != tokens[name] {
+			t.Errorf("Expected token %s on %s, got %s (%v)", tokens[name].Hex(), name, token.Hex(), err)
+		}
+	}
+
+	if failed := contract.FailedChains(results); len(failed) != 1 || failed[0] != "arbitrum" {
+		t.Errorf("Expected only arbitrum to fail, got %v", failed)
+	}
+
+	t.Run("Save", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "deployments.json")
+		if err := contract.SaveDeployments(results, path); err != nil {
+			t.Fatalf("Failed to save deployments: %v", err)
+		}
+		content, _ := os.ReadFile(path)
+		var saved map[string]contract.DeployResult
+		if err := json.Unmarshal(content, &saved); err != nil {
+			t.Fatalf("Failed to decode deployments: %v", err)
+		}
+		if saved["base"] != results["base"] || saved["arbitrum"].Error == "" {
+			t.Errorf("Expected the results to round-trip, got %+v", saved)
+		}
+	})
+
+	t.Run("InvalidInput", func(t *testing.T) {
+		if _, err := contract.DeployToChains(ctx, chains[:1], map[string]common.Address{}, root); err == nil {
+			t.Error("Expected a chain without a token to be rejected")
+		}
+		if _, err := contract.DeployToChains(ctx, []contract.ChainConfig{chains[0], chains[0]}, tokens, root); err == nil {
+			t.Error("Expected a repeated chain to be rejected")
+		}
+	})
+}
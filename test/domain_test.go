@@ -0,0 +1,159 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestDomainSeparator(t *testing.T) {
+	claims := data.GenerateTestData(16)
+	build := func(domain []byte) (*merkle.MerkleTree, *merkle.ProofSet) {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		opts.DomainSeparator = domain
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		proofs, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		return tree, proofs
+	}
+
+	plain, _ := build(nil)
+	season1, proofs1 := build(merkle.DomainSeparatorFor("season-1"))
+	season2, _ := build(merkle.DomainSeparatorFor("season-2"))
+
+	t.Run("Roots", func(t *testing.T) {
+		if season1.GetRootHash() == season2.GetRootHash() || season1.GetRootHash() == plain.GetRootHash() {
+			t.Fatal("Expected every domain to give its own root")
+		}
+		// No separator hashes leaves as before it existed
+		if empty, _ := build([]byte{}); empty.GetRootHash() != plain.GetRootHash() {
+			t.Error("Expected an empty separator to keep the plain root")
+		}
+		if plain.Metadata() != merkle.DefaultMetadata() {
+			t.Errorf("Expected plain trees to keep the default metadata, got %+v", plain.Metadata())
+		}
+	})
+
+	t.Run("CrossVerification", func(t *testing.T) {
+		claim := season1.Claims[5]
+		proof := proofs1.Proofs[claim.Address.Hex()]
+		for name, tc := range map[string]struct {
+			root  *merkle.MerkleTree
+			valid bool
+		}{
+			"SameDomain":    {season1, true},
+			"OtherDomain":   {season2, false},
+			"WithoutDomain": {plain, false},
+		} {
+			valid, err := merkle.VerifyProof(tc.root.Root.Hash, claim, proof.Proof, tc.root.Options())
+			if err != nil || valid != tc.valid {
+				t.Errorf("%s: expected valid=%v, got %v (%v)", name, tc.valid, valid, err)
+			}
+		}
+		// The right root is not enough without the domain
+		if valid, _ := merkle.VerifyProof(season1.Root.Hash, claim, proof.Proof, merkle.DefaultTreeOptions()); valid {
+			t.Error("Expected the proof to need its domain")
+		}
+	})
+
+	t.Run("Leaves", func(t *testing.T) {
+		claim := claims[2]
+		indexFirst := merkle.DefaultTreeOptions()
+		indexFirst.IndexFirst = true
+		noIndex := merkle.DefaultTreeOptions()
+		noIndex.IncludeIndex = false
+		for name, opts := range map[string]merkle.TreeOptions{"Default": merkle.DefaultTreeOptions(), "IndexFirst": indexFirst, "NoIndex": noIndex} {
+			opts.DomainSeparator = merkle.DomainSeparatorFor("season-1")
+			expected, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+			if optimized, _ := merkle.OptimizedHashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts); !bytes.Equal(optimized, expected) {
+				t.Errorf("%s: expected the pooled hash to match", name)
+			}
+			opts.DomainSeparator = nil
+			plainLeaf, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+			if bytes.Equal(plainLeaf, expected) {
+				t.Errorf("%s: expected the separator to change the leaf", name)
+			}
+		}
+		// The separator is prepended to the preimage
+		opts := merkle.DefaultTreeOptions()
+		opts.IncludeIndex = false
+		opts.DomainSeparator = []byte("domain")
+		leaf, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+		if !bytes.Equal(leaf, crypto.Keccak256([]byte("domain"), claim.Address.Bytes(), common.LeftPadBytes(claim.Amount.Bytes(), 32))) {
+			t.Error("Expected keccak256(domain ++ address ++ amount)")
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		metadata := season1.Metadata()
+		if metadata.DomainSeparator != "0x"+hex.EncodeToString(crypto.Keccak256([]byte("season-1"))) {
+			t.Errorf("Expected the separator in the metadata, got %q", metadata.DomainSeparator)
+		}
+		encoded, _ := json.Marshal(metadata)
+		var decoded merkle.TreeMetadata
+		json.Unmarshal(encoded, &decoded)
+		if !bytes.Equal(decoded.Options().DomainSeparator, merkle.DomainSeparatorFor("season-1")) {
+			t.Errorf("Expected the separator to round-trip, got %s", encoded)
+		}
+
+		// Separators that aren't strict hex are rejected, not truncated
+		for _, separator := range []string{"0xzz", "0x123", "abcd"} {
+			file := fmt.Sprintf(`{"merkleRoot": %q, "metadata": {"sortedPairs": true, "domainSeparator": %q}, "proofs": {}}`, season1.GetRootHash(), separator)
+			if _, _, err := data.LoadProofsJSON(strings.NewReader(file)); !errors.Is(err, merkle.ErrInvalidHex) {
+				t.Errorf("%s: expected an invalid hex error, got %v", separator, err)
+			}
+		}
+
+		// An exported proof set checks and serves with its domain
+		var exported bytes.Buffer
+		if err := data.ExportProofsBinary(proofs1, season1.Root.Hash, &exported); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		loaded, root, err := data.LoadProofsBinary(&exported)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		claim := season1.Claims[9]
+		if check, err := data.CheckClaim(season1.GetRootHash(), loaded, claim.Address, nil); err != nil || check.Status != data.ClaimValid {
+			t.Errorf("Expected the loaded proof to check, got %+v (%v)", check, err)
+		}
+
+		server := mustServer(t)(api.NewAPIServerFromProofs(season1.GetRootHash(), loaded)).SetupRoutes()
+		body, _ := json.Marshal(map[string]interface{}{
+			"address": claim.Address.Hex(),
+			"amount":  claim.Amount.String(),
+			"proof":   loaded.Proofs[claim.Address.Hex()].Proof,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&response)
+		if response["valid"] != true {
+			t.Errorf("Expected /api/verify to use the domain, got %v", response)
+		}
+		if !bytes.Equal(root, season1.Root.Hash) {
+			t.Error("Expected the exported root")
+		}
+	})
+}
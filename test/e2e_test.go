@@ -0,0 +1,17 @@
+package test
+
+import (
+	"testing"
+
+	"merkle-airdrop/pkg/e2e"
+)
+
+func TestEndToEndClaim(t *testing.T) {
+	// Odd and even sizes exercise the duplicated-last-node path on-chain
+	for _, numClaims := range []int{1, 7, 100} {
+		result := e2e.RunScenario(t, numClaims)
+		if result.Balance.Cmp(result.Amount) != 0 {
+			t.Errorf("Expected balance %s, got %s", result.Amount, result.Balance)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// wrapTwice adds two layers of context, as callers up the stack would
+func wrapTwice(err error) error {
+	return fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", err))
+}
+
+func TestSentinelErrors(t *testing.T) {
+	tree, err := merkle.NewMerkleTree(data.GenerateTestData(8))
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	_, notFound := tree.GenerateProof(common.HexToAddress("0xdead"))
+	_, noClaims := merkle.NewMerkleTree(nil)
+	for name, tc := range map[string]struct {
+		err    error
+		target error
+	}{
+		"AddressNotFound": {notFound, merkle.ErrAddressNotFound},
+		"EmptyClaims":     {noClaims, merkle.ErrEmptyClaims},
+		"EmptyClaimsData": {data.ValidateClaimsData(nil), merkle.ErrEmptyClaims},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if !errors.Is(tc.err, tc.target) {
+				t.Fatalf("Expected %v to be %v", tc.err, tc.target)
+			}
+			if !errors.Is(wrapTwice(tc.err), tc.target) {
+				t.Fatalf("Expected %v to survive wrapping", tc.target)
+			}
+		})
+	}
+
+	if errors.Is(wrapTwice(noClaims), merkle.ErrAddressNotFound) {
+		t.Error("Expected the sentinels to be distinct")
+	}
+}
+
+func TestInvalidAmountError(t *testing.T) {
+	claims := data.GenerateTestData(4)
+	claims[2].Amount = big.NewInt(-5)
+	_, err := merkle.NewMerkleTree(claims)
+
+	var amountErr *merkle.InvalidAmountError
+	if !errors.As(wrapTwice(err), &amountErr) {
+		t.Fatalf("Expected an InvalidAmountError, got %v", err)
+	}
+	if amountErr.Index != 2 || amountErr.Value != "-5" {
+		t.Errorf("Expected claim 2's amount -5, got index %d value %q", amountErr.Index, amountErr.Value)
+	}
+
+	// Loaders report the line as a RowError around the amount error
+	path := filepath.Join(t.TempDir(), "claims.csv")
+	csv := "address,amount\n0x1111111111111111111111111111111111111111,10\n0x2222222222222222222222222222222222222222,1.5\n"
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = data.LoadAirdropFromCSV(path)
+	var rowErr *data.RowError
+	if !errors.As(wrapTwice(err), &rowErr) || rowErr.Row != 3 {
+		t.Fatalf("Expected a RowError for line 3, got %v", err)
+	}
+	if !errors.As(wrapTwice(err), &amountErr) || amountErr.Index != 1 || amountErr.Value != "1.5" {
+		t.Fatalf("Expected an InvalidAmountError for claim 1, got %v", err)
+	}
+
+	claim := merkle.AirdropClaim{Address: common.HexToAddress("0x1"), Amount: big.NewInt(-1), Index: 7}
+	_, err = merkle.VerifyProof(make([]byte, 32), claim, nil, merkle.DefaultTreeOptions())
+	if !errors.As(wrapTwice(err), &amountErr) || amountErr.Index != 7 {
+		t.Fatalf("Expected verification to report the claim's index, got %v", err)
+	}
+}
+
+func TestInvalidProofError(t *testing.T) {
+	tree, err := merkle.NewMerkleTree(data.GenerateTestData(8))
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	claim := *tree.Leaves[0].Data
+	proof, _ := tree.GenerateProof(claim.Address)
+	positional := merkle.DefaultTreeOptions()
+	positional.SortedPairs = false
+
+	for name, tc := range map[string]struct {
+		proof []string
+		opts  merkle.TreeOptions
+	}{
+		"BadHex":            {[]string{"0xzz"}, merkle.DefaultTreeOptions()},
+		"ShortElement":      {[]string{"0x1234"}, merkle.DefaultTreeOptions()},
+		"PositionsRequired": {proof.Proof, positional},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := merkle.VerifyProof(tree.Root.Hash, claim, tc.proof, tc.opts)
+			var proofErr *merkle.InvalidProofError
+			if !errors.As(wrapTwice(err), &proofErr) {
+				t.Fatalf("Expected an InvalidProofError, got %v", err)
+			}
+		})
+	}
+
+	// A well-formed proof that doesn't reach the root is no error
+	other := *tree.Leaves[1].Data
+	if valid, err := merkle.VerifyProof(tree.Root.Hash, other, proof.Proof, merkle.DefaultTreeOptions()); valid || err != nil {
+		t.Errorf("Expected a wrong proof to be invalid without error, got %v, %v", valid, err)
+	}
+}
+
+func TestDuplicateAddressError(t *testing.T) {
+	claims := data.GenerateTestData(4)
+	claims[3].Address = claims[1].Address
+
+	err := data.ValidateClaimsData(claims)
+	var dupErr *data.DuplicateAddressError
+	if !errors.As(wrapTwice(err), &dupErr) {
+		t.Fatalf("Expected a DuplicateAddressError, got %v", err)
+	}
+	if dupErr.Index != 3 || dupErr.Address != claims[1].Address {
+		t.Errorf("Expected the repeat at index 3, got %d %s", dupErr.Index, dupErr.Address.Hex())
+	}
+
+	path := filepath.Join(t.TempDir(), "weights.csv")
+	csv := "address,weight\n0x1111111111111111111111111111111111111111,1\n0x1111111111111111111111111111111111111111,2\n"
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = data.LoadWeightsFromCSV(path)
+	var rowErr *data.RowError
+	if !errors.As(wrapTwice(err), &rowErr) || rowErr.Row != 3 || !errors.As(wrapTwice(err), &dupErr) {
+		t.Fatalf("Expected a duplicate address on line 3, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func buildProofSet(t testing.TB, count int) (*merkle.MerkleTree, *merkle.ProofSet) {
+	t.Helper()
+
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(count), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateProofSet()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+	return tree, proofs
+}
+
+// buildSpreadProofSet is buildProofSet with random addresses, since the
+// sequential test addresses all share the same prefix
+func buildSpreadProofSet(t *testing.T, count int) (*merkle.MerkleTree, *merkle.ProofSet) {
+	t.Helper()
+
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateRandomTestData(count, 1), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateProofSet()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+	return tree, proofs
+}
+
+func TestBinaryProofExport(t *testing.T) {
+	tree, proofs := buildProofSet(t, 10000)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &buf); err != nil {
+			t.Fatalf("Failed to export proofs: %v", err)
+		}
+
+		loaded, root, err := data.LoadProofsBinary(&buf)
+		if err != nil {
+			t.Fatalf("Failed to load proofs: %v", err)
+		}
+		if !bytes.Equal(root, tree.Root.Hash) {
+			t.Errorf("Expected root 0x%x, got 0x%x", tree.Root.Hash, root)
+		}
+		if !reflect.DeepEqual(loaded.Proofs, proofs.Proofs) {
+			t.Error("Loaded proofs differ from exported proofs")
+		}
+	})
+
+	t.Run("GzipSmallerThanJSON", func(t *testing.T) {
+		jsonBytes, err := json.MarshalIndent(map[string]interface{}{
+			"merkleRoot": tree.GetRootHash(),
+			"proofs":     proofs.Proofs,
+		}, "", "  ")
+		if err != nil {
+			t.Fatalf("Failed to encode JSON: %v", err)
+		}
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if err := data.ExportProofsBinary(proofs, tree.Root.Hash, gz); err != nil {
+			t.Fatalf("Failed to export proofs: %v", err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatalf("Failed to close gzip stream: %v", err)
+		}
+		binSize := buf.Len()
+
+		if len(jsonBytes) < 5*binSize {
+			t.Errorf("Expected binary output at least 5x smaller: JSON %d bytes, binary %d bytes", len(jsonBytes), binSize)
+		}
+
+		loaded, _, err := data.LoadProofsBinary(&buf)
+		if err != nil {
+			t.Fatalf("Failed to load gzip proofs: %v", err)
+		}
+		if !reflect.DeepEqual(loaded.Proofs, proofs.Proofs) {
+			t.Error("Loaded gzip proofs differ from exported proofs")
+		}
+	})
+
+	t.Run("ServeFromFile", func(t *testing.T) {
+		filename := filepath.Join(t.TempDir(), "proofs.bin")
+		if err := data.SaveProofsBinaryFile(proofs, tree.Root.Hash, filename, true); err != nil {
+			t.Fatalf("Failed to save proofs: %v", err)
+		}
+
+		root, loaded, err := data.LoadProofsFile(filename)
+		if err != nil {
+			t.Fatalf("Failed to load proofs file: %v", err)
+		}
+		if root != tree.GetRootHash() {
+			t.Errorf("Expected root %s, got %s", tree.GetRootHash(), root)
+		}
+
+		handler := mustServer(t)(api.NewAPIServerFromProofs(root, loaded)).SetupRoutes()
+		addr := tree.Claims[42].Address.Hex()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/proof/"+addr, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		var response map[string]interface{}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if response["merkleRoot"] != tree.GetRootHash() {
+			t.Errorf("Expected merkleRoot %s, got %v", tree.GetRootHash(), response["merkleRoot"])
+		}
+		if response["amount"] != proofs.Proofs[addr].Amount {
+			t.Errorf("Expected amount %s, got %v", proofs.Proofs[addr].Amount, response["amount"])
+		}
+	})
+}
+
+func TestShardedProofExport(t *testing.T) {
+	tree, proofs := buildSpreadProofSet(t, 1000)
+	root := tree.GetRootHash()
+
+	for _, bits := range []int{4, 8} {
+		t.Run(fmt.Sprintf("%dBits", bits), func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "proofs")
+			if err := data.ExportProofsSharded(proofs, root, dir, bits, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+				t.Fatalf("Failed to export shards: %v", err)
+			}
+
+			var index data.ShardIndex
+			content, err := os.ReadFile(filepath.Join(dir, data.ShardIndexFile))
+			if err != nil {
+				t.Fatalf("Failed to read index: %v", err)
+			}
+			if err := json.Unmarshal(content, &index); err != nil {
+				t.Fatalf("Failed to decode index: %v", err)
+			}
+			if len(index.Shards) != 1<<bits {
+				t.Fatalf("Expected %d shards, got %d", 1<<bits, len(index.Shards))
+			}
+
+			seen := 0
+			for prefix, name := range index.Shards {
+				file, err := os.Open(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatalf("Failed to open shard %s: %v", prefix, err)
+				}
+				shardRoot, shard, err := data.LoadProofsJSON(file)
+				file.Close()
+				if err != nil {
+					t.Fatalf("Failed to load shard %s: %v", prefix, err)
+				}
+				if shardRoot != root || shard.Metadata != proofs.Metadata {
+					t.Errorf("Shard %s has root %s and metadata %+v", prefix, shardRoot, shard.Metadata)
+				}
+
+				for address, proof := range shard.Proofs {
+					if !strings.HasPrefix(strings.ToLower(address[2:]), prefix) {
+						t.Errorf("Address %s is in shard %s", address, prefix)
+					}
+					if !reflect.DeepEqual(proof, proofs.Proofs[address]) {
+						t.Errorf("Proof for %s differs from the original", address)
+					}
+				}
+				seen += shard.Len()
+			}
+			if seen != proofs.Len() {
+				t.Errorf("Expected %d proofs across shards, got %d", proofs.Len(), seen)
+			}
+
+			loadedRoot, loaded, err := data.LoadProofsFile(dir)
+			if err != nil {
+				t.Fatalf("Failed to load sharded proofs: %v", err)
+			}
+			if loadedRoot != root {
+				t.Errorf("Expected root %s, got %s", root, loadedRoot)
+			}
+			if !reflect.DeepEqual(loaded.Proofs, proofs.Proofs) || loaded.Metadata != proofs.Metadata {
+				t.Error("Reassembled proofs differ from the original set")
+			}
+		})
+	}
+
+	t.Run("MissingShard", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := data.ExportProofsSharded(proofs, root, dir, 4, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export shards: %v", err)
+		}
+
+		prefix := data.ShardPrefix(tree.Claims[0].Address.Hex(), 4)
+		if err := os.Remove(filepath.Join(dir, prefix+".json")); err != nil {
+			t.Fatalf("Failed to remove shard: %v", err)
+		}
+		if _, _, err := data.LoadShardedProofs(dir); err == nil {
+			t.Error("Expected an error for a missing shard")
+		}
+	})
+
+	t.Run("InvalidBits", func(t *testing.T) {
+		for _, bits := range []int{0, 3, 20} {
+			if err := data.ExportProofsSharded(proofs, root, t.TempDir(), bits, data.AddressChecksum, data.ProofMarshaller{}); err == nil {
+				t.Errorf("Expected an error for %d shard bits", bits)
+			}
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestProofMarshaller(t *testing.T) {
+	tree, proofs := buildProofSet(t, 20)
+	address := tree.Claims[5].Address.Hex()
+	proof := proofs.Proofs[address]
+
+	profiles := map[string]struct {
+		spec string
+		keys []string // Keys of an encoded proof, in order
+	}{
+		"Default":     {"", []string{"proof", "index", "amount", "root"}},
+		"MerkleProof": {"proof=merkleProof,amount=value", []string{"merkleProof", "index", "value", "root"}},
+		"NoIndex":     {"-index", []string{"proof", "amount", "root"}},
+		"Swapped":     {"proof=amount,amount=proof", []string{"amount", "index", "proof", "root"}},
+	}
+	for name, profile := range profiles {
+		t.Run(name, func(t *testing.T) {
+			fields, err := data.ParseProofMarshaller(profile.spec)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", profile.spec, err)
+			}
+			if again, _ := data.ParseProofMarshaller(fields.String()); again != fields {
+				t.Errorf("Expected %q to parse back to %+v, got %+v", fields.String(), fields, again)
+			}
+
+			encoded, err := fields.Marshal(proof)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if keys := objectKeys(t, encoded); !reflect.DeepEqual(keys, profile.keys) {
+				t.Errorf("Expected keys %v, got %v in %s", profile.keys, keys, encoded)
+			}
+
+			decoded, err := fields.Unmarshal(encoded)
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			want := *proof
+			if fields.OmitIndex {
+				want.Index = 0
+			}
+			if !reflect.DeepEqual(*decoded, want) {
+				t.Errorf("Expected %+v back, got %+v", want, *decoded)
+			}
+
+			// Files say how their proofs are named, so they load back
+			var out bytes.Buffer
+			if err := data.ExportProofsSubset(proofs, []common.Address{tree.Claims[5].Address}, tree.GetRootHash(), &out, fields); err != nil {
+				t.Fatalf("Failed to export: %v", err)
+			}
+			_, loaded, err := data.LoadProofsJSON(&out)
+			if err != nil {
+				t.Fatalf("Failed to load the export: %v", err)
+			}
+			if !reflect.DeepEqual(*loaded.Proofs[address], want) {
+				t.Errorf("Expected %+v loaded, got %+v", want, *loaded.Proofs[address])
+			}
+
+			dir := t.TempDir()
+			if err := data.ExportProofsSharded(proofs, tree.GetRootHash(), dir, 4, data.AddressChecksum, fields); err != nil {
+				t.Fatalf("Failed to export shards: %v", err)
+			}
+			_, sharded, err := data.LoadShardedProofs(dir)
+			if err != nil || !reflect.DeepEqual(*sharded.Proofs[address], want) {
+				t.Errorf("Expected %+v from the shards, got %v (%v)", want, sharded, err)
+			}
+		})
+	}
+
+	t.Run("DefaultUnchanged", func(t *testing.T) {
+		var fields data.ProofMarshaller
+		want, _ := json.Marshal(proof)
+		if got, _ := fields.Marshal(proof); !bytes.Equal(got, want) {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+
+		var canonical bytes.Buffer
+		data.ExportProofsCanonical(proofs, tree.GetRootHash(), &canonical, data.AddressChecksum, fields)
+		if strings.Contains(canonical.String(), "proofFields") {
+			t.Error("Expected no proofFields in a default export")
+		}
+		var file struct {
+			Proofs map[string]merkle.MerkleProof `json:"proofs"`
+		}
+		json.Unmarshal(canonical.Bytes(), &file)
+		indented, _ := json.MarshalIndent(file.Proofs[address], "    ", "  ")
+		if !strings.Contains(canonical.String(), string(indented)) {
+			t.Errorf("Expected the canonical proof encoded as before, got %s", canonical.String())
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, spec := range []string{"proof", "nonce=x", "-proof", "proof=index", "amount=positions", "index=", "proof=a,amount=a"} {
+			if _, err := data.ParseProofMarshaller(spec); err == nil {
+				t.Errorf("Expected %q to be refused", spec)
+			}
+		}
+		// An omitted field's name is free for another
+		if _, err := data.ParseProofMarshaller("-index,amount=index"); err != nil {
+			t.Errorf("Expected an omitted field's name to be usable, got %v", err)
+		}
+		fields, _ := data.ParseProofMarshaller("amount=success")
+		if err := api.ValidateProofFields(fields); err == nil {
+			t.Error("Expected a proof field named like a response key to be refused")
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		fields, _ := data.ParseProofMarshaller("proof=merkleProof,amount=value,-index")
+		handler := api.MustNewAPIServer(tree, proofs.Proofs, api.WithProofFields(fields)).SetupRoutes()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/proof/"+address, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", w.Code)
+		}
+		keys := objectKeys(t, w.Body.Bytes())
+		if want := []string{"address", "merkleProof", "value", "merkleRoot", "success"}; !reflect.DeepEqual(keys, want) {
+			t.Errorf("Expected keys %v, got %v", want, keys)
+		}
+
+		// Without the option responses are as they were
+		plain := httptest.NewRecorder()
+		api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes().ServeHTTP(plain, httptest.NewRequest(http.MethodGet, "/api/proof/"+address, nil))
+		want, _ := json.Marshal(api.ProofResponse{
+			Address:    address,
+			Proof:      proof.Proof,
+			Amount:     proof.Amount,
+			Index:      proof.Index,
+			MerkleRoot: tree.GetRootHash(),
+			Success:    true,
+		})
+		if got := bytes.TrimSpace(plain.Body.Bytes()); !bytes.Equal(got, want) {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	})
+
+	t.Run("CLIFile", func(t *testing.T) {
+		// A build's proofs file with proofFields loads as any other
+		path := filepath.Join(t.TempDir(), "merkle_proofs.json")
+		fields, _ := data.ParseProofMarshaller("proof=merkleProof,amount=value")
+		content, _ := json.Marshal(map[string]interface{}{
+			"merkleRoot":  tree.GetRootHash(),
+			"metadata":    proofs.Metadata,
+			"proofFields": fields,
+			"proofs":      fields.Proofs(proofs.Proofs),
+		})
+		os.WriteFile(path, content, 0o644)
+		_, loaded, err := data.LoadProofsFile(path)
+		if err != nil || !reflect.DeepEqual(loaded.Proofs, proofs.Proofs) {
+			t.Errorf("Expected the proofs back, got %v", err)
+		}
+	})
+}
+
+// objectKeys returns the top-level keys of a JSON object in order
+func objectKeys(t *testing.T, encoded []byte) []string {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.Token()
+	var keys []string
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", encoded, err)
+		}
+		keys = append(keys, key.(string))
+		var value json.RawMessage
+		decoder.Decode(&value)
+	}
+	return keys
+}
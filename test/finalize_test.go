@@ -0,0 +1,236 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/internal/reload"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeRootReader answers every contract with root
+type fakeRootReader struct {
+	root [32]byte
+	err  error
+}
+
+func (f *fakeRootReader) MerkleRoot(common.Address) ([32]byte, error) {
+	return f.root, f.err
+}
+
+func TestFinalization(t *testing.T) {
+	const (
+		adminToken  = "admin-secret"
+		unlockToken = "unlock-secret"
+		contract    = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+		txHash      = "0x9f2c4a6b3e1d0c8f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f"
+	)
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, "finalization.json")
+	configPath := filepath.Join(dir, "config.json")
+	cfg := config.DefaultConfig()
+	cfg.Logging.File = ""
+	cfg.Server.AdminTokens = []string{adminToken}
+	if err := config.SaveConfig(cfg, configPath); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	tree, _ := buildProofSet(t, 10)
+	discard := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// start serves tree as a server started with the lock at lockPath would,
+	// its reloader and appender refusing changes while it holds
+	start := func(t *testing.T, reader api.RootReader) (http.Handler, *reload.Reloader) {
+		t.Helper()
+		lock, err := api.NewFinalizationLock(lockPath)
+		if err != nil {
+			t.Fatalf("Failed to open the lock: %v", err)
+		}
+		tunables, _ := reload.NewTunables(cfg)
+		reloader := reload.New(configPath, cfg, tunables, reload.WithFinalization(lock), reload.WithLogger(discard))
+		opts := []api.Option{
+			api.WithAdminTokens(cfg.Server.AdminTokens),
+			api.WithConfigReloader(reloader),
+			api.WithCampaign(api.CampaignMeta{Name: "Season 1"}, ""),
+			api.WithFinalization(lock, []string{unlockToken}),
+			api.WithLogger(discard),
+		}
+		if reader != nil {
+			opts = append(opts, api.WithRootReader(reader))
+		}
+		var appender *rebuild.Appender
+		serve := func(tree *merkle.MerkleTree) (http.Handler, error) {
+			proofs, err := tree.GenerateAllProofs()
+			if err != nil {
+				return nil, err
+			}
+			return api.MustNewAPIServer(tree, proofs, append(slices.Clip(opts), api.WithClaimAppender(appender))...).SetupRoutes(), nil
+		}
+		appender = rebuild.NewAppender(serve, discard)
+		if err := appender.Start(tree); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		return appender, reloader
+	}
+	do := func(handler http.Handler, method, path, token string, body interface{}) (int, []byte) {
+		var encoded []byte
+		if body != nil {
+			encoded, _ = json.Marshal(body)
+		}
+		r := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+		r.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code, w.Body.Bytes()
+	}
+	errorCode := func(body []byte) string {
+		var apiErr api.ErrorResponse
+		json.Unmarshal(body, &apiErr)
+		return apiErr.Error.Code
+	}
+	finalizeReq := api.FinalizeRequest{ContractAddress: contract, TxHash: txHash, Operator: "ops@example.org"}
+	appendReq := api.AppendClaimsRequest{Claims: []api.AppendClaimEntry{{Address: "0x00000000000000000000000000000000000000a1", Amount: "500"}}}
+	// locked checks that the changes a finalized campaign refuses answer 423
+	// with the finalization
+	locked := func(t *testing.T, handler http.Handler) {
+		t.Helper()
+		for _, tc := range []struct {
+			method, path string
+			body         interface{}
+		}{
+			{http.MethodPost, "/api/admin/config/reload", nil},
+			{http.MethodPost, "/api/admin/claims", appendReq},
+			{http.MethodPut, "/api/admin/campaign", api.CampaignMeta{Name: "Season 2"}},
+			{http.MethodPost, "/api/admin/finalize", finalizeReq},
+		} {
+			status, body := do(handler, tc.method, tc.path, adminToken, tc.body)
+			var response api.FinalizedResponse
+			json.Unmarshal(body, &response)
+			if status != http.StatusLocked || response.Error.Code != api.CodeFinalized || response.Finalization.MerkleRoot != tree.GetRootHash() {
+				t.Errorf("%s %s: expected 423 with the finalization, got %d %s", tc.method, tc.path, status, body)
+			}
+		}
+	}
+
+	t.Run("RootMismatch", func(t *testing.T) {
+		handler, _ := start(t, &fakeRootReader{root: [32]byte{1}})
+		if status, body := do(handler, http.MethodPost, "/api/admin/finalize", adminToken, finalizeReq); status != http.StatusConflict || errorCode(body) != api.CodeRootMismatch {
+			t.Errorf("Expected a contract with another root to be refused, got %d %s", status, body)
+		}
+		handler, _ = start(t, &fakeRootReader{err: errors.New("connection refused")})
+		if status, body := do(handler, http.MethodPost, "/api/admin/finalize", adminToken, finalizeReq); status != http.StatusBadGateway || errorCode(body) != api.CodeChainUnavailable {
+			t.Errorf("Expected an unreachable node to be reported, got %d %s", status, body)
+		}
+		bad := finalizeReq
+		bad.TxHash = "0x1234"
+		if status, body := do(handler, http.MethodPost, "/api/admin/finalize", adminToken, bad); status != http.StatusBadRequest {
+			t.Errorf("Expected a short tx hash to be rejected, got %d %s", status, body)
+		}
+		if _, err := os.Stat(lockPath); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Expected no finalization to be saved")
+		}
+	})
+
+	t.Run("Lock", func(t *testing.T) {
+		var root [32]byte
+		copy(root[:], tree.Root.Hash)
+		handler, reloader := start(t, &fakeRootReader{root: root})
+		status, body := do(handler, http.MethodPost, "/api/admin/finalize", adminToken, finalizeReq)
+		var response api.FinalizeResponse
+		json.Unmarshal(body, &response)
+		record := response.Finalization
+		if status != http.StatusOK || record.MerkleRoot != tree.GetRootHash() || !record.RootVerified || record.ContractAddress != contract || record.Operator != finalizeReq.Operator || record.FinalizedAt.IsZero() {
+			t.Fatalf("Expected the finalization, got %d %s", status, body)
+		}
+		locked(t, handler)
+
+		// SIGUSR1 reloads go to the reloader directly
+		var finalizedErr *api.FinalizedError
+		if _, err := reloader.ReloadConfig(); !errors.As(err, &finalizedErr) || finalizedErr.Finalization != record {
+			t.Errorf("Expected the reloader to refuse, got %v", err)
+		}
+
+		for _, path := range []string{"/api/campaign", "/api/stats"} {
+			_, body := do(handler, http.MethodGet, path, "", nil)
+			var shown struct{ Finalization *api.Finalization }
+			json.Unmarshal(body, &shown)
+			if shown.Finalization == nil || *shown.Finalization != record {
+				t.Errorf("%s: expected the finalization, got %s", path, body)
+			}
+		}
+
+		// A restarted server reads the lock back
+		restarted, _ := start(t, nil)
+		locked(t, restarted)
+	})
+
+	// Unlocks the campaign Lock finalized
+	t.Run("ForceUnlock", func(t *testing.T) {
+		handler, _ := start(t, nil)
+		unlockReq := api.UnlockRequest{Operator: "ops@example.org", Reason: "redeploying after an audit finding"}
+		if status, _ := do(handler, http.MethodPost, "/api/admin/finalize/force-unlock", adminToken, unlockReq); status != http.StatusUnauthorized {
+			t.Errorf("Expected an admin token not to unlock, got %d", status)
+		}
+		if status, _ := do(handler, http.MethodPost, "/api/admin/finalize/force-unlock", unlockToken, api.UnlockRequest{Operator: "ops@example.org"}); status != http.StatusBadRequest {
+			t.Errorf("Expected an unlock without a reason to be rejected, got %d", status)
+		}
+		status, body := do(handler, http.MethodPost, "/api/admin/finalize/force-unlock", unlockToken, unlockReq)
+		var response api.UnlockResponse
+		json.Unmarshal(body, &response)
+		if status != http.StatusOK || response.Unlocked.MerkleRoot != tree.GetRootHash() {
+			t.Fatalf("Expected the finalization to be lifted, got %d %s", status, body)
+		}
+		if _, err := os.Stat(lockPath); !errors.Is(err, os.ErrNotExist) {
+			t.Error("Expected the saved finalization to be deleted")
+		}
+
+		if status, body := do(handler, http.MethodPost, "/api/admin/config/reload", adminToken, nil); status != http.StatusOK {
+			t.Errorf("Expected reloads after unlocking, got %d %s", status, body)
+		}
+		if status, body := do(handler, http.MethodPost, "/api/admin/claims", adminToken, appendReq); status != http.StatusOK {
+			t.Errorf("Expected appends after unlocking, got %d %s", status, body)
+		}
+		if status, body := do(handler, http.MethodPost, "/api/admin/finalize/force-unlock", unlockToken, unlockReq); status != http.StatusConflict || errorCode(body) != api.CodeNotFinalized {
+			t.Errorf("Expected nothing left to unlock, got %d %s", status, body)
+		}
+	})
+
+	t.Run("NoUnlockTokens", func(t *testing.T) {
+		lock, _ := api.NewFinalizationLock("")
+		lock.Finalize(api.Finalization{MerkleRoot: tree.GetRootHash()})
+		proofs, _ := tree.GenerateAllProofs()
+		handler := api.MustNewAPIServer(tree, proofs, api.WithAdminTokens([]string{adminToken}), api.WithFinalization(lock, nil)).SetupRoutes()
+		if status, _ := do(handler, http.MethodPost, "/api/admin/finalize/force-unlock", adminToken, api.UnlockRequest{Operator: "x", Reason: "y"}); status != http.StatusNotFound {
+			t.Errorf("Expected no unlock route without unlock tokens, got %d", status)
+		}
+		if _, ok := lock.Finalization(); !ok {
+			t.Error("Expected the campaign to stay finalized")
+		}
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		lock, _ := api.NewFinalizationLock("")
+		lock.Finalize(api.Finalization{MerkleRoot: tree.GetRootHash()})
+		proofs, _ := tree.GenerateAllProofs()
+		handler := api.MustNewAPIServer(tree, proofs, api.WithFinalization(lock, nil)).SetupRoutes()
+		if status, body := do(handler, http.MethodGet, "/api/proof/"+tree.Claims[0].Address.Hex(), "", nil); status != http.StatusOK {
+			t.Errorf("Expected proofs to be served when finalized, got %d %s", status, body)
+		}
+	})
+}
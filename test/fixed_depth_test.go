@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestFixedDepth(t *testing.T) {
+	claims := data.GenerateTestData(100) // 7 levels deep
+	for name, opts := range map[string]merkle.TreeOptions{
+		"Duplicate":  merkle.DefaultTreeOptions(),
+		"Zero":       {CopyClaims: true, IncludeIndex: true, SortedPairs: true, OddLeafPolicy: merkle.ZeroPad},
+		"Promote":    {CopyClaims: true, IncludeIndex: true, SortedPairs: true, OddLeafPolicy: merkle.Promote},
+		"Positional": {CopyClaims: true, IncludeIndex: true, OddLeafPolicy: merkle.DuplicateLast},
+	} {
+		t.Run(name, func(t *testing.T) {
+			natural, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+			if err != nil {
+				t.Fatalf("Failed to build tree: %v", err)
+			}
+			opts.FixedDepth = 20
+			tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+			if err != nil {
+				t.Fatalf("Failed to build fixed-depth tree: %v", err)
+			}
+			if tree.GetRootHash() != natural.GetRootHash() {
+				t.Fatal("Expected padding to leave the root unchanged")
+			}
+
+			for _, leaf := range tree.Leaves {
+				claim := *leaf.Data
+				want, _ := natural.GenerateProof(claim.Address)
+				proof, err := tree.GenerateProof(claim.Address)
+				if err != nil {
+					t.Fatalf("Failed to generate proof: %v", err)
+				}
+				if len(proof.Proof) != 20 || proof.PaddingCount != 20-len(want.Proof) {
+					t.Fatalf("Expected %d real and %d padding elements, got %d with padding count %d",
+						len(want.Proof), 20-len(want.Proof), len(proof.Proof), proof.PaddingCount)
+				}
+				if strings.Join(proof.Proof[:len(want.Proof)], ",") != strings.Join(want.Proof, ",") || proof.Positions != want.Positions {
+					t.Fatal("Expected the real elements to be the unpadded proof")
+				}
+				valid, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof, proof.Positions, opts)
+				if err != nil || !valid {
+					t.Fatalf("Expected padded proof to verify: %v", err)
+				}
+			}
+
+			claim := *tree.Leaves[0].Data
+			proof, _ := tree.GenerateProof(claim.Address)
+			tampered := append([]string(nil), proof.Proof...)
+			tampered[19] = "0x" + strings.Repeat("11", 32)
+			if valid, _ := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, tampered, proof.Positions, opts); valid {
+				t.Error("Expected tampered padding to fail verification")
+			}
+			if _, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof[:19], proof.Positions, opts); err == nil {
+				t.Error("Expected a proof shorter than the fixed depth to fail")
+			}
+			unpadded, _ := natural.GenerateProof(claim.Address)
+			if _, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, unpadded.Proof, unpadded.Positions, opts); err == nil {
+				t.Error("Expected an unpadded proof to fail against fixed-depth options")
+			}
+		})
+	}
+}
+
+func TestFixedDepthTooShallow(t *testing.T) {
+	for name, depth := range map[string]int{"BelowTree": 6, "Negative": -1, "AboveMax": merkle.MaxFixedDepth + 1} {
+		t.Run(name, func(t *testing.T) {
+			opts := merkle.DefaultTreeOptions()
+			opts.FixedDepth = depth
+			if _, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(100), opts); err == nil {
+				t.Errorf("Expected a fixed depth of %d to fail", depth)
+			}
+		})
+	}
+
+	opts := merkle.DefaultTreeOptions()
+	opts.FixedDepth = 7
+	if _, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(100), opts); err != nil {
+		t.Errorf("Expected the tree's own depth to be accepted: %v", err)
+	}
+}
+
+func TestFixedDepthExports(t *testing.T) {
+	opts := merkle.DefaultTreeOptions()
+	opts.OddLeafPolicy = merkle.ZeroPad
+	opts.FixedDepth = 20
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(100), opts)
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateProofSet()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &buf); err != nil {
+		t.Fatalf("Failed to export proofs: %v", err)
+	}
+	loaded, _, err := data.LoadProofsBinary(&buf)
+	if err != nil {
+		t.Fatalf("Failed to load proofs: %v", err)
+	}
+	if loaded.Metadata.FixedDepth != 20 || loaded.Metadata.Options().FixedDepth != 20 {
+		t.Fatalf("Expected the fixed depth in the metadata, got %+v", loaded.Metadata)
+	}
+	for address, proof := range proofs.Proofs {
+		if got := loaded.Proofs[address]; got.PaddingCount != proof.PaddingCount || len(got.Proof) != 20 {
+			t.Fatalf("Expected %s's padding count %d to survive, got %d", address, proof.PaddingCount, got.PaddingCount)
+		}
+	}
+
+	claim := *tree.Leaves[3].Data
+	proof, _ := tree.GenerateProof(claim.Address)
+	link, err := merkle.EncodeClaimLinkWithOptions("https://claim.example/", claim, proof, opts)
+	if err != nil {
+		t.Fatalf("Failed to encode claim link: %v", err)
+	}
+	_, decoded, err := merkle.DecodeClaimLink(link, tree.Root.Hash, opts)
+	if err != nil {
+		t.Fatalf("Failed to decode claim link: %v", err)
+	}
+	if decoded.PaddingCount != proof.PaddingCount {
+		t.Errorf("Expected padding count %d from the link, got %d", proof.PaddingCount, decoded.PaddingCount)
+	}
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/internal/fsutil"
+	"merkle-airdrop/pkg/data"
+)
+
+func TestAtomicWriteFile(t *testing.T) {
+	// entries lists the directory so leftover temporary files show up
+	entries := func(t *testing.T, dir string) []string {
+		t.Helper()
+		list, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("Failed to read directory: %v", err)
+		}
+		var names []string
+		for _, entry := range list {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "out.json")
+		err := fsutil.AtomicWriteFile(path, func(w io.Writer) error {
+			_, err := io.WriteString(w, "{}\n")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+
+		content, _ := os.ReadFile(path)
+		info, _ := os.Stat(path)
+		if string(content) != "{}\n" || info.Mode().Perm() != 0644 {
+			t.Errorf("Expected {} with mode 0644, got %q with %v", content, info.Mode().Perm())
+		}
+		if names := entries(t, dir); len(names) != 1 {
+			t.Errorf("Expected only the output file, got %v", names)
+		}
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "merkle_proofs.json")
+		if err := os.WriteFile(path, []byte("original"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		failure := errors.New("disk full")
+		err := fsutil.AtomicWriteFile(path, func(w io.Writer) error {
+			io.WriteString(w, strings.Repeat("partial", 10000))
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Expected the write error, got %v", err)
+		}
+
+		if content, _ := os.ReadFile(path); string(content) != "original" {
+			t.Errorf("Expected the original file to be untouched, got %d bytes", len(content))
+		}
+		if names := entries(t, dir); len(names) != 1 {
+			t.Errorf("Expected the temporary file to be removed, got %v", names)
+		}
+	})
+
+	t.Run("KeepsMode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.SaveConfig(config.DefaultConfig(), path); err != nil {
+			t.Fatalf("Failed to save config: %v", err)
+		}
+
+		info, _ := os.Stat(path)
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected mode 0600 to be kept, got %v", info.Mode().Perm())
+		}
+		if _, err := config.LoadConfig(path); err != nil {
+			t.Errorf("Failed to load saved config: %v", err)
+		}
+	})
+
+	t.Run("ExporterError", func(t *testing.T) {
+		tree, proofs := buildProofSet(t, 100)
+		path := filepath.Join(t.TempDir(), "merkle_proofs.bin")
+		if err := data.SaveProofsBinaryFile(proofs, tree.Root.Hash, path, false); err != nil {
+			t.Fatalf("Failed to save proofs: %v", err)
+		}
+		original, _ := os.ReadFile(path)
+
+		// A bad amount partway through fails the export after the header
+		proofs.Proofs[tree.Claims[60].Address.Hex()].Amount = "lots"
+		if err := data.SaveProofsBinaryFile(proofs, tree.Root.Hash, path, true); err == nil {
+			t.Fatal("Expected the export to fail")
+		}
+
+		if content, _ := os.ReadFile(path); string(content) != string(original) {
+			t.Error("Expected the previous export to be untouched")
+		}
+		if _, _, err := data.LoadProofsFile(path); err != nil {
+			t.Errorf("Expected the previous export to load, got %v", err)
+		}
+	})
+
+	t.Run("CheckOverwrite", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "links.csv")
+		if err := fsutil.CheckOverwrite(path, false); err != nil {
+			t.Errorf("Expected a missing file to be writable, got %v", err)
+		}
+
+		os.WriteFile(path, nil, 0644)
+		if err := fsutil.CheckOverwrite(path, false); !errors.Is(err, fsutil.ErrExists) {
+			t.Errorf("Expected ErrExists, got %v", err)
+		}
+		if err := fsutil.CheckOverwrite(path, true); err != nil {
+			t.Errorf("Expected -overwrite to allow the file, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGenericTree(t *testing.T) {
+	t.Run("EVMRoots", func(t *testing.T) {
+		variants := map[string]func(*merkle.TreeOptions){
+			"Default":    func(*merkle.TreeOptions) {},
+			"NoIndex":    func(o *merkle.TreeOptions) { o.IncludeIndex = false },
+			"IndexFirst": func(o *merkle.TreeOptions) { o.IndexFirst = true },
+			"Positional": func(o *merkle.TreeOptions) { o.SortedPairs = false },
+		}
+		for name, configure := range variants {
+			opts := merkle.DefaultTreeOptions()
+			configure(&opts)
+			tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateRandomTestData(257, 3), opts)
+			if err != nil {
+				t.Fatalf("%s: failed to build tree: %v", name, err)
+			}
+
+			// The EVM tree's leaf order, with address bytes as keys
+			claims := make([]merkle.GenericClaim, len(tree.Claims))
+			for i, claim := range tree.Claims {
+				claims[i] = claim.Generic()
+			}
+			opts.SortOrder = merkle.PreserveInput
+			generic, err := merkle.NewGenericMerkleTree(claims, opts)
+			if err != nil {
+				t.Fatalf("%s: failed to build generic tree: %v", name, err)
+			}
+			if generic.GetRootHash() != tree.GetRootHash() {
+				t.Errorf("%s: expected root %s, got %s", name, tree.GetRootHash(), generic.GetRootHash())
+			}
+
+			claim := tree.Claims[100]
+			want, _ := tree.GenerateProof(claim.Address)
+			got, _ := generic.GenerateProof(claim.Address.Bytes())
+			if strings.Join(got.Proof, ",") != strings.Join(want.Proof, ",") || got.Positions != want.Positions {
+				t.Errorf("%s: expected the address proof for the same key", name)
+			}
+		}
+
+		// Addresses that sort alike either way keep the ethers.js roots
+		claims := make([]merkle.GenericClaim, 0, 3)
+		for _, claim := range encodingFixture() {
+			claims = append(claims, claim.Generic())
+		}
+		generic, err := merkle.NewGenericMerkleTree(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build generic tree: %v", err)
+		}
+		if root := "0x57eb43ba4d0a26ced311b9438bb92adfd0efe16e633e419ba355aa19fa7e8d1a"; generic.GetRootHash() != root {
+			t.Errorf("Expected root %s, got %s", root, generic.GetRootHash())
+		}
+	})
+
+	t.Run("Solana", func(t *testing.T) {
+		claims, err := data.LoadGenericClaimsFromCSV("solana_claims.csv", data.KeyBase58)
+		if err != nil {
+			t.Fatalf("Failed to load claims: %v", err)
+		}
+		if len(claims) != 8 {
+			t.Fatalf("Expected 8 claims, got %d", len(claims))
+		}
+		for _, claim := range claims {
+			if len(claim.Key) != 32 {
+				t.Errorf("Expected a 32-byte key, got %d bytes for %s", len(claim.Key), claim.Key)
+			}
+		}
+		if !bytes.Equal(claims[0].Key, make([]byte, 32)) {
+			t.Errorf("Expected the system program key to decode to zeros, got %s", claims[0].Key)
+		}
+
+		opts := merkle.DefaultTreeOptions()
+		tree, err := merkle.NewGenericMerkleTree(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		proofs, err := tree.GenerateAllProofs()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		for _, claim := range tree.Claims {
+			proof := proofs[claim.Key.String()]
+			valid, err := merkle.VerifyGenericProof(tree.Root(), claim, proof.Proof, proof.Positions, opts)
+			if err != nil || !valid {
+				t.Errorf("Expected the proof for %s to verify, got %v", data.KeyBase58.Encode(claim.Key), err)
+			}
+
+			forged := claim
+			forged.Amount = new(big.Int).Add(claim.Amount, big.NewInt(1))
+			if valid, _ := merkle.VerifyGenericProof(tree.Root(), forged, proof.Proof, proof.Positions, opts); valid {
+				t.Errorf("Expected a forged amount for %s to fail", claim.Key)
+			}
+		}
+
+		// A 32-byte key fills the word an address is padded into
+		claim := tree.Claims[0]
+		preimage := make([]byte, 32+32+4)
+		copy(preimage, claim.Key)
+		claim.Amount.FillBytes(preimage[32:64])
+		binary.BigEndian.PutUint32(preimage[64:], claim.Index)
+		leaf, err := merkle.HashGenericLeaf(claim.Key, claim.Amount, claim.Index, opts)
+		if err != nil || !bytes.Equal(leaf, crypto.Keccak256(preimage)) {
+			t.Errorf("Expected keccak256(key, amount, uint32(index)), got %x (%v)", leaf, err)
+		}
+	})
+
+	t.Run("KeyFormats", func(t *testing.T) {
+		for _, tc := range []struct {
+			format data.KeyFormat
+			field  string
+			want   []byte
+		}{
+			{data.KeyHex, "0x00ff10", []byte{0x00, 0xff, 0x10}},
+			{data.KeyHex, "abcd", []byte{0xab, 0xcd}},
+			{data.KeyBase58, "1112", []byte{0, 0, 0, 1}},
+			{data.KeyRaw, "user@example.com", []byte("user@example.com")},
+		} {
+			key, err := tc.format.Decode(tc.field)
+			if err != nil || !bytes.Equal(key, tc.want) {
+				t.Errorf("%s %q: expected %x, got %x (%v)", tc.format, tc.field, tc.want, key, err)
+			}
+			if encoded := tc.format.Encode(key); tc.format != data.KeyHex && encoded != tc.field {
+				t.Errorf("%s: expected %q to round-trip, got %q", tc.format, tc.field, encoded)
+			}
+		}
+
+		for _, tc := range []struct {
+			format data.KeyFormat
+			field  string
+		}{
+			{data.KeyHex, "0xzz"},
+			{data.KeyHex, ""},
+			{data.KeyBase58, "0OIl"},
+			{data.KeyRaw, strings.Repeat("x", 33)},
+		} {
+			if _, err := tc.format.Decode(tc.field); err == nil {
+				t.Errorf("%s: expected %q to be rejected", tc.format, tc.field)
+			}
+		}
+		if _, err := data.ParseKeyFormat("base64"); err == nil {
+			t.Error("Expected an unknown key format to be rejected")
+		}
+	})
+
+	t.Run("InvalidClaims", func(t *testing.T) {
+		amount := big.NewInt(1)
+		for name, claims := range map[string][]merkle.GenericClaim{
+			"EmptyKey":     {{Key: nil, Amount: amount}},
+			"LongKey":      {{Key: make([]byte, 33), Amount: amount}},
+			"DuplicateKey": {{Key: []byte{1}, Amount: amount}, {Key: []byte{1}, Amount: amount}},
+			"NoAmount":     {{Key: []byte{1}}},
+		} {
+			if _, err := merkle.NewGenericMerkleTree(claims, merkle.DefaultTreeOptions()); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
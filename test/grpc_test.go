@@ -0,0 +1,303 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/grpcapi"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dialAirdrop serves srv over an in-memory listener and returns a client
+func dialAirdrop(t *testing.T, srv grpcapi.AirdropServer) grpcapi.AirdropClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	grpcapi.RegisterAirdropServer(server, srv)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return grpcapi.NewAirdropClient(conn)
+}
+
+func TestGRPCServer(t *testing.T) {
+	tree, proofs := buildProofSet(t, 50)
+	client := dialAirdrop(t, grpcapi.NewServer(tree, proofs.Proofs))
+	ctx := context.Background()
+
+	claim := tree.Claims[17]
+	expected := proofs.Proofs[claim.Address.Hex()]
+
+	t.Run("GetRoot", func(t *testing.T) {
+		resp, err := client.GetRoot(ctx, &grpcapi.GetRootRequest{})
+		if err != nil {
+			t.Fatalf("GetRoot failed: %v", err)
+		}
+		if !bytes.Equal(resp.MerkleRoot, tree.Root.Hash) {
+			t.Errorf("Expected root 0x%x, got 0x%x", tree.Root.Hash, resp.MerkleRoot)
+		}
+		if !resp.IncludeIndex {
+			t.Error("Expected includeIndex to be true")
+		}
+	})
+
+	t.Run("GetProof", func(t *testing.T) {
+		resp, err := client.GetProof(ctx, &grpcapi.GetProofRequest{Address: claim.Address.Bytes()})
+		if err != nil {
+			t.Fatalf("GetProof failed: %v", err)
+		}
+		if resp.Amount != expected.Amount || resp.Index != expected.Index {
+			t.Errorf("Expected amount %s index %d, got %s index %d", expected.Amount, expected.Index, resp.Amount, resp.Index)
+		}
+		if len(resp.Proof) != len(expected.Proof) {
+			t.Fatalf("Expected %d proof hashes, got %d", len(expected.Proof), len(resp.Proof))
+		}
+		for i, hash := range resp.Proof {
+			if fmt.Sprintf("0x%x", hash) != expected.Proof[i] {
+				t.Errorf("Proof hash %d mismatch", i)
+			}
+		}
+
+		unknown := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+		_, err = client.GetProof(ctx, &grpcapi.GetProofRequest{Address: unknown.Bytes()})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NotFound for unknown address, got %v", err)
+		}
+
+		_, err = client.GetProof(ctx, &grpcapi.GetProofRequest{Address: []byte{1, 2, 3}})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for short address, got %v", err)
+		}
+	})
+
+	t.Run("GetProofByIndex", func(t *testing.T) {
+		resp, err := client.GetProofByIndex(ctx, &grpcapi.GetProofByIndexRequest{Index: claim.Index})
+		if err != nil {
+			t.Fatalf("GetProofByIndex failed: %v", err)
+		}
+		if common.BytesToAddress(resp.Address) != claim.Address {
+			t.Errorf("Expected address %s, got %x", claim.Address.Hex(), resp.Address)
+		}
+
+		_, err = client.GetProofByIndex(ctx, &grpcapi.GetProofByIndexRequest{Index: 50})
+		if status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NotFound for out-of-range index, got %v", err)
+		}
+	})
+
+	t.Run("VerifyProof", func(t *testing.T) {
+		proof, err := client.GetProof(ctx, &grpcapi.GetProofRequest{Address: claim.Address.Bytes()})
+		if err != nil {
+			t.Fatalf("GetProof failed: %v", err)
+		}
+
+		resp, err := client.VerifyProof(ctx, &grpcapi.VerifyProofRequest{
+			Address: claim.Address.Bytes(),
+			Amount:  expected.Amount,
+			Proof:   proof.Proof,
+		})
+		if err != nil {
+			t.Fatalf("VerifyProof failed: %v", err)
+		}
+		if !resp.Valid {
+			t.Error("Expected genuine proof to be valid")
+		}
+
+		resp, err = client.VerifyProof(ctx, &grpcapi.VerifyProofRequest{
+			Address: claim.Address.Bytes(),
+			Amount:  "1",
+			Proof:   proof.Proof,
+		})
+		if err != nil {
+			t.Fatalf("VerifyProof failed: %v", err)
+		}
+		if resp.Valid {
+			t.Error("Expected proof with wrong amount to be invalid")
+		}
+
+		_, err = client.VerifyProof(ctx, &grpcapi.VerifyProofRequest{
+			Address: claim.Address.Bytes(),
+			Amount:  expected.Amount,
+			Proof:   [][]byte{{0x01}},
+		})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for malformed proof, got %v", err)
+		}
+	})
+
+	t.Run("GetStats", func(t *testing.T) {
+		resp, err := client.GetStats(ctx, &grpcapi.GetStatsRequest{})
+		if err != nil {
+			t.Fatalf("GetStats failed: %v", err)
+		}
+		if resp.TotalClaims != 50 || resp.TotalProofs != 50 {
+			t.Errorf("Expected 50 claims and proofs, got %d and %d", resp.TotalClaims, resp.TotalProofs)
+		}
+		if resp.ProofDepth != 6 {
+			t.Errorf("Expected proof depth 6, got %d", resp.ProofDepth)
+		}
+	})
+
+	t.Run("ListClaims", func(t *testing.T) {
+		stream, err := client.ListClaims(ctx, &grpcapi.ListClaimsRequest{StartIndex: 10, Limit: 5})
+		if err != nil {
+			t.Fatalf("ListClaims failed: %v", err)
+		}
+
+		var indices []uint32
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Recv failed: %v", err)
+			}
+			if len(msg.Proof) != 0 {
+				t.Error("Expected proofs to be omitted")
+			}
+			indices = append(indices, msg.Index)
+		}
+
+		if fmt.Sprint(indices) != "[10 11 12 13 14]" {
+			t.Errorf("Expected indices 10-14, got %v", indices)
+		}
+	})
+
+	t.Run("FromProofs", func(t *testing.T) {
+		if _, err := grpcapi.NewServerFromProofs(tree.GetRootHash(), proofs); err != nil {
+			t.Fatalf("Failed to serve the proof set: %v", err)
+		}
+		for _, root := range []string{strings.TrimPrefix(tree.GetRootHash(), "0x"), tree.GetRootHash()[:40], tree.GetRootHash() + "00"} {
+			if _, err := grpcapi.NewServerFromProofs(root, proofs); !errors.Is(err, merkle.ErrInvalidHex) {
+				t.Errorf("%s: expected an invalid hex error, got %v", root, err)
+			}
+		}
+	})
+}
+
+func TestGRPCMatchesHTTP(t *testing.T) {
+	tree, proofs := buildProofSet(t, 30)
+	handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+	client := dialAirdrop(t, grpcapi.NewServer(tree, proofs.Proofs))
+
+	address := tree.Claims[9].Address
+
+	req := httptest.NewRequest(http.MethodGet, "/api/proof/"+address.Hex(), nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var httpProof struct {
+		Proof      []string `json:"proof"`
+		Amount     string   `json:"amount"`
+		Index      uint32   `json:"index"`
+		MerkleRoot string   `json:"merkleRoot"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&httpProof); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	grpcProof, err := client.GetProof(context.Background(), &grpcapi.GetProofRequest{Address: address.Bytes()})
+	if err != nil {
+		t.Fatalf("GetProof failed: %v", err)
+	}
+	root, err := client.GetRoot(context.Background(), &grpcapi.GetRootRequest{})
+	if err != nil {
+		t.Fatalf("GetRoot failed: %v", err)
+	}
+
+	if fmt.Sprintf("0x%x", root.MerkleRoot) != httpProof.MerkleRoot {
+		t.Errorf("Root mismatch: HTTP %s, gRPC 0x%x", httpProof.MerkleRoot, root.MerkleRoot)
+	}
+	if grpcProof.Amount != httpProof.Amount || grpcProof.Index != httpProof.Index {
+		t.Errorf("Claim mismatch: HTTP %s/%d, gRPC %s/%d", httpProof.Amount, httpProof.Index, grpcProof.Amount, grpcProof.Index)
+	}
+
+	grpcHex := make([]string, len(grpcProof.Proof))
+	for i, hash := range grpcProof.Proof {
+		grpcHex[i] = fmt.Sprintf("0x%x", hash)
+	}
+	if fmt.Sprint(grpcHex) != fmt.Sprint(httpProof.Proof) {
+		t.Errorf("Proof mismatch: HTTP %v, gRPC %v", httpProof.Proof, grpcHex)
+	}
+}
+
+func TestGRPCClaimWindow(t *testing.T) {
+	tree, proofs := buildProofSet(t, 10)
+	window := &api.ClaimWindow{Start: "2026-06-01T12:00:00Z", End: "2026-07-01T00:00:00Z"}
+	clock := &stoppedClock{now: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)}
+	server := api.MustNewAPIServer(tree, proofs.Proofs,
+		api.WithCampaign(api.CampaignMeta{Name: "Season 1", ClaimWindow: window}, ""),
+		api.WithClock(clock),
+	)
+	client := dialAirdrop(t, grpcapi.NewServer(tree, proofs.Proofs, grpcapi.WithClaimGate(server.ClaimWindowError)))
+	ctx := context.Background()
+	address := tree.Claims[3].Address
+
+	// calls returns the status code of every RPC that serves a claim
+	calls := func() []codes.Code {
+		_, errProof := client.GetProof(ctx, &grpcapi.GetProofRequest{Address: address.Bytes()})
+		_, errIndex := client.GetProofByIndex(ctx, &grpcapi.GetProofByIndexRequest{Index: 3})
+		var errList error
+		if stream, err := client.ListClaims(ctx, &grpcapi.ListClaimsRequest{IncludeProofs: true}); err != nil {
+			errList = err
+		} else {
+			_, errList = stream.Recv()
+		}
+		return []codes.Code{status.Code(errProof), status.Code(errIndex), status.Code(errList)}
+	}
+
+	for _, tc := range []struct {
+		name string
+		now  time.Time
+		want codes.Code
+	}{
+		{"Upcoming", time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), codes.FailedPrecondition},
+		{"Open", time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC), codes.OK},
+		{"Closed", time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), codes.FailedPrecondition},
+	} {
+		clock.now = tc.now
+		for i, got := range calls() {
+			if got != tc.want {
+				t.Errorf("%s: expected call %d to answer %s, got %s", tc.name, i, tc.want, got)
+			}
+		}
+	}
+
+	if _, err := client.GetRoot(ctx, &grpcapi.GetRootRequest{}); err != nil {
+		t.Errorf("Expected the root to be served outside the window: %v", err)
+	}
+}
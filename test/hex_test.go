@@ -0,0 +1,183 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// hexGoldenFile locks the hex written for hashes, amounts and exported
+// proofs. Regenerate it only for a deliberate format change:
+// go test ./test -run TestHexEncoding -update
+const hexGoldenFile = "hex_golden.json"
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files")
+
+// hexGolden is the content of hexGoldenFile
+type hexGolden struct {
+	Hashes  []string        `json:"hashes"`
+	Amounts []string        `json:"amounts"`
+	Proofs  json.RawMessage `json:"proofs"` // A canonical export
+}
+
+func TestHexEncoding(t *testing.T) {
+	tree, proofs := buildProofSet(t, 5)
+	maxAmount := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	t.Run("Golden", func(t *testing.T) {
+		var golden hexGolden
+		leading := make([]byte, merkle.HashLength)
+		leading[merkle.HashLength-1] = 0x01
+		for _, hash := range [][]byte{make([]byte, merkle.HashLength), leading, tree.Root.Hash} {
+			golden.Hashes = append(golden.Hashes, merkle.EncodeHash(hash))
+		}
+		for _, amount := range []*big.Int{big.NewInt(0), big.NewInt(0x12c), tree.Claims[0].Amount, maxAmount} {
+			encoded, err := merkle.EncodeUint256(amount)
+			if err != nil {
+				t.Fatalf("Failed to encode %s: %v", amount, err)
+			}
+			golden.Amounts = append(golden.Amounts, encoded)
+		}
+		var export bytes.Buffer
+		if err := data.ExportProofsCanonical(proofs, tree.GetRootHash(), &export, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		golden.Proofs = export.Bytes()
+
+		generated, _ := json.MarshalIndent(golden, "", "  ")
+		generated = append(generated, '\n')
+		if *updateGolden {
+			if err := os.WriteFile(hexGoldenFile, generated, 0o644); err != nil {
+				t.Fatalf("Failed to write %s: %v", hexGoldenFile, err)
+			}
+		}
+		want, err := os.ReadFile(hexGoldenFile)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", hexGoldenFile, err)
+		}
+		if !bytes.Equal(generated, want) {
+			t.Fatalf("Encoded hex differs from %s; if the format changed on purpose, regenerate it with: go test ./test -run TestHexEncoding -update", hexGoldenFile)
+		}
+		for _, encoded := range append(golden.Hashes, golden.Amounts...) {
+			if len(encoded) != 66 {
+				t.Errorf("Expected 66 characters, got %d in %s", len(encoded), encoded)
+			}
+		}
+	})
+
+	t.Run("Exporters", func(t *testing.T) {
+		// The binary format stores raw bytes; reading it back must give
+		// the same strings as the tree
+		var binary bytes.Buffer
+		if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &binary); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		loaded, root, err := data.LoadProofsBinary(&binary)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if merkle.EncodeHash(root) != tree.GetRootHash() {
+			t.Errorf("Expected root %s, got %x", tree.GetRootHash(), root)
+		}
+		handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+		for address, proof := range proofs.Proofs {
+			if got := loaded.Proofs[address]; got == nil || !reflect.DeepEqual(got.Proof, proof.Proof) || got.Root != proof.Root {
+				t.Errorf("%s: expected %+v from the binary file, got %+v", address, proof, got)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/proof/"+address, nil))
+			var served api.ProofResponse
+			json.Unmarshal(w.Body.Bytes(), &served)
+			if served.MerkleRoot != tree.GetRootHash() || !reflect.DeepEqual(served.Proof, proof.Proof) {
+				t.Errorf("%s: expected the tree's proof, got %s", address, w.Body)
+			}
+		}
+	})
+
+	t.Run("DecodeHash", func(t *testing.T) {
+		root := tree.GetRootHash()
+		for _, tc := range []struct {
+			name  string
+			input string
+			ok    bool
+		}{
+			{"Lowercase", root, true},
+			{"Uppercase", "0x" + strings.ToUpper(root[2:]), true},
+			{"MissingPrefix", root[2:], false},
+			{"UppercasePrefix", "0X" + root[2:], false},
+			{"OddLength", root[:len(root)-1], false},
+			{"Short", root[:len(root)-2], false},
+			{"OverLong", root + "00", false},
+			{"NotHex", root[:len(root)-2] + "zz", false},
+			{"Empty", "", false},
+			{"PrefixOnly", "0x", false},
+		} {
+			decoded, err := merkle.DecodeHash(tc.input)
+			switch {
+			case tc.ok && (err != nil || !bytes.Equal(decoded, tree.Root.Hash)):
+				t.Errorf("%s: expected the root, got %x (%v)", tc.name, decoded, err)
+			case !tc.ok && !errors.Is(err, merkle.ErrInvalidHex):
+				t.Errorf("%s: expected ErrInvalidHex for %q, got %x (%v)", tc.name, tc.input, decoded, err)
+			}
+		}
+	})
+
+	t.Run("DecodeUint256", func(t *testing.T) {
+		for _, tc := range []struct {
+			input string
+			want  *big.Int // nil to fail
+		}{
+			{"0x0de0b6b3a7640000", big.NewInt(1e18)},
+			{"0x0DE0B6B3A7640000", big.NewInt(1e18)},
+			{"0x" + strings.Repeat("00", 31) + "01", big.NewInt(1)},
+			{"0x" + strings.Repeat("ff", 32), maxAmount},
+			{"0x012c", big.NewInt(300)},
+			{"0x12c", nil},
+			{"012c", nil},
+			{"0x", nil},
+			{"0x" + strings.Repeat("00", 33), nil},
+			{"0x-1", nil},
+		} {
+			amount, err := merkle.DecodeUint256(tc.input)
+			switch {
+			case tc.want != nil && (err != nil || amount.Cmp(tc.want) != 0):
+				t.Errorf("%s: expected %s, got %v (%v)", tc.input, tc.want, amount, err)
+			case tc.want == nil && !errors.Is(err, merkle.ErrInvalidHex):
+				t.Errorf("%s: expected ErrInvalidHex, got %v (%v)", tc.input, amount, err)
+			}
+		}
+
+		for _, amount := range []*big.Int{nil, big.NewInt(-1), new(big.Int).Lsh(big.NewInt(1), 256)} {
+			if encoded, err := merkle.EncodeUint256(amount); err == nil {
+				t.Errorf("Expected %v to be rejected, got %s", amount, encoded)
+			}
+		}
+	})
+
+	t.Run("StrictInputs", func(t *testing.T) {
+		// Proof elements and roots from users are decoded strictly too
+		address := tree.Claims[0].Address
+		proof := *proofs.Proofs[address.Hex()]
+		proof.Proof = append([]string(nil), proof.Proof...)
+		proof.Proof[0] = strings.TrimPrefix(proof.Proof[0], "0x")
+		if ok, err := merkle.VerifyMerkleProof(tree.Root.Hash, tree.Claims[0], &proof, tree.Options()); ok || err == nil {
+			t.Errorf("Expected an element without its prefix to be rejected, got %v %v", ok, err)
+		}
+		if _, err := api.NewAPIServerFromProofs(tree.GetRootHash()[2:], proofs); err == nil {
+			t.Error("Expected a root without its prefix to be rejected")
+		}
+	})
+}
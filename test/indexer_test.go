@@ -0,0 +1,221 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/e2e"
+	"merkle-airdrop/pkg/indexer"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// flakyLogBackend fails the first failures FilterLogs calls and, with
+// noSubscriptions, answers subscriptions like an HTTP-only node
+type flakyLogBackend struct {
+	indexer.Backend
+	noSubscriptions bool
+	failures        atomic.Int32
+}
+
+func (b *flakyLogBackend) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	if b.failures.Add(-1) >= 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+	return b.Backend.FilterLogs(ctx, q)
+}
+
+func (b *flakyLogBackend) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if b.noSubscriptions {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	return b.Backend.SubscribeFilterLogs(ctx, q, ch)
+}
+
+func TestWatchClaims(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(8), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	// eventually polls cond until it holds or a few seconds pass
+	eventually := func(t *testing.T, what string, cond func() bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s", what)
+			}
+		}
+	}
+
+	// deploy starts a chain with a funded distributor and a claim function
+	// that mines each claim in its own block
+	deploy := func(t *testing.T) (*simulated.Backend, common.Address, func(i int) common.Hash) {
+		t.Helper()
+		key, _ := crypto.GenerateKey()
+		deployer := crypto.PubkeyToAddress(key.PublicKey)
+		backend := simulated.NewBackend(types.GenesisAlloc{
+			deployer: {Balance: new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18))},
+		})
+		t.Cleanup(func() { backend.Close() })
+		eth := backend.Client()
+		chainID := big.NewInt(1337)
+		auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		total := merkle.TotalAmount(tree.Claims)
+		tokenAddr, _, token, err := e2e.DeployTestToken(auth, eth, total)
+		if err != nil {
+			t.Fatalf("Failed to deploy token: %v", err)
+		}
+		backend.Commit()
+		var root [32]byte
+		copy(root[:], tree.Root.Hash)
+		client := contract.NewContractClientWithBackend(eth, key, chainID)
+		distributorAddr, err := client.DeployAirdrop(tokenAddr, root)
+		if err != nil {
+			t.Fatalf("Failed to deploy distributor: %v", err)
+		}
+		backend.Commit()
+		if _, err := token.Transfer(auth, distributorAddr, total); err != nil {
+			t.Fatalf("Failed to fund distributor: %v", err)
+		}
+		backend.Commit()
+
+		claim := func(i int) common.Hash {
+			t.Helper()
+			c := tree.Claims[i]
+			proofArgs, _ := contract.DecodeProof(proofs[c.Address.Hex()].Proof)
+			tx, err := client.Claim(distributorAddr, c.Index, c.Address, c.Amount, proofArgs)
+			if err != nil {
+				t.Fatalf("Failed to claim %d: %v", i, err)
+			}
+			backend.Commit()
+			return tx.Hash()
+		}
+		return backend, distributorAddr, claim
+	}
+
+	// watch runs WatchClaims until the test ends, returning its result
+	watch := func(t *testing.T, client indexer.Backend, distributor common.Address, store indexer.ClaimStore, progress *indexer.Progress) <-chan error {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- indexer.WatchClaims(ctx, client, distributor, store,
+				indexer.WithProgress(progress),
+				indexer.WithPollInterval(20*time.Millisecond),
+				indexer.WithBackoff(time.Millisecond, 10*time.Millisecond),
+			)
+		}()
+		t.Cleanup(func() {
+			cancel()
+			<-done
+		})
+		return done
+	}
+
+	t.Run("Subscription", func(t *testing.T) {
+		backend, distributorAddr, claim := deploy(t)
+		claim(1)
+		claim(3)
+
+		store, progress := indexer.NewMemoryStore(), new(indexer.Progress)
+		watch(t, backend.Client(), distributorAddr, store, progress)
+		eventually(t, "the backfill", func() bool { return progress.Synced() && store.Len() == 2 })
+
+		// A live claim arrives over the subscription
+		tx := claim(5)
+		eventually(t, "the live claim", func() bool { return store.Len() == 3 })
+		recorded, _ := store.Claim(uint32(5))
+		if recorded.Account != tree.Claims[5].Address || recorded.Amount.Cmp(tree.Claims[5].Amount) != 0 || recorded.TxHash != tx || recorded.Block == 0 {
+			t.Errorf("Expected claim 5 by %s for %s in tx %s, got %+v", tree.Claims[5].Address.Hex(), tree.Claims[5].Amount, tx.Hex(), recorded)
+		}
+		for i, want := range map[uint32]bool{1: true, 3: true, 5: true, 0: false, 4: false} {
+			if claimed, err := store.IsClaimed(i); err != nil || claimed != want {
+				t.Errorf("Index %d: expected claimed=%v, got %v (%v)", i, want, claimed, err)
+			}
+		}
+
+		head, _ := backend.Client().BlockNumber(context.Background())
+		eventually(t, "the head", func() bool { return progress.HeadBlock() == head && progress.Lag() == 0 })
+
+		// /api/stats reports the indexer's progress
+		handler := api.MustNewAPIServer(tree, proofs, api.WithIndexerProgress(progress)).SetupRoutes()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+		var stats struct {
+			Indexer map[string]interface{} `json:"indexer"`
+		}
+		json.NewDecoder(w.Body).Decode(&stats)
+		if stats.Indexer["headBlock"] != float64(head) || stats.Indexer["lagBlocks"] != float64(0) || stats.Indexer["synced"] != true {
+			t.Errorf("Expected the indexer's progress in the stats, got %v", stats.Indexer)
+		}
+	})
+
+	t.Run("PollingAndReconnect", func(t *testing.T) {
+		backend, distributorAddr, claim := deploy(t)
+		claim(2)
+
+		// Two dropped connections before the backfill gets through, on a
+		// node without subscriptions
+		flaky := &flakyLogBackend{Backend: backend.Client(), noSubscriptions: true}
+		flaky.failures.Store(2)
+		store, progress := indexer.NewMemoryStore(), new(indexer.Progress)
+		watch(t, flaky, distributorAddr, store, progress)
+		eventually(t, "the backfill", func() bool { return progress.Synced() && store.Len() == 1 })
+
+		claim(6)
+		eventually(t, "the polled claim", func() bool { return store.Len() == 2 })
+		if claimed, _ := store.IsClaimed(uint32(6)); !claimed {
+			t.Error("Expected claim 6 to be recorded")
+		}
+	})
+
+	t.Run("StartBlock", func(t *testing.T) {
+		backend, distributorAddr, claim := deploy(t)
+		claim(0)
+		head, _ := backend.Client().BlockNumber(context.Background())
+		claim(7)
+
+		// Claims before the start block are not backfilled
+		store, progress := indexer.NewMemoryStore(), new(indexer.Progress)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- indexer.WatchClaims(ctx, backend.Client(), distributorAddr, store,
+				indexer.WithProgress(progress), indexer.WithStartBlock(head+1))
+		}()
+		eventually(t, "the backfill", func() bool { return progress.Synced() })
+		if claimed, _ := store.IsClaimed(0); claimed || store.Len() != 1 {
+			t.Errorf("Expected only claim 7, got %d claims", store.Len())
+		}
+
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected WatchClaims to return the context's error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestProvidedIndices(t *testing.T) {
+	const (
+		addrA = "0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa"
+		addrB = "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"
+		addrC = "0xcCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCCC"
+	)
+
+	t.Run("Gap", func(t *testing.T) {
+		filename := writeCSV(t, "address,amount,index\n"+
+			addrA+",1,0\n"+
+			addrB+",2,1\n"+
+			addrC+",3,3\n")
+		_, err := data.LoadAirdropFromCSVWithIndices(filename)
+		if err == nil || !strings.Contains(err.Error(), "2 is missing") {
+			t.Errorf("Expected a gap at index 2, got %v", err)
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		filename := writeCSV(t, "address,amount,index\n"+
+			addrA+",1,0\n"+
+			addrB+",2,1\n"+
+			addrC+",3,1\n")
+		_, err := data.LoadAirdropFromCSVWithIndices(filename)
+		if err == nil || !strings.Contains(err.Error(), "duplicate index 1") {
+			t.Errorf("Expected a duplicate index error, got %v", err)
+		}
+	})
+
+	t.Run("OutOfOrder", func(t *testing.T) {
+		// Complete but shuffled indices load as given and survive the tree
+		filename := writeCSV(t, "index,address,amount\n"+
+			"2,"+addrA+",1\n"+
+			"0,"+addrC+",3\n"+
+			"1,"+addrB+",2\n")
+		claims, err := data.LoadAirdropFromCSVWithIndices(filename)
+		if err != nil {
+			t.Fatalf("Failed to load CSV: %v", err)
+		}
+		want := map[string]uint32{addrA: 2, addrB: 1, addrC: 0}
+
+		opts := merkle.DefaultTreeOptions()
+		opts.KeepIndices = true
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		for _, claim := range tree.Claims {
+			if claim.Index != want[claim.Address.Hex()] {
+				t.Errorf("Expected %s to keep index %d, got %d", claim.Address.Hex(), want[claim.Address.Hex()], claim.Index)
+			}
+			proof, err := tree.GenerateProof(claim.Address)
+			if err != nil {
+				t.Fatalf("Failed to generate proof: %v", err)
+			}
+			if valid, err := merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, tree.Options()); err != nil || !valid {
+				t.Errorf("Expected proof for %s to verify, got %v", claim.Address.Hex(), err)
+			}
+		}
+		if !tree.Metadata().KeepIndices {
+			t.Error("Expected the metadata to record kept indices")
+		}
+
+		// Without KeepIndices the tree renumbers by address
+		renumbered, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		for i, claim := range renumbered.Claims {
+			if claim.Index != uint32(i) {
+				t.Errorf("Expected the default tree to rewrite indices, got %s at %d with index %d", claim.Address.Hex(), i, claim.Index)
+			}
+		}
+	})
+
+	t.Run("TreeRejectsDuplicates", func(t *testing.T) {
+		claims := data.GenerateTestData(4)
+		claims[3].Index = 1
+		opts := merkle.DefaultTreeOptions()
+		opts.KeepIndices = true
+		if _, err := merkle.NewMerkleTreeWithOptions(claims, opts); err == nil {
+			t.Error("Expected duplicate indices to be rejected")
+		}
+	})
+
+	t.Run("CheckProofIndices", func(t *testing.T) {
+		tree, proofs := buildProofSet(t, 20)
+		claims := data.CloneClaims(tree.Claims)
+		if issues := data.CheckProofIndices(claims, proofs); len(issues) != 0 {
+			t.Fatalf("Expected no issues, got %v", issues)
+		}
+
+		// Swap two indices, drop one claim's proof and add a stray proof
+		claims[1].Index, claims[2].Index = claims[2].Index, claims[1].Index
+		delete(proofs.Proofs, claims[5].Address.Hex())
+		proofs.Proofs[addrA] = &merkle.MerkleProof{Index: 99, Amount: "1"}
+
+		issues := data.CheckProofIndices(claims, proofs)
+		problems := make(map[string]string, len(issues))
+		for _, issue := range issues {
+			problems[issue.Address] = issue.Problem
+		}
+		if len(issues) != 4 {
+			t.Errorf("Expected 4 issues, got %v", issues)
+		}
+		if !strings.Contains(problems[claims[1].Address.Hex()], "proof index 1, CSV index 2") {
+			t.Errorf("Expected an index mismatch, got %q", problems[claims[1].Address.Hex()])
+		}
+		if problems[claims[5].Address.Hex()] != "no proof" {
+			t.Errorf("Expected a missing proof, got %q", problems[claims[5].Address.Hex()])
+		}
+		if !strings.Contains(problems[addrA], "not in the CSV") {
+			t.Errorf("Expected a stray proof, got %q", problems[addrA])
+		}
+	})
+}
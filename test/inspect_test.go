@@ -0,0 +1,124 @@
+package test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestInspectTree(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(oddFixture(7), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	// Fold the 7-leaf tree by hand
+	must := func(hash []byte, err error) []byte {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hash
+	}
+	var leaves [7][]byte
+	for i, claim := range tree.Claims {
+		leaves[i] = must(merkle.HashLeaf(claim.Address, claim.Amount, claim.Index))
+	}
+	h01 := must(merkle.HashInternal(leaves[0], leaves[1]))
+	h23 := must(merkle.HashInternal(leaves[2], leaves[3]))
+	h45 := must(merkle.HashInternal(leaves[4], leaves[5]))
+	h66 := must(merkle.HashInternal(leaves[6], leaves[6]))
+	h0123 := must(merkle.HashInternal(h01, h23))
+	h4566 := must(merkle.HashInternal(h45, h66))
+	root := must(merkle.HashInternal(h0123, h4566))
+
+	t.Run("Levels", func(t *testing.T) {
+		if tree.Levels() != 4 {
+			t.Fatalf("Expected 4 levels, got %d", tree.Levels())
+		}
+		for i, want := range []int{7, 4, 2, 1} {
+			if got := len(tree.Level(i)); got != want {
+				t.Errorf("Level %d: expected %d nodes, got %d", i, want, got)
+			}
+		}
+		if !bytes.Equal(tree.Level(3)[0], root) || !bytes.Equal(tree.Level(1)[3], h66) {
+			t.Error("Expected the levels to hold the hand-folded hashes")
+		}
+		if tree.Level(-1) != nil || tree.Level(4) != nil {
+			t.Error("Expected no levels outside the tree")
+		}
+	})
+
+	t.Run("PathForAddress", func(t *testing.T) {
+		cases := map[int][]merkle.PathStep{
+			6: {
+				{Level: 0, Position: 6, Hash: leaves[6], Sibling: leaves[6], Parent: h66},
+				{Level: 1, Position: 3, Hash: h66, Sibling: h45, Parent: h4566},
+				{Level: 2, Position: 1, Hash: h4566, Sibling: h0123, Parent: root},
+			},
+			1: {
+				{Level: 0, Position: 1, Hash: leaves[1], Sibling: leaves[0], Parent: h01},
+				{Level: 1, Position: 0, Hash: h01, Sibling: h23, Parent: h0123},
+				{Level: 2, Position: 0, Hash: h0123, Sibling: h4566, Parent: root},
+			},
+		}
+		for leaf, want := range cases {
+			steps, err := tree.PathForAddress(tree.Claims[leaf].Address)
+			if err != nil {
+				t.Fatalf("Leaf %d: %v", leaf, err)
+			}
+			if len(steps) != len(want) {
+				t.Fatalf("Leaf %d: expected %d steps, got %d", leaf, len(want), len(steps))
+			}
+			for i, step := range steps {
+				w := want[i]
+				if step.Level != w.Level || step.Position != w.Position ||
+					!bytes.Equal(step.Hash, w.Hash) || !bytes.Equal(step.Sibling, w.Sibling) || !bytes.Equal(step.Parent, w.Parent) {
+					t.Errorf("Leaf %d, step %d: expected %x, got %x", leaf, i, w, step)
+				}
+			}
+		}
+
+		if _, err := tree.PathForAddress(oddFixture(8)[7].Address); err == nil {
+			t.Error("Expected an unknown address to be rejected")
+		}
+	})
+
+	t.Run("Promoted", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.OddLeafPolicy = merkle.Promote
+		promoted, _ := merkle.NewMerkleTreeWithOptions(oddFixture(7), opts)
+		steps, _ := promoted.PathForAddress(promoted.Claims[6].Address)
+		if steps[0].Sibling != nil || !bytes.Equal(steps[0].Parent, steps[0].Hash) {
+			t.Errorf("Expected the odd leaf to be carried up unchanged, got %x", steps[0])
+		}
+	})
+
+	t.Run("DOT", func(t *testing.T) {
+		var full bytes.Buffer
+		if err := tree.ExportDOT(&full, 7); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		dot := full.String()
+		if !strings.HasPrefix(dot, "digraph merkle {") || !strings.Contains(dot, tree.Claims[0].Address.Hex()) {
+			t.Errorf("Expected a digraph labelled with addresses, got:\n%s", dot)
+		}
+		// 1+2+4 internal nodes with two edges each, one of them for the duplicate
+		if edges := strings.Count(dot, "->"); edges != 14 {
+			t.Errorf("Expected 14 edges, got %d", edges)
+		}
+		if err := tree.ExportDOT(&full, 6); err == nil {
+			t.Error("Expected a tree over maxLeaves to be refused")
+		}
+
+		var path bytes.Buffer
+		if err := tree.ExportAddressDOT(&path, tree.Claims[6].Address); err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		if dot := path.String(); !strings.Contains(dot, "n0_6 ") || strings.Contains(dot, "n0_0 ") {
+			t.Errorf("Expected only the path to leaf 6 and its siblings, got:\n%s", dot)
+		}
+	})
+}
@@ -2,8 +2,11 @@ package test
 
 import (
 	"encoding/json"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -38,12 +41,28 @@ func TestFullWorkflow(t *testing.T) {
 		t.Errorf("Expected %d proofs, got %d", len(claims), len(proofs))
 	}
 
-	// Step 6: Test API endpoints
-	testAPIEndpoints(t, tree, proofs)
+	// Step 6: Test API endpoints, with proofs in memory and on disk
+	t.Run("MemoryStore", func(t *testing.T) {
+		testAPIEndpoints(t, tree, proofs, merkle.MapProofStore(proofs))
+	})
+	t.Run("DiskStore", func(t *testing.T) {
+		store, err := merkle.CreateDiskProofStore(filepath.Join(t.TempDir(), "proofs.store"))
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		defer store.Close()
+		if err := tree.GenerateAllProofsInto(store); err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		testAPIEndpoints(t, tree, proofs, store)
+	})
 }
 
-func testAPIEndpoints(t *testing.T, tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof) {
-	server := api.NewAPIServer(tree, proofs)
+func testAPIEndpoints(t *testing.T, tree *merkle.MerkleTree, proofs map[string]*merkle.MerkleProof, store merkle.ProofStore) {
+	server, err := api.NewAPIServerBuilder().Tree(tree).ProofStore(store).Build()
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
 	handler := server.SetupRoutes()
 
 	// Test root endpoint
@@ -120,6 +139,9 @@ func testAPIEndpoints(t *testing.T, tree *merkle.MerkleTree, proofs map[string]*
 		if totalClaims != len(tree.Claims) {
 			t.Errorf("Expected %d total claims, got %d", len(tree.Claims), totalClaims)
 		}
+		if want := merkle.TotalAmount(tree.Claims).String(); response["totalAmount"] != want {
+			t.Errorf("Expected total amount %s, got %v", want, response["totalAmount"])
+		}
 	})
 
 	// Test verify endpoint
@@ -153,6 +175,53 @@ func testAPIEndpoints(t *testing.T, tree *merkle.MerkleTree, proofs map[string]*
 			t.Error("Expected success to be true")
 		}
 	})
+
+	// Every handler reports errors with the same envelope
+	t.Run("ErrorEnvelope", func(t *testing.T) {
+		missing := "0x000000000000000000000000000000000000dEaD"
+		cases := []struct {
+			method, path, body string
+			status             int
+			code               string
+		}{
+			{http.MethodPost, "/api/root", "", http.StatusMethodNotAllowed, api.CodeMethodNotAllowed},
+			{http.MethodGet, "/api/proof/not-an-address", "", http.StatusBadRequest, api.CodeInvalidAddress},
+			{http.MethodGet, "/api/proof/" + missing, "", http.StatusNotFound, api.CodeAddressNotFound},
+			{http.MethodGet, "/api/eligible/0x12", "", http.StatusBadRequest, api.CodeInvalidAddress},
+			{http.MethodGet, "/api/link/" + missing, "", http.StatusForbidden, api.CodeEndpointDisabled},
+			{http.MethodDelete, "/api/stats", "", http.StatusMethodNotAllowed, api.CodeMethodNotAllowed},
+			{http.MethodPost, "/api/verify", "{", http.StatusBadRequest, api.CodeInvalidRequest},
+			{http.MethodPost, "/api/verify", `{"address": "` + missing + `", "amount": "ten"}`, http.StatusBadRequest, api.CodeInvalidAmount},
+			{http.MethodPost, "/api/verify", `{"address": "` + missing + `", "amount": "10", "proof": ["0xzz"]}`, http.StatusBadRequest, api.CodeInvalidProof},
+			{http.MethodPost, "/api/verify", `{"address": "` + missing + `", "amount": "-10", "proof": []}`, http.StatusBadRequest, api.CodeInvalidAmount},
+			{http.MethodGet, "/api/unknown", "", http.StatusNotFound, api.CodeNotFound},
+			{http.MethodGet, "/unknown", "", http.StatusNotFound, api.CodeNotFound},
+			{http.MethodPost, "/", "", http.StatusNotFound, api.CodeNotFound},
+		}
+
+		for _, tc := range cases {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tc.status {
+				t.Errorf("%s %s: expected status %d, got %d", tc.method, tc.path, tc.status, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("%s %s: expected a JSON content type, got %q", tc.method, tc.path, ct)
+			}
+
+			var response api.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Errorf("%s %s: failed to decode error: %v", tc.method, tc.path, err)
+				continue
+			}
+			if response.Success || response.Error.Code != tc.code || response.Error.Message == "" {
+				t.Errorf("%s %s: expected code %s, got %+v", tc.method, tc.path, tc.code, response)
+			}
+		}
+	})
 }
 
 func TestDataValidation(t *testing.T) {
@@ -181,18 +250,26 @@ func TestDataValidation(t *testing.T) {
 }
 
 func TestTreeProperties(t *testing.T) {
-	claims := data.GenerateTestData(100)
-	tree, err := merkle.NewMerkleTree(claims)
+	claims := data.GenerateRandomTestData(100, 3)
+	original := data.CloneClaims(claims)
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
 	if err != nil {
 		t.Fatalf("Failed to build tree: %v", err)
 	}
+	if !reflect.DeepEqual(claims, original) {
+		t.Fatal("Expected building to leave the input claims unchanged")
+	}
 
 	// Test root hash consistency
 	rootHash1 := tree.GetRootHash()
 
-	// Rebuild tree with same data
-
-	tree2, err := merkle.NewMerkleTree(claims)
+	// Rebuild from an independently shuffled copy; the tree sorts, so the
+	// root must not depend on input order
+	shuffled := data.CloneClaims(original)
+	rand.New(rand.NewSource(11)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	tree2, err := merkle.NewMerkleTreeWithOptions(shuffled, merkle.DefaultTreeOptions())
 	if err != nil {
 		t.Fatalf("Failed to rebuild tree: %v", err)
 	}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+)
+
+func TestCSVLayout(t *testing.T) {
+	want, err := data.LoadAirdropFromCSV("layout_claims.csv")
+	if err != nil || len(want) != 5 {
+		t.Fatalf("Failed to load the canonical fixture: %d claims (%v)", len(want), err)
+	}
+
+	t.Run("Fixtures", func(t *testing.T) {
+		for _, tc := range []struct {
+			file string
+			opts data.LoadOptions
+		}{
+			{"layout_claims.tsv", data.DefaultLoadOptions()},
+			{"layout_claims.tsv", data.LoadOptions{Delimiter: '\t', HasHeader: true}},
+			{"layout_claims_semicolon_bom.csv", data.DefaultLoadOptions()},
+			{"layout_claims_amount_col5.csv", data.DefaultLoadOptions()},
+			{"layout_claims_amount_col5.csv", data.LoadOptions{AddressColumn: "2", AmountColumn: "4", HasHeader: true}},
+			{"layout_claims_amount_col5.csv", data.LoadOptions{Delimiter: ',', AddressColumn: "ADDRESS", AmountColumn: "amount", HasHeader: true}},
+		} {
+			got, err := data.LoadAirdropFromCSVWithOptions(tc.file, tc.opts)
+			if err != nil {
+				t.Errorf("%s %+v: %v", tc.file, tc.opts, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %+v: expected the canonical claims, got %v", tc.file, tc.opts, got)
+			}
+		}
+	})
+
+	t.Run("NoHeader", func(t *testing.T) {
+		path := writeCSV(t, "x;0x0000000000000000000000000000000000000001;1000000000000000000\n")
+		got, err := data.LoadAirdropFromCSVWithOptions(path, data.LoadOptions{AddressColumn: "1", AmountColumn: "2"})
+		if err != nil || !reflect.DeepEqual(got, want[:1]) {
+			t.Errorf("Expected the first canonical claim, got %v (%v)", got, err)
+		}
+		if _, err := data.LoadAirdropFromCSVWithOptions(path, data.LoadOptions{AddressColumn: "wallet"}); err == nil || !strings.Contains(err.Error(), "needs a header") {
+			t.Errorf("Expected a named column without a header to fail, got %v", err)
+		}
+	})
+
+	t.Run("Detection", func(t *testing.T) {
+		// Commas inside quotes don't count
+		path := writeCSV(t, "\"name, full\"|address|amount\n\"a, b\"|0x0000000000000000000000000000000000000001|1000000000000000000\n")
+		got, err := data.LoadAirdropFromCSV(path)
+		if err != nil || !reflect.DeepEqual(got, want[:1]) {
+			t.Errorf("Expected a pipe-delimited file, got %v (%v)", got, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			content string
+			opts    data.LoadOptions
+			want    string
+		}{
+			{"UnknownColumn", "address,amount\n", data.LoadOptions{AmountColumn: "value", HasHeader: true}, "header has no value column"},
+			{"SameColumn", "address,amount\n", data.LoadOptions{AmountColumn: "0", HasHeader: true}, "both column 0"},
+			{"ShortRow", "rank,address,amount\n1,0x0000000000000000000000000000000000000001\n", data.DefaultLoadOptions(), "expected at least 3 fields"},
+		} {
+			_, err := data.LoadAirdropFromCSVWithOptions(writeCSV(t, tc.content), tc.opts)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("%s: expected %q, got %v", tc.name, tc.want, err)
+			}
+		}
+		var rowErr *data.RowError
+		_, err := data.LoadAirdropFromCSV(writeCSV(t, "rank,address,amount\n1,0x0000000000000000000000000000000000000001\n"))
+		if !errors.As(err, &rowErr) || rowErr.Row != 2 {
+			t.Errorf("Expected a RowError for line 2, got %v", err)
+		}
+	})
+
+	t.Run("ParseDelimiter", func(t *testing.T) {
+		for name, want := range map[string]rune{"": 0, "tab": '\t', `\t`: '\t', ";": ';', "|": '|'} {
+			if got, err := data.ParseDelimiter(name); err != nil || got != want {
+				t.Errorf("%q: expected %q, got %q (%v)", name, want, got, err)
+			}
+		}
+		for _, name := range []string{";;", `"`, "\n"} {
+			if _, err := data.ParseDelimiter(name); err == nil {
+				t.Errorf("%q: expected an error", name)
+			}
+		}
+	})
+}
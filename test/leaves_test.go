@@ -0,0 +1,108 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestLeafHashFile(t *testing.T) {
+	claims := data.GenerateTestData(37) // Odd levels on the way up
+	for name, opts := range map[string]merkle.TreeOptions{
+		"Sorted":     merkle.DefaultTreeOptions(),
+		"Positional": {CopyClaims: true, IncludeIndex: true, OddLeafPolicy: merkle.Promote},
+	} {
+		for _, format := range []merkle.LeafFileFormat{merkle.LeafFileHex, merkle.LeafFileBinary} {
+			t.Run(name+"/"+format.String(), func(t *testing.T) {
+				tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+				if err != nil {
+					t.Fatalf("Failed to build tree: %v", err)
+				}
+				path := filepath.Join(t.TempDir(), "leaves")
+				if err := merkle.WriteLeafHashFile(path, tree, format); err != nil {
+					t.Fatalf("Failed to write leaf file: %v", err)
+				}
+
+				built, err := merkle.BuildFromLeafHashFileWithOptions(path, format, opts)
+				if err != nil {
+					t.Fatalf("Failed to build from leaf file: %v", err)
+				}
+				if built.GetRootHash() != tree.GetRootHash() {
+					t.Fatalf("Expected root %s, got %s", tree.GetRootHash(), built.GetRootHash())
+				}
+
+				for i, leaf := range tree.Leaves {
+					want, err := tree.GenerateProof(leaf.Data.Address)
+					if err != nil {
+						t.Fatalf("Failed to generate proof: %v", err)
+					}
+					got, err := built.ProofAt(i)
+					if err != nil {
+						t.Fatalf("Failed to get proof %d: %v", i, err)
+					}
+					if strings.Join(got.Proof, ",") != strings.Join(want.Proof, ",") || got.Positions != want.Positions || got.Index != uint32(i) {
+						t.Fatalf("Expected leaf %d's proof to match the claims tree's", i)
+					}
+					valid, err := merkle.VerifyLeafProof(built.Root.Hash, leaf.Hash, got.Proof, got.Positions, opts)
+					if err != nil || !valid {
+						t.Fatalf("Expected leaf %d's proof to verify: %v", i, err)
+					}
+					claim := *leaf.Data
+					if valid, _ := merkle.VerifyProofWithPositions(built.Root.Hash, claim, got.Proof, got.Positions, opts); !valid {
+						t.Fatalf("Expected leaf %d's proof to verify for its claim", i)
+					}
+				}
+
+				if _, err := built.GenerateAllProofs(); err == nil {
+					t.Error("Expected proofs by address to fail without claims")
+				}
+				if _, err := built.ProofAt(len(tree.Leaves)); err == nil {
+					t.Error("Expected an out of range position to fail")
+				}
+			})
+		}
+	}
+}
+
+func TestLeafHashFileInvalid(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	for name, tc := range map[string]struct {
+		format  merkle.LeafFileFormat
+		content string
+		err     string
+	}{
+		"ShortHexLine":    {merkle.LeafFileHex, "0x" + hash + "\n0x" + hash[:62] + "\n", "line 2"},
+		"BadHex":          {merkle.LeafFileHex, strings.Repeat("zz", 32) + "\n", "line 1"},
+		"BlankLine":       {merkle.LeafFileHex, hash + "\n\n" + hash + "\n", "line 2"},
+		"TruncatedBinary": {merkle.LeafFileBinary, strings.Repeat("x", 32*3+5), "last entry has 5 bytes"},
+		"Empty":           {merkle.LeafFileBinary, "", "no leaf hashes"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "leaves")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := merkle.BuildFromLeafHashFile(path, tc.format)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected an error mentioning %q, got %v", tc.err, err)
+			}
+		})
+	}
+
+	// Uppercase hex without the prefix is accepted
+	path := filepath.Join(t.TempDir(), "leaves")
+	if err := os.WriteFile(path, []byte(strings.ToUpper(hash)+"\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := merkle.BuildFromLeafHashFile(path, merkle.LeafFileHex)
+	if err != nil {
+		t.Fatalf("Failed to build from leaf file: %v", err)
+	}
+	if tree.GetRootHash() != "0x"+hash {
+		t.Errorf("Expected a single leaf to be the root, got %s", tree.GetRootHash())
+	}
+}
@@ -0,0 +1,197 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// forgeClaimLink builds a link around an arbitrary JSON payload
+func forgeClaimLink(t *testing.T, payload []byte) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if _, err := gz.Write(payload); err != nil {
+		t.Fatalf("Failed to compress payload: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to compress payload: %v", err)
+	}
+	return "https://claim.example.org/?claim=" + base64.RawURLEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestClaimLinks(t *testing.T) {
+	const baseURL = "https://claim.example.org/airdrop?ref=qr"
+
+	tree, proofs := buildProofSet(t, 1000)
+	root := tree.Root.Hash
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		for _, i := range []int{0, 1, 499, 999} {
+			claim := tree.Claims[i]
+			proof := proofs.Proofs[claim.Address.Hex()]
+
+			link, err := merkle.EncodeClaimLink(baseURL, claim, proof)
+			if err != nil {
+				t.Fatalf("Failed to encode link: %v", err)
+			}
+			if !strings.HasPrefix(link, "https://claim.example.org/airdrop?") || !strings.Contains(link, "ref=qr") {
+				t.Errorf("Expected the base URL and its query to be kept, got %s", link)
+			}
+
+			decoded, decodedProof, err := merkle.DecodeClaimLink(link, root, tree.Options())
+			if err != nil {
+				t.Fatalf("Failed to decode link: %v", err)
+			}
+			if decoded.Address != claim.Address || decoded.Amount.Cmp(claim.Amount) != 0 || decoded.Index != claim.Index {
+				t.Errorf("Expected claim %+v, got %+v", claim, decoded)
+			}
+			if strings.Join(decodedProof.Proof, ",") != strings.Join(proof.Proof, ",") {
+				t.Error("Decoded proof differs from the original")
+			}
+		}
+	})
+
+	t.Run("WithoutIndex", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.IncludeIndex = false
+		noIndex, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(50), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		claim := noIndex.Claims[7]
+		proof, err := noIndex.GenerateProof(claim.Address)
+		if err != nil {
+			t.Fatalf("Failed to generate proof: %v", err)
+		}
+
+		link, err := merkle.EncodeClaimLinkWithOptions(baseURL, claim, proof, opts)
+		if err != nil {
+			t.Fatalf("Failed to encode link: %v", err)
+		}
+		if _, _, err := merkle.DecodeClaimLink(link, noIndex.Root.Hash, opts); err != nil {
+			t.Errorf("Failed to decode link: %v", err)
+		}
+	})
+
+	t.Run("WrongRoot", func(t *testing.T) {
+		claim := tree.Claims[3]
+		link, err := merkle.EncodeClaimLink(baseURL, claim, proofs.Proofs[claim.Address.Hex()])
+		if err != nil {
+			t.Fatalf("Failed to encode link: %v", err)
+		}
+
+		other, _ := buildProofSet(t, 999)
+		_, _, err = merkle.DecodeClaimLink(link, other.Root.Hash, other.Options())
+		if err == nil || !strings.Contains(err.Error(), "root") {
+			t.Errorf("Expected a root mismatch error, got %v", err)
+		}
+	})
+
+	t.Run("MalformedRoot", func(t *testing.T) {
+		// The root must be strict hex, not merely decode to the tree's
+		claim := tree.Claims[3]
+		payload, _ := json.Marshal(map[string]interface{}{
+			"address": claim.Address.Hex(),
+			"amount":  claim.Amount.String(),
+			"index":   claim.Index,
+			"proof":   proofs.Proofs[claim.Address.Hex()].Proof,
+			"root":    strings.TrimPrefix(tree.GetRootHash(), "0x"),
+		})
+		if _, _, err := merkle.DecodeClaimLink(forgeClaimLink(t, payload), root, tree.Options()); !errors.Is(err, merkle.ErrInvalidHex) {
+			t.Errorf("Expected a root without its 0x prefix to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("TamperedAmount", func(t *testing.T) {
+		claim := tree.Claims[3]
+		payload, _ := json.Marshal(map[string]interface{}{
+			"address": claim.Address.Hex(),
+			"amount":  "999999999999999999999999",
+			"index":   claim.Index,
+			"proof":   proofs.Proofs[claim.Address.Hex()].Proof,
+			"root":    tree.GetRootHash(),
+		})
+
+		if _, _, err := merkle.DecodeClaimLink(forgeClaimLink(t, payload), root, tree.Options()); err == nil {
+			t.Error("Expected a link with a forged amount to be rejected")
+		}
+	})
+
+	t.Run("OversizedPayload", func(t *testing.T) {
+		// Too long to fit a QR code
+		long := "https://claim.example.org/?claim=" + strings.Repeat("A", merkle.MaxClaimLinkPayload+1)
+		if _, _, err := merkle.DecodeClaimLink(long, root, tree.Options()); err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("Expected an oversized link to be rejected, got %v", err)
+		}
+
+		// Short link that decompresses into a huge proof
+		huge, _ := json.Marshal(map[string]interface{}{
+			"address": tree.Claims[0].Address.Hex(),
+			"amount":  tree.Claims[0].Amount.String(),
+			"proof":   []string{strings.Repeat("0", 1<<18)},
+			"root":    tree.GetRootHash(),
+		})
+		link := forgeClaimLink(t, huge)
+		if encoded := strings.SplitN(link, "=", 2)[1]; len(encoded) > merkle.MaxClaimLinkPayload {
+			t.Fatalf("Expected the forged payload to fit the size limit, got %d bytes", len(encoded))
+		}
+		if _, _, err := merkle.DecodeClaimLink(link, root, tree.Options()); err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("Expected a decompression bomb to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w
+		}
+		member := tree.Claims[42]
+
+		handler := api.MustNewAPIServer(tree, proofs.Proofs, api.WithClaimLinkURL(baseURL)).SetupRoutes()
+		w := get(handler, "/api/link/"+strings.ToLower(member.Address.Hex()))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+		var response struct {
+			Link string `json:"link"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if _, err := url.Parse(response.Link); err != nil {
+			t.Fatalf("Expected a URL, got %q", response.Link)
+		}
+		decoded, _, err := merkle.DecodeClaimLink(response.Link, root, tree.Options())
+		if err != nil || decoded.Address != member.Address {
+			t.Errorf("Expected a link for %s, got %+v (%v)", member.Address.Hex(), decoded, err)
+		}
+
+		if w := get(handler, "/api/link/0x000000000000000000000000000000000000dEaD"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for an unknown address, got %d", w.Code)
+		}
+
+		disabled := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+		if w := get(disabled, "/api/link/"+member.Address.Hex()); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 without a claim link URL, got %d", w.Code)
+		}
+		restricted := api.MustNewAPIServer(tree, proofs.Proofs, api.WithClaimLinkURL(baseURL), api.WithProofsDisabled()).SetupRoutes()
+		if w := get(restricted, "/api/link/"+member.Address.Hex()); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 with proofs disabled, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+
+	"github.com/google/uuid"
+)
+
+// logLines decodes captured JSON log output
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestIDs(t *testing.T) {
+	tree, proofs := buildProofSet(t, 50)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	handler := api.MustNewAPIServer(tree, proofs.Proofs, api.WithLogger(logger)).SetupRoutes()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		logs.Reset()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/proof/0x000000000000000000000000000000000000dEaD", nil)
+		req.Header.Set(api.RequestIDHeader, "support-ticket-4711")
+		w := serve(req)
+
+		if got := w.Header().Get(api.RequestIDHeader); got != "support-ticket-4711" {
+			t.Errorf("Expected the request ID to be echoed, got %q", got)
+		}
+		var response api.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode error: %v", err)
+		}
+		if response.RequestID != "support-ticket-4711" {
+			t.Errorf("Expected the request ID in the error envelope, got %q", response.RequestID)
+		}
+	})
+
+	t.Run("Generated", func(t *testing.T) {
+		for _, supplied := range []string{"", "has spaces", strings.Repeat("x", 200)} {
+			req := httptest.NewRequest(http.MethodGet, "/api/root", nil)
+			if supplied != "" {
+				req.Header.Set(api.RequestIDHeader, supplied)
+			}
+			w := serve(req)
+
+			id := w.Header().Get(api.RequestIDHeader)
+			if _, err := uuid.Parse(id); err != nil {
+				t.Errorf("Expected a generated UUID for %q, got %q", supplied, id)
+			}
+		}
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		claim := tree.Claims[3]
+		body, _ := json.Marshal(map[string]interface{}{
+			"address": claim.Address.Hex(),
+			"amount":  claim.Amount.String(),
+			"proof":   proofs.Proofs[claim.Address.Hex()].Proof,
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(api.RequestIDHeader, "verify-42")
+		if w := serve(req); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+
+		lines := logLines(t, &logs)
+		messages := make(map[string]map[string]interface{})
+		for _, line := range lines {
+			if line["request_id"] != "verify-42" {
+				t.Errorf("Expected every log line to carry the request ID, got %v", line)
+			}
+			messages[line["msg"].(string)] = line
+		}
+
+		verified, ok := messages["proof verified"]
+		if !ok || verified["valid"] != true || verified["address"] != claim.Address.Hex() {
+			t.Errorf("Expected a handler log line for the verification, got %v", lines)
+		}
+		access, ok := messages["request"]
+		if !ok || access["method"] != http.MethodPost || access["path"] != "/api/verify" || access["status"] != float64(http.StatusOK) {
+			t.Errorf("Expected an access log line, got %v", lines)
+		}
+		if len(lines) != 2 {
+			t.Errorf("Expected one handler line and one access line, got %d", len(lines))
+		}
+	})
+
+	t.Run("AccessLogStatus", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/unknown", nil)
+		w := serve(req)
+
+		lines := logLines(t, &logs)
+		if len(lines) != 1 {
+			t.Fatalf("Expected one access log line, got %v", lines)
+		}
+		if lines[0]["status"] != float64(http.StatusNotFound) || lines[0]["request_id"] != w.Header().Get(api.RequestIDHeader) {
+			t.Errorf("Expected a 404 line with the generated ID, got %v", lines[0])
+		}
+	})
+
+	t.Run("Context", func(t *testing.T) {
+		if id := api.RequestIDFromContext(context.Background()); id != "" {
+			t.Errorf("Expected no request ID outside a request, got %q", id)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMaxClaims(t *testing.T) {
+	// tooMany checks err is a TooManyClaimsError for limit that stopped at row
+	tooMany := func(t *testing.T, err error, limit, row int) {
+		t.Helper()
+		var limitErr *merkle.TooManyClaimsError
+		if !errors.Is(err, merkle.ErrTooManyClaims) || !errors.As(err, &limitErr) {
+			t.Fatalf("Expected a TooManyClaimsError, got %v", err)
+		}
+		if limitErr.Limit != limit || limitErr.Seen != limit+1 || limitErr.Row != row {
+			t.Errorf("Expected to stop at row %d after %d claims, got %+v", row, limit+1, limitErr)
+		}
+	}
+
+	// max_claims.csv has six claims, then a malformed row a loader that
+	// read on would fail on
+	t.Run("CSV", func(t *testing.T) {
+		opts := data.DefaultLoadOptions()
+		opts.MaxClaims = 3
+		_, err := data.LoadAirdropFromCSVWithOptions("max_claims.csv", opts)
+		tooMany(t, err, 3, 5)
+
+		opts.MaxClaims = 7
+		var rowErr *data.RowError
+		if _, err := data.LoadAirdropFromCSVWithOptions("max_claims.csv", opts); !errors.As(err, &rowErr) || rowErr.Row != 8 {
+			t.Errorf("Expected the malformed row to be read under a higher limit, got %v", err)
+		}
+	})
+
+	t.Run("Indices", func(t *testing.T) {
+		path := writeCSV(t, "address,amount,index\n"+
+			"0x0000000000000000000000000000000000000001,100,0\n"+
+			"0x0000000000000000000000000000000000000002,200,1\n"+
+			"not-an-address,300,2\n")
+		_, err := data.LoadAirdropFromCSVWithIndicesLimit(path, 2)
+		tooMany(t, err, 2, 4)
+		if _, err := data.LoadAirdropFromCSVWithIndicesLimit(path, 0); errors.Is(err, merkle.ErrTooManyClaims) || err == nil {
+			t.Errorf("Expected no limit when zero, got %v", err)
+		}
+	})
+
+	t.Run("DB", func(t *testing.T) {
+		const query = "SELECT address, amount FROM allowlist"
+		db := sql.OpenDB(&fakeConnector{result: fakeResult{query: query, columns: []string{"address", "amount"}, rows: [][]driver.Value{
+			{"0x0000000000000000000000000000000000000001", "100"},
+			{"0x0000000000000000000000000000000000000002", "200"},
+			{nil, nil}, // The query's own error, were it read
+		}}})
+		t.Cleanup(func() { db.Close() })
+		_, err := data.LoadAirdropFromDBWithLimit(context.Background(), db, query, 1)
+		tooMany(t, err, 1, 2)
+	})
+
+	t.Run("Tree", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.MaxClaims = 5
+		_, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(6), opts)
+		var limitErr *merkle.TooManyClaimsError
+		if !errors.As(err, &limitErr) || limitErr.Seen != 6 || limitErr.Row != 0 {
+			t.Errorf("Expected 6 claims over the limit of 5, got %v", err)
+		}
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(5), opts)
+		if err != nil {
+			t.Fatalf("Expected a tree at the limit, got %v", err)
+		}
+		added := []merkle.AirdropClaim{{Address: common.HexToAddress("0x00000000000000000000000000000000000000a1"), Amount: big.NewInt(1)}}
+		if _, err := tree.AddClaims(added); !errors.Is(err, merkle.ErrTooManyClaims) {
+			t.Errorf("Expected an addition over the limit to fail, got %v", err)
+		}
+
+		opts.MaxClaims = -1
+		if _, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(5), opts); err == nil {
+			t.Error("Expected a negative limit to be rejected")
+		}
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		const token = "admin-secret"
+		opts := merkle.DefaultTreeOptions()
+		opts.MaxClaims = 10
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(9), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		var appender *rebuild.Appender
+		appender = rebuild.NewAppender(func(tree *merkle.MerkleTree) (http.Handler, error) {
+			proofs, err := tree.GenerateAllProofs()
+			if err != nil {
+				return nil, err
+			}
+			return api.MustNewAPIServer(tree, proofs, api.WithAdminTokens([]string{token}), api.WithClaimAppender(appender)).SetupRoutes(), nil
+		}, nil)
+		if err := appender.Start(tree); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		appendClaims := func(addresses ...string) (int, string) {
+			req := api.AppendClaimsRequest{}
+			for _, address := range addresses {
+				req.Claims = append(req.Claims, api.AppendClaimEntry{Address: address, Amount: "500"})
+			}
+			body, _ := json.Marshal(req)
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/claims", bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			appender.ServeHTTP(w, r)
+			var apiErr api.ErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &apiErr)
+			return w.Code, apiErr.Error.Code
+		}
+
+		if status, code := appendClaims("0x00000000000000000000000000000000000000a1", "0x00000000000000000000000000000000000000a2"); status != http.StatusUnprocessableEntity || code != api.CodeTooManyClaims {
+			t.Errorf("Expected 11 claims to be refused, got %d %s", status, code)
+		}
+		if versions := appender.TreeVersions(); len(versions) != 1 {
+			t.Errorf("Expected the served tree to be kept, got %d versions", len(versions))
+		}
+		if status, _ := appendClaims("0x00000000000000000000000000000000000000a1"); status != http.StatusOK {
+			t.Errorf("Expected the tenth claim to be appended, got %d", status)
+		}
+	})
+}
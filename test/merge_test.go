@@ -0,0 +1,118 @@
+package test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestMergeClaimSources(t *testing.T) {
+	a := common.HexToAddress("0x000000000000000000000000000000000000000a")
+	b := common.HexToAddress("0x000000000000000000000000000000000000000b")
+	c := common.HexToAddress("0x000000000000000000000000000000000000000c")
+	d := common.HexToAddress("0x000000000000000000000000000000000000000d")
+	claim := func(address common.Address, amount int64) merkle.AirdropClaim {
+		return merkle.AirdropClaim{Address: address, Amount: big.NewInt(amount)}
+	}
+
+	// a is in all three sources, b in two, c and d in one each
+	nft := data.ClaimSource{
+		Label:      "nft",
+		Claims:     []merkle.AirdropClaim{claim(a, 10), claim(b, 7), claim(a, 5)},
+		Multiplier: big.NewRat(2, 1),
+	}
+	stakers := data.ClaimSource{
+		Label:      "stakers",
+		Claims:     []merkle.AirdropClaim{claim(a, 3), claim(c, 5)},
+		Multiplier: big.NewRat(2, 3),
+	}
+	voters := data.ClaimSource{
+		Label: "voters",
+		Load: func() ([]merkle.AirdropClaim, error) {
+			return []merkle.AirdropClaim{claim(a, 1), claim(b, 999), claim(d, 0)}, nil
+		},
+		Flat: big.NewInt(100),
+	}
+
+	claims, report, err := data.MergeClaimSources([]data.ClaimSource{nft, stakers, voters})
+	if err != nil {
+		t.Fatalf("Failed to merge: %v", err)
+	}
+
+	// a: 15*2 + floor(3*2/3) + 100, b: 7*2 + 100, c: floor(5*2/3), d: 100
+	want := map[common.Address]int64{a: 132, b: 114, c: 3, d: 100}
+	if len(claims) != len(want) {
+		t.Fatalf("Expected %d claims, got %d", len(want), len(claims))
+	}
+	for i, claim := range claims {
+		if claim.Index != uint32(i) {
+			t.Errorf("Expected claim %d to have index %d, got %d", i, i, claim.Index)
+		}
+		if claim.Amount.Int64() != want[claim.Address] {
+			t.Errorf("Expected %s to get %d, got %s", claim.Address.Hex(), want[claim.Address], claim.Amount)
+		}
+	}
+	if claims[0].Address != a || claims[3].Address != d {
+		t.Error("Expected claims ordered by address")
+	}
+	if report.Total.Int64() != 349 || report.Addresses != 4 {
+		t.Errorf("Expected 4 claims totalling 349, got %d totalling %s", report.Addresses, report.Total)
+	}
+
+	type sourceWant struct {
+		addresses, overlapping int
+		input, output          int64
+		remainder              *big.Rat
+	}
+	sources := []sourceWant{
+		{addresses: 2, overlapping: 2, input: 22, output: 44, remainder: new(big.Rat)},
+		{addresses: 2, overlapping: 1, input: 8, output: 5, remainder: big.NewRat(1, 3)},
+		{addresses: 3, overlapping: 2, input: 1000, output: 300, remainder: new(big.Rat)},
+	}
+	for i, w := range sources {
+		got := report.Sources[i]
+		if got.Addresses != w.addresses || got.Overlapping != w.overlapping ||
+			got.Input.Int64() != w.input || got.Output.Int64() != w.output || got.Remainder.Cmp(w.remainder) != 0 {
+			t.Errorf("Unexpected report for %s: %+v", got.Label, got)
+		}
+	}
+	if report.Overlaps[3] != 1 || report.Overlaps[2] != 1 || len(report.Overlaps) != 2 {
+		t.Errorf("Expected one address in three sources and one in two, got %v", report.Overlaps)
+	}
+
+	t.Run("ZeroAmounts", func(t *testing.T) {
+		claims, report, err := data.MergeClaimSources([]data.ClaimSource{
+			{Label: "small", Claims: []merkle.AirdropClaim{claim(a, 1), claim(b, 4)}, Multiplier: big.NewRat(1, 3)},
+		})
+		if err != nil {
+			t.Fatalf("Failed to merge: %v", err)
+		}
+		if len(claims) != 1 || claims[0].Address != b || claims[0].Amount.Int64() != 1 || report.Zero != 1 {
+			t.Errorf("Expected only b with 1 and one zero address, got %d claims and %d zero", len(claims), report.Zero)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		loadErr := errors.New("boom")
+		for name, sources := range map[string][]data.ClaimSource{
+			"None":     nil,
+			"Both":     {{Label: "x", Claims: nft.Claims, Multiplier: big.NewRat(1, 1), Flat: big.NewInt(1)}},
+			"Negative": {{Label: "x", Claims: nft.Claims, Multiplier: big.NewRat(-1, 1)}},
+			"Load":     {{Label: "x", Load: func() ([]merkle.AirdropClaim, error) { return nil, loadErr }}},
+			"Amount":   {{Label: "x", Claims: []merkle.AirdropClaim{claim(a, -1)}}},
+		} {
+			_, _, err := data.MergeClaimSources(sources)
+			if err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+			if name == "Load" && !errors.Is(err, loadErr) {
+				t.Errorf("Expected the load error to be wrapped, got %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// oddFixture extends pairsFixture to n claims; leaf L4 is
+// 0x1f13a2033d37e49d8ca1a6115ea6a09b4431c5cb92c25a81401864c728680cfa
+func oddFixture(n int) []merkle.AirdropClaim {
+	claims := make([]merkle.AirdropClaim, n)
+	for i := range claims {
+		claims[i] = merkle.AirdropClaim{
+			Address: common.BigToAddress(big.NewInt(int64(i + 1))),
+			Amount:  big.NewInt(int64(100 * (i + 1))),
+		}
+	}
+	return claims
+}
+
+func TestOddLeafPolicy(t *testing.T) {
+	policies := []merkle.OddLeafPolicy{merkle.DuplicateLast, merkle.Promote, merkle.ZeroPad}
+
+	t.Run("FixtureRoots", func(t *testing.T) {
+		// With sorted pairs h(a, b) and Z = 32 zero bytes:
+		//
+		//	3 leaves: duplicate h(h(L0, L1), h(L2, L2)), promote h(h(L0, L1), L2),
+		//	          zero h(h(L0, L1), h(L2, Z))
+		//	5 leaves: duplicate h(H0123, h(h(L4, L4), h(L4, L4))),
+		//	          promote h(H0123, L4), zero h(H0123, h(h(L4, Z), Z))
+		roots := map[int]map[merkle.OddLeafPolicy]string{
+			3: {
+				merkle.DuplicateLast: "0xa2ffa7f03bffdfa9eda4e473fffa8d0d9d70f6954e4237659c65d6eae2bdb331",
+				merkle.Promote:       "0x8fe011e511a8839dd8788d357274bf0f83ca44fa4038aab7ca6d88bb0ee69c41",
+				merkle.ZeroPad:       "0xf1d60a370efb53366c1d54b91a628b1f8ad57992b0504466d7253fd31ddd3026",
+			},
+			5: {
+				merkle.DuplicateLast: "0x0a30d94d0f830ad658c4ca84e4287bdf3795f0319268269de1c015230c290a63",
+				merkle.Promote:       "0x5af639ac8bf7c29853051af4984ce6ff73868e890dfae5cabea3dbaa2068fa1b",
+				merkle.ZeroPad:       "0x4cf69735c0c39d43b632a202480f7d9361362b1abe5d8742af5145b6922737dd",
+			},
+		}
+		for n, byPolicy := range roots {
+			for policy, want := range byPolicy {
+				opts := merkle.DefaultTreeOptions()
+				opts.OddLeafPolicy = policy
+				tree, err := merkle.NewMerkleTreeWithOptions(oddFixture(n), opts)
+				if err != nil {
+					t.Fatalf("%d leaves, %s: failed to build tree: %v", n, policy, err)
+				}
+				if got := tree.GetRootHash(); got != want {
+					t.Errorf("%d leaves, %s: expected root %s, got %s", n, policy, want, got)
+				}
+
+				claims := make([]merkle.GenericClaim, n)
+				for i, claim := range tree.Claims {
+					claims[i] = claim.Generic()
+				}
+				opts.SortOrder = merkle.PreserveInput
+				generic, err := merkle.NewGenericMerkleTree(claims, opts)
+				if err != nil || generic.GetRootHash() != want {
+					t.Errorf("%d leaves, %s: expected the generic tree to match, got %v", n, policy, err)
+				}
+			}
+		}
+	})
+
+	t.Run("Proofs", func(t *testing.T) {
+		for _, policy := range policies {
+			for _, sorted := range []bool{true, false} {
+				for n := 1; n <= 17; n++ {
+					opts := merkle.DefaultTreeOptions()
+					opts.OddLeafPolicy = policy
+					opts.SortedPairs = sorted
+					tree, err := merkle.NewMerkleTreeWithOptions(oddFixture(n), opts)
+					if err != nil {
+						t.Fatalf("Failed to build tree: %v", err)
+					}
+					proofs, err := tree.GenerateAllProofs()
+					if err != nil {
+						t.Fatalf("Failed to generate proofs: %v", err)
+					}
+					for _, claim := range tree.Claims {
+						proof := proofs[claim.Address.Hex()]
+						valid, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof, proof.Positions, tree.Options())
+						if err != nil || !valid {
+							t.Fatalf("%s, sorted=%v, %d leaves: proof for claim %d failed: %v", policy, sorted, n, claim.Index, err)
+						}
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("PromotedProofLength", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.OddLeafPolicy = merkle.Promote
+		tree, _ := merkle.NewMerkleTreeWithOptions(oddFixture(5), opts)
+		last, _ := tree.GenerateProof(tree.Claims[4].Address)
+		first, _ := tree.GenerateProof(tree.Claims[0].Address)
+		if len(last.Proof) != 1 || len(first.Proof) != 3 {
+			t.Errorf("Expected proofs of 1 and 3 elements, got %d and %d", len(last.Proof), len(first.Proof))
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.OddLeafPolicy = merkle.ZeroPad
+		tree, _ := merkle.NewMerkleTreeWithOptions(oddFixture(3), opts)
+		encoded, _ := json.Marshal(tree.Metadata())
+		if !strings.Contains(string(encoded), `"oddLeafPolicy":"zero"`) {
+			t.Errorf("Expected the policy in metadata, got %s", encoded)
+		}
+		var decoded merkle.TreeMetadata
+		if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Options().OddLeafPolicy != merkle.ZeroPad {
+			t.Errorf("Expected the policy to round-trip, got %v (%v)", decoded.OddLeafPolicy, err)
+		}
+
+		// The default stays out of existing exports
+		encoded, _ = json.Marshal(merkle.DefaultMetadata())
+		if strings.Contains(string(encoded), "oddLeafPolicy") {
+			t.Errorf("Expected no policy in default metadata, got %s", encoded)
+		}
+
+		if policy := data.UniswapTreeOptions().OddLeafPolicy; policy != merkle.Promote {
+			t.Errorf("Expected the Uniswap preset to promote odd nodes, got %s", policy)
+		}
+		if _, err := merkle.ParseOddLeafPolicy("pad"); err == nil {
+			t.Error("Expected an unknown policy to be rejected")
+		}
+	})
+}
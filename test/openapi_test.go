@@ -0,0 +1,193 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// openAPISpec is the part of an OpenAPI document the tests read
+type openAPISpec struct {
+	OpenAPI    string                                `json:"openapi"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(12), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, _ := tree.GenerateAllProofs()
+
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	// spec fetches and parses the server's document
+	spec := func(handler http.Handler) openAPISpec {
+		t.Helper()
+		w := get(handler, "/api/openapi.json")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("Expected the document, got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		var doc openAPISpec
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Failed to parse the document: %v", err)
+		}
+		if doc.OpenAPI != "3.0.3" {
+			t.Errorf("Expected OpenAPI 3.0.3, got %q", doc.OpenAPI)
+		}
+		return doc
+	}
+	// operations lists the document's paths with their methods
+	operations := func(doc openAPISpec) []string {
+		var ops []string
+		for path, item := range doc.Paths {
+			var methods []string
+			for method := range item {
+				methods = append(methods, method)
+			}
+			sort.Strings(methods)
+			ops = append(ops, path+" "+strings.Join(methods, ","))
+		}
+		sort.Strings(ops)
+		return ops
+	}
+
+	t.Run("EveryRoute", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs,
+			api.WithReservation(api.NewMemoryClaimStore()),
+			api.WithCampaign(api.CampaignMeta{Name: "Season 1"}, ""),
+			api.WithBloomFilter(0.01),
+		).SetupRoutes()
+		want := []string{
+			"/api/admin/campaign put",
+			"/api/admin/issuance/{address} delete",
+			"/api/admin/simulate post",
+			"/api/bloom get,head",
+			"/api/campaign get",
+			"/api/docs get",
+			"/api/eligible/{address} get",
+			"/api/link/{address} get",
+			"/api/nonce/{address} get",
+			"/api/openapi.json get",
+			"/api/progress get",
+			"/api/proof/{address} get",
+			"/api/root get",
+			"/api/schema get",
+			"/api/stats get",
+			"/api/verify post",
+			"/api/verify/batch post",
+			"/healthz get",
+			"/readyz get",
+		}
+		doc := spec(handler)
+		if got := operations(doc); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected operations\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+		}
+
+		var admin struct {
+			Security []map[string][]string `json:"security"`
+		}
+		json.Unmarshal(doc.Paths["/api/admin/campaign"]["put"], &admin)
+		if len(admin.Security) != 1 {
+			t.Errorf("Expected admin routes to require the bearer token, got %v", admin.Security)
+		}
+
+		page := get(handler, "/api/docs")
+		if page.Code != http.StatusOK || !strings.HasPrefix(page.Header().Get("Content-Type"), "text/html") {
+			t.Fatalf("Expected the docs page, got %d", page.Code)
+		}
+		for _, path := range want {
+			if path := strings.Fields(path)[0]; !strings.Contains(page.Body.String(), path) {
+				t.Errorf("Expected the docs page to list %s", path)
+			}
+		}
+	})
+
+	t.Run("DisabledRoutes", func(t *testing.T) {
+		doc := spec(api.MustNewAPIServer(tree, proofs).SetupRoutes())
+		for _, path := range []string{"/api/bloom", "/api/campaign", "/api/nonce/{address}"} {
+			if _, ok := doc.Paths[path]; ok {
+				t.Errorf("Expected %s to be left out while disabled", path)
+			}
+		}
+	})
+
+	t.Run("SchemasMatchResponses", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		doc := spec(handler)
+		address := tree.Claims[3].Address.Hex()
+		for path, schema := range map[string]string{
+			"/api/root":             "RootResponse",
+			"/api/stats":            "StatsResponse",
+			"/api/proof/" + address: "ProofResponse",
+			"/healthz":              "HealthResponse",
+		} {
+			component, ok := doc.Components.Schemas[schema]
+			if !ok {
+				t.Fatalf("Expected a %s schema", schema)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(get(handler, path).Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode %s: %v", path, err)
+			}
+			for key := range body {
+				if _, ok := component.Properties[key]; !ok {
+					t.Errorf("%s sent %q, which %s doesn't describe", path, key, schema)
+				}
+			}
+			for _, key := range component.Required {
+				if _, ok := body[key]; !ok {
+					t.Errorf("%s left out %q, which %s requires", path, key, schema)
+				}
+			}
+		}
+	})
+}
+
+func TestEndpointRegistration(t *testing.T) {
+	router := api.NewRouter(nil)
+	router.Handle(api.Endpoint{
+		Path:      "/api/thing/",
+		Param:     "id",
+		Methods:   []string{http.MethodGet},
+		Responses: map[int]interface{}{http.StatusOK: api.HealthResponse{}},
+		Handler:   func(w http.ResponseWriter, r *http.Request) {},
+	})
+	doc := api.OpenAPIDocument(router.Endpoints())
+	if _, ok := doc["paths"].(map[string]interface{})["/api/thing/{id}"]; !ok {
+		t.Errorf("Expected the path parameter in the document, got %v", doc["paths"])
+	}
+
+	for name, endpoint := range map[string]api.Endpoint{
+		"NoMethods":      {Path: "/api/a", Handler: func(w http.ResponseWriter, r *http.Request) {}},
+		"NoHandler":      {Path: "/api/b", Methods: []string{http.MethodGet}},
+		"UnnamedParam":   {Path: "/api/c/", Methods: []string{http.MethodGet}, Handler: func(w http.ResponseWriter, r *http.Request) {}},
+		"ParamWithSlash": {Path: "/api/d", Param: "id", Methods: []string{http.MethodGet}, Handler: func(w http.ResponseWriter, r *http.Request) {}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected registration to panic")
+				}
+			}()
+			router.Handle(endpoint)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"merkle-airdrop/pkg/merkle"
+	"merkle-airdrop/pkg/merkle/testvectors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestOptimizedHashLeaf(t *testing.T) {
+	encodings := map[string]merkle.TreeOptions{
+		"default":    merkle.DefaultTreeOptions(),
+		"packed":     {SortedPairs: true},
+		"indexFirst": {IncludeIndex: true, IndexFirst: true, SortedPairs: true},
+	}
+
+	t.Run("RandomSample", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(11))
+		for i := 0; i < 20000; i++ {
+			var address common.Address
+			rng.Read(address[:])
+			amount := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(rng.Intn(257))))
+			index := rng.Uint32()
+
+			for name, opts := range encodings {
+				// Checked against the hand-built preimage of the test vectors
+				want := crypto.Keccak256(testvectors.Preimage(name, merkle.AirdropClaim{Address: address, Amount: amount, Index: index}))
+				got, err := merkle.OptimizedHashLeafWithOptions(address, amount, index, opts)
+				if err != nil || !bytes.Equal(got, want) {
+					t.Fatalf("%s: expected %x for %s, %s, %d, got %x (%v)", name, want, address.Hex(), amount, index, got, err)
+				}
+			}
+		}
+	})
+
+	t.Run("Allocations", func(t *testing.T) {
+		address := common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+		amount, _ := new(big.Int).SetString("123456789000000000000", 10)
+		for name, opts := range encodings {
+			// Both share one pooled implementation, leaving the returned
+			// hash as the only allocation
+			plain := testing.AllocsPerRun(1000, func() { merkle.HashLeafWithOptions(address, amount, 7, opts) })
+			pooled := testing.AllocsPerRun(1000, func() { merkle.OptimizedHashLeafWithOptions(address, amount, 7, opts) })
+			if plain > 1 || pooled > 1 {
+				t.Errorf("%s: expected at most 1 allocation, got %.0f for HashLeaf and %.0f pooled", name, plain, pooled)
+			}
+		}
+	})
+}
@@ -0,0 +1,255 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// pairsFixture is four claims for addresses 0x…01 to 0x…04 with amounts
+// 100 to 400. Their leaves, keccak256(pad32(address) || uint256(amount) ||
+// uint32(index)), are:
+//
+//	L0 = 0x5b6dcf99e5554aed937deee8a4f68de0ca59f06945b3086d4bea6325dc3d3ec8
+//	L1 = 0x3e8f1ebea51fff3a1e656024e695e22e1bb7a5eb4fb872a3999bccd1d2bcea27
+//	L2 = 0xccb329593cb2e36d4a97dd143fbf6e187611ddbbee753335453349aa5009ff19
+//	L3 = 0x79cf72fbad1a9696a57d88e66dd60c8788015541dc29728d5968c95f15da1b6a
+func pairsFixture() []merkle.AirdropClaim {
+	claims := make([]merkle.AirdropClaim, 4)
+	for i := range claims {
+		claims[i] = merkle.AirdropClaim{
+			Address: common.BigToAddress(big.NewInt(int64(i + 1))),
+			Amount:  big.NewInt(int64(100 * (i + 1))),
+		}
+	}
+	return claims
+}
+
+func TestSortedPairs(t *testing.T) {
+	build := func(t *testing.T, claims []merkle.AirdropClaim, sorted bool) *merkle.MerkleTree {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		opts.SortedPairs = sorted
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		return tree
+	}
+
+	t.Run("FixtureRoots", func(t *testing.T) {
+		// Sorted: L1 < L0 and L3 < L2, so the level above is
+		// H01 = keccak(L1 || L0) and H23 = keccak(L3 || L2). H01 < H23, so
+		// the root is keccak(H01 || H23).
+		//
+		// Positional: the left child always comes first, so the root is
+		// keccak(keccak(L0 || L1) || keccak(L2 || L3)).
+		roots := map[bool]string{
+			true:  "0x81cb710a0a0c37a0e368769a60dc89e2c1a3f34ff105de88459e7f0064796bbe",
+			false: "0x0a156e07244db2bb0bc990153575d4425bb244e8740be127a7f404716516339b",
+		}
+		for sorted, want := range roots {
+			tree := build(t, pairsFixture(), sorted)
+			if got := tree.GetRootHash(); got != want {
+				t.Errorf("SortedPairs=%v: expected root %s, got %s", sorted, want, got)
+			}
+			if tree.Metadata().SortedPairs != sorted {
+				t.Errorf("SortedPairs=%v: metadata records %v", sorted, tree.Metadata().SortedPairs)
+			}
+		}
+	})
+
+	t.Run("Positions", func(t *testing.T) {
+		for _, n := range []int{4, 5, 13} {
+			claims := pairsFixture()
+			if n != 4 {
+				claims = data.GenerateTestData(n)
+			}
+			tree := build(t, claims, false)
+			opts := tree.Options()
+
+			for i, claim := range tree.Claims {
+				proof, err := tree.GenerateProof(claim.Address)
+				if err != nil {
+					t.Fatalf("Failed to generate proof: %v", err)
+				}
+				// A leaf is a right child wherever its position has a set bit
+				if proof.Positions != uint64(i) {
+					t.Errorf("%d leaves: leaf %d has positions %b", n, i, proof.Positions)
+				}
+
+				valid, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof, proof.Positions, opts)
+				if err != nil || !valid {
+					t.Errorf("%d leaves: proof %d did not verify (%v)", n, i, err)
+				}
+				if len(proof.Proof) > 0 {
+					valid, _ := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof, proof.Positions^1, opts)
+					if valid && proof.Proof[0] != fmt.Sprintf("0x%x", tree.Leaves[i].Hash) {
+						t.Errorf("%d leaves: proof %d verified with a flipped position", n, i)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("PositionsRequired", func(t *testing.T) {
+		tree := build(t, pairsFixture(), false)
+		claim := tree.Claims[1]
+		proof, _ := tree.GenerateProof(claim.Address)
+
+		if _, err := merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, tree.Options()); err == nil {
+			t.Error("Expected VerifyProof to require positions")
+		}
+		if _, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof, 1<<len(proof.Proof), tree.Options()); err == nil {
+			t.Error("Expected positions beyond the proof to be rejected")
+		}
+
+		// Sorted trees ignore positions
+		sorted := build(t, pairsFixture(), true)
+		proof, _ = sorted.GenerateProof(claim.Address)
+		if proof.Positions != 0 {
+			t.Errorf("Expected no positions for a sorted tree, got %b", proof.Positions)
+		}
+		valid, err := merkle.VerifyProofWithPositions(sorted.Root.Hash, claim, proof.Proof, 3, sorted.Options())
+		if err != nil || !valid {
+			t.Errorf("Expected a sorted proof to verify regardless of positions (%v)", err)
+		}
+	})
+
+	t.Run("Exports", func(t *testing.T) {
+		tree := build(t, data.GenerateTestData(9), false)
+		proofs, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+
+		var binary bytes.Buffer
+		if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &binary); err != nil {
+			t.Fatalf("Failed to export proofs: %v", err)
+		}
+		fromBinary, _, err := data.LoadProofsBinary(&binary)
+		if err != nil {
+			t.Fatalf("Failed to load binary proofs: %v", err)
+		}
+
+		content, _ := json.Marshal(map[string]interface{}{
+			"merkleRoot": tree.GetRootHash(),
+			"metadata":   proofs.Metadata,
+			"proofs":     proofs.Proofs,
+		})
+		_, fromJSON, err := data.LoadProofsJSON(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("Failed to load JSON proofs: %v", err)
+		}
+
+		for name, loaded := range map[string]*merkle.ProofSet{"Binary": fromBinary, "JSON": fromJSON} {
+			if loaded.Metadata.SortedPairs {
+				t.Errorf("%s: expected metadata to record positional pairs", name)
+			}
+			opts := loaded.Metadata.Options()
+			for _, claim := range tree.Claims {
+				proof := loaded.Proofs[claim.Address.Hex()]
+				valid, err := merkle.VerifyProofWithPositions(tree.Root.Hash, claim, proof.Proof, proof.Positions, opts)
+				if err != nil || !valid {
+					t.Errorf("%s: proof for %s did not verify (%v)", name, claim.Address.Hex(), err)
+				}
+			}
+		}
+
+		// Files from before the option have sorted pairs
+		_, old, err := data.LoadProofsJSON(strings.NewReader(`{"merkleRoot":"0x00","metadata":{"includeIndex":true},"proofs":{}}`))
+		if err != nil {
+			t.Fatalf("Failed to load proofs: %v", err)
+		}
+		if !old.Metadata.SortedPairs {
+			t.Error("Expected metadata without sortedPairs to mean sorted pairs")
+		}
+	})
+
+	t.Run("ClaimLink", func(t *testing.T) {
+		tree := build(t, pairsFixture(), false)
+		claim := tree.Claims[3]
+		proof, _ := tree.GenerateProof(claim.Address)
+
+		link, err := merkle.EncodeClaimLinkWithOptions("https://claim.example/", claim, proof, tree.Options())
+		if err != nil {
+			t.Fatalf("Failed to encode link: %v", err)
+		}
+		_, decoded, err := merkle.DecodeClaimLink(link, tree.Root.Hash, tree.Options())
+		if err != nil {
+			t.Fatalf("Failed to decode link: %v", err)
+		}
+		if decoded.Positions != proof.Positions {
+			t.Errorf("Expected positions %b, got %b", proof.Positions, decoded.Positions)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		tree := build(t, pairsFixture(), false)
+		proofs, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+		claim := tree.Claims[2]
+
+		req := httptest.NewRequest(http.MethodGet, "/api/proof/"+claim.Address.Hex(), nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var fetched struct {
+			Proof     []string `json:"proof"`
+			Positions *uint64  `json:"positions"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&fetched); err != nil {
+			t.Fatalf("Failed to decode proof: %v", err)
+		}
+		if fetched.Positions == nil || *fetched.Positions != 2 {
+			t.Fatalf("Expected positions 2 for leaf 2, got %v", fetched.Positions)
+		}
+
+		verify := func(payload map[string]interface{}) bool {
+			t.Helper()
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			var response struct {
+				Valid bool `json:"valid"`
+			}
+			json.NewDecoder(w.Body).Decode(&response)
+			return response.Valid
+		}
+
+		payload := map[string]interface{}{
+			"address": claim.Address.Hex(),
+			"amount":  claim.Amount.String(),
+			"proof":   fetched.Proof,
+		}
+		if !verify(payload) {
+			t.Error("Expected the proof to verify with the recorded positions")
+		}
+		payload["positions"] = *fetched.Positions
+		if !verify(payload) {
+			t.Error("Expected the proof to verify with explicit positions")
+		}
+		payload["positions"] = 1
+		if verify(payload) {
+			t.Error("Expected the proof to fail with wrong positions")
+		}
+	})
+}
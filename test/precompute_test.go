@@ -0,0 +1,119 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestPrecompute(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(40), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	get := func(handler http.Handler, path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		return w, body
+	}
+
+	t.Run("ServesWhileGenerating", func(t *testing.T) {
+		server := mustServer(t)(api.NewPrecomputingAPIServer(tree))
+		handler := server.SetupRoutes()
+
+		for _, path := range []string{"/api/root", "/healthz"} {
+			if w, _ := get(handler, path); w.Code != http.StatusOK {
+				t.Errorf("Expected %s to answer before the proofs exist, got %d", path, w.Code)
+			}
+		}
+		if _, progress := get(handler, "/api/progress"); progress["done"] != 0.0 || progress["total"] != 40.0 || progress["complete"] != false {
+			t.Errorf("Expected no proofs to be ready, got %v", progress)
+		}
+
+		address := tree.Claims[7].Address.Hex()
+		w, body := get(handler, "/api/proof/"+address)
+		if w.Code != http.StatusAccepted || body["ready"] != false || w.Header().Get("Retry-After") == "" {
+			t.Fatalf("Expected 202 for a proof not yet generated, got %d %v", w.Code, body)
+		}
+
+		// The request moved the proof ahead of the rest
+		deadline := time.Now().Add(5 * time.Second)
+		for w.Code == http.StatusAccepted && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+			w, body = get(handler, "/api/proof/"+address)
+		}
+		if w.Code != http.StatusOK || body["amount"] != tree.Claims[7].Amount.String() {
+			t.Fatalf("Expected the proof to become ready, got %d %v", w.Code, body)
+		}
+		if _, progress := get(handler, "/api/progress"); progress["done"] != 1.0 || progress["percent"] != 2.5 {
+			t.Errorf("Expected one proof to be ready, got %v", progress)
+		}
+
+		// Unknown addresses never become ready
+		if w, _ := get(handler, "/api/proof/0x000000000000000000000000000000000000dEaD"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an address outside the airdrop, got %d", w.Code)
+		}
+
+		if err := server.Precompute(); err != nil {
+			t.Fatalf("Precompute failed: %v", err)
+		}
+		if _, progress := get(handler, "/api/progress"); progress["done"] != 40.0 || progress["complete"] != true {
+			t.Errorf("Expected every proof to be ready, got %v", progress)
+		}
+		for _, claim := range tree.Claims {
+			if w, _ := get(handler, "/api/proof/"+claim.Address.Hex()); w.Code != http.StatusOK {
+				t.Fatalf("Expected 200 for %s after precomputing, got %d", claim.Address.Hex(), w.Code)
+			}
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		server := mustServer(t)(api.NewPrecomputingAPIServer(tree))
+		handler := server.SetupRoutes()
+
+		done := make(chan error)
+		go func() { done <- server.Precompute() }()
+		for _, claim := range tree.Claims {
+			if w, _ := get(handler, "/api/proof/"+claim.Address.Hex()); w.Code != http.StatusOK && w.Code != http.StatusAccepted {
+				t.Errorf("Expected 200 or 202 mid-generation, got %d", w.Code)
+			}
+			get(handler, "/api/progress")
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("Precompute failed: %v", err)
+		}
+		if _, progress := get(handler, "/api/progress"); progress["done"] != 40.0 {
+			t.Errorf("Expected each proof to be counted once, got %v", progress)
+		}
+	})
+
+	t.Run("SignatureFirst", func(t *testing.T) {
+		// A pending proof confirms membership, so it takes a signature too
+		server := mustServer(t)(api.NewPrecomputingAPIServer(tree, api.WithPrivacyMode(0)))
+		w, _ := get(server.SetupRoutes(), "/api/proof/"+tree.Claims[7].Address.Hex())
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for an unsigned request mid-generation, got %d", w.Code)
+		}
+	})
+
+	t.Run("Precomputed", func(t *testing.T) {
+		proofs, err := tree.GenerateAllProofs()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		if _, progress := get(handler, "/api/progress"); progress["complete"] != true || progress["percent"] != 100.0 {
+			t.Errorf("Expected progress to be complete without precomputing, got %v", progress)
+		}
+	})
+}
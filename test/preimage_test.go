@@ -0,0 +1,100 @@
+package test
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"merkle-airdrop/pkg/merkle"
+	"merkle-airdrop/pkg/merkle/testvectors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestLeafPreimage(t *testing.T) {
+	// The test vectors' encodings, with and without a domain separator
+	encodings := make(map[string]merkle.TreeOptions)
+	names := make(map[string]string)
+	for _, encoding := range testvectors.Encodings {
+		opts := encoding.Metadata.Options()
+		encodings[encoding.Name] = opts
+		opts.DomainSeparator = merkle.DomainSeparatorFor("Season 1")
+		encodings[encoding.Name+"+domain"] = opts
+		names[encoding.Name], names[encoding.Name+"+domain"] = encoding.Name, encoding.Name
+	}
+	// reference builds the preimage by hand, independently of pkg/merkle
+	reference := func(name string, claim merkle.AirdropClaim) []byte {
+		return append(append([]byte{}, encodings[name].DomainSeparator...), testvectors.Preimage(names[name], claim)...)
+	}
+
+	t.Run("MatchesHashes", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(5))
+		hash := make([]byte, 32)
+		for i := 0; i < 5000; i++ {
+			var claim merkle.AirdropClaim
+			rng.Read(claim.Address[:])
+			claim.Amount = new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(rng.Intn(257))))
+			claim.Index = rng.Uint32()
+
+			for name, opts := range encodings {
+				want := reference(name, claim)
+				// Appending keeps what dst held
+				preimage, err := merkle.EncodeLeafPreimage([]byte("prefix"), claim, opts)
+				if err != nil || !bytes.Equal(preimage, append([]byte("prefix"), want...)) {
+					t.Fatalf("%s: expected preimage %x, got %x (%v)", name, want, preimage, err)
+				}
+
+				wantHash := crypto.Keccak256(want)
+				if err := merkle.HashLeafInto(hash, claim, opts); err != nil || !bytes.Equal(hash, wantHash) {
+					t.Fatalf("%s: expected hash %x, got %x (%v)", name, wantHash, hash, err)
+				}
+				if got, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts); !bytes.Equal(got, wantHash) {
+					t.Fatalf("%s: HashLeafWithOptions disagrees: %x", name, got)
+				}
+				if got, _ := merkle.HashGenericLeaf(claim.Address.Bytes(), claim.Amount, claim.Index, opts); !bytes.Equal(got, wantHash) {
+					t.Fatalf("%s: HashGenericLeaf disagrees: %x", name, got)
+				}
+			}
+		}
+	})
+
+	t.Run("AllocationFree", func(t *testing.T) {
+		claim := merkle.AirdropClaim{
+			Address: common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"),
+			Amount:  new(big.Int).Lsh(big.NewInt(1), 200),
+			Index:   42,
+		}
+		for name, opts := range encodings {
+			// Thousands of preimages into one preallocated arena
+			arena := make([]byte, 0, 1000*128)
+			allocs := testing.AllocsPerRun(10, func() {
+				arena = arena[:0]
+				for i := 0; i < 1000; i++ {
+					claim.Index = uint32(i)
+					arena, _ = merkle.EncodeLeafPreimage(arena, claim, opts)
+				}
+			})
+			if allocs != 0 {
+				t.Errorf("%s: expected EncodeLeafPreimage not to allocate, got %.1f allocations", name, allocs)
+			}
+
+			hash := make([]byte, 32)
+			if allocs := testing.AllocsPerRun(1000, func() { merkle.HashLeafInto(hash, claim, opts) }); allocs != 0 {
+				t.Errorf("%s: expected HashLeafInto not to allocate, got %.1f allocations", name, allocs)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		claim := merkle.AirdropClaim{Address: common.HexToAddress("0x1"), Amount: big.NewInt(-1)}
+		if _, err := merkle.EncodeLeafPreimage(nil, claim, merkle.DefaultTreeOptions()); err == nil {
+			t.Error("Expected an error for a negative amount")
+		}
+		claim.Amount = big.NewInt(1)
+		if err := merkle.HashLeafInto(make([]byte, 20), claim, merkle.DefaultTreeOptions()); err == nil {
+			t.Error("Expected an error for a hash buffer that isn't 32 bytes")
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// fixedHistory is an api.TreeHistory holding trees by version
+type fixedHistory map[int]*merkle.MerkleTree
+
+func (h fixedHistory) TreeAt(version int) (*merkle.MerkleTree, bool) {
+	tree, ok := h[version]
+	return tree, ok
+}
+
+func TestPrivacyMode(t *testing.T) {
+	const verifyAttempts = 3
+	keyed, keysByAddress := data.GenerateTestDataWithKeys(2, 3)
+	var keys []*ecdsa.PrivateKey
+	for _, claim := range keyed {
+		keys = append(keys, keysByAddress[claim.Address])
+	}
+	claims := append(data.GenerateTestData(6), keyed...)
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	clock := &stoppedClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var logs bytes.Buffer
+	handler := api.MustNewAPIServer(tree, proofs,
+		api.WithPrivacyMode(verifyAttempts),
+		api.WithClock(clock),
+		api.WithLogger(slog.New(slog.NewJSONHandler(&logs, nil))),
+		api.WithClaimLinkURL("https://claim.example"),
+		api.WithTreeHistory(fixedHistory{1: tree, 2: tree}),
+	).SetupRoutes()
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, api.ErrorResponse) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var apiErr api.ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &apiErr)
+		return w, apiErr
+	}
+	verify := func(claim merkle.AirdropClaim, amount *big.Int, query string) (*httptest.ResponseRecorder, api.ErrorResponse) {
+		body, _ := json.Marshal(api.VerifyRequest{
+			Address: claim.Address.Hex(),
+			Amount:  amount.String(),
+			Proof:   proofs[claim.Address.Hex()].Proof,
+		})
+		return do(http.MethodPost, "/api/verify"+query, string(body))
+	}
+	owner := crypto.PubkeyToAddress(keys[0].PublicKey)
+
+	t.Run("ProofRequiresSignature", func(t *testing.T) {
+		if w, apiErr := do(http.MethodGet, "/api/proof/"+owner.Hex(), ""); w.Code != http.StatusUnauthorized || apiErr.Error.Code != api.CodeInvalidSignature {
+			t.Errorf("Expected 401 %s without a signature, got %d %s", api.CodeInvalidSignature, w.Code, w.Body)
+		}
+
+		w, _ := do(http.MethodGet, "/api/nonce/"+owner.Hex(), "")
+		var issued api.NonceResponse
+		json.Unmarshal(w.Body.Bytes(), &issued)
+		forged := personalSign(t, keys[1], issued.Message)
+		if w, _ := do(http.MethodGet, "/api/proof/"+owner.Hex()+"?signature="+forged, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for another key's signature, got %d %s", w.Code, w.Body)
+		}
+
+		// Unlike reservation mode, the holder can fetch the proof again
+		for i := 0; i < 2; i++ {
+			w, _ := do(http.MethodGet, "/api/nonce/"+owner.Hex(), "")
+			json.Unmarshal(w.Body.Bytes(), &issued)
+			w, _ = do(http.MethodGet, "/api/proof/"+owner.Hex()+"?signature="+personalSign(t, keys[0], issued.Message), "")
+			var proof api.ProofResponse
+			json.Unmarshal(w.Body.Bytes(), &proof)
+			if w.Code != http.StatusOK || proof.Amount != keyed[0].Amount.String() {
+				t.Fatalf("Fetch %d: expected the proof, got %d %s", i+1, w.Code, w.Body)
+			}
+			if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+				t.Errorf("Expected Cache-Control no-store, got %q", cc)
+			}
+		}
+	})
+
+	t.Run("BlockedSurfaces", func(t *testing.T) {
+		address := claims[0].Address.Hex()
+		var batch bytes.Buffer
+		json.NewEncoder(&batch).Encode(api.VerifyRequest{Address: address, Amount: "1", Proof: proofs[address].Proof})
+		for _, tc := range []struct {
+			name string
+			req  *http.Request
+		}{
+			{"ClaimLink", httptest.NewRequest(http.MethodGet, "/api/link/"+address, nil)},
+			{"Consistency", httptest.NewRequest(http.MethodGet, "/api/consistency/"+address+"?from=1&to=2", nil)},
+			{"TierStats", httptest.NewRequest(http.MethodGet, "/api/stats?tiers=100,1000", nil)},
+			{"Batch", httptest.NewRequest(http.MethodPost, "/api/verify/batch", &batch)},
+		} {
+			tc.req.Header.Set("Content-Type", "application/x-ndjson")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, tc.req)
+			var apiErr api.ErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &apiErr)
+			if w.Code != http.StatusForbidden || apiErr.Error.Code != api.CodeEndpointDisabled {
+				t.Errorf("%s: expected 403 %s, got %d %s", tc.name, api.CodeEndpointDisabled, w.Code, w.Body)
+			}
+		}
+
+		// Stats without tiers only give totals
+		if w, _ := do(http.MethodGet, "/api/stats", ""); w.Code != http.StatusOK {
+			t.Errorf("Expected stats, got %d %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("TraceOmitsReference", func(t *testing.T) {
+		claim := claims[1]
+		w, _ := verify(claim, new(big.Int).Add(claim.Amount, big.NewInt(1)), "?trace=true")
+		var response api.VerifyResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusOK || response.Valid || response.Trace == nil {
+			t.Fatalf("Expected an invalid trace, got %d %s", w.Code, w.Body)
+		}
+		if response.Trace.Failure != merkle.RootMismatch || strings.Contains(w.Body.String(), claim.Amount.String()) {
+			t.Errorf("Expected ROOT_MISMATCH without the tree's amount, got %s", w.Body)
+		}
+	})
+
+	t.Run("LogsOmitAmounts", func(t *testing.T) {
+		logs.Reset()
+		claim := claims[4]
+		if w, _ := verify(claim, claim.Amount, ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d %s", w.Code, w.Body)
+		}
+		var logged bool
+		for _, line := range logLines(t, &logs) {
+			if line["msg"] != "proof verified" {
+				continue
+			}
+			logged = true
+			if _, ok := line["amount"]; ok {
+				t.Errorf("Expected the amount to be left out of the log, got %v", line)
+			}
+		}
+		if !logged {
+			t.Error("Expected the verification to be logged")
+		}
+	})
+
+	t.Run("VerifyAttemptLimit", func(t *testing.T) {
+		target, other := claims[2], claims[3]
+		guess := big.NewInt(1)
+		for i := 0; i < verifyAttempts; i++ {
+			if w, _ := verify(target, guess, ""); w.Code != http.StatusOK {
+				t.Fatalf("Attempt %d: expected 200, got %d %s", i+1, w.Code, w.Body)
+			}
+			guess.Add(guess, big.NewInt(1))
+		}
+
+		// Even the right amount is refused once the address is used up
+		w, apiErr := verify(target, target.Amount, "")
+		if w.Code != http.StatusTooManyRequests || apiErr.Error.Code != api.CodeRateLimited || w.Header().Get("Retry-After") == "" {
+			t.Fatalf("Expected 429 %s with Retry-After, got %d %s", api.CodeRateLimited, w.Code, w.Body)
+		}
+
+		// The limit is per address
+		if w, _ := verify(other, other.Amount, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"valid":true`) {
+			t.Errorf("Expected another address to verify, got %d %s", w.Code, w.Body)
+		}
+
+		clock.now = clock.now.Add(time.Hour)
+		if w, _ := verify(target, target.Amount, ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"valid":true`) {
+			t.Errorf("Expected the limit to reset after an hour, got %d %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("RedactedExport", func(t *testing.T) {
+		proofSet := &merkle.ProofSet{Proofs: proofs, Metadata: tree.Metadata()}
+		var export bytes.Buffer
+		if err := data.ExportProofsRedacted(proofSet, nil, tree.GetRootHash(), &export); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		if strings.Contains(export.String(), `"amount"`) {
+			t.Errorf("Expected no amounts in the export, got %s", export.String())
+		}
+		file, err := data.LoadRedactedProofs(&export)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		if file.TotalClaims != len(claims) {
+			t.Errorf("Expected %d proofs, got %d", len(claims), file.TotalClaims)
+		}
+		if err := file.Verify(); err != nil {
+			t.Errorf("Expected the export to verify: %v", err)
+		}
+		claim := claims[4]
+		if ok, err := file.VerifyClaim(claim.Address, claim.Amount); !ok || err != nil {
+			t.Errorf("Expected the claim's amount to verify, got %v %v", ok, err)
+		}
+		if ok, _ := file.VerifyClaim(claim.Address, new(big.Int).Add(claim.Amount, big.NewInt(1))); ok {
+			t.Error("Expected another amount not to verify")
+		}
+
+		// A proof that doesn't lead to the root is never written out
+		tampered := *proofs[claim.Address.Hex()]
+		tampered.Proof = append([]string{merkle.EncodeHash(make([]byte, merkle.HashLength))}, tampered.Proof[1:]...)
+		bad := &merkle.ProofSet{Proofs: map[string]*merkle.MerkleProof{claim.Address.Hex(): &tampered}, Metadata: tree.Metadata()}
+		if err := data.ExportProofsRedacted(bad, nil, tree.GetRootHash(), &bytes.Buffer{}); err == nil {
+			t.Error("Expected a tampered proof to fail the export")
+		}
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Server.PrivacyMode = true
+		cfg.Server.GRPCPort = 9090
+		if err := cfg.ValidateAll(); err == nil || !strings.Contains(err.Error(), "privacy_mode") {
+			t.Errorf("Expected privacy_mode with gRPC to be rejected, got %v", err)
+		}
+		cfg = config.DefaultConfig()
+		cfg.Server.PrivacyVerifyAttempts = 3
+		if err := cfg.ValidateAll(); err == nil || !strings.Contains(err.Error(), "requires privacy_mode") {
+			t.Errorf("Expected attempts without privacy_mode to be rejected, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// withinDeadline fails t if fn hasn't returned after a generous deadline,
+// so a deadlocked worker pool fails the test rather than hanging it
+func withinDeadline(t *testing.T, name string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("%s: still running after 10s", name)
+	}
+}
+
+// settleGoroutines waits for the goroutine count to fall back to at most
+// want, returning the last count seen
+func settleGoroutines(want int) int {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= want || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProofPool(t *testing.T) {
+	build := func(t *testing.T, count, workers, buffer int) *merkle.MerkleTree {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		opts.Workers = workers
+		opts.ProofBuffer = buffer
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(count), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		return tree
+	}
+
+	t.Run("NoClaims", func(t *testing.T) {
+		emptied := build(t, 50, 4, 0)
+		emptied.Claims = emptied.Claims[:0]
+		for name, tree := range map[string]*merkle.MerkleTree{
+			"ZeroValue": {Claims: []merkle.AirdropClaim{}},
+			"NoLeaves":  {},
+			"Emptied":   emptied,
+		} {
+			withinDeadline(t, name, func() {
+				if proofs, err := tree.GenerateAllProofs(); !errors.Is(err, merkle.ErrEmptyClaims) {
+					t.Errorf("%s: expected ErrEmptyClaims, got %d proofs (%v)", name, len(proofs), err)
+				}
+				if _, err := tree.GenerateAllProofsWithWorkers(0); !errors.Is(err, merkle.ErrEmptyClaims) {
+					t.Errorf("%s: expected ErrEmptyClaims with one worker per CPU, got %v", name, err)
+				}
+				err := tree.GenerateAllProofsTo(func(string, *merkle.MerkleProof) error { return nil })
+				if !errors.Is(err, merkle.ErrEmptyClaims) {
+					t.Errorf("%s: expected ErrEmptyClaims from GenerateAllProofsTo, got %v", name, err)
+				}
+				if err := tree.ForEachProof(func(common.Address, *merkle.MerkleProof) {}); !errors.Is(err, merkle.ErrEmptyClaims) {
+					t.Errorf("%s: expected ErrEmptyClaims from ForEachProof, got %v", name, err)
+				}
+			})
+		}
+	})
+
+	t.Run("WorkerCounts", func(t *testing.T) {
+		// More workers than leaves, and a single leaf, still prove every claim
+		for _, tc := range []struct{ claims, workers int }{{1, 0}, {1, 8}, {3, 64}, {100, 1}} {
+			tree := build(t, tc.claims, tc.workers, 0)
+			name := fmt.Sprintf("%d claims, %d workers", tc.claims, tc.workers)
+			withinDeadline(t, name, func() {
+				proofs, err := tree.GenerateAllProofs()
+				if err != nil || len(proofs) != tc.claims {
+					t.Errorf("%s: expected %d proofs, got %d (%v)", name, tc.claims, len(proofs), err)
+				}
+			})
+		}
+	})
+
+	t.Run("NoLeakOnError", func(t *testing.T) {
+		const count = 2000
+		tree := build(t, count, 8, 16)
+		before := runtime.NumGoroutine()
+		for _, failAt := range []int{1, 2, 17, count / 2, count - 1, count} {
+			failure := errors.New("store unavailable")
+			calls := 0
+			name := fmt.Sprintf("failing at proof %d", failAt)
+			withinDeadline(t, name, func() {
+				err := tree.GenerateAllProofsTo(func(string, *merkle.MerkleProof) error {
+					if calls++; calls == failAt {
+						return failure
+					}
+					return nil
+				})
+				if !errors.Is(err, failure) || calls != failAt {
+					t.Errorf("%s: expected the failure after %d calls, got %v after %d", name, failAt, err, calls)
+				}
+			})
+			if n := settleGoroutines(before); n > before {
+				buf := make([]byte, 1<<16)
+				t.Fatalf("%s: %d goroutines left running, expected %d:\n%s", name, n, before, buf[:runtime.Stack(buf, true)])
+			}
+		}
+	})
+}
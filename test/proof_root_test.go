@@ -0,0 +1,213 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/client"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestProofRoot(t *testing.T) {
+	tree, proofs := buildProofSet(t, 10)
+	root := hexutil.MustDecode(tree.GetRootHash())
+
+	// The next campaign: same addresses, one amount changed
+	claims := data.GenerateTestData(10)
+	claims[9].Amount.Add(claims[9].Amount, big.NewInt(1))
+	newTree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	newRoot := hexutil.MustDecode(newTree.GetRootHash())
+
+	claim := tree.Claims[3]
+	proof := proofs.Proofs[claim.Address.Hex()]
+	opts := tree.Options()
+
+	t.Run("Generated", func(t *testing.T) {
+		for address, proof := range proofs.Proofs {
+			if proof.Root != tree.GetRootHash() {
+				t.Fatalf("Expected %s's proof to record the root, got %q", address, proof.Root)
+			}
+		}
+		single, _ := tree.GenerateProof(claim.Address)
+		if single.Root != tree.GetRootHash() {
+			t.Errorf("Expected GenerateProof to record the root, got %q", single.Root)
+		}
+	})
+
+	t.Run("Matching", func(t *testing.T) {
+		if valid, err := merkle.VerifyMerkleProof(root, claim, proof, opts); !valid || err != nil {
+			t.Errorf("Expected the proof to verify, got %v (%v)", valid, err)
+		}
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		valid, err := merkle.VerifyMerkleProof(newRoot, claim, proof, opts)
+		if valid || !errors.Is(err, merkle.ErrRootMismatch) {
+			t.Fatalf("Expected ErrRootMismatch, got %v (%v)", valid, err)
+		}
+		var mismatch *merkle.RootMismatchError
+		if !errors.As(err, &mismatch) || mismatch.ProofRoot != tree.GetRootHash() || mismatch.Root != newTree.GetRootHash() {
+			t.Errorf("Expected both roots in the error, got %+v", mismatch)
+		}
+
+		malformed := *proof
+		malformed.Root = "0x1234"
+		var proofErr *merkle.InvalidProofError
+		if _, err := merkle.VerifyMerkleProof(root, claim, &malformed, opts); !errors.As(err, &proofErr) {
+			t.Errorf("Expected a malformed root to be an InvalidProofError, got %v", err)
+		}
+	})
+
+	t.Run("MissingField", func(t *testing.T) {
+		// A proofs file from before roots were recorded
+		encoded, _ := json.Marshal(map[string]interface{}{
+			"merkleRoot": tree.GetRootHash(),
+			"metadata":   proofs.Metadata,
+			"proofs": map[string]interface{}{
+				claim.Address.Hex(): map[string]interface{}{"proof": proof.Proof, "index": proof.Index, "amount": proof.Amount},
+			},
+		})
+		_, old, err := data.LoadProofsJSON(bytes.NewReader(encoded))
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		legacy := old.Proofs[claim.Address.Hex()]
+		if legacy.Root != "" {
+			t.Fatalf("Expected no root, got %q", legacy.Root)
+		}
+		if valid, err := merkle.VerifyMerkleProof(root, claim, legacy, opts); !valid || err != nil {
+			t.Errorf("Expected a proof without a root to verify, got %v (%v)", valid, err)
+		}
+		if valid, err := merkle.VerifyMerkleProof(newRoot, claim, legacy, opts); valid || err != nil {
+			t.Errorf("Expected a proof without a root to be plain invalid elsewhere, got %v (%v)", valid, err)
+		}
+
+		// Exports record the root for it
+		var buf bytes.Buffer
+		if err := data.ExportProofsSubset(old, []common.Address{claim.Address, tree.Claims[0].Address}, tree.GetRootHash(), &buf, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		_, exported, _ := data.LoadProofsJSON(&buf)
+		if got := exported.Proofs[claim.Address.Hex()].Root; got != tree.GetRootHash() {
+			t.Errorf("Expected the export to record the root, got %q", got)
+		}
+		if legacy.Root != "" {
+			t.Error("Expected the export to leave the loaded proof alone")
+		}
+	})
+
+	t.Run("Exports", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := data.ExportProofsCanonical(proofs, tree.GetRootHash(), &buf, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		_, canonical, _ := data.LoadProofsJSON(&buf)
+		if got := canonical.Proofs[claim.Address.Hex()].Root; got != tree.GetRootHash() {
+			t.Errorf("Expected the canonical export to record the root, got %q", got)
+		}
+
+		buf.Reset()
+		if err := data.ExportProofsBinary(proofs, root, &buf); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		binary, _, err := data.LoadProofsBinary(&buf)
+		if err != nil || binary.Proofs[claim.Address.Hex()].Root != tree.GetRootHash() {
+			t.Errorf("Expected binary proofs to load with the root (%v)", err)
+		}
+
+		// Proofs of one tree exported under another root are refused
+		if err := data.ExportProofsSubset(proofs, []common.Address{claim.Address}, newTree.GetRootHash(), &buf, data.ProofMarshaller{}); !errors.Is(err, merkle.ErrRootMismatch) {
+			t.Errorf("Expected a subset under another root to fail, got %v", err)
+		}
+		if err := data.ExportProofsBinary(proofs, newRoot, &buf); !errors.Is(err, merkle.ErrRootMismatch) {
+			t.Errorf("Expected a binary export under another root to fail, got %v", err)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		newProofs, _ := newTree.GenerateAllProofs()
+		handler := api.MustNewAPIServer(newTree, newProofs).SetupRoutes()
+		newProof, _ := newTree.GenerateProof(claim.Address)
+		verify := func(t *testing.T, body map[string]interface{}) (int, []byte) {
+			t.Helper()
+			encoded, _ := json.Marshal(body)
+			req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(encoded))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			return w.Code, w.Body.Bytes()
+		}
+		request := func(proof *merkle.MerkleProof) map[string]interface{} {
+			return map[string]interface{}{"address": claim.Address.Hex(), "amount": proof.Amount, "proof": proof.Proof}
+		}
+
+		stale := request(proof)
+		stale["root"] = proof.Root
+		status, body := verify(t, stale)
+		var apiErr api.ErrorResponse
+		json.Unmarshal(body, &apiErr)
+		if status != http.StatusConflict || apiErr.Error.Code != api.CodeRootMismatch || !strings.Contains(apiErr.Error.Message, tree.GetRootHash()) {
+			t.Errorf("Expected a 409 ROOT_MISMATCH naming the proof's root, got %d %s", status, body)
+		}
+		echoed := request(proof)
+		echoed["merkleRoot"] = tree.GetRootHash()
+		if status, body := verify(t, echoed); status != http.StatusConflict {
+			t.Errorf("Expected an echoed stale merkleRoot to be a 409, got %d %s", status, body)
+		}
+
+		current := request(newProof)
+		current["root"] = newProof.Root
+		var verification api.VerifyResponse
+		status, body = verify(t, current)
+		json.Unmarshal(body, &verification)
+		if status != http.StatusOK || !verification.Valid {
+			t.Errorf("Expected the current proof to verify, got %d %s", status, body)
+		}
+		status, body = verify(t, request(newProof))
+		json.Unmarshal(body, &verification)
+		if status != http.StatusOK || !verification.Valid {
+			t.Errorf("Expected a proof without a root to verify, got %d %s", status, body)
+		}
+
+		t.Run("Batch", func(t *testing.T) {
+			line, _ := json.Marshal(data.BatchEntry{Address: claim.Address.Hex(), Amount: proof.Amount, Proof: proof.Proof, Root: proof.Root})
+			req := httptest.NewRequest(http.MethodPost, "/api/verify/batch", bytes.NewReader(append(line, '\n')))
+			req.Header.Set("Content-Type", "application/x-ndjson")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			var result data.BatchResult
+			json.Unmarshal(bytes.SplitN(w.Body.Bytes(), []byte("\n"), 2)[0], &result)
+			if result.Valid || !strings.Contains(result.Reason, "proof is for root") {
+				t.Errorf("Expected the stale entry to be invalid for its root, got %s", w.Body.String())
+			}
+		})
+
+		t.Run("Client", func(t *testing.T) {
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			c, _ := client.NewClient(server.URL)
+			fetched, err := c.GetProof(context.Background(), claim.Address)
+			if err != nil || fetched.Root != newTree.GetRootHash() {
+				t.Fatalf("Expected the fetched proof to record the served root, got %+v (%v)", fetched, err)
+			}
+			if _, err := c.VerifyProof(context.Background(), claim, proof); !errors.Is(err, merkle.ErrRootMismatch) {
+				t.Errorf("Expected the client to report ErrRootMismatch, got %v", err)
+			}
+		})
+	})
+}
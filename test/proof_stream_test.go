@@ -0,0 +1,147 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestProofStream(t *testing.T) {
+	build := func(t *testing.T, count, workers, buffer int) *merkle.MerkleTree {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		opts.Workers = workers
+		opts.ProofBuffer = buffer
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(count), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		return tree
+	}
+
+	t.Run("LeafOrder", func(t *testing.T) {
+		tree := build(t, 1000, 8, 16)
+		want, err := tree.GenerateAllProofs()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		got := make(map[string]*merkle.MerkleProof)
+		var order []string
+		err = tree.GenerateAllProofsTo(func(address string, proof *merkle.MerkleProof) error {
+			got[address] = proof
+			order = append(order, address)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to stream proofs: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Error("Expected the streamed proofs to match GenerateAllProofs")
+		}
+		for i, address := range order {
+			if address != tree.Claims[i].Address.Hex() {
+				t.Fatalf("Expected proof %d for %s, got %s", i, tree.Claims[i].Address.Hex(), address)
+			}
+		}
+	})
+
+	t.Run("SlowSink", func(t *testing.T) {
+		const buffer = 32
+		var peak int
+		opts := merkle.DefaultTreeOptions()
+		opts.Workers = 8
+		opts.ProofBuffer = buffer
+		opts.OnProofHeld = func(held int) { peak = max(peak, held) }
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(2000), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+
+		count := 0
+		err = tree.GenerateAllProofsTo(func(string, *merkle.MerkleProof) error {
+			if count%100 == 0 {
+				time.Sleep(time.Millisecond) // Lets the workers catch up and wait
+			}
+			count++
+			return nil
+		})
+		if err != nil || count != 2000 {
+			t.Fatalf("Expected 2000 proofs, got %d (%v)", count, err)
+		}
+		if peak > buffer {
+			t.Errorf("Expected at most %d proofs held, got %d", buffer, peak)
+		}
+		if peak < buffer/2 {
+			t.Errorf("Expected the workers to fill the buffer ahead of the sink, got a peak of %d", peak)
+		}
+
+		// The disk store is filled the same way
+		peak = 0
+		if err := tree.GenerateAllProofsInto(merkle.MapProofStore{}); err != nil {
+			t.Fatalf("Failed to fill the store: %v", err)
+		}
+		if peak == 0 || peak > buffer {
+			t.Errorf("Expected the store to fill within the buffer, got a peak of %d", peak)
+		}
+	})
+
+	t.Run("SinkError", func(t *testing.T) {
+		tree := build(t, 5000, 4, 8)
+		stop := errors.New("disk full")
+		calls := 0
+		err := tree.GenerateAllProofsTo(func(string, *merkle.MerkleProof) error {
+			if calls++; calls == 100 {
+				return stop
+			}
+			return nil
+		})
+		if !errors.Is(err, stop) || calls != 100 {
+			t.Errorf("Expected generation to stop at the sink's error, got %d calls (%v)", calls, err)
+		}
+	})
+
+	t.Run("Spill", func(t *testing.T) {
+		tree := build(t, 1000, 4, 16)
+		var buf bytes.Buffer
+		if err := data.ExportTreeProofsBinary(tree, &buf); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		loaded, root, err := data.LoadProofsBinary(&buf)
+		if err != nil {
+			t.Fatalf("Failed to load: %v", err)
+		}
+		want, _ := tree.GenerateProofSet()
+		if !bytes.Equal(root, tree.Root.Hash) || !reflect.DeepEqual(loaded.Proofs, want.Proofs) || loaded.Metadata != want.Metadata {
+			t.Error("Expected the spilled file to load as the tree's proofs")
+		}
+
+		// The same bytes as exporting the generated set
+		var exported bytes.Buffer
+		data.ExportProofsBinary(want, tree.Root.Hash, &exported)
+		buf.Reset()
+		data.ExportTreeProofsBinary(tree, &buf)
+		if !bytes.Equal(buf.Bytes(), exported.Bytes()) {
+			t.Error("Expected the spilled file to match ExportProofsBinary")
+		}
+
+		pw, _ := data.NewBinaryProofWriter(&buf, tree.Root.Hash, tree.Metadata(), 2)
+		pw.WriteProof(tree.Claims[0].Address.Hex(), want.Proofs[tree.Claims[0].Address.Hex()])
+		if err := pw.Close(); err == nil || !strings.Contains(err.Error(), "1 of the header's 2") {
+			t.Errorf("Expected a short file to fail, got %v", err)
+		}
+	})
+
+	t.Run("NegativeBuffer", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.ProofBuffer = -1
+		if _, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), opts); err == nil {
+			t.Error("Expected a negative proof buffer to be rejected")
+		}
+	})
+}
@@ -0,0 +1,196 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func FuzzHashLeaf(f *testing.F) {
+	f.Add([]byte{}, []byte{}, uint32(0), true, false)
+	f.Add(bytes.Repeat([]byte{0xff}, 20), bytes.Repeat([]byte{0xff}, 32), ^uint32(0), true, true)
+	f.Add([]byte{0x01}, []byte{0x01}, uint32(1), false, false)
+
+	f.Fuzz(func(t *testing.T, addr, amountBytes []byte, index uint32, includeIndex, indexFirst bool) {
+		// Amounts are uint256 values; anything wider is rejected before hashing
+		if len(amountBytes) > 32 {
+			amountBytes = amountBytes[:32]
+		}
+		address := common.BytesToAddress(addr)
+		amount := new(big.Int).SetBytes(amountBytes)
+
+		opts := merkle.DefaultTreeOptions()
+		opts.IncludeIndex = includeIndex
+		opts.IndexFirst = indexFirst
+		leaf, err := merkle.HashLeafWithOptions(address, amount, index, opts)
+		if err != nil || len(leaf) != 32 {
+			t.Fatalf("Expected a 32-byte leaf, got %d bytes (%v)", len(leaf), err)
+		}
+		if pooled, _ := merkle.OptimizedHashLeafWithOptions(address, amount, index, opts); !bytes.Equal(leaf, pooled) {
+			t.Fatal("Expected the pooled leaf hash to match")
+		}
+	})
+}
+
+func FuzzVerifyProof(f *testing.F) {
+	tree, proofs := buildProofSet(f, 13)
+	for i, claim := range tree.Claims {
+		proof, _ := hex.DecodeString(joinProof(proofs.Proofs[claim.Address.Hex()].Proof))
+		f.Add(uint8(i), proof, claim.Amount.Uint64())
+	}
+	f.Add(uint8(0), []byte{}, uint64(0))
+
+	f.Fuzz(func(t *testing.T, pick uint8, raw []byte, amount uint64) {
+		claim := tree.Claims[int(pick)%len(tree.Claims)]
+		claim.Amount = new(big.Int).SetUint64(amount)
+
+		var proof []string
+		for len(raw) > 0 {
+			n := min(32, len(raw))
+			proof = append(proof, "0x"+hex.EncodeToString(raw[:n]))
+			raw = raw[n:]
+		}
+
+		valid, err := merkle.VerifyProof(tree.Root.Hash, claim, proof, tree.Options())
+		if !valid {
+			return
+		}
+		if err != nil {
+			t.Fatalf("Expected no error with a valid result, got %v", err)
+		}
+		genuine := proofs.Proofs[claim.Address.Hex()]
+		if claim.Amount.String() != genuine.Amount || joinProof(proof) != joinProof(genuine.Proof) {
+			t.Fatalf("Forged proof verified for %s: amount %s, proof %v", claim.Address.Hex(), claim.Amount, proof)
+		}
+	})
+}
+
+// joinProof concatenates hex proof elements without their prefixes
+func joinProof(proof []string) string {
+	var joined string
+	for _, element := range proof {
+		joined += element[2:]
+	}
+	return joined
+}
+
+func TestProofProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	maxAmount := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+	for n := 1; n <= 257; n++ {
+		claims := make([]merkle.AirdropClaim, n)
+		for i := range claims {
+			var addr common.Address
+			rng.Read(addr[:])
+			var amount *big.Int
+			switch rng.Intn(4) {
+			case 0:
+				amount = new(big.Int) // zero
+			case 1:
+				amount = new(big.Int).Set(maxAmount)
+			default:
+				amount = new(big.Int).Rand(rng, maxAmount)
+			}
+			claims[i] = merkle.AirdropClaim{Address: addr, Amount: amount}
+		}
+
+		// Cycle through the encodings so each size is checked under one
+		opts := merkle.DefaultTreeOptions()
+		switch n % 3 {
+		case 1:
+			opts.IncludeIndex = false
+		case 2:
+			opts.SortedPairs = false
+		}
+
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("%d claims: failed to build tree: %v", n, err)
+		}
+		proofs, err := tree.GenerateAllProofs()
+		if err != nil {
+			t.Fatalf("%d claims: failed to generate proofs: %v", n, err)
+		}
+
+		for _, claim := range tree.Claims {
+			proof := proofs[claim.Address.Hex()]
+			path := make([][]byte, len(proof.Proof))
+			for i, element := range proof.Proof {
+				path[i], _ = hex.DecodeString(element[2:])
+			}
+
+			valid, err := merkle.VerifyProofBytesWithPositions(tree.Root.Hash, claim, path, proof.Positions, opts)
+			if err != nil || !valid {
+				t.Fatalf("%d claims: proof for index %d did not verify (%v)", n, claim.Index, err)
+			}
+
+			if len(path) == 0 {
+				continue
+			}
+			i, bit := rng.Intn(len(path)), rng.Intn(256)
+			path[i][bit/8] ^= 1 << (bit % 8)
+			valid, _ = merkle.VerifyProofBytesWithPositions(tree.Root.Hash, claim, path, proof.Positions, opts)
+			if valid {
+				t.Fatalf("%d claims: proof for index %d verified with bit %d of element %d flipped", n, claim.Index, bit, i)
+			}
+		}
+	}
+
+	t.Run("InvalidAmounts", func(t *testing.T) {
+		for _, amount := range []*big.Int{nil, big.NewInt(-1), new(big.Int).Lsh(big.NewInt(1), 256)} {
+			claims := data.GenerateTestData(4)
+			claims[2].Amount = amount
+			if _, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions()); err == nil {
+				t.Errorf("Expected amount %v to be rejected", amount)
+			}
+		}
+
+		path := filepath.Join(t.TempDir(), "airdrop.csv")
+		os.WriteFile(path, []byte("address,amount\n0x742d35Cc6634C0532925a3b8D8c6632F2d50a9a6,-1\n"), 0644)
+		if _, err := data.LoadAirdropFromCSV(path); err == nil {
+			t.Error("Expected a negative CSV amount to be rejected")
+		}
+	})
+}
+
+func FuzzLoadAirdropCSV(f *testing.F) {
+	f.Add([]byte("address,amount\n0x742d35Cc6634C0532925a3b8D8c6632F2d50a9a6,100\n"))
+	f.Add([]byte("address,amount,index\n0x742d35Cc6634C0532925a3b8D8c6632F2d50a9a6,100,0\n"))
+	f.Add([]byte("address,amount\n0x742d35Cc6634C0532925a3b8D8c6632F2d50a9a6,-1\n"))
+	f.Add([]byte("\"unterminated\n,,,"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, content []byte) {
+		path := filepath.Join(t.TempDir(), "airdrop.csv")
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		loaders := map[string]func(string) ([]merkle.AirdropClaim, error){
+			"Plain":       data.LoadAirdropFromCSV,
+			"WithIndices": data.LoadAirdropFromCSVWithIndices,
+		}
+		for name, load := range loaders {
+			claims, err := load(path)
+			if err != nil || len(claims) == 0 {
+				continue
+			}
+			// Whatever loads must build a tree
+			opts := merkle.DefaultTreeOptions()
+			opts.SortOrder = merkle.SortByIndex
+			if _, err := merkle.NewMerkleTreeWithOptions(claims, opts); err != nil {
+				t.Errorf("%s: loaded claims fail to build a tree: %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// fakeClock ticks when told to and advances a second on every reading
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	ticks chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now
+	c.now = c.now.Add(time.Second)
+	return now
+}
+
+func (c *fakeClock) NewTicker(time.Duration) (<-chan time.Time, func()) {
+	return c.ticks, func() {}
+}
+
+// set moves the clock to t
+func (c *fakeClock) set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func TestRebuildScheduler(t *testing.T) {
+	const (
+		firstClaims  = "address,amount\n0x0000000000000000000000000000000000000001,100\n0x0000000000000000000000000000000000000002,200\n"
+		secondClaims = "address,amount\n0x0000000000000000000000000000000000000001,100\n0x0000000000000000000000000000000000000003,300\n"
+	)
+	rootOf := func(content string) string {
+		t.Helper()
+		claims, err := data.LoadAirdropFromCSV(writeCSV(t, content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree.GetRootHash()
+	}
+
+	// start serves the CSV at path, rebuilt on the clock's ticks
+	start := func(t *testing.T, path string, clock *fakeClock, opts ...rebuild.Option) (*rebuild.Scheduler, error) {
+		var scheduler *rebuild.Scheduler
+		source := func(context.Context) ([]merkle.AirdropClaim, error) {
+			return data.LoadAirdropFromCSV(path)
+		}
+		build := func(claims []merkle.AirdropClaim) (http.Handler, error) {
+			tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+			if err != nil {
+				return nil, err
+			}
+			proofs, err := tree.GenerateAllProofs()
+			if err != nil {
+				return nil, err
+			}
+			return api.MustNewAPIServer(tree, proofs, api.WithRebuildProgress(scheduler)).SetupRoutes(), nil
+		}
+		scheduler = rebuild.New(source, build, time.Minute, append(opts, rebuild.WithClock(clock))...)
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return scheduler, scheduler.Start(ctx)
+	}
+	get := func(t *testing.T, handler http.Handler, path string) map[string]interface{} {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		return body
+	}
+	// tick runs one rebuild at the given time and waits for its outcome
+	tick := func(t *testing.T, scheduler *rebuild.Scheduler, clock *fakeClock, at time.Time) api.RebuildStatus {
+		t.Helper()
+		clock.set(at)
+		clock.ticks <- at
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+			if status, ok := scheduler.RebuildStatus(); ok && status.LastRebuild.Equal(at) {
+				return status
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for the tick at %v", at)
+			}
+		}
+	}
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("ChangingSource", func(t *testing.T) {
+		path := writeCSV(t, firstClaims)
+		clock := &fakeClock{ticks: make(chan time.Time)}
+		scheduler, err := start(t, path, clock)
+		if err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		if root := get(t, scheduler, "/api/root")["merkleRoot"]; root != rootOf(firstClaims) {
+			t.Fatalf("Expected the first tree, got %v", root)
+		}
+		if _, ok := get(t, scheduler, "/api/stats")["rebuild"]; ok {
+			t.Error("Expected no rebuild in stats before the first tick")
+		}
+
+		status := tick(t, scheduler, clock, base)
+		if status.Outcome != rebuild.OutcomeUnchanged || status.Rebuilds != 0 {
+			t.Errorf("Expected an unchanged source to be skipped, got %+v", status)
+		}
+
+		os.WriteFile(path, []byte(secondClaims), 0o644)
+		status = tick(t, scheduler, clock, base.Add(time.Hour))
+		if status.Outcome != rebuild.OutcomeRebuilt || status.Rebuilds != 1 || status.Duration != "1s" {
+			t.Errorf("Expected a rebuild, got %+v", status)
+		}
+		if root := get(t, scheduler, "/api/root")["merkleRoot"]; root != rootOf(secondClaims) {
+			t.Errorf("Expected the rebuilt tree to be served, got %v", root)
+		}
+		stats, _ := get(t, scheduler, "/api/stats")["rebuild"].(map[string]interface{})
+		if stats["outcome"] != rebuild.OutcomeRebuilt || stats["lastRebuild"] != base.Add(time.Hour).Format(time.RFC3339) || stats["rebuilds"] != float64(1) {
+			t.Errorf("Expected the rebuild in stats, got %v", stats)
+		}
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		path := writeCSV(t, firstClaims)
+		clock := &fakeClock{ticks: make(chan time.Time)}
+		scheduler, err := start(t, path, clock)
+		if err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+
+		os.WriteFile(path, []byte("address,amount\nnot-an-address,1\n"), 0o644)
+		for i := 1; i <= 2; i++ {
+			status := tick(t, scheduler, clock, base.Add(time.Duration(i)*time.Hour))
+			if status.Outcome != rebuild.OutcomeFailed || status.Failures != i || !strings.Contains(status.Error, "not-an-address") {
+				t.Errorf("Tick %d: expected a failure naming the bad row, got %+v", i, status)
+			}
+		}
+		if root := get(t, scheduler, "/api/root")["merkleRoot"]; root != rootOf(firstClaims) {
+			t.Errorf("Expected the old tree to keep serving, got %v", root)
+		}
+		stats, _ := get(t, scheduler, "/api/stats")["rebuild"].(map[string]interface{})
+		if stats["outcome"] != rebuild.OutcomeFailed || stats["error"] == nil {
+			t.Errorf("Expected the failure in stats, got %v", stats)
+		}
+
+		// Retried on the next tick once the source is fixed
+		os.WriteFile(path, []byte(secondClaims), 0o644)
+		status := tick(t, scheduler, clock, base.Add(3*time.Hour))
+		if status.Outcome != rebuild.OutcomeRebuilt || status.Failures != 0 || status.Error != "" {
+			t.Errorf("Expected the fixed source to be rebuilt, got %+v", status)
+		}
+		if root := get(t, scheduler, "/api/root")["merkleRoot"]; root != rootOf(secondClaims) {
+			t.Errorf("Expected the rebuilt tree to be served, got %v", root)
+		}
+	})
+
+	t.Run("Finalized", func(t *testing.T) {
+		path := writeCSV(t, firstClaims)
+		clock := &fakeClock{ticks: make(chan time.Time)}
+		lock, _ := api.NewFinalizationLock("")
+		scheduler, err := start(t, path, clock, rebuild.WithFinalization(lock))
+		if err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+		lock.Finalize(api.Finalization{MerkleRoot: rootOf(firstClaims)})
+
+		os.WriteFile(path, []byte(secondClaims), 0o644)
+		status := tick(t, scheduler, clock, base)
+		if status.Outcome != rebuild.OutcomeLocked || !strings.Contains(status.Error, "finalized") {
+			t.Errorf("Expected changed claims to be locked out, got %+v", status)
+		}
+		if root := get(t, scheduler, "/api/root")["merkleRoot"]; root != rootOf(firstClaims) {
+			t.Errorf("Expected the finalized tree to keep serving, got %v", root)
+		}
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		path := writeCSV(t, firstClaims)
+		clock := &fakeClock{ticks: make(chan time.Time)}
+		calls := 0
+		served := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		build := func([]merkle.AirdropClaim) (http.Handler, error) {
+			if calls++; calls > 1 {
+				panic("out of memory")
+			}
+			return served, nil
+		}
+		source := func(context.Context) ([]merkle.AirdropClaim, error) {
+			return data.LoadAirdropFromCSV(path)
+		}
+		scheduler := rebuild.New(source, build, time.Minute, rebuild.WithClock(clock))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := scheduler.Start(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		os.WriteFile(path, []byte(secondClaims), 0o644)
+		status := tick(t, scheduler, clock, base)
+		if status.Outcome != rebuild.OutcomeFailed || !strings.Contains(status.Error, "out of memory") {
+			t.Errorf("Expected a panicking build to fail the tick, got %+v", status)
+		}
+	})
+
+	t.Run("StartFails", func(t *testing.T) {
+		path := writeCSV(t, "address,amount\n")
+		if _, err := start(t, path, &fakeClock{ticks: make(chan time.Time)}); err == nil {
+			t.Error("Expected a first build without claims to fail")
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+	"merkle-airdrop/pkg/release"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestReleaseManifest(t *testing.T) {
+	tree, proofs := buildProofSet(t, 40)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "airdrop_data.csv")
+	proofsFile := filepath.Join(dir, "merkle_proofs.json")
+	configFile := filepath.Join(dir, "config.json")
+	deploymentsFile := filepath.Join(dir, "deployments.json")
+	manifestFile := filepath.Join(dir, release.DefaultManifestFile)
+
+	if err := data.SaveClaimsToCSV(tree.Claims, input, data.AddressChecksum); err != nil {
+		t.Fatalf("Failed to save claims: %v", err)
+	}
+	var export bytes.Buffer
+	if err := data.ExportProofsCanonical(proofs, tree.GetRootHash(), &export, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if err := os.WriteFile(proofsFile, export.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configFile, []byte(`{"server": {"port": 8081}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := contract.SaveDeployments(map[string]contract.DeployResult{
+		"sepolia": {ChainID: 11155111, MerkleRoot: tree.GetRootHash(), Contract: common.HexToAddress("0x1000000000000000000000000000000000000001"), TxHash: common.HexToHash("0xab"), BlockNumber: 42, Attempts: 1},
+		"base":    {ChainID: 8453, MerkleRoot: tree.GetRootHash(), Attempts: 3, Error: "insufficient funds"},
+	}, deploymentsFile)
+	if err != nil {
+		t.Fatalf("Failed to save deployments: %v", err)
+	}
+
+	opts := release.Options{
+		Campaign:    "Season 1",
+		Version:     "1.0.0",
+		Input:       input,
+		Layout:      data.DefaultLoadOptions(),
+		Proofs:      proofsFile,
+		Config:      configFile,
+		Deployments: deploymentsFile,
+		CreatedAt:   time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	manifest, err := release.Create(manifestFile, opts)
+	if err != nil {
+		t.Fatalf("Failed to create manifest: %v", err)
+	}
+
+	t.Run("Contents", func(t *testing.T) {
+		loaded, err := release.LoadManifest(manifestFile)
+		if err != nil {
+			t.Fatalf("Failed to load manifest: %v", err)
+		}
+		switch {
+		case loaded.MerkleRoot != tree.GetRootHash():
+			t.Errorf("Expected root %s, got %s", tree.GetRootHash(), loaded.MerkleRoot)
+		case loaded.TotalClaims != 40 || loaded.TotalAmount != merkle.TotalAmount(tree.Claims).String():
+			t.Errorf("Expected 40 claims of %s, got %d of %s", merkle.TotalAmount(tree.Claims), loaded.TotalClaims, loaded.TotalAmount)
+		case loaded.LeafEncoding != tree.Metadata():
+			t.Errorf("Expected leaf encoding %+v, got %+v", tree.Metadata(), loaded.LeafEncoding)
+		case loaded.Input.Path != "airdrop_data.csv" || loaded.Proofs.Path != "merkle_proofs.json" || loaded.Config == nil:
+			t.Errorf("Expected paths relative to the manifest, got %+v %+v %+v", loaded.Input, loaded.Proofs, loaded.Config)
+		case loaded.CreatedAt != "2026-03-01T12:00:00Z" || loaded.Generator.Version == "":
+			t.Errorf("Expected the creation time and generator, got %s %+v", loaded.CreatedAt, loaded.Generator)
+		}
+		if len(manifest.Deployments) != 1 || manifest.Deployments["sepolia"].Contract != "0x1000000000000000000000000000000000000001" {
+			t.Errorf("Expected only the successful deployment, got %+v", manifest.Deployments)
+		}
+		if err := release.VerifyManifest(manifestFile); err != nil {
+			t.Errorf("Expected an untouched release to verify: %v", err)
+		}
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		for _, tc := range []struct {
+			artifact string
+			path     string
+		}{
+			{"input", input},
+			{"proofs", proofsFile},
+			{"config", configFile},
+		} {
+			original, err := os.ReadFile(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tampered := bytes.Replace(original, []byte("1"), []byte("2"), 1)
+			if err := os.WriteFile(tc.path, tampered, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			err = release.VerifyManifest(manifestFile)
+			var artifactErr *release.ArtifactError
+			if !errors.As(err, &artifactErr) || artifactErr.Artifact != tc.artifact || artifactErr.Path != filepath.Base(tc.path) || !errors.Is(err, release.ErrChecksumMismatch) {
+				t.Errorf("%s: expected a checksum mismatch of %s, got %v", tc.artifact, filepath.Base(tc.path), err)
+			}
+			// Only the tampered file is blamed
+			if err != nil && strings.Count(err.Error(), "\n") != 0 {
+				t.Errorf("%s: expected one failing artifact, got %v", tc.artifact, err)
+			}
+			if err := os.WriteFile(tc.path, original, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		config, _ := os.ReadFile(configFile)
+		os.Remove(configFile)
+		if err := release.VerifyManifest(manifestFile); err == nil || !strings.Contains(err.Error(), "config config.json") {
+			t.Errorf("Expected a missing config to be reported, got %v", err)
+		}
+		os.WriteFile(configFile, config, 0o644)
+	})
+
+	t.Run("ResignedProofs", func(t *testing.T) {
+		// A proofs file swapped along with its checksum still has to verify
+		address := tree.Claims[7].Address.Hex()
+		corrupted := *proofs
+		corrupted.Proofs = make(map[string]*merkle.MerkleProof, len(proofs.Proofs))
+		for a, proof := range proofs.Proofs {
+			corrupted.Proofs[a] = proof
+		}
+		bad := *proofs.Proofs[address]
+		bad.Proof = append([]string{merkle.EncodeHash(make([]byte, merkle.HashLength))}, bad.Proof[1:]...)
+		corrupted.Proofs[address] = &bad
+
+		dir := t.TempDir()
+		copyFile(t, input, filepath.Join(dir, "airdrop_data.csv"))
+		copyFile(t, proofsFile, filepath.Join(dir, "merkle_proofs.json"))
+		manifestFile := filepath.Join(dir, release.DefaultManifestFile)
+		if _, err := release.Create(manifestFile, release.Options{Version: "1.0.1", Input: filepath.Join(dir, "airdrop_data.csv"), Layout: data.DefaultLoadOptions(), Proofs: filepath.Join(dir, "merkle_proofs.json")}); err != nil {
+			t.Fatalf("Failed to create manifest: %v", err)
+		}
+
+		var export bytes.Buffer
+		if err := data.ExportProofsCanonical(&corrupted, tree.GetRootHash(), &export, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		os.WriteFile(filepath.Join(dir, "merkle_proofs.json"), export.Bytes(), 0o644)
+		loaded, _ := release.LoadManifest(manifestFile)
+		loaded.Proofs.SHA256 = sha256Hex(export.Bytes())
+		encoded, _ := json.Marshal(loaded)
+		os.WriteFile(manifestFile, encoded, 0o644)
+
+		err := release.VerifyManifestSample(manifestFile, 0)
+		var artifactErr *release.ArtifactError
+		if !errors.As(err, &artifactErr) || artifactErr.Artifact != "proofs" || !strings.Contains(err.Error(), address) {
+			t.Errorf("Expected the proof of %s to fail, got %v", address, err)
+		}
+	})
+
+	t.Run("Create", func(t *testing.T) {
+		other, otherProofs := buildProofSet(t, 41)
+		otherFile := filepath.Join(t.TempDir(), "other_proofs.json")
+		var export bytes.Buffer
+		data.ExportProofsCanonical(otherProofs, other.GetRootHash(), &export, data.AddressChecksum, data.ProofMarshaller{})
+		os.WriteFile(otherFile, export.Bytes(), 0o644)
+
+		for _, tc := range []struct {
+			name   string
+			modify func(*release.Options)
+			want   string
+		}{
+			{"NoVersion", func(o *release.Options) { o.Version = "" }, "invalid version"},
+			{"PrefixedVersion", func(o *release.Options) { o.Version = "v1.0.0" }, "invalid version"},
+			{"OtherTree", func(o *release.Options) { o.Proofs = otherFile }, "builds root"},
+			{"MissingInput", func(o *release.Options) { o.Input = filepath.Join(dir, "missing.csv") }, "input"},
+		} {
+			o := opts
+			tc.modify(&o)
+			if _, err := release.Create(filepath.Join(t.TempDir(), "release.json"), o); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("%s: expected %q, got %v", tc.name, tc.want, err)
+			}
+		}
+		for _, version := range []string{"0.1.0", "2.0.0-rc.1", "1.2.3+build.5"} {
+			if err := release.ValidateVersion(version); err != nil {
+				t.Errorf("Expected %s to be valid: %v", version, err)
+			}
+		}
+	})
+}
+
+// copyFile copies the file at src to dst
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	content, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sha256Hex returns the hex SHA-256 of content
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
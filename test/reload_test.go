@@ -0,0 +1,258 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/internal/reload"
+)
+
+func TestConfigReload(t *testing.T) {
+	const token = "admin-secret"
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	cfg := config.DefaultConfig()
+	cfg.Logging.File = ""
+	cfg.Server.AdminTokens = []string{token}
+	cfg.Server.AbuseMaxNotFound = 2
+	cfg.Server.AbuseAction = "reject"
+	cfg.Database.Password = "db-secret"
+	cfg.Campaign.Name = "Season 1"
+	if err := config.SaveConfig(cfg, path); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	// save writes the running config with mutate applied to the file
+	save := func(t *testing.T, mutate func(*config.Config)) {
+		t.Helper()
+		next, _ := config.LoadConfig(path)
+		mutate(next)
+		if err := config.SaveConfig(next, path); err != nil {
+			t.Fatalf("Failed to save config: %v", err)
+		}
+	}
+
+	tunables, err := reload.NewTunables(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create tunables: %v", err)
+	}
+	abuse, _ := reload.AbuseConfig(cfg.Server)
+	level := new(slog.LevelVar)
+	reloader := reload.New(path, cfg, tunables, reload.WithLogLevel(level), reload.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	tree, proofs := buildProofSet(t, 10)
+	opts := []api.Option{
+		api.WithAdminTokens(cfg.Server.AdminTokens),
+		api.WithTunables(tunables),
+		api.WithConfigReloader(reloader),
+		api.WithAbuseDetection(abuse),
+		api.WithCampaign(api.CampaignMeta{Name: cfg.Campaign.Name}, ""),
+		api.WithResponseCache(cfg.Merkle.CacheSize, time.Duration(cfg.Merkle.CacheTTL)*time.Second),
+	}
+	server := httptest.NewServer(api.MustNewAPIServer(tree, proofs.Proofs, opts...).SetupRoutes())
+	defer server.Close()
+
+	do := func(t *testing.T, method, path string, header http.Header) (*http.Response, []byte) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+	admin := http.Header{"Authorization": {"Bearer " + token}}
+	reloadConfig := func(t *testing.T) (int, api.ConfigReloadResponse, []byte) {
+		t.Helper()
+		resp, body := do(t, http.MethodPost, "/api/admin/config/reload", admin)
+		var result api.ConfigReloadResponse
+		json.Unmarshal(body, &result)
+		return resp.StatusCode, result, body
+	}
+	settings := func(changes []api.SettingChange) []string {
+		names := make([]string, len(changes))
+		for i, change := range changes {
+			names[i] = change.Setting
+		}
+		return names
+	}
+	missing := "/api/proof/0x00000000000000000000000000000000deadbeef"
+	proofPath := "/api/proof/" + tree.Claims[0].Address.Hex()
+
+	// Three misses put the client over the threshold of two
+	for i := 0; i < 3; i++ {
+		do(t, http.MethodGet, missing, nil)
+	}
+	if resp, _ := do(t, http.MethodGet, missing, nil); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected the client to be throttled before the reload, got %d", resp.StatusCode)
+	}
+
+	t.Run("Apply", func(t *testing.T) {
+		save(t, func(c *config.Config) {
+			c.Server.AbuseMaxNotFound = 50
+			c.Server.CORSOrigins = []string{"https://claim.example.org"}
+			c.Merkle.CacheTTL = 1
+			c.Logging.Level = "debug"
+			c.Campaign.ClaimEnd = "2020-01-01T00:00:00Z"
+			c.Server.Port = 9999
+			c.Database.Name = "other"
+		})
+		status, result, body := reloadConfig(t)
+		if status != http.StatusOK || !result.Success {
+			t.Fatalf("Expected the reload to succeed, got %d %s", status, body)
+		}
+		wantApplied := []string{"campaign.claim_end", "logging.level", "merkle.cache_ttl", "server.abuse_max_not_found", "server.cors_origins"}
+		if got := settings(result.Applied); !slices.Equal(sorted(got), wantApplied) {
+			t.Errorf("Expected %v applied, got %v", wantApplied, got)
+		}
+		if got := settings(result.Skipped); !slices.Equal(sorted(got), []string{"database.name", "server.port"}) {
+			t.Errorf("Expected the port and database skipped, got %v", got)
+		}
+		for _, change := range result.Skipped {
+			if change.Reason == "" {
+				t.Errorf("Expected a reason for skipping %s", change.Setting)
+			}
+		}
+
+		// The same listener serves with the new settings
+		if resp, _ := do(t, http.MethodGet, missing, nil); resp.StatusCode == http.StatusTooManyRequests {
+			t.Error("Expected the raised threshold to stop throttling")
+		}
+		resp, _ := do(t, http.MethodGet, "/api/root", http.Header{"Origin": {"https://claim.example.org"}})
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://claim.example.org" {
+			t.Errorf("Expected the allowed origin echoed, got %q", got)
+		}
+		resp, _ = do(t, http.MethodGet, "/api/root", http.Header{"Origin": {"https://evil.example"}})
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no CORS grant for another origin, got %q", got)
+		}
+		if resp, _ := do(t, http.MethodGet, proofPath, nil); resp.StatusCode != http.StatusGone {
+			t.Errorf("Expected the closed claim window to answer 410, got %d", resp.StatusCode)
+		}
+		if level.Level() != slog.LevelDebug {
+			t.Errorf("Expected the debug log level, got %s", level.Level())
+		}
+
+		// Responses cached from now on expire after the new TTL
+		if resp, _ := do(t, http.MethodGet, "/api/stats", nil); resp.Header.Get("X-Cache") != "MISS" {
+			t.Fatalf("Expected a fresh stats response, got %q", resp.Header.Get("X-Cache"))
+		}
+		if resp, _ := do(t, http.MethodGet, "/api/stats", nil); resp.Header.Get("X-Cache") != "HIT" {
+			t.Errorf("Expected the stats to be cached, got %q", resp.Header.Get("X-Cache"))
+		}
+		time.Sleep(1100 * time.Millisecond)
+		if resp, _ := do(t, http.MethodGet, "/api/stats", nil); resp.Header.Get("X-Cache") != "MISS" {
+			t.Errorf("Expected the cached stats to expire after a second, got %q", resp.Header.Get("X-Cache"))
+		}
+
+		// Servers built later, as rebuilds do, share the settings
+		rebuilt := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/root", nil)
+		req.Header.Set("Origin", "https://evil.example")
+		api.MustNewAPIServer(tree, proofs.Proofs, opts...).SetupRoutes().ServeHTTP(rebuilt, req)
+		if got := rebuilt.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected a rebuilt server to use the reloaded CORS policy, got %q", got)
+		}
+	})
+
+	t.Run("RunningConfig", func(t *testing.T) {
+		resp, body := do(t, http.MethodGet, "/api/admin/config", admin)
+		var running struct {
+			Config config.Config `json:"config"`
+		}
+		if err := json.Unmarshal(body, &running); err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("Failed to read the running config: %d %v", resp.StatusCode, err)
+		}
+		got := running.Config
+		if got.Server.Port != 8080 || got.Database.Name != cfg.Database.Name {
+			t.Errorf("Expected skipped settings at their running values, got port %d and database %q", got.Server.Port, got.Database.Name)
+		}
+		if got.Server.AbuseMaxNotFound != 50 || got.Logging.Level != "debug" {
+			t.Errorf("Expected applied settings in the running config, got %+v", got.Server)
+		}
+		if strings.Contains(string(body), token) || strings.Contains(string(body), "db-secret") {
+			t.Errorf("Expected secrets to be redacted, got %s", body)
+		}
+		if len(got.Server.AdminTokens) != 1 || got.Server.AdminTokens[0] != config.RedactedValue || got.Database.Password != config.RedactedValue {
+			t.Errorf("Expected redacted placeholders, got %v and %q", got.Server.AdminTokens, got.Database.Password)
+		}
+		if resp, _ := do(t, http.MethodGet, "/api/admin/config", nil); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected the config to need an admin token, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		save(t, func(c *config.Config) {
+			c.Server.AbuseAction = "ban"
+			c.Server.CORSOrigins = nil
+		})
+		status, _, body := reloadConfig(t)
+		var apiErr api.ErrorResponse
+		json.Unmarshal(body, &apiErr)
+		if status != http.StatusUnprocessableEntity || apiErr.Error.Code != api.CodeInvalidConfig || !strings.Contains(apiErr.Error.Message, "abuse_action") {
+			t.Fatalf("Expected an invalid config to be rejected, got %d %s", status, body)
+		}
+		// Nothing of the rejected file is applied
+		resp, _ := do(t, http.MethodGet, "/api/root", http.Header{"Origin": {"https://evil.example"}})
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected the CORS policy to be kept, got %q", got)
+		}
+	})
+
+	t.Run("AbuseToggle", func(t *testing.T) {
+		save(t, func(c *config.Config) {
+			c.Server.AbuseAction = "reject"
+			c.Server.AbuseMaxNotFound = 0
+		})
+		status, result, body := reloadConfig(t)
+		if status != http.StatusOK {
+			t.Fatalf("Expected the reload to succeed, got %d %s", status, body)
+		}
+		i := slices.IndexFunc(result.Skipped, func(c api.SettingChange) bool { return c.Setting == "server.abuse_max_not_found" })
+		if i < 0 || !strings.Contains(result.Skipped[i].Reason, "restart") {
+			t.Errorf("Expected turning abuse detection off to need a restart, got %+v", result.Skipped)
+		}
+		if got := settings(result.Applied); !slices.Contains(got, "server.cors_origins") {
+			t.Errorf("Expected the other changes applied, got %v", got)
+		}
+		if tunables.Abuse().MaxNotFound != 50 {
+			t.Errorf("Expected the threshold kept, got %d", tunables.Abuse().MaxNotFound)
+		}
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		next := *cfg
+		next.Server.CORSOrigins = []string{"*"}
+		next.Merkle.Domain = "season-1"
+		changed, err := config.Diff(cfg, &next)
+		if err != nil || !slices.Equal(changed, []string{"merkle.domain", "server.cors_origins"}) {
+			t.Errorf("Expected two changed settings, got %v (%v)", changed, err)
+		}
+		if changed, _ := config.Diff(cfg, cfg.Redacted()); !slices.Contains(changed, "database.password") {
+			t.Errorf("Expected the redacted password to differ, got %v", changed)
+		}
+		if cfg.Database.Password != "db-secret" {
+			t.Error("Expected Redacted to leave the config alone")
+		}
+	})
+}
+
+// sorted returns a sorted copy of s
+func sorted(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
@@ -0,0 +1,207 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// personalSign signs message the way wallets do for personal_sign
+func personalSign(t *testing.T, key *ecdsa.PrivateKey, message string) string {
+	t.Helper()
+	sig, err := crypto.Sign(accounts.TextHash([]byte(message)), key)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(sig)
+}
+
+func TestReservation(t *testing.T) {
+	keyed, keysByAddress := data.GenerateTestDataWithKeys(2, 1)
+	var keys []*ecdsa.PrivateKey
+	for _, claim := range keyed {
+		keys = append(keys, keysByAddress[claim.Address])
+	}
+	claims := append(data.GenerateTestData(10), keyed...)
+	tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	store := api.NewMemoryClaimStore()
+	handler := api.MustNewAPIServer(tree, proofs, api.WithReservation(store), api.WithAdminTokens([]string{"admin-secret"})).SetupRoutes()
+
+	do := func(method, path string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body
+	}
+
+	// fetch signs a fresh nonce for key and requests its proof
+	fetch := func(key *ecdsa.PrivateKey) (int, map[string]interface{}) {
+		address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+		code, nonce := do(http.MethodGet, "/api/nonce/"+address)
+		if code != http.StatusOK {
+			t.Fatalf("Expected a nonce, got %d", code)
+		}
+		return do(http.MethodGet, "/api/proof/"+address+"?signature="+personalSign(t, key, nonce["message"].(string)))
+	}
+
+	first := crypto.PubkeyToAddress(keys[0].PublicKey)
+
+	t.Run("RequiresSignature", func(t *testing.T) {
+		if code, _ := do(http.MethodGet, "/api/proof/"+first.Hex()); code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 without a signature, got %d", code)
+		}
+
+		// A signature from another key doesn't prove control of the address
+		_, nonce := do(http.MethodGet, "/api/nonce/"+first.Hex())
+		forged := personalSign(t, keys[1], nonce["message"].(string))
+		if code, body := do(http.MethodGet, "/api/proof/"+first.Hex()+"?signature="+forged); code != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for another key's signature, got %d: %v", code, body)
+		}
+	})
+
+	var issuedAt string
+	t.Run("IssuedOnce", func(t *testing.T) {
+		code, body := fetch(keys[0])
+		if code != http.StatusOK {
+			t.Fatalf("Expected the first fetch to succeed, got %d: %v", code, body)
+		}
+		issuedAt, _ = body["issuedAt"].(string)
+		if valid, _ := merkle.VerifyProof(tree.Root.Hash, tree.Claims[indexOf(tree, first)], toStrings(body["proof"]), tree.Options()); !valid {
+			t.Error("Expected the issued proof to verify")
+		}
+
+		code, body = fetch(keys[0])
+		if code != http.StatusConflict {
+			t.Fatalf("Expected status 409 for the second fetch, got %d", code)
+		}
+		if body["issuedAt"] != issuedAt || body["error"].(map[string]interface{})["code"] != api.CodeAlreadyIssued {
+			t.Errorf("Expected the original issuance time %s, got %v", issuedAt, body)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, issuedAt); err != nil {
+			t.Errorf("Expected an RFC 3339 timestamp, got %q", issuedAt)
+		}
+	})
+
+	t.Run("OtherAddressUnaffected", func(t *testing.T) {
+		if code, body := fetch(keys[1]); code != http.StatusOK {
+			t.Errorf("Expected another address to fetch its proof, got %d: %v", code, body)
+		}
+	})
+
+	t.Run("NonceSingleUse", func(t *testing.T) {
+		_, nonce := do(http.MethodGet, "/api/nonce/"+first.Hex())
+		signature := personalSign(t, keys[0], nonce["message"].(string))
+		do(http.MethodGet, "/api/proof/"+first.Hex()+"?signature="+signature)
+		if code, _ := do(http.MethodGet, "/api/proof/"+first.Hex()+"?signature="+signature); code != http.StatusUnauthorized {
+			t.Errorf("Expected a replayed signature to be rejected, got %d", code)
+		}
+	})
+
+	t.Run("NoncesNotReplaced", func(t *testing.T) {
+		// Anyone can fetch nonces for an address without voiding its holder's
+		_, nonce := do(http.MethodGet, "/api/nonce/"+first.Hex())
+		signature := personalSign(t, keys[0], nonce["message"].(string))
+		for i := 0; i < 7; i++ {
+			do(http.MethodGet, "/api/nonce/"+first.Hex())
+		}
+		if code, body := do(http.MethodGet, "/api/proof/"+first.Hex()+"?signature="+signature); code == http.StatusUnauthorized {
+			t.Errorf("Expected the holder's nonce to survive others being fetched, got %d: %v", code, body)
+		}
+
+		// Past the eight kept per address the oldest is dropped
+		_, nonce = do(http.MethodGet, "/api/nonce/"+first.Hex())
+		signature = personalSign(t, keys[0], nonce["message"].(string))
+		for i := 0; i < 8; i++ {
+			do(http.MethodGet, "/api/nonce/"+first.Hex())
+		}
+		if code, _ := do(http.MethodGet, "/api/proof/"+first.Hex()+"?signature="+signature); code != http.StatusUnauthorized {
+			t.Errorf("Expected the oldest nonce to be dropped, got %d", code)
+		}
+	})
+
+	t.Run("AdminReset", func(t *testing.T) {
+		if code, _ := do(http.MethodDelete, "/api/admin/issuance/"+first.Hex()); code != http.StatusOK {
+			t.Fatalf("Expected the reset to succeed, got %d", code)
+		}
+		if code, _ := do(http.MethodDelete, "/api/admin/issuance/"+first.Hex()); code != http.StatusNotFound {
+			t.Errorf("Expected status 404 with nothing to reset, got %d", code)
+		}
+		if code, _ := fetch(keys[0]); code != http.StatusOK {
+			t.Errorf("Expected the proof to be issued again after a reset, got %d", code)
+		}
+	})
+
+	t.Run("LinksDisabled", func(t *testing.T) {
+		linked := api.MustNewAPIServer(tree, proofs, api.WithReservation(api.NewMemoryClaimStore()), api.WithClaimLinkURL("https://claim.example.org")).SetupRoutes()
+		w := httptest.NewRecorder()
+		linked.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/link/"+first.Hex(), nil))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected links to be disabled in reservation mode, got %d", w.Code)
+		}
+	})
+
+	t.Run("FileStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "issued.json")
+		store, err := api.NewFileClaimStore(path)
+		if err != nil {
+			t.Fatalf("Failed to open store: %v", err)
+		}
+		at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		if _, first, err := store.MarkIssued(common.HexToAddress("0x01"), at); err != nil || !first {
+			t.Fatalf("Expected a first issuance, got %v", err)
+		}
+
+		reopened, err := api.NewFileClaimStore(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen store: %v", err)
+		}
+		issued, first, _ := reopened.MarkIssued(common.HexToAddress("0x01"), time.Now())
+		if first || !issued.Equal(at) {
+			t.Errorf("Expected the issuance at %v to persist, got %v", at, issued)
+		}
+	})
+}
+
+// indexOf returns the position of address in the tree's claims
+func indexOf(tree *merkle.MerkleTree, address common.Address) int {
+	for i, claim := range tree.Claims {
+		if claim.Address == address {
+			return i
+		}
+	}
+	return -1
+}
+
+// toStrings converts a decoded JSON array of strings
+func toStrings(v interface{}) []string {
+	var out []string
+	for _, s := range v.([]interface{}) {
+		out = append(out, s.(string))
+	}
+	return out
+}
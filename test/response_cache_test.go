@@ -0,0 +1,120 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/cache"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestResponseCache(t *testing.T) {
+	t.Run("LRU", func(t *testing.T) {
+		c := cache.New[string, int](2, time.Hour)
+		c.Add("a", 1)
+		c.Add("b", 2)
+		c.Get("a") // b is now the least recently used
+		c.Add("c", 3)
+
+		if _, ok := c.Get("b"); ok {
+			t.Error("Expected b to be evicted")
+		}
+		for key, want := range map[string]int{"a": 1, "c": 3} {
+			if got, ok := c.Get(key); !ok || got != want {
+				t.Errorf("Expected %s = %d, got %d (%v)", key, want, got, ok)
+			}
+		}
+		if c.Len() != 2 {
+			t.Errorf("Expected the size bound of 2, got %d entries", c.Len())
+		}
+
+		c.Purge()
+		if _, ok := c.Get("a"); ok || c.Len() != 0 {
+			t.Error("Expected Purge to empty the cache")
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		c := cache.New[string, int](4, 20*time.Millisecond)
+		c.Add("a", 1)
+		if _, ok := c.Get("a"); !ok {
+			t.Fatal("Expected a fresh entry to be cached")
+		}
+		time.Sleep(40 * time.Millisecond)
+		if _, ok := c.Get("a"); ok {
+			t.Error("Expected the entry to expire")
+		}
+	})
+
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(50), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	get := func(handler http.Handler, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %s, got %d", path, w.Code)
+		}
+		return w
+	}
+
+	t.Run("Hit", func(t *testing.T) {
+		server := api.MustNewAPIServer(tree, proofs, api.WithResponseCache(8, time.Minute))
+		handler := server.SetupRoutes()
+
+		first := get(handler, "/api/stats")
+		if first.Header().Get("X-Cache") != "MISS" {
+			t.Errorf("Expected the first request to miss, got %q", first.Header().Get("X-Cache"))
+		}
+		second := get(handler, "/api/stats")
+		if second.Header().Get("X-Cache") != "HIT" {
+			t.Errorf("Expected the second request to hit, got %q", second.Header().Get("X-Cache"))
+		}
+		if second.Body.String() != first.Body.String() || second.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Expected the cached response to match, got %q", second.Body)
+		}
+
+		// The query is part of the key
+		if w := get(handler, "/api/stats?v=2"); w.Header().Get("X-Cache") != "MISS" {
+			t.Error("Expected another query to miss")
+		}
+
+		server.InvalidateResponseCache()
+		if w := get(handler, "/api/stats"); w.Header().Get("X-Cache") != "MISS" {
+			t.Error("Expected invalidation to drop the cached response")
+		}
+	})
+
+	t.Run("SizeBound", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs, api.WithResponseCache(2, time.Minute)).SetupRoutes()
+		for i := 0; i < 3; i++ {
+			get(handler, fmt.Sprintf("/api/stats?page=%d", i))
+		}
+		if w := get(handler, "/api/stats?page=0"); w.Header().Get("X-Cache") != "MISS" {
+			t.Error("Expected the oldest response to be evicted")
+		}
+		if w := get(handler, "/api/stats?page=2"); w.Header().Get("X-Cache") != "HIT" {
+			t.Error("Expected the newest response to stay cached")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		get(handler, "/api/stats")
+		if w := get(handler, "/api/stats"); w.Header().Get("X-Cache") != "" {
+			t.Errorf("Expected no X-Cache header without a cache, got %q", w.Header().Get("X-Cache"))
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// fakeCode serves contract code for a fixed set of addresses
+type fakeCode struct {
+	code map[common.Address][]byte
+	err  error
+}
+
+func (f fakeCode) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return f.code[account], f.err
+}
+
+func TestSanityReport(t *testing.T) {
+	// eoa returns a recipient outside the precompile range
+	eoa := func(i int) common.Address {
+		return common.HexToAddress(fmt.Sprintf("0x%040x", 0x100000+i))
+	}
+	claim := func(address common.Address, amount string) merkle.AirdropClaim {
+		value, _ := new(big.Int).SetString(amount, 10)
+		return merkle.AirdropClaim{Address: address, Amount: value}
+	}
+	rules := func(report data.Report) map[string]int {
+		found := make(map[string]int)
+		for _, f := range report.Findings {
+			found[f.Rule]++
+		}
+		return found
+	}
+
+	t.Run("Clean", func(t *testing.T) {
+		claims := []merkle.AirdropClaim{claim(eoa(1), "1000"), claim(eoa(2), "2500000000000000000")}
+		if report := data.SanityReport(claims, data.DefaultSanityOptions()); len(report.Findings) != 0 {
+			t.Errorf("Expected no findings, got %v", report.Findings)
+		}
+	})
+
+	t.Run("SharedAmount", func(t *testing.T) {
+		var claims []merkle.AirdropClaim
+		for i := 0; i < 4; i++ {
+			claims = append(claims, claim(eoa(i), "5000"))
+		}
+		claims = append(claims, claim(eoa(9), "7"))
+		report := data.SanityReport(claims, data.SanityOptions{MaxSharedAmount: 3})
+		if len(report.Findings) != 1 || report.Findings[0].Rule != data.RuleSharedAmount || report.Findings[0].Index != 0 || report.Warnings != 1 {
+			t.Fatalf("Expected one shared-amount warning, got %v", report.Findings)
+		}
+		if report := data.SanityReport(claims, data.SanityOptions{MaxSharedAmount: 4}); len(report.Findings) != 0 {
+			t.Errorf("Expected no finding at the limit, got %v", report.Findings)
+		}
+	})
+
+	t.Run("AddressAmount", func(t *testing.T) {
+		address := eoa(5)
+		claims := []merkle.AirdropClaim{{Address: address, Amount: new(big.Int).SetBytes(address.Bytes())}}
+		report := data.SanityReport(claims, data.SanityOptions{})
+		if len(report.Findings) != 1 || report.Findings[0].Rule != data.RuleAddressAmount || report.Errors != 1 {
+			t.Errorf("Expected an address-amount error, got %v", report.Findings)
+		}
+	})
+
+	t.Run("ContractRecipient", func(t *testing.T) {
+		weth := common.HexToAddress("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+		precompile := common.HexToAddress("0x0000000000000000000000000000000000000004")
+		deployed := eoa(3)
+		claims := []merkle.AirdropClaim{claim(weth, "10"), claim(precompile, "10"), claim(deployed, "11"), claim(eoa(4), "12")}
+
+		report := data.SanityReport(claims, data.SanityOptions{})
+		if found := rules(report); found[data.RuleContractRecipient] != 2 {
+			t.Errorf("Expected the known contract and the precompile flagged offline, got %v", report.Findings)
+		}
+
+		code := fakeCode{code: map[common.Address][]byte{deployed: {0x60, 0x80}}}
+		report = data.SanityReport(claims, data.SanityOptions{Code: code})
+		if found := rules(report); found[data.RuleContractRecipient] != 3 || report.Findings[2].Index != 2 {
+			t.Errorf("Expected the deployed contract flagged too, got %v", report.Findings)
+		}
+
+		report = data.SanityReport(claims[3:], data.SanityOptions{Code: fakeCode{err: errors.New("rpc down")}})
+		if report.Warnings != 1 || report.Errors != 0 {
+			t.Errorf("Expected a failed lookup to be a warning, got %v", report.Findings)
+		}
+	})
+
+	t.Run("Dust", func(t *testing.T) {
+		claims := []merkle.AirdropClaim{claim(eoa(1), "999"), claim(eoa(2), "1000")}
+		report := data.SanityReport(claims, data.SanityOptions{DustThreshold: big.NewInt(1000)})
+		if len(report.Findings) != 1 || report.Findings[0].Rule != data.RuleDust || report.Findings[0].Index != 0 {
+			t.Errorf("Expected one dust warning, got %v", report.Findings)
+		}
+	})
+
+	t.Run("Precision", func(t *testing.T) {
+		// Trailing zeros of a scaled amount are not significant
+		claims := []merkle.AirdropClaim{claim(eoa(1), "123456"), claim(eoa(2), "1234567"), claim(eoa(3), "1000000000000")}
+		report := data.SanityReport(claims, data.SanityOptions{MaxSignificantDigits: 6})
+		if len(report.Findings) != 1 || report.Findings[0].Rule != data.RulePrecision || report.Findings[0].Index != 1 || report.Errors != 1 {
+			t.Errorf("Expected one precision error, got %v", report.Findings)
+		}
+	})
+
+	t.Run("OrderAndJSON", func(t *testing.T) {
+		address := eoa(7)
+		claims := []merkle.AirdropClaim{claim(eoa(1), "5"), {Address: address, Amount: new(big.Int).SetBytes(address.Bytes())}}
+		report := data.SanityReport(claims, data.SanityOptions{DustThreshold: big.NewInt(10), MaxSignificantDigits: 3})
+		if len(report.Findings) != 3 || report.Findings[0].Index != 0 || report.Findings[1].Rule != data.RuleAddressAmount || report.Findings[2].Rule != data.RulePrecision {
+			t.Fatalf("Expected findings ordered by claim then rule, got %v", report.Findings)
+		}
+
+		encoded, _ := json.Marshal(report)
+		var decoded data.Report
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatalf("Failed to decode report: %v", err)
+		}
+		if decoded.Findings[0].Severity != data.SeverityWarning || decoded.Findings[1].Severity != data.SeverityError || decoded.Errors != 2 || decoded.Warnings != 1 {
+			t.Errorf("Expected the report to round-trip, got %s", encoded)
+		}
+		var raw map[string][]map[string]interface{}
+		json.Unmarshal(encoded, &raw)
+		if raw["findings"][0]["severity"] != "warning" {
+			t.Errorf("Expected severities encoded by name, got %s", encoded)
+		}
+	})
+}
@@ -0,0 +1,513 @@
+// Types of the Merkle Airdrop API bodies, generated from the structs of
+// internal/api by the CLI's schema command. Do not edit.
+
+/** Codes of APIError.code */
+export type ErrorCode =
+  /** Malformed address in the path or body */
+  | "INVALID_ADDRESS"
+  /** Amount is not a base-10 integer */
+  | "INVALID_AMOUNT"
+  /** Malformed proof element */
+  | "INVALID_PROOF"
+  /** Body is not valid JSON or has unknown fields */
+  | "INVALID_REQUEST"
+  /** Body exceeds the server's limit */
+  | "PAYLOAD_TOO_LARGE"
+  /** Unknown query parameter value */
+  | "INVALID_PARAMETER"
+  /** Campaign update fails validation */
+  | "INVALID_CAMPAIGN"
+  /** Address is not in the airdrop */
+  | "ADDRESS_NOT_FOUND"
+  /** No archived campaign of that name */
+  | "CAMPAIGN_NOT_FOUND"
+  /** Endpoint turned off by server config */
+  | "ENDPOINT_DISABLED"
+  /** Verify-only server holds no claims to answer from */
+  | "NO_CLAIM_DATA"
+  /** Missing or wrong admin token */
+  | "UNAUTHORIZED"
+  /** Missing or wrong nonce signature in reservation or privacy mode */
+  | "INVALID_SIGNATURE"
+  /** Proof was already handed out in reservation mode */
+  | "ALREADY_ISSUED"
+  /** No issuance to reset */
+  | "NOT_ISSUED"
+  /** Client is throttled by abuse detection */
+  | "RATE_LIMITED"
+  /** No such route */
+  | "NOT_FOUND"
+  /** Route exists for other methods */
+  | "METHOD_NOT_ALLOWED"
+  /** Body is not application/json */
+  | "UNSUPPORTED_MEDIA_TYPE"
+  /** Server failed to build the response */
+  | "INTERNAL_ERROR"
+  /** Startup self-test found proofs that don't verify */
+  | "SELF_TEST_FAILED"
+  /** Claim window hasn't opened yet */
+  | "CLAIM_NOT_OPEN"
+  /** Claim window has closed */
+  | "CLAIM_CLOSED"
+  /** Address given twice in one request */
+  | "DUPLICATE_ADDRESS"
+  /** Appended address already has a claim */
+  | "CLAIM_EXISTS"
+  /** Reloaded config can't be read or fails validation */
+  | "INVALID_CONFIG"
+  /** Proof was generated against another root */
+  | "ROOT_MISMATCH"
+  /** No tree of that version is held */
+  | "VERSION_NOT_FOUND"
+  /** Campaign is locked since its root was deployed */
+  | "CAMPAIGN_FINALIZED"
+  /** No finalization to unlock */
+  | "NOT_FINALIZED"
+  /** Contract state couldn't be read from the node */
+  | "CHAIN_UNAVAILABLE"
+  /** Appended claims would exceed the tree's claim limit */
+  | "TOO_MANY_CLAIMS"
+  /** Server was not made by a constructor */
+  | "NOT_INITIALIZED"
+;
+
+export interface APIError {
+  code: ErrorCode;
+  message: string;
+}
+
+export interface AbuseClientStatus {
+  ip: string;
+  lastSeen: string;
+  limited: number;
+  notFound: number;
+  probes: number;
+  throttled: boolean;
+}
+
+export interface AbuseResetResponse {
+  reset: number;
+  success: boolean;
+}
+
+export interface AbuseResponse {
+  action: string;
+  clients: AbuseClientStatus[];
+  maxNotFound: number;
+  success: boolean;
+  window: string;
+}
+
+export interface AddressNotFoundResponse {
+  error: APIError;
+  requestId?: string;
+  success: boolean;
+  suggestions: Suggestion[];
+}
+
+export interface AlreadyIssuedResponse {
+  error: APIError;
+  issuedAt: string;
+  requestId?: string;
+  success: boolean;
+}
+
+export interface AppendClaimEntry {
+  address: string;
+  amount: string;
+}
+
+export interface AppendClaimsRequest {
+  claims: AppendClaimEntry[];
+}
+
+export interface AppendClaimsResponse {
+  claims: ProofResponse[];
+  merkleRoot: string;
+  previousRoot: string;
+  success: boolean;
+  version: number;
+}
+
+export interface BatchEntry {
+  address: string;
+  amount: string;
+  index?: number;
+  positions?: number;
+  proof: string[];
+  root?: string;
+}
+
+export interface BatchResult {
+  address?: string;
+  line: number;
+  reason?: string;
+  valid: boolean;
+}
+
+export interface CacheStats {
+  capacity: number;
+  hits: number;
+  misses: number;
+  size: number;
+}
+
+export interface CampaignMeta {
+  chainId?: number;
+  claimWindow?: ClaimWindow;
+  contractAddress?: string;
+  deadline?: string;
+  name: string;
+  tokenSymbol?: string;
+}
+
+export interface CampaignResponse {
+  basePath: string;
+  campaign: CampaignMeta;
+  finalization?: Finalization;
+  merkleRoot: string;
+  success: boolean;
+  totalClaims: number;
+}
+
+export interface CampaignUpdateResponse {
+  campaign: CampaignMeta;
+  success: boolean;
+}
+
+export interface ClaimClosedResponse {
+  closedAt: string;
+  error: APIError;
+  requestId?: string;
+  success: boolean;
+}
+
+export interface ClaimExistsResponse {
+  error: APIError;
+  existing: ExistingClaim[];
+  requestId?: string;
+  success: boolean;
+}
+
+export interface ClaimLinkResponse {
+  address: string;
+  link: string;
+  merkleRoot: string;
+  success: boolean;
+}
+
+export interface ClaimNotOpenResponse {
+  countdownSeconds: number;
+  error: APIError;
+  opensAt: string;
+  requestId?: string;
+  success: boolean;
+}
+
+export interface ClaimWindow {
+  end?: string;
+  start?: string;
+}
+
+export interface ClaimWindowStatus {
+  end?: string;
+  phase: string;
+  start?: string;
+}
+
+export interface ConfigReloadResponse {
+  applied: SettingChange[];
+  skipped: SettingChange[];
+  success: boolean;
+}
+
+export interface ConfigResponse {
+  config: unknown;
+  success: boolean;
+}
+
+export interface ConsistencyProof {
+  address: string;
+  amount: string;
+  index: number;
+  newMetadata: TreeMetadata;
+  newProof: MerkleProof;
+  newRoot: string;
+  oldMetadata: TreeMetadata;
+  oldProof: MerkleProof;
+  oldRoot: string;
+}
+
+export interface ConsistencyResponse {
+  changed: boolean;
+  consistency?: ConsistencyProof;
+  difference?: DifferenceProof;
+  from: number;
+  success: boolean;
+  to: number;
+}
+
+export interface DifferenceProof {
+  address: string;
+  new?: MerkleProof;
+  newMetadata: TreeMetadata;
+  newRoot: string;
+  old?: MerkleProof;
+  oldMetadata: TreeMetadata;
+  oldRoot: string;
+}
+
+export interface EligibilityResponse {
+  eligible: boolean;
+}
+
+export interface ErrorResponse {
+  error: APIError;
+  requestId?: string;
+  success: boolean;
+}
+
+export interface ExistingClaim {
+  address: string;
+  amount: string;
+  index: number;
+}
+
+export interface Finalization {
+  contractAddress: string;
+  finalizedAt: string;
+  merkleRoot: string;
+  operator: string;
+  rootVerified: boolean;
+  txHash: string;
+}
+
+export interface FinalizeRequest {
+  contractAddress: string;
+  operator: string;
+  txHash: string;
+}
+
+export interface FinalizeResponse {
+  finalization: Finalization;
+  success: boolean;
+}
+
+export interface FinalizedResponse {
+  error: APIError;
+  finalization: Finalization;
+  requestId?: string;
+  success: boolean;
+}
+
+export interface HealthResponse {
+  status: string;
+}
+
+export interface IndexStats {
+  gapCount: number;
+  gaps: number[];
+  maxIndex: number;
+  words: number;
+}
+
+export interface IndexerStatus {
+  headBlock: number;
+  indexedBlock: number;
+  lagBlocks: number;
+  synced: boolean;
+}
+
+export interface IssuanceResetResponse {
+  address: string;
+  success: boolean;
+}
+
+export interface MerkleProof {
+  amount: string;
+  index: number;
+  paddingCount?: number;
+  positions?: number;
+  proof: string[];
+  root?: string;
+}
+
+export interface NonceResponse {
+  expiresAt: number;
+  message: string;
+  nonce: string;
+  success: boolean;
+}
+
+export interface ProgressResponse {
+  complete: boolean;
+  done: number;
+  percent: number;
+  success: boolean;
+  total: number;
+}
+
+export interface ProofPendingResponse {
+  address: string;
+  progress: number;
+  ready: boolean;
+  success: boolean;
+}
+
+export interface ProofResponse {
+  address: string;
+  amount: string;
+  amountDisplay?: string;
+  campaign?: string;
+  index: number;
+  issuedAt?: string;
+  merkleRoot: string;
+  paddingCount?: number;
+  positions?: number;
+  proof: string[];
+  success: boolean;
+}
+
+export interface RebuildStatus {
+  duration: string;
+  error?: string;
+  failures: number;
+  lastRebuild: string;
+  outcome: string;
+  rebuilds: number;
+}
+
+export interface RootResponse {
+  campaign?: string;
+  merkleRoot: string;
+  metadata: TreeMetadata;
+  success: boolean;
+}
+
+export interface SelfTestResult {
+  at: string;
+  checked: number;
+  duration: string;
+  error?: string;
+  passed: boolean;
+}
+
+export interface SettingChange {
+  reason?: string;
+  setting: string;
+}
+
+export interface SimulateClaimRequest {
+  address: string;
+  amount: string;
+}
+
+export interface SimulateClaimResponse {
+  claim: ProofResponse;
+  currentRoot: string;
+  merkleRoot: string;
+  success: boolean;
+}
+
+export interface StatsResponse {
+  amountBits: number;
+  claimWindow: ClaimWindowStatus;
+  finalization?: Finalization;
+  indexer?: IndexerStatus;
+  indices: IndexStats;
+  merkleRoot: string;
+  proofCache?: CacheStats;
+  proofDepth: number;
+  rebuild?: RebuildStatus;
+  selfTest?: SelfTestResult;
+  success: boolean;
+  tiers?: TierStats[];
+  totalAmount?: string;
+  totalClaims: number;
+  totalProofs: number;
+}
+
+export interface Suggestion {
+  address: string;
+  claimed?: boolean;
+}
+
+export interface TierStats {
+  addresses: number;
+  max?: string;
+  min: string;
+  total: string;
+}
+
+export interface TraceStep {
+  hash?: string;
+  left?: boolean;
+  sibling: string;
+}
+
+export interface TreeMetadata {
+  domainSeparator?: string;
+  fixedDepth?: number;
+  includeIndex: boolean;
+  indexFirst?: boolean;
+  keepIndices?: boolean;
+  maxAmountBits?: number;
+  oddLeafPolicy?: string;
+  sortOrder: string;
+  sortedPairs: boolean;
+}
+
+export interface TreeVersion {
+  added: number;
+  createdAt: string;
+  merkleRoot: string;
+  totalClaims: number;
+  version: number;
+}
+
+export interface TreeVersionsResponse {
+  success: boolean;
+  versions: TreeVersion[];
+}
+
+export interface UnlockRequest {
+  operator: string;
+  reason: string;
+}
+
+export interface UnlockResponse {
+  success: boolean;
+  unlocked: Finalization;
+}
+
+export interface VerificationTrace {
+  computedRoot?: string;
+  expectedRoot: string;
+  failedStep?: number;
+  failure?: string;
+  leaf: string;
+  reason?: string;
+  steps: TraceStep[];
+  valid: boolean;
+}
+
+export interface VerifyRequest {
+  address: string;
+  amount: string;
+  index?: number;
+  merkleRoot?: string;
+  positions?: number;
+  proof: string[];
+  root?: string;
+}
+
+export interface VerifyResponse {
+  address: string;
+  alreadyClaimed?: boolean;
+  amount: string;
+  contractAddress?: string;
+  merkleRoot: string;
+  success: boolean;
+  trace?: VerificationTrace;
+  valid: boolean;
+}
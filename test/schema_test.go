@@ -0,0 +1,157 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// Golden copies of the generated schemas. A struct change must come with
+// regenerated copies, so the frontend's contract changes explicitly:
+// go test ./test -run TestSchema -update
+const (
+	schemaGoldenFile = "schema_golden.json"
+	tsGoldenFile     = "schema_golden.d.ts"
+)
+
+// jsonSchemaDoc is the part of /api/schema the tests read
+type jsonSchemaDoc struct {
+	Schema string `json:"$schema"`
+	Defs   map[string]struct {
+		Properties map[string]struct {
+			OneOf []struct {
+				Const       string `json:"const"`
+				Description string `json:"description"`
+			} `json:"oneOf"`
+		} `json:"properties"`
+	} `json:"$defs"`
+}
+
+// noTreeHistory holds no earlier trees
+type noTreeHistory struct{}
+
+func (noTreeHistory) TreeAt(int) (*merkle.MerkleTree, bool) { return nil, false }
+
+// fixedConfig is a config that never changes
+type fixedConfig struct{}
+
+func (fixedConfig) ReloadConfig() (api.ReloadResult, error) { return api.ReloadResult{}, nil }
+
+func (fixedConfig) RunningConfig() interface{} { return map[string]interface{}{} }
+
+func TestSchema(t *testing.T) {
+	tree, proofs := buildProofSet(t, 8)
+	generated, _ := json.MarshalIndent(api.JSONSchema(), "", "  ")
+	generated = append(generated, '\n')
+
+	t.Run("Golden", func(t *testing.T) {
+		for file, content := range map[string][]byte{
+			schemaGoldenFile: generated,
+			tsGoldenFile:     []byte(api.TypeScriptDefinitions()),
+		} {
+			if *updateGolden {
+				if err := os.WriteFile(file, content, 0o644); err != nil {
+					t.Fatalf("Failed to write %s: %v", file, err)
+				}
+			}
+			want, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", file, err)
+			}
+			if !bytes.Equal(content, want) {
+				t.Errorf("The API's bodies no longer match %s; if the change is intended, regenerate it with: go test ./test -run TestSchema -update", file)
+			}
+		}
+	})
+
+	t.Run("Endpoint", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/schema", nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("Expected the schema, got %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		if !bytes.Equal(append(w.Body.Bytes(), '\n'), generated) {
+			t.Error("Expected /api/schema to serve api.JSONSchema")
+		}
+	})
+
+	var doc jsonSchemaDoc
+	if err := json.Unmarshal(generated, &doc); err != nil {
+		t.Fatalf("Failed to parse the schema: %v", err)
+	}
+
+	t.Run("EveryBody", func(t *testing.T) {
+		// Every body a server with each feature on documents is in the schema
+		lock, _ := api.NewFinalizationLock("")
+		handler := api.MustNewAPIServer(tree, proofs.Proofs,
+			api.WithAdminTokens([]string{"admin-secret"}),
+			api.WithReservation(api.NewMemoryClaimStore()),
+			api.WithAbuseDetection(api.AbuseConfig{Window: time.Minute, MaxNotFound: 100, Action: api.AbuseReject}),
+			api.WithCampaign(api.CampaignMeta{Name: "Season 1"}, ""),
+			api.WithClaimAppender(refusingAppender{}),
+			api.WithTreeHistory(noTreeHistory{}),
+			api.WithFinalization(lock, nil),
+			api.WithConfigReloader(fixedConfig{}),
+			api.WithBloomFilter(0.01),
+			api.WithClaimLinkURL("https://claim.example"),
+		).SetupRoutes()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+		var spec openAPISpec
+		if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil || len(spec.Components.Schemas) == 0 {
+			t.Fatalf("Failed to read the OpenAPI document: %v", err)
+		}
+		for name := range spec.Components.Schemas {
+			if _, ok := doc.Defs[name]; !ok {
+				t.Errorf("%s is an endpoint's body but is missing from /api/schema", name)
+			}
+		}
+		if doc.Schema != "https://json-schema.org/draft/2020-12/schema" {
+			t.Errorf("Expected a draft 2020-12 schema, got %q", doc.Schema)
+		}
+	})
+
+	t.Run("ErrorCodes", func(t *testing.T) {
+		// Every Code constant is listed with a description
+		file, err := parser.ParseFile(token.NewFileSet(), "../internal/api/errors.go", nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse errors.go: %v", err)
+		}
+		var codes []string
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok || len(spec.Names) != 1 || !strings.HasPrefix(spec.Names[0].Name, "Code") || len(spec.Values) != 1 {
+				return true
+			}
+			if lit, ok := spec.Values[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				code, _ := strconv.Unquote(lit.Value)
+				codes = append(codes, code)
+			}
+			return true
+		})
+		documented := map[string]string{}
+		for _, option := range doc.Defs["APIError"].Properties["code"].OneOf {
+			documented[option.Const] = option.Description
+		}
+		if len(codes) == 0 || len(documented) != len(codes) {
+			t.Errorf("Expected %d documented codes, got %d", len(codes), len(documented))
+		}
+		for _, code := range codes {
+			if documented[code] == "" {
+				t.Errorf("%s is missing from the schema's error codes", code)
+			}
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestSelfTest(t *testing.T) {
+	tree, proofs := buildProofSet(t, 20)
+
+	get := func(server *api.APIServer, path string, v interface{}) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		json.NewDecoder(w.Body).Decode(v)
+		return w.Code
+	}
+
+	t.Run("Passes", func(t *testing.T) {
+		server := mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), proofs))
+		var ready api.HealthResponse
+		if status := get(server, "/readyz", &ready); status != http.StatusOK {
+			t.Errorf("Expected /readyz to pass before a self-test, got %d", status)
+		}
+
+		result, err := server.SelfTest(100, 0)
+		if err != nil || !result.Passed || result.Checked != 20 {
+			t.Fatalf("Expected all 20 proofs checked, got %+v (%v)", result, err)
+		}
+		var stats api.StatsResponse
+		get(server, "/api/stats", &stats)
+		if stats.SelfTest == nil || !stats.SelfTest.Passed || stats.SelfTest.At.IsZero() {
+			t.Errorf("Expected the passed self-test in /api/stats, got %+v", stats.SelfTest)
+		}
+		if status := get(server, "/readyz", &ready); status != http.StatusOK || ready.Status != "ok" {
+			t.Errorf("Expected /readyz to pass, got %d %+v", status, ready)
+		}
+
+		// Samples bound the proofs checked
+		if result, _ := server.SelfTest(5, 0); result.Checked != 5 {
+			t.Errorf("Expected 5 proofs checked, got %d", result.Checked)
+		}
+	})
+
+	t.Run("MismatchedProofs", func(t *testing.T) {
+		// Proofs of one build served with the root of another, as after a
+		// partial deploy
+		other, _ := buildProofSet(t, 21)
+		server := mustServer(t)(api.NewAPIServerFromProofs(other.GetRootHash(), proofs))
+		_, err := server.SelfTest(10, 0)
+		if err == nil || !strings.Contains(err.Error(), "does not verify against root "+other.GetRootHash()) {
+			t.Fatalf("Expected a clear mismatch error, got %v", err)
+		}
+
+		var failed api.ErrorResponse
+		if status := get(server, "/readyz", &failed); status != http.StatusServiceUnavailable || failed.Error.Code != api.CodeSelfTestFailed {
+			t.Errorf("Expected /readyz to fail with %s, got %d %+v", api.CodeSelfTestFailed, status, failed)
+		}
+		var stats api.StatsResponse
+		get(server, "/api/stats", &stats)
+		if stats.SelfTest == nil || stats.SelfTest.Passed || stats.SelfTest.Error != err.Error() {
+			t.Errorf("Expected the failure in /api/stats, got %+v", stats.SelfTest)
+		}
+	})
+
+	t.Run("OneBadProof", func(t *testing.T) {
+		tampered := &merkle.ProofSet{Proofs: make(map[string]*merkle.MerkleProof), Metadata: proofs.Metadata}
+		for address, proof := range proofs.Proofs {
+			tampered.Proofs[address] = proof
+		}
+		bad := tree.Claims[13].Address.Hex()
+		changed := *proofs.Proofs[bad]
+		changed.Amount = "1"
+		tampered.Proofs[bad] = &changed
+
+		_, err := mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), tampered)).SelfTest(20, 0)
+		if err == nil || !strings.Contains(err.Error(), bad) {
+			t.Errorf("Expected the bad proof of %s to be named, got %v", bad, err)
+		}
+	})
+
+	t.Run("Budget", func(t *testing.T) {
+		_, err := mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), proofs)).SelfTest(20, 1)
+		if err == nil || !strings.Contains(err.Error(), "budget") {
+			t.Errorf("Expected the budget to run out, got %v", err)
+		}
+	})
+
+	t.Run("Lazy", func(t *testing.T) {
+		server := mustServer(t)(api.NewLazyAPIServer(tree, 10))
+		if _, err := server.SelfTest(100, 0); err != nil {
+			t.Fatalf("Expected the lazy server to pass, got %v", err)
+		}
+		var stats api.StatsResponse
+		get(server, "/api/stats", &stats)
+		if stats.ProofCache.Size != 0 || stats.ProofCache.Misses != 0 {
+			t.Errorf("Expected the self-test to leave the cache alone, got %+v", stats.ProofCache)
+		}
+	})
+
+	t.Run("VerifyOnly", func(t *testing.T) {
+		if _, err := mustServer(t)(api.NewVerifyOnlyServer(tree.Root.Hash, tree.Options())).SelfTest(10, 0); err == nil {
+			t.Error("Expected a verify-only server to refuse the self-test")
+		}
+	})
+}
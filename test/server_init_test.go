@@ -0,0 +1,144 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// mustServer returns a function failing t when a server constructor does,
+// for chaining constructors that return an error
+func mustServer(t testing.TB) func(*api.APIServer, error) *api.APIServer {
+	return func(server *api.APIServer, err error) *api.APIServer {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		return server
+	}
+}
+
+func TestServerInitialization(t *testing.T) {
+	tree, proofs := buildProofSet(t, 10)
+
+	t.Run("Constructors", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			new  func() (*api.APIServer, error)
+			want error // nil for any error
+		}{
+			{"NilTree", func() (*api.APIServer, error) { return api.NewAPIServer(nil, proofs.Proofs) }, api.ErrNoTree},
+			{"UnbuiltTree", func() (*api.APIServer, error) { return api.NewAPIServer(&merkle.MerkleTree{}, proofs.Proofs) }, api.ErrNoTree},
+			{"NilProofs", func() (*api.APIServer, error) { return api.NewAPIServer(tree, nil) }, api.ErrNoProofs},
+			{"EmptyProofs", func() (*api.APIServer, error) { return api.NewAPIServer(tree, map[string]*merkle.MerkleProof{}) }, api.ErrNoProofs},
+			{"NilStore", func() (*api.APIServer, error) { return api.NewAPIServerWithStore(tree, nil) }, api.ErrNoProofs},
+			{"LazyNilTree", func() (*api.APIServer, error) { return api.NewLazyAPIServer(nil, 8) }, api.ErrNoTree},
+			{"PrecomputingNilTree", func() (*api.APIServer, error) { return api.NewPrecomputingAPIServer(nil) }, api.ErrNoTree},
+			{"NilProofSet", func() (*api.APIServer, error) { return api.NewAPIServerFromProofs(tree.GetRootHash(), nil) }, api.ErrNoProofs},
+			{"BadRoot", func() (*api.APIServer, error) { return api.NewAPIServerFromProofs("0x1234", proofs) }, nil},
+			{"ShortVerifyRoot", func() (*api.APIServer, error) { return api.NewVerifyOnlyServer(tree.Root.Hash[:16], tree.Options()) }, nil},
+		} {
+			server, err := tc.new()
+			if server != nil || err == nil || (tc.want != nil && !errors.Is(err, tc.want)) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+			}
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected MustNewAPIServer to panic on a nil tree")
+			}
+		}()
+		api.MustNewAPIServer(nil, proofs.Proofs)
+	})
+
+	t.Run("Builder", func(t *testing.T) {
+		other, otherProofs := buildProofSet(t, 11)
+		meta := api.CampaignMeta{Name: "Season 1"}
+		for _, tc := range []struct {
+			name    string
+			builder *api.APIServerBuilder
+			want    string // Empty to succeed
+		}{
+			{"TreeAndProofs", api.NewAPIServerBuilder().Tree(tree).Proofs(proofs.Proofs).Campaign(meta, ""), ""},
+			{"ProofSet", api.NewAPIServerBuilder().ProofSet(tree.GetRootHash(), proofs), ""},
+			{"TreeAndProofSet", api.NewAPIServerBuilder().Tree(tree).ProofSet(tree.GetRootHash(), proofs), ""},
+			{"StoreAndRoot", api.NewAPIServerBuilder().ProofStore(merkle.MapProofStore(proofs.Proofs)).Root(tree.GetRootHash(), tree.Metadata()), ""},
+			{"NoProofs", api.NewAPIServerBuilder().Tree(tree), api.ErrNoProofs.Error()},
+			{"StoreWithoutRoot", api.NewAPIServerBuilder().ProofStore(merkle.MapProofStore(proofs.Proofs)), api.ErrNoTree.Error()},
+			{"OtherTreesProofs", api.NewAPIServerBuilder().Tree(tree).ProofSet(other.GetRootHash(), otherProofs), "not the tree's"},
+			{"MissingProofs", api.NewAPIServerBuilder().Tree(other).Proofs(proofs.Proofs), "10 proofs for the tree's 11 addresses"},
+			{"InvalidCampaign", api.NewAPIServerBuilder().Tree(tree).Proofs(proofs.Proofs).Campaign(api.CampaignMeta{}, ""), "invalid campaign"},
+		} {
+			server, err := tc.builder.Build()
+			switch {
+			case tc.want == "" && err != nil:
+				t.Errorf("%s: %v", tc.name, err)
+			case tc.want == "":
+				w := httptest.NewRecorder()
+				server.SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/root", nil))
+				if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tree.GetRootHash()) {
+					t.Errorf("%s: expected the root, got %d %s", tc.name, w.Code, w.Body)
+				}
+			case err == nil || !strings.Contains(err.Error(), tc.want):
+				t.Errorf("%s: expected %q, got %v", tc.name, tc.want, err)
+			}
+		}
+
+		server, _ := api.NewAPIServerBuilder().Tree(tree).Proofs(proofs.Proofs).Campaign(meta, "").Build()
+		w := httptest.NewRecorder()
+		server.SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/campaign", nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Season 1") {
+			t.Errorf("Expected the builder's campaign, got %d %s", w.Code, w.Body)
+		}
+	})
+
+	t.Run("ZeroValue", func(t *testing.T) {
+		// Every route a fully configured server has
+		full := api.MustNewAPIServer(tree, proofs.Proofs,
+			api.WithAdminTokens([]string{"admin-secret"}),
+			api.WithReservation(api.NewMemoryClaimStore()),
+			api.WithCampaign(api.CampaignMeta{Name: "Season 1"}, ""),
+			api.WithBloomFilter(0.01),
+			api.WithClaimLinkURL("https://claim.example"),
+		).SetupRoutes()
+		w := httptest.NewRecorder()
+		full.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+		var doc openAPISpec
+		if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || len(doc.Paths) == 0 {
+			t.Fatalf("Failed to read the routes: %v", err)
+		}
+
+		handler := (&api.APIServer{}).SetupRoutes()
+		address := tree.Claims[0].Address.Hex()
+		for path, item := range doc.Paths {
+			for method := range item {
+				target := strings.ReplaceAll(path, "{address}", address)
+				method = strings.ToUpper(method)
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							t.Errorf("%s %s panicked: %v", method, target, r)
+						}
+					}()
+					r := httptest.NewRequest(method, target, strings.NewReader("{}"))
+					r.Header.Set("Content-Type", "application/json")
+					r.Header.Set("Authorization", "Bearer admin-secret")
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, r)
+					var body api.ErrorResponse
+					json.Unmarshal(w.Body.Bytes(), &body)
+					if w.Code != http.StatusServiceUnavailable || body.Error.Code != api.CodeNotInitialized {
+						t.Errorf("%s %s: expected 503 %s, got %d %s", method, target, api.CodeNotInitialized, w.Code, w.Body)
+					}
+				}()
+			}
+		}
+	})
+}
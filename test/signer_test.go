@@ -0,0 +1,195 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"merkle-airdrop/pkg/contract"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// writeKeystore encrypts a fresh key into dir and returns its path and address
+func writeKeystore(t *testing.T, dir, passphrase string) (string, common.Address) {
+	t.Helper()
+
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key := &keystore.Key{
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}
+	keyJSON, err := keystore.EncryptKey(key, passphrase, keystore.LightScryptN, keystore.LightScryptP)
+	if err != nil {
+		t.Fatalf("Failed to encrypt key: %v", err)
+	}
+
+	path := filepath.Join(dir, "keystore.json")
+	if err := os.WriteFile(path, keyJSON, 0600); err != nil {
+		t.Fatalf("Failed to write keystore: %v", err)
+	}
+	return path, key.Address
+}
+
+func unsignedTx(nonce uint64) *types.Transaction {
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(20000000000),
+		Gas:       21000,
+		To:        &common.Address{0x01},
+		Value:     big.NewInt(1),
+	})
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	path, address := writeKeystore(t, t.TempDir(), "correct horse")
+	chainID := big.NewInt(1337)
+
+	t.Run("RecoveredSenderMatches", func(t *testing.T) {
+		signer, err := contract.NewKeystoreSigner(path, "correct horse")
+		if err != nil {
+			t.Fatalf("Failed to open keystore: %v", err)
+		}
+		if signer.Address() != address {
+			t.Errorf("Expected address %s, got %s", address.Hex(), signer.Address().Hex())
+		}
+
+		signed, err := signer.SignTx(unsignedTx(0), chainID)
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil {
+			t.Fatalf("Failed to recover sender: %v", err)
+		}
+		if sender != address {
+			t.Errorf("Expected sender %s, got %s", address.Hex(), sender.Hex())
+		}
+	})
+
+	t.Run("WrongPassphrase", func(t *testing.T) {
+		if _, err := contract.NewKeystoreSigner(path, "wrong"); err == nil {
+			t.Error("Expected error for wrong passphrase")
+		}
+	})
+
+	t.Run("DeployThroughClient", func(t *testing.T) {
+		signer, err := contract.NewKeystoreSigner(path, "correct horse")
+		if err != nil {
+			t.Fatalf("Failed to open keystore: %v", err)
+		}
+
+		backend := simulated.NewBackend(types.GenesisAlloc{
+			address: {Balance: new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))},
+		})
+		defer backend.Close()
+		eth := backend.Client()
+
+		simChainID, err := eth.ChainID(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to read chain ID: %v", err)
+		}
+
+		client := contract.NewContractClientWithSigner(eth, signer, simChainID)
+		distributor, err := client.DeployAirdrop(common.Address{0x02}, [32]byte{0x03})
+		if err != nil {
+			t.Fatalf("Failed to deploy: %v", err)
+		}
+		backend.Commit()
+
+		// Deployment addresses derive from the sender's nonce
+		if want := crypto.CreateAddress(address, 0); distributor != want {
+			t.Errorf("Expected deployment from %s at %s, got %s", address.Hex(), want.Hex(), distributor.Hex())
+		}
+		nonce, err := eth.NonceAt(context.Background(), address, nil)
+		if err != nil {
+			t.Fatalf("Failed to read nonce: %v", err)
+		}
+		if nonce != 1 {
+			t.Errorf("Expected keystore account nonce 1, got %d", nonce)
+		}
+	})
+}
+
+func TestRemoteSigner(t *testing.T) {
+	chainID := big.NewInt(1337)
+
+	// signingService signs every request with key
+	signingService := func(t *testing.T, key *contract.KeySigner) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Address string `json:"address"`
+				ChainID string `json:"chainId"`
+				Tx      string `json:"tx"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			tx := new(types.Transaction)
+			if err := tx.UnmarshalBinary(hexutil.MustDecode(req.Tx)); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			id, _ := new(big.Int).SetString(req.ChainID, 10)
+			signed, err := key.SignTx(tx, id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			raw, _ := signed.MarshalBinary()
+			json.NewEncoder(w).Encode(map[string]string{"signedTx": hexutil.Encode(raw)})
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+
+	newKey := func(t *testing.T) *contract.KeySigner {
+		privateKey, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		return contract.NewKeySigner(privateKey)
+	}
+
+	t.Run("RecoveredSenderMatches", func(t *testing.T) {
+		key := newKey(t)
+		signer := contract.NewRemoteSigner(signingService(t, key).URL, key.Address())
+
+		signed, err := signer.SignTx(unsignedTx(3), chainID)
+		if err != nil {
+			t.Fatalf("Failed to sign: %v", err)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil {
+			t.Fatalf("Failed to recover sender: %v", err)
+		}
+		if sender != key.Address() {
+			t.Errorf("Expected sender %s, got %s", key.Address().Hex(), sender.Hex())
+		}
+	})
+
+	t.Run("WrongAccountRejected", func(t *testing.T) {
+		signer := contract.NewRemoteSigner(signingService(t, newKey(t)).URL, newKey(t).Address())
+		if _, err := signer.SignTx(unsignedTx(3), chainID); err == nil {
+			t.Error("Expected error when the service signs as another account")
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSimulateAddClaim(t *testing.T) {
+	positional := func(policy merkle.OddLeafPolicy) merkle.TreeOptions {
+		opts := merkle.DefaultTreeOptions()
+		opts.SortedPairs = false
+		opts.OddLeafPolicy = policy
+		return opts
+	}
+	withOptions := func(change func(*merkle.TreeOptions)) merkle.TreeOptions {
+		opts := merkle.DefaultTreeOptions()
+		change(&opts)
+		return opts
+	}
+	options := map[string]func() merkle.TreeOptions{
+		"Default":   merkle.DefaultTreeOptions,
+		"Duplicate": func() merkle.TreeOptions { return positional(merkle.DuplicateLast) },
+		"Promote":   func() merkle.TreeOptions { return positional(merkle.Promote) },
+		"ZeroPad":   func() merkle.TreeOptions { return positional(merkle.ZeroPad) },
+		"ByIndex": func() merkle.TreeOptions {
+			return withOptions(func(o *merkle.TreeOptions) { o.SortOrder = merkle.SortByIndex })
+		},
+		"Input": func() merkle.TreeOptions {
+			return withOptions(func(o *merkle.TreeOptions) { o.SortOrder = merkle.PreserveInput })
+		},
+		"FixedDepth": func() merkle.TreeOptions { return withOptions(func(o *merkle.TreeOptions) { o.FixedDepth = 8 }) },
+		"Domain": func() merkle.TreeOptions {
+			return withOptions(func(o *merkle.TreeOptions) { o.DomainSeparator = merkle.DomainSeparatorFor("simulate") })
+		},
+		"Allocator": func() merkle.TreeOptions {
+			return withOptions(func(o *merkle.TreeOptions) { o.Indices = merkle.NewIndexAllocator() })
+		},
+	}
+
+	t.Run("MatchesRebuild", func(t *testing.T) {
+		// Random addresses land before, between and after the tree's
+		outsiders := data.GenerateRandomTestData(4, 7)
+		for name, newOptions := range options {
+			for _, size := range []int{1, 2, 3, 4, 5, 8, 13, 33} {
+				for _, outsider := range outsiders {
+					label := fmt.Sprintf("%s/%d/%s", name, size, outsider.Address.Hex())
+					tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateRandomTestData(size, 1), newOptions())
+					if err != nil {
+						t.Fatalf("%s: failed to build tree: %v", label, err)
+					}
+					before := tree.GetRootHash()
+					first, _ := tree.GenerateProof(tree.Claims[0].Address)
+
+					root, proof, err := tree.SimulateAddClaim(outsider)
+					if err != nil {
+						t.Fatalf("%s: %v", label, err)
+					}
+					if tree.GetRootHash() != before || len(tree.Claims) != size {
+						t.Fatalf("%s: the tree changed to root %s with %d claims", label, tree.GetRootHash(), len(tree.Claims))
+					}
+					if again, _ := tree.GenerateProof(tree.Claims[0].Address); !reflect.DeepEqual(again, first) {
+						t.Errorf("%s: the tree's proofs changed", label)
+					}
+
+					rebuilt, err := tree.AddClaims([]merkle.AirdropClaim{outsider})
+					if err != nil {
+						t.Fatalf("%s: failed to rebuild: %v", label, err)
+					}
+					if !bytes.Equal(root, rebuilt.Root.Hash) {
+						t.Errorf("%s: simulated root 0x%x, rebuilt %s", label, root, rebuilt.GetRootHash())
+					}
+					want, _ := rebuilt.GenerateProof(outsider.Address)
+					if !reflect.DeepEqual(proof, want) {
+						t.Errorf("%s: simulated proof %+v, rebuilt %+v", label, proof, want)
+					}
+					claim := outsider
+					claim.Index = proof.Index
+					if ok, err := merkle.VerifyMerkleProof(root, claim, proof, tree.Options()); !ok || err != nil {
+						t.Errorf("%s: the simulated proof doesn't verify (%v)", label, err)
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("FromScratch", func(t *testing.T) {
+		claims := data.GenerateTestData(10)
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		added := merkle.AirdropClaim{Address: common.HexToAddress("0x00000000000000000000000000000000000005ff"), Amount: big.NewInt(12345)}
+		root, proof, err := tree.SimulateAddClaim(added)
+		if err != nil {
+			t.Fatalf("Failed to simulate: %v", err)
+		}
+
+		// Built from nothing, with the indices the simulation kept
+		added.Index = proof.Index
+		opts := merkle.DefaultTreeOptions()
+		opts.KeepIndices = true
+		fresh, err := merkle.NewMerkleTreeWithOptions(append(append([]merkle.AirdropClaim(nil), tree.Claims...), added), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		if !bytes.Equal(root, fresh.Root.Hash) || proof.Index != 10 {
+			t.Errorf("Expected root %s and index 10, got 0x%x and %d", fresh.GetRootHash(), root, proof.Index)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.MaxClaims = 5
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(4), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		have := tree.Claims[2]
+		_, _, err = tree.SimulateAddClaim(merkle.AirdropClaim{Address: have.Address, Amount: big.NewInt(1)})
+		var existingErr *merkle.ExistingClaimError
+		if !errors.As(err, &existingErr) || existingErr.Claims[0].Amount.Cmp(have.Amount) != 0 {
+			t.Errorf("Expected an ExistingClaimError with amount %s, got %v", have.Amount, err)
+		}
+
+		outsider := merkle.AirdropClaim{Address: common.HexToAddress("0x00000000000000000000000000000000000000a1"), Amount: big.NewInt(1)}
+		if _, _, err := tree.SimulateAddClaim(outsider); err != nil {
+			t.Errorf("Expected a fifth claim to fit, got %v", err)
+		}
+		full, _ := tree.AddClaims([]merkle.AirdropClaim{outsider})
+		outsider.Address = common.HexToAddress("0x00000000000000000000000000000000000000a2")
+		if _, _, err := full.SimulateAddClaim(outsider); !errors.Is(err, merkle.ErrTooManyClaims) {
+			t.Errorf("Expected a sixth claim to be over the limit, got %v", err)
+		}
+		outsider.Amount = big.NewInt(-1)
+		if _, _, err := tree.SimulateAddClaim(outsider); err == nil {
+			t.Error("Expected a negative amount to be rejected")
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		const token = "admin-secret"
+		tree, proofs := buildProofSet(t, 10)
+		handler := api.MustNewAPIServer(tree, proofs.Proofs, api.WithAdminTokens([]string{token})).SetupRoutes()
+		simulate := func(req api.SimulateClaimRequest, auth string) (*httptest.ResponseRecorder, []byte) {
+			body, _ := json.Marshal(req)
+			r := httptest.NewRequest(http.MethodPost, "/api/admin/simulate", bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			if auth != "" {
+				r.Header.Set("Authorization", "Bearer "+auth)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			return w, w.Body.Bytes()
+		}
+		before := tree.GetRootHash()
+
+		outsider := "0x00000000000000000000000000000000deadbeef"
+		w, body := simulate(api.SimulateClaimRequest{Address: outsider, Amount: "500"}, token)
+		var response api.SimulateClaimResponse
+		if err := json.Unmarshal(body, &response); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("Expected 200, got %d %s", w.Code, body)
+		}
+		rebuilt, _ := tree.AddClaims([]merkle.AirdropClaim{{Address: common.HexToAddress(outsider), Amount: big.NewInt(500)}})
+		want, _ := rebuilt.GenerateProof(common.HexToAddress(outsider))
+		if response.MerkleRoot != rebuilt.GetRootHash() || response.CurrentRoot != before || !reflect.DeepEqual(response.Claim.Proof, want.Proof) {
+			t.Errorf("Expected root %s from %s, got %s", rebuilt.GetRootHash(), before, body)
+		}
+		if tree.GetRootHash() != before {
+			t.Errorf("Expected the served root to stay %s, got %s", before, tree.GetRootHash())
+		}
+
+		have := tree.Claims[0]
+		w, body = simulate(api.SimulateClaimRequest{Address: have.Address.Hex(), Amount: "1"}, token)
+		var exists api.ClaimExistsResponse
+		json.Unmarshal(body, &exists)
+		if w.Code != http.StatusConflict || len(exists.Existing) != 1 || exists.Existing[0].Amount != have.Amount.String() {
+			t.Errorf("Expected 409 with amount %s, got %d %s", have.Amount, w.Code, body)
+		}
+		if w, body := simulate(api.SimulateClaimRequest{Address: outsider, Amount: "-5"}, token); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected a negative amount to be refused, got %d %s", w.Code, body)
+		}
+		if w, _ := simulate(api.SimulateClaimRequest{Address: outsider, Amount: "500"}, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without a token, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,194 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestSmallTrees(t *testing.T) {
+	sorted := merkle.DefaultTreeOptions()
+	sorted.SortedPairs = true
+	promote := merkle.DefaultTreeOptions()
+	promote.OddLeafPolicy = merkle.Promote
+	zeroPad := merkle.DefaultTreeOptions()
+	zeroPad.OddLeafPolicy = merkle.ZeroPad
+	fixed := merkle.DefaultTreeOptions()
+	fixed.FixedDepth = 4
+	fixedSorted := sorted
+	fixedSorted.FixedDepth = 4
+	variants := map[string]merkle.TreeOptions{
+		"Default":     merkle.DefaultTreeOptions(),
+		"SortedPairs": sorted,
+		"Promote":     promote,
+		"ZeroPad":     zeroPad,
+		"FixedDepth":  fixed,
+		"FixedSorted": fixedSorted,
+	}
+	// depths is the proof length of each leaf by claim count, unpadded
+	depths := map[int][]int{1: {0}, 2: {1, 1}, 3: {2, 2, 2}}
+
+	for _, count := range []int{1, 2, 3} {
+		for name, opts := range variants {
+			tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(count), opts)
+			if err != nil {
+				t.Fatalf("%d/%s: failed to build tree: %v", count, name, err)
+			}
+			root := hexutil.MustDecode(tree.GetRootHash())
+			proofs, err := tree.GenerateAllProofs()
+			if err != nil || len(proofs) != count {
+				t.Fatalf("%d/%s: expected %d proofs, got %d (%v)", count, name, count, len(proofs), err)
+			}
+
+			for i, claim := range tree.Claims {
+				proof, err := tree.GenerateProof(claim.Address)
+				if err != nil {
+					t.Fatalf("%d/%s: failed to generate proof: %v", count, name, err)
+				}
+				want := depths[count][i]
+				if opts.OddLeafPolicy == merkle.Promote && count == 3 && i == 2 {
+					want = 1
+				}
+				if opts.FixedDepth != 0 {
+					want = opts.FixedDepth
+				}
+				if proof.Proof == nil || len(proof.Proof) != want {
+					t.Errorf("%d/%s: expected a proof of %d elements for claim %d, got %#v", count, name, want, i, proof.Proof)
+				}
+				if valid, err := merkle.VerifyProofWithPositions(root, claim, proof.Proof, proof.Positions, opts); !valid || err != nil {
+					t.Errorf("%d/%s: expected the proof of claim %d to verify (%v)", count, name, i, err)
+				}
+				tampered := claim
+				tampered.Amount = new(big.Int).Add(claim.Amount, big.NewInt(1))
+				if valid, _ := merkle.VerifyProofWithPositions(root, tampered, proof.Proof, proof.Positions, opts); valid {
+					t.Errorf("%d/%s: expected a changed amount not to verify for claim %d", count, name, i)
+				}
+				if all := proofs[claim.Address.Hex()]; len(all.Proof) != len(proof.Proof) || all.Positions != proof.Positions {
+					t.Errorf("%d/%s: expected GenerateAllProofs to match GenerateProof for claim %d", count, name, i)
+				}
+			}
+		}
+	}
+
+	// Exports write the empty proof of a single-claim tree as []
+	t.Run("JSONShape", func(t *testing.T) {
+		tree, proofs := buildProofSet(t, 1)
+		address := tree.Claims[0].Address
+
+		encoded, _ := json.Marshal(proofs.Proofs[address.Hex()])
+		assertEmptyProof(t, "MerkleProof", encoded)
+
+		var canonical bytes.Buffer
+		if err := data.ExportProofsCanonical(proofs, tree.GetRootHash(), &canonical, data.AddressChecksum, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export: %v", err)
+		}
+		var file struct {
+			Proofs map[string]json.RawMessage `json:"proofs"`
+		}
+		json.Unmarshal(canonical.Bytes(), &file)
+		assertEmptyProof(t, "Canonical", file.Proofs[address.Hex()])
+
+		root, loaded, err := data.LoadProofsJSON(bytes.NewReader(canonical.Bytes()))
+		if err != nil || root != tree.GetRootHash() {
+			t.Fatalf("Failed to load the export: %v", err)
+		}
+		encoded, _ = json.Marshal(loaded.Proofs[address.Hex()])
+		assertEmptyProof(t, "Loaded", encoded)
+
+		var binary bytes.Buffer
+		if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &binary); err != nil {
+			t.Fatalf("Failed to export binary: %v", err)
+		}
+		decoded, _, err := data.LoadProofsBinary(&binary)
+		if err != nil {
+			t.Fatalf("Failed to load binary: %v", err)
+		}
+		encoded, _ = json.Marshal(decoded.Proofs[address.Hex()])
+		assertEmptyProof(t, "Binary", encoded)
+
+		var subset bytes.Buffer
+		if err := data.ExportProofsSubset(proofs, []common.Address{address}, tree.GetRootHash(), &subset, data.ProofMarshaller{}); err != nil {
+			t.Fatalf("Failed to export subset: %v", err)
+		}
+		json.Unmarshal(subset.Bytes(), &file)
+		assertEmptyProof(t, "Subset", file.Proofs[address.Hex()])
+
+		served := httptest.NewRecorder()
+		api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes().ServeHTTP(served, httptest.NewRequest(http.MethodGet, "/api/proof/"+address.Hex(), nil))
+		assertEmptyProof(t, "API", served.Body.Bytes())
+	})
+
+	t.Run("UniswapImport", func(t *testing.T) {
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(1), data.UniswapTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		claim := tree.Claims[0]
+		amount, _ := merkle.EncodeUint256(claim.Amount)
+		for name, proof := range map[string]string{"Null": `"proof": null,`, "Missing": ``} {
+			file := fmt.Sprintf(`{"merkleRoot": %q, "claims": {%q: {%s "index": 0, "amount": %q}}}`,
+				tree.GetRootHash(), claim.Address.Hex(), proof, amount)
+			_, _, proofs, err := data.ImportUniswapFormat(strings.NewReader(file))
+			if err != nil {
+				t.Fatalf("%s: failed to import: %v", name, err)
+			}
+			encoded, _ := json.Marshal(proofs[claim.Address.Hex()])
+			assertEmptyProof(t, name, encoded)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		for _, tc := range []struct {
+			count, fixedDepth, depth int
+		}{
+			{1, 0, 0},
+			{2, 0, 1},
+			{3, 0, 2},
+			{1, 4, 4},
+			{3, 4, 4},
+		} {
+			opts := merkle.DefaultTreeOptions()
+			opts.FixedDepth = tc.fixedDepth
+			tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(tc.count), opts)
+			if err != nil {
+				t.Fatalf("Failed to build tree: %v", err)
+			}
+			proofs, _ := tree.GenerateAllProofs()
+			w := httptest.NewRecorder()
+			api.MustNewAPIServer(tree, proofs).SetupRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+			var stats api.StatsResponse
+			if err := json.NewDecoder(w.Body).Decode(&stats); err != nil {
+				t.Fatalf("Failed to decode stats: %v", err)
+			}
+			if stats.TotalClaims != tc.count || stats.TotalProofs != tc.count || stats.ProofDepth != tc.depth {
+				t.Errorf("%d claims, fixed depth %d: expected depth %d, got %+v", tc.count, tc.fixedDepth, tc.depth, stats)
+			}
+		}
+	})
+}
+
+// assertEmptyProof checks that the proof member of body is an empty array
+func assertEmptyProof(t *testing.T, name string, body []byte) {
+	t.Helper()
+	var decoded struct {
+		Proof json.RawMessage `json:"proof"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("%s: failed to decode %s: %v", name, body, err)
+	}
+	if string(decoded.Proof) != "[]" {
+		t.Errorf("%s: expected an empty proof array, got %s", name, decoded.Proof)
+	}
+}
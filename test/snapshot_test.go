@@ -0,0 +1,187 @@
+package test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/e2e"
+	"merkle-airdrop/pkg/merkle"
+	"merkle-airdrop/pkg/snapshot"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// flakyClient fails the first failures log queries
+type flakyClient struct {
+	snapshot.Client
+	failures int
+	calls    int
+}
+
+func (c *flakyClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.calls++
+	if c.failures > 0 {
+		c.failures--
+		return nil, errors.New("rate limited")
+	}
+	return c.Client.FilterLogs(ctx, q)
+}
+
+func TestERC20Snapshot(t *testing.T) {
+	ctx := context.Background()
+
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := types.GenesisAlloc{}
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %v", err)
+		}
+		keys[i] = key
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = types.Account{Balance: new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))}
+	}
+	deployer, alice := crypto.PubkeyToAddress(keys[0].PublicKey), crypto.PubkeyToAddress(keys[1].PublicKey)
+	bob, dust := common.Address{0xb0}, common.Address{0xd0}
+
+	backend := simulated.NewBackend(alloc)
+	defer backend.Close()
+	eth := backend.Client()
+
+	chainID, err := eth.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("Failed to read chain ID: %v", err)
+	}
+	transactor := func(key *ecdsa.PrivateKey) *bind.TransactOpts {
+		auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+		if err != nil {
+			t.Fatalf("Failed to create transactor: %v", err)
+		}
+		return auth
+	}
+
+	tokenAddr, _, token, err := e2e.DeployTestToken(transactor(keys[0]), eth, big.NewInt(1000))
+	if err != nil {
+		t.Fatalf("Failed to deploy token: %v", err)
+	}
+	backend.Commit()
+
+	transfer := func(from *ecdsa.PrivateKey, to common.Address, amount int64) uint64 {
+		t.Helper()
+		if _, err := token.Transfer(transactor(from), to, big.NewInt(amount)); err != nil {
+			t.Fatalf("Failed to transfer: %v", err)
+		}
+		backend.Commit()
+		block, err := eth.BlockNumber(ctx)
+		if err != nil {
+			t.Fatalf("Failed to read block number: %v", err)
+		}
+		return block
+	}
+
+	transfer(keys[0], alice, 300)
+	transfer(keys[0], bob, 200)
+	snapshotBlock := transfer(keys[0], dust, 5)
+	head := transfer(keys[1], bob, 100) // After the snapshot block
+
+	amountsOf := func(claims []merkle.AirdropClaim) map[common.Address]int64 {
+		amounts := make(map[common.Address]int64)
+		for i, claim := range claims {
+			if claim.Index != uint32(i) {
+				t.Errorf("Expected claim %d to have index %d, got %d", i, i, claim.Index)
+			}
+			amounts[claim.Address] = claim.Amount.Int64()
+		}
+		return amounts
+	}
+	expectAmounts := func(t *testing.T, claims []merkle.AirdropClaim, want map[common.Address]int64) {
+		t.Helper()
+		got := amountsOf(claims)
+		if len(got) != len(want) {
+			t.Errorf("Expected %d claims, got %d: %v", len(want), len(got), got)
+		}
+		for address, amount := range want {
+			if got[address] != amount {
+				t.Errorf("Expected %s to claim %d, got %d", address.Hex(), amount, got[address])
+			}
+		}
+	}
+
+	t.Run("HistoricalBalances", func(t *testing.T) {
+		claims, err := snapshot.TakeERC20Snapshot(ctx, eth, tokenAddr, snapshotBlock, big.NewInt(1))
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		expectAmounts(t, claims, map[common.Address]int64{deployer: 495, alice: 300, bob: 200, dust: 5})
+
+		claims, err = snapshot.TakeERC20Snapshot(ctx, eth, tokenAddr, head, big.NewInt(1))
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		expectAmounts(t, claims, map[common.Address]int64{deployer: 495, alice: 200, bob: 300, dust: 5})
+	})
+
+	t.Run("ProRataWithDustFilter", func(t *testing.T) {
+		opts := snapshot.DefaultOptions()
+		opts.BlockRange = 1 // One query per block
+		opts.TotalAllocation = big.NewInt(1000)
+
+		claims, err := snapshot.TakeERC20SnapshotWithOptions(ctx, eth, tokenAddr, snapshotBlock, big.NewInt(10), opts)
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+
+		// 1000 over 995 tokens rounds down to 497, 301 and 201; the
+		// remaining 1 goes to the largest holder
+		expectAmounts(t, claims, map[common.Address]int64{deployer: 498, alice: 301, bob: 201})
+
+		total := new(big.Int)
+		for _, claim := range claims {
+			total.Add(total, claim.Amount)
+		}
+		if total.Cmp(opts.TotalAllocation) != 0 {
+			t.Errorf("Expected claims to total %s, got %s", opts.TotalAllocation, total)
+		}
+	})
+
+	t.Run("Retries", func(t *testing.T) {
+		opts := snapshot.DefaultOptions()
+		opts.RetryDelay = time.Millisecond
+
+		client := &flakyClient{Client: eth, failures: 2}
+		claims, err := snapshot.TakeERC20SnapshotWithOptions(ctx, client, tokenAddr, snapshotBlock, big.NewInt(1), opts)
+		if err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		if len(claims) != 4 || client.calls != 3 {
+			t.Errorf("Expected 4 claims after 3 calls, got %d claims after %d calls", len(claims), client.calls)
+		}
+
+		client = &flakyClient{Client: eth, failures: opts.MaxRetries + 1}
+		if _, err := snapshot.TakeERC20SnapshotWithOptions(ctx, client, tokenAddr, snapshotBlock, big.NewInt(1), opts); err == nil || !strings.Contains(err.Error(), "rate limited") {
+			t.Errorf("Expected the query error after exhausting retries, got %v", err)
+		}
+	})
+
+	t.Run("InvalidRange", func(t *testing.T) {
+		if _, err := snapshot.TakeERC20Snapshot(ctx, eth, tokenAddr, head+10, nil); err == nil {
+			t.Error("Expected an error for a block beyond the chain head")
+		}
+
+		// Starting after the mint leaves senders with negative balances
+		opts := snapshot.DefaultOptions()
+		opts.FromBlock = snapshotBlock
+		if _, err := snapshot.TakeERC20SnapshotWithOptions(ctx, eth, tokenAddr, head, nil, opts); err == nil {
+			t.Error("Expected an error when the scan misses the mint")
+		}
+	})
+}
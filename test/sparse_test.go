@@ -0,0 +1,194 @@
+package test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// naiveSparseRoot hashes every leaf of a small sparse tree
+func naiveSparseRoot(claims []merkle.AirdropClaim, depth int) []byte {
+	level := make([][]byte, 1<<depth)
+	for i := range level {
+		level[i] = make([]byte, 32)
+	}
+	for _, claim := range claims {
+		key := merkle.SparseKey(claim.Address, depth)
+		position := new(big.Int).Rsh(new(big.Int).SetBytes(key[:]), uint(256-depth)).Int64()
+		level[position], _ = merkle.HashLeaf(claim.Address, claim.Amount, claim.Index)
+	}
+	for len(level) > 1 {
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			next[i] = crypto.Keccak256(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+	return level[0]
+}
+
+func TestSparseMerkleTree(t *testing.T) {
+	claims := data.GenerateRandomTestData(1000, 3)
+	tree, err := merkle.NewSparseMerkleTreeFromClaims(claims, merkle.DefaultSparseDepth)
+	if err != nil {
+		t.Fatalf("Failed to build sparse tree: %v", err)
+	}
+	root := tree.RootHash()
+
+	sanctioned := []common.Address{
+		common.HexToAddress("0x8589427373D6D84E98730D7795D8f6f8731FDA16"),
+		common.HexToAddress("0x722122dF12D4e14e13Ac3b6895a86e84145b6967"),
+		common.HexToAddress("0x0000000000000000000000000000000000000000"),
+	}
+
+	t.Run("Inclusion", func(t *testing.T) {
+		for _, i := range []int{0, 1, 500, 999} {
+			proof, err := tree.ProveInclusion(claims[i].Address)
+			if err != nil {
+				t.Fatalf("Failed to prove inclusion: %v", err)
+			}
+			if valid, err := merkle.VerifySparseProof(root, proof); err != nil || !valid {
+				t.Errorf("Expected inclusion proof for claim %d to verify, got %v (%v)", i, valid, err)
+			}
+			if len(proof.Siblings) > 20 {
+				t.Errorf("Expected empty siblings to be compressed away, got %d", len(proof.Siblings))
+			}
+
+			proof.Amount = "1"
+			if valid, _ := merkle.VerifySparseProof(root, proof); valid {
+				t.Error("Expected a proof with a changed amount to fail")
+			}
+		}
+	})
+
+	t.Run("NonInclusion", func(t *testing.T) {
+		for _, address := range sanctioned {
+			proof, err := tree.ProveNonInclusion(address)
+			if err != nil {
+				t.Fatalf("Failed to prove non-inclusion of %s: %v", address.Hex(), err)
+			}
+			if valid, err := merkle.VerifySparseProof(root, proof); err != nil || !valid {
+				t.Errorf("Expected non-inclusion proof for %s to verify, got %v (%v)", address.Hex(), valid, err)
+			}
+
+			// Claiming the empty leaf is an inclusion fails
+			proof.Included, proof.Amount = true, "1"
+			if valid, _ := merkle.VerifySparseProof(root, proof); valid {
+				t.Errorf("Expected a forged inclusion proof for %s to fail", address.Hex())
+			}
+		}
+
+		if _, err := tree.ProveNonInclusion(claims[7].Address); err == nil {
+			t.Error("Expected non-inclusion of a member to fail")
+		}
+		if _, err := tree.ProveInclusion(sanctioned[0]); err == nil {
+			t.Error("Expected inclusion of a non-member to fail")
+		}
+
+		// A member's proof does not show it absent
+		proof, _ := tree.ProveInclusion(claims[7].Address)
+		proof.Included, proof.Amount, proof.Index = false, "", 0
+		if valid, _ := merkle.VerifySparseProof(root, proof); valid {
+			t.Error("Expected a non-inclusion proof for a member to fail")
+		}
+	})
+
+	t.Run("MatchesFullTree", func(t *testing.T) {
+		// Few enough leaves at depth 8 to hash the whole tree
+		var small []merkle.AirdropClaim
+		seen := make(map[[32]byte]bool)
+		for _, claim := range claims {
+			key := merkle.SparseKey(claim.Address, 8)
+			if !seen[key] {
+				seen[key] = true
+				small = append(small, claim)
+			}
+			if len(small) == 40 {
+				break
+			}
+		}
+		sparse, err := merkle.NewSparseMerkleTreeFromClaims(small, 8)
+		if err != nil {
+			t.Fatalf("Failed to build sparse tree: %v", err)
+		}
+		if want := naiveSparseRoot(small, 8); !bytes.Equal(sparse.RootHash(), want) {
+			t.Errorf("Expected root %x, got %x", want, sparse.RootHash())
+		}
+	})
+
+	t.Run("Insert", func(t *testing.T) {
+		incremental, _ := merkle.NewSparseMerkleTree(merkle.DefaultSparseDepth)
+		for _, claim := range claims[:10] {
+			if err := incremental.Insert(claim); err != nil {
+				t.Fatalf("Insert failed: %v", err)
+			}
+		}
+		before := incremental.RootHash()
+		stale, _ := incremental.ProveNonInclusion(claims[10].Address)
+
+		if err := incremental.Insert(claims[10]); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+		if bytes.Equal(before, incremental.RootHash()) {
+			t.Error("Expected the root to change after an insert")
+		}
+		if valid, _ := merkle.VerifySparseProof(incremental.RootHash(), stale); valid {
+			t.Error("Expected a non-inclusion proof to fail after the address was inserted")
+		}
+		if err := incremental.Insert(claims[10]); err == nil {
+			t.Error("Expected a repeated insert to fail")
+		}
+		if _, err := merkle.NewSparseMerkleTree(257); err == nil {
+			t.Error("Expected an error for a depth over 256")
+		}
+	})
+
+	t.Run("FullDepth", func(t *testing.T) {
+		full, err := merkle.NewSparseMerkleTreeFromClaims(claims[:100], merkle.MaxSparseDepth)
+		if err != nil {
+			t.Fatalf("Failed to build sparse tree: %v", err)
+		}
+		proof, err := full.ProveNonInclusion(sanctioned[1])
+		if err != nil {
+			t.Fatalf("Failed to prove non-inclusion: %v", err)
+		}
+		if valid, err := merkle.VerifySparseProof(full.RootHash(), proof); err != nil || !valid {
+			t.Errorf("Expected non-inclusion proof to verify, got %v (%v)", valid, err)
+		}
+		if valid, _ := merkle.VerifySparseProof(root, proof); valid {
+			t.Error("Expected the proof to fail against another tree's root")
+		}
+	})
+}
+
+func TestSparseMerkleTreeLarge(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large sparse tree in short mode")
+	}
+
+	claims := data.GenerateRandomTestData(100000, 5)
+	start := time.Now()
+	tree, err := merkle.NewSparseMerkleTreeFromClaims(claims, merkle.DefaultSparseDepth)
+	if err != nil {
+		t.Fatalf("Failed to build sparse tree: %v", err)
+	}
+	root := tree.RootHash()
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("Expected a 100k-claim sparse tree to build in seconds, took %v", elapsed)
+	}
+
+	proof, err := tree.ProveNonInclusion(common.HexToAddress("0x8589427373D6D84E98730D7795D8f6f8731FDA16"))
+	if err != nil {
+		t.Fatalf("Failed to prove non-inclusion: %v", err)
+	}
+	if valid, err := merkle.VerifySparseProof(root, proof); err != nil || !valid {
+		t.Errorf("Expected non-inclusion proof to verify, got %v (%v)", valid, err)
+	}
+}
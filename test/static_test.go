@@ -0,0 +1,103 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestStaticSite(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, err := tree.GenerateAllProofs()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	parent := t.TempDir()
+	os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("hunter2"), 0644)
+	dir := filepath.Join(parent, "site")
+	os.MkdirAll(filepath.Join(dir, "assets"), 0755)
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<html data-root="{{MERKLE_ROOT}}" data-contract="{{CONTRACT_ADDRESS}}"></html>`), 0644)
+	os.WriteFile(filepath.Join(dir, "assets", "app.js"), []byte("console.log('claim')"), 0644)
+	os.WriteFile(filepath.Join(dir, "assets", "app.css"), []byte("body{}"), 0644)
+	if err := os.Symlink(filepath.Join(parent, "secret.txt"), filepath.Join(dir, "escape.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	const contract = "0x000000000000000000000000000000000000dEaD"
+	handler := api.MustNewAPIServer(tree, proofs, api.WithStaticDir(dir, contract)).SetupRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Index", func(t *testing.T) {
+		want := `<html data-root="` + tree.GetRootHash() + `" data-contract="` + contract + `"></html>`
+		for _, path := range []string{"/", "/index.html", "/claim/0x742d35Cc6634C0532925a3b8D8c6632F2d50a9a6"} {
+			w := get(path)
+			if w.Code != http.StatusOK || w.Body.String() != want {
+				t.Errorf("%s: expected the filled-in index, got %d %q", path, w.Code, w.Body)
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") {
+				t.Errorf("%s: expected text/html, got %q", path, w.Header().Get("Content-Type"))
+			}
+		}
+	})
+
+	t.Run("Assets", func(t *testing.T) {
+		for path, contentType := range map[string]string{
+			"/assets/app.js":  "text/javascript",
+			"/assets/app.css": "text/css",
+		} {
+			w := get(path)
+			if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), contentType) {
+				t.Errorf("%s: expected %s, got %d %q", path, contentType, w.Code, w.Header().Get("Content-Type"))
+			}
+		}
+		if w := get("/assets/missing.js"); w.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for a missing asset, got %d", w.Code)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		if w := get("/api/root"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), tree.GetRootHash()) {
+			t.Errorf("Expected API routes to be unaffected, got %d", w.Code)
+		}
+		// An unknown API route is a JSON 404, not the SPA fallback
+		w := get("/api/unknown")
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"NOT_FOUND"`) {
+			t.Errorf("Expected a JSON 404 for an unknown API route, got %d %q", w.Code, w.Body)
+		}
+	})
+
+	t.Run("Traversal", func(t *testing.T) {
+		for _, path := range []string{"/%2e%2e/secret.txt", "/assets/..%2f..%2fsecret.txt", "/escape.txt"} {
+			if w := get(path); strings.Contains(w.Body.String(), "hunter2") {
+				t.Errorf("%s: served a file outside the static directory", path)
+			}
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		handler := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "/api/docs" {
+			t.Errorf("Expected the root to redirect to the docs without a static directory, got %d %q", w.Code, w.Header().Get("Location"))
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestProofStore(t *testing.T) {
+	tree, proofs := buildProofSet(t, 50)
+
+	// storeContents reads a store back into a map through Iterate and Get
+	storeContents := func(t *testing.T, store merkle.ProofStore) map[string]*merkle.MerkleProof {
+		t.Helper()
+		got := make(map[string]*merkle.MerkleProof)
+		err := store.Iterate(func(address common.Address, proof *merkle.MerkleProof) bool {
+			got[address.Hex()] = proof
+			fetched, exists, err := store.Get(address)
+			if err != nil || !exists || !reflect.DeepEqual(fetched, proof) {
+				t.Errorf("Get(%s) disagrees with Iterate: %v", address.Hex(), err)
+			}
+			return true
+		})
+		if err != nil {
+			t.Fatalf("Failed to iterate: %v", err)
+		}
+		if len(got) != store.Len() {
+			t.Errorf("Iterated %d proofs, Len says %d", len(got), store.Len())
+		}
+		return got
+	}
+
+	t.Run("Map", func(t *testing.T) {
+		store := merkle.MapProofStore{}
+		if err := tree.GenerateAllProofsInto(store); err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		if got := storeContents(t, store); !reflect.DeepEqual(got, proofs.Proofs) {
+			t.Error("Expected the same proofs as GenerateAllProofs")
+		}
+		if _, exists, _ := store.Get(common.HexToAddress("0xdead")); exists {
+			t.Error("Expected no proof for an address outside the airdrop")
+		}
+	})
+
+	t.Run("Disk", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "proofs.store")
+		store, err := merkle.CreateDiskProofStore(path)
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		if err := tree.GenerateAllProofsInto(store); err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		// Records still buffered are readable
+		if proof, exists, err := store.Get(tree.Claims[49].Address); err != nil || !exists || proof.Index != 49 {
+			t.Errorf("Expected the last proof before a flush, got %v %v", proof, err)
+		}
+		if got := storeContents(t, store); !reflect.DeepEqual(got, proofs.Proofs) {
+			t.Error("Expected the same proofs as GenerateAllProofs")
+		}
+
+		// A later record replaces an earlier one, across reopening
+		replaced := *proofs.Proofs[tree.Claims[0].Address.Hex()]
+		replaced.Amount = "12345"
+		if err := store.Put(tree.Claims[0].Address, &replaced); err != nil {
+			t.Fatalf("Failed to put: %v", err)
+		}
+		if err := store.Close(); err != nil {
+			t.Fatalf("Failed to close: %v", err)
+		}
+
+		reopened, err := merkle.OpenDiskProofStore(path)
+		if err != nil {
+			t.Fatalf("Failed to reopen: %v", err)
+		}
+		got := storeContents(t, reopened)
+		if len(got) != 50 || got[tree.Claims[0].Address.Hex()].Amount != "12345" {
+			t.Errorf("Expected 50 proofs with the replacement, got %d", len(got))
+		}
+		reopened.Close()
+	})
+
+	t.Run("DiskPositionsAndPadding", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.SortedPairs = false
+		opts.FixedDepth = 10
+		unsorted, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(13), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		want, _ := unsorted.GenerateAllProofs()
+
+		store, err := merkle.CreateDiskProofStore(filepath.Join(t.TempDir(), "proofs.store"))
+		if err != nil {
+			t.Fatalf("Failed to create store: %v", err)
+		}
+		defer store.Close()
+		if err := unsorted.GenerateAllProofsInto(store); err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		if got := storeContents(t, store); !reflect.DeepEqual(got, want) {
+			t.Error("Expected positions and padding to survive the disk encoding")
+		}
+	})
+
+	t.Run("DiskTruncatedRecord", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "proofs.store")
+		store, _ := merkle.CreateDiskProofStore(path)
+		tree.GenerateAllProofsInto(store)
+		store.Close()
+
+		// A crash mid-append leaves part of a record behind
+		file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		file.Write(common.HexToAddress("0xbeef").Bytes()[:7])
+		file.Close()
+
+		reopened, err := merkle.OpenDiskProofStore(path)
+		if err != nil {
+			t.Fatalf("Expected the incomplete record to be dropped, got %v", err)
+		}
+		defer reopened.Close()
+		if reopened.Len() != 50 {
+			t.Errorf("Expected 50 proofs, got %d", reopened.Len())
+		}
+		// Appends continue after the last complete record
+		if err := reopened.Put(common.HexToAddress("0xbeef"), proofs.Proofs[tree.Claims[1].Address.Hex()]); err != nil {
+			t.Fatalf("Failed to put: %v", err)
+		}
+		if got := storeContents(t, reopened); len(got) != 51 {
+			t.Errorf("Expected 51 proofs, got %d", len(got))
+		}
+	})
+
+	t.Run("DiskNotAStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "claims.csv")
+		os.WriteFile(path, []byte("address,amount\n"), 0o644)
+		if _, err := merkle.OpenDiskProofStore(path); err == nil {
+			t.Error("Expected an error for a file that isn't a proof store")
+		}
+	})
+
+	t.Run("DiskRejectsInvalidProofs", func(t *testing.T) {
+		store, _ := merkle.CreateDiskProofStore(filepath.Join(t.TempDir(), "proofs.store"))
+		defer store.Close()
+		if err := store.Put(common.HexToAddress("0x1"), &merkle.MerkleProof{Amount: "-1"}); err == nil {
+			t.Error("Expected an error for a negative amount")
+		}
+		if err := store.Put(common.HexToAddress("0x1"), &merkle.MerkleProof{Amount: "1", Proof: []string{"0x1234"}}); err == nil {
+			t.Error("Expected an error for a short proof hash")
+		}
+		if store.Len() != 0 {
+			t.Errorf("Expected rejected proofs to be left out, got %d", store.Len())
+		}
+	})
+
+	t.Run("ServerFromDiskStore", func(t *testing.T) {
+		store, _ := merkle.CreateDiskProofStore(filepath.Join(t.TempDir(), "proofs.store"))
+		defer store.Close()
+		tree.GenerateAllProofsInto(store)
+
+		server, err := api.NewAPIServerFromStore(tree.GetRootHash(), store, proofs.Metadata, api.WithSuggestions())
+		if err != nil {
+			t.Fatalf("Failed to create server: %v", err)
+		}
+		handler := server.SetupRoutes()
+
+		address := tree.Claims[7].Address.Hex()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/proof/"+address, nil))
+		var proof api.ProofResponse
+		json.NewDecoder(w.Body).Decode(&proof)
+		if w.Code != http.StatusOK || !reflect.DeepEqual(proof.Proof, proofs.Proofs[address].Proof) {
+			t.Errorf("Expected the stored proof, got %d %s", w.Code, w.Body)
+		}
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+		var stats api.StatsResponse
+		json.NewDecoder(w.Body).Decode(&stats)
+		if stats.TotalClaims != 50 || stats.TotalAmount != merkle.TotalAmount(tree.Claims).String() {
+			t.Errorf("Expected stats over the stored proofs, got %+v", stats)
+		}
+
+		if result, err := server.SelfTest(10, 0); err != nil || !result.Passed {
+			t.Errorf("Expected the self-test to pass, got %v", err)
+		}
+	})
+}
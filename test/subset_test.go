@@ -0,0 +1,113 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestExportProofsSubset(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(20), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	set, err := tree.GenerateProofSet()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+	// Keys in lowercase, as -address-case lower writes them
+	set.Proofs = data.AddressLower.FormatProofs(set.Proofs)
+
+	absent := []common.Address{
+		common.HexToAddress("0x00000000000000000000000000000000deadbeef"),
+		common.HexToAddress("0x000000000000000000000000000000000000f00d"),
+	}
+	present := []common.Address{tree.Claims[2].Address, tree.Claims[9].Address, tree.Claims[17].Address}
+
+	// Requested in every case, with a repeat and a comment
+	list := strings.Join([]string{
+		"# partner addresses",
+		present[0].Hex(),
+		strings.ToLower(absent[0].Hex()),
+		"0x" + strings.ToUpper(present[1].Hex()[2:]),
+		"",
+		absent[1].Hex(),
+		strings.ToLower(present[2].Hex()),
+		present[0].Hex(),
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "partner.txt")
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := data.LoadAddressesFromFile(path)
+	if err != nil || len(addresses) != 5 {
+		t.Fatalf("Expected 5 distinct addresses, got %v (%v)", addresses, err)
+	}
+
+	if missing := data.MissingAddresses(set, addresses); !reflect.DeepEqual(missing, absent) {
+		t.Errorf("Expected %v to be missing, got %v", absent, missing)
+	}
+
+	var out bytes.Buffer
+	if err := data.ExportProofsSubset(set, addresses, tree.GetRootHash(), &out, data.ProofMarshaller{}); err != nil {
+		t.Fatalf("ExportProofsSubset failed: %v", err)
+	}
+
+	// Nobody else's claim is in the file
+	for i, claim := range tree.Claims {
+		if i == 2 || i == 9 || i == 17 {
+			continue
+		}
+		if strings.Contains(strings.ToLower(out.String()), strings.ToLower(claim.Address.Hex()[2:])) {
+			t.Errorf("Expected %s to be left out", claim.Address.Hex())
+		}
+	}
+
+	var file struct {
+		TotalClaims int `json:"totalClaims"`
+		Metadata    struct {
+			MissingAddresses []string `json:"missingAddresses"`
+		} `json:"metadata"`
+	}
+	json.Unmarshal(out.Bytes(), &file)
+	if file.TotalClaims != 3 || !reflect.DeepEqual(file.Metadata.MissingAddresses, []string{absent[0].Hex(), absent[1].Hex()}) {
+		t.Errorf("Expected 3 claims and 2 missing addresses, got %+v", file)
+	}
+
+	// The subset reads back like a full export and its proofs verify
+	root, subset, err := data.LoadProofsJSON(&out)
+	if err != nil {
+		t.Fatalf("Failed to load the subset: %v", err)
+	}
+	if root != tree.GetRootHash() || subset.Len() != 3 || subset.Metadata != tree.Metadata() {
+		t.Fatalf("Expected 3 proofs under %s with the tree's metadata, got %d under %s", tree.GetRootHash(), subset.Len(), root)
+	}
+	for _, i := range []int{2, 9, 17} {
+		claim := tree.Claims[i]
+		proof, ok := subset.Get(claim.Address)
+		if !ok {
+			t.Errorf("Expected a proof for %s", claim.Address.Hex())
+			continue
+		}
+		if valid, err := merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, subset.Metadata.Options()); err != nil || !valid {
+			t.Errorf("Expected the proof for %s to verify, got %v", claim.Address.Hex(), err)
+		}
+	}
+
+	if err := data.ExportProofsSubset(set, addresses, "0x1234", &out, data.ProofMarshaller{}); err == nil {
+		t.Error("Expected an invalid root to be rejected")
+	}
+	os.WriteFile(path, []byte("0x12\n"), 0o644)
+	if _, err := data.LoadAddressesFromFile(path); err == nil {
+		t.Error("Expected an invalid address to be rejected")
+	}
+}
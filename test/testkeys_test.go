@@ -0,0 +1,81 @@
+package test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"merkle-airdrop/pkg/contract"
+	"merkle-airdrop/pkg/data"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestGenerateTestDataWithKeys(t *testing.T) {
+	claims, keys := data.GenerateTestDataWithKeys(20, 1)
+	if len(claims) != 20 || len(keys) != 20 {
+		t.Fatalf("Expected 20 claims and keys, got %d and %d", len(claims), len(keys))
+	}
+
+	t.Run("Deterministic", func(t *testing.T) {
+		again, againKeys := data.GenerateTestDataWithKeys(20, 1)
+		for i, claim := range claims {
+			if again[i].Address != claim.Address || again[i].Amount.Cmp(claim.Amount) != 0 {
+				t.Errorf("Claim %d: expected %s again, got %s", i, claim.Address.Hex(), again[i].Address.Hex())
+			}
+			if !againKeys[claim.Address].Equal(keys[claim.Address]) {
+				t.Errorf("Claim %d: expected the same key again", i)
+			}
+		}
+
+		// Pinned so the derivation can't change without updating fixtures
+		if want := common.HexToAddress("0x7E01808fa32319e4cb19dE7A7aC5de438441C1B1"); claims[0].Address != want {
+			t.Errorf("Expected the first seed 1 address %s, got %s", want.Hex(), claims[0].Address.Hex())
+		}
+
+		other, _ := data.GenerateTestDataWithKeys(20, 2)
+		for i := range other {
+			if other[i].Address == claims[i].Address {
+				t.Errorf("Claim %d: expected another seed to give another address", i)
+			}
+		}
+	})
+
+	t.Run("KeysControlAddresses", func(t *testing.T) {
+		hash := accounts.TextHash([]byte("claim"))
+		for _, claim := range claims {
+			key := keys[claim.Address]
+			if crypto.PubkeyToAddress(key.PublicKey) != claim.Address {
+				t.Fatalf("Expected the key of %s to derive it", claim.Address.Hex())
+			}
+			sig, err := crypto.Sign(hash, key)
+			if err != nil {
+				t.Fatalf("Failed to sign: %v", err)
+			}
+			pub, err := crypto.SigToPub(hash, sig)
+			if err != nil || crypto.PubkeyToAddress(*pub) != claim.Address {
+				t.Errorf("Expected a signature recovering %s (%v)", claim.Address.Hex(), err)
+			}
+			if !data.IsTestKeyAddress(claim.Address) {
+				t.Errorf("Expected %s to be recorded as a test key", claim.Address.Hex())
+			}
+		}
+		if data.IsTestKeyAddress(common.HexToAddress("0x01")) {
+			t.Error("Expected other addresses not to be test keys")
+		}
+	})
+
+	t.Run("RefusedOnMainnet", func(t *testing.T) {
+		key := keys[claims[0].Address]
+		for _, chainID := range []int64{1, 137, 42161} {
+			client := contract.NewContractClientWithBackend(nil, key, big.NewInt(chainID))
+			_, err := client.Claim(common.HexToAddress("0x02"), 0, claims[0].Address, claims[0].Amount, nil)
+			var testKey *contract.TestKeyError
+			if !errors.As(err, &testKey) || testKey.ChainID != uint64(chainID) {
+				t.Errorf("Chain %d: expected a TestKeyError, got %v", chainID, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestTierBreakdown(t *testing.T) {
+	tokens := func(n int64) *big.Int {
+		return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+	}
+	claimsOf := func(amounts ...*big.Int) []merkle.AirdropClaim {
+		claims := make([]merkle.AirdropClaim, len(amounts))
+		for i, amount := range amounts {
+			claims[i] = merkle.AirdropClaim{Amount: amount, Index: uint32(i)}
+		}
+		return claims
+	}
+	type want struct {
+		addresses int
+		total     *big.Int
+	}
+	check := func(t *testing.T, tiers []merkle.Tier, wants ...want) {
+		t.Helper()
+		if len(tiers) != len(wants) {
+			t.Fatalf("Expected %d tiers, got %d", len(wants), len(tiers))
+		}
+		for i, w := range wants {
+			if tiers[i].Addresses != w.addresses || tiers[i].Total.Cmp(w.total) != 0 {
+				t.Errorf("Tier %d: expected %d addresses totalling %s, got %d totalling %s", i, w.addresses, w.total, tiers[i].Addresses, tiers[i].Total)
+			}
+		}
+	}
+
+	boundaries, err := merkle.ParseTierBoundaries("100e18, 1000e18,10000e18")
+	if err != nil {
+		t.Fatalf("Failed to parse boundaries: %v", err)
+	}
+	if !reflect.DeepEqual(boundaries, []*big.Int{tokens(100), tokens(1000), tokens(10000)}) {
+		t.Fatalf("Unexpected boundaries %v", boundaries)
+	}
+
+	// Amounts on a boundary go to the tier above; the 1000-10000 tier is empty
+	claims := claimsOf(tokens(1), new(big.Int).Sub(tokens(100), big.NewInt(1)), tokens(100), tokens(500),
+		new(big.Int).Sub(tokens(1000), big.NewInt(1)), tokens(10000), tokens(50000))
+	tiers, err := merkle.TierBreakdown(claims, boundaries)
+	if err != nil {
+		t.Fatalf("Failed to break down: %v", err)
+	}
+	check(t, tiers,
+		want{2, new(big.Int).Sub(tokens(101), big.NewInt(1))},
+		want{3, new(big.Int).Sub(tokens(1600), big.NewInt(1))},
+		want{0, new(big.Int)},
+		want{2, tokens(60000)},
+	)
+	if tiers[0].Min.Sign() != 0 || tiers[1].Min.Cmp(tokens(100)) != 0 || tiers[1].Max.Cmp(tokens(1000)) != 0 || tiers[3].Max != nil {
+		t.Errorf("Unexpected tier bounds %+v", tiers)
+	}
+
+	t.Run("SingleBoundary", func(t *testing.T) {
+		tiers, err := merkle.TierBreakdown(claimsOf(big.NewInt(9), big.NewInt(10), big.NewInt(11)), []*big.Int{big.NewInt(10)})
+		if err != nil {
+			t.Fatalf("Failed to break down: %v", err)
+		}
+		check(t, tiers, want{1, big.NewInt(9)}, want{2, big.NewInt(21)})
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		tiers, err := merkle.TierBreakdown(nil, boundaries)
+		if err != nil {
+			t.Fatalf("Failed to break down: %v", err)
+		}
+		check(t, tiers, want{0, new(big.Int)}, want{0, new(big.Int)}, want{0, new(big.Int)}, want{0, new(big.Int)})
+
+		// Without boundaries everything is one tier
+		tiers, _ = merkle.TierBreakdown(claims, nil)
+		check(t, tiers, want{len(claims), merkle.TotalAmount(claims)})
+	})
+
+	t.Run("InvalidBoundaries", func(t *testing.T) {
+		for _, list := range []string{"100,100", "100,10", "0,10", "-5", "1.5", "abc", "10,,20"} {
+			if _, err := merkle.ParseTierBoundaries(list); err == nil {
+				t.Errorf("Expected %q to be refused", list)
+			}
+		}
+		if _, err := merkle.TierBreakdown(claims, []*big.Int{big.NewInt(2), big.NewInt(1)}); err == nil {
+			t.Error("Expected decreasing boundaries to be refused")
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		tree, proofs := buildProofSet(t, 20) // k tokens for k = 1..20
+		for name, server := range map[string]*api.APIServer{
+			"Tree":   api.MustNewAPIServer(tree, proofs.Proofs),
+			"Proofs": mustServer(t)(api.NewAPIServerFromProofs(tree.GetRootHash(), proofs)),
+		} {
+			handler := server.SetupRoutes()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats?tiers=5e18,15e18", nil))
+			var stats api.StatsResponse
+			json.NewDecoder(w.Body).Decode(&stats)
+			want := []api.TierStats{
+				{Min: "0", Max: tokens(5).String(), Addresses: 4, Total: tokens(10).String()},
+				{Min: tokens(5).String(), Max: tokens(15).String(), Addresses: 10, Total: tokens(95).String()},
+				{Min: tokens(15).String(), Addresses: 6, Total: tokens(105).String()},
+			}
+			if w.Code != http.StatusOK || !reflect.DeepEqual(stats.Tiers, want) {
+				t.Errorf("%s: expected tiers %+v, got %d %+v", name, want, w.Code, stats.Tiers)
+			}
+
+			bad := httptest.NewRecorder()
+			handler.ServeHTTP(bad, httptest.NewRequest(http.MethodGet, "/api/stats?tiers=10,5", nil))
+			var failed api.ErrorResponse
+			json.NewDecoder(bad.Body).Decode(&failed)
+			if bad.Code != http.StatusBadRequest || failed.Error.Code != api.CodeInvalidParameter {
+				t.Errorf("%s: expected 400 %s for bad tiers, got %d %+v", name, api.CodeInvalidParameter, bad.Code, failed)
+			}
+		}
+	})
+}
@@ -0,0 +1,371 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTreeClaimsMutation(t *testing.T) {
+	t.Run("CopiedClaimsUnaffected", func(t *testing.T) {
+		claims := data.GenerateTestData(16)
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+
+		target := tree.Claims[3].Address
+		wantAmount := tree.Claims[3].Amount.String()
+		fingerprint := tree.Fingerprint()
+
+		// Reuse the source buffer the way the ingestion code did
+		for i := range claims {
+			claims[i].Amount.SetInt64(1)
+			claims[i].Address = claims[0].Address
+		}
+
+		proof, err := tree.GenerateProof(target)
+		if err != nil {
+			t.Fatalf("Failed to generate proof after mutation: %v", err)
+		}
+		if proof.Amount != wantAmount {
+			t.Errorf("Expected amount %s, got %s", wantAmount, proof.Amount)
+		}
+
+		if _, err := tree.GenerateAllProofs(); err != nil {
+			t.Errorf("Failed to generate all proofs after mutation: %v", err)
+		}
+
+		if !bytes.Equal(fingerprint, tree.Fingerprint()) {
+			t.Error("Expected fingerprint to be unchanged")
+		}
+	})
+
+	t.Run("SharedClaimsAmountChanged", func(t *testing.T) {
+		claims := data.GenerateTestData(16)
+		tree, err := merkle.NewMerkleTree(claims)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+
+		target := claims[5].Address
+		claims[5].Amount.Add(claims[5].Amount, big.NewInt(1))
+
+		if _, err := tree.GenerateProof(target); err == nil {
+			t.Error("Expected error generating proof for mutated claim")
+		}
+		if _, err := tree.GenerateAllProofs(); err == nil {
+			t.Error("Expected error generating all proofs after mutation")
+		}
+	})
+
+	t.Run("SharedClaimsAddressChanged", func(t *testing.T) {
+		claims := data.GenerateTestData(16)
+		tree, err := merkle.NewMerkleTree(claims)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+
+		target := claims[7].Address
+		claims[7].Address = claims[8].Address
+
+		_, err = tree.GenerateProof(target)
+		if err == nil {
+			t.Fatal("Expected error generating proof for overwritten address")
+		}
+		if errors.Is(err, merkle.ErrAddressNotFound) {
+			t.Errorf("Expected mutation error, got %v", err)
+		}
+	})
+
+	t.Run("SharedClaimsUntouched", func(t *testing.T) {
+		claims := data.GenerateTestData(16)
+		tree, err := merkle.NewMerkleTree(claims)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+
+		if _, err := tree.GenerateAllProofs(); err != nil {
+			t.Errorf("Failed to generate proofs: %v", err)
+		}
+		if len(tree.Fingerprint()) != 32 {
+			t.Errorf("Expected 32-byte fingerprint, got %d bytes", len(tree.Fingerprint()))
+		}
+	})
+}
+
+// encodingFixture is a tiny claim set whose roots were computed with ethers.js
+// (solidityPackedKeccak256 leaves, sorted-pair keccak256 nodes)
+func encodingFixture() []merkle.AirdropClaim {
+	return []merkle.AirdropClaim{
+		{Address: common.HexToAddress("0x1111111111111111111111111111111111111111"), Amount: big.NewInt(100)},
+		{Address: common.HexToAddress("0x2222222222222222222222222222222222222222"), Amount: big.NewInt(200)},
+		{Address: common.HexToAddress("0x3333333333333333333333333333333333333333"), Amount: big.NewInt(300)},
+	}
+}
+
+func TestLeafEncodingIncludeIndex(t *testing.T) {
+	tests := []struct {
+		name         string
+		includeIndex bool
+		expectedRoot string
+	}{
+		// leaf = keccak256(abi.encodePacked(uint256(uint160(account)), amount, uint32(index)))
+		{"WithIndex", true, "0x57eb43ba4d0a26ced311b9438bb92adfd0efe16e633e419ba355aa19fa7e8d1a"},
+		// leaf = keccak256(abi.encodePacked(account, amount))
+		{"WithoutIndex", false, "0x322f4b68238759844c200efcc27a8ecac22b877e40c119e4fddae9c389767288"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := merkle.DefaultTreeOptions()
+			opts.IncludeIndex = tt.includeIndex
+
+			tree, err := merkle.NewMerkleTreeWithOptions(encodingFixture(), opts)
+			if err != nil {
+				t.Fatalf("Failed to build tree: %v", err)
+			}
+			if tree.GetRootHash() != tt.expectedRoot {
+				t.Errorf("Expected root %s, got %s", tt.expectedRoot, tree.GetRootHash())
+			}
+			if tree.Metadata().IncludeIndex != tt.includeIndex {
+				t.Errorf("Expected metadata includeIndex %v", tt.includeIndex)
+			}
+
+			other := opts
+			other.IncludeIndex = !tt.includeIndex
+
+			for _, claim := range tree.Claims {
+				proof, err := tree.GenerateProof(claim.Address)
+				if err != nil {
+					t.Fatalf("Failed to generate proof: %v", err)
+				}
+
+				valid, err := merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, opts)
+				if err != nil || !valid {
+					t.Errorf("Expected proof for %s to verify (err: %v)", claim.Address.Hex(), err)
+				}
+
+				valid, _ = merkle.VerifyProof(tree.Root.Hash, claim, proof.Proof, other)
+				if valid {
+					t.Errorf("Expected proof for %s to fail with the other encoding", claim.Address.Hex())
+				}
+			}
+		})
+	}
+
+	t.Run("OptimizedHashMatches", func(t *testing.T) {
+		for _, includeIndex := range []bool{true, false} {
+			opts := merkle.DefaultTreeOptions()
+			opts.IncludeIndex = includeIndex
+			for i, claim := range encodingFixture() {
+				want, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, uint32(i), opts)
+				got, _ := merkle.OptimizedHashLeafWithOptions(claim.Address, claim.Amount, uint32(i), opts)
+				if !bytes.Equal(want, got) {
+					t.Errorf("Optimized hash mismatch (includeIndex %v, claim %d)", includeIndex, i)
+				}
+			}
+		}
+	})
+}
+
+// referenceProof rebuilds every level from the leaves, the way proofs were
+// generated before levels were kept on the tree
+func referenceProof(t *testing.T, tree *merkle.MerkleTree, index int) []string {
+	t.Helper()
+	var proof []string
+	level := make([][]byte, len(tree.Leaves))
+	for i, leaf := range tree.Leaves {
+		level[i] = leaf.Hash
+	}
+
+	for len(level) > 1 {
+		sibling := index ^ 1
+		if sibling >= len(level) {
+			sibling = index
+		}
+		proof = append(proof, fmt.Sprintf("0x%x", level[sibling]))
+
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			parent, err := merkle.HashInternal(level[i], right)
+			if err != nil {
+				t.Fatalf("Failed to hash reference level: %v", err)
+			}
+			next = append(next, parent)
+		}
+		level = next
+		index /= 2
+	}
+	return proof
+}
+
+func TestProofGeneration(t *testing.T) {
+	t.Run("MatchesReference", func(t *testing.T) {
+		for size := 1; size <= 40; size++ {
+			tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(size), merkle.DefaultTreeOptions())
+			if err != nil {
+				t.Fatalf("Failed to build tree: %v", err)
+			}
+			proofs, err := tree.GenerateAllProofs()
+			if err != nil {
+				t.Fatalf("Failed to generate proofs: %v", err)
+			}
+
+			for i, claim := range tree.Claims {
+				want := referenceProof(t, tree, i)
+				got := proofs[claim.Address.Hex()].Proof
+				if !reflect.DeepEqual(want, got) && !(len(want) == 0 && len(got) == 0) {
+					t.Errorf("Size %d, leaf %d: expected %v, got %v", size, i, want, got)
+				}
+			}
+		}
+	})
+
+	t.Run("Allocations", func(t *testing.T) {
+		tree, err := merkle.NewMerkleTree(data.GenerateTestData(1000))
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		address := tree.Claims[500].Address
+
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := tree.GenerateProof(address); err != nil {
+				t.Fatal(err)
+			}
+		})
+		// Rebuilding the levels per proof cost thousands of allocations
+		if allocs > 10 {
+			t.Errorf("Expected at most 10 allocations per proof, got %.0f", allocs)
+		}
+	})
+}
+
+// orderFixture is listed out of address order with positional indices; its
+// root was computed with ethers.js from the leaves in this order
+func orderFixture() []merkle.AirdropClaim {
+	return []merkle.AirdropClaim{
+		{Address: common.HexToAddress("0x3333333333333333333333333333333333333333"), Amount: big.NewInt(300), Index: 0},
+		{Address: common.HexToAddress("0x1111111111111111111111111111111111111111"), Amount: big.NewInt(100), Index: 1},
+		{Address: common.HexToAddress("0x4444444444444444444444444444444444444444"), Amount: big.NewInt(400), Index: 2},
+		{Address: common.HexToAddress("0x2222222222222222222222222222222222222222"), Amount: big.NewInt(200), Index: 3},
+		{Address: common.HexToAddress("0x5555555555555555555555555555555555555555"), Amount: big.NewInt(500), Index: 4},
+	}
+}
+
+func TestLeafSortOrder(t *testing.T) {
+	const preservedRoot = "0x21a0dcd9bb89e666b8d74229a0e509f325c81f42c9b1872ac597028043dda51b"
+
+	build := func(t *testing.T, claims []merkle.AirdropClaim, order merkle.SortOrder) *merkle.MerkleTree {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		opts.SortOrder = order
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		return tree
+	}
+
+	// swapFirst exchanges the first two claims and re-numbers by position
+	swapFirst := func(claims []merkle.AirdropClaim) []merkle.AirdropClaim {
+		claims[0], claims[1] = claims[1], claims[0]
+		for i := range claims {
+			claims[i].Index = uint32(i)
+		}
+		return claims
+	}
+
+	t.Run("PreserveInputMatchesExpectedRoot", func(t *testing.T) {
+		tree := build(t, orderFixture(), merkle.PreserveInput)
+		if tree.GetRootHash() != preservedRoot {
+			t.Errorf("Expected root %s, got %s", preservedRoot, tree.GetRootHash())
+		}
+
+		proofs, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		if proofs.Metadata.SortOrder != merkle.PreserveInput {
+			t.Errorf("Expected metadata sort order input, got %s", proofs.Metadata.SortOrder)
+		}
+
+		for i, want := range orderFixture() {
+			proof, _ := proofs.Get(want.Address)
+			if proof.Index != uint32(i) {
+				t.Errorf("Expected %s at index %d, got %d", want.Address.Hex(), i, proof.Index)
+			}
+			valid, err := merkle.VerifyProof(tree.Root.Hash, want, proof.Proof, tree.Options())
+			if err != nil || !valid {
+				t.Errorf("Expected proof for %s to verify (err: %v)", want.Address.Hex(), err)
+			}
+		}
+	})
+
+	t.Run("ReorderingChangesRoot", func(t *testing.T) {
+		preserved := build(t, swapFirst(orderFixture()), merkle.PreserveInput)
+		if preserved.GetRootHash() == preservedRoot {
+			t.Error("Expected reordered input to change the preserved-order root")
+		}
+
+		sorted := build(t, orderFixture(), merkle.SortByAddress)
+		resorted := build(t, swapFirst(orderFixture()), merkle.SortByAddress)
+		if sorted.GetRootHash() != resorted.GetRootHash() {
+			t.Error("Expected reordered input to keep the address-sorted root")
+		}
+	})
+
+	t.Run("SortByIndex", func(t *testing.T) {
+		claims := orderFixture()
+		claims[0], claims[4] = claims[4], claims[0]
+		claims[1], claims[3] = claims[3], claims[1]
+
+		tree := build(t, claims, merkle.SortByIndex)
+		if tree.GetRootHash() != preservedRoot {
+			t.Errorf("Expected root %s, got %s", preservedRoot, tree.GetRootHash())
+		}
+	})
+
+	t.Run("DuplicateIndex", func(t *testing.T) {
+		claims := orderFixture()
+		claims[2].Index = 0
+
+		opts := merkle.DefaultTreeOptions()
+		opts.SortOrder = merkle.PreserveInput
+		if _, err := merkle.NewMerkleTreeWithOptions(claims, opts); err == nil {
+			t.Error("Expected error for duplicate claim index")
+		}
+	})
+
+	t.Run("MetadataRoundTrip", func(t *testing.T) {
+		tree := build(t, orderFixture(), merkle.PreserveInput)
+		proofs, err := tree.GenerateProofSet()
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := data.ExportProofsBinary(proofs, tree.Root.Hash, &buf); err != nil {
+			t.Fatalf("Failed to export proofs: %v", err)
+		}
+		loaded, _, err := data.LoadProofsBinary(&buf)
+		if err != nil {
+			t.Fatalf("Failed to load proofs: %v", err)
+		}
+		if loaded.Metadata.SortOrder != merkle.PreserveInput {
+			t.Errorf("Expected loaded sort order input, got %s", loaded.Metadata.SortOrder)
+		}
+	})
+}
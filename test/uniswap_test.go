@@ -0,0 +1,221 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// uniswap_claims.json was written the way Uniswap's merkle-distributor
+// generator writes it: five accounts, hex amounts, leaves sorted by hash and
+// the odd node carried up, which gives index 3 a one-element proof
+const uniswapFixture = "uniswap_claims.json"
+
+func TestImportUniswapFormat(t *testing.T) {
+	fixture, err := os.ReadFile(uniswapFixture)
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	t.Run("Golden", func(t *testing.T) {
+		root, claims, proofs, err := data.ImportUniswapFormat(bytes.NewReader(fixture))
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if root != "0x250b8441613d89a52c6170d4298a03ce5840b9f9b5369652883c2c14e0f434ea" {
+			t.Errorf("Unexpected root %s", root)
+		}
+		if len(claims) != 5 || len(proofs) != 5 {
+			t.Fatalf("Expected 5 claims and proofs, got %d and %d", len(claims), len(proofs))
+		}
+		for i, claim := range claims {
+			if claim.Index != uint32(i) {
+				t.Errorf("Expected claims in index order, got index %d at %d", claim.Index, i)
+			}
+		}
+
+		want := map[string]string{
+			"0x00000000219ab540356cBB839Cbe05303d7705Fa": "250",
+			"0x742d35Cc6634c0532925a3b8D8C6632F2d50a9a6": "1000000000000000000",
+			"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2": "3",
+		}
+		for address, amount := range want {
+			if proof := proofs[address]; proof == nil || proof.Amount != amount {
+				t.Errorf("Expected %s to claim %s, got %+v", address, amount, proof)
+			}
+		}
+		if len(proofs["0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2"].Proof) != 1 {
+			t.Error("Expected the carried-up leaf to keep its short proof")
+		}
+	})
+
+	// rewrite decodes the fixture, lets fn change it and encodes it again
+	rewrite := func(t *testing.T, fn func(file map[string]interface{})) []byte {
+		t.Helper()
+		var file map[string]interface{}
+		if err := json.Unmarshal(fixture, &file); err != nil {
+			t.Fatal(err)
+		}
+		fn(file)
+		content, _ := json.Marshal(file)
+		return content
+	}
+
+	t.Run("WrongRoot", func(t *testing.T) {
+		content := rewrite(t, func(file map[string]interface{}) {
+			file["merkleRoot"] = "0x" + strings.Repeat("11", 32)
+		})
+		_, _, _, err := data.ImportUniswapFormat(bytes.NewReader(content))
+		if err == nil || !strings.Contains(err.Error(), "5 of 5 proofs do not verify") {
+			t.Fatalf("Expected every proof to fail, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "0x00000000219ab540356cBB839Cbe05303d7705Fa") {
+			t.Errorf("Expected the failing addresses to be named, got %v", err)
+		}
+	})
+
+	t.Run("OtherEncoding", func(t *testing.T) {
+		// A valid proof under another leaf encoding must not pass
+		claims := pairsFixture()
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		entries := map[string]interface{}{}
+		for _, claim := range tree.Claims {
+			proof, _ := tree.GenerateProof(claim.Address)
+			amount, _ := merkle.EncodeUint256(claim.Amount)
+			entries[claim.Address.Hex()] = map[string]interface{}{
+				"index":  claim.Index,
+				"amount": amount,
+				"proof":  proof.Proof,
+			}
+		}
+		content, _ := json.Marshal(map[string]interface{}{"merkleRoot": tree.GetRootHash(), "claims": entries})
+
+		_, _, _, err = data.ImportUniswapFormat(bytes.NewReader(content))
+		if err == nil || !strings.Contains(err.Error(), "4 of 4 proofs do not verify") {
+			t.Errorf("Expected the native encoding to be rejected, got %v", err)
+		}
+	})
+
+	t.Run("UppercaseRoot", func(t *testing.T) {
+		// The root is returned as the proofs record it
+		lower := "0x250b8441613d89a52c6170d4298a03ce5840b9f9b5369652883c2c14e0f434ea"
+		content := rewrite(t, func(file map[string]interface{}) {
+			file["merkleRoot"] = "0x" + strings.ToUpper(lower[2:])
+		})
+		root, _, proofs, err := data.ImportUniswapFormat(bytes.NewReader(content))
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		if root != lower {
+			t.Errorf("Expected the root in lowercase, got %s", root)
+		}
+		for address, proof := range proofs {
+			if proof.Root != root {
+				t.Errorf("Expected %s's proof to record %s, got %s", address, root, proof.Root)
+			}
+		}
+	})
+
+	t.Run("DeterministicErrors", func(t *testing.T) {
+		content := rewrite(t, func(file map[string]interface{}) {
+			claims := file["claims"].(map[string]interface{})
+			for address, entry := range claims {
+				claims[strings.ToLower(address)] = entry
+			}
+			claims["0xnot-an-address"] = map[string]interface{}{"index": 99, "amount": "0x01"}
+			claims["0xalso-not-one"] = map[string]interface{}{"index": 98, "amount": "0x01"}
+		})
+		_, _, _, first := data.ImportUniswapFormat(bytes.NewReader(content))
+		if first == nil {
+			t.Fatal("Expected the import to fail")
+		}
+		for i := 0; i < 20; i++ {
+			if _, _, _, err := data.ImportUniswapFormat(bytes.NewReader(content)); err == nil || err.Error() != first.Error() {
+				t.Fatalf("Expected the same error on every run, got %v and %v", first, err)
+			}
+		}
+
+		duplicates := rewrite(t, func(file map[string]interface{}) {
+			claims := file["claims"].(map[string]interface{})
+			for address, entry := range claims {
+				claims[strings.ToLower(address)] = entry
+			}
+		})
+		_, _, _, err := data.ImportUniswapFormat(bytes.NewReader(duplicates))
+		var duplicate *data.DuplicateAddressError
+		if !errors.As(err, &duplicate) {
+			t.Fatalf("Expected a DuplicateAddressError, got %v", err)
+		}
+		for i := 0; i < 20; i++ {
+			_, _, _, again := data.ImportUniswapFormat(bytes.NewReader(duplicates))
+			var repeated *data.DuplicateAddressError
+			if !errors.As(again, &repeated) || *repeated != *duplicate {
+				t.Fatalf("Expected the same duplicate on every run, got %+v and %v", duplicate, again)
+			}
+		}
+	})
+
+	t.Run("TokenTotal", func(t *testing.T) {
+		content := rewrite(t, func(file map[string]interface{}) {
+			file["tokenTotal"] = "0x01"
+		})
+		if _, _, _, err := data.ImportUniswapFormat(bytes.NewReader(content)); err == nil || !strings.Contains(err.Error(), "tokenTotal") {
+			t.Errorf("Expected a tokenTotal mismatch, got %v", err)
+		}
+	})
+
+	t.Run("Serve", func(t *testing.T) {
+		root, _, imported, err := data.ImportUniswapFormat(bytes.NewReader(fixture))
+		if err != nil {
+			t.Fatalf("Import failed: %v", err)
+		}
+		proofs := &merkle.ProofSet{Proofs: imported, Metadata: data.UniswapTreeOptions().Metadata()}
+		handler := mustServer(t)(api.NewAPIServerFromProofs(root, proofs)).SetupRoutes()
+
+		address := "0x1f9840a85d5aF5bf1D1762F925BDADdC4201F984"
+		for amount, want := range map[string]bool{imported[address].Amount: true, "1": false} {
+			body, _ := json.Marshal(map[string]interface{}{
+				"address": address,
+				"amount":  amount,
+				"proof":   imported[address].Proof,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			var response struct {
+				Valid bool `json:"valid"`
+			}
+			json.NewDecoder(w.Body).Decode(&response)
+			if w.Code != http.StatusOK || response.Valid != want {
+				t.Errorf("Amount %s: expected valid=%v, got %d %+v", amount, want, w.Code, response)
+			}
+		}
+	})
+
+	t.Run("OptimizedLeaf", func(t *testing.T) {
+		opts := data.UniswapTreeOptions()
+		claim := pairsFixture()[2]
+		claim.Index = 7
+		claim.Amount = new(big.Int).Lsh(big.NewInt(1), 200)
+		want, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+		got, _ := merkle.OptimizedHashLeafWithOptions(claim.Address, claim.Amount, claim.Index, opts)
+		if !bytes.Equal(want, got) {
+			t.Error("Expected the pooled index-first leaf to match")
+		}
+	})
+}
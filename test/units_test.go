@@ -0,0 +1,115 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestAmountUnits(t *testing.T) {
+	amount := func(s string) *big.Int {
+		n, _ := new(big.Int).SetString(s, 10)
+		return n
+	}
+
+	t.Run("Format", func(t *testing.T) {
+		maxAmount := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+		cases := []struct {
+			amount   *big.Int
+			unit     data.AmountUnit
+			decimals int // Token decimals
+			want     string
+		}{
+			{big.NewInt(1), data.UnitWei, 18, "1"},
+			{big.NewInt(1), data.UnitGwei, 18, "0.000000001"},
+			{big.NewInt(1), data.UnitEther, 18, "0.000000000000000001"},
+			{amount("999999999999999999"), data.UnitEther, 18, "0.999999999999999999"},
+			{amount("1000000000000000000"), data.UnitEther, 18, "1"},
+			{amount("1000000000000000000"), data.UnitGwei, 18, "1000000000"},
+			{amount("1500000000000000000"), data.UnitToken, 18, "1.5"},
+			{big.NewInt(0), data.UnitEther, 18, "0"},
+			{maxAmount, data.UnitEther, 18, "115792089237316195423570985008687907853269984665640564039457.584007913129639935"},
+
+			// A 6-decimal token, such as USDC
+			{big.NewInt(1), data.UnitToken, 6, "0.000001"},
+			{big.NewInt(999999), data.UnitToken, 6, "0.999999"},
+			{big.NewInt(1000000), data.UnitToken, 6, "1"},
+			{big.NewInt(1234500000), data.UnitToken, 6, "1234.5"},
+			{big.NewInt(1234500000), data.UnitEther, 6, "0.0000000012345"},
+			{big.NewInt(42), data.UnitToken, 0, "42"},
+		}
+		for _, tc := range cases {
+			if got := tc.unit.Format(tc.amount, tc.decimals); got != tc.want {
+				t.Errorf("%s in %s (%d decimals): expected %s, got %s", tc.amount, tc.unit, tc.decimals, tc.want, got)
+			}
+		}
+		if got := data.FormatUnits(big.NewInt(-15), 1); got != "-1.5" {
+			t.Errorf("Expected -1.5, got %s", got)
+		}
+	})
+
+	t.Run("Parse", func(t *testing.T) {
+		for _, u := range []data.AmountUnit{data.UnitWei, data.UnitGwei, data.UnitEther, data.UnitToken} {
+			if parsed, err := data.ParseAmountUnit(u.String()); err != nil || parsed != u {
+				t.Errorf("Expected %s to round-trip, got %v (%v)", u, parsed, err)
+			}
+		}
+		for _, name := range []string{"Ether", "eth", "finney"} {
+			if _, err := data.ParseAmountUnit(name); err == nil {
+				t.Errorf("Expected unit %q to be rejected", name)
+			}
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		claims := []merkle.AirdropClaim{
+			{Address: common.HexToAddress("0x0000000000000000000000000000000000000001"), Amount: big.NewInt(1)},
+			{Address: common.HexToAddress("0x0000000000000000000000000000000000000002"), Amount: big.NewInt(2500000)},
+		}
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		proofs, _ := tree.GenerateAllProofs()
+		handler := api.MustNewAPIServer(tree, proofs, api.WithTokenDecimals(6)).SetupRoutes()
+
+		get := func(path string) (int, map[string]interface{}) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+			var body map[string]interface{}
+			json.NewDecoder(rec.Body).Decode(&body)
+			return rec.Code, body
+		}
+
+		for _, tc := range []struct {
+			path    string
+			amount  string
+			display interface{}
+		}{
+			{"/api/proof/0x0000000000000000000000000000000000000002", "2500000", nil},
+			{"/api/proof/0x0000000000000000000000000000000000000002?unit=token", "2500000", "2.5"},
+			{"/api/proof/0x0000000000000000000000000000000000000002?unit=gwei", "2500000", "0.0025"},
+			{"/api/proof/0x0000000000000000000000000000000000000001?unit=ether", "1", "0.000000000000000001"},
+			{"/api/proof/0x0000000000000000000000000000000000000001?unit=wei", "1", "1"},
+		} {
+			status, body := get(tc.path)
+			if status != http.StatusOK || body["amount"] != tc.amount || body["amountDisplay"] != tc.display {
+				t.Errorf("%s: expected amount %s displayed as %v, got %d %v", tc.path, tc.amount, tc.display, status, body)
+			}
+		}
+
+		status, body := get("/api/proof/0x0000000000000000000000000000000000000001?unit=satoshi")
+		envelope, _ := body["error"].(map[string]interface{})
+		if status != http.StatusBadRequest || envelope["code"] != api.CodeInvalidParameter {
+			t.Errorf("Expected 400 %s for an unknown unit, got %d %v", api.CodeInvalidParameter, status, body)
+		}
+	})
+}
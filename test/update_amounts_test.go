@@ -0,0 +1,336 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/rebuild"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// sameProofButRoot reports whether two proofs differ at most in their roots
+func sameProofButRoot(a, b *merkle.MerkleProof) bool {
+	x, y := *a, *b
+	x.Root, y.Root = "", ""
+	return reflect.DeepEqual(x, y)
+}
+
+func TestUpdateAmounts(t *testing.T) {
+	layouts := map[string]func(*merkle.TreeOptions){
+		"Default":    func(*merkle.TreeOptions) {},
+		"Positions":  func(o *merkle.TreeOptions) { o.SortedPairs = false },
+		"Promote":    func(o *merkle.TreeOptions) { o.OddLeafPolicy = merkle.Promote; o.SortedPairs = false },
+		"ZeroPad":    func(o *merkle.TreeOptions) { o.OddLeafPolicy = merkle.ZeroPad },
+		"FixedDepth": func(o *merkle.TreeOptions) { o.FixedDepth = 12 },
+		"PreserveInput": func(o *merkle.TreeOptions) {
+			o.SortOrder = merkle.PreserveInput
+			o.IncludeIndex = true
+			o.IndexFirst = true
+		},
+		"Serial": func(o *merkle.TreeOptions) { o.Workers = 1 },
+	}
+	build := func(t *testing.T, claims []merkle.AirdropClaim, layout func(*merkle.TreeOptions)) *merkle.MerkleTree {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		layout(&opts)
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		return tree
+	}
+
+	t.Run("MatchesRebuild", func(t *testing.T) {
+		for name, layout := range layouts {
+			for _, count := range []int{1, 2, 7, 33, 100} {
+				for _, every := range []int{1, 3, 50} {
+					label := fmt.Sprintf("%s, %d claims, every %d", name, count, every)
+					claims := data.GenerateTestData(count)
+					tree := build(t, claims, layout)
+					before, _ := tree.GenerateAllProofs()
+
+					changes := map[common.Address]*big.Int{}
+					updated := data.CloneClaims(tree.Claims)
+					for i := range updated {
+						if i%every == 0 {
+							updated[i].Amount = new(big.Int).Add(updated[i].Amount, big.NewInt(int64(i+1)))
+							changes[updated[i].Address] = updated[i].Amount
+						}
+					}
+					changed, err := tree.UpdateAmounts(changes)
+					if err != nil {
+						t.Fatalf("%s: %v", label, err)
+					}
+
+					rebuilt := build(t, updated, layout)
+					if tree.GetRootHash() != rebuilt.GetRootHash() {
+						t.Fatalf("%s: expected root %s, got %s", label, rebuilt.GetRootHash(), tree.GetRootHash())
+					}
+					for level := 0; level < rebuilt.Levels(); level++ {
+						if !reflect.DeepEqual(tree.Level(level), rebuilt.Level(level)) {
+							t.Fatalf("%s: level %d differs from a rebuild", label, level)
+						}
+					}
+					after, err := tree.GenerateAllProofs()
+					if err != nil {
+						t.Fatalf("%s: updated tree fails its integrity check: %v", label, err)
+					}
+					want, _ := rebuilt.GenerateAllProofs()
+					if !reflect.DeepEqual(after, want) {
+						t.Fatalf("%s: proofs differ from a rebuild", label)
+					}
+
+					// Exactly the proofs that changed are returned, as the rebuild has them
+					for address, proof := range want {
+						differs := !sameProofButRoot(before[address], proof)
+						got, ok := changed[address]
+						switch {
+						case differs && !ok:
+							t.Errorf("%s: %s's proof changed but wasn't returned", label, address)
+						case !differs && ok:
+							t.Errorf("%s: %s's proof didn't change but was returned", label, address)
+						case ok && !reflect.DeepEqual(got, proof):
+							t.Errorf("%s: %s's returned proof differs from a rebuild", label, address)
+						}
+					}
+				}
+			}
+		}
+	})
+
+	t.Run("Clone", func(t *testing.T) {
+		for name, layout := range layouts {
+			for _, count := range []int{1, 2, 7, 33} {
+				label := fmt.Sprintf("%s, %d claims", name, count)
+				tree := build(t, data.GenerateTestData(count), layout)
+				root := tree.GetRootHash()
+				before, _ := tree.GenerateAllProofs()
+
+				clone := tree.Clone()
+				if clone.GetRootHash() != root {
+					t.Fatalf("%s: expected the clone's root %s, got %s", label, root, clone.GetRootHash())
+				}
+				updated := data.CloneClaims(tree.Claims)
+				changes := map[common.Address]*big.Int{}
+				for i := range updated {
+					updated[i].Amount = new(big.Int).Add(updated[i].Amount, big.NewInt(1))
+					changes[updated[i].Address] = updated[i].Amount
+				}
+				if _, err := clone.UpdateAmounts(changes); err != nil {
+					t.Fatalf("%s: %v", label, err)
+				}
+
+				// The clone matches a rebuild and the tree is as it was
+				rebuilt := build(t, updated, layout)
+				if clone.GetRootHash() != rebuilt.GetRootHash() {
+					t.Fatalf("%s: expected the updated clone's root %s, got %s", label, rebuilt.GetRootHash(), clone.GetRootHash())
+				}
+				got, err := clone.GenerateAllProofs()
+				want, _ := rebuilt.GenerateAllProofs()
+				if err != nil || !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: updated clone's proofs differ from a rebuild (%v)", label, err)
+				}
+				after, err := tree.GenerateAllProofs()
+				if err != nil || tree.GetRootHash() != root || !reflect.DeepEqual(after, before) {
+					t.Fatalf("%s: expected updating the clone to leave the tree unchanged (%v)", label, err)
+				}
+			}
+		}
+	})
+
+	t.Run("ServedClone", func(t *testing.T) {
+		// The served tree is read throughout while its clone is updated
+		// and swapped in by the rebuild scheduler
+		claims := data.GenerateTestData(500)
+		served, err := merkle.NewMerkleTree(data.CloneClaims(claims))
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		updated := data.CloneClaims(claims)
+		changes := map[common.Address]*big.Int{}
+		for i := 0; i < len(updated); i += 7 {
+			updated[i].Amount = new(big.Int).Add(updated[i].Amount, big.NewInt(1))
+			changes[updated[i].Address] = updated[i].Amount
+		}
+
+		var sources sync.Mutex
+		current := claims
+		source := func(context.Context) ([]merkle.AirdropClaim, error) {
+			sources.Lock()
+			defer sources.Unlock()
+			return current, nil
+		}
+		// The first build serves the tree; the next serves its updated clone
+		var tree *merkle.MerkleTree
+		build := func([]merkle.AirdropClaim) (http.Handler, error) {
+			next := served
+			if tree != nil {
+				next = tree.Clone()
+				if _, err := next.UpdateAmounts(changes); err != nil {
+					return nil, err
+				}
+			}
+			proofs, err := next.GenerateAllProofs()
+			if err != nil {
+				return nil, err
+			}
+			tree = next
+			return api.MustNewAPIServer(next, proofs).SetupRoutes(), nil
+		}
+		clock := &fakeClock{ticks: make(chan time.Time)}
+		scheduler := rebuild.New(source, build, time.Minute, rebuild.WithClock(clock))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if err := scheduler.Start(ctx); err != nil {
+			t.Fatalf("Failed to start: %v", err)
+		}
+
+		root := served.GetRootHash()
+		rootBytes, _ := merkle.DecodeHash(root)
+		stop := make(chan struct{})
+		var readers sync.WaitGroup
+		for r := 0; r < 4; r++ {
+			readers.Add(1)
+			go func(r int) {
+				defer readers.Done()
+				for i := r; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					claim := claims[i%len(claims)]
+					proof, err := served.GenerateProof(claim.Address)
+					if err != nil {
+						t.Errorf("Failed to prove %s: %v", claim.Address.Hex(), err)
+						return
+					}
+					if ok, err := merkle.VerifyProof(rootBytes, claim, proof.Proof, served.Options()); !ok || err != nil || proof.Root != root {
+						t.Errorf("Expected %s's proof to verify under the served root (%v)", claim.Address.Hex(), err)
+						return
+					}
+					if i%100 == r {
+						served.GenerateAllProofsTo(func(string, *merkle.MerkleProof) error { return nil })
+					}
+				}
+			}(r)
+		}
+
+		sources.Lock()
+		current = updated
+		sources.Unlock()
+		at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock.set(at)
+		clock.ticks <- at
+		var status api.RebuildStatus
+		for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+			var ok bool
+			if status, ok = scheduler.RebuildStatus(); ok && status.LastRebuild.Equal(at) {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("Timed out waiting for the update")
+			}
+		}
+		close(stop)
+		readers.Wait()
+
+		if status.Outcome != rebuild.OutcomeRebuilt {
+			t.Fatalf("Expected the updated clone to be served, got %+v", status)
+		}
+		rebuilt, _ := merkle.NewMerkleTree(updated)
+		if got := tree.GetRootHash(); got != rebuilt.GetRootHash() || served.GetRootHash() != root {
+			t.Errorf("Expected the clone to serve the rebuilt root %s and the tree to keep %s, got %s and %s", rebuilt.GetRootHash(), root, got, served.GetRootHash())
+		}
+	})
+
+	t.Run("CallerClaims", func(t *testing.T) {
+		// A tree sharing the caller's claims updates them, with its own copies
+		claims := data.GenerateTestData(20)
+		tree, err := merkle.NewMerkleTree(claims)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		amount := big.NewInt(12345)
+		if _, err := tree.UpdateAmounts(map[common.Address]*big.Int{claims[4].Address: amount}); err != nil {
+			t.Fatal(err)
+		}
+		amount.SetInt64(1)
+		if claims[4].Amount.Int64() != 12345 {
+			t.Errorf("Expected the caller's claim to hold the new amount, got %s", claims[4].Amount)
+		}
+		if _, err := tree.GenerateProof(claims[4].Address); err != nil {
+			t.Errorf("Expected the updated claim to prove: %v", err)
+		}
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		tree := build(t, data.GenerateTestData(10), layouts["Default"])
+		root := tree.GetRootHash()
+		for name, changes := range map[string]map[common.Address]*big.Int{
+			"None":       nil,
+			"SameAmount": {tree.Claims[3].Address: new(big.Int).Set(tree.Claims[3].Amount)},
+		} {
+			changed, err := tree.UpdateAmounts(changes)
+			if err != nil || len(changed) != 0 || tree.GetRootHash() != root {
+				t.Errorf("%s: expected no changes, got %d proofs, root %s (%v)", name, len(changed), tree.GetRootHash(), err)
+			}
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.MaxAmountBits = 96
+		tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(10), opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree: %v", err)
+		}
+		root := tree.GetRootHash()
+		known := tree.Claims[2].Address
+		original := new(big.Int).Set(tree.Claims[2].Amount)
+		for _, tc := range []struct {
+			name    string
+			address common.Address
+			amount  *big.Int
+			want    error
+		}{
+			{"Unknown", common.HexToAddress("0x00000000000000000000000000000000000000ff"), big.NewInt(1), merkle.ErrAddressNotFound},
+			{"Zero", tree.Claims[5].Address, big.NewInt(0), &merkle.InvalidAmountError{}},
+			{"Negative", tree.Claims[5].Address, big.NewInt(-1), &merkle.InvalidAmountError{}},
+			{"Missing", tree.Claims[5].Address, nil, &merkle.InvalidAmountError{}},
+			{"TooWide", tree.Claims[5].Address, new(big.Int).Lsh(big.NewInt(1), 100), nil},
+		} {
+			// A valid change alongside is not applied either
+			_, err := tree.UpdateAmounts(map[common.Address]*big.Int{known: big.NewInt(777), tc.address: tc.amount})
+			var amountErr *merkle.InvalidAmountError
+			switch want := tc.want.(type) {
+			case nil:
+				if err == nil {
+					t.Errorf("%s: expected an error", tc.name)
+				}
+			case *merkle.InvalidAmountError:
+				if !errors.As(err, &amountErr) {
+					t.Errorf("%s: expected an InvalidAmountError, got %v", tc.name, err)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("%s: expected %v, got %v", tc.name, want, err)
+				}
+			}
+			if tree.GetRootHash() != root || tree.Claims[2].Amount.Cmp(original) != 0 {
+				t.Errorf("%s: expected the tree to be unchanged", tc.name)
+			}
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"slices"
+	"testing"
+
+	"merkle-airdrop/pkg/merkle"
+	"merkle-airdrop/pkg/merkle/testvectors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// vectorsFile is the checked-in vectors. Regenerate it only for a
+// deliberate change to hashing: go run ./cmd/cli vectors -out test/vectors.json
+const vectorsFile = "vectors.json"
+
+func TestVectors(t *testing.T) {
+	golden, err := os.ReadFile(vectorsFile)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", vectorsFile, err)
+	}
+	var file testvectors.File
+	if err := json.Unmarshal(golden, &file); err != nil {
+		t.Fatalf("Failed to parse %s: %v", vectorsFile, err)
+	}
+
+	t.Run("Golden", func(t *testing.T) {
+		var generated bytes.Buffer
+		if err := testvectors.Write(&generated); err != nil {
+			t.Fatalf("Failed to generate vectors: %v", err)
+		}
+		if !bytes.Equal(generated.Bytes(), golden) {
+			t.Fatalf("Generated vectors differ from %s; if hashing changed on purpose, regenerate it with: go run ./cmd/cli vectors -out test/vectors.json", vectorsFile)
+		}
+	})
+
+	t.Run("Leaves", func(t *testing.T) {
+		if len(file.Leaves) == 0 {
+			t.Fatal("Expected leaf vectors")
+		}
+		for _, leaf := range file.Leaves {
+			amount, _ := new(big.Int).SetString(leaf.Amount, 10)
+			claim := merkle.AirdropClaim{Address: common.HexToAddress(leaf.Address), Amount: amount, Index: leaf.Index}
+			for _, encoding := range file.Encodings {
+				want := leaf.Hashes[encoding.Name]
+				hash, err := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, encoding.Metadata.Options())
+				if err != nil || "0x"+hex.EncodeToString(hash) != want {
+					t.Errorf("%s %s: expected leaf %s, got %x (%v)", encoding.Name, leaf.Address, want, hash, err)
+				}
+
+				// The documented preimage, built without pkg/merkle
+				preimage := testvectors.Preimage(encoding.Name, claim)
+				if "0x"+hex.EncodeToString(preimage) != leaf.Preimages[encoding.Name] {
+					t.Errorf("%s %s: expected preimage %s, got %x", encoding.Name, leaf.Address, leaf.Preimages[encoding.Name], preimage)
+				}
+				if "0x"+hex.EncodeToString(crypto.Keccak256(preimage)) != want {
+					t.Errorf("%s %s: expected the preimage to hash to %s", encoding.Name, leaf.Address, want)
+				}
+			}
+		}
+	})
+
+	t.Run("Trees", func(t *testing.T) {
+		if len(file.Trees) == 0 {
+			t.Fatal("Expected tree vectors")
+		}
+		for _, vector := range file.Trees {
+			claims := make([]merkle.AirdropClaim, len(vector.Claims))
+			for i, c := range vector.Claims {
+				amount, _ := new(big.Int).SetString(c.Amount, 10)
+				claims[i] = merkle.AirdropClaim{Address: common.HexToAddress(c.Address), Amount: amount, Index: c.Index}
+			}
+			opts := vector.Metadata.Options()
+			tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+			if err != nil {
+				t.Fatalf("%s: failed to build tree: %v", vector.Name, err)
+			}
+			if tree.GetRootHash() != vector.Root {
+				t.Errorf("%s: expected root %s, got %s", vector.Name, vector.Root, tree.GetRootHash())
+				continue
+			}
+			root, _ := hex.DecodeString(vector.Root[2:])
+
+			for i, c := range vector.Claims {
+				if leaf := "0x" + hex.EncodeToString(tree.Leaves[i].Hash); leaf != c.Leaf {
+					t.Errorf("%s: expected leaf %d to be %s, got %s", vector.Name, i, c.Leaf, leaf)
+				}
+				proof, err := tree.GenerateProof(claims[i].Address)
+				if err != nil {
+					t.Fatalf("%s: failed to generate proof: %v", vector.Name, err)
+				}
+				if !slices.Equal(proof.Proof, c.Proof) || proof.Positions != c.Positions {
+					t.Errorf("%s: expected proof %v (positions %d) for %s, got %v (%d)", vector.Name, c.Proof, c.Positions, c.Address, proof.Proof, proof.Positions)
+				}
+				if valid, err := merkle.VerifyProofWithPositions(root, claims[i], c.Proof, c.Positions, opts); err != nil || !valid {
+					t.Errorf("%s: expected the proof for %s to verify, got %v", vector.Name, c.Address, err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	tree, proofs := buildProofSet(t, 50)
+
+	// entries writes n lines cycling through valid and invalid entries,
+	// returning the expected validity of each non-blank line by number
+	entries := func(n int, withIndex bool) (*bytes.Buffer, map[int]bool) {
+		var buf bytes.Buffer
+		expected := make(map[int]bool)
+		for line := 1; line <= n; line++ {
+			claim := tree.Claims[line%len(tree.Claims)]
+			entry := map[string]interface{}{
+				"address": claim.Address.Hex(),
+				"amount":  claim.Amount.String(),
+				"proof":   proofs.Proofs[claim.Address.Hex()].Proof,
+			}
+			if withIndex {
+				entry["index"] = claim.Index
+			}
+			valid := true
+			switch line % 7 {
+			case 1:
+				entry["amount"] = "1"
+				valid = false
+			case 3:
+				buf.WriteString("{not json\n")
+				expected[line] = false
+				continue
+			case 5:
+				buf.WriteString("\n")
+				continue
+			}
+			encoded, _ := json.Marshal(entry)
+			buf.Write(append(encoded, '\n'))
+			expected[line] = valid
+		}
+		return &buf, expected
+	}
+	// check reads result lines, requiring them in input order
+	check := func(t *testing.T, results []data.BatchResult, expected map[int]bool) {
+		t.Helper()
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+		}
+		previous := 0
+		for _, result := range results {
+			if result.Line <= previous {
+				t.Fatalf("Expected results in input order, got line %d after %d", result.Line, previous)
+			}
+			previous = result.Line
+			if valid, ok := expected[result.Line]; !ok || valid != result.Valid {
+				t.Errorf("Line %d: expected valid=%v, got %+v", result.Line, valid, result)
+			}
+			if !result.Valid && result.Reason == "" {
+				t.Errorf("Line %d: expected a reason", result.Line)
+			}
+		}
+	}
+	decode := func(t *testing.T, r *bytes.Buffer) []data.BatchResult {
+		t.Helper()
+		var results []data.BatchResult
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var result data.BatchResult
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatalf("Failed to decode %q: %v", scanner.Text(), err)
+			}
+			results = append(results, result)
+		}
+		return results
+	}
+
+	t.Run("Offline", func(t *testing.T) {
+		input, expected := entries(700, true)
+		var out bytes.Buffer
+		summary, err := data.VerifyBatch(input, &out, tree.Root.Hash, tree.Options(), data.BatchOptions{Workers: 4})
+		if err != nil {
+			t.Fatalf("VerifyBatch failed: %v", err)
+		}
+		check(t, decode(t, &out), expected)
+		if summary.Entries != len(expected) || summary.Valid+summary.Invalid != summary.Entries {
+			t.Errorf("Unexpected summary %+v", summary)
+		}
+
+		// Without an index or a lookup to find it, entries are invalid
+		input, _ = entries(2, false)
+		out.Reset()
+		data.VerifyBatch(input, &out, tree.Root.Hash, tree.Options(), data.BatchOptions{})
+		if results := decode(t, &out); len(results) != 2 || results[1].Reason != "missing index" {
+			t.Errorf("Expected a missing index to be reported, got %+v", results)
+		}
+
+		input, _ = entries(20, true)
+		out.Reset()
+		if _, err := data.VerifyBatch(input, &out, tree.Root.Hash, tree.Options(), data.BatchOptions{MaxEntries: 5}); err == nil {
+			t.Error("Expected too many entries to be an error")
+		}
+		if results := decode(t, &out); len(results) != 5 {
+			t.Errorf("Expected the first 5 results, got %d", len(results))
+		}
+	})
+
+	server := httptest.NewServer(api.MustNewAPIServer(tree, proofs.Proofs, api.WithWorkers(3)).SetupRoutes())
+	defer server.Close()
+	post := func(t *testing.T, body *bytes.Buffer, contentType string) (*http.Response, *bytes.Buffer) {
+		t.Helper()
+		resp, err := http.Post(server.URL+"/api/verify/batch", contentType, body)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var out bytes.Buffer
+		out.ReadFrom(resp.Body)
+		return resp, &out
+	}
+
+	t.Run("API", func(t *testing.T) {
+		// The server fills in the recorded indices
+		input, expected := entries(300, false)
+		resp, out := post(t, input, "application/x-ndjson")
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Fatalf("Expected an NDJSON stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		check(t, decode(t, out), expected)
+
+		if resp, _ := post(t, bytes.NewBufferString("{}"), "application/json"); resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected 415 for a JSON body, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("TooManyEntries", func(t *testing.T) {
+		claim := tree.Claims[0]
+		line := fmt.Sprintf(`{"address":%q,"amount":%q,"proof":[%s]}`+"\n", claim.Address.Hex(), claim.Amount, `"`+strings.Join(proofs.Proofs[claim.Address.Hex()].Proof, `","`)+`"`)
+		input := bytes.NewBufferString(strings.Repeat(line, api.MaxBatchEntries+1))
+		_, out := post(t, input, "application/x-ndjson")
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != api.MaxBatchEntries+1 {
+			t.Fatalf("Expected %d results and an error line, got %d lines", api.MaxBatchEntries, len(lines))
+		}
+		var last api.ErrorResponse
+		json.Unmarshal([]byte(lines[len(lines)-1]), &last)
+		if last.Error.Code != api.CodePayloadTooLarge {
+			t.Errorf("Expected a PAYLOAD_TOO_LARGE last line, got %s", lines[len(lines)-1])
+		}
+	})
+}
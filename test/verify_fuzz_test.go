@@ -0,0 +1,106 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestHashInternalLengths(t *testing.T) {
+	hash := make([]byte, 32)
+	for _, n := range []int{0, 1, 31, 33, 64} {
+		if _, err := merkle.HashInternal(hash, make([]byte, n)); err == nil {
+			t.Errorf("Expected an error for a %d-byte right hash", n)
+		}
+		if _, err := merkle.HashInternal(make([]byte, n), hash); err == nil {
+			t.Errorf("Expected an error for a %d-byte left hash", n)
+		}
+	}
+	if _, err := merkle.HashInternal(hash, hash); err != nil {
+		t.Errorf("Expected 32-byte hashes to be accepted, got %v", err)
+	}
+}
+
+// postVerify sends a verify request for the given proof elements and checks
+// the server answered 200 or 400 with a JSON body, rather than panicking
+func postVerify(t *testing.T, handler http.Handler, address, amount string, proof []string) int {
+	t.Helper()
+	body, _ := json.Marshal(map[string]interface{}{"address": address, "amount": amount, "proof": proof})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK && w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 200 or 400, got %d: %s", w.Code, w.Body)
+	}
+	if !json.Valid(w.Body.Bytes()) {
+		t.Fatalf("Expected a JSON body, got %q", w.Body)
+	}
+	return w.Code
+}
+
+func TestVerifyRandomProofs(t *testing.T) {
+	tree, proofs := buildProofSet(t, 32)
+	handler := api.MustNewAPIServer(tree, proofs.Proofs).SetupRoutes()
+	claim := tree.Claims[5]
+	rng := rand.New(rand.NewSource(7))
+
+	t.Run("ShortElement", func(t *testing.T) {
+		proof := append([]string(nil), proofs.Proofs[claim.Address.Hex()].Proof...)
+		proof[1] = proof[1][:len(proof[1])-2] // 31 bytes
+		if code := postVerify(t, handler, claim.Address.Hex(), claim.Amount.String(), proof); code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for a 31-byte element, got %d", code)
+		}
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		for i := 0; i < 500; i++ {
+			proof := make([]string, rng.Intn(40))
+			for j := range proof {
+				element := make([]byte, rng.Intn(65))
+				rng.Read(element)
+				proof[j] = "0x" + hex.EncodeToString(element)
+			}
+			postVerify(t, handler, claim.Address.Hex(), claim.Amount.String(), proof)
+		}
+	})
+}
+
+func FuzzVerifyProofBytes(f *testing.F) {
+	tree, _ := buildProofSet(f, 8)
+	claim := tree.Claims[3]
+	proof, _ := tree.GenerateProof(claim.Address)
+
+	seed, _ := hex.DecodeString(proof.Proof[0][2:])
+	f.Add(seed, uint8(32))
+	f.Add(seed[:31], uint8(31))
+	f.Add([]byte{}, uint8(0))
+	f.Add(bytes.Repeat([]byte{0xff}, 100), uint8(33))
+
+	f.Fuzz(func(t *testing.T, raw []byte, size uint8) {
+		// Split the input into elements of the fuzzed size
+		var elements [][]byte
+		if size == 0 {
+			elements = [][]byte{raw}
+		} else {
+			for len(raw) > 0 {
+				n := min(int(size), len(raw))
+				elements = append(elements, raw[:n])
+				raw = raw[n:]
+			}
+		}
+
+		valid, err := merkle.VerifyProofBytes(tree.Root.Hash, claim, elements, tree.Options())
+		if valid && err != nil {
+			t.Errorf("Expected no error with a valid result, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/merkle"
+)
+
+func TestVerifyOnlyServer(t *testing.T) {
+	// The proofs come from a full tree the server never sees
+	tree, proofs := buildProofSet(t, 20)
+	handler := mustServer(t)(api.NewVerifyOnlyServer(tree.Root.Hash, tree.Options(), api.WithSuggestions(), api.WithBloomFilter(0.01))).SetupRoutes()
+
+	request := func(method, path string, payload interface{}) (int, map[string]interface{}) {
+		t.Helper()
+		var body bytes.Buffer
+		if payload != nil {
+			json.NewEncoder(&body).Encode(payload)
+		}
+		req := httptest.NewRequest(method, path, &body)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var response map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&response)
+		return w.Code, response
+	}
+
+	status, root := request(http.MethodGet, "/api/root", nil)
+	if status != http.StatusOK || root["merkleRoot"] != tree.GetRootHash() {
+		t.Fatalf("Expected the root, got %d %v", status, root)
+	}
+
+	claim := tree.Claims[7]
+	proof := proofs.Proofs[claim.Address.Hex()]
+	payload := map[string]interface{}{
+		"address": claim.Address.Hex(),
+		"amount":  claim.Amount.String(),
+		"index":   claim.Index,
+		"proof":   proof.Proof,
+	}
+	if status, response := request(http.MethodPost, "/api/verify", payload); status != http.StatusOK || response["valid"] != true {
+		t.Errorf("Expected the proof to verify, got %d %v", status, response)
+	}
+	payload["amount"] = "1"
+	if status, response := request(http.MethodPost, "/api/verify", payload); status != http.StatusOK || response["valid"] != false {
+		t.Errorf("Expected a wrong amount to fail verification, got %d %v", status, response)
+	}
+
+	// The index can't be looked up without claims
+	delete(payload, "index")
+	if status, _ := request(http.MethodPost, "/api/verify", payload); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without an index, got %d", status)
+	}
+
+	for _, path := range []string{"/api/proof/" + claim.Address.Hex(), "/api/eligible/" + claim.Address.Hex(), "/api/stats", "/api/progress", "/api/bloom"} {
+		status, response := request(http.MethodGet, path, nil)
+		if status != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, status)
+			continue
+		}
+		if path != "/api/bloom" {
+			if apiErr, _ := response["error"].(map[string]interface{}); apiErr["code"] != api.CodeNoClaimData {
+				t.Errorf("%s: expected %s, got %v", path, api.CodeNoClaimData, response)
+			}
+		}
+	}
+
+	// Positions are required for proofs that need them
+	opts := merkle.DefaultTreeOptions()
+	opts.SortedPairs = false
+	positional := mustServer(t)(api.NewVerifyOnlyServer(tree.Root.Hash, opts)).SetupRoutes()
+	body, _ := json.Marshal(map[string]interface{}{"address": claim.Address.Hex(), "amount": claim.Amount.String(), "index": 7, "proof": proof.Proof})
+	req := httptest.NewRequest(http.MethodPost, "/api/verify", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	positional.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without positions, got %d", w.Code)
+	}
+}
@@ -0,0 +1,106 @@
+package test
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCheckClaim(t *testing.T) {
+	claims := data.GenerateTestData(20)
+	for name, opts := range map[string]merkle.TreeOptions{
+		"Sorted":     merkle.DefaultTreeOptions(),
+		"Positional": {CopyClaims: true, IncludeIndex: true, OddLeafPolicy: merkle.Promote},
+	} {
+		t.Run(name, func(t *testing.T) {
+			tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+			if err != nil {
+				t.Fatalf("Failed to build tree: %v", err)
+			}
+			set, err := tree.GenerateProofSet()
+			if err != nil {
+				t.Fatalf("Failed to generate proofs: %v", err)
+			}
+			root := tree.GetRootHash()
+			claim := claims[7]
+
+			check := func(address common.Address, amount *big.Int) *data.ClaimCheck {
+				t.Helper()
+				result, err := data.CheckClaim(root, set, address, amount)
+				if err != nil {
+					t.Fatalf("Failed to check claim: %v", err)
+				}
+				return result
+			}
+
+			for _, amount := range []*big.Int{nil, claim.Amount} {
+				if result := check(claim.Address, amount); result.Status != data.ClaimValid || result.Amount != claim.Amount.String() {
+					t.Errorf("Expected a valid claim of %s, got %s of %s: %s", claim.Amount, result.Status, result.Amount, result.Reason)
+				}
+			}
+
+			more := new(big.Int).Add(claim.Amount, big.NewInt(1))
+			result := check(claim.Address, more)
+			if result.Status != data.ClaimInvalid || !result.AmountMismatch || result.TreeAmount != claim.Amount.String() {
+				t.Errorf("Expected an amount mismatch against %s, got %+v", claim.Amount, result)
+			}
+			if !strings.Contains(result.Reason, claim.Amount.String()) {
+				t.Errorf("Expected the reason to give the tree amount, got %q", result.Reason)
+			}
+
+			if result := check(common.HexToAddress("0x00000000000000000000000000000000DeaDBeef"), nil); result.Status != data.ClaimNotFound {
+				t.Errorf("Expected an unknown address to be not found, got %s", result.Status)
+			}
+
+			if _, err := data.CheckClaim("0x1234", set, claim.Address, nil); err == nil {
+				t.Error("Expected an invalid root to be an error")
+			}
+		})
+	}
+}
+
+func TestCheckClaimTamperedProof(t *testing.T) {
+	claims := data.GenerateTestData(8)
+	tree, err := merkle.NewMerkleTree(data.CloneClaims(claims))
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	set, err := tree.GenerateProofSet()
+	if err != nil {
+		t.Fatalf("Failed to generate proofs: %v", err)
+	}
+
+	claim := claims[2]
+	proof := set.Proofs[claim.Address.Hex()]
+	proof.Proof[0] = proof.Proof[1]
+	// A lowercase key is found for the checksummed address
+	delete(set.Proofs, claim.Address.Hex())
+	set.Proofs[strings.ToLower(claim.Address.Hex())] = proof
+
+	result, err := data.CheckClaim(tree.GetRootHash(), set, claim.Address, nil)
+	if err != nil {
+		t.Fatalf("Failed to check claim: %v", err)
+	}
+	if result.Status != data.ClaimInvalid || result.AmountMismatch {
+		t.Errorf("Expected a tampered proof to be invalid without an amount mismatch, got %+v", result)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to encode check: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"status":"invalid"`) {
+		t.Errorf("Expected the status to encode by name, got %s", encoded)
+	}
+	for status, code := range map[data.ClaimStatus]int{data.ClaimValid: 0, data.ClaimInvalid: 1, data.ClaimNotFound: 2} {
+		if int(status) != code {
+			t.Errorf("Expected %s to be exit code %d, got %d", status, code, int(status))
+		}
+	}
+}
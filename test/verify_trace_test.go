@@ -0,0 +1,161 @@
+package test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestVerificationTrace(t *testing.T) {
+	tree, proofs := buildProofSet(t, 10)
+	claim := tree.Claims[3]
+	proof := proofs.Proofs[claim.Address.Hex()]
+	ref := &merkle.TraceReference{Proof: proof}
+
+	// A tree in which another claim changed, so claim's proof is stale
+	claims := data.GenerateTestData(10)
+	claims[0].Amount = new(big.Int).Add(claims[0].Amount, big.NewInt(1))
+	staleTree, err := merkle.NewMerkleTreeWithOptions(claims, merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	stale, err := staleTree.GenerateProof(claim.Address)
+	if err != nil || stale.Root == "" {
+		t.Fatalf("Expected a proof recording its root, got %+v (%v)", stale, err)
+	}
+
+	wrongAmount := claim
+	wrongAmount.Amount = new(big.Int).Add(claim.Amount, big.NewInt(1))
+	outsider := claim
+	outsider.Address = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+	// withElement returns proof with element i replaced
+	withElement := func(i int, element string) *merkle.MerkleProof {
+		changed := *proof
+		changed.Proof = append([]string(nil), proof.Proof...)
+		changed.Proof[i] = element
+		return &changed
+	}
+	sibling, _ := hex.DecodeString(strings.TrimPrefix(proof.Proof[1], "0x"))
+	sibling[7] ^= 0x01
+	flipped := withElement(1, "0x"+hex.EncodeToString(sibling))
+	malformed := withElement(2, "0x"+strings.Repeat("zz", 32))
+
+	t.Run("Valid", func(t *testing.T) {
+		trace, err := merkle.TraceMerkleProof(tree.Root.Hash, claim, proof, tree.Options(), ref)
+		if err != nil || !trace.Valid || trace.Failure != "" {
+			t.Fatalf("Expected a valid trace, got %+v (%v)", trace, err)
+		}
+		leaf, _ := merkle.HashLeafWithOptions(claim.Address, claim.Amount, claim.Index, tree.Options())
+		if trace.Leaf != "0x"+hex.EncodeToString(leaf) || len(trace.Steps) != len(proof.Proof) {
+			t.Errorf("Expected the leaf and a step per element, got %+v", trace)
+		}
+		last := trace.Steps[len(trace.Steps)-1].Hash
+		if last != trace.ComputedRoot || trace.ComputedRoot != tree.GetRootHash() || trace.ExpectedRoot != tree.GetRootHash() {
+			t.Errorf("Expected the last step to reach the root, got %s, %s and %s", last, trace.ComputedRoot, trace.ExpectedRoot)
+		}
+	})
+
+	t.Run("Classification", func(t *testing.T) {
+		for _, tc := range []struct {
+			name  string
+			claim merkle.AirdropClaim
+			proof *merkle.MerkleProof
+			ref   *merkle.TraceReference
+			want  merkle.VerifyFailure
+			step  int // -1 for none
+		}{
+			{"WrongAmount", wrongAmount, proof, ref, merkle.LeafMismatch, -1},
+			{"NotInTree", outsider, proof, &merkle.TraceReference{}, merkle.LeafMismatch, -1},
+			{"FlippedByte", claim, flipped, ref, merkle.PathCorrupt, 1},
+			{"FlippedByteWithoutTree", claim, flipped, nil, merkle.RootMismatch, -1},
+			{"Malformed", claim, malformed, nil, merkle.PathCorrupt, 2},
+			{"StaleRoot", claim, stale, ref, merkle.RootMismatch, -1},
+		} {
+			trace, err := merkle.TraceMerkleProof(tree.Root.Hash, tc.claim, tc.proof, tree.Options(), tc.ref)
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+				continue
+			}
+			if trace.Valid || trace.Failure != tc.want || trace.Reason == "" {
+				t.Errorf("%s: expected %s, got %+v", tc.name, tc.want, trace)
+			}
+			switch {
+			case tc.step < 0 && trace.FailedStep != nil:
+				t.Errorf("%s: expected no failed step, got %d", tc.name, *trace.FailedStep)
+			case tc.step >= 0 && (trace.FailedStep == nil || *trace.FailedStep != tc.step):
+				t.Errorf("%s: expected step %d to fail, got %v", tc.name, tc.step, trace.FailedStep)
+			}
+		}
+	})
+
+	t.Run("ProofsFile", func(t *testing.T) {
+		trace, err := data.TraceClaim(tree.GetRootHash(), proofs, claim.Address, wrongAmount.Amount)
+		if err != nil || trace == nil || trace.Failure != merkle.LeafMismatch {
+			t.Errorf("Expected a LEAF_MISMATCH trace, got %+v (%v)", trace, err)
+		}
+		if trace, err := data.TraceClaim(tree.GetRootHash(), proofs, tree.Claims[0].Address, nil); err != nil || !trace.Valid {
+			t.Errorf("Expected the file's own amount to verify, got %+v (%v)", trace, err)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		served, _ := tree.GenerateAllProofs()
+		handler := api.MustNewAPIServer(tree, served, api.WithTraceLimit(4)).SetupRoutes()
+		verify := func(path string, req api.VerifyRequest) (*httptest.ResponseRecorder, api.VerifyResponse) {
+			body, _ := json.Marshal(req)
+			r := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			var response api.VerifyResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			return w, response
+		}
+		request := func(claim merkle.AirdropClaim, proof *merkle.MerkleProof) api.VerifyRequest {
+			return api.VerifyRequest{Address: claim.Address.Hex(), Amount: claim.Amount.String(), Proof: proof.Proof, Root: proof.Root}
+		}
+
+		for _, tc := range []struct {
+			name string
+			req  api.VerifyRequest
+			want merkle.VerifyFailure
+		}{
+			{"WrongAmount", request(wrongAmount, proof), merkle.LeafMismatch},
+			{"FlippedByte", request(claim, flipped), merkle.PathCorrupt},
+			{"StaleRoot", request(claim, stale), merkle.RootMismatch},
+		} {
+			w, response := verify("/api/verify?trace=true", tc.req)
+			if w.Code != http.StatusOK || response.Valid || response.Trace == nil || response.Trace.Failure != tc.want {
+				t.Errorf("%s: expected a %s trace, got %d %s", tc.name, tc.want, w.Code, w.Body)
+			}
+		}
+
+		// Untraced, a stale proof is still a 409 and nothing is explained
+		if w, response := verify("/api/verify", request(claim, stale)); w.Code != http.StatusConflict || response.Trace != nil {
+			t.Errorf("Expected 409 without a trace, got %d %s", w.Code, w.Body)
+		}
+
+		w, _ := verify("/api/verify?trace=true", request(claim, proof))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected the fourth trace to be answered, got %d %s", w.Code, w.Body)
+		}
+		w, _ = verify("/api/verify?trace=true", request(claim, proof))
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected the fifth trace to be limited, got %d %s", w.Code, w.Body)
+		}
+		if w, response := verify("/api/verify", request(claim, proof)); w.Code != http.StatusOK || !response.Valid {
+			t.Errorf("Expected untraced checks to go on, got %d %s", w.Code, w.Body)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"merkle-airdrop/internal/api"
+	"merkle-airdrop/internal/config"
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// stoppedClock is an api.Clock that only moves when set
+type stoppedClock struct {
+	now time.Time
+}
+
+func (c *stoppedClock) Now() time.Time {
+	return c.now
+}
+
+func TestClaimWindow(t *testing.T) {
+	tree, err := merkle.NewMerkleTreeWithOptions(data.GenerateTestData(5), merkle.DefaultTreeOptions())
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+	proofs, _ := tree.GenerateAllProofs()
+	window := &api.ClaimWindow{Start: "2026-06-01T12:00:00Z", End: "2026-07-01T00:00:00Z"}
+	start, _ := time.Parse(time.RFC3339, window.Start)
+	end, _ := time.Parse(time.RFC3339, window.End)
+
+	clock := &stoppedClock{}
+	handler := api.MustNewAPIServer(tree, proofs,
+		api.WithCampaign(api.CampaignMeta{Name: "Season 1", ClaimWindow: window}, ""),
+		api.WithAdminTokens([]string{"admin-secret"}),
+		api.WithClaimLinkURL("https://claim.example/"),
+		api.WithClock(clock),
+	).SetupRoutes()
+	address := tree.Claims[0].Address.Hex()
+
+	get := func(path, token string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w, body
+	}
+	code := func(body map[string]interface{}) interface{} {
+		envelope, _ := body["error"].(map[string]interface{})
+		return envelope["code"]
+	}
+	phase := func() interface{} {
+		_, stats := get("/api/stats", "")
+		window, _ := stats["claimWindow"].(map[string]interface{})
+		if window["start"] != "2026-06-01T12:00:00Z" || window["end"] != "2026-07-01T00:00:00Z" {
+			t.Errorf("Expected the window in stats, got %v", window)
+		}
+		return window["phase"]
+	}
+
+	t.Run("BeforeStart", func(t *testing.T) {
+		clock.now = start.Add(-90*time.Second - time.Millisecond)
+		w, body := get("/api/proof/"+address, "")
+		if w.Code != http.StatusForbidden || code(body) != api.CodeClaimNotOpen {
+			t.Fatalf("Expected 403 CLAIM_NOT_OPEN, got %d %v", w.Code, body)
+		}
+		if body["opensAt"] != "2026-06-01T12:00:00Z" || body["countdownSeconds"] != float64(91) || w.Header().Get("Retry-After") != "91" {
+			t.Errorf("Expected the opening time and a 91s countdown, got %v", body)
+		}
+		if _, ok := body["proof"]; ok {
+			t.Error("Expected no proof before the window opens")
+		}
+		if w, _ := get("/api/link/"+address, ""); w.Code != http.StatusForbidden {
+			t.Errorf("Expected claim links to be held back too, got %d", w.Code)
+		}
+		if got := phase(); got != "upcoming" {
+			t.Errorf("Expected phase upcoming, got %v", got)
+		}
+	})
+
+	t.Run("During", func(t *testing.T) {
+		for _, now := range []time.Time{start, end.Add(-time.Nanosecond)} {
+			clock.now = now
+			if w, body := get("/api/proof/"+address, ""); w.Code != http.StatusOK || body["proof"] == nil {
+				t.Errorf("Expected the proof at %v, got %d %v", now, w.Code, body)
+			}
+		}
+		if got := phase(); got != "open" {
+			t.Errorf("Expected phase open, got %v", got)
+		}
+	})
+
+	t.Run("AfterEnd", func(t *testing.T) {
+		clock.now = end
+		w, body := get("/api/proof/"+address, "")
+		if w.Code != http.StatusGone || code(body) != api.CodeClaimClosed || body["closedAt"] != "2026-07-01T00:00:00Z" {
+			t.Fatalf("Expected 410 CLAIM_CLOSED with the closing time, got %d %v", w.Code, body)
+		}
+		if got := phase(); got != "closed" {
+			t.Errorf("Expected phase closed, got %v", got)
+		}
+	})
+
+	t.Run("AdminBypass", func(t *testing.T) {
+		for _, now := range []time.Time{start.Add(-time.Hour), end.Add(time.Hour)} {
+			clock.now = now
+			if w, body := get("/api/proof/"+address, "admin-secret"); w.Code != http.StatusOK || body["proof"] == nil {
+				t.Errorf("Expected an admin to get the proof at %v, got %d %v", now, w.Code, body)
+			}
+			if w, _ := get("/api/proof/"+address, "wrong-token"); w.Code == http.StatusOK {
+				t.Errorf("Expected a wrong token not to bypass the window at %v", now)
+			}
+		}
+	})
+
+	t.Run("NoWindow", func(t *testing.T) {
+		plain := api.MustNewAPIServer(tree, proofs).SetupRoutes()
+		w := httptest.NewRecorder()
+		plain.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+		var body map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if window, _ := body["claimWindow"].(map[string]interface{}); len(window) != 1 || window["phase"] != "open" {
+			t.Errorf("Expected an open phase without a window, got %v", body["claimWindow"])
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		for _, invalid := range []api.ClaimWindow{
+			{Start: "June 1st"},
+			{End: "2026-07-01"},
+			{Start: window.End, End: window.Start},
+		} {
+			if err := (api.CampaignMeta{Name: "Season 1", ClaimWindow: &invalid}).Validate(); err == nil {
+				t.Errorf("Expected %+v to be rejected", invalid)
+			}
+		}
+
+		cfg := config.DefaultConfig()
+		cfg.Campaign.Name = "Season 1"
+		cfg.Campaign.ClaimStart = window.End
+		cfg.Campaign.ClaimEnd = window.Start
+		if err := cfg.ValidateAll(); err == nil {
+			t.Error("Expected a config window ending before it starts to be rejected")
+		}
+	})
+}
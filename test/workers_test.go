@@ -0,0 +1,142 @@
+package test
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+
+	"merkle-airdrop/pkg/data"
+	"merkle-airdrop/pkg/merkle"
+)
+
+// countWorkers records the pool size of every worker reported to observe,
+// as an OnWorkerStart hook
+type countWorkers struct {
+	mu    sync.Mutex
+	pools []int
+}
+
+func (c *countWorkers) observe(poolSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pools = append(c.pools, poolSize)
+}
+
+// reset returns the pool sizes seen so far and forgets them
+func (c *countWorkers) reset() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pools := c.pools
+	c.pools = nil
+	return pools
+}
+
+func TestWorkerCount(t *testing.T) {
+	claims := data.GenerateTestData(20000)
+
+	var counter countWorkers
+	build := func(t *testing.T, workers int) *merkle.MerkleTree {
+		t.Helper()
+		opts := merkle.DefaultTreeOptions()
+		opts.Workers = workers
+		opts.OnWorkerStart = counter.observe
+		tree, err := merkle.NewMerkleTreeWithOptions(claims, opts)
+		if err != nil {
+			t.Fatalf("Failed to build tree with %d workers: %v", workers, err)
+		}
+		return tree
+	}
+
+	t.Run("IdenticalOutputs", func(t *testing.T) {
+		serial, parallel := build(t, 1), build(t, 8)
+		if serial.GetRootHash() != parallel.GetRootHash() {
+			t.Fatalf("Expected the same root, got %s and %s", serial.GetRootHash(), parallel.GetRootHash())
+		}
+
+		one, err := serial.GenerateAllProofsWithWorkers(1)
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		eight, err := parallel.GenerateAllProofsWithWorkers(8)
+		if err != nil {
+			t.Fatalf("Failed to generate proofs: %v", err)
+		}
+		if len(one) != len(claims) || !reflect.DeepEqual(one, eight) {
+			t.Error("Expected identical proofs with 1 and 8 workers")
+		}
+	})
+
+	t.Run("Goroutines", func(t *testing.T) {
+		counter.reset()
+
+		// A serial build starts no goroutines at all
+		tree := build(t, 1)
+		if pools := counter.reset(); len(pools) != 0 {
+			t.Errorf("Expected no workers for a serial build, got %d", len(pools))
+		}
+
+		// Levels get a worker per 1024 nodes, at most 8: 20000 leaves and
+		// 10000 nodes one level up get 8, then 4, then 2, then none
+		build(t, 8)
+		want := make([]int, 0, 22)
+		for _, size := range []int{8, 8, 4, 2} {
+			for i := 0; i < size; i++ {
+				want = append(want, size)
+			}
+		}
+		if pools := counter.reset(); !reflect.DeepEqual(pools, want) {
+			t.Errorf("Expected workers in pools %v, got %v", want, pools)
+		}
+
+		for _, workers := range []int{1, 3, 8} {
+			if _, err := tree.GenerateAllProofsWithWorkers(workers); err != nil {
+				t.Fatalf("Failed to generate proofs: %v", err)
+			}
+			if pools := counter.reset(); len(pools) != workers {
+				t.Errorf("Expected %d proof workers, got %d", workers, len(pools))
+			}
+		}
+
+		// Zero means one per CPU, for proofs and when taken from the tree
+		tree.GenerateAllProofsWithWorkers(0)
+		if pools := counter.reset(); len(pools) != runtime.NumCPU() {
+			t.Errorf("Expected %d proof workers, got %d", runtime.NumCPU(), len(pools))
+		}
+		tree.GenerateAllProofs()
+		if pools := counter.reset(); len(pools) != 1 {
+			t.Errorf("Expected the tree's single worker, got %d", len(pools))
+		}
+
+		for _, workers := range []int{2, 5} {
+			bp := merkle.NewBatchProcessor(100, workers)
+			bp.OnWorkerStart = counter.observe
+			if err := bp.ProcessClaims(claims[:1000], func([]merkle.AirdropClaim) error { return nil }); err != nil {
+				t.Fatalf("ProcessClaims failed: %v", err)
+			}
+			if pools := counter.reset(); len(pools) != workers {
+				t.Errorf("Expected %d batch workers, got %d", workers, len(pools))
+			}
+		}
+	})
+
+	t.Run("Negative", func(t *testing.T) {
+		opts := merkle.DefaultTreeOptions()
+		opts.Workers = -1
+		if _, err := merkle.NewMerkleTreeWithOptions(claims[:10], opts); err == nil {
+			t.Error("Expected a negative worker count to be rejected by the tree")
+		}
+		if _, err := merkle.NewGenericMerkleTree([]merkle.GenericClaim{claims[0].Generic()}, opts); err == nil {
+			t.Error("Expected a negative worker count to be rejected by the generic tree")
+		}
+
+		tree := build(t, 0)
+		if _, err := tree.GenerateAllProofsWithWorkers(-2); err == nil {
+			t.Error("Expected a negative worker count to be rejected by proof generation")
+		}
+		bp := merkle.NewBatchProcessor(10, -1)
+		if err := bp.ProcessClaims(claims[:10], func([]merkle.AirdropClaim) error { return nil }); err == nil {
+			t.Error("Expected a negative worker count to be rejected by the batch processor")
+		}
+	})
+}